@@ -13,7 +13,7 @@ die() {
 if [[ -z "$USE_BAZEL" || "$USE_BAZEL" -eq "0" ]]; then
   must go get -t ./...
 else
-  BAZEL_VERSION="${BAZEL_VERSION:-0.25.0}"
+  BAZEL_VERSION="${BAZEL_VERSION:-6.4.0}"
   case "$TRAVIS_OS_NAME" in
     linux)
       BAZEL_INSTALLER_URL="https://github.com/bazelbuild/bazel/releases/download/${BAZEL_VERSION}/bazel-${BAZEL_VERSION}-installer-linux-x86_64.sh"
@@ -32,7 +32,7 @@ else
   must /tmp/bazel.sh --user
   rm -f /tmp/bazel.sh
   if [[ ! -z "$TRAVIS_GO_VERSION" ]]; then
-    must $SEDI -e 's/^go_register_toolchains()/go_register_toolchains(go_version="host")/' WORKSPACE
+    must $SEDI -e 's/^go_register_toolchains(.*)/go_register_toolchains(go_version = "host")/' WORKSPACE
   fi
   must "$HOME/bin/bazel" --bazelrc=_travis/bazelrc version
   must "$HOME/bin/bazel" --bazelrc=_travis/bazelrc fetch //...
@@ -0,0 +1,48 @@
+package capnp
+
+// AllocRecord describes one allocation made while building a message.
+// See Message.AllocTrace.
+type AllocRecord struct {
+	// Size is the number of bytes allocated, padded to a word.
+	Size Size
+
+	// Segment is the segment the object was allocated in.
+	Segment SegmentID
+
+	// NewSegment is true if the allocation did not fit in the requested
+	// segment and the arena had to allocate or grow a segment for it.
+	NewSegment bool
+
+	// Caller is the function, file, and line of the first caller
+	// outside this package that asked for the allocation, usually a
+	// generated constructor or setter.
+	Caller string
+}
+
+// AllocTrace returns the allocations made in m since it was created or
+// last reset, in order.  Allocations are only recorded when the package
+// is built with the capnpdebug build tag; otherwise AllocTrace always
+// returns nil.  The trace helps track down why a message grows larger
+// than expected.
+func (m *Message) AllocTrace() []AllocRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.allocs) == 0 {
+		return nil
+	}
+	trace := make([]AllocRecord, len(m.allocs))
+	copy(trace, m.allocs)
+	return trace
+}
+
+func (m *Message) traceAlloc(s *Segment, sz Size, newSeg bool) {
+	r := AllocRecord{
+		Size:       sz,
+		Segment:    s.ID(),
+		NewSegment: newSeg,
+		Caller:     allocCaller(),
+	}
+	m.mu.Lock()
+	m.allocs = append(m.allocs, r)
+	m.mu.Unlock()
+}
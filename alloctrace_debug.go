@@ -0,0 +1,27 @@
+// +build capnpdebug
+
+package capnp
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const allocDebug = true
+
+// allocCaller returns the location of the first frame on the stack
+// outside this package.
+func allocCaller() string {
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var f runtime.Frame
+	for more := true; more; {
+		f, more = frames.Next()
+		if !strings.HasPrefix(f.Function, "zombiezen.com/go/capnproto2.") {
+			break
+		}
+	}
+	return f.Function + " (" + f.File + ":" + strconv.Itoa(f.Line) + ")"
+}
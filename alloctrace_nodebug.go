@@ -0,0 +1,9 @@
+// +build !capnpdebug
+
+package capnp
+
+const allocDebug = false
+
+func allocCaller() string {
+	return ""
+}
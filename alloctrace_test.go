@@ -0,0 +1,56 @@
+// +build capnpdebug
+
+package capnp_test
+
+import (
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestAllocTrace(t *testing.T) {
+	msg, seg, err := capnp.NewMessage(capnp.MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := capnp.NewRootStruct(seg, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, strings.Repeat("x", 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := msg.AllocTrace()
+	want := []capnp.Size{8, 16, 104}
+	if len(trace) != len(want) {
+		t.Fatalf("len(AllocTrace()) = %d; want %d: %+v", len(trace), len(want), trace)
+	}
+	var total capnp.Size
+	for i, r := range trace {
+		if r.Size != want[i] {
+			t.Errorf("AllocTrace()[%d].Size = %d; want %d", i, r.Size, want[i])
+		}
+		if !strings.Contains(r.Caller, "TestAllocTrace") {
+			t.Errorf("AllocTrace()[%d].Caller = %q; want TestAllocTrace", i, r.Caller)
+		}
+		total += r.Size
+	}
+	var used capnp.Size
+	for i := int64(0); i < msg.NumSegments(); i++ {
+		s, err := msg.Segment(capnp.SegmentID(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		used += capnp.Size(len(s.Data()))
+	}
+	if total != used {
+		t.Errorf("AllocTrace() total = %d bytes; message uses %d", total, used)
+	}
+
+	msg.Reset(capnp.MultiSegment(nil))
+	if trace := msg.AllocTrace(); trace != nil {
+		t.Errorf("after Reset, AllocTrace() = %+v; want nil", trace)
+	}
+}
@@ -0,0 +1,147 @@
+// Package capidcheck defines an analyzer that reports capability IDs
+// moved from one message to another.
+//
+// A CapabilityID is an index into its message's capability table.
+// Pointers set with Struct.SetPtr and friends are copied into the
+// destination message, capabilities included, but an Interface built
+// by hand with capnp.NewInterface is not: its ID must come from the
+// destination message, usually by way of Message.AddCap.  The analyzer
+// reports calls like
+//
+//	capnp.NewInterface(dst.Segment(), src.Capability())
+//
+// where the segment and the capability ID come from different values.
+package capidcheck // import "zombiezen.com/go/capnproto2/analysis/capidcheck"
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `check for capability IDs used outside their message
+
+A capability ID is only meaningful in the message whose capability
+table it indexes.  To refer to the same capability from another
+message, add its client to that message with Message.AddCap.`
+
+// Analyzer reports capability IDs used outside their message.
+var Analyzer = &analysis.Analyzer{
+	Name:     "capnpcapid",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const capnpPath = "zombiezen.com/go/capnproto2"
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	filter := []ast.Node{(*ast.CallExpr)(nil)}
+	insp.WithStack(filter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		call := n.(*ast.CallExpr)
+		if !push || !isNewInterface(pass.TypesInfo, call) || len(call.Args) != 2 {
+			return true
+		}
+		src := methodReceiver(call.Args[1], "Capability")
+		if src == nil {
+			return true
+		}
+		dst := segmentOrigin(pass.TypesInfo, enclosingBody(stack), call.Args[0])
+		if dst == nil {
+			// Unknown origin: give it the benefit of the doubt.
+			return true
+		}
+		if types.ExprString(src) != types.ExprString(dst) {
+			pass.Reportf(call.Args[1].Pos(), "capability ID from %s used in the message of %s; use Message.AddCap(%s.Client())",
+				types.ExprString(src), types.ExprString(dst), types.ExprString(src))
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// segmentOrigin returns the expression that seg was obtained from by
+// calling Segment, or nil if it is not known.  If seg is a local
+// variable that is assigned exactly once in body, its assignment is
+// consulted.
+func segmentOrigin(info *types.Info, body *ast.BlockStmt, seg ast.Expr) ast.Expr {
+	seg = ast.Unparen(seg)
+	if x := methodReceiver(seg, "Segment"); x != nil {
+		return x
+	}
+	id, ok := seg.(*ast.Ident)
+	if !ok || body == nil {
+		return nil
+	}
+	v := info.Uses[id]
+	if v == nil {
+		return nil
+	}
+	var origin ast.Expr
+	n := 0
+	ast.Inspect(body, func(node ast.Node) bool {
+		a, ok := node.(*ast.AssignStmt)
+		if !ok {
+			return true
+		}
+		for i, lhs := range a.Lhs {
+			if lid, ok := lhs.(*ast.Ident); !ok || info.ObjectOf(lid) != v {
+				continue
+			}
+			n++
+			if len(a.Lhs) == len(a.Rhs) {
+				origin = methodReceiver(a.Rhs[i], "Segment")
+			}
+		}
+		return true
+	})
+	if n != 1 {
+		return nil
+	}
+	return origin
+}
+
+// enclosingBody returns the body of the innermost function in stack.
+func enclosingBody(stack []ast.Node) *ast.BlockStmt {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch f := stack[i].(type) {
+		case *ast.FuncDecl:
+			return f.Body
+		case *ast.FuncLit:
+			return f.Body
+		}
+	}
+	return nil
+}
+
+// methodReceiver returns x if e is a call of the form x.name(), or nil
+// otherwise.
+func methodReceiver(e ast.Expr, name string) ast.Expr {
+	call, ok := ast.Unparen(e).(*ast.CallExpr)
+	if !ok || len(call.Args) != 0 {
+		return nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return nil
+	}
+	return sel.X
+}
+
+func isNewInterface(info *types.Info, call *ast.CallExpr) bool {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == capnpPath && fn.Name() == "NewInterface"
+}
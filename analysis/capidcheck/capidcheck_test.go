@@ -0,0 +1,12 @@
+package capidcheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"zombiezen.com/go/capnproto2/analysis/capidcheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), capidcheck.Analyzer, "a")
+}
@@ -0,0 +1,19 @@
+package a
+
+import capnp "zombiezen.com/go/capnproto2"
+
+func crossMessage(dst capnp.Struct, src capnp.Interface) {
+	capnp.NewInterface(dst.Segment(), src.Capability()) // want `capability ID from src used in the message of dst`
+	seg := dst.Segment()
+	capnp.NewInterface(seg, (src.Capability())) // want `capability ID from src used in the message of dst`
+}
+
+func sameMessage(dst capnp.Struct, src capnp.Interface, other *capnp.Segment) {
+	capnp.NewInterface(src.Segment(), src.Capability())
+	seg := dst.Segment()
+	capnp.NewInterface(seg, seg.Message().AddCap(src.Client()))
+	capnp.NewInterface(other, src.Capability())
+	seg2 := dst.Segment()
+	seg2 = src.Segment()
+	capnp.NewInterface(seg2, src.Capability())
+}
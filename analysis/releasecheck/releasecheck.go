@@ -0,0 +1,241 @@
+// Package releasecheck defines an analyzer that reports call results
+// that are never released.
+//
+// Every generated method that makes a call returns a promise that
+// embeds *capnp.Pipeline.  Closing the promise releases the
+// capabilities in the call's results; a promise that is discarded or
+// only used to read results leaks them until the message is garbage
+// collected.  The analyzer reports promises that are:
+//
+//   - discarded, as in a call used as a statement,
+//   - used only in a chain, as in c.Foo(ctx, nil).Struct(), or
+//   - stored in a local variable that is never closed and never
+//     escapes the function.
+//
+// A promise that is only used to pipeline, as in c.Foo(ctx, nil).Bar(),
+// is not reported: pipelined calls are made on the promise before its
+// results arrive, and the results are released along with the
+// pipelined capabilities.
+package releasecheck // import "zombiezen.com/go/capnproto2/analysis/releasecheck"
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `check that the results of Cap'n Proto calls are released
+
+Promises returned by generated call methods hold the capabilities in
+the call's results.  They should be closed, usually with a deferred
+call to Close, once the results are no longer needed.`
+
+// Analyzer reports unreleased call results.
+var Analyzer = &analysis.Analyzer{
+	Name:     "capnprelease",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const capnpPath = "zombiezen.com/go/capnproto2"
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	filter := []ast.Node{(*ast.CallExpr)(nil)}
+	insp.WithStack(filter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		if !isCallMethod(pass.TypesInfo, call) {
+			return true
+		}
+		checkUse(pass, call, stack)
+		return true
+	})
+	return nil, nil
+}
+
+// checkUse reports call if its result is not released.  stack is the
+// path from the file to call, inclusive.
+func checkUse(pass *analysis.Pass, call *ast.CallExpr, stack []ast.Node) {
+	name := calleeName(call)
+	var child ast.Node = call
+	i := len(stack) - 2
+	for ; i >= 0; i-- {
+		if _, ok := stack[i].(*ast.ParenExpr); !ok {
+			break
+		}
+		child = stack[i]
+	}
+	if i < 0 {
+		return
+	}
+	switch parent := stack[i].(type) {
+	case *ast.ExprStmt:
+		pass.Reportf(call.Pos(), "result of %s is discarded; close it to release the call's results", name)
+	case *ast.SelectorExpr:
+		if parent.X == child && parent.Sel.Name != "Close" && !isPipelineAccess(pass.TypesInfo, parent) {
+			pass.Reportf(call.Pos(), "result of %s is never closed", name)
+		}
+	case *ast.AssignStmt:
+		for j, rhs := range parent.Rhs {
+			if rhs == child && j < len(parent.Lhs) {
+				checkVar(pass, call, name, parent.Lhs[j], stack[:i])
+			}
+		}
+	case *ast.ValueSpec:
+		for j, v := range parent.Values {
+			if v == child && j < len(parent.Names) {
+				checkVar(pass, call, name, parent.Names[j], stack[:i])
+			}
+		}
+	}
+	// Other uses, like passing the promise to a function or returning
+	// it, hand off responsibility for closing it.
+}
+
+// checkVar reports call if the variable it is assigned to is never
+// closed and does not escape the enclosing function.
+func checkVar(pass *analysis.Pass, call *ast.CallExpr, name string, lhs ast.Expr, stack []ast.Node) {
+	id, ok := lhs.(*ast.Ident)
+	if !ok {
+		// Stored in a field, element, or dereferenced pointer.
+		return
+	}
+	if id.Name == "_" {
+		pass.Reportf(call.Pos(), "result of %s is discarded; close it to release the call's results", name)
+		return
+	}
+	obj := pass.TypesInfo.ObjectOf(id)
+	if obj == nil || obj.Parent() == obj.Pkg().Scope() {
+		return
+	}
+	body := enclosingBody(stack)
+	if body == nil {
+		return
+	}
+	uses, selected, pipelined, closed := 0, 0, 0, false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && pass.TypesInfo.Uses[x] == obj {
+				selected++
+				if n.Sel.Name == "Close" {
+					closed = true
+				}
+				if isPipelineAccess(pass.TypesInfo, n) {
+					pipelined++
+				}
+			}
+		case *ast.Ident:
+			if pass.TypesInfo.Uses[n] == obj {
+				uses++
+			}
+		}
+		return true
+	})
+	if !closed && uses == selected && pipelined < selected {
+		pass.Reportf(call.Pos(), "result of %s is never closed", name)
+	}
+}
+
+// isPipelineAccess reports whether sel selects a method that pipelines
+// on a promise: one that returns another promise, a
+// *capnp.PipelineClient, or a generated client type.
+func isPipelineAccess(info *types.Info, sel *ast.SelectorExpr) bool {
+	sig, ok := info.TypeOf(sel).(*types.Signature)
+	if !ok || sig.Results().Len() != 1 {
+		return false
+	}
+	t := sig.Results().At(0).Type()
+	if isPromise(t) {
+		return true
+	}
+	if p, ok := types.Unalias(t).(*types.Pointer); ok && isNamed(p.Elem(), capnpPath, "PipelineClient") {
+		return true
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Name() == "Client" && isNamed(f.Type(), capnpPath, "Client") {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingBody returns the body of the innermost function in stack.
+func enclosingBody(stack []ast.Node) *ast.BlockStmt {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch f := stack[i].(type) {
+		case *ast.FuncDecl:
+			return f.Body
+		case *ast.FuncLit:
+			return f.Body
+		}
+	}
+	return nil
+}
+
+// isCallMethod reports whether call looks like a generated call
+// method: it takes a context as its first argument and returns a
+// promise.
+func isCallMethod(info *types.Info, call *ast.CallExpr) bool {
+	sig, ok := info.TypeOf(call.Fun).(*types.Signature)
+	if !ok || sig.Params().Len() == 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	if !isNamed(sig.Params().At(0).Type(), "context", "Context") {
+		return false
+	}
+	return isPromise(sig.Results().At(0).Type())
+}
+
+// isPromise reports whether t is *capnp.Pipeline or a struct that
+// embeds it.
+func isPromise(t types.Type) bool {
+	if isPipeline(t) {
+		return true
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && isPipeline(f.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPipeline(t types.Type) bool {
+	p, ok := types.Unalias(t).(*types.Pointer)
+	return ok && isNamed(p.Elem(), capnpPath, "Pipeline")
+}
+
+func isNamed(t types.Type, pkgPath, name string) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := n.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkgPath && obj.Name() == name
+}
+
+func calleeName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	case *ast.Ident:
+		return fun.Name
+	}
+	return "call"
+}
@@ -0,0 +1,12 @@
+package releasecheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"zombiezen.com/go/capnproto2/analysis/releasecheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), releasecheck.Analyzer, "a")
+}
@@ -0,0 +1,89 @@
+package a
+
+import (
+	"context"
+
+	netctx "golang.org/x/net/context"
+	capnp "zombiezen.com/go/capnproto2"
+)
+
+type Foo struct{}
+
+type Foo_bar_Results_Promise struct{ *capnp.Pipeline }
+
+func (c Foo) Bar(ctx context.Context, params func(capnp.Struct) error) Foo_bar_Results_Promise {
+	return Foo_bar_Results_Promise{new(capnp.Pipeline)}
+}
+
+func (p Foo_bar_Results_Promise) Baz() Baz { return Baz{} }
+
+func (p Foo_bar_Results_Promise) Next() Foo_bar_Results_Promise { return p }
+
+type Baz struct{ Client capnp.Client }
+
+func (c Baz) Qux(ctx context.Context) *capnp.Pipeline {
+	return new(capnp.Pipeline)
+}
+
+func (c Foo) Raw(ctx context.Context) *capnp.Pipeline {
+	return new(capnp.Pipeline)
+}
+
+func (c Foo) Old(ctx netctx.Context) *capnp.Pipeline {
+	return new(capnp.Pipeline)
+}
+
+func discarded(ctx context.Context, c Foo) {
+	c.Bar(ctx, nil) // want `result of Bar is discarded`
+	_ = c.Raw(ctx)  // want `result of Raw is discarded`
+	c.Old(ctx)      // want `result of Old is discarded`
+}
+
+func chained(ctx context.Context, c Foo) {
+	c.Bar(ctx, nil).Struct() // want `result of Bar is never closed`
+	c.Bar(ctx, nil).Close()
+}
+
+func pipelined(ctx context.Context, c Foo) {
+	q := c.Bar(ctx, nil).Baz().Qux(ctx)
+	defer q.Close()
+	r := c.Bar(ctx, nil).Next().Baz().Qux(ctx)
+	defer r.Close()
+	s := c.Raw(ctx).GetPipeline(0).Client()
+	_ = s
+	p := c.Bar(ctx, nil)
+	t := p.Baz().Qux(ctx)
+	defer t.Close()
+	p2 := c.Bar(ctx, nil) // want `result of Bar is never closed`
+	p2.Baz()
+	p2.Struct()
+}
+
+func unclosed(ctx context.Context, c Foo) {
+	p := c.Bar(ctx, nil) // want `result of Bar is never closed`
+	p.Struct()
+	var q = (c.Raw(ctx)) // want `result of Raw is never closed`
+	q.Struct()
+}
+
+func closed(ctx context.Context, c Foo) {
+	p := c.Bar(ctx, nil)
+	defer p.Close()
+	p.Struct()
+	f := func() {
+		q := c.Raw(ctx)
+		q.Close()
+	}
+	f()
+}
+
+func escapes(ctx context.Context, c Foo, ps []*capnp.Pipeline) Foo_bar_Results_Promise {
+	ps[0] = c.Raw(ctx)
+	q := c.Raw(ctx)
+	take(q)
+	take(c.Raw(ctx))
+	p := c.Bar(ctx, nil)
+	return p
+}
+
+func take(p *capnp.Pipeline) {}
@@ -0,0 +1,6 @@
+// Package context is a stub of golang.org/x/net/context for testing.
+package context
+
+import "context"
+
+type Context = context.Context
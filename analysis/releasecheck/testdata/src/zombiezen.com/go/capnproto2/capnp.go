@@ -0,0 +1,18 @@
+// Package capnp is a stub of the Cap'n Proto runtime for testing.
+package capnp
+
+type Struct struct{}
+
+type Client interface{}
+
+type Pipeline struct{}
+
+type PipelineClient Pipeline
+
+func (p *Pipeline) Struct() (Struct, error) { return Struct{}, nil }
+
+func (p *Pipeline) Close() error { return nil }
+
+func (p *Pipeline) GetPipeline(off uint16) *Pipeline { return p }
+
+func (p *Pipeline) Client() *PipelineClient { return (*PipelineClient)(p) }
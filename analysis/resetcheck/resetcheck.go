@@ -0,0 +1,239 @@
+// Package resetcheck defines an analyzer that reports message data
+// used after its message was reset.
+//
+// Message.Reset invalidates every Struct, List, Ptr, Interface, and
+// Segment that was read from the message.  The analyzer tracks local
+// variables assigned from expressions that mention a *capnp.Message,
+// directly or through other such variables, and reports uses of them
+// that follow a call to Reset on that message without an intervening
+// assignment.  The analysis is by source position within a function,
+// so it does not follow values across function calls.
+package resetcheck // import "zombiezen.com/go/capnproto2/analysis/resetcheck"
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `check for message data used after its message was reset
+
+Calling Reset on a Message invalidates all structs, lists, pointers,
+and segments previously read from it.  They must be read again from the
+message after it is reset.`
+
+// Analyzer reports message data used after a reset.
+var Analyzer = &analysis.Analyzer{
+	Name:     "capnpreset",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const capnpPath = "zombiezen.com/go/capnproto2"
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		if body := n.(*ast.FuncDecl).Body; body != nil {
+			checkFunc(pass, body)
+		}
+	})
+	return nil, nil
+}
+
+// A reset is a call to Message.Reset.
+type reset struct {
+	msg types.Object
+	pos token.Pos
+}
+
+// checkFunc reports uses after reset in a single function body.
+// Function literals are treated as part of the enclosing body.
+func checkFunc(pass *analysis.Pass, body *ast.BlockStmt) {
+	var resets []reset
+	var assigns []assignment
+	lhs := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if msg := resetMessage(pass.TypesInfo, n); msg != nil {
+				resets = append(resets, reset{msg, n.End()})
+			}
+		case *ast.AssignStmt:
+			for _, e := range n.Lhs {
+				if id, ok := e.(*ast.Ident); ok {
+					lhs[id] = true
+					assigns = append(assigns, assignment{id, n.Rhs, n.End()})
+				}
+			}
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				lhs[id] = true
+				assigns = append(assigns, assignment{id, n.Values, n.End()})
+			}
+		case *ast.RangeStmt:
+			for _, e := range []ast.Expr{n.Key, n.Value} {
+				if id, ok := e.(*ast.Ident); ok {
+					lhs[id] = true
+					assigns = append(assigns, assignment{id, []ast.Expr{n.X}, n.X.End()})
+				}
+			}
+		}
+		return true
+	})
+	if len(resets) == 0 {
+		return
+	}
+	derived := deriveVars(pass.TypesInfo, assigns)
+	reported := make(map[types.Object]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || lhs[id] {
+			return true
+		}
+		v := pass.TypesInfo.Uses[id]
+		if v == nil || reported[v] || derived[v] == nil {
+			return true
+		}
+		last := lastAssign(pass.TypesInfo, assigns, v, id.Pos())
+		for _, r := range resets {
+			if derived[v][r.msg] && last < r.pos && r.pos < id.Pos() {
+				pass.Reportf(id.Pos(), "%s is used after %s was reset", id.Name, r.msg.Name())
+				reported[v] = true
+				break
+			}
+		}
+		return true
+	})
+}
+
+// An assignment records a variable being assigned from one or more
+// expressions.
+type assignment struct {
+	id  *ast.Ident
+	rhs []ast.Expr
+	end token.Pos
+}
+
+// deriveVars returns the set of messages each message data variable
+// was derived from.
+func deriveVars(info *types.Info, assigns []assignment) map[types.Object]map[types.Object]bool {
+	derived := make(map[types.Object]map[types.Object]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, a := range assigns {
+			v := info.ObjectOf(a.id)
+			if v == nil || !isData(v.Type()) {
+				continue
+			}
+			for _, e := range a.rhs {
+				ast.Inspect(e, func(n ast.Node) bool {
+					id, ok := n.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					u := info.Uses[id]
+					if u == nil {
+						return true
+					}
+					var msgs []types.Object
+					if isMessage(u.Type()) {
+						msgs = []types.Object{u}
+					} else {
+						for m := range derived[u] {
+							msgs = append(msgs, m)
+						}
+					}
+					for _, m := range msgs {
+						if derived[v] == nil {
+							derived[v] = make(map[types.Object]bool)
+						}
+						if !derived[v][m] {
+							derived[v][m] = true
+							changed = true
+						}
+					}
+					return true
+				})
+			}
+		}
+	}
+	return derived
+}
+
+// lastAssign returns the end of the last assignment to v that
+// precedes pos, or token.NoPos if there is none.
+func lastAssign(info *types.Info, assigns []assignment, v types.Object, pos token.Pos) token.Pos {
+	last := token.NoPos
+	for _, a := range assigns {
+		if a.end < pos && a.end > last && info.ObjectOf(a.id) == v {
+			last = a.end
+		}
+	}
+	return last
+}
+
+// resetMessage returns the message variable that call resets, or nil
+// if call is not a call to Message.Reset on a variable.
+func resetMessage(info *types.Info, call *ast.CallExpr) types.Object {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Reset" {
+		return nil
+	}
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	obj := info.Uses[id]
+	if obj == nil || !isMessage(obj.Type()) {
+		return nil
+	}
+	return obj
+}
+
+func isMessage(t types.Type) bool {
+	p, ok := types.Unalias(t).(*types.Pointer)
+	return ok && isCapnp(p.Elem(), "Message")
+}
+
+// isData reports whether t is a type whose values refer to data in a
+// message: a capnp pointer type, a segment, or a struct that embeds
+// one of those, like a generated struct type.
+func isData(t types.Type) bool {
+	if p, ok := types.Unalias(t).(*types.Pointer); ok {
+		return isCapnp(p.Elem(), "Segment")
+	}
+	if n, ok := types.Unalias(t).(*types.Named); ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == capnpPath {
+		switch name := n.Obj().Name(); name {
+		case "Struct", "Ptr", "List", "Pointer", "Interface":
+			return true
+		default:
+			return strings.HasSuffix(name, "List")
+		}
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && isData(f.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCapnp(t types.Type, name string) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := n.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == capnpPath && obj.Name() == name
+}
@@ -0,0 +1,12 @@
+package resetcheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"zombiezen.com/go/capnproto2/analysis/resetcheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), resetcheck.Analyzer, "a")
+}
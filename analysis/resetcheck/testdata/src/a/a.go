@@ -0,0 +1,42 @@
+package a
+
+import capnp "zombiezen.com/go/capnproto2"
+
+type Foo struct{ capnp.Struct }
+
+func ReadRootFoo(msg *capnp.Message) (Foo, error) {
+	p, err := msg.RootPtr()
+	return Foo{p.Struct()}, err
+}
+
+func useAfterReset(msg *capnp.Message, arena capnp.Arena) {
+	foo, _ := ReadRootFoo(msg)
+	s := foo.Struct
+	msg.Reset(arena)
+	foo.Uint8(0) // want `foo is used after msg was reset`
+	s.Uint8(0)   // want `s is used after msg was reset`
+	foo.Uint8(1)
+}
+
+func segmentAfterReset(msg *capnp.Message, arena capnp.Arena) {
+	seg, _ := msg.Segment(0)
+	msg.Reset(arena)
+	_ = seg // want `seg is used after msg was reset`
+}
+
+func rereadAfterReset(msg *capnp.Message, arenas []capnp.Arena) {
+	var foo Foo
+	for _, a := range arenas {
+		msg.Reset(a)
+		foo, _ = ReadRootFoo(msg)
+		foo.Uint8(0)
+	}
+}
+
+func otherMessage(msg, other *capnp.Message, arena capnp.Arena) {
+	foo, _ := ReadRootFoo(other)
+	n := foo.Uint8(0)
+	msg.Reset(arena)
+	foo.Uint8(0)
+	_ = n
+}
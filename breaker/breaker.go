@@ -0,0 +1,257 @@
+// Package breaker provides a capability that stops calling a failing
+// capability for a while, like a circuit breaker.
+//
+// A Breaker is useful in front of a flaky backend: once the backend has
+// failed several calls in a row, further calls fail right away instead
+// of piling up on it, and after a cooldown a few probe calls decide
+// whether it has recovered.  A Breaker is a capnp.Client, so it can be
+// wrapped by or wrap other clients, like a switchboard.Switchboard that
+// routes calls among backends or a client from capnp.WithTimeout.
+package breaker // import "zombiezen.com/go/capnproto2/breaker"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
+)
+
+// A State is the state of a breaker.
+type State int
+
+// Breaker states.
+const (
+	// Closed is the state of a breaker that makes calls.
+	Closed State = iota
+	// Open is the state of a breaker that fails calls with ErrOpen.
+	Open
+	// HalfOpen is the state of a breaker whose cooldown has elapsed.
+	// It makes a limited number of probe calls to decide whether to
+	// close again.
+	HalfOpen
+)
+
+// String returns the state's name.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// An Option is an option for creating a breaker.
+type Option struct {
+	f func(*Breaker)
+}
+
+// Threshold sets the number of consecutive failed calls that opens the
+// breaker.  The default is 5.
+func Threshold(n int) Option {
+	return Option{func(b *Breaker) {
+		b.threshold = n
+	}}
+}
+
+// Cooldown sets how long the breaker stays open before it lets probe
+// calls through.  The default is 30 seconds.
+func Cooldown(d time.Duration) Option {
+	return Option{func(b *Breaker) {
+		b.cooldown = d
+	}}
+}
+
+// Probes sets the number of calls that a half-open breaker makes at
+// once.  The breaker closes once they all succeed and opens again as
+// soon as one fails.  The default is 1.
+func Probes(n int) Option {
+	return Option{func(b *Breaker) {
+		b.probes = n
+	}}
+}
+
+// IsFailure sets the function that decides whether a call's error
+// counts as a failure of the backend.  By default, every error counts
+// except context.Canceled, which is usually the caller giving up.
+// Timeouts count as failures.
+func IsFailure(f func(error) bool) Option {
+	return Option{func(b *Breaker) {
+		b.isFailure = f
+	}}
+}
+
+// OnStateChange sets a function that is called whenever the breaker
+// changes state, which is useful for metrics and logging.  It is called
+// while the breaker's lock is held, so it must not block or make calls
+// on the breaker.
+func OnStateChange(f func(from, to State)) Option {
+	return Option{func(b *Breaker) {
+		b.onChange = f
+	}}
+}
+
+// Clock sets the clock that the breaker's cooldown is measured on.  By
+// default, the breaker uses clock.Real.
+func Clock(clk clock.Clock) Option {
+	return Option{func(b *Breaker) {
+		b.clock = clk
+	}}
+}
+
+// A Breaker is a capnp.Client that forwards calls to another client
+// until it fails too many calls in a row.  It is safe to use from
+// multiple goroutines.
+//
+// A call counts as failed if its answer fails, as reported by the
+// answer's Struct method.  Calls that were made before the breaker last
+// changed state don't count.
+type Breaker struct {
+	c         capnp.Client
+	threshold int
+	cooldown  time.Duration
+	probes    int
+	isFailure func(error) bool
+	onChange  func(from, to State)
+	clock     clock.Clock
+
+	mu       sync.Mutex
+	state    State
+	gen      uint64    // incremented on each state change
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the breaker last opened
+	inFlight int       // probes in flight while half-open
+	passed   int       // probes that succeeded while half-open
+}
+
+// New returns a breaker in front of c.  The breaker takes ownership
+// of c.
+func New(c capnp.Client, options ...Option) *Breaker {
+	b := &Breaker{
+		c:         c,
+		threshold: 5,
+		cooldown:  30 * time.Second,
+		probes:    1,
+		isFailure: defaultIsFailure,
+		clock:     clock.Real,
+	}
+	for _, o := range options {
+		o.f(b)
+	}
+	return b
+}
+
+func defaultIsFailure(err error) bool {
+	return err != context.Canceled
+}
+
+// State returns the breaker's current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkCooldownLocked()
+	return b.state
+}
+
+// Call forwards call to the underlying client, unless the breaker is
+// open or its probes are in flight, in which case the call fails with
+// ErrOpen.
+func (b *Breaker) Call(call *capnp.Call) capnp.Answer {
+	b.mu.Lock()
+	b.checkCooldownLocked()
+	switch {
+	case b.state == Open, b.state == HalfOpen && b.inFlight+b.passed >= b.probes:
+		b.mu.Unlock()
+		return capnp.ErrorAnswer(ErrOpen)
+	case b.state == HalfOpen:
+		b.inFlight++
+	}
+	gen := b.gen
+	b.mu.Unlock()
+
+	ans := b.c.Call(call)
+	if capnp.IsFixedAnswer(ans) {
+		_, err := ans.Struct()
+		b.record(gen, err)
+		return ans
+	}
+	go func() {
+		_, err := ans.Struct()
+		b.record(gen, err)
+	}()
+	return ans
+}
+
+// record updates the breaker with the outcome of a call made in
+// generation gen.
+func (b *Breaker) record(gen uint64, err error) {
+	failed := err != nil && b.isFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.setStateLocked(Open)
+		}
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.setStateLocked(Open)
+			return
+		}
+		if err == nil {
+			b.passed++
+		}
+		if b.passed >= b.probes {
+			b.setStateLocked(Closed)
+		}
+	}
+}
+
+// checkCooldownLocked moves an open breaker to half-open once its
+// cooldown has elapsed.  The caller must be holding onto b.mu.
+func (b *Breaker) checkCooldownLocked() {
+	if b.state == Open && !b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+		b.setStateLocked(HalfOpen)
+	}
+}
+
+// setStateLocked changes the breaker's state and starts a new
+// generation.  The caller must be holding onto b.mu.
+func (b *Breaker) setStateLocked(s State) {
+	from := b.state
+	b.state = s
+	b.gen++
+	b.failures, b.inFlight, b.passed = 0, 0, 0
+	if s == Open {
+		b.openedAt = b.clock.Now()
+	}
+	if b.onChange != nil {
+		b.onChange(from, s)
+	}
+}
+
+// Close closes the underlying client.
+func (b *Breaker) Close() error {
+	return b.c.Close()
+}
+
+// ErrOpen is the error returned from calls that the breaker did not
+// make because it is open.
+var ErrOpen = errors.New("breaker: circuit open")
@@ -0,0 +1,132 @@
+package breaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/breaker"
+	"zombiezen.com/go/capnproto2/clock"
+)
+
+var errBackend = errors.New("backend down")
+
+// flakyClient fails its calls while down is true.
+type flakyClient struct {
+	down  bool
+	calls int
+}
+
+func (fc *flakyClient) Call(call *capnp.Call) capnp.Answer {
+	fc.calls++
+	if fc.down {
+		return capnp.ErrorAnswer(errBackend)
+	}
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	s, _ := capnp.NewRootStruct(seg, capnp.ObjectSize{})
+	return capnp.ImmediateAnswer(s)
+}
+
+func (fc *flakyClient) Close() error {
+	return nil
+}
+
+func call(c capnp.Client) error {
+	_, err := c.Call(&capnp.Call{
+		Ctx:    context.Background(),
+		Method: capnp.Method{InterfaceID: 0xa7b1e7e44ac8ff31},
+	}).Struct()
+	return err
+}
+
+func TestBreaker(t *testing.T) {
+	fc := new(flakyClient)
+	clk := clock.NewFake(time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var changes []string
+	b := breaker.New(fc,
+		breaker.Threshold(3),
+		breaker.Cooldown(time.Minute),
+		breaker.Clock(clk),
+		breaker.OnStateChange(func(from, to breaker.State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		}))
+	defer b.Close()
+
+	fc.down = true
+	for i := 0; i < 3; i++ {
+		if err := call(b); err != errBackend {
+			t.Fatalf("call #%d error = %v; want %v", i+1, err, errBackend)
+		}
+	}
+	if s := b.State(); s != breaker.Open {
+		t.Fatalf("after 3 failures, state = %v; want open", s)
+	}
+	if err := call(b); err != breaker.ErrOpen {
+		t.Errorf("call while open error = %v; want %v", err, breaker.ErrOpen)
+	}
+	if fc.calls != 3 {
+		t.Errorf("backend called %d times; want 3", fc.calls)
+	}
+
+	// A failed probe opens the breaker again.
+	clk.Advance(time.Minute)
+	if s := b.State(); s != breaker.HalfOpen {
+		t.Fatalf("after cooldown, state = %v; want half-open", s)
+	}
+	if err := call(b); err != errBackend {
+		t.Errorf("probe error = %v; want %v", err, errBackend)
+	}
+	if s := b.State(); s != breaker.Open {
+		t.Fatalf("after failed probe, state = %v; want open", s)
+	}
+
+	// A successful probe closes it.
+	clk.Advance(time.Minute)
+	fc.down = false
+	if err := call(b); err != nil {
+		t.Errorf("probe error = %v; want <nil>", err)
+	}
+	if s := b.State(); s != breaker.Closed {
+		t.Fatalf("after successful probe, state = %v; want closed", s)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %q; want %q", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state changes = %q; want %q", changes, want)
+			break
+		}
+	}
+}
+
+func TestBreaker_SuccessResets(t *testing.T) {
+	fc := new(flakyClient)
+	b := breaker.New(fc, breaker.Threshold(2))
+	defer b.Close()
+	for i := 0; i < 3; i++ {
+		fc.down = true
+		call(b)
+		fc.down = false
+		call(b)
+	}
+	if s := b.State(); s != breaker.Closed {
+		t.Errorf("after alternating calls, state = %v; want closed", s)
+	}
+}
+
+func TestBreaker_IsFailure(t *testing.T) {
+	fc := &flakyClient{down: true}
+	b := breaker.New(fc, breaker.Threshold(1), breaker.IsFailure(func(err error) bool {
+		return err != errBackend
+	}))
+	defer b.Close()
+	call(b)
+	if s := b.State(); s != breaker.Closed {
+		t.Errorf("after ignored error, state = %v; want closed", s)
+	}
+}
@@ -0,0 +1,438 @@
+// Package callcache provides a capability that memoizes the results of
+// calls to another capability.
+//
+// A Cache is useful in front of a slow or remote capability whose
+// methods are pure lookups: repeated calls with the same parameters
+// return the cached results, and concurrent identical calls share a
+// single call to the underlying capability.  With the JoinOnly option,
+// a Cache only does the latter, which suits idempotent methods whose
+// results go stale too quickly to cache but which many callers make at
+// once.
+package callcache // import "zombiezen.com/go/capnproto2/callcache"
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
+)
+
+// A Method designates a method whose results are cached, and for how
+// long.  A TTL <= 0 caches results until they are evicted.  TTLs are
+// ignored by caches created with JoinOnly.
+type Method struct {
+	InterfaceID uint64
+	MethodID    uint16
+	TTL         time.Duration
+}
+
+// An Option is an option for creating a cache.
+type Option struct {
+	f func(*Cache)
+}
+
+// JoinOnly makes the cache share results only among calls that are in
+// flight together: identical calls made while a call is outstanding
+// wait for its results, but the results are not kept once the call
+// returns.  Each joined call owns its copy of the results, capabilities
+// included, as if it had made the call alone; the underlying
+// capabilities are released once every copy of them is closed.
+func JoinOnly() Option {
+	return Option{func(cc *Cache) {
+		cc.joinOnly = true
+	}}
+}
+
+// MaxEntries limits the number of results that the cache holds.  Once
+// the limit is reached, the least recently used results are evicted.
+// By default, there is no limit.
+func MaxEntries(n int) Option {
+	return Option{func(cc *Cache) {
+		cc.max = n
+	}}
+}
+
+// Clock sets the clock that the cache's TTLs are measured on.  By
+// default, the cache uses clock.Real.
+func Clock(clk clock.Clock) Option {
+	return Option{func(cc *Cache) {
+		cc.clock = clk
+	}}
+}
+
+// A Cache is a capnp.Client that forwards calls to another client and
+// caches the results of designated methods, keyed by the canonical form
+// of the call's parameters (see capnp.Canonicalize).  Calls whose
+// parameters hold capabilities are never cached.  It is safe to use
+// from multiple goroutines.
+//
+// Every call answered from the cache gets its own copy of the results,
+// which the caller may release as usual.  The capabilities in cached
+// results are shared, though: the cache owns them and closes them once
+// the results are evicted, so callers should not hold onto them for
+// longer than the method's TTL.  Calls pipelined on a cached answer are
+// made on the cached capabilities right away.
+//
+// Identical calls made while a call is in flight wait for its results
+// instead of making another call, so they see its failure, including
+// the cancellation of the first call's context.  Failed calls are not
+// cached.
+type Cache struct {
+	c        capnp.Client
+	methods  map[methodID]time.Duration
+	clock    clock.Clock
+	max      int
+	joinOnly bool
+
+	mu      sync.Mutex
+	entries map[entryKey]*entry
+	lru     *list.List // resolved entries, most recently used first
+	closed  bool
+}
+
+type methodID struct {
+	interfaceID uint64
+	methodID    uint16
+}
+
+type entryKey struct {
+	methodID
+	params string
+}
+
+// An entry is the results of a call, shared by the calls that were
+// answered from it.
+type entry struct {
+	key     entryKey
+	ans     capnp.Answer  // set before started is closed
+	started chan struct{} // closed once ans is set
+	done    chan struct{} // closed once the call returns
+	refs    *capRefs      // nil unless the cache is join-only
+
+	// The fields below are protected by the cache's mu.
+	s       capnp.Struct
+	err     error
+	expires time.Time     // zero if the entry never expires
+	elem    *list.Element // nil unless the entry is in the cache's lru
+	waiters []*answer     // answers waiting for the call to return
+}
+
+// New returns a cache in front of c that caches the results of the
+// given methods.  The cache takes ownership of c.
+func New(c capnp.Client, methods []Method, options ...Option) *Cache {
+	cc := &Cache{
+		c:       c,
+		methods: make(map[methodID]time.Duration, len(methods)),
+		clock:   clock.Real,
+		entries: make(map[entryKey]*entry),
+		lru:     list.New(),
+	}
+	for _, m := range methods {
+		cc.methods[methodID{m.InterfaceID, m.MethodID}] = m.TTL
+	}
+	for _, o := range options {
+		o.f(cc)
+	}
+	return cc
+}
+
+// Call answers call from the cache if its method is cached, making the
+// call on the underlying client only if there are no unexpired results
+// for its parameters.  Calls to other methods are forwarded unchanged.
+func (cc *Cache) Call(call *capnp.Call) capnp.Answer {
+	ttl, ok := cc.methods[methodID{call.Method.InterfaceID, call.Method.MethodID}]
+	if !ok {
+		return cc.c.Call(call)
+	}
+	call, err := call.Copy(nil)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	if msg := call.Params.Segment(); msg != nil && len(msg.Message().CapTable) > 0 {
+		// Capabilities can't be compared, so the call is not cacheable.
+		return cc.c.Call(call)
+	}
+	params, err := capnp.Canonicalize(call.Params)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	key := entryKey{methodID{call.Method.InterfaceID, call.Method.MethodID}, string(params)}
+
+	cc.mu.Lock()
+	if cc.closed {
+		cc.mu.Unlock()
+		return capnp.ErrorAnswer(errClosed)
+	}
+	var evicted []*entry
+	e := cc.entries[key]
+	if e != nil && e.elem != nil && !e.expires.IsZero() && !cc.clock.Now().Before(e.expires) {
+		cc.removeLocked(e)
+		evicted = append(evicted, e)
+		e = nil
+	}
+	if e != nil {
+		ans := &answer{e: e}
+		if e.elem != nil {
+			cc.lru.MoveToFront(e.elem)
+			ans.resolve(e.s, e.err)
+		} else {
+			e.waiters = append(e.waiters, ans)
+		}
+		cc.mu.Unlock()
+		releaseAll(evicted)
+		return ans
+	}
+	e = &entry{
+		key:     key,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if cc.joinOnly {
+		e.refs = &capRefs{e: e}
+	}
+	ans := &answer{e: e}
+	e.waiters = append(e.waiters, ans)
+	cc.entries[key] = e
+	cc.mu.Unlock()
+	releaseAll(evicted)
+
+	e.ans = cc.c.Call(call)
+	close(e.started)
+	go cc.resolve(e, ttl)
+	return ans
+}
+
+// resolve waits for e's call to return and adds its results to the
+// cache.  It is run in its own goroutine.
+func (cc *Cache) resolve(e *entry, ttl time.Duration) {
+	s, err := e.ans.Struct()
+	cc.mu.Lock()
+	e.s, e.err = s, err
+	for _, ans := range e.waiters {
+		ans.resolve(s, err)
+	}
+	e.waiters = nil
+	close(e.done)
+	if cc.joinOnly {
+		if cc.entries[e.key] == e {
+			delete(cc.entries, e.key)
+		}
+		cc.mu.Unlock()
+		if err == nil {
+			e.refs.resolved()
+		}
+		return
+	}
+	var evicted []*entry
+	if err != nil || cc.closed || cc.entries[e.key] != e {
+		if cc.entries[e.key] == e {
+			delete(cc.entries, e.key)
+		}
+		if err == nil {
+			evicted = append(evicted, e)
+		}
+	} else {
+		if ttl > 0 {
+			e.expires = cc.clock.Now().Add(ttl)
+		}
+		e.elem = cc.lru.PushFront(e)
+		for cc.max > 0 && cc.lru.Len() > cc.max {
+			old := cc.lru.Back().Value.(*entry)
+			cc.removeLocked(old)
+			evicted = append(evicted, old)
+		}
+	}
+	cc.mu.Unlock()
+	releaseAll(evicted)
+}
+
+// removeLocked removes a resolved entry from the cache.  The caller
+// must be holding onto cc.mu and must release the entry's results once
+// it is no longer holding onto it.
+func (cc *Cache) removeLocked(e *entry) {
+	delete(cc.entries, e.key)
+	cc.lru.Remove(e.elem)
+	e.elem = nil
+}
+
+// releaseAll releases the results of evicted entries.
+func releaseAll(evicted []*entry) {
+	for _, e := range evicted {
+		capnp.NewPipeline(e.ans).Close()
+	}
+}
+
+// Len returns the number of results that the cache holds.
+func (cc *Cache) Len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.lru.Len()
+}
+
+// Close releases the cached results and closes the underlying client.
+// Subsequent calls fail.
+func (cc *Cache) Close() error {
+	cc.mu.Lock()
+	if cc.closed {
+		cc.mu.Unlock()
+		return errClosed
+	}
+	cc.closed = true
+	var evicted []*entry
+	for e := cc.lru.Front(); e != nil; e = e.Next() {
+		evicted = append(evicted, e.Value.(*entry))
+	}
+	cc.entries = nil
+	cc.lru.Init()
+	cc.mu.Unlock()
+	releaseAll(evicted)
+	return cc.c.Close()
+}
+
+// An answer is the answer to a call made through a cache.  Once the
+// entry is resolved, it holds its own copy of the results.
+type answer struct {
+	e   *entry
+	s   capnp.Struct // set by resolve before e.done is closed
+	err error
+}
+
+// resolve sets the answer's copy of the results.  The caller must be
+// holding onto the cache's mu.
+func (ans *answer) resolve(s capnp.Struct, err error) {
+	if err == nil {
+		ans.s, ans.err = shareResults(s, ans.e.refs)
+	} else {
+		ans.err = err
+	}
+}
+
+func (ans *answer) Struct() (capnp.Struct, error) {
+	<-ans.e.done
+	return ans.s, ans.err
+}
+
+func (ans *answer) PipelineCall(transform []capnp.PipelineOp, call *capnp.Call) capnp.Answer {
+	select {
+	case <-ans.e.done:
+		if ans.err != nil {
+			return capnp.ErrorAnswer(ans.err)
+		}
+		p, err := capnp.TransformPtr(ans.s.ToPtr(), transform)
+		if err != nil {
+			return capnp.ErrorAnswer(err)
+		}
+		c := p.Interface().Client()
+		if c == nil {
+			return capnp.ErrorAnswer(capnp.ErrNullClient)
+		}
+		return c.Call(call)
+	default:
+	}
+	<-ans.e.started
+	return ans.e.ans.PipelineCall(transform, call)
+}
+
+func (ans *answer) PipelineClose(transform []capnp.PipelineOp) error {
+	// The cache owns the capabilities, or, if the cache is join-only,
+	// they are released along with the answer's copy of the results.
+	return nil
+}
+
+// shareResults copies s into a new message whose capabilities can be
+// closed without closing the cached ones.  If refs is not nil, closing
+// the copies drops references counted by refs instead.
+func shareResults(s capnp.Struct, refs *capRefs) (capnp.Struct, error) {
+	if !s.IsValid() {
+		return s, nil
+	}
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	if err := msg.SetRootPtr(s.ToPtr()); err != nil {
+		return capnp.Struct{}, err
+	}
+	for i, c := range msg.CapTable {
+		switch {
+		case c == nil:
+		case refs != nil:
+			refs.add()
+			msg.CapTable[i] = &joinedClient{Client: c, refs: refs}
+		default:
+			msg.CapTable[i] = sharedClient{c}
+		}
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.Struct(), nil
+}
+
+// A sharedClient is a cached capability in a copy of cached results.
+// Closing it does not close the capability.
+type sharedClient struct {
+	capnp.Client
+}
+
+func (sharedClient) Close() error {
+	return nil
+}
+
+// A joinedClient is a capability in a join-only entry's results that
+// one joined call holds.
+type joinedClient struct {
+	capnp.Client
+	refs *capRefs
+	once sync.Once
+}
+
+func (jc *joinedClient) Close() error {
+	jc.once.Do(jc.refs.drop)
+	return nil
+}
+
+// capRefs counts the joinedClients that hold a join-only entry's
+// capabilities.  The entry's results are released once the entry is
+// resolved and all of them are closed.
+type capRefs struct {
+	e *entry
+
+	mu   sync.Mutex
+	n    int
+	done bool
+}
+
+func (r *capRefs) add() {
+	r.mu.Lock()
+	r.n++
+	r.mu.Unlock()
+}
+
+func (r *capRefs) drop() {
+	r.mu.Lock()
+	r.n--
+	release := r.done && r.n == 0
+	r.mu.Unlock()
+	if release {
+		releaseAll([]*entry{r.e})
+	}
+}
+
+// resolved is called once every joined call has its copy of the
+// entry's results.
+func (r *capRefs) resolved() {
+	r.mu.Lock()
+	r.done = true
+	release := r.n == 0
+	r.mu.Unlock()
+	if release {
+		releaseAll([]*entry{r.e})
+	}
+}
+
+var errClosed = errors.New("callcache: closed")
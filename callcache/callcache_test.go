@@ -0,0 +1,248 @@
+package callcache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/callcache"
+	"zombiezen.com/go/capnproto2/clock"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/internal/fulfiller"
+)
+
+// countEcho is an Echo server that counts its calls.
+type countEcho struct {
+	mu     sync.Mutex
+	n      int
+	closed bool
+}
+
+func (e *countEcho) Echo(call air.Echo_echo) error {
+	e.mu.Lock()
+	e.n++
+	e.mu.Unlock()
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	return call.Results.SetOut(in + in)
+}
+
+func (e *countEcho) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *countEcho) calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.n
+}
+
+func echoMethods(ttl time.Duration) []callcache.Method {
+	return []callcache.Method{{InterfaceID: air.Echo_TypeID, MethodID: 0, TTL: ttl}}
+}
+
+func echo(t *testing.T, e air.Echo, in string) {
+	t.Helper()
+	res, err := e.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn(in)
+	}).Struct()
+	if err != nil {
+		t.Fatalf("echo(%q): %v", in, err)
+	}
+	if out, _ := res.Out(); out != in+in {
+		t.Errorf("echo(%q) = %q; want %q", in, out, in+in)
+	}
+}
+
+func TestCache(t *testing.T) {
+	srv := new(countEcho)
+	cc := callcache.New(air.Echo_ServerToClient(srv).Client, echoMethods(0))
+	e := air.Echo{Client: cc}
+	echo(t, e, "foo")
+	echo(t, e, "foo")
+	if n := srv.calls(); n != 1 {
+		t.Errorf("after two identical calls, server called %d times; want 1", n)
+	}
+	echo(t, e, "bar")
+	if n := srv.calls(); n != 2 {
+		t.Errorf("after a different call, server called %d times; want 2", n)
+	}
+	if n := cc.Len(); n != 2 {
+		t.Errorf("Len() = %d; want 2", n)
+	}
+	if err := cc.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !srv.closed {
+		t.Error("Close did not close the underlying client")
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	srv := new(countEcho)
+	cc := callcache.New(air.Echo_ServerToClient(srv).Client, echoMethods(time.Minute), callcache.Clock(clk))
+	defer cc.Close()
+	e := air.Echo{Client: cc}
+	echo(t, e, "foo")
+	clk.Advance(59 * time.Second)
+	echo(t, e, "foo")
+	if n := srv.calls(); n != 1 {
+		t.Errorf("before TTL, server called %d times; want 1", n)
+	}
+	clk.Advance(time.Second)
+	echo(t, e, "foo")
+	if n := srv.calls(); n != 2 {
+		t.Errorf("after TTL, server called %d times; want 2", n)
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	srv := new(countEcho)
+	cc := callcache.New(air.Echo_ServerToClient(srv).Client, echoMethods(0), callcache.MaxEntries(1))
+	defer cc.Close()
+	e := air.Echo{Client: cc}
+	echo(t, e, "foo")
+	echo(t, e, "bar")
+	if n := cc.Len(); n != 1 {
+		t.Errorf("Len() = %d; want 1", n)
+	}
+	echo(t, e, "foo")
+	if n := srv.calls(); n != 3 {
+		t.Errorf("after evicted call, server called %d times; want 3", n)
+	}
+}
+
+// lookupMethod is a method of lookupClient's made-up interface.
+var lookupMethod = capnp.Method{InterfaceID: 0xd6bd2a8bd0da3f8b, MethodID: 0}
+
+// lookupClient returns results that hold an Echo capability.
+type lookupClient struct {
+	echo capnp.Client
+	n    int
+}
+
+func (lc *lookupClient) Call(call *capnp.Call) capnp.Answer {
+	lc.n++
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	s, err := capnp.NewRootStruct(seg, capnp.ObjectSize{PointerCount: 1})
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	if err := s.SetPtr(0, capnp.NewInterface(seg, msg.AddCap(lc.echo)).ToPtr()); err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	return capnp.ImmediateAnswer(s)
+}
+
+func (lc *lookupClient) Close() error {
+	return nil
+}
+
+func TestCache_Pipeline(t *testing.T) {
+	srv := new(countEcho)
+	lc := &lookupClient{echo: air.Echo_ServerToClient(srv).Client}
+	cc := callcache.New(lc, []callcache.Method{{InterfaceID: lookupMethod.InterfaceID, MethodID: lookupMethod.MethodID}})
+	lookup := func() *capnp.Pipeline {
+		return capnp.NewPipeline(cc.Call(&capnp.Call{
+			Ctx:    context.Background(),
+			Method: lookupMethod,
+		}))
+	}
+	for i := 0; i < 2; i++ {
+		p := lookup()
+		echo(t, air.Echo{Client: p.GetPipeline(0).Client()}, "foo")
+		if err := p.Close(); err != nil {
+			t.Errorf("Close #%d: %v", i+1, err)
+		}
+	}
+	if lc.n != 1 {
+		t.Errorf("lookup called %d times; want 1", lc.n)
+	}
+	if srv.closed {
+		t.Error("closing cached results closed the cached capability")
+	}
+	if err := cc.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !srv.closed {
+		t.Error("closing the cache did not close the cached capability")
+	}
+}
+
+func TestCache_JoinOnly(t *testing.T) {
+	srv := new(countEcho)
+	pc := new(pendingClient)
+	cc := callcache.New(pc, []callcache.Method{{InterfaceID: lookupMethod.InterfaceID, MethodID: lookupMethod.MethodID}}, callcache.JoinOnly())
+	defer cc.Close()
+	lookup := func() *capnp.Pipeline {
+		return capnp.NewPipeline(cc.Call(&capnp.Call{
+			Ctx:    context.Background(),
+			Method: lookupMethod,
+		}))
+	}
+	p1, p2 := lookup(), lookup()
+	if pc.n != 1 {
+		t.Errorf("after concurrent calls, lookup called %d times; want 1", pc.n)
+	}
+	if err := pc.fulfill(air.Echo_ServerToClient(srv).Client); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range []*capnp.Pipeline{p1, p2} {
+		echo(t, air.Echo{Client: p.GetPipeline(0).Client()}, "foo")
+		if err := p.Close(); err != nil {
+			t.Errorf("Close #%d: %v", i+1, err)
+		}
+		if closed := i == 1; srv.closed != closed {
+			t.Errorf("after closing %d of 2 joined results, capability closed = %t; want %t", i+1, srv.closed, closed)
+		}
+	}
+	lookup()
+	if pc.n != 2 {
+		t.Errorf("after call returned, lookup called %d times; want 2", pc.n)
+	}
+}
+
+// pendingClient returns results that hold an Echo capability once they
+// are fulfilled.
+type pendingClient struct {
+	f *fulfiller.Fulfiller
+	n int
+}
+
+func (pc *pendingClient) Call(call *capnp.Call) capnp.Answer {
+	pc.n++
+	pc.f = new(fulfiller.Fulfiller)
+	return pc.f
+}
+
+// fulfill returns echo from the last call.
+func (pc *pendingClient) fulfill(echo capnp.Client) error {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return err
+	}
+	s, err := capnp.NewRootStruct(seg, capnp.ObjectSize{PointerCount: 1})
+	if err != nil {
+		return err
+	}
+	if err := s.SetPtr(0, capnp.NewInterface(seg, msg.AddCap(echo)).ToPtr()); err != nil {
+		return err
+	}
+	pc.f.Fulfill(s)
+	return nil
+}
+
+func (pc *pendingClient) Close() error {
+	return nil
+}
@@ -1,8 +1,11 @@
 package capnp
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 )
 
 // Canonicalize encodes a struct into its canonical form: a single-
@@ -97,7 +100,7 @@ func canonicalList(dst *Segment, l List) (List, error) {
 	if !l.IsValid() {
 		return List{}, nil
 	}
-	if l.size.PointerCount == 0 {
+	if l.size.PointerCount == 0 && l.flags&isCompositeList == 0 {
 		// Data only, just copy over.
 		sz := l.allocSize()
 		_, newAddr, err := alloc(dst, sz)
@@ -138,6 +141,21 @@ func canonicalList(dst *Segment, l List) (List, error) {
 	}
 
 	// Struct/composite list
+	cl, err := NewCompositeList(dst, canonicalElemSize(l), l.length)
+	if err != nil {
+		return List{}, err
+	}
+	for i := 0; i < cl.Len(); i++ {
+		if err := fillCanonicalStruct(cl.Struct(i), l.Struct(i)); err != nil {
+			return List{}, fmt.Errorf("element %d: %v", i, err)
+		}
+	}
+	return cl, nil
+}
+
+// canonicalElemSize returns the smallest element size that can hold
+// every struct in a composite list.
+func canonicalElemSize(l List) ObjectSize {
 	var elemSize ObjectSize
 	for i := 0; i < l.Len(); i++ {
 		sz := canonicalStructSize(l.Struct(i))
@@ -148,14 +166,330 @@ func canonicalList(dst *Segment, l List) (List, error) {
 			elemSize.PointerCount = sz.PointerCount
 		}
 	}
-	cl, err := NewCompositeList(dst, elemSize, l.length)
+	return elemSize
+}
+
+// WriteCanonical writes the canonical form of s to w.  The bytes
+// written are the same as those returned by Canonicalize, but the
+// canonical copy is never built in memory: s is traversed once to lay
+// out its objects and again to write them, using memory proportional
+// to the number of pointers rather than the size of the message.
+// Since s is traversed twice, its pointers count twice against the
+// message's read limit.
+func WriteCanonical(w io.Writer, s Struct) error {
+	cw := &canonicalWriter{w: w, buf: make([]byte, 0, 4096)}
+	if !s.IsValid() {
+		cw.writeWord(0)
+		return cw.flush()
+	}
+	total, err := cw.layoutStruct(s)
 	if err != nil {
-		return List{}, err
+		return fmt.Errorf("canonicalize: %v", err)
 	}
-	for i := 0; i < cl.Len(); i++ {
-		if err := fillCanonicalStruct(cl.Struct(i), l.Struct(i)); err != nil {
-			return List{}, fmt.Errorf("element %d: %v", i, err)
+	if (total+1)*uint64(wordSize) > uint64(maxSize) {
+		return fmt.Errorf("canonicalize: %v", errOverflow)
+	}
+	cw.writeWord(cw.rawPtr(s.ToPtr(), 0, 1))
+	if err := cw.emitStruct(s, 1); err != nil {
+		return fmt.Errorf("canonicalize: %v", err)
+	}
+	return cw.flush()
+}
+
+// CanonicalHash writes the canonical form of s to h and returns the
+// resulting digest.  The digest is suitable for signing.
+func CanonicalHash(h hash.Hash, s Struct) ([]byte, error) {
+	if err := WriteCanonical(h, s); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// canonicalWriter streams a canonical message.  Addresses and sizes
+// are in words.
+type canonicalWriter struct {
+	w   io.Writer
+	buf []byte
+	err error
+
+	// sizes holds the size of each object pointed to, in the order
+	// that the pointers are written.  The layout pass fills it in and
+	// the emit pass consumes it.
+	sizes []uint32
+	next  int
+
+	// stack holds the objects that have been pointed to but not yet
+	// written.
+	stack []canonicalObject
+}
+
+type canonicalObject struct {
+	p   Ptr
+	pos uint64
+}
+
+func (cw *canonicalWriter) layoutPtr(p Ptr) (uint64, error) {
+	if !p.IsValid() {
+		return 0, nil
+	}
+	switch p.flags.ptrType() {
+	case structPtrType:
+		return cw.layoutStruct(p.Struct())
+	case listPtrType:
+		return cw.layoutList(p.List())
+	case interfacePtrType:
+		return 0, errors.New("cannot canonicalize interface")
+	default:
+		panic("unreachable")
+	}
+}
+
+func (cw *canonicalWriter) layoutStruct(s Struct) (uint64, error) {
+	sz := canonicalStructSize(s)
+	if sz.isZero() {
+		return 0, nil
+	}
+	base := cw.reserve(int(sz.PointerCount))
+	total := uint64(sz.totalSize() / wordSize)
+	for i := uint16(0); i < sz.PointerCount; i++ {
+		n, err := cw.layoutField(s, i, base+int(i))
+		if err != nil {
+			return 0, fmt.Errorf("pointer %d: %v", i, err)
 		}
+		total += n
 	}
-	return cl, nil
+	return total, nil
+}
+
+func (cw *canonicalWriter) layoutList(l List) (uint64, error) {
+	if l.size.PointerCount == 0 && l.flags&isCompositeList == 0 {
+		return uint64(l.allocSize().padToWord() / wordSize), nil
+	}
+	if l.flags&isCompositeList == 0 {
+		base := cw.reserve(l.Len())
+		total := uint64(l.Len())
+		for i := 0; i < l.Len(); i++ {
+			p, err := PointerList{l}.PtrAt(i)
+			if err != nil {
+				return 0, fmt.Errorf("element %d: %v", i, err)
+			}
+			n, err := cw.setSize(base+i, p)
+			if err != nil {
+				return 0, fmt.Errorf("element %d: %v", i, err)
+			}
+			total += n
+		}
+		return total, nil
+	}
+
+	elemSize := canonicalElemSize(l)
+	if _, ok := elemSize.totalSize().times(l.length); !ok {
+		return 0, errOverflow
+	}
+	pc := int(elemSize.PointerCount)
+	base := cw.reserve(l.Len() * pc)
+	total := 1 + uint64(l.Len())*uint64(elemSize.totalSize()/wordSize)
+	for i := 0; i < l.Len(); i++ {
+		s := l.Struct(i)
+		for j := 0; j < pc; j++ {
+			n, err := cw.layoutField(s, uint16(j), base+i*pc+j)
+			if err != nil {
+				return 0, fmt.Errorf("element %d: pointer %d: %v", i, j, err)
+			}
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// layoutField lays out the object pointed to by the i'th pointer of s
+// and records its size in slot.
+func (cw *canonicalWriter) layoutField(s Struct, i uint16, slot int) (uint64, error) {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return 0, err
+	}
+	return cw.setSize(slot, p)
+}
+
+func (cw *canonicalWriter) setSize(slot int, p Ptr) (uint64, error) {
+	n, err := cw.layoutPtr(p)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(maxSize/wordSize) {
+		return 0, errOverflow
+	}
+	cw.sizes[slot] = uint32(n)
+	return n, nil
+}
+
+// reserve adds n slots to cw.sizes and returns the index of the first.
+func (cw *canonicalWriter) reserve(n int) int {
+	base := len(cw.sizes)
+	for i := 0; i < n; i++ {
+		cw.sizes = append(cw.sizes, 0)
+	}
+	return base
+}
+
+func (cw *canonicalWriter) emitStruct(s Struct, pos uint64) error {
+	sz := canonicalStructSize(s)
+	if sz.isZero() {
+		return nil
+	}
+	base := len(cw.stack)
+	cw.writeStruct(s, sz)
+	if _, err := cw.pushFields(s, sz, pos, pos+uint64(sz.totalSize()/wordSize)); err != nil {
+		return err
+	}
+	return cw.emitStack(base)
+}
+
+func (cw *canonicalWriter) emitList(l List, pos uint64) error {
+	if l.size.PointerCount == 0 && l.flags&isCompositeList == 0 {
+		sz := l.allocSize()
+		end, _ := l.off.addSize(sz) // list was already validated
+		cw.write(l.seg.data[l.off:end])
+		var pad [wordSize]byte
+		cw.write(pad[:sz.padToWord()-sz])
+		return nil
+	}
+	base := len(cw.stack)
+	if l.flags&isCompositeList == 0 {
+		child := pos + uint64(l.Len())
+		for i := 0; i < l.Len(); i++ {
+			p, err := PointerList{l}.PtrAt(i)
+			if err != nil {
+				return fmt.Errorf("element %d: %v", i, err)
+			}
+			child = cw.push(p, pos+uint64(i), child)
+		}
+		return cw.emitStack(base)
+	}
+
+	elemSize := canonicalElemSize(l)
+	cw.writeWord(rawStructPointer(pointerOffset(l.length), elemSize))
+	elemWords := uint64(elemSize.totalSize() / wordSize)
+	child := pos + 1 + uint64(l.Len())*elemWords
+	for i := 0; i < l.Len(); i++ {
+		s := l.Struct(i)
+		cw.writeStruct(s, elemSize)
+		var err error
+		child, err = cw.pushFields(s, elemSize, pos+1+uint64(i)*elemWords, child)
+		if err != nil {
+			return fmt.Errorf("element %d: %v", i, err)
+		}
+	}
+	return cw.emitStack(base)
+}
+
+// writeStruct writes the data section of s, truncated or padded to sz.
+// The pointer section is written by pushFields.
+func (cw *canonicalWriter) writeStruct(s Struct, sz ObjectSize) {
+	for off := DataOffset(0); Size(off) < sz.DataSize; off += DataOffset(wordSize) {
+		cw.writeWord(rawPointer(s.Uint64(off)))
+	}
+}
+
+// pushFields writes the pointer section of s, which starts after the
+// data section of the struct at pos, and pushes the objects pointed to,
+// starting at child.  It returns the position after the last object.
+func (cw *canonicalWriter) pushFields(s Struct, sz ObjectSize, pos, child uint64) (uint64, error) {
+	ptrs := pos + uint64(sz.DataSize/wordSize)
+	for i := uint16(0); i < sz.PointerCount; i++ {
+		p, err := s.Ptr(i)
+		if err != nil {
+			return 0, fmt.Errorf("pointer %d: %v", i, err)
+		}
+		child = cw.push(p, ptrs+uint64(i), child)
+	}
+	return child, nil
+}
+
+// push writes a pointer at paddr to p, which will be written at pos.
+// It returns the position after p's object.
+func (cw *canonicalWriter) push(p Ptr, paddr, pos uint64) uint64 {
+	cw.writeWord(cw.rawPtr(p, paddr, pos))
+	n := uint64(cw.sizes[cw.next])
+	cw.next++
+	if n > 0 {
+		cw.stack = append(cw.stack, canonicalObject{p, pos})
+	}
+	return pos + n
+}
+
+// emitStack writes the objects pushed since base.
+func (cw *canonicalWriter) emitStack(base int) error {
+	for k := base; k < len(cw.stack) && cw.err == nil; k++ {
+		obj := cw.stack[k]
+		var err error
+		switch obj.p.flags.ptrType() {
+		case structPtrType:
+			err = cw.emitStruct(obj.p.Struct(), obj.pos)
+		case listPtrType:
+			err = cw.emitList(obj.p.List(), obj.pos)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	cw.stack = cw.stack[:base]
+	return nil
+}
+
+// rawPtr returns the canonical encoding of a pointer at paddr to p's
+// object at pos.
+func (cw *canonicalWriter) rawPtr(p Ptr, paddr, pos uint64) rawPointer {
+	if !p.IsValid() {
+		return 0
+	}
+	off := pointerOffset(int64(pos) - int64(paddr) - 1)
+	switch p.flags.ptrType() {
+	case structPtrType:
+		sz := canonicalStructSize(p.Struct())
+		if sz.isZero() {
+			return rawStructPointer(-1, ObjectSize{})
+		}
+		return rawStructPointer(off, sz)
+	case listPtrType:
+		l := p.List()
+		switch {
+		case l.size.PointerCount == 0 && l.flags&isCompositeList == 0:
+			return l.raw().withOffset(off)
+		case l.flags&isCompositeList == 0:
+			return rawListPointer(off, pointerList, l.length)
+		default:
+			return rawListPointer(off, compositeList, l.length*canonicalElemSize(l).totalWordCount())
+		}
+	default:
+		// Interfaces are rejected during layout.
+		panic("unreachable")
+	}
+}
+
+func (cw *canonicalWriter) writeWord(w rawPointer) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(w))
+	cw.write(b[:])
+}
+
+func (cw *canonicalWriter) write(b []byte) {
+	for len(b) > 0 && cw.err == nil {
+		if len(cw.buf) == cap(cw.buf) {
+			cw.err = cw.flush()
+		}
+		n := copy(cw.buf[len(cw.buf):cap(cw.buf)], b)
+		cw.buf = cw.buf[:len(cw.buf)+n]
+		b = b[n:]
+	}
+}
+
+func (cw *canonicalWriter) flush() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	_, err := cw.w.Write(cw.buf)
+	cw.buf = cw.buf[:0]
+	return err
 }
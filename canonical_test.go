@@ -2,6 +2,7 @@ package capnp
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
 	"testing"
 )
@@ -190,3 +191,145 @@ func TestCanonicalize(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalizeDataStructList(t *testing.T) {
+	_, seg, _ := NewMessage(SingleSegment(nil))
+	s, _ := NewStruct(seg, ObjectSize{PointerCount: 1})
+	l, _ := NewCompositeList(seg, ObjectSize{DataSize: 16}, 2)
+	s.SetPtr(0, l.ToPtr())
+	l.Struct(0).SetUint64(0, 0xaa)
+	l.Struct(1).SetUint64(0, 0xbb)
+	b, err := Canonicalize(s)
+	if err != nil {
+		t.Fatal("Canonicalize(data struct list):", err)
+	}
+	want := ([]byte{
+		0, 0, 0, 0, 0, 0, 1, 0,
+		0x01, 0, 0, 0, 0x17, 0, 0, 0,
+		0x08, 0, 0, 0, 1, 0, 0, 0,
+		0xaa, 0, 0, 0, 0, 0, 0, 0,
+		0xbb, 0, 0, 0, 0, 0, 0, 0,
+	})
+	if !bytes.Equal(b, want) {
+		t.Errorf("Canonicalize(data struct list) =\n%s\n; want\n%s", hex.Dump(b), hex.Dump(want))
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(*Segment) Struct
+	}{
+		{"null", func(*Segment) Struct {
+			return Struct{}
+		}},
+		{"empty struct", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{})
+			return s
+		}},
+		{"zero data, zero pointer struct", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+			return s
+		}},
+		{"truncated data", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{DataSize: 24, PointerCount: 2})
+			s.SetUint32(4, 0xfeed)
+			return s
+		}},
+		{"nested", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 4})
+			s.SetUint64(0, 42)
+			sub, _ := NewStruct(seg, ObjectSize{PointerCount: 2})
+			sub.SetText(1, "inner")
+			empty, _ := NewStruct(seg, ObjectSize{})
+			sub.SetPtr(0, empty.ToPtr())
+			s.SetPtr(1, sub.ToPtr())
+			s.SetText(2, "hello, world")
+			s.SetData(3, []byte{})
+			return s
+		}},
+		{"primitive lists", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{PointerCount: 4})
+			bl, _ := NewBitList(seg, 11)
+			bl.Set(0, true)
+			bl.Set(10, true)
+			s.SetPtr(0, bl.ToPtr())
+			u16, _ := NewUInt16List(seg, 3)
+			u16.Set(2, 0xabcd)
+			s.SetPtr(1, u16.ToPtr())
+			s.SetPtr(2, NewVoidList(seg, 5).ToPtr())
+			u64, _ := NewUInt64List(seg, 2)
+			u64.Set(1, 7)
+			s.SetPtr(3, u64.ToPtr())
+			return s
+		}},
+		{"pointer list", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{PointerCount: 1})
+			tl, _ := NewTextList(seg, 3)
+			tl.Set(0, "a")
+			tl.Set(2, "ccc")
+			s.SetPtr(0, tl.ToPtr())
+			return s
+		}},
+		{"struct list", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{PointerCount: 2})
+			l, _ := NewCompositeList(seg, ObjectSize{DataSize: 16, PointerCount: 2}, 3)
+			s.SetPtr(0, l.ToPtr())
+			l.Struct(0).SetUint64(0, 0xdeadbeef)
+			l.Struct(0).SetText(1, "first")
+			inner, _ := NewCompositeList(seg, ObjectSize{DataSize: 8}, 2)
+			inner.Struct(1).SetUint8(0, 9)
+			l.Struct(2).SetPtr(0, inner.ToPtr())
+			l.Struct(2).SetText(1, "last")
+			s.SetText(1, "after")
+			return s
+		}},
+		{"large data", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{PointerCount: 2})
+			s.SetData(0, bytes.Repeat([]byte("0123456789"), 1001))
+			s.SetText(1, "after")
+			return s
+		}},
+		{"zero-length struct list", func(seg *Segment) Struct {
+			s, _ := NewStruct(seg, ObjectSize{PointerCount: 2})
+			l, _ := NewCompositeList(seg, ObjectSize{DataSize: 16, PointerCount: 2}, 0)
+			s.SetPtr(0, l.ToPtr())
+			s.SetText(1, "x")
+			return s
+		}},
+	}
+	for _, test := range tests {
+		_, seg, _ := NewMessage(SingleSegment(nil))
+		s := test.build(seg)
+		want, err := Canonicalize(s)
+		if err != nil {
+			t.Errorf("%s: Canonicalize: %v", test.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := WriteCanonical(&buf, s); err != nil {
+			t.Errorf("%s: WriteCanonical: %v", test.name, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: WriteCanonical wrote\n%s\n; want\n%s", test.name, hex.Dump(buf.Bytes()), hex.Dump(want))
+		}
+		sum, err := CanonicalHash(sha256.New(), s)
+		if err != nil {
+			t.Errorf("%s: CanonicalHash: %v", test.name, err)
+			continue
+		}
+		if wantSum := sha256.Sum256(want); !bytes.Equal(sum, wantSum[:]) {
+			t.Errorf("%s: CanonicalHash = %x; want %x", test.name, sum, wantSum)
+		}
+	}
+}
+
+func TestWriteCanonicalInterface(t *testing.T) {
+	_, seg, _ := NewMessage(SingleSegment(nil))
+	s, _ := NewStruct(seg, ObjectSize{PointerCount: 1})
+	s.SetPtr(0, NewInterface(seg, 0).ToPtr())
+	if err := WriteCanonical(new(bytes.Buffer), s); err == nil {
+		t.Error("WriteCanonical(struct with interface) = <nil>; want error")
+	}
+}
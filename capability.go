@@ -3,6 +3,8 @@ package capnp
 import (
 	"errors"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -236,6 +238,25 @@ func SetOptionValue(key, value interface{}) CallOption {
 	}}
 }
 
+// tailCallKey is the option key set by TailCallOf.
+type tailCallKey struct{}
+
+// TailCallOf returns a call option that marks a call as the tail of
+// the call that was made with opts: the new call's answer will be used
+// as the answer of the original call.  A connection that delivered the
+// original call can use this to send the results of the tail call
+// directly to the original caller.
+func TailCallOf(opts CallOptions) CallOption {
+	return SetOptionValue(tailCallKey{}, opts)
+}
+
+// TailOf returns the options of the call that a call made with opts is
+// the tail of.  ok is false if opts were not marked with TailCallOf.
+func TailOf(opts CallOptions) (parent CallOptions, ok bool) {
+	parent, ok = opts.Value(tailCallKey{}).(CallOptions)
+	return parent, ok
+}
+
 // An Answer is the deferred result of a client call, which is usually wrapped by a Pipeline.
 type Answer interface {
 	// Struct waits until the call is finished and returns the result.
@@ -253,6 +274,7 @@ type Pipeline struct {
 	answer Answer
 	parent *Pipeline
 	op     PipelineOp
+	closed int32 // set by Close on the root pipeline
 }
 
 // NewPipeline returns a new pipeline based on an answer.
@@ -293,6 +315,34 @@ func (p *Pipeline) Struct() (Struct, error) {
 	return ptr.Struct(), nil
 }
 
+// Close waits until the answer is resolved and then releases the
+// capabilities in the results of the call that p is derived from.  If
+// the answer owns the results' message, Close releases that too (see
+// Message.ReleaseAnswer).  The results and clients obtained from them
+// must not be used afterward.  Generated promise types embed *Pipeline,
+// so every generated call returns a promise that can be closed this
+// way.  Closing p or any pipeline derived from the same root more than
+// once has no further effect.  Close does not cancel the call: cancel
+// the call's context for that.
+func (p *Pipeline) Close() error {
+	root := p
+	for root.parent != nil {
+		root = root.parent
+	}
+	if !atomic.CompareAndSwapInt32(&root.closed, 0, 1) {
+		return nil
+	}
+	s, err := p.answer.Struct()
+	if err != nil || s.Segment() == nil {
+		// The call failed, so there are no results to release.
+		return nil
+	}
+	msg := s.Segment().Message()
+	err = msg.ReleaseCaps()
+	msg.ReleaseAnswer()
+	return err
+}
+
 // Client returns the client version of p.
 func (p *Pipeline) Client() *PipelineClient {
 	return (*PipelineClient)(p)
@@ -517,6 +567,50 @@ func IsErrorClient(c Client) bool {
 	return ok
 }
 
+// A SharedClient is a Client whose capability is shared by several
+// independently owned references, like the clients that package rpc
+// hands out.  The capability is closed once every reference has been
+// closed or stolen.  rpc.Share makes a SharedClient from any Client.
+type SharedClient interface {
+	Client
+
+	// Snapshot returns a new reference to the same capability.  The
+	// caller owns the new reference and must close it independently
+	// of the receiver.
+	Snapshot() Client
+
+	// Steal moves the receiver's reference to a new reference without
+	// changing the count.  Afterward, calls on the receiver fail and
+	// closing it does not affect the capability.
+	Steal() Client
+}
+
+// ErrNotShared is the error that Snapshot returns for a client that is
+// not a SharedClient.
+var ErrNotShared = errors.New("capnp: snapshot of client that is not shared")
+
+// Snapshot returns a new reference to c that the caller owns and must
+// close independently of c.  If c is not a SharedClient, there is no
+// count to add the reference to, so Snapshot returns a client that
+// fails with ErrNotShared.
+func Snapshot(c Client) Client {
+	if sc, ok := c.(SharedClient); ok {
+		return sc.Snapshot()
+	}
+	return ErrorClient(ErrNotShared)
+}
+
+// Steal moves the caller's reference to c to a new client, which the
+// caller hands to its new owner.  c must not be used afterward.  If c is
+// a SharedClient, closing c afterward is an error instead of a second
+// release.  Otherwise, c itself is returned.
+func Steal(c Client) Client {
+	if sc, ok := c.(SharedClient); ok {
+		return sc.Steal()
+	}
+	return c
+}
+
 // MethodError is an error on an associated method.
 type MethodError struct {
 	Method *Method
@@ -539,3 +633,149 @@ func IsUnimplemented(e error) bool {
 	}
 	return e == ErrUnimplemented
 }
+
+// RestrictClient returns a client that forwards calls to c only for the
+// methods in allowed, which are matched by interface and method ID.
+// Calls to other methods fail with ErrPermissionDenied without reaching
+// c.  The returned client takes ownership of c.
+//
+// The restricted client may be exported over an rpc.Conn like any other
+// client, in which case the remote caller receives an exception whose
+// reason reports the permission error.
+func RestrictClient(c Client, allowed []Method) Client {
+	return FilterClient(c, NewMethodSet(allowed).Has, ErrPermissionDenied)
+}
+
+// DenyMethods returns a client that forwards calls to c except for the
+// methods in denied, which fail with ErrPermissionDenied.  It is the
+// inverse of RestrictClient.  The returned client takes ownership of c.
+func DenyMethods(c Client, denied []Method) Client {
+	set := NewMethodSet(denied)
+	return FilterClient(c, func(m *Method) bool {
+		return !set.Has(m)
+	}, ErrPermissionDenied)
+}
+
+// FilterClient returns a client that forwards a call to c if allow
+// returns true for its method.  Other calls fail with a *MethodError
+// wrapping err without reaching c.  allow is called from the goroutine
+// making the call and may be called concurrently.  The returned client
+// takes ownership of c.
+func FilterClient(c Client, allow func(m *Method) bool, err error) Client {
+	return &filteredClient{c: c, allow: allow, err: err}
+}
+
+// A MethodSet is a set of methods, matched by interface and method ID.
+// It is safe to use from multiple goroutines.
+type MethodSet struct {
+	m map[methodKey]struct{}
+}
+
+type methodKey struct {
+	interfaceID uint64
+	methodID    uint16
+}
+
+// NewMethodSet returns a set of the given methods.
+func NewMethodSet(methods []Method) MethodSet {
+	set := make(map[methodKey]struct{}, len(methods))
+	for _, m := range methods {
+		set[methodKey{m.InterfaceID, m.MethodID}] = struct{}{}
+	}
+	return MethodSet{set}
+}
+
+// Has reports whether the set contains a method with the same interface
+// and method ID as m.
+func (s MethodSet) Has(m *Method) bool {
+	_, ok := s.m[methodKey{m.InterfaceID, m.MethodID}]
+	return ok
+}
+
+type filteredClient struct {
+	c     Client
+	allow func(m *Method) bool
+	err   error
+}
+
+func (fc *filteredClient) Call(call *Call) Answer {
+	if !fc.allow(&call.Method) {
+		return ErrorAnswer(&MethodError{Method: &call.Method, Err: fc.err})
+	}
+	return fc.c.Call(call)
+}
+
+func (fc *filteredClient) Close() error {
+	return fc.c.Close()
+}
+
+// ErrPermissionDenied is the error returned when a method is called on
+// a client from RestrictClient or DenyMethods that does not permit it.
+var ErrPermissionDenied = errors.New("capnp: permission denied")
+
+// IsPermissionDenied reports whether e indicates a call rejected by
+// RestrictClient or DenyMethods.  Errors received from a remote vat are
+// exceptions and do not satisfy IsPermissionDenied.
+func IsPermissionDenied(e error) bool {
+	if me, ok := e.(*MethodError); ok {
+		e = me.Err
+	}
+	return e == ErrPermissionDenied
+}
+
+// ErrTimeout is the error returned when a call runs longer than its
+// server allows, as set by a server.Method's Timeout.  An rpc.Conn
+// returns it to the remote caller as an overloaded exception.
+var ErrTimeout = errors.New("capnp: call timed out")
+
+// IsTimeout reports whether e indicates a call that timed out on its
+// server.  Errors received from a remote vat are exceptions and do not
+// satisfy IsTimeout.
+func IsTimeout(e error) bool {
+	if me, ok := e.(*MethodError); ok {
+		e = me.Err
+	}
+	return e == ErrTimeout
+}
+
+// WithTimeout returns a client that makes calls on c with a timeout of
+// d, so that callers get a sensible default without setting a deadline
+// at every call site.  A call whose context already has a deadline is
+// forwarded unchanged, even if its deadline is later.  The timeout's
+// context is released once the call returns.  The returned client takes
+// ownership of c.
+func WithTimeout(c Client, d time.Duration) Client {
+	return &timeoutClient{c: c, d: d}
+}
+
+type timeoutClient struct {
+	c Client
+	d time.Duration
+}
+
+func (tc *timeoutClient) Call(call *Call) Answer {
+	ctx := call.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return tc.c.Call(call)
+	}
+	ctx, cancel := context.WithTimeout(ctx, tc.d)
+	tcall := *call
+	tcall.Ctx = ctx
+	ans := tc.c.Call(&tcall)
+	if IsFixedAnswer(ans) {
+		cancel()
+		return ans
+	}
+	go func() {
+		ans.Struct()
+		cancel()
+	}()
+	return ans
+}
+
+func (tc *timeoutClient) Close() error {
+	return tc.c.Close()
+}
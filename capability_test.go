@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"testing"
+	"time"
+
+	"golang.org/x/net/context"
 )
 
 func TestToInterface(t *testing.T) {
@@ -255,6 +258,178 @@ func TestIsUnimplemented(t *testing.T) {
 	}
 }
 
+func TestRestrictClient(t *testing.T) {
+	allowed := Method{InterfaceID: 0x1234, MethodID: 1}
+	other := Method{InterfaceID: 0x1234, MethodID: 2}
+	otherIface := Method{InterfaceID: 0x5678, MethodID: 1}
+	tests := []struct {
+		name   string
+		c      Client
+		method Method
+		ok     bool
+	}{
+		{"RestrictClient allowed", RestrictClient(new(closeCounter), []Method{allowed}), allowed, true},
+		{"RestrictClient other method", RestrictClient(new(closeCounter), []Method{allowed}), other, false},
+		{"RestrictClient other interface", RestrictClient(new(closeCounter), []Method{allowed}), otherIface, false},
+		{"RestrictClient empty", RestrictClient(new(closeCounter), nil), allowed, false},
+		{"DenyMethods denied", DenyMethods(new(closeCounter), []Method{allowed}), allowed, false},
+		{"DenyMethods other", DenyMethods(new(closeCounter), []Method{allowed}), other, true},
+	}
+	for _, test := range tests {
+		m := test.method
+		_, err := test.c.Call(&Call{Method: m}).Struct()
+		if test.ok {
+			// closeCounter rejects every call as unimplemented.
+			if !IsUnimplemented(err) {
+				t.Errorf("%s: error = %v; want call forwarded", test.name, err)
+			}
+		} else if !IsPermissionDenied(err) {
+			t.Errorf("%s: error = %v; want permission denied", test.name, err)
+		}
+	}
+
+	cc := new(closeCounter)
+	if err := RestrictClient(cc, nil).Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if cc.n != 1 {
+		t.Errorf("underlying client closed %d times; want 1", cc.n)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	hc := new(hangClient)
+	c := WithTimeout(hc, time.Millisecond)
+	_, err := c.Call(&Call{Ctx: context.Background()}).Struct()
+	if err != context.DeadlineExceeded {
+		t.Errorf("call without deadline: error = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	want := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), want)
+	cancel()
+	c.Call(&Call{Ctx: ctx}).Struct()
+	if !hc.deadline.Equal(want) {
+		t.Errorf("call with deadline: deadline = %v; want %v", hc.deadline, want)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !hc.closed {
+		t.Error("underlying client not closed")
+	}
+}
+
+func TestPipelineClose(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(closeCounter)
+	if err := root.SetPtr(0, NewInterface(seg, msg.AddCap(c)).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	released := 0
+	msg.AddReleaser(func() { released++ })
+	msg.SetAnswerOwned()
+	p := NewPipeline(ImmediateAnswer(root))
+	if err := p.GetPipeline(0).Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if c.n != 1 {
+		t.Errorf("client closed %d times; want 1", c.n)
+	}
+	if released != 1 {
+		t.Errorf("message released %d times; want 1", released)
+	}
+	if err := p.Close(); err != nil {
+		t.Error("second Close:", err)
+	}
+	if c.n != 1 {
+		t.Errorf("after second Close, client closed %d times; want 1", c.n)
+	}
+	if released != 1 {
+		t.Errorf("after second Close, message released %d times; want 1", released)
+	}
+	if ptr, _ := root.Ptr(0); ptr.Interface().Client() != nil {
+		t.Error("capability still present after Close")
+	}
+
+	// Another pipeline over the same results does not release the
+	// message again.
+	if err := NewPipeline(ImmediateAnswer(root)).Close(); err != nil {
+		t.Error("Close on second pipeline:", err)
+	}
+	if released != 1 {
+		t.Errorf("after Close on second pipeline, message released %d times; want 1", released)
+	}
+
+	if err := NewPipeline(ErrorAnswer(ErrNullClient)).Close(); err != nil {
+		t.Errorf("Close on failed call = %v; want <nil>", err)
+	}
+}
+
+func TestPipelineCloseNotOwned(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := 0
+	msg.AddReleaser(func() { released++ })
+	for i := 0; i < 2; i++ {
+		if err := NewPipeline(ImmediateAnswer(root)).Close(); err != nil {
+			t.Errorf("Close #%d: %v", i+1, err)
+		}
+	}
+	if released != 0 {
+		t.Errorf("message released %d times; want 0, since no answer owns it", released)
+	}
+	msg.Release()
+	if released != 1 {
+		t.Errorf("after Release, message released %d times; want 1", released)
+	}
+}
+
+// hangClient answers calls once their context is done, recording the
+// last call's deadline.
+type hangClient struct {
+	deadline time.Time
+	closed   bool
+}
+
+func (hc *hangClient) Call(call *Call) Answer {
+	hc.deadline, _ = call.Ctx.Deadline()
+	<-call.Ctx.Done()
+	return ErrorAnswer(call.Ctx.Err())
+}
+
+func (hc *hangClient) Close() error {
+	hc.closed = true
+	return nil
+}
+
+type closeCounter struct {
+	n int
+}
+
+func (c *closeCounter) Call(call *Call) Answer {
+	return ErrorAnswer(ErrUnimplemented)
+}
+
+func (c *closeCounter) Close() error {
+	c.n++
+	return nil
+}
+
 func mustMarshal(t *testing.T, msg *Message) []byte {
 	data, err := msg.Marshal()
 	if err != nil {
@@ -50,39 +50,27 @@ func (s *Segment) slice(base Address, sz Size) []byte {
 	return s.data[base : base+Address(sz)]
 }
 
-func (s *Segment) readUint8(addr Address) uint8 {
-	return s.slice(addr, 1)[0]
-}
-
-func (s *Segment) readUint16(addr Address) uint16 {
-	return binary.LittleEndian.Uint16(s.slice(addr, 2))
-}
-
-func (s *Segment) readUint32(addr Address) uint32 {
-	return binary.LittleEndian.Uint32(s.slice(addr, 4))
-}
-
-func (s *Segment) readUint64(addr Address) uint64 {
-	return binary.LittleEndian.Uint64(s.slice(addr, 8))
-}
-
 func (s *Segment) readRawPointer(addr Address) rawPointer {
 	return rawPointer(s.readUint64(addr))
 }
 
 func (s *Segment) writeUint8(addr Address, val uint8) {
+	s.checkWritable()
 	s.slice(addr, 1)[0] = val
 }
 
 func (s *Segment) writeUint16(addr Address, val uint16) {
+	s.checkWritable()
 	binary.LittleEndian.PutUint16(s.slice(addr, 2), val)
 }
 
 func (s *Segment) writeUint32(addr Address, val uint32) {
+	s.checkWritable()
 	binary.LittleEndian.PutUint32(s.slice(addr, 4), val)
 }
 
 func (s *Segment) writeUint64(addr Address, val uint64) {
+	s.checkWritable()
 	binary.LittleEndian.PutUint64(s.slice(addr, 8), val)
 }
 
@@ -90,6 +78,14 @@ func (s *Segment) writeRawPointer(addr Address, val rawPointer) {
 	s.writeUint64(addr, uint64(val))
 }
 
+// checkWritable panics if the segment's message is frozen.  Like an
+// out of bounds write, writing to a frozen message is programmer error.
+func (s *Segment) checkWritable() {
+	if s.msg != nil && s.msg.frozen {
+		panic(errFrozen)
+	}
+}
+
 // root returns a 1-element pointer list that references the first word
 // in the segment.  This only makes sense to call on the first segment
 // in a message.
@@ -294,7 +290,11 @@ func (s *Segment) resolveFarPointer(paddr Address) (dst *Segment, base Address,
 }
 
 func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
+	if s.msg.frozen {
+		return errFrozen
+	}
 	if !src.IsValid() {
+		s.zeroOrphan(off, Ptr{})
 		s.writeRawPointer(off, 0)
 		return nil
 	}
@@ -309,6 +309,7 @@ func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
 		if st.size.isZero() {
 			// Zero-sized structs should always be encoded with offset -1 in
 			// order to avoid conflating with null.  No allocation needed.
+			s.zeroOrphan(off, Ptr{})
 			s.writeRawPointer(off, rawStructPointer(-1, ObjectSize{}))
 			return nil
 		}
@@ -383,12 +384,14 @@ func (s *Segment) writePtr(off Address, src Ptr, forceCopy bool) error {
 			c := s.msg.AddCap(i.Client())
 			i = NewInterface(s, c)
 		}
+		s.zeroOrphan(off, Ptr{})
 		s.writeRawPointer(off, i.value(off))
 		return nil
 	default:
 		panic("unreachable")
 	}
 
+	s.zeroOrphan(off, src)
 	switch {
 	case src.seg == s:
 		// Common case: src is in same segment as pointer.
@@ -0,0 +1,24 @@
+// +build !capnpunsafe !386,!amd64,!arm64,!ppc64le
+
+package capnp
+
+import "encoding/binary"
+
+// boundsCheckedReads is true if out of range segment reads panic.
+const boundsCheckedReads = true
+
+func (s *Segment) readUint8(addr Address) uint8 {
+	return s.slice(addr, 1)[0]
+}
+
+func (s *Segment) readUint16(addr Address) uint16 {
+	return binary.LittleEndian.Uint16(s.slice(addr, 2))
+}
+
+func (s *Segment) readUint32(addr Address) uint32 {
+	return binary.LittleEndian.Uint32(s.slice(addr, 4))
+}
+
+func (s *Segment) readUint64(addr Address) uint64 {
+	return binary.LittleEndian.Uint64(s.slice(addr, 8))
+}
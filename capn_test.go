@@ -81,6 +81,10 @@ func TestSegmentReadUint8(t *testing.T) {
 		{data: []byte{1, 42, 2}, addr: 3, panics: true},
 	}
 	for _, test := range tests {
+		if test.panics && !boundsCheckedReads {
+			// Reading out of bounds is undefined with capnpunsafe.
+			continue
+		}
 		seg := &Segment{data: test.data}
 		var val uint8
 		err := catchPanic(func() {
@@ -119,6 +123,10 @@ func TestSegmentReadUint16(t *testing.T) {
 		{data: []byte{0x34, 0x12, 0x56}, addr: 2, panics: true},
 	}
 	for _, test := range tests {
+		if test.panics && !boundsCheckedReads {
+			// Reading out of bounds is undefined with capnpunsafe.
+			continue
+		}
 		seg := &Segment{data: test.data}
 		var val uint16
 		err := catchPanic(func() {
@@ -158,6 +166,10 @@ func TestSegmentReadUint32(t *testing.T) {
 		{data: []byte{0xff, 0x78, 0x56, 0x34, 0x12, 0xff}, addr: 3, panics: true},
 	}
 	for _, test := range tests {
+		if test.panics && !boundsCheckedReads {
+			// Reading out of bounds is undefined with capnpunsafe.
+			continue
+		}
 		seg := &Segment{data: test.data}
 		var val uint32
 		err := catchPanic(func() {
@@ -202,6 +214,10 @@ func TestSegmentReadUint64(t *testing.T) {
 		{data: []byte{0xff, 0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01, 0xff}, addr: 3, panics: true},
 	}
 	for _, test := range tests {
+		if test.panics && !boundsCheckedReads {
+			// Reading out of bounds is undefined with capnpunsafe.
+			continue
+		}
 		seg := &Segment{data: test.data}
 		var val uint64
 		err := catchPanic(func() {
@@ -509,6 +525,206 @@ func TestSetPtrToZeroSizeStruct(t *testing.T) {
 	}
 }
 
+func TestStructTextDataEquals(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 3})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal("root.SetText(0, \"hello\"):", err)
+	}
+	if err := root.SetData(1, []byte{1, 2, 3}); err != nil {
+		t.Fatal("root.SetData(1, ...):", err)
+	}
+
+	textTests := []struct {
+		i    uint16
+		s    string
+		want bool
+	}{
+		{0, "hello", true},
+		{0, "hell", false},
+		{0, "hello!", false},
+		{0, "", false},
+		{1, "\x01\x02", false}, // data isn't NUL-terminated
+		{2, "", true},
+		{2, "x", false},
+	}
+	for _, test := range textTests {
+		eq, err := root.TextEquals(test.i, test.s)
+		if err != nil {
+			t.Errorf("root.TextEquals(%d, %q): %v", test.i, test.s, err)
+			continue
+		}
+		if eq != test.want {
+			t.Errorf("root.TextEquals(%d, %q) = %t; want %t", test.i, test.s, eq, test.want)
+		}
+	}
+
+	dataTests := []struct {
+		i    uint16
+		b    []byte
+		want bool
+	}{
+		{1, []byte{1, 2, 3}, true},
+		{1, []byte{1, 2}, false},
+		{1, nil, false},
+		{0, []byte("hello\x00"), true},
+		{2, nil, true},
+		{2, []byte{}, true},
+		{2, []byte{0}, false},
+	}
+	for _, test := range dataTests {
+		eq, err := root.DataEquals(test.i, test.b)
+		if err != nil {
+			t.Errorf("root.DataEquals(%d, %v): %v", test.i, test.b, err)
+			continue
+		}
+		if eq != test.want {
+			t.Errorf("root.DataEquals(%d, %v) = %t; want %t", test.i, test.b, eq, test.want)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		root.TextEquals(0, "hello")
+		root.DataEquals(1, []byte{1, 2, 3})
+	})
+	if allocs != 0 {
+		t.Errorf("TextEquals and DataEquals allocated %.1f times per run; want 0", allocs)
+	}
+}
+
+func TestStructText(t *testing.T) {
+	msg := &Message{Arena: MultiSegment([][]byte{
+		{
+			0, 0, 0, 0, 0, 0, 4, 0, // root struct pointer
+			13, 0, 0, 0, 0x1a, 0, 0, 0, // near text pointer
+			2, 0, 0, 0, 1, 0, 0, 0, // far pointer to segment 1
+			0, 0, 0, 0, 0, 0, 0, 0, // null pointer
+			5, 0, 0, 0, 0x12, 0, 0, 0, // data pointer without NUL
+			'h', 'i', 0, 0, 0, 0, 0, 0,
+			'x', 'y', 0, 0, 0, 0, 0, 0,
+		},
+		{
+			1, 0, 0, 0, 0x22, 0, 0, 0, // landing pad
+			'a', 'b', 'c', 0, 0, 0, 0, 0,
+		},
+	})}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	root := p.Struct()
+	tests := []struct {
+		i    uint16
+		want string
+	}{
+		{0, "hi"},
+		{1, "abc"},
+		{2, ""},
+		{3, ""},
+		{4, ""},
+	}
+	for _, test := range tests {
+		s, err := root.Text(test.i)
+		if err != nil {
+			t.Errorf("root.Text(%d): %v", test.i, err)
+		} else if s != test.want {
+			t.Errorf("root.Text(%d) = %q; want %q", test.i, s, test.want)
+		}
+		b, err := root.TextBytes(test.i)
+		if err != nil {
+			t.Errorf("root.TextBytes(%d): %v", test.i, err)
+		} else if string(b) != test.want {
+			t.Errorf("root.TextBytes(%d) = %q; want %q", test.i, b, test.want)
+		}
+		ptr, _ := root.Ptr(test.i)
+		if want := ptr.Text(); s != want {
+			t.Errorf("root.Text(%d) = %q; Ptr(%d).Text() = %q", test.i, s, test.i, want)
+		}
+	}
+	if s, err := (Struct{}).Text(0); s != "" || err != nil {
+		t.Errorf("Struct{}.Text(0) = %q, %v; want \"\", <nil>", s, err)
+	}
+
+	msg.ReadLimiter().Reset(2)
+	if _, err := root.Text(0); err == nil {
+		t.Error("root.Text(0) with exhausted read limit did not return an error")
+	}
+}
+
+func TestStructPresent(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 16})
+	if err != nil {
+		t.Fatal("NewRootStruct:", err)
+	}
+	root.SetPresent(8, 0, true)
+	root.SetPresent(8, 63, true)
+	root.SetPresent(8, 5, true)
+	root.SetPresent(8, 5, false)
+	if got, want := root.Uint64(8), uint64(1|1<<63); got != want {
+		t.Errorf("bitmap = %#x; want %#x", got, want)
+	}
+	for _, n := range []uint{0, 63} {
+		if !root.Present(8, n) {
+			t.Errorf("Present(8, %d) = false; want true", n)
+		}
+	}
+	for _, n := range []uint{1, 5, 64} {
+		if root.Present(8, n) {
+			t.Errorf("Present(8, %d) = true; want false", n)
+		}
+	}
+	if root.Present(16, 0) {
+		t.Error("Present past end of data section = true; want false")
+	}
+}
+
+func TestReadPtrRecordsError(t *testing.T) {
+	msg := &Message{Arena: SingleSegment([]byte{
+		0, 0, 0, 0, 0, 0, 2, 0, // root struct pointer
+		2, 0, 0, 0, 9, 0, 0, 0, // far pointer to nonexistent segment
+		1, 0, 0, 0, 0x1a, 0, 0, 0, // text pointer
+		'h', 'i', 0, 0, 0, 0, 0, 0,
+	})}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	root := p.Struct()
+	if got := root.ReadText(1); got != "hi" {
+		t.Errorf("root.ReadText(1) = %q; want \"hi\"", got)
+	}
+	if err := msg.Err(); err != nil {
+		t.Errorf("after valid read, msg.Err() = %v; want <nil>", err)
+	}
+	if got := root.ReadPtr(0); got.IsValid() {
+		t.Errorf("root.ReadPtr(0) = %#v; want null", got)
+	}
+	firstErr := msg.Err()
+	if firstErr == nil {
+		t.Fatal("after invalid read, msg.Err() = <nil>; want error")
+	}
+	if got := root.ReadData(1); string(got) != "hi\x00" {
+		t.Errorf("root.ReadData(1) = %q; want \"hi\\x00\"", got)
+	}
+	if err := msg.Err(); err != firstErr {
+		t.Errorf("msg.Err() = %v; want first error %v", err, firstErr)
+	}
+	msg.Reset(SingleSegment(nil))
+	if err := msg.Err(); err != nil {
+		t.Errorf("after Reset, msg.Err() = %v; want <nil>", err)
+	}
+}
+
 func TestReadFarPointers(t *testing.T) {
 	msg := &Message{
 		// an rpc.capnp Message
@@ -0,0 +1,41 @@
+// +build capnpunsafe
+// +build 386 amd64 arm64 ppc64le
+
+package capnp
+
+import "unsafe"
+
+// The capnpunsafe build tag replaces the segment read functions with
+// ones that load directly from memory, skipping Go's slice bounds
+// checks.  The bounds of a struct or list are validated once, when its
+// pointer is read, so every access through a valid Struct or List is
+// already in range.  However, reading through a pointer obtained before
+// a Message.Reset or a Decoder reusing its buffer will read arbitrary
+// memory instead of panicking, so only use this tag for trusted inputs
+// in hot loops.  It is limited to little-endian architectures that
+// permit unaligned loads.
+//
+// Only the checks that guard against reading outside the segment are
+// removed.  Struct.Uint8 and its siblings still compare the field's
+// offset with the size of the struct's data section, since a struct
+// written with an older version of its schema can be smaller than the
+// reader expects, and the field must then read as zero.  Likewise,
+// List accessors still check the index against the list's length.
+
+const boundsCheckedReads = false
+
+func (s *Segment) readUint8(addr Address) uint8 {
+	return *(*uint8)(unsafe.Pointer(uintptr(unsafe.Pointer(&s.data[0])) + uintptr(addr)))
+}
+
+func (s *Segment) readUint16(addr Address) uint16 {
+	return *(*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(&s.data[0])) + uintptr(addr)))
+}
+
+func (s *Segment) readUint32(addr Address) uint32 {
+	return *(*uint32)(unsafe.Pointer(uintptr(unsafe.Pointer(&s.data[0])) + uintptr(addr)))
+}
+
+func (s *Segment) readUint64(addr Address) uint64 {
+	return *(*uint64)(unsafe.Pointer(uintptr(unsafe.Pointer(&s.data[0])) + uintptr(addr)))
+}
@@ -31,17 +31,37 @@ import (
 const (
 	capnpImport   = "zombiezen.com/go/capnproto2"
 	textImport    = capnpImport + "/encoding/text"
+	jsonImport    = capnpImport + "/encoding/json"
 	schemasImport = capnpImport + "/schemas"
 	serverImport  = capnpImport + "/server"
 	contextImport = "golang.org/x/net/context"
 )
 
+// Type IDs from std/wellknown.capnp.
+const (
+	timestampTypeID = 0xa92a0e17fbe5cadd
+	durationTypeID  = 0xd90938768dfb38c4
+	uuidTypeID      = 0xba7e1900fea96dad
+	decimalTypeID   = 0xfa7f2299899a67f1
+)
+
+// wellKnownTypes are the types that struct fields get conversion
+// accessors for, keyed by type ID.
+var wellKnownTypes = map[uint64]*wellKnownType{
+	timestampTypeID: {Suffix: "Time", Type: "Time", Time: true, Get: "Time", Set: "SetTime"},
+	durationTypeID:  {Suffix: "Duration", Type: "Duration", Time: true, Get: "Duration", Set: "SetDuration"},
+	uuidTypeID:      {Suffix: "Bytes", Type: "[16]byte", Get: "Bytes", Set: "SetBytes"},
+	decimalTypeID:   {Suffix: "Text", Type: "string", Get: "Text", GetErr: true, Set: "SetText", SetErr: true},
+}
+
 // genoptions are parameters that control code generation.
 // Usually passed on the command line.
 type genoptions struct {
 	promises      bool
 	schemas       bool
 	structStrings bool
+	verify        bool
+	json          bool
 }
 
 type renderer interface {
@@ -458,7 +478,7 @@ func (g *generator) defineConstNodes(nodes []*node) error {
 	return nil
 }
 
-func (g *generator) defineField(n *node, f field) (err error) {
+func (g *generator) defineField(n *node, f field, bitmap *field) (err error) {
 	defer func() {
 		if err != nil {
 			err = fmt.Errorf("field %s.%s: %v", n.shortDisplayName(), f.Name, err)
@@ -482,6 +502,22 @@ func (g *generator) defineField(n *node, f field) (err error) {
 		Field:       f,
 		Annotations: ann,
 		FieldType:   ftyp,
+		Presence:    presenceBit(bitmap, f, t),
+	}
+	if ann.HasFixed {
+		if t.Which() != schema.Type_Which_data {
+			return errors.New("$Go.fixed applies only to Data fields")
+		}
+		if ann.Fixed == 0 {
+			return errors.New("$Go.fixed size must be positive")
+		}
+		if f.Slot().HadExplicitDefault() {
+			return errors.New("fixed-size Data field cannot have a default value")
+		}
+		return renderStructFixedDataField(g.r, structFixedDataFieldParams{
+			structFieldParams: params,
+			Size:              ann.Fixed,
+		})
 	}
 	switch t.Which() {
 	case schema.Type_Which_void:
@@ -570,9 +606,12 @@ func (g *generator) defineField(n *node, f field) (err error) {
 			return err
 		}
 		return renderStructStructField(g.r, structStructFieldParams{
-			structFieldParams: params,
-			TypeNode:          tn,
-			Default:           defref,
+			structObjectFieldParams: structObjectFieldParams{
+				structFieldParams: params,
+				TypeNode:          tn,
+				Default:           defref,
+			},
+			WellKnown: wellKnownTypes[tn.Id()],
 		})
 
 	case schema.Type_Which_anyPointer:
@@ -809,6 +848,16 @@ func (g *generator) defineStruct(n *node) error {
 			return err
 		}
 	}
+	if g.opts.verify {
+		if err := g.defineStructVerify(n); err != nil {
+			return err
+		}
+	}
+	if g.opts.json && !n.StructNode().IsGroup() {
+		if err := renderStructJSON(g.r, structJSONParams{G: g, Node: n}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -884,10 +933,17 @@ func (g *generator) defineStructFuncs(n *node) error {
 		return fmt.Errorf("struct funcs for %s: %v", n, err)
 	}
 
+	var bitmap *field
+	if !n.StructNode().IsGroup() {
+		bitmap, err = n.presenceBitmap()
+		if err != nil {
+			return err
+		}
+	}
 	for _, f := range n.codeOrderFields() {
 		switch f.Which() {
 		case schema.Field_Which_slot:
-			if err := g.defineField(n, f); err != nil {
+			if err := g.defineField(n, f, bitmap); err != nil {
 				return err
 			}
 		case schema.Field_Which_group:
@@ -988,6 +1044,146 @@ func (g *generator) defineStructPromise(n *node) error {
 	return nil
 }
 
+func (g *generator) defineStructVerify(n *node) error {
+	params := structVerifyParams{G: g, Node: n}
+	var groups []*node
+	for _, f := range n.codeOrderFields() {
+		var c verifyCheck
+		if f.Which() == schema.Field_Which_group {
+			grp, err := g.nodes.mustFind(f.Group().TypeId())
+			if err != nil {
+				return err
+			}
+			c = verifyCheck{G: g, Field: f, Kind: "group", TypeName: grp.Name}
+			groups = append(groups, grp)
+		} else {
+			var err error
+			c, err = g.verifyCheck(n, f)
+			if err != nil {
+				return fmt.Errorf("verifier for field %s.%s: %v", n.shortDisplayName(), f.Name, err)
+			}
+			if c.Kind == "" {
+				continue
+			}
+		}
+		if f.HasDiscriminant() {
+			params.UnionChecks = append(params.UnionChecks, c)
+		} else {
+			params.Checks = append(params.Checks, c)
+		}
+	}
+	err := renderStructVerify(g.r, params)
+	if err != nil {
+		return fmt.Errorf("verifier for %s: %v", n, err)
+	}
+	for _, grp := range groups {
+		if err := g.defineStructVerify(grp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyCheck returns the check that the verifier makes for a slot
+// field.  Fields that can't be malformed, like numbers, have no check
+// and return a zero verifyCheck.
+func (g *generator) verifyCheck(n *node, f field) (verifyCheck, error) {
+	c := verifyCheck{G: g, Field: f, Offset: f.Slot().Offset()}
+	t, _ := f.Slot().Type()
+	switch t.Which() {
+	case schema.Type_Which_enum:
+		en, err := g.nodes.mustFind(t.Enum().TypeId())
+		if err != nil {
+			return verifyCheck{}, err
+		}
+		def, _ := f.Slot().DefaultValue()
+		es, _ := en.Enum().Enumerants()
+		c.Kind = "enum"
+		c.Offset *= 2
+		c.Name = en.shortDisplayName()
+		c.Count = es.Len()
+		c.Default = def.Enum()
+	case schema.Type_Which_text:
+		c.Kind = "text"
+	case schema.Type_Which_data:
+		c.Kind = "data"
+		fann, _ := f.Annotations()
+		if ann := parseAnnotations(fann); ann.HasFixed {
+			c.Kind = "fixedData"
+			c.Size = strconv.FormatUint(uint64(ann.Fixed), 10)
+		}
+	case schema.Type_Which_interface:
+		c.Kind = "interface"
+	case schema.Type_Which_anyPointer:
+		c.Kind = "anyPointer"
+	case schema.Type_Which_structType:
+		c.Kind = "struct"
+		if err := g.setVerifyType(&c, t.StructType().TypeId(), n); err != nil {
+			return verifyCheck{}, err
+		}
+	case schema.Type_Which_list:
+		et, _ := t.List().ElementType()
+		switch et.Which() {
+		case schema.Type_Which_void:
+			c.Kind, c.Size = "list", g.objectSizeLiteral(0, 0)
+		case schema.Type_Which_bool:
+			c.Kind = "bitList"
+		case schema.Type_Which_int8, schema.Type_Which_uint8:
+			c.Kind, c.Size = "list", g.objectSizeLiteral(1, 0)
+		case schema.Type_Which_int16, schema.Type_Which_uint16:
+			c.Kind, c.Size = "list", g.objectSizeLiteral(2, 0)
+		case schema.Type_Which_int32, schema.Type_Which_uint32, schema.Type_Which_float32:
+			c.Kind, c.Size = "list", g.objectSizeLiteral(4, 0)
+		case schema.Type_Which_int64, schema.Type_Which_uint64, schema.Type_Which_float64:
+			c.Kind, c.Size = "list", g.objectSizeLiteral(8, 0)
+		case schema.Type_Which_list, schema.Type_Which_interface, schema.Type_Which_anyPointer:
+			c.Kind, c.Size = "list", g.objectSizeLiteral(0, 1)
+		case schema.Type_Which_text:
+			c.Kind = "textList"
+		case schema.Type_Which_data:
+			c.Kind = "dataList"
+		case schema.Type_Which_enum:
+			en, err := g.nodes.mustFind(et.Enum().TypeId())
+			if err != nil {
+				return verifyCheck{}, err
+			}
+			es, _ := en.Enum().Enumerants()
+			c.Kind = "enumList"
+			c.Name = en.shortDisplayName()
+			c.Count = es.Len()
+		case schema.Type_Which_structType:
+			c.Kind = "structList"
+			if err := g.setVerifyType(&c, et.StructType().TypeId(), n); err != nil {
+				return verifyCheck{}, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// setVerifyType sets c.TypeName to the name of the struct type with the
+// given ID if it is declared in the file being generated.  The
+// verifiers of other files' types may not have been generated, so
+// those are checked without their schema.
+func (g *generator) setVerifyType(c *verifyCheck, id uint64, rel *node) error {
+	tn, err := g.nodes.mustFind(id)
+	if err != nil {
+		return err
+	}
+	for _, fn := range g.nodes[g.fileID].nodes {
+		if fn == tn {
+			c.TypeName, err = g.RemoteNodeName(tn, rel)
+			return err
+		}
+	}
+	return nil
+}
+
+// objectSizeLiteral returns a capnp.ObjectSize literal.
+func (g *generator) objectSizeLiteral(dataSize, pointerCount int) string {
+	return fmt.Sprintf("%s.ObjectSize{DataSize: %d, PointerCount: %d}", g.imports.Capnp(), dataSize, pointerCount)
+}
+
 func (g *generator) definePromiseField(n *node, f field) error {
 	slot := f.Slot()
 	switch t, _ := slot.Type(); t.Which() {
@@ -1124,6 +1320,9 @@ func generateFile(reqf schema.CodeGeneratorRequest_RequestedFile, nodes nodeMap,
 	if opts.structStrings && !opts.schemas {
 		return errors.New("cannot generate struct String() methods without embedding schemas")
 	}
+	if opts.json && !opts.schemas {
+		return errors.New("cannot generate JSON methods without embedding schemas")
+	}
 	id := reqf.Id()
 	fname, _ := reqf.Filename()
 	g := newGenerator(id, nodes, opts)
@@ -1167,6 +1366,8 @@ func main() {
 	flag.BoolVar(&opts.promises, "promises", true, "generate code for promises")
 	flag.BoolVar(&opts.schemas, "schemas", true, "embed schema information in generated code")
 	flag.BoolVar(&opts.structStrings, "structstrings", true, "generate String() methods for structs (-schemas must be true)")
+	flag.BoolVar(&opts.verify, "verify", false, "generate VerifyX functions that check a message's structure")
+	flag.BoolVar(&opts.json, "json", false, "generate MarshalJSON and UnmarshalJSON methods for structs (-schemas must be true)")
 	flag.Parse()
 
 	msg, err := capnp.NewDecoder(os.Stdin).Decode()
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
@@ -15,8 +17,11 @@ import (
 	"zombiezen.com/go/capnproto2"
 	"zombiezen.com/go/capnproto2/encoding/text"
 	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/std/wellknown"
 )
 
+var update = flag.Bool("update", false, "rewrite internal/streamtest/stream.capnp.go")
+
 func readTestFile(name string) ([]byte, error) {
 	path := filepath.Join("testdata", name)
 	return ioutil.ReadFile(path)
@@ -336,8 +341,16 @@ func TestDefineFile(t *testing.T) {
 			schemas:       true,
 			structStrings: true,
 		}},
+		{0x832bcc6686a26d56, "aircraft.capnp.out", genoptions{
+			promises:      true,
+			schemas:       true,
+			structStrings: true,
+			verify:        true,
+		}},
 		{0x83c2b5818e83ab19, "group.capnp.out", defaultOptions},
+		{0x83c2b5818e83ab19, "group.capnp.out", genoptions{verify: true}},
 		{0xb312981b2552a250, "rpc.capnp.out", defaultOptions},
+		{0xb312981b2552a250, "rpc.capnp.out", genoptions{verify: true}},
 		{0xd68755941d99d05e, "scopes.capnp.out", defaultOptions},
 		{0xecd50d792c3d9992, "util.capnp.out", defaultOptions},
 	}
@@ -411,6 +424,583 @@ func TestSchemaVarLiteral(t *testing.T) {
 	}
 }
 
+// presenceRequest builds a request for a file with one struct:
+//
+//	struct Presence {
+//	  present @0 :UInt64 $Go.presence;
+//	  count @1 :Int32;
+//	  flag @2 :Bool;
+//	  ratio @3 :Float64;
+//	  label @4 :Text;
+//	}
+//
+// The type of the bitmap field can be changed with bitmapType.
+func presenceRequest(bitmapType schema.Type_Which) (schema.CodeGeneratorRequest, error) {
+	const (
+		fileID   = 0xa7f3dc1b2e4c9d51
+		structID = 0xe0b1c8f6d92a4f37
+	)
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	req, err := schema.NewRootCodeGeneratorRequest(seg)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	nodes, err := req.NewNodes(2)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+
+	file := nodes.At(0)
+	file.SetId(fileID)
+	file.SetDisplayName("presence.capnp")
+	file.SetFile()
+	nested, _ := file.NewNestedNodes(1)
+	nested.At(0).SetName("Presence")
+	nested.At(0).SetId(structID)
+	fann, _ := file.NewAnnotations(2)
+	fann.At(0).SetId(capnp.Package)
+	v, _ := fann.At(0).NewValue()
+	v.SetText("presence")
+	fann.At(1).SetId(capnp.Import)
+	v, _ = fann.At(1).NewValue()
+	v.SetText("zombiezen.com/go/capnproto2/capnpc-go/testdata/presence")
+
+	st := nodes.At(1)
+	st.SetId(structID)
+	st.SetDisplayName("presence.capnp:Presence")
+	st.SetDisplayNamePrefixLength(uint32(len("presence.capnp:")))
+	st.SetScopeId(fileID)
+	st.SetStructNode()
+	st.StructNode().SetDataWordCount(3)
+	st.StructNode().SetPointerCount(1)
+	st.StructNode().SetPreferredListEncoding(schema.ElementSize_inlineComposite)
+	fields := []struct {
+		name   string
+		offset uint32
+		typ    schema.Type_Which
+	}{
+		{"present", 0, bitmapType},
+		{"count", 2, schema.Type_Which_int32},
+		{"flag", 96, schema.Type_Which_bool},
+		{"ratio", 2, schema.Type_Which_float64},
+		{"label", 0, schema.Type_Which_text},
+	}
+	fl, err := st.StructNode().NewFields(int32(len(fields)))
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	for i, f := range fields {
+		sf := fl.At(i)
+		sf.SetName(f.name)
+		sf.SetCodeOrder(uint16(i))
+		sf.SetDiscriminantValue(schema.Field_noDiscriminant)
+		sf.Ordinal().SetExplicit(uint16(i))
+		sf.SetSlot()
+		sf.Slot().SetOffset(f.offset)
+		t, _ := sf.Slot().NewType()
+		def, _ := sf.Slot().NewDefaultValue()
+		switch f.typ {
+		case schema.Type_Which_uint64:
+			t.SetUint64()
+			def.SetUint64(0)
+		case schema.Type_Which_int32:
+			t.SetInt32()
+			def.SetInt32(0)
+		case schema.Type_Which_bool:
+			t.SetBool()
+			def.SetBool(false)
+		case schema.Type_Which_float64:
+			t.SetFloat64()
+			def.SetFloat64(0)
+		case schema.Type_Which_text:
+			t.SetText()
+			def.SetText("")
+		}
+		if i == 0 {
+			ann, _ := sf.NewAnnotations(1)
+			ann.At(0).SetId(capnp.Presence)
+			v, _ := ann.At(0).NewValue()
+			v.SetVoid()
+		}
+	}
+	return req, nil
+}
+
+func TestStaticData_Dedup(t *testing.T) {
+	newText := func(s string) capnp.Ptr {
+		_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		txt, err := capnp.NewText(seg, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txt.ToPtr()
+	}
+	var sd staticData
+	sd.init(0x1234)
+	a, err := sd.copyData(newText("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := sd.copyData(newText("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := sd.copyData(newText("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a2 != a {
+		t.Errorf("second copy of \"foo\" = %v; want %v", a2, a)
+	}
+	if b == a {
+		t.Errorf("copy of \"bar\" = %v, same as \"foo\"", b)
+	}
+	if len(sd.buf) != b.End {
+		t.Errorf("len(buf) = %d; want %d", len(sd.buf), b.End)
+	}
+}
+
+func TestPresence(t *testing.T) {
+	const fileID = 0xa7f3dc1b2e4c9d51
+	req, err := presenceRequest(schema.Type_Which_uint64)
+	if err != nil {
+		t.Fatal("presenceRequest:", err)
+	}
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{})
+	getCalls := traceGenerator(g)
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	want := map[string]*presenceParams{
+		"present": nil,
+		"count":   {Offset: 0, Bit: 1},
+		"flag":    {Offset: 0, Bit: 2},
+		"ratio":   {Offset: 0, Bit: 3},
+		"label":   nil,
+	}
+	for _, call := range getCalls() {
+		var p structFieldParams
+		switch params := call.params.(type) {
+		case structUintFieldParams:
+			p = params.structFieldParams
+		case structIntFieldParams:
+			p = params.structFieldParams
+		case structBoolFieldParams:
+			p = params.structFieldParams
+		case structFloatFieldParams:
+			p = params.structFieldParams
+		case structTextFieldParams:
+			p = params.structFieldParams
+		default:
+			continue
+		}
+		w, ok := want[p.Field.Name]
+		if !ok {
+			t.Errorf("rendered unexpected field %q", p.Field.Name)
+			continue
+		}
+		delete(want, p.Field.Name)
+		switch {
+		case w == nil && p.Presence != nil:
+			t.Errorf("field %q Presence = %+v; want nil", p.Field.Name, *p.Presence)
+		case w != nil && p.Presence == nil:
+			t.Errorf("field %q Presence = nil; want %+v", p.Field.Name, *w)
+		case w != nil && *p.Presence != *w:
+			t.Errorf("field %q Presence = %+v; want %+v", p.Field.Name, *p.Presence, *w)
+		}
+	}
+	for name := range want {
+		t.Errorf("field %q not rendered", name)
+	}
+	src := g.generate()
+	if _, err := parser.ParseFile(token.NewFileSet(), "presence.capnp.go", src, 0); err != nil {
+		t.Fatalf("generated code failed to parse: %v", err)
+	}
+	for _, fn := range []string{"HasCount", "ClearCount", "HasFlag", "ClearFlag", "HasRatio", "ClearRatio"} {
+		if !bytes.Contains(src, []byte(") "+fn+"() ")) {
+			t.Errorf("generated code missing %s method", fn)
+		}
+	}
+	if bytes.Contains(src, []byte(") HasPresent() ")) {
+		t.Error("generated code has HasPresent method for the bitmap itself")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const fileID = 0x832bcc6686a26d56
+	req := mustReadGeneratorRequest(t, "aircraft.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{verify: true})
+	getCalls := traceGenerator(g)
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	type check struct {
+		kind, name string
+		offset     uint32
+		count      int
+		typeName   string
+	}
+	want := map[string][]check{
+		"PlaneBase": {
+			{kind: "text", offset: 0},
+			{kind: "enumList", name: "Airport", offset: 1, count: 7},
+		},
+		"B737": {
+			{kind: "struct", offset: 0, typeName: "PlaneBase"},
+		},
+		"Zdate": nil,
+	}
+	for _, call := range getCalls() {
+		p, ok := call.params.(structVerifyParams)
+		if !ok {
+			continue
+		}
+		w, ok := want[p.Node.Name]
+		if !ok {
+			continue
+		}
+		delete(want, p.Node.Name)
+		if len(p.UnionChecks) > 0 {
+			t.Errorf("%s has union checks", p.Node.Name)
+		}
+		if len(p.Checks) != len(w) {
+			t.Errorf("%s has %d checks; want %d", p.Node.Name, len(p.Checks), len(w))
+			continue
+		}
+		for i, c := range p.Checks {
+			got := check{kind: c.Kind, name: c.Name, offset: c.Offset, count: c.Count, typeName: c.TypeName}
+			if got != w[i] {
+				t.Errorf("%s check %d = %+v; want %+v", p.Node.Name, i, got, w[i])
+			}
+		}
+	}
+	for name := range want {
+		t.Errorf("verifier for %s not rendered", name)
+	}
+	src := g.generate()
+	if _, err := parser.ParseFile(token.NewFileSet(), "aircraft.capnp.go", src, 0); err != nil {
+		t.Fatalf("generated code failed to parse: %v", err)
+	}
+	for _, s := range []string{
+		"func VerifyPlaneBase(msg *capnp.Message) error {",
+		"func (s Z) verify() error {",
+		`capnp.VerifyEnum("Z union", uint16(s.Which()), `,
+		"case Z_Which_zvec:",
+	} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Errorf("generated code missing %q", s)
+		}
+	}
+}
+
+func TestJSONMethods(t *testing.T) {
+	const fileID = 0x832bcc6686a26d56
+	req := mustReadGeneratorRequest(t, "aircraft.capnp.out")
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{schemas: true, json: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src := g.generate()
+	if _, err := parser.ParseFile(token.NewFileSet(), "aircraft.capnp.go", src, 0); err != nil {
+		t.Fatalf("generated code failed to parse: %v", err)
+	}
+	for _, s := range []string{
+		`json "zombiezen.com/go/capnproto2/encoding/json"`,
+		"func (s Zdate) MarshalJSON() ([]byte, error) {",
+		"return json.Marshal(Zdate_TypeID, s.Struct)",
+		"func (s *Zdate) UnmarshalJSON(data []byte) error {",
+		"st, err := json.Unmarshal(Zdate_TypeID, data)",
+	} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Errorf("generated code missing %q", s)
+		}
+	}
+	if bytes.Contains(src, []byte("func (s Z_grp) MarshalJSON()")) {
+		t.Error("generated JSON methods for a group")
+	}
+
+	var reqf schema.CodeGeneratorRequest_RequestedFile
+	if err := generateFile(reqf, nodes, genoptions{json: true}); err == nil {
+		t.Error("generateFile with -json and without -schemas succeeded")
+	}
+}
+
+func TestPresence_BadBitmap(t *testing.T) {
+	const fileID = 0xa7f3dc1b2e4c9d51
+	req, err := presenceRequest(schema.Type_Which_int32)
+	if err != nil {
+		t.Fatal("presenceRequest:", err)
+	}
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{})
+	if err := g.defineFile(); err == nil {
+		t.Error("defineFile with Int32 presence bitmap succeeded; want error")
+	}
+}
+
+// fixedDataRequest builds a request for a file with one struct:
+//
+//	struct Hashed {
+//	  hash @0 :Data $Go.fixed(32);
+//	}
+//
+// The type of the field can be changed with typ.
+func fixedDataRequest(typ schema.Type_Which) (schema.CodeGeneratorRequest, error) {
+	const (
+		fileID   = 0xb95e3c0a7d4f1e26
+		structID = 0xd3a8f15c6e0b7942
+	)
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	req, err := schema.NewRootCodeGeneratorRequest(seg)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	nodes, err := req.NewNodes(2)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+
+	file := nodes.At(0)
+	file.SetId(fileID)
+	file.SetDisplayName("fixed.capnp")
+	file.SetFile()
+	nested, _ := file.NewNestedNodes(1)
+	nested.At(0).SetName("Hashed")
+	nested.At(0).SetId(structID)
+	fann, _ := file.NewAnnotations(2)
+	fann.At(0).SetId(capnp.Package)
+	v, _ := fann.At(0).NewValue()
+	v.SetText("fixed")
+	fann.At(1).SetId(capnp.Import)
+	v, _ = fann.At(1).NewValue()
+	v.SetText("zombiezen.com/go/capnproto2/capnpc-go/testdata/fixed")
+
+	st := nodes.At(1)
+	st.SetId(structID)
+	st.SetDisplayName("fixed.capnp:Hashed")
+	st.SetDisplayNamePrefixLength(uint32(len("fixed.capnp:")))
+	st.SetScopeId(fileID)
+	st.SetStructNode()
+	st.StructNode().SetPointerCount(1)
+	st.StructNode().SetPreferredListEncoding(schema.ElementSize_inlineComposite)
+	fl, err := st.StructNode().NewFields(1)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	f := fl.At(0)
+	f.SetName("hash")
+	f.SetDiscriminantValue(schema.Field_noDiscriminant)
+	f.Ordinal().SetExplicit(0)
+	f.SetSlot()
+	t, _ := f.Slot().NewType()
+	def, _ := f.Slot().NewDefaultValue()
+	switch typ {
+	case schema.Type_Which_data:
+		t.SetData()
+		def.SetData(nil)
+	case schema.Type_Which_text:
+		t.SetText()
+		def.SetText("")
+	}
+	ann, _ := f.NewAnnotations(1)
+	ann.At(0).SetId(capnp.Fixed)
+	v, _ = ann.At(0).NewValue()
+	v.SetUint32(32)
+	return req, nil
+}
+
+func TestFixedData(t *testing.T) {
+	const fileID = 0xb95e3c0a7d4f1e26
+	req, err := fixedDataRequest(schema.Type_Which_data)
+	if err != nil {
+		t.Fatal("fixedDataRequest:", err)
+	}
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{verify: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src := g.generate()
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixed.capnp.go", src, 0); err != nil {
+		t.Fatalf("generated code failed to parse: %v", err)
+	}
+	for _, want := range []string{
+		") Hash() ([32]byte, error) {",
+		") ReadHash() [32]byte {",
+		") SetHash(v [32]byte) error {",
+		"capnp.VerifyFixedData(s.Struct, 0, 32)",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+}
+
+func TestFixedData_NotData(t *testing.T) {
+	const fileID = 0xb95e3c0a7d4f1e26
+	req, err := fixedDataRequest(schema.Type_Which_text)
+	if err != nil {
+		t.Fatal("fixedDataRequest:", err)
+	}
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{})
+	if err := g.defineFile(); err == nil {
+		t.Error("defineFile with $Go.fixed on a Text field succeeded; want error")
+	}
+}
+
+func TestStreamingMethod(t *testing.T) {
+	const (
+		fileID       = 0xc4d1e6a3b2f50987
+		interfaceID  = 0xd9e2f1a0b3c4d5e6
+		paramsID     = 0xe1f2a3b4c5d6e7f8
+		streamFileID = 0x86c366a91393f3f8
+	)
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := schema.NewRootCodeGeneratorRequest(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := req.NewNodes(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := nodes.At(0)
+	file.SetId(fileID)
+	file.SetDisplayName("stream.capnp")
+	file.SetFile()
+	nested, _ := file.NewNestedNodes(1)
+	nested.At(0).SetName("Writer")
+	nested.At(0).SetId(interfaceID)
+	fann, _ := file.NewAnnotations(2)
+	fann.At(0).SetId(capnp.Package)
+	v, _ := fann.At(0).NewValue()
+	v.SetText("streamtest")
+	fann.At(1).SetId(capnp.Import)
+	v, _ = fann.At(1).NewValue()
+	v.SetText("zombiezen.com/go/capnproto2/internal/streamtest")
+
+	iface := nodes.At(1)
+	iface.SetId(interfaceID)
+	iface.SetDisplayName("stream.capnp:Writer")
+	iface.SetDisplayNamePrefixLength(uint32(len("stream.capnp:")))
+	iface.SetScopeId(fileID)
+	iface.SetInterface()
+	methods, _ := iface.Interface().NewMethods(1)
+	methods.At(0).SetName("write")
+	methods.At(0).SetParamStructType(paramsID)
+	methods.At(0).SetResultStructType(streamResultID)
+
+	params := nodes.At(2)
+	params.SetId(paramsID)
+	params.SetDisplayName("stream.capnp:Writer.write$Params")
+	params.SetDisplayNamePrefixLength(uint32(len("stream.capnp:Writer.")))
+	params.SetStructNode()
+	params.StructNode().SetPointerCount(1)
+	params.StructNode().SetPreferredListEncoding(schema.ElementSize_inlineComposite)
+	pf, _ := params.StructNode().NewFields(1)
+	pf.At(0).SetName("chunk")
+	pf.At(0).SetDiscriminantValue(schema.Field_noDiscriminant)
+	pf.At(0).Ordinal().SetExplicit(0)
+	pf.At(0).SetSlot()
+	pt, _ := pf.At(0).Slot().NewType()
+	pt.SetData()
+	pdef, _ := pf.At(0).Slot().NewDefaultValue()
+	pdef.SetData(nil)
+
+	// The results are StreamResult from the standard stream.capnp, which
+	// has no Go package.
+	sfile := nodes.At(3)
+	sfile.SetId(streamFileID)
+	sfile.SetDisplayName("capnp/stream.capnp")
+	sfile.SetFile()
+	snested, _ := sfile.NewNestedNodes(1)
+	snested.At(0).SetName("StreamResult")
+	snested.At(0).SetId(streamResultID)
+	result := nodes.At(4)
+	result.SetId(streamResultID)
+	result.SetDisplayName("capnp/stream.capnp:StreamResult")
+	result.SetDisplayNamePrefixLength(uint32(len("capnp/stream.capnp:")))
+	result.SetScopeId(streamFileID)
+	result.SetStructNode()
+	result.StructNode().SetPreferredListEncoding(schema.ElementSize_empty)
+
+	nm, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nm, genoptions{promises: true, schemas: true, structStrings: true})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src, err := format.Source(g.generate())
+	if err != nil {
+		t.Fatalf("generated code failed to parse: %v", err)
+	}
+	for _, s := range []string{
+		"opts ...capnp.CallOption) error {",
+		"return capnp.StreamCall(c.Client, call)",
+		"Results capnp.Struct",
+	} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Errorf("generated code missing %q", s)
+		}
+	}
+	if bytes.Contains(src, []byte("StreamResult")) {
+		t.Error("generated code refers to StreamResult")
+	}
+
+	// The checked-in copy is compiled and used by the rpc tests.
+	path := filepath.Join("..", "internal", "streamtest", "stream.capnp.go")
+	if *update {
+		if err := ioutil.WriteFile(path, src, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s; run go test -update to rewrite it", path)
+	}
+}
+
 type traceRenderer struct {
 	renderer
 	calls []renderCall
@@ -469,3 +1059,145 @@ func nodeListString(n []*node) string {
 	b.WriteByte(']')
 	return b.String()
 }
+
+// wellKnownRequest returns a request for a file with a struct whose
+// fields are the types in std/wellknown.capnp.
+func wellKnownRequest() (schema.CodeGeneratorRequest, error) {
+	const (
+		fileID   = 0xe2b6d7c84a1f3059
+		structID = 0x9c41e0b7d25a6f83
+		wkFileID = 0xfc58552e8c670230
+	)
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	req, err := schema.NewRootCodeGeneratorRequest(seg)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	nodes, err := req.NewNodes(7)
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	setFile := func(n schema.Node, id uint64, name, dir string, nested map[string]uint64) {
+		n.SetId(id)
+		n.SetDisplayName(name + ".capnp")
+		n.SetFile()
+		nn, _ := n.NewNestedNodes(int32(len(nested)))
+		i := 0
+		for name, id := range nested {
+			nn.At(i).SetName(name)
+			nn.At(i).SetId(id)
+			i++
+		}
+		ann, _ := n.NewAnnotations(2)
+		ann.At(0).SetId(capnp.Package)
+		v, _ := ann.At(0).NewValue()
+		v.SetText(name)
+		ann.At(1).SetId(capnp.Import)
+		v, _ = ann.At(1).NewValue()
+		v.SetText("zombiezen.com/go/capnproto2/" + dir)
+	}
+	setStruct := func(n schema.Node, id, scope uint64, file, name string, fields map[string]uint64) {
+		n.SetId(id)
+		n.SetDisplayName(file + ".capnp:" + name)
+		n.SetDisplayNamePrefixLength(uint32(len(file + ".capnp:")))
+		n.SetScopeId(scope)
+		n.SetStructNode()
+		n.StructNode().SetDataWordCount(2)
+		n.StructNode().SetPointerCount(uint16(len(fields)))
+		n.StructNode().SetPreferredListEncoding(schema.ElementSize_inlineComposite)
+		fl, _ := n.StructNode().NewFields(int32(len(fields)))
+		i := 0
+		for name, typeID := range fields {
+			f := fl.At(i)
+			f.SetName(name)
+			f.SetCodeOrder(uint16(i))
+			f.SetDiscriminantValue(schema.Field_noDiscriminant)
+			f.Ordinal().SetExplicit(uint16(i))
+			f.SetSlot()
+			f.Slot().SetOffset(uint32(i))
+			t, _ := f.Slot().NewType()
+			t.SetStructType()
+			t.StructType().SetTypeId(typeID)
+			def, _ := f.Slot().NewDefaultValue()
+			def.SetStructValuePtr(capnp.Ptr{})
+			i++
+		}
+	}
+	setFile(nodes.At(0), fileID, "event", "capnpc-go/testdata/event", map[string]uint64{"Event": structID})
+	setStruct(nodes.At(1), structID, fileID, "event", "Event", map[string]uint64{
+		"at":    timestampTypeID,
+		"took":  durationTypeID,
+		"id":    uuidTypeID,
+		"price": decimalTypeID,
+	})
+	setFile(nodes.At(2), wkFileID, "wellknown", "std/wellknown", map[string]uint64{
+		"Timestamp": timestampTypeID,
+		"Duration":  durationTypeID,
+		"UUID":      uuidTypeID,
+		"Decimal":   decimalTypeID,
+	})
+	setStruct(nodes.At(3), timestampTypeID, wkFileID, "wellknown", "Timestamp", nil)
+	setStruct(nodes.At(4), durationTypeID, wkFileID, "wellknown", "Duration", nil)
+	setStruct(nodes.At(5), uuidTypeID, wkFileID, "wellknown", "UUID", nil)
+	setStruct(nodes.At(6), decimalTypeID, wkFileID, "wellknown", "Decimal", nil)
+	return req, nil
+}
+
+func TestWellKnownAccessors(t *testing.T) {
+	const fileID = 0xe2b6d7c84a1f3059
+	req, err := wellKnownRequest()
+	if err != nil {
+		t.Fatal("wellKnownRequest:", err)
+	}
+	nodes, err := buildNodeMap(req)
+	if err != nil {
+		t.Fatal("buildNodeMap:", err)
+	}
+	g := newGenerator(fileID, nodes, genoptions{})
+	if err := g.defineFile(); err != nil {
+		t.Fatal("defineFile:", err)
+	}
+	src := g.generate()
+	if _, err := parser.ParseFile(token.NewFileSet(), "event.capnp.go", src, 0); err != nil {
+		t.Fatalf("generated code failed to parse: %v\n%s", err, src)
+	}
+	for _, want := range []string{
+		"time \"time\"\n",
+		") AtTime() (time.Time, error) {",
+		") SetAtTime(v time.Time) error {",
+		") TookDuration() (time.Duration, error) {",
+		") SetTookDuration(v time.Duration) error {",
+		"return time.Time{}, err",
+		"return 0, err",
+		") IdBytes() ([16]byte, error) {",
+		") SetIdBytes(v [16]byte) error {",
+		"return [16]byte{}, err",
+		") PriceText() (string, error) {",
+		") SetPriceText(v string) error {",
+		"return \"\", err",
+		"return v.Text()\n",
+		"if err := ss.SetText(v); err != nil {",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+}
+
+func TestWellKnownTypeIDs(t *testing.T) {
+	if timestampTypeID != wellknown.Timestamp_TypeID {
+		t.Errorf("timestampTypeID = %#x; want %#x", uint64(timestampTypeID), uint64(wellknown.Timestamp_TypeID))
+	}
+	if durationTypeID != wellknown.Duration_TypeID {
+		t.Errorf("durationTypeID = %#x; want %#x", uint64(durationTypeID), uint64(wellknown.Duration_TypeID))
+	}
+	if uuidTypeID != wellknown.UUID_TypeID {
+		t.Errorf("uuidTypeID = %#x; want %#x", uint64(uuidTypeID), uint64(wellknown.UUID_TypeID))
+	}
+	if decimalTypeID != wellknown.Decimal_TypeID {
+		t.Errorf("decimalTypeID = %#x; want %#x", uint64(decimalTypeID), uint64(wellknown.Decimal_TypeID))
+	}
+}
@@ -9,14 +9,23 @@ import (
 	"zombiezen.com/go/capnproto2"
 )
 
+// staticData is the byte slice that holds the encoded default values and
+// constants of a file.  Identical values share the same bytes.
+//
+// Pointer field defaults are decoded from the slice by the field's
+// getter, only when it reads a null pointer, so they add nothing to
+// package initialization.  Struct and list constants are exported as
+// variables, so they are still decoded when the package is initialized.
 type staticData struct {
 	name string
 	buf  []byte
+	refs map[string]staticDataRef // keyed on encoded value
 }
 
 func (sd *staticData) init(fileID uint64) {
 	sd.name = fmt.Sprintf("x_%x", fileID)
 	sd.buf = make([]byte, 0, 4096)
+	sd.refs = make(map[string]staticDataRef)
 }
 
 func (sd *staticData) copyData(obj capnp.Ptr) (staticDataRef, error) {
@@ -32,10 +41,14 @@ func (sd *staticData) copyData(obj capnp.Ptr) (staticDataRef, error) {
 	if err != nil {
 		return staticDataRef{}, err
 	}
+	if ref, ok := sd.refs[string(data)]; ok {
+		return ref, nil
+	}
 	ref := staticDataRef{data: sd}
 	ref.Start = len(sd.buf)
 	sd.buf = append(sd.buf, data...)
 	ref.End = len(sd.buf)
+	sd.refs[string(data)] = ref
 	return ref, nil
 }
 
@@ -67,10 +80,12 @@ func (i *imports) init() {
 	i.reserve(importSpec{path: schemasImport, name: "schemas"})
 	i.reserve(importSpec{path: serverImport, name: "server"})
 	i.reserve(importSpec{path: textImport, name: "text"})
+	i.reserve(importSpec{path: jsonImport, name: "json"})
 	i.reserve(importSpec{path: contextImport, name: "context"})
 
 	i.reserve(importSpec{path: "math", name: "math"})
 	i.reserve(importSpec{path: "strconv", name: "strconv"})
+	i.reserve(importSpec{path: "time", name: "time"})
 }
 
 func (i *imports) Capnp() string {
@@ -89,6 +104,10 @@ func (i *imports) Text() string {
 	return i.add(importSpec{path: textImport, name: "text"})
 }
 
+func (i *imports) JSON() string {
+	return i.add(importSpec{path: jsonImport, name: "json"})
+}
+
 func (i *imports) Context() string {
 	return i.add(importSpec{path: contextImport, name: "context"})
 }
@@ -101,6 +120,10 @@ func (i *imports) Strconv() string {
 	return i.add(importSpec{path: "strconv", name: "strconv"})
 }
 
+func (i *imports) Time() string {
+	return i.add(importSpec{path: "time", name: "time"})
+}
+
 func (i *imports) usedImports() []importSpec {
 	specs := make([]importSpec, 0, len(i.specs))
 	for _, s := range i.specs {
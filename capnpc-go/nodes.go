@@ -31,6 +31,55 @@ func (n *node) codeOrderFields() []field {
 	return mbrs
 }
 
+// presenceBitmap returns the field of n annotated with $Go.presence,
+// or nil if n does not have a presence bitmap.
+func (n *node) presenceBitmap() (*field, error) {
+	var bitmap *field
+	for _, f := range n.codeOrderFields() {
+		fann, _ := f.Annotations()
+		if !parseAnnotations(fann).Presence {
+			continue
+		}
+		if bitmap != nil {
+			return nil, fmt.Errorf("%s has more than one presence bitmap", n)
+		}
+		if f.Which() != schema.Field_Which_slot || f.HasDiscriminant() {
+			return nil, fmt.Errorf("presence bitmap %s.%s must be a UInt64 field outside of a union", n.shortDisplayName(), f.Name)
+		}
+		if t, _ := f.Slot().Type(); t.Which() != schema.Type_Which_uint64 {
+			return nil, fmt.Errorf("presence bitmap %s.%s must be a UInt64 field outside of a union", n.shortDisplayName(), f.Name)
+		}
+		f := f
+		bitmap = &f
+	}
+	return bitmap, nil
+}
+
+// presenceBit returns the bit that tracks f in bitmap, or nil if f is
+// not tracked.  Only scalar fields outside of unions whose ordinals fit
+// in the bitmap are tracked.
+func presenceBit(bitmap *field, f field, t schema.Type) *presenceParams {
+	if bitmap == nil || f.HasDiscriminant() || f.CodeOrder() == bitmap.CodeOrder() {
+		return nil
+	}
+	switch t.Which() {
+	case schema.Type_Which_bool,
+		schema.Type_Which_int8, schema.Type_Which_int16, schema.Type_Which_int32, schema.Type_Which_int64,
+		schema.Type_Which_uint8, schema.Type_Which_uint16, schema.Type_Which_uint32, schema.Type_Which_uint64,
+		schema.Type_Which_float32, schema.Type_Which_float64,
+		schema.Type_Which_enum:
+	default:
+		return nil
+	}
+	if f.Ordinal().Which() != schema.Field_ordinal_Which_explicit || f.Ordinal().Explicit() >= 64 {
+		return nil
+	}
+	return &presenceParams{
+		Offset: bitmap.Slot().Offset() * 8,
+		Bit:    f.Ordinal().Explicit(),
+	}
+}
+
 // DiscriminantOffset returns the byte offset of the struct union discriminant.
 func (n *node) DiscriminantOffset() (uint32, error) {
 	if n == nil {
@@ -100,6 +149,15 @@ type interfaceMethod struct {
 	Results      *node
 }
 
+// streamResultID is the ID of StreamResult in /capnp/stream.capnp, the
+// result type of methods declared with "-> stream".
+const streamResultID = 0x995f9a3377c0b16e
+
+// IsStreaming reports whether the method was declared with "-> stream".
+func (m interfaceMethod) IsStreaming() bool {
+	return m.Results.Id() == streamResultID
+}
+
 func methodSet(methods []interfaceMethod, n *node, nodes nodeMap) ([]interfaceMethod, error) {
 	ms, _ := n.Interface().Methods()
 	for i := 0; i < ms.Len(); i++ {
@@ -155,6 +213,9 @@ type annotations struct {
 	TagType   int
 	CustomTag string
 	Name      string
+	Presence  bool
+	Fixed     uint32
+	HasFixed  bool
 }
 
 func parseAnnotations(list schema.Annotation_List) *annotations {
@@ -177,6 +238,11 @@ func parseAnnotations(list schema.Annotation_List) *annotations {
 			ann.TagType = noTag
 		case capnp.Name:
 			ann.Name = text
+		case capnp.Presence:
+			ann.Presence = true
+		case capnp.Fixed:
+			ann.Fixed = val.Uint32()
+			ann.HasFixed = true
 		}
 	}
 	return ann
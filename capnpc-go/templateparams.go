@@ -40,6 +40,11 @@ type baseStructFuncsParams struct {
 	StringMethod bool
 }
 
+type structJSONParams struct {
+	G    *generator
+	Node *node
+}
+
 type structFuncsParams struct {
 	G    *generator
 	Node *node
@@ -58,6 +63,14 @@ type structFieldParams struct {
 	Field       field
 	Annotations *annotations
 	FieldType   string
+	Presence    *presenceParams // nil if the field is not tracked
+}
+
+// presenceParams locates the bit in a $Go.presence bitmap that tracks
+// a field.
+type presenceParams struct {
+	Offset uint32 // byte offset of the bitmap
+	Bit    uint16
 }
 
 type (
@@ -66,7 +79,6 @@ type (
 	structVoidFieldParams      structFieldParams
 	structListFieldParams      structObjectFieldParams
 	structPointerFieldParams   structObjectFieldParams
-	structStructFieldParams    structObjectFieldParams
 )
 
 type structBoolFieldParams struct {
@@ -110,12 +122,54 @@ type structDataFieldParams struct {
 	Default []byte
 }
 
+type structFixedDataFieldParams struct {
+	structFieldParams
+	Size uint32 // from $Go.fixed
+}
+
 type structObjectFieldParams struct {
 	structFieldParams
 	TypeNode *node
 	Default  staticDataRef
 }
 
+type structStructFieldParams struct {
+	structObjectFieldParams
+	WellKnown *wellKnownType // nil unless the field's type is in std/wellknown.capnp
+}
+
+// A wellKnownType is a type from std/wellknown.capnp that struct fields
+// get accessors for that convert to and from a Go type.
+type wellKnownType struct {
+	Suffix string // of the accessor names
+	Type   string // Go type
+	Time   bool   // whether Type is an exported name in package time
+	Get    string // conversion method on the field's type
+	GetErr bool   // whether Get also returns an error
+	Set    string // method on the field's type that sets it from a Type
+	SetErr bool   // whether Set returns an error
+}
+
+// GoType returns the Go type that the accessors convert to and from.
+func (wk *wellKnownType) GoType(g *generator) string {
+	if wk.Time {
+		return g.Imports().Time() + "." + wk.Type
+	}
+	return wk.Type
+}
+
+// Zero returns the zero value of the Go type.
+func (wk *wellKnownType) Zero(g *generator) string {
+	switch wk.Type {
+	case "Duration":
+		return "0"
+	case "string":
+		return `""`
+	default:
+		return wk.GoType(g) + "{}"
+	}
+}
+
 type structListParams struct {
 	G            *generator
 	Node         *node
@@ -135,6 +189,34 @@ type promiseParams struct {
 	Fields []field
 }
 
+type structVerifyParams struct {
+	G           *generator
+	Node        *node
+	Checks      []verifyCheck // fields outside the union
+	UnionChecks []verifyCheck
+}
+
+func (p structVerifyParams) IsBase() bool {
+	return !p.Node.StructNode().IsGroup()
+}
+
+func (p structVerifyParams) UnionName() string {
+	return p.Node.shortDisplayName() + " union"
+}
+
+// verifyCheck describes how a verifier checks one field.
+type verifyCheck struct {
+	G        *generator
+	Field    field
+	Kind     string // enum, text, data, struct, list, bitList, ...
+	Offset   uint32 // pointer index, or byte offset for enums
+	Name     string // enum name for error messages
+	Count    int    // number of enumerants
+	Default  uint16 // enum default
+	Size     string // element size for lists, or byte count for fixed data
+	TypeName string // struct or group type with a generated verifier
+}
+
 type promiseGroupParams struct {
 	G     *generator
 	Node  *node
@@ -0,0 +1,324 @@
+// Package captype checks that capabilities implement the interface
+// types that a schema declares for them.
+//
+// A Cap'n Proto message only records an index into the capability
+// table for an interface pointer, so nothing stops a peer (or a bug)
+// from placing a CallSequence where the schema says an Echo belongs.
+// The mismatch is normally discovered much later, when a call fails
+// with an unimplemented method error.  A Checker uses the schema
+// registry to find the interface-typed fields of a struct and verifies
+// each capability up front.
+//
+// Only capabilities whose implementation is visible in the current
+// process (clients created by the server package, possibly wrapped by
+// the rpc package) can be verified.  Capabilities hosted by a remote
+// vat are assumed to conform, since checking them would require making
+// calls.
+package captype // import "zombiezen.com/go/capnproto2/captype"
+
+import (
+	"fmt"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// A Checker verifies capabilities against their schema types.
+// The zero value checks against the default registry.  It is safe to
+// use from multiple goroutines.
+type Checker struct {
+	mu    sync.Mutex
+	nodes nodemap.Map
+}
+
+// UseRegistry changes the registry that the checker consults for
+// schemas from the default registry.
+func (chk *Checker) UseRegistry(reg *schemas.Registry) {
+	chk.mu.Lock()
+	chk.nodes.UseRegistry(reg)
+	chk.mu.Unlock()
+}
+
+// Client checks that c implements every method of the interface with
+// the given ID, including methods inherited from superclasses.
+// A null client always passes, as does a client whose implementation
+// can't be inspected locally.
+func (chk *Checker) Client(interfaceID uint64, c capnp.Client) error {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+	return chk.checkClient("", interfaceID, c)
+}
+
+// Struct checks every capability reachable from s through fields whose
+// declared type is an interface, recursing into groups, structs, and
+// lists.  Only the active member of a union is checked.  Types that are
+// not present in the registry are not checked.
+func (chk *Checker) Struct(typeID uint64, s capnp.Struct) error {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+	return chk.checkStruct("", typeID, s)
+}
+
+// Params checks the capabilities in the parameters of a call to m.
+func (chk *Checker) Params(m *capnp.Method, params capnp.Struct) error {
+	return chk.methodStruct(m, params, true)
+}
+
+// Results checks the capabilities in the results of a call to m.
+func (chk *Checker) Results(m *capnp.Method, results capnp.Struct) error {
+	return chk.methodStruct(m, results, false)
+}
+
+func (chk *Checker) methodStruct(m *capnp.Method, s capnp.Struct, params bool) error {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+	n, err := chk.find(m.InterfaceID)
+	if err != nil || !n.IsValid() {
+		return err
+	}
+	if n.Which() != schema.Node_Which_interface {
+		return fmt.Errorf("captype: %#x is not an interface", m.InterfaceID)
+	}
+	methods, err := n.Interface().Methods()
+	if err != nil {
+		return err
+	}
+	if int(m.MethodID) >= methods.Len() {
+		// Unknown method: nothing to check against.
+		return nil
+	}
+	meth := methods.At(int(m.MethodID))
+	if params {
+		return chk.checkStruct("", meth.ParamStructType(), s)
+	}
+	return chk.checkStruct("", meth.ResultStructType(), s)
+}
+
+// find returns the node with the given ID or an invalid node if the
+// registry does not have it.  The caller must be holding onto chk.mu.
+func (chk *Checker) find(id uint64) (schema.Node, error) {
+	n, err := chk.nodes.Find(id)
+	if schemas.IsNotFound(err) {
+		return schema.Node{}, nil
+	}
+	return n, err
+}
+
+func (chk *Checker) checkStruct(path string, typeID uint64, s capnp.Struct) error {
+	if !s.IsValid() {
+		return nil
+	}
+	n, err := chk.find(typeID)
+	if err != nil || !n.IsValid() {
+		return err
+	}
+	if n.Which() != schema.Node_Which_structNode {
+		return fmt.Errorf("captype: %#x is not a struct", typeID)
+	}
+	var discriminant uint16
+	if n.StructNode().DiscriminantCount() > 0 {
+		discriminant = s.Uint16(capnp.DataOffset(n.StructNode().DiscriminantOffset() * 2))
+	}
+	fields, err := n.StructNode().Fields()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.At(i)
+		if dv := f.DiscriminantValue(); !(dv == schema.Field_noDiscriminant || dv == discriminant) {
+			continue
+		}
+		name, err := f.Name()
+		if err != nil {
+			return err
+		}
+		fpath := joinPath(path, name)
+		switch f.Which() {
+		case schema.Field_Which_group:
+			if err := chk.checkStruct(fpath, f.Group().TypeId(), s); err != nil {
+				return err
+			}
+		case schema.Field_Which_slot:
+			if err := chk.checkSlot(fpath, s, f.Slot()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (chk *Checker) checkSlot(path string, s capnp.Struct, slot schema.Field_slot) error {
+	typ, err := slot.Type()
+	if err != nil {
+		return err
+	}
+	switch typ.Which() {
+	case schema.Type_Which_interface, schema.Type_Which_structType, schema.Type_Which_list:
+	default:
+		return nil
+	}
+	p, err := s.Ptr(uint16(slot.Offset()))
+	if err != nil {
+		return err
+	}
+	return chk.checkPtr(path, typ, p)
+}
+
+func (chk *Checker) checkPtr(path string, typ schema.Type, p capnp.Ptr) error {
+	switch typ.Which() {
+	case schema.Type_Which_interface:
+		return chk.checkClient(path, typ.Interface().TypeId(), p.Interface().Client())
+	case schema.Type_Which_structType:
+		return chk.checkStruct(path, typ.StructType().TypeId(), p.Struct())
+	case schema.Type_Which_list:
+		elem, err := typ.List().ElementType()
+		if err != nil {
+			return err
+		}
+		l := p.List()
+		switch elem.Which() {
+		case schema.Type_Which_structType:
+			for i := 0; i < l.Len(); i++ {
+				if err := chk.checkStruct(indexPath(path, i), elem.StructType().TypeId(), l.Struct(i)); err != nil {
+					return err
+				}
+			}
+		case schema.Type_Which_interface, schema.Type_Which_list:
+			pl := capnp.PointerList{List: l}
+			for i := 0; i < l.Len(); i++ {
+				ep, err := pl.PtrAt(i)
+				if err != nil {
+					return err
+				}
+				if err := chk.checkPtr(indexPath(path, i), elem, ep); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkClient checks c against an interface.  The caller must be
+// holding onto chk.mu.
+func (chk *Checker) checkClient(path string, interfaceID uint64, c capnp.Client) error {
+	c = unwrap(c)
+	if c == nil {
+		return nil
+	}
+	if _, known := server.Implements(c, &capnp.Method{InterfaceID: interfaceID}); !known {
+		// Not a local server, like an import from a remote vat.
+		return nil
+	}
+	return chk.checkMethods(path, interfaceID, interfaceID, c, make(map[uint64]bool))
+}
+
+func (chk *Checker) checkMethods(path string, want, interfaceID uint64, c capnp.Client, visited map[uint64]bool) error {
+	if visited[interfaceID] {
+		return nil
+	}
+	visited[interfaceID] = true
+	n, err := chk.find(interfaceID)
+	if err != nil || !n.IsValid() {
+		return err
+	}
+	if n.Which() != schema.Node_Which_interface {
+		return fmt.Errorf("captype: %#x is not an interface", interfaceID)
+	}
+	methods, err := n.Interface().Methods()
+	if err != nil {
+		return err
+	}
+	ifaceName, _ := n.DisplayName()
+	for i := 0; i < methods.Len(); i++ {
+		m := &capnp.Method{
+			InterfaceID:   interfaceID,
+			MethodID:      uint16(i),
+			InterfaceName: ifaceName,
+		}
+		if ok, _ := server.Implements(c, m); !ok {
+			m.MethodName, _ = methods.At(i).Name()
+			return &MismatchError{
+				Field:       path,
+				InterfaceID: want,
+				Missing:     *m,
+			}
+		}
+	}
+	supers, err := n.Interface().Superclasses()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < supers.Len(); i++ {
+		if err := chk.checkMethods(path, want, supers.At(i).Id(), c, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unwrap follows clients that delegate to another client, like the
+// reference-counted clients handed out by the rpc package.  It returns
+// nil if the underlying client is not yet known.
+func unwrap(c capnp.Client) capnp.Client {
+	type wrapper interface {
+		Client() capnp.Client
+	}
+	for {
+		w, ok := c.(wrapper)
+		if !ok {
+			return c
+		}
+		c = w.Client()
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// MismatchError is returned when a capability does not implement the
+// interface declared by its schema type.
+type MismatchError struct {
+	// Field is the path to the field holding the capability, like
+	// "base.echo" or "bases[2].echo".  It is empty if the client was
+	// checked directly.
+	Field string
+
+	// InterfaceID is the ID of the interface the capability should
+	// implement.
+	InterfaceID uint64
+
+	// Missing is the first method found that the capability does not
+	// implement.  It may belong to a superclass of InterfaceID.
+	Missing capnp.Method
+}
+
+// Error returns a description of the mismatch.
+func (e *MismatchError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("captype: capability does not implement @%#x (missing %v)", e.InterfaceID, &e.Missing)
+	}
+	return fmt.Sprintf("captype: capability in %s does not implement @%#x (missing %v)", e.Field, e.InterfaceID, &e.Missing)
+}
+
+// IsMismatch reports whether e indicates a capability that does not
+// implement its declared interface.
+func IsMismatch(e error) bool {
+	if me, ok := e.(*capnp.MethodError); ok {
+		e = me.Err
+	}
+	_, ok := e.(*MismatchError)
+	return ok
+}
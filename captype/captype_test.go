@@ -0,0 +1,158 @@
+package captype_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/captype"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+type echoImpl struct{}
+
+func (echoImpl) Echo(call air.Echo_echo) error {
+	return nil
+}
+
+type callSeq struct{}
+
+func (callSeq) GetNumber(call air.CallSequence_getNumber) error {
+	return nil
+}
+
+func TestClient(t *testing.T) {
+	echo := air.Echo_ServerToClient(echoImpl{})
+	defer echo.Client.Close()
+	seq := air.CallSequence_ServerToClient(callSeq{})
+	defer seq.Client.Close()
+
+	chk := new(captype.Checker)
+	if err := chk.Client(air.Echo_TypeID, echo.Client); err != nil {
+		t.Errorf("Client(Echo, echo) = %v; want <nil>", err)
+	}
+	if err := chk.Client(air.Echo_TypeID, nil); err != nil {
+		t.Errorf("Client(Echo, nil) = %v; want <nil>", err)
+	}
+	if err := chk.Client(air.Echo_TypeID, capnp.ErrorClient(capnp.ErrNullClient)); err != nil {
+		t.Errorf("Client(Echo, ErrorClient) = %v; want <nil>", err)
+	}
+	err := chk.Client(air.Echo_TypeID, seq.Client)
+	if !captype.IsMismatch(err) {
+		t.Fatalf("Client(Echo, seq) = %v; want mismatch", err)
+	}
+	me := err.(*captype.MismatchError)
+	if me.InterfaceID != air.Echo_TypeID || me.Missing.InterfaceID != air.Echo_TypeID || me.Missing.MethodID != 0 {
+		t.Errorf("Client(Echo, seq) = %+v; want missing Echo.echo", me)
+	}
+	if me.Missing.MethodName != "echo" {
+		t.Errorf("Client(Echo, seq).Missing.MethodName = %q; want \"echo\"", me.Missing.MethodName)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	echo := air.Echo_ServerToClient(echoImpl{})
+	defer echo.Client.Close()
+	seq := air.CallSequence_ServerToClient(callSeq{})
+	defer seq.Client.Close()
+
+	tests := []struct {
+		name  string
+		build func(*capnp.Segment) (uint64, capnp.Struct, error)
+		field string // empty if no mismatch
+	}{
+		{
+			name: "empty",
+			build: func(seg *capnp.Segment) (uint64, capnp.Struct, error) {
+				h, err := air.NewRootHoth(seg)
+				return air.Hoth_TypeID, h.Struct, err
+			},
+		},
+		{
+			name: "nested match",
+			build: func(seg *capnp.Segment) (uint64, capnp.Struct, error) {
+				h, err := air.NewRootHoth(seg)
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				base, err := h.NewBase()
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				return air.Hoth_TypeID, h.Struct, base.SetEcho(echo)
+			},
+		},
+		{
+			name: "nested mismatch",
+			build: func(seg *capnp.Segment) (uint64, capnp.Struct, error) {
+				h, err := air.NewRootHoth(seg)
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				base, err := h.NewBase()
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				return air.Hoth_TypeID, h.Struct, base.SetEcho(air.Echo{Client: seq.Client})
+			},
+			field: "base.echo",
+		},
+		{
+			name: "list mismatch",
+			build: func(seg *capnp.Segment) (uint64, capnp.Struct, error) {
+				eb, err := air.NewRootEchoBases(seg)
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				bases, err := eb.NewBases(2)
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				if err := bases.At(0).SetEcho(echo); err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				return air.EchoBases_TypeID, eb.Struct, bases.At(1).SetEcho(air.Echo{Client: seq.Client})
+			},
+			field: "bases[1].echo",
+		},
+		{
+			name: "unknown type",
+			build: func(seg *capnp.Segment) (uint64, capnp.Struct, error) {
+				h, err := air.NewRootHoth(seg)
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				base, err := h.NewBase()
+				if err != nil {
+					return 0, capnp.Struct{}, err
+				}
+				return 0xdeadbeef, h.Struct, base.SetEcho(air.Echo{Client: seq.Client})
+			},
+		},
+	}
+	for _, test := range tests {
+		_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		typeID, s, err := test.build(seg)
+		if err != nil {
+			t.Errorf("%s: build: %v", test.name, err)
+			continue
+		}
+		err = new(captype.Checker).Struct(typeID, s)
+		if test.field == "" {
+			if err != nil {
+				t.Errorf("%s: Struct(...) = %v; want <nil>", test.name, err)
+			}
+			continue
+		}
+		me, ok := err.(*captype.MismatchError)
+		if !ok {
+			t.Errorf("%s: Struct(...) = %v; want mismatch", test.name, err)
+			continue
+		}
+		if me.Field != test.field {
+			t.Errorf("%s: Struct(...).Field = %q; want %q", test.name, me.Field, test.field)
+		}
+	}
+}
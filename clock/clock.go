@@ -0,0 +1,162 @@
+// Package clock provides the source of time used by the rpc and server
+// packages' timeouts.
+//
+// Production code uses Real.  Tests can substitute a Fake and advance
+// it explicitly, which exercises timeout behavior deterministically
+// and without sleeping.
+package clock // import "zombiezen.com/go/capnproto2/clock"
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time and schedules functions to run later.
+// A Clock must be safe to use from multiple goroutines.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc arranges for f to be called once d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// A Timer is a function scheduled by Clock.AfterFunc.
+type Timer interface {
+	// Stop prevents the function from being called.  It returns false
+	// if the function has already been called or the timer has already
+	// been stopped.
+	Stop() bool
+}
+
+// Real is the clock provided by the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// A Fake is a clock that only moves when it is advanced.  The zero
+// value is a clock stopped at the zero time.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers []*fakeTimer
+}
+
+// NewFake returns a fake clock set to t.
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t}
+}
+
+// Now returns the fake clock's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// AfterFunc schedules fn to be called by the Advance call that moves
+// the clock d or more past the current time.  If d <= 0, fn is called
+// by the next call to Advance.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	t := &fakeTimer{clock: f, when: f.now.Add(d), seq: f.seq, f: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d and calls the functions of the
+// timers that have come due, in the order of their scheduled times,
+// from the goroutine that called Advance.  The functions are called
+// without holding any of the clock's locks, so they may schedule
+// further timers, which are also called if they come due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		t := f.nextLocked(end)
+		if t == nil {
+			break
+		}
+		if t.when.After(f.now) {
+			f.now = t.when
+		}
+		f.mu.Unlock()
+		t.f()
+		f.mu.Lock()
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+// Pending returns the number of timers that have been scheduled and
+// neither called nor stopped.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// nextLocked removes and returns the earliest timer due by end, or
+// nil if there is none.  The caller must be holding f.mu.
+func (f *Fake) nextLocked(end time.Time) *fakeTimer {
+	if len(f.timers) == 0 {
+		return nil
+	}
+	sort.Sort(byWhen(f.timers))
+	t := f.timers[0]
+	if t.when.After(end) {
+		return nil
+	}
+	f.timers = f.timers[1:]
+	return t
+}
+
+type fakeTimer struct {
+	clock *Fake
+	when  time.Time
+	seq   uint64
+	f     func()
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, u := range t.clock.timers {
+		if u == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// byWhen sorts timers by scheduled time, breaking ties by the order in
+// which they were scheduled.
+type byWhen []*fakeTimer
+
+func (b byWhen) Len() int {
+	return len(b)
+}
+
+func (b byWhen) Less(i, j int) bool {
+	if !b[i].when.Equal(b[j].when) {
+		return b[i].when.Before(b[j].when)
+	}
+	return b[i].seq < b[j].seq
+}
+
+func (b byWhen) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
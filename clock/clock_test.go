@@ -0,0 +1,77 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"zombiezen.com/go/capnproto2/clock"
+)
+
+func TestFakeAdvance(t *testing.T) {
+	start := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	f := clock.NewFake(start)
+	var order []string
+	var at []time.Duration
+	record := func(name string) func() {
+		return func() {
+			order = append(order, name)
+			at = append(at, f.Now().Sub(start))
+		}
+	}
+	f.AfterFunc(2*time.Second, record("b"))
+	f.AfterFunc(1*time.Second, record("a"))
+	f.AfterFunc(2*time.Second, record("c"))
+	stopped := f.AfterFunc(1500*time.Millisecond, record("stopped"))
+	f.AfterFunc(5*time.Second, record("late"))
+	if !stopped.Stop() {
+		t.Error("Stop() = false on pending timer")
+	}
+	if stopped.Stop() {
+		t.Error("second Stop() = true")
+	}
+
+	f.Advance(3 * time.Second)
+
+	want := []string{"a", "b", "c"}
+	if len(order) != len(want) {
+		t.Fatalf("called %v; want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("call #%d = %q; want %q", i, order[i], want[i])
+		}
+	}
+	if at[0] != 1*time.Second || at[1] != 2*time.Second {
+		t.Errorf("timers saw times %v; want [1s 2s 2s]", at)
+	}
+	if got := f.Now().Sub(start); got != 3*time.Second {
+		t.Errorf("after Advance, Now() = start+%v; want start+3s", got)
+	}
+	if n := f.Pending(); n != 1 {
+		t.Errorf("Pending() = %d; want 1", n)
+	}
+}
+
+func TestFakeAdvanceSchedulesFromTimer(t *testing.T) {
+	f := new(clock.Fake)
+	n := 0
+	var tick func()
+	tick = func() {
+		n++
+		f.AfterFunc(time.Second, tick)
+	}
+	f.AfterFunc(time.Second, tick)
+	f.Advance(3 * time.Second)
+	if n != 3 {
+		t.Errorf("ticked %d times in 3s; want 3", n)
+	}
+}
+
+func TestRealAfterFunc(t *testing.T) {
+	done := make(chan struct{})
+	clock.Real.AfterFunc(time.Millisecond, func() { close(done) })
+	<-done
+	if tm := clock.Real.AfterFunc(time.Hour, func() {}); !tm.Stop() {
+		t.Error("Stop() = false on pending real timer")
+	}
+}
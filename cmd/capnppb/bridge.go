@@ -0,0 +1,767 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// bridgeOptions controls code generation.
+type bridgeOptions struct {
+	pkg        string // package name of the generated file
+	capnpImp   string // import path of the capnp Go package; "" uses $Go.import
+	protoImp   string // import path of the protobuf Go package; "" uses go_package
+	protoFiles []string
+}
+
+// A capnpNode is a struct or enum declared in a requested schema file.
+type capnpNode struct {
+	schema.Node
+	goName string
+}
+
+// A protoType is a message or enum declared in a proto file.
+type protoType struct {
+	fullName string // without leading dot
+	goName   string
+	msg      *descriptorpb.DescriptorProto     // nil for enums
+	enum     *descriptorpb.EnumDescriptorProto // nil for messages
+	valuePre string                            // prefix of enum value constants
+}
+
+// A bridge holds the state for generating one file.
+type bridge struct {
+	opts     bridgeOptions
+	capnpPkg string
+	protoPkg string
+
+	nodes  map[uint64]*capnpNode
+	protos map[string]*protoType // by full name
+
+	// pairs maps capnp node IDs to their matching proto types.
+	pairs map[uint64]*protoType
+
+	buf bytes.Buffer
+}
+
+// generateBridge returns the source of a Go file that converts between
+// the capnp types in req's requested files and the matching protobuf
+// types in fds.
+func generateBridge(req schema.CodeGeneratorRequest, fds *descriptorpb.FileDescriptorSet, opts bridgeOptions) ([]byte, error) {
+	b := &bridge{
+		opts:   opts,
+		nodes:  make(map[uint64]*capnpNode),
+		protos: make(map[string]*protoType),
+		pairs:  make(map[uint64]*protoType),
+	}
+	if err := b.loadCapnp(req); err != nil {
+		return nil, err
+	}
+	if err := b.loadProto(fds); err != nil {
+		return nil, err
+	}
+	b.match()
+	if len(b.pairs) == 0 {
+		return nil, fmt.Errorf("no capnp types match a proto type")
+	}
+	b.generate()
+	src, err := format.Source(b.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+// loadCapnp indexes the structs and enums of the requested files.
+func (b *bridge) loadCapnp(req schema.CodeGeneratorRequest) error {
+	rnodes, err := req.Nodes()
+	if err != nil {
+		return err
+	}
+	all := make(map[uint64]schema.Node, rnodes.Len())
+	for i := 0; i < rnodes.Len(); i++ {
+		n := rnodes.At(i)
+		all[n.Id()] = n
+	}
+	reqFiles, err := req.RequestedFiles()
+	if err != nil {
+		return err
+	}
+	if reqFiles.Len() != 1 {
+		return fmt.Errorf("need exactly one requested capnp file, got %d", reqFiles.Len())
+	}
+	file := all[reqFiles.At(0).Id()]
+	fann, err := file.Annotations()
+	if err != nil {
+		return err
+	}
+	pkg, imp := goAnnotations(fann)
+	if b.opts.capnpImp != "" {
+		imp = b.opts.capnpImp
+	}
+	if imp == "" {
+		return fmt.Errorf("capnp file has no $Go.import annotation; use -capnpimport")
+	}
+	if pkg == "" {
+		pkg = path.Base(imp)
+	}
+	b.opts.capnpImp, b.capnpPkg = imp, pkg
+	return b.addNested(all, file, "")
+}
+
+func (b *bridge) addNested(all map[uint64]schema.Node, parent schema.Node, base string) error {
+	nested, err := parent.NestedNodes()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < nested.Len(); i++ {
+		nn := nested.At(i)
+		n, ok := all[nn.Id()]
+		if !ok {
+			continue
+		}
+		name, err := nn.Name()
+		if err != nil {
+			return err
+		}
+		ann, err := n.Annotations()
+		if err != nil {
+			return err
+		}
+		if rename := goName(ann); rename != "" {
+			name = rename
+		}
+		if base == "" {
+			name = strings.Title(name)
+		} else {
+			name = base + "_" + name
+		}
+		switch n.Which() {
+		case schema.Node_Which_structNode:
+			if !n.StructNode().IsGroup() {
+				b.nodes[n.Id()] = &capnpNode{Node: n, goName: name}
+			}
+		case schema.Node_Which_enum:
+			b.nodes[n.Id()] = &capnpNode{Node: n, goName: name}
+		}
+		if err := b.addNested(all, n, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadProto indexes the messages and enums of the proto files that
+// share the selected Go package.
+func (b *bridge) loadProto(fds *descriptorpb.FileDescriptorSet) error {
+	files := fds.GetFile()
+	if len(files) == 0 {
+		return fmt.Errorf("empty descriptor set")
+	}
+	want := make(map[string]bool)
+	for _, name := range b.opts.protoFiles {
+		want[name] = true
+	}
+	if len(want) == 0 {
+		// protoc lists dependencies first.
+		want[files[len(files)-1].GetName()] = true
+	}
+	firstImp := ""
+	for _, f := range files {
+		if !want[f.GetName()] {
+			continue
+		}
+		if f.GetSyntax() != "proto3" {
+			return fmt.Errorf("%s: only proto3 files are supported", f.GetName())
+		}
+		imp, pkg := goPackage(f)
+		if firstImp == "" {
+			firstImp = imp
+		} else if imp != firstImp {
+			return fmt.Errorf("%s: go_package %q differs from %q", f.GetName(), imp, firstImp)
+		}
+		if b.opts.protoImp == "" {
+			b.opts.protoImp = imp
+		}
+		if b.protoPkg == "" {
+			b.protoPkg = pkg
+		}
+		prefix := f.GetPackage()
+		if prefix != "" {
+			prefix += "."
+		}
+		for _, e := range f.GetEnumType() {
+			b.addProtoEnum(prefix, "", e)
+		}
+		for _, m := range f.GetMessageType() {
+			b.addProtoMessage(prefix, "", m)
+		}
+	}
+	if b.opts.protoImp == "" {
+		return fmt.Errorf("proto file has no go_package option; use -pbimport")
+	}
+	if b.protoPkg == b.capnpPkg {
+		b.protoPkg += "pb"
+	}
+	return nil
+}
+
+func (b *bridge) addProtoMessage(scope, goScope string, m *descriptorpb.DescriptorProto) {
+	goName := goScope + goCamelCase(m.GetName())
+	full := scope + m.GetName()
+	b.protos[full] = &protoType{fullName: full, goName: goName, msg: m}
+	if m.GetOptions().GetMapEntry() {
+		return
+	}
+	for _, e := range m.GetEnumType() {
+		b.addProtoEnum(full+".", goName+"_", e)
+	}
+	for _, n := range m.GetNestedType() {
+		b.addProtoMessage(full+".", goName+"_", n)
+	}
+}
+
+func (b *bridge) addProtoEnum(scope, goScope string, e *descriptorpb.EnumDescriptorProto) {
+	goName := goScope + goCamelCase(e.GetName())
+	// protoc-gen-go prefixes the values of nested enums with the name
+	// of the enclosing message rather than the enum.
+	pre := goName + "_"
+	if goScope != "" {
+		pre = goScope
+	}
+	full := scope + e.GetName()
+	b.protos[full] = &protoType{fullName: full, goName: goName, enum: e, valuePre: pre}
+}
+
+// match pairs capnp types with proto types of the same kind whose Go
+// names are equal, ignoring case and underscores.
+func (b *bridge) match() {
+	byName := make(map[string]*protoType)
+	for _, p := range b.protos {
+		if p.msg.GetOptions().GetMapEntry() {
+			continue
+		}
+		byName[normalize(p.goName)] = p
+	}
+	for id, n := range b.nodes {
+		p := byName[normalize(n.goName)]
+		if p == nil {
+			continue
+		}
+		if (n.Which() == schema.Node_Which_enum) != (p.enum != nil) {
+			continue
+		}
+		b.pairs[id] = p
+	}
+}
+
+func (b *bridge) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&b.buf, format, args...)
+}
+
+func (b *bridge) generate() {
+	b.printf("// Code generated by capnppb. DO NOT EDIT.\n\n")
+	b.printf("// Package %s converts between Cap'n Proto and protobuf types.\n", b.opts.pkg)
+	b.printf("package %s\n\n", b.opts.pkg)
+	b.printf("import (\n")
+	b.printf("\t%s %q\n", b.capnpPkg, b.opts.capnpImp)
+	b.printf("\t%s %q\n", b.protoPkg, b.opts.protoImp)
+	b.printf(")\n\n")
+
+	ids := make([]uint64, 0, len(b.pairs))
+	for id := range b.pairs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return b.nodes[ids[i]].goName < b.nodes[ids[j]].goName
+	})
+	for _, id := range ids {
+		n, p := b.nodes[id], b.pairs[id]
+		if p.enum != nil {
+			b.generateEnum(n, p)
+		} else {
+			b.generateStruct(n, p)
+		}
+	}
+}
+
+func (b *bridge) generateEnum(n *capnpNode, p *protoType) {
+	cname := b.capnpPkg + "." + n.goName
+	pname := b.protoPkg + "." + p.goName
+	type pair struct{ c, p string }
+	var pairs []pair
+	values := make(map[string]string)
+	for _, v := range p.enum.GetValue() {
+		name := v.GetName()
+		values[normalize(name)] = name
+		// Allow values to be prefixed with the enum name, as the
+		// protobuf style guide recommends.
+		values[normalize(strings.TrimPrefix(normalize(name), normalize(p.enum.GetName())))] = name
+	}
+	ens, _ := n.Enum().Enumerants()
+	var unmatched []string
+	for i := 0; i < ens.Len(); i++ {
+		e := ens.At(i)
+		name, _ := e.Name()
+		ann, _ := e.Annotations()
+		goEnumerant := name
+		if rename := goName(ann); rename != "" {
+			goEnumerant = rename
+		}
+		pv, ok := values[normalize(name)]
+		if !ok {
+			unmatched = append(unmatched, name)
+			continue
+		}
+		pairs = append(pairs, pair{
+			c: b.capnpPkg + "." + n.goName + "_" + goEnumerant,
+			p: b.protoPkg + "." + p.valuePre + pv,
+		})
+	}
+
+	b.printf("// %sToProto converts v to a %s.\n", n.goName, pname)
+	b.printf("// Values without a counterpart convert to zero.\n")
+	for _, name := range unmatched {
+		b.printf("// The capnp enumerant %s has no protobuf value.\n", name)
+	}
+	b.printf("func %sToProto(v %s) %s {\n", n.goName, cname, pname)
+	b.printf("switch v {\n")
+	for _, pr := range pairs {
+		b.printf("case %s:\nreturn %s\n", pr.c, pr.p)
+	}
+	b.printf("default:\nreturn 0\n}\n}\n\n")
+
+	b.printf("// %sFromProto converts v to a %s.\n", n.goName, cname)
+	b.printf("// Values without a counterpart convert to zero.\n")
+	b.printf("func %sFromProto(v %s) %s {\n", n.goName, pname, cname)
+	b.printf("switch v {\n")
+	for _, pr := range pairs {
+		b.printf("case %s:\nreturn %s\n", pr.p, pr.c)
+	}
+	b.printf("default:\nreturn 0\n}\n}\n\n")
+}
+
+// A fieldPair is a capnp field and the proto field that matches it.
+type fieldPair struct {
+	name  string // capnp field name
+	cname string // Go name of capnp accessors
+	pname string // Go name of proto field
+	typ   schema.Type
+	pf    *descriptorpb.FieldDescriptorProto
+}
+
+func (b *bridge) generateStruct(n *capnpNode, p *protoType) {
+	cname := b.capnpPkg + "." + n.goName
+	pname := b.protoPkg + "." + p.goName
+
+	pfields := make(map[string]*descriptorpb.FieldDescriptorProto)
+	for _, f := range p.msg.GetField() {
+		pfields[normalize(f.GetName())] = f
+	}
+	var pairs []fieldPair
+	var skipped []string
+	fields, _ := n.StructNode().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.At(i)
+		name, _ := f.Name()
+		pf := pfields[normalize(name)]
+		if pf == nil {
+			skipped = append(skipped, fmt.Sprintf("%s has no protobuf field", name))
+			continue
+		}
+		if reason := b.unsupported(f, pf); reason != "" {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", name, reason))
+			continue
+		}
+		ann, _ := f.Annotations()
+		goField := name
+		if rename := goName(ann); rename != "" {
+			goField = rename
+		}
+		typ, _ := f.Slot().Type()
+		pairs = append(pairs, fieldPair{
+			name:  name,
+			cname: strings.Title(goField),
+			pname: goCamelCase(pf.GetName()),
+			typ:   typ,
+			pf:    pf,
+		})
+	}
+
+	b.printf("// %sToProto converts s to a %s.\n", n.goName, pname)
+	for _, s := range skipped {
+		b.printf("// Not converted: %s.\n", s)
+	}
+	b.printf("func %sToProto(s %s) (*%s, error) {\n", n.goName, cname, pname)
+	b.printf("m := new(%s)\n", pname)
+	if needsErr(pairs) {
+		b.printf("var err error\n")
+	}
+	for _, fp := range pairs {
+		b.toProto(fp)
+	}
+	b.printf("return m, nil\n}\n\n")
+
+	b.printf("// %sFromProto sets the fields of s from m.\n", n.goName)
+	b.printf("func %sFromProto(s %s, m *%s) error {\n", n.goName, cname, pname)
+	if needsErr(pairs) {
+		b.printf("var err error\n")
+	}
+	for _, fp := range pairs {
+		b.fromProto(fp)
+	}
+	b.printf("return nil\n}\n\n")
+}
+
+func needsErr(pairs []fieldPair) bool {
+	for _, fp := range pairs {
+		switch fp.typ.Which() {
+		case schema.Type_Which_text, schema.Type_Which_data, schema.Type_Which_structType, schema.Type_Which_list:
+			return true
+		}
+	}
+	return false
+}
+
+// unsupported returns the reason that f cannot be converted to or from
+// pf, or the empty string if it can.
+func (b *bridge) unsupported(f schema.Field, pf *descriptorpb.FieldDescriptorProto) string {
+	if f.Which() != schema.Field_Which_slot {
+		return "groups are not supported"
+	}
+	if f.DiscriminantValue() != schema.Field_noDiscriminant {
+		return "union members are not supported"
+	}
+	if pf.OneofIndex != nil {
+		return "oneof and optional fields are not supported"
+	}
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return err.Error()
+	}
+	repeated := pf.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	if typ.Which() == schema.Type_Which_list {
+		if !repeated {
+			return "capnp list matches a singular protobuf field"
+		}
+		if p := b.protos[strings.TrimPrefix(pf.GetTypeName(), ".")]; p != nil && p.msg.GetOptions().GetMapEntry() {
+			return "map fields are not supported"
+		}
+		elem, err := typ.List().ElementType()
+		if err != nil {
+			return err.Error()
+		}
+		if elem.Which() == schema.Type_Which_list {
+			return "lists of lists are not supported"
+		}
+		return b.typeMismatch(elem, pf)
+	}
+	if repeated {
+		return "repeated protobuf field matches a capnp non-list"
+	}
+	return b.typeMismatch(typ, pf)
+}
+
+// typeMismatch returns a description of why values of capnp type t
+// cannot be converted to pf's type, or the empty string if they can.
+func (b *bridge) typeMismatch(t schema.Type, pf *descriptorpb.FieldDescriptorProto) string {
+	pk := pf.GetType()
+	ok := false
+	switch t.Which() {
+	case schema.Type_Which_bool:
+		ok = pk == descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	case schema.Type_Which_int8, schema.Type_Which_int16, schema.Type_Which_int32, schema.Type_Which_int64,
+		schema.Type_Which_uint8, schema.Type_Which_uint16, schema.Type_Which_uint32, schema.Type_Which_uint64:
+		ok = protoGoType(pk) != "" && isInteger(pk)
+	case schema.Type_Which_float32, schema.Type_Which_float64:
+		ok = pk == descriptorpb.FieldDescriptorProto_TYPE_FLOAT || pk == descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	case schema.Type_Which_text:
+		ok = pk == descriptorpb.FieldDescriptorProto_TYPE_STRING
+	case schema.Type_Which_data:
+		ok = pk == descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	case schema.Type_Which_enum:
+		p := b.pairs[t.Enum().TypeId()]
+		ok = pk == descriptorpb.FieldDescriptorProto_TYPE_ENUM && p != nil && "."+p.fullName == pf.GetTypeName()
+	case schema.Type_Which_structType:
+		p := b.pairs[t.StructType().TypeId()]
+		ok = pk == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE && p != nil && "."+p.fullName == pf.GetTypeName()
+	default:
+		return fmt.Sprintf("capnp type %v is not supported", t.Which())
+	}
+	if !ok {
+		return fmt.Sprintf("capnp %v does not match protobuf %v", t.Which(), strings.ToLower(strings.TrimPrefix(pk.String(), "TYPE_")))
+	}
+	return ""
+}
+
+func isInteger(k descriptorpb.FieldDescriptorProto_Type) bool {
+	switch k {
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	}
+	return true
+}
+
+// protoGoType returns the Go type of scalar protobuf kind k, or "" if
+// k is not a scalar.
+func protoGoType(k descriptorpb.FieldDescriptorProto_Type) string {
+	switch k {
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		return "bool"
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return "int32"
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_SINT64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return "int64"
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
+		return "uint32"
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		return "uint64"
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		return "float32"
+	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		return "float64"
+	}
+	return ""
+}
+
+// capnpGoType returns the Go type of capnp scalar type t.
+func capnpGoType(t schema.Type) string {
+	switch t.Which() {
+	case schema.Type_Which_bool:
+		return "bool"
+	case schema.Type_Which_int8:
+		return "int8"
+	case schema.Type_Which_int16:
+		return "int16"
+	case schema.Type_Which_int32:
+		return "int32"
+	case schema.Type_Which_int64:
+		return "int64"
+	case schema.Type_Which_uint8:
+		return "uint8"
+	case schema.Type_Which_uint16:
+		return "uint16"
+	case schema.Type_Which_uint32:
+		return "uint32"
+	case schema.Type_Which_uint64:
+		return "uint64"
+	case schema.Type_Which_float32:
+		return "float32"
+	case schema.Type_Which_float64:
+		return "float64"
+	}
+	return ""
+}
+
+// capnpListType returns the Go type of a capnp list of elem.
+func (b *bridge) capnpListType(elem schema.Type) string {
+	switch elem.Which() {
+	case schema.Type_Which_bool:
+		return "capnp.BitList"
+	case schema.Type_Which_text:
+		return "capnp.TextList"
+	case schema.Type_Which_data:
+		return "capnp.DataList"
+	case schema.Type_Which_enum:
+		return b.capnpPkg + "." + b.nodes[elem.Enum().TypeId()].goName + "_List"
+	case schema.Type_Which_structType:
+		return b.capnpPkg + "." + b.nodes[elem.StructType().TypeId()].goName + "_List"
+	}
+	t := capnpGoType(elem)
+	return "capnp." + strings.Title(strings.Replace(t, "uint", "UInt", 1)) + "List"
+}
+
+// toProto writes the statements that copy a field from s to m.
+func (b *bridge) toProto(fp fieldPair) {
+	switch fp.typ.Which() {
+	case schema.Type_Which_text, schema.Type_Which_data:
+		b.printf("if m.%s, err = s.%s(); err != nil {\nreturn nil, err\n}\n", fp.pname, fp.cname)
+	case schema.Type_Which_structType:
+		sn := b.nodes[fp.typ.StructType().TypeId()]
+		b.printf("if s.Has%s() {\n", fp.cname)
+		b.printf("var v %s.%s\n", b.capnpPkg, sn.goName)
+		b.printf("if v, err = s.%s(); err != nil {\nreturn nil, err\n}\n", fp.cname)
+		b.printf("if m.%s, err = %sToProto(v); err != nil {\nreturn nil, err\n}\n", fp.pname, sn.goName)
+		b.printf("}\n")
+	case schema.Type_Which_list:
+		elem, _ := fp.typ.List().ElementType()
+		b.printf("if s.Has%s() {\n", fp.cname)
+		b.printf("var l %s\n", b.capnpListType(elem))
+		b.printf("if l, err = s.%s(); err != nil {\nreturn nil, err\n}\n", fp.cname)
+		b.printf("m.%s = make([]%s, l.Len())\n", fp.pname, b.protoElemType(fp.pf))
+		b.printf("for i := range m.%s {\n", fp.pname)
+		switch elem.Which() {
+		case schema.Type_Which_text, schema.Type_Which_data:
+			b.printf("if m.%s[i], err = l.At(i); err != nil {\nreturn nil, err\n}\n", fp.pname)
+		case schema.Type_Which_structType:
+			sn := b.nodes[elem.StructType().TypeId()]
+			b.printf("if m.%s[i], err = %sToProto(l.At(i)); err != nil {\nreturn nil, err\n}\n", fp.pname, sn.goName)
+		default:
+			b.printf("m.%s[i] = %s\n", fp.pname, b.scalarToProto(elem, fp.pf, "l.At(i)"))
+		}
+		b.printf("}\n}\n")
+	default:
+		b.printf("m.%s = %s\n", fp.pname, b.scalarToProto(fp.typ, fp.pf, "s."+fp.cname+"()"))
+	}
+}
+
+// fromProto writes the statements that copy a field from m to s.
+func (b *bridge) fromProto(fp fieldPair) {
+	switch fp.typ.Which() {
+	case schema.Type_Which_text, schema.Type_Which_data:
+		b.printf("if err = s.Set%s(m.%s); err != nil {\nreturn err\n}\n", fp.cname, fp.pname)
+	case schema.Type_Which_structType:
+		sn := b.nodes[fp.typ.StructType().TypeId()]
+		b.printf("if m.%s != nil {\n", fp.pname)
+		b.printf("var v %s.%s\n", b.capnpPkg, sn.goName)
+		b.printf("if v, err = s.New%s(); err != nil {\nreturn err\n}\n", fp.cname)
+		b.printf("if err = %sFromProto(v, m.%s); err != nil {\nreturn err\n}\n", sn.goName, fp.pname)
+		b.printf("}\n")
+	case schema.Type_Which_list:
+		elem, _ := fp.typ.List().ElementType()
+		b.printf("if len(m.%s) > 0 {\n", fp.pname)
+		b.printf("var l %s\n", b.capnpListType(elem))
+		b.printf("if l, err = s.New%s(int32(len(m.%s))); err != nil {\nreturn err\n}\n", fp.cname, fp.pname)
+		b.printf("for i, v := range m.%s {\n", fp.pname)
+		switch elem.Which() {
+		case schema.Type_Which_text, schema.Type_Which_data:
+			b.printf("if err = l.Set(i, v); err != nil {\nreturn err\n}\n")
+		case schema.Type_Which_structType:
+			sn := b.nodes[elem.StructType().TypeId()]
+			b.printf("if v != nil {\nif err = %sFromProto(l.At(i), v); err != nil {\nreturn err\n}\n}\n", sn.goName)
+		default:
+			b.printf("l.Set(i, %s)\n", b.scalarFromProto(elem, "v"))
+		}
+		b.printf("}\n}\n")
+	default:
+		b.printf("s.Set%s(%s)\n", fp.cname, b.scalarFromProto(fp.typ, "m."+fp.pname))
+	}
+}
+
+// scalarToProto returns an expression converting capnp value expr of
+// type t to the type of pf.
+func (b *bridge) scalarToProto(t schema.Type, pf *descriptorpb.FieldDescriptorProto, expr string) string {
+	switch t.Which() {
+	case schema.Type_Which_bool:
+		return expr
+	case schema.Type_Which_enum:
+		return b.nodes[t.Enum().TypeId()].goName + "ToProto(" + expr + ")"
+	}
+	return protoGoType(pf.GetType()) + "(" + expr + ")"
+}
+
+// scalarFromProto returns an expression converting protobuf value expr
+// to capnp type t.
+func (b *bridge) scalarFromProto(t schema.Type, expr string) string {
+	switch t.Which() {
+	case schema.Type_Which_bool:
+		return expr
+	case schema.Type_Which_enum:
+		return b.nodes[t.Enum().TypeId()].goName + "FromProto(" + expr + ")"
+	}
+	return capnpGoType(t) + "(" + expr + ")"
+}
+
+// protoElemType returns the Go element type of repeated field pf.
+func (b *bridge) protoElemType(pf *descriptorpb.FieldDescriptorProto) string {
+	switch pf.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
+		return "string"
+	case descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		return "[]byte"
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		return b.protoPkg + "." + b.protos[strings.TrimPrefix(pf.GetTypeName(), ".")].goName
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+		return "*" + b.protoPkg + "." + b.protos[strings.TrimPrefix(pf.GetTypeName(), ".")].goName
+	}
+	return protoGoType(pf.GetType())
+}
+
+// goAnnotations returns the $Go.package and $Go.import annotations.
+func goAnnotations(list schema.Annotation_List) (pkg, imp string) {
+	for i := 0; i < list.Len(); i++ {
+		a := list.At(i)
+		val, _ := a.Value()
+		text, _ := val.Text()
+		switch a.Id() {
+		case capnp.Package:
+			pkg = text
+		case capnp.Import:
+			imp = text
+		}
+	}
+	return
+}
+
+// goName returns the $Go.name annotation, if any.
+func goName(list schema.Annotation_List) string {
+	for i := 0; i < list.Len(); i++ {
+		a := list.At(i)
+		if a.Id() == capnp.Name {
+			val, _ := a.Value()
+			text, _ := val.Text()
+			return text
+		}
+	}
+	return ""
+}
+
+// goPackage returns the Go import path and package name of a proto
+// file, following protoc-gen-go's interpretation of go_package.
+func goPackage(f *descriptorpb.FileDescriptorProto) (imp, name string) {
+	gp := f.GetOptions().GetGoPackage()
+	if i := strings.IndexByte(gp, ';'); i >= 0 {
+		return gp[:i], gp[i+1:]
+	}
+	if gp == "" {
+		return "", strings.Replace(f.GetPackage(), ".", "_", -1)
+	}
+	return gp, strings.Replace(path.Base(gp), "-", "_", -1)
+}
+
+// normalize folds case and drops underscores so that names written in
+// capnp and protobuf style compare equal.
+func normalize(s string) string {
+	return strings.ToLower(strings.Replace(s, "_", "", -1))
+}
+
+// goCamelCase returns the Go name protoc-gen-go uses for a protobuf
+// identifier.
+func goCamelCase(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip the dot in ".x".
+		case c == '.':
+			out = append(out, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			out = append(out, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip the underscore in "_x".
+		case '0' <= c && c <= '9':
+			out = append(out, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			out = append(out, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				out = append(out, s[i+1])
+			}
+		}
+	}
+	return string(out)
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+var update = flag.Bool("update", false, "rewrite internal/aircraftbridge/bridge.go")
+
+func readAircraftRequest(t *testing.T) schema.CodeGeneratorRequest {
+	data, err := ioutil.ReadFile(filepath.Join("..", "..", "capnpc-go", "testdata", "aircraft.capnp.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	req, err := schema.ReadRootCodeGeneratorRequest(msg)
+	if err != nil {
+		t.Fatal("ReadRootCodeGeneratorRequest:", err)
+	}
+	return req
+}
+
+func field(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(jsonName(name)),
+		Number:   proto.Int32(num),
+		Type:     typ.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	if repeated {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	return f
+}
+
+// jsonName returns the JSON name that protoc assigns to a field.
+func jsonName(name string) string {
+	var out []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '_' && i+1 < len(name) && isLower(name[i+1]) {
+			i++
+			out = append(out, name[i]-('a'-'A'))
+			continue
+		}
+		out = append(out, name[i])
+	}
+	return string(out)
+}
+
+// aircraftProto returns the descriptor of internal/aircraftpb/aircraft.proto,
+// which mirrors part of aircraft.capnp.
+func aircraftProto() *descriptorpb.FileDescriptorSet {
+	const (
+		typeBool    = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+		typeInt32   = descriptorpb.FieldDescriptorProto_TYPE_INT32
+		typeUint32  = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+		typeInt64   = descriptorpb.FieldDescriptorProto_TYPE_INT64
+		typeDouble  = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+		typeString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		typeBytes   = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+		typeEnum    = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		typeMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	enumValue := func(name string, n int32) *descriptorpb.EnumValueDescriptorProto {
+		return &descriptorpb.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(n)}
+	}
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("aircraft.proto"),
+			Package: proto.String("aircraft"),
+			Syntax:  proto.String("proto3"),
+			Options: &descriptorpb.FileOptions{
+				GoPackage: proto.String("zombiezen.com/go/capnproto2/cmd/capnppb/internal/aircraftpb"),
+			},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Airport"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					enumValue("AIRPORT_NONE", 0),
+					enumValue("AIRPORT_JFK", 1),
+					enumValue("AIRPORT_LAX", 2),
+					enumValue("SFO", 3),
+				},
+			}},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Zdate"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("year", 1, typeInt32, "", false),
+						field("month", 2, typeUint32, "", false),
+						field("day", 3, typeUint32, "", false),
+					},
+				},
+				{
+					Name: proto.String("Zdata"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("data", 1, typeBytes, "", false),
+					},
+				},
+				{
+					Name: proto.String("PlaneBase"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("name", 1, typeString, "", false),
+						field("homes", 2, typeEnum, ".aircraft.Airport", true),
+						field("rating", 3, typeString, "", false),
+						field("can_fly", 4, typeBool, "", false),
+						field("max_speed", 6, typeDouble, "", false),
+					},
+				},
+				{
+					Name: proto.String("B737"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("base", 1, typeMessage, ".aircraft.PlaneBase", false),
+					},
+				},
+				{
+					Name: proto.String("Unrelated"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("x", 1, typeInt64, "", false),
+					},
+				},
+			},
+		}},
+	}
+}
+
+func TestGenerateBridge(t *testing.T) {
+	req := readAircraftRequest(t)
+	src, err := generateBridge(req, aircraftProto(), bridgeOptions{pkg: "bridge"})
+	if err != nil {
+		t.Fatal("generateBridge:", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "bridge.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	out := string(src)
+	want := []string{
+		"package bridge",
+		`"zombiezen.com/go/capnproto2/internal/aircraftlib"`,
+		`aircraftpb "zombiezen.com/go/capnproto2/cmd/capnppb/internal/aircraftpb"`,
+
+		"func AirportToProto(v aircraftlib.Airport) aircraftpb.Airport {",
+		"case aircraftlib.Airport_jfk:\n\t\treturn aircraftpb.Airport_AIRPORT_JFK",
+		"case aircraftlib.Airport_sfo:\n\t\treturn aircraftpb.Airport_SFO",
+		"// The capnp enumerant dfw has no protobuf value.",
+		"func AirportFromProto(v aircraftpb.Airport) aircraftlib.Airport {",
+
+		"func ZdateToProto(s aircraftlib.Zdate) (*aircraftpb.Zdate, error) {",
+		"m.Year = int32(s.Year())",
+		"m.Month = uint32(s.Month())",
+		"func ZdateFromProto(s aircraftlib.Zdate, m *aircraftpb.Zdate) error {",
+		"s.SetYear(int16(m.Year))",
+
+		"if m.Data, err = s.Data(); err != nil {",
+		"if err = s.SetData(m.Data); err != nil {",
+
+		"// Not converted: rating: capnp int64 does not match protobuf string.",
+		"// Not converted: capacity has no protobuf field.",
+		"m.CanFly = s.CanFly()",
+		"m.MaxSpeed = float64(s.MaxSpeed())",
+		"m.Homes = make([]aircraftpb.Airport, l.Len())",
+		"m.Homes[i] = AirportToProto(l.At(i))",
+		"if l, err = s.NewHomes(int32(len(m.Homes))); err != nil {",
+		"l.Set(i, AirportFromProto(v))",
+
+		"if m.Base, err = PlaneBaseToProto(v); err != nil {",
+		"if v, err = s.NewBase(); err != nil {",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("generated code does not contain %q", w)
+		}
+	}
+	if strings.Contains(out, "Unrelated") {
+		t.Error("generated code mentions unmatched proto message Unrelated")
+	}
+	if t.Failed() {
+		t.Logf("generated code:\n%s", src)
+	}
+}
+
+// TestGenerateBridge_Compiled checks that the generated code matches
+// the checked-in copy in internal/aircraftbridge, which is compiled
+// against the protoc-gen-go output in internal/aircraftpb and tested
+// there.
+func TestGenerateBridge_Compiled(t *testing.T) {
+	req := readAircraftRequest(t)
+	src, err := generateBridge(req, aircraftProto(), bridgeOptions{pkg: "aircraftbridge"})
+	if err != nil {
+		t.Fatal("generateBridge:", err)
+	}
+	path := filepath.Join("internal", "aircraftbridge", "bridge.go")
+	if *update {
+		if err := ioutil.WriteFile(path, src, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s; run go test -update to rewrite it", path)
+	}
+}
+
+func TestGenerateBridge_NoMatches(t *testing.T) {
+	req := readAircraftRequest(t)
+	fds := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("empty.proto"),
+			Syntax:  proto.String("proto3"),
+			Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/emptypb")},
+		}},
+	}
+	if _, err := generateBridge(req, fds, bridgeOptions{pkg: "bridge"}); err == nil {
+		t.Error("generateBridge with no matching types succeeded")
+	}
+}
+
+func TestGenerateBridge_Proto2(t *testing.T) {
+	req := readAircraftRequest(t)
+	fds := aircraftProto()
+	fds.File[0].Syntax = proto.String("proto2")
+	if _, err := generateBridge(req, fds, bridgeOptions{pkg: "bridge"}); err == nil {
+		t.Error("generateBridge with proto2 file succeeded")
+	}
+}
+
+func TestGoCamelCase(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"foo", "Foo"},
+		{"foo_bar", "FooBar"},
+		{"can_fly", "CanFly"},
+		{"fooBar", "FooBar"},
+		{"B737", "B737"},
+		{"foo_1", "Foo_1"},
+		{"_foo", "XFoo"},
+		{"FOO_BAR", "FOO_BAR"},
+	}
+	for _, test := range tests {
+		if got := goCamelCase(test.in); got != test.out {
+			t.Errorf("goCamelCase(%q) = %q; want %q", test.in, got, test.out)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Code generated by capnppb. DO NOT EDIT.
+
+// Package aircraftbridge converts between Cap'n Proto and protobuf types.
+package aircraftbridge
+
+import (
+	aircraftpb "zombiezen.com/go/capnproto2/cmd/capnppb/internal/aircraftpb"
+	aircraftlib "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+// AirportToProto converts v to a aircraftpb.Airport.
+// Values without a counterpart convert to zero.
+// The capnp enumerant luv has no protobuf value.
+// The capnp enumerant dfw has no protobuf value.
+// The capnp enumerant test has no protobuf value.
+func AirportToProto(v aircraftlib.Airport) aircraftpb.Airport {
+	switch v {
+	case aircraftlib.Airport_none:
+		return aircraftpb.Airport_AIRPORT_NONE
+	case aircraftlib.Airport_jfk:
+		return aircraftpb.Airport_AIRPORT_JFK
+	case aircraftlib.Airport_lax:
+		return aircraftpb.Airport_AIRPORT_LAX
+	case aircraftlib.Airport_sfo:
+		return aircraftpb.Airport_SFO
+	default:
+		return 0
+	}
+}
+
+// AirportFromProto converts v to a aircraftlib.Airport.
+// Values without a counterpart convert to zero.
+func AirportFromProto(v aircraftpb.Airport) aircraftlib.Airport {
+	switch v {
+	case aircraftpb.Airport_AIRPORT_NONE:
+		return aircraftlib.Airport_none
+	case aircraftpb.Airport_AIRPORT_JFK:
+		return aircraftlib.Airport_jfk
+	case aircraftpb.Airport_AIRPORT_LAX:
+		return aircraftlib.Airport_lax
+	case aircraftpb.Airport_SFO:
+		return aircraftlib.Airport_sfo
+	default:
+		return 0
+	}
+}
+
+// B737ToProto converts s to a aircraftpb.B737.
+func B737ToProto(s aircraftlib.B737) (*aircraftpb.B737, error) {
+	m := new(aircraftpb.B737)
+	var err error
+	if s.HasBase() {
+		var v aircraftlib.PlaneBase
+		if v, err = s.Base(); err != nil {
+			return nil, err
+		}
+		if m.Base, err = PlaneBaseToProto(v); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// B737FromProto sets the fields of s from m.
+func B737FromProto(s aircraftlib.B737, m *aircraftpb.B737) error {
+	var err error
+	if m.Base != nil {
+		var v aircraftlib.PlaneBase
+		if v, err = s.NewBase(); err != nil {
+			return err
+		}
+		if err = PlaneBaseFromProto(v, m.Base); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlaneBaseToProto converts s to a aircraftpb.PlaneBase.
+// Not converted: rating: capnp int64 does not match protobuf string.
+// Not converted: capacity has no protobuf field.
+func PlaneBaseToProto(s aircraftlib.PlaneBase) (*aircraftpb.PlaneBase, error) {
+	m := new(aircraftpb.PlaneBase)
+	var err error
+	if m.Name, err = s.Name(); err != nil {
+		return nil, err
+	}
+	if s.HasHomes() {
+		var l aircraftlib.Airport_List
+		if l, err = s.Homes(); err != nil {
+			return nil, err
+		}
+		m.Homes = make([]aircraftpb.Airport, l.Len())
+		for i := range m.Homes {
+			m.Homes[i] = AirportToProto(l.At(i))
+		}
+	}
+	m.CanFly = s.CanFly()
+	m.MaxSpeed = float64(s.MaxSpeed())
+	return m, nil
+}
+
+// PlaneBaseFromProto sets the fields of s from m.
+func PlaneBaseFromProto(s aircraftlib.PlaneBase, m *aircraftpb.PlaneBase) error {
+	var err error
+	if err = s.SetName(m.Name); err != nil {
+		return err
+	}
+	if len(m.Homes) > 0 {
+		var l aircraftlib.Airport_List
+		if l, err = s.NewHomes(int32(len(m.Homes))); err != nil {
+			return err
+		}
+		for i, v := range m.Homes {
+			l.Set(i, AirportFromProto(v))
+		}
+	}
+	s.SetCanFly(m.CanFly)
+	s.SetMaxSpeed(float64(m.MaxSpeed))
+	return nil
+}
+
+// ZdataToProto converts s to a aircraftpb.Zdata.
+func ZdataToProto(s aircraftlib.Zdata) (*aircraftpb.Zdata, error) {
+	m := new(aircraftpb.Zdata)
+	var err error
+	if m.Data, err = s.Data(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ZdataFromProto sets the fields of s from m.
+func ZdataFromProto(s aircraftlib.Zdata, m *aircraftpb.Zdata) error {
+	var err error
+	if err = s.SetData(m.Data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ZdateToProto converts s to a aircraftpb.Zdate.
+func ZdateToProto(s aircraftlib.Zdate) (*aircraftpb.Zdate, error) {
+	m := new(aircraftpb.Zdate)
+	m.Year = int32(s.Year())
+	m.Month = uint32(s.Month())
+	m.Day = uint32(s.Day())
+	return m, nil
+}
+
+// ZdateFromProto sets the fields of s from m.
+func ZdateFromProto(s aircraftlib.Zdate, m *aircraftpb.Zdate) error {
+	s.SetYear(int16(m.Year))
+	s.SetMonth(uint8(m.Month))
+	s.SetDay(uint8(m.Day))
+	return nil
+}
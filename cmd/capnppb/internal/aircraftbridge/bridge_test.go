@@ -0,0 +1,94 @@
+package aircraftbridge
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/cmd/capnppb/internal/aircraftpb"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+func TestPlaneBaseRoundTrip(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := air.NewRootB737(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := b.NewBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.SetName("Spirit")
+	base.SetRating(7)
+	base.SetCanFly(true)
+	base.SetMaxSpeed(590.5)
+	homes, err := base.NewHomes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	homes.Set(0, air.Airport_jfk)
+	homes.Set(1, air.Airport_dfw)
+
+	m, err := B737ToProto(b)
+	if err != nil {
+		t.Fatal("B737ToProto:", err)
+	}
+	mb := m.GetBase()
+	if mb.GetName() != "Spirit" || !mb.GetCanFly() || mb.GetMaxSpeed() != 590.5 || mb.GetRating() != "" {
+		t.Errorf("B737ToProto base = %v; want name Spirit, canFly, maxSpeed 590.5, no rating", mb)
+	}
+	if h := mb.GetHomes(); len(h) != 2 || h[0] != aircraftpb.Airport_AIRPORT_JFK || h[1] != aircraftpb.Airport_AIRPORT_NONE {
+		t.Errorf("B737ToProto homes = %v; want [AIRPORT_JFK AIRPORT_NONE]", h)
+	}
+
+	_, seg, err = capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := air.NewRootB737(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := B737FromProto(b2, m); err != nil {
+		t.Fatal("B737FromProto:", err)
+	}
+	base2, err := b2.Base()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := base2.Name(); name != "Spirit" || !base2.CanFly() || base2.MaxSpeed() != 590.5 || base2.Rating() != 0 {
+		t.Errorf("B737FromProto base = %v", base2)
+	}
+	homes2, err := base2.Homes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if homes2.Len() != 2 || homes2.At(0) != air.Airport_jfk || homes2.At(1) != air.Airport_none {
+		t.Errorf("B737FromProto homes = %v; want [jfk none]", homes2)
+	}
+}
+
+func TestZdataRoundTrip(t *testing.T) {
+	m := &aircraftpb.Zdata{Data: []byte("abc")}
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := air.NewRootZdata(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ZdataFromProto(z, m); err != nil {
+		t.Fatal("ZdataFromProto:", err)
+	}
+	m2, err := ZdataToProto(z)
+	if err != nil {
+		t.Fatal("ZdataToProto:", err)
+	}
+	if string(m2.GetData()) != "abc" {
+		t.Errorf("ZdataToProto data = %q; want \"abc\"", m2.GetData())
+	}
+}
@@ -0,0 +1,512 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        (unknown)
+// source: aircraft.proto
+
+package aircraftpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Airport int32
+
+const (
+	Airport_AIRPORT_NONE Airport = 0
+	Airport_AIRPORT_JFK  Airport = 1
+	Airport_AIRPORT_LAX  Airport = 2
+	Airport_SFO          Airport = 3
+)
+
+// Enum value maps for Airport.
+var (
+	Airport_name = map[int32]string{
+		0: "AIRPORT_NONE",
+		1: "AIRPORT_JFK",
+		2: "AIRPORT_LAX",
+		3: "SFO",
+	}
+	Airport_value = map[string]int32{
+		"AIRPORT_NONE": 0,
+		"AIRPORT_JFK":  1,
+		"AIRPORT_LAX":  2,
+		"SFO":          3,
+	}
+)
+
+func (x Airport) Enum() *Airport {
+	p := new(Airport)
+	*p = x
+	return p
+}
+
+func (x Airport) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Airport) Descriptor() protoreflect.EnumDescriptor {
+	return file_aircraft_proto_enumTypes[0].Descriptor()
+}
+
+func (Airport) Type() protoreflect.EnumType {
+	return &file_aircraft_proto_enumTypes[0]
+}
+
+func (x Airport) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Airport.Descriptor instead.
+func (Airport) EnumDescriptor() ([]byte, []int) {
+	return file_aircraft_proto_rawDescGZIP(), []int{0}
+}
+
+type Zdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Year  int32  `protobuf:"varint,1,opt,name=year,proto3" json:"year,omitempty"`
+	Month uint32 `protobuf:"varint,2,opt,name=month,proto3" json:"month,omitempty"`
+	Day   uint32 `protobuf:"varint,3,opt,name=day,proto3" json:"day,omitempty"`
+}
+
+func (x *Zdate) Reset() {
+	*x = Zdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aircraft_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Zdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Zdate) ProtoMessage() {}
+
+func (x *Zdate) ProtoReflect() protoreflect.Message {
+	mi := &file_aircraft_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Zdate.ProtoReflect.Descriptor instead.
+func (*Zdate) Descriptor() ([]byte, []int) {
+	return file_aircraft_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Zdate) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *Zdate) GetMonth() uint32 {
+	if x != nil {
+		return x.Month
+	}
+	return 0
+}
+
+func (x *Zdate) GetDay() uint32 {
+	if x != nil {
+		return x.Day
+	}
+	return 0
+}
+
+type Zdata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *Zdata) Reset() {
+	*x = Zdata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aircraft_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Zdata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Zdata) ProtoMessage() {}
+
+func (x *Zdata) ProtoReflect() protoreflect.Message {
+	mi := &file_aircraft_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Zdata.ProtoReflect.Descriptor instead.
+func (*Zdata) Descriptor() ([]byte, []int) {
+	return file_aircraft_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Zdata) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type PlaneBase struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Homes    []Airport `protobuf:"varint,2,rep,packed,name=homes,proto3,enum=aircraft.Airport" json:"homes,omitempty"`
+	Rating   string    `protobuf:"bytes,3,opt,name=rating,proto3" json:"rating,omitempty"`
+	CanFly   bool      `protobuf:"varint,4,opt,name=can_fly,json=canFly,proto3" json:"can_fly,omitempty"`
+	MaxSpeed float64   `protobuf:"fixed64,6,opt,name=max_speed,json=maxSpeed,proto3" json:"max_speed,omitempty"`
+}
+
+func (x *PlaneBase) Reset() {
+	*x = PlaneBase{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aircraft_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PlaneBase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaneBase) ProtoMessage() {}
+
+func (x *PlaneBase) ProtoReflect() protoreflect.Message {
+	mi := &file_aircraft_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaneBase.ProtoReflect.Descriptor instead.
+func (*PlaneBase) Descriptor() ([]byte, []int) {
+	return file_aircraft_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PlaneBase) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PlaneBase) GetHomes() []Airport {
+	if x != nil {
+		return x.Homes
+	}
+	return nil
+}
+
+func (x *PlaneBase) GetRating() string {
+	if x != nil {
+		return x.Rating
+	}
+	return ""
+}
+
+func (x *PlaneBase) GetCanFly() bool {
+	if x != nil {
+		return x.CanFly
+	}
+	return false
+}
+
+func (x *PlaneBase) GetMaxSpeed() float64 {
+	if x != nil {
+		return x.MaxSpeed
+	}
+	return 0
+}
+
+type B737 struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Base *PlaneBase `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+}
+
+func (x *B737) Reset() {
+	*x = B737{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aircraft_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *B737) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*B737) ProtoMessage() {}
+
+func (x *B737) ProtoReflect() protoreflect.Message {
+	mi := &file_aircraft_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use B737.ProtoReflect.Descriptor instead.
+func (*B737) Descriptor() ([]byte, []int) {
+	return file_aircraft_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *B737) GetBase() *PlaneBase {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+type Unrelated struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X int64 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+}
+
+func (x *Unrelated) Reset() {
+	*x = Unrelated{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aircraft_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Unrelated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Unrelated) ProtoMessage() {}
+
+func (x *Unrelated) ProtoReflect() protoreflect.Message {
+	mi := &file_aircraft_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Unrelated.ProtoReflect.Descriptor instead.
+func (*Unrelated) Descriptor() ([]byte, []int) {
+	return file_aircraft_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Unrelated) GetX() int64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+var File_aircraft_proto protoreflect.FileDescriptor
+
+var file_aircraft_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x61, 0x69, 0x72, 0x63, 0x72, 0x61, 0x66, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x08, 0x61, 0x69, 0x72, 0x63, 0x72, 0x61, 0x66, 0x74, 0x22, 0x43, 0x0a, 0x05, 0x5a, 0x64,
+	0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x79, 0x65, 0x61, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x04, 0x79, 0x65, 0x61, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x6e, 0x74, 0x68,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6d, 0x6f, 0x6e, 0x74, 0x68, 0x12, 0x10, 0x0a,
+	0x03, 0x64, 0x61, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x64, 0x61, 0x79, 0x22,
+	0x1b, 0x0a, 0x05, 0x5a, 0x64, 0x61, 0x74, 0x61, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x96, 0x01, 0x0a,
+	0x09, 0x50, 0x6c, 0x61, 0x6e, 0x65, 0x42, 0x61, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x27,
+	0x0a, 0x05, 0x68, 0x6f, 0x6d, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x11, 0x2e,
+	0x61, 0x69, 0x72, 0x63, 0x72, 0x61, 0x66, 0x74, 0x2e, 0x41, 0x69, 0x72, 0x70, 0x6f, 0x72, 0x74,
+	0x52, 0x05, 0x68, 0x6f, 0x6d, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e,
+	0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x61, 0x74, 0x69, 0x6e, 0x67, 0x12,
+	0x17, 0x0a, 0x07, 0x63, 0x61, 0x6e, 0x5f, 0x66, 0x6c, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x06, 0x63, 0x61, 0x6e, 0x46, 0x6c, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x61, 0x78, 0x5f,
+	0x73, 0x70, 0x65, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6d, 0x61, 0x78,
+	0x53, 0x70, 0x65, 0x65, 0x64, 0x22, 0x2f, 0x0a, 0x04, 0x42, 0x37, 0x33, 0x37, 0x12, 0x27, 0x0a,
+	0x04, 0x62, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x61, 0x69,
+	0x72, 0x63, 0x72, 0x61, 0x66, 0x74, 0x2e, 0x50, 0x6c, 0x61, 0x6e, 0x65, 0x42, 0x61, 0x73, 0x65,
+	0x52, 0x04, 0x62, 0x61, 0x73, 0x65, 0x22, 0x19, 0x0a, 0x09, 0x55, 0x6e, 0x72, 0x65, 0x6c, 0x61,
+	0x74, 0x65, 0x64, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x01,
+	0x78, 0x2a, 0x46, 0x0a, 0x07, 0x41, 0x69, 0x72, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x0c,
+	0x41, 0x49, 0x52, 0x50, 0x4f, 0x52, 0x54, 0x5f, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0f,
+	0x0a, 0x0b, 0x41, 0x49, 0x52, 0x50, 0x4f, 0x52, 0x54, 0x5f, 0x4a, 0x46, 0x4b, 0x10, 0x01, 0x12,
+	0x0f, 0x0a, 0x0b, 0x41, 0x49, 0x52, 0x50, 0x4f, 0x52, 0x54, 0x5f, 0x4c, 0x41, 0x58, 0x10, 0x02,
+	0x12, 0x07, 0x0a, 0x03, 0x53, 0x46, 0x4f, 0x10, 0x03, 0x42, 0x3d, 0x5a, 0x3b, 0x7a, 0x6f, 0x6d,
+	0x62, 0x69, 0x65, 0x7a, 0x65, 0x6e, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x2f, 0x63, 0x61,
+	0x70, 0x6e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x32, 0x2f, 0x63, 0x6d, 0x64, 0x2f, 0x63, 0x61, 0x70,
+	0x6e, 0x70, 0x70, 0x62, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x61, 0x69,
+	0x72, 0x63, 0x72, 0x61, 0x66, 0x74, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_aircraft_proto_rawDescOnce sync.Once
+	file_aircraft_proto_rawDescData = file_aircraft_proto_rawDesc
+)
+
+func file_aircraft_proto_rawDescGZIP() []byte {
+	file_aircraft_proto_rawDescOnce.Do(func() {
+		file_aircraft_proto_rawDescData = protoimpl.X.CompressGZIP(file_aircraft_proto_rawDescData)
+	})
+	return file_aircraft_proto_rawDescData
+}
+
+var file_aircraft_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_aircraft_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_aircraft_proto_goTypes = []interface{}{
+	(Airport)(0),      // 0: aircraft.Airport
+	(*Zdate)(nil),     // 1: aircraft.Zdate
+	(*Zdata)(nil),     // 2: aircraft.Zdata
+	(*PlaneBase)(nil), // 3: aircraft.PlaneBase
+	(*B737)(nil),      // 4: aircraft.B737
+	(*Unrelated)(nil), // 5: aircraft.Unrelated
+}
+var file_aircraft_proto_depIdxs = []int32{
+	0, // 0: aircraft.PlaneBase.homes:type_name -> aircraft.Airport
+	3, // 1: aircraft.B737.base:type_name -> aircraft.PlaneBase
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_aircraft_proto_init() }
+func file_aircraft_proto_init() {
+	if File_aircraft_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_aircraft_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Zdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aircraft_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Zdata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aircraft_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PlaneBase); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aircraft_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*B737); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aircraft_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Unrelated); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_aircraft_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_aircraft_proto_goTypes,
+		DependencyIndexes: file_aircraft_proto_depIdxs,
+		EnumInfos:         file_aircraft_proto_enumTypes,
+		MessageInfos:      file_aircraft_proto_msgTypes,
+	}.Build()
+	File_aircraft_proto = out.File
+	file_aircraft_proto_rawDesc = nil
+	file_aircraft_proto_goTypes = nil
+	file_aircraft_proto_depIdxs = nil
+}
@@ -0,0 +1,110 @@
+/*
+capnppb generates functions that convert between the Go types generated
+by capnpc-go and protoc-gen-go for equivalent schemas.
+
+It reads a Cap'n Proto code generator request for one schema file and a
+protobuf FileDescriptorSet, such as those produced by
+
+	capnp compile -o- foo.capnp > foo.capnp.req
+	protoc --include_imports -o foo.pb foo.proto
+
+and writes a Go file with a pair of functions for each struct or enum
+that has a protobuf counterpart:
+
+	capnppb -capnp=foo.capnp.req -proto=foo.pb -o foo_bridge.go
+
+Types are matched by their generated Go names and fields by their
+schema names, ignoring case and underscores, so that fooBar matches
+foo_bar.  Enumerants may also carry the enum name as a prefix, as in
+COLOR_RED.  Fields that cannot be converted, like unions and groups,
+are listed in a comment on the generated function.  Only proto3 files
+are supported.
+*/
+package main // import "zombiezen.com/go/capnproto2/cmd/capnppb"
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+func main() {
+	var opts bridgeOptions
+	capnpPath := flag.String("capnp", "", "code generator request file (default stdin)")
+	protoPath := flag.String("proto", "", "protobuf FileDescriptorSet file")
+	outPath := flag.String("o", "", "output file (default stdout)")
+	protoFiles := flag.String("protofiles", "", "comma-separated proto files to convert (default the last file in the set)")
+	flag.StringVar(&opts.pkg, "package", "", "package name of the generated file")
+	flag.StringVar(&opts.capnpImp, "capnpimport", "", "import path of the capnp Go package (default $Go.import)")
+	flag.StringVar(&opts.protoImp, "pbimport", "", "import path of the protobuf Go package (default go_package)")
+	flag.Parse()
+	if *protoPath == "" || opts.pkg == "" {
+		fmt.Fprintln(os.Stderr, "usage: capnppb -proto=FILE -package=NAME [-capnp=FILE] [-o=FILE]")
+		os.Exit(2)
+	}
+	if *protoFiles != "" {
+		opts.protoFiles = strings.Split(*protoFiles, ",")
+	}
+
+	req, err := readRequest(*capnpPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "capnppb: reading capnp request:", err)
+		os.Exit(1)
+	}
+	fds, err := readDescriptorSet(*protoPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "capnppb: reading proto descriptors:", err)
+		os.Exit(1)
+	}
+	src, err := generateBridge(req, fds, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "capnppb:", err)
+		os.Exit(1)
+	}
+	if *outPath == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(*outPath, src, 0666)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "capnppb:", err)
+		os.Exit(1)
+	}
+}
+
+func readRequest(path string) (schema.CodeGeneratorRequest, error) {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return schema.CodeGeneratorRequest{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+	msg, err := capnp.NewDecoder(r).Decode()
+	if err != nil {
+		return schema.CodeGeneratorRequest{}, err
+	}
+	return schema.ReadRootCodeGeneratorRequest(msg)
+}
+
+func readDescriptorSet(path string) (*descriptorpb.FileDescriptorSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fds := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(data, fds); err != nil {
+		return nil, err
+	}
+	return fds, nil
+}
@@ -0,0 +1,31 @@
+/*
+capnpvet reports common misuses of the Cap'n Proto runtime:
+
+	capnprelease  call results that are never closed
+	capnpreset    message data used after Message.Reset
+	capnpcapid    capability IDs used outside their message
+
+It can be run on its own with package patterns, like
+
+	capnpvet ./...
+
+or by go vet:
+
+	go vet -vettool=$(which capnpvet) ./...
+*/
+package main // import "zombiezen.com/go/capnproto2/cmd/capnpvet"
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+	"zombiezen.com/go/capnproto2/analysis/capidcheck"
+	"zombiezen.com/go/capnproto2/analysis/releasecheck"
+	"zombiezen.com/go/capnproto2/analysis/resetcheck"
+)
+
+func main() {
+	multichecker.Main(
+		releasecheck.Analyzer,
+		resetcheck.Analyzer,
+		capidcheck.Analyzer,
+	)
+}
@@ -0,0 +1,316 @@
+package capnp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Column scans read a single data field out of every struct in a list.
+// They are equivalent to calling List.Struct and then reading the field
+// on each element, but the bounds checks are done once up front and the
+// loop reads straight from the segment, which matters when scanning
+// lists with millions of elements.
+//
+// Like the accessors on Struct, column scans return the raw value
+// stored in the message: generated code XORs a field with its default
+// value, so callers scanning a field with a non-zero default must do
+// the same.  Elements too small to contain the field read as zero.
+
+// column returns the address of the field at off in the first element
+// and the distance between elements.  ok is false if no element of the
+// list can contain a field of size sz at off.
+func (p List) column(off DataOffset, sz Size) (base Address, stride Size, ok bool) {
+	if p.seg == nil || p.flags&isBitList != 0 || Size(off)+sz > p.size.DataSize {
+		return 0, 0, false
+	}
+	// The list's bounds were checked when the pointer was read.
+	return p.off.addOffset(off), p.size.totalSize(), true
+}
+
+// Uint8Column returns the uint8 at off in each struct of the list.
+func (p List) Uint8Column(off DataOffset) []uint8 {
+	return p.AppendUint8Column(make([]uint8, 0, p.Len()), off)
+}
+
+// AppendUint8Column appends the uint8 at off in each struct of the list
+// to dst and returns the extended slice.
+func (p List) AppendUint8Column(dst []uint8, off DataOffset) []uint8 {
+	n := p.Len()
+	base, stride, ok := p.column(off, 1)
+	if !ok {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		dst = append(dst, data[a])
+		a += Address(stride)
+	}
+	return dst
+}
+
+// RangeUint8Column calls f with the index and the uint8 at off of each
+// struct in the list, in order.  If f returns false, the scan stops.
+func (p List) RangeUint8Column(off DataOffset, f func(i int, v uint8) bool) {
+	n := p.Len()
+	base, stride, ok := p.column(off, 1)
+	if !ok {
+		for i := 0; i < n; i++ {
+			if !f(i, 0) {
+				return
+			}
+		}
+		return
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		if !f(i, data[a]) {
+			return
+		}
+		a += Address(stride)
+	}
+}
+
+// Uint16Column returns the uint16 at off in each struct of the list.
+func (p List) Uint16Column(off DataOffset) []uint16 {
+	return p.AppendUint16Column(make([]uint16, 0, p.Len()), off)
+}
+
+// AppendUint16Column appends the uint16 at off in each struct of the list
+// to dst and returns the extended slice.
+func (p List) AppendUint16Column(dst []uint16, off DataOffset) []uint16 {
+	n := p.Len()
+	base, stride, ok := p.column(off, 2)
+	if !ok {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		dst = append(dst, binary.LittleEndian.Uint16(data[a:]))
+		a += Address(stride)
+	}
+	return dst
+}
+
+// RangeUint16Column calls f with the index and the uint16 at off of each
+// struct in the list, in order.  If f returns false, the scan stops.
+func (p List) RangeUint16Column(off DataOffset, f func(i int, v uint16) bool) {
+	n := p.Len()
+	base, stride, ok := p.column(off, 2)
+	if !ok {
+		for i := 0; i < n; i++ {
+			if !f(i, 0) {
+				return
+			}
+		}
+		return
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		if !f(i, binary.LittleEndian.Uint16(data[a:])) {
+			return
+		}
+		a += Address(stride)
+	}
+}
+
+// Uint32Column returns the uint32 at off in each struct of the list.
+func (p List) Uint32Column(off DataOffset) []uint32 {
+	return p.AppendUint32Column(make([]uint32, 0, p.Len()), off)
+}
+
+// AppendUint32Column appends the uint32 at off in each struct of the list
+// to dst and returns the extended slice.
+func (p List) AppendUint32Column(dst []uint32, off DataOffset) []uint32 {
+	n := p.Len()
+	base, stride, ok := p.column(off, 4)
+	if !ok {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		dst = append(dst, binary.LittleEndian.Uint32(data[a:]))
+		a += Address(stride)
+	}
+	return dst
+}
+
+// RangeUint32Column calls f with the index and the uint32 at off of each
+// struct in the list, in order.  If f returns false, the scan stops.
+func (p List) RangeUint32Column(off DataOffset, f func(i int, v uint32) bool) {
+	n := p.Len()
+	base, stride, ok := p.column(off, 4)
+	if !ok {
+		for i := 0; i < n; i++ {
+			if !f(i, 0) {
+				return
+			}
+		}
+		return
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		if !f(i, binary.LittleEndian.Uint32(data[a:])) {
+			return
+		}
+		a += Address(stride)
+	}
+}
+
+// Uint64Column returns the uint64 at off in each struct of the list.
+func (p List) Uint64Column(off DataOffset) []uint64 {
+	return p.AppendUint64Column(make([]uint64, 0, p.Len()), off)
+}
+
+// AppendUint64Column appends the uint64 at off in each struct of the list
+// to dst and returns the extended slice.
+func (p List) AppendUint64Column(dst []uint64, off DataOffset) []uint64 {
+	n := p.Len()
+	base, stride, ok := p.column(off, 8)
+	if !ok {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		dst = append(dst, binary.LittleEndian.Uint64(data[a:]))
+		a += Address(stride)
+	}
+	return dst
+}
+
+// RangeUint64Column calls f with the index and the uint64 at off of each
+// struct in the list, in order.  If f returns false, the scan stops.
+func (p List) RangeUint64Column(off DataOffset, f func(i int, v uint64) bool) {
+	n := p.Len()
+	base, stride, ok := p.column(off, 8)
+	if !ok {
+		for i := 0; i < n; i++ {
+			if !f(i, 0) {
+				return
+			}
+		}
+		return
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		if !f(i, binary.LittleEndian.Uint64(data[a:])) {
+			return
+		}
+		a += Address(stride)
+	}
+}
+
+// Float32Column returns the float32 at off in each struct of the list.
+func (p List) Float32Column(off DataOffset) []float32 {
+	return p.AppendFloat32Column(make([]float32, 0, p.Len()), off)
+}
+
+// AppendFloat32Column appends the float32 at off in each struct of the list
+// to dst and returns the extended slice.
+func (p List) AppendFloat32Column(dst []float32, off DataOffset) []float32 {
+	n := p.Len()
+	base, stride, ok := p.column(off, 4)
+	if !ok {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		dst = append(dst, math.Float32frombits(binary.LittleEndian.Uint32(data[a:])))
+		a += Address(stride)
+	}
+	return dst
+}
+
+// RangeFloat32Column calls f with the index and the float32 at off of each
+// struct in the list, in order.  If f returns false, the scan stops.
+func (p List) RangeFloat32Column(off DataOffset, f func(i int, v float32) bool) {
+	n := p.Len()
+	base, stride, ok := p.column(off, 4)
+	if !ok {
+		for i := 0; i < n; i++ {
+			if !f(i, 0) {
+				return
+			}
+		}
+		return
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		if !f(i, math.Float32frombits(binary.LittleEndian.Uint32(data[a:]))) {
+			return
+		}
+		a += Address(stride)
+	}
+}
+
+// Float64Column returns the float64 at off in each struct of the list.
+func (p List) Float64Column(off DataOffset) []float64 {
+	return p.AppendFloat64Column(make([]float64, 0, p.Len()), off)
+}
+
+// AppendFloat64Column appends the float64 at off in each struct of the list
+// to dst and returns the extended slice.
+func (p List) AppendFloat64Column(dst []float64, off DataOffset) []float64 {
+	n := p.Len()
+	base, stride, ok := p.column(off, 8)
+	if !ok {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		dst = append(dst, math.Float64frombits(binary.LittleEndian.Uint64(data[a:])))
+		a += Address(stride)
+	}
+	return dst
+}
+
+// RangeFloat64Column calls f with the index and the float64 at off of each
+// struct in the list, in order.  If f returns false, the scan stops.
+func (p List) RangeFloat64Column(off DataOffset, f func(i int, v float64) bool) {
+	n := p.Len()
+	base, stride, ok := p.column(off, 8)
+	if !ok {
+		for i := 0; i < n; i++ {
+			if !f(i, 0) {
+				return
+			}
+		}
+		return
+	}
+	data := p.seg.data
+	a := base
+	for i := 0; i < n; i++ {
+		if !f(i, math.Float64frombits(binary.LittleEndian.Uint64(data[a:]))) {
+			return
+		}
+		a += Address(stride)
+	}
+}
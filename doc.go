@@ -380,5 +380,28 @@ implemented Calculator:
 A note about message ordering: when implementing a server method, you
 are responsible for acknowledging delivery of a method call.  Failure to
 do so can cause deadlocks.  See the server.Ack function for more details.
+
+Mixing generated code
+
+Code generated by the upstream capnpc-go uses the same runtime API as
+code generated by this module's capnpc-go, so the two can be built into
+one binary, which lets a large codebase move its packages over one at a
+time.  Packages generated from the same schema file by both generators
+may be linked together: the second package's schema registration is
+ignored (see schemas.Registry.Register).
+
+The generated types of the two packages are distinct Go types with the
+same underlying type, so a Go conversion moves a value from one package
+to the other without copying:
+
+	var oldParams oldpkg.Calculator_evaluate_Params
+	var oldCalc oldpkg.Calculator
+	params := newpkg.Calculator_evaluate_Params(oldParams)
+	calc := newpkg.Calculator(oldCalc)
+
+The same applies to enums, lists, and promises.  Features that this
+module's capnpc-go generates only when asked to, such as the -json and
+-verify flags, are not available on the upstream package's types until
+they are converted.
 */
 package capnp // import "zombiezen.com/go/capnproto2"
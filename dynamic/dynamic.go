@@ -0,0 +1,354 @@
+// Package dynamic implements Cap'n Proto interfaces from schemas that
+// are loaded at runtime instead of compiled into generated code.
+//
+// A Server dispatches calls to functions registered for each interface
+// ID, using a dispatch table built from CodeGeneratorRequest messages,
+// like the ones that the capnp tool writes.  Loading new schemas swaps
+// the table atomically, so a schema-driven gateway can pick up schema
+// changes without restarting or dropping calls.  The functions get the
+// registry of the schemas that each call was dispatched with, which
+// they can pass to encoding/json, encoding/text, or encoding/walk to
+// read the parameters and write the results.
+package dynamic // import "zombiezen.com/go/capnproto2/dynamic"
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// A Method is a method of an interface in a loaded schema.
+type Method struct {
+	// Method has the interface's display name and the method's name
+	// filled in from the schema.
+	capnp.Method
+
+	ParamsTypeID  uint64
+	ResultsTypeID uint64
+	ResultsSize   capnp.ObjectSize
+
+	// Registry holds the schemas that the method was loaded from.
+	Registry *schemas.Registry
+}
+
+// A Func implements the methods of an interface.  It is called with the
+// method that was called, which may be inherited from a superclass of
+// the interface it was registered for.  server.Ack and server.TailCall
+// may be used with options as in any server method.
+type Func func(ctx context.Context, m *Method, options capnp.CallOptions, params, results capnp.Struct) error
+
+// A Server is a capnp.Client that dispatches calls with schemas loaded
+// at runtime.  Calls are delivered in order, as with the server
+// package, even across loads.  It is safe to use from multiple
+// goroutines.
+type Server struct {
+	options []server.Option
+
+	// mu is held for reading while a call is delivered, so that a
+	// replaced table isn't closed while it is being called.
+	mu     sync.RWMutex
+	funcs  map[uint64]Func
+	set    *schemaSet
+	client capnp.Client // nil until the first load
+	closed bool
+}
+
+// A schemaSet is the interfaces and struct sizes of loaded schemas.
+type schemaSet struct {
+	reg        *schemas.Registry
+	interfaces map[uint64]schema.Node
+	sizes      map[uint64]capnp.ObjectSize
+}
+
+// New returns a server with no schemas loaded.  The options apply to
+// each dispatch table that the server builds.
+func New(options ...server.Option) *Server {
+	return &Server{options: options}
+}
+
+// Handle registers f to implement the interface with the given ID,
+// replacing any previous function.  If f is nil, the function for the
+// interface is removed.  The change applies to calls made after Handle
+// returns.
+func (s *Server) Handle(interfaceID uint64, f Func) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	old, hadOld := s.funcs[interfaceID]
+	if f == nil {
+		delete(s.funcs, interfaceID)
+	} else {
+		if s.funcs == nil {
+			s.funcs = make(map[uint64]Func)
+		}
+		s.funcs[interfaceID] = f
+	}
+	if s.set == nil {
+		return nil
+	}
+	if err := s.switchLocked(s.set); err != nil {
+		if hadOld {
+			s.funcs[interfaceID] = old
+		} else {
+			delete(s.funcs, interfaceID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Load replaces the server's schemas with the ones in reqs, each of
+// which is a CodeGeneratorRequest message in the standard stream
+// framing.  Calls made after Load returns are dispatched with the new
+// schemas; calls that were delivered before keep running.  If Load
+// returns an error, the server keeps its previous schemas.
+func (s *Server) Load(reqs ...[]byte) error {
+	set, err := readSchemaSet(reqs)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	return s.switchLocked(set)
+}
+
+// switchLocked builds a dispatch table for set and s.funcs and makes
+// it current.  The caller must be holding onto s.mu for writing.
+func (s *Server) switchLocked(set *schemaSet) error {
+	methods, err := set.methods(s.funcs)
+	if err != nil {
+		return err
+	}
+	old := s.client
+	s.set = set
+	s.client = server.New(methods, nil, s.options...)
+	if old != nil {
+		// Every call on old has been delivered, since s.mu is held, so
+		// closing old doesn't reject any of them.
+		old.Close()
+	}
+	return nil
+}
+
+// Registry returns the registry of the server's current schemas, or
+// nil if none have been loaded.
+func (s *Server) Registry() *schemas.Registry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.set == nil {
+		return nil
+	}
+	return s.set.reg
+}
+
+// Call dispatches call with the current schemas.  Calls to interfaces
+// or methods that aren't in the schemas, or that have no function,
+// fail with capnp.ErrUnimplemented.
+func (s *Server) Call(call *capnp.Call) capnp.Answer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return capnp.ErrorAnswer(errClosed)
+	}
+	if s.client == nil {
+		return capnp.ErrorAnswer(&capnp.MethodError{
+			Method: &call.Method,
+			Err:    capnp.ErrUnimplemented,
+		})
+	}
+	return s.client.Call(call)
+}
+
+// Close closes the current dispatch table.  Calls that were delivered
+// keep running, but subsequent calls fail.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	s.closed = true
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Close()
+}
+
+// readSchemaSet parses CodeGeneratorRequest messages.
+func readSchemaSet(reqs [][]byte) (*schemaSet, error) {
+	set := &schemaSet{
+		reg:        new(schemas.Registry),
+		interfaces: make(map[uint64]schema.Node),
+		sizes:      make(map[uint64]capnp.ObjectSize),
+	}
+	for i, data := range reqs {
+		msg, err := capnp.Unmarshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("dynamic: schema %d: %v", i, err)
+		}
+		req, err := schema.ReadRootCodeGeneratorRequest(msg)
+		if err != nil {
+			return nil, fmt.Errorf("dynamic: schema %d: %v", i, err)
+		}
+		nodes, err := req.Nodes()
+		if err != nil {
+			return nil, fmt.Errorf("dynamic: schema %d: %v", i, err)
+		}
+		ids := make([]uint64, 0, nodes.Len())
+		seen := make(map[uint64]bool, nodes.Len())
+		for j := 0; j < nodes.Len(); j++ {
+			n := nodes.At(j)
+			if !seen[n.Id()] {
+				seen[n.Id()] = true
+				ids = append(ids, n.Id())
+			}
+			switch n.Which() {
+			case schema.Node_Which_structNode:
+				set.sizes[n.Id()] = capnp.ObjectSize{
+					DataSize:     capnp.Size(n.StructNode().DataWordCount()) * 8,
+					PointerCount: n.StructNode().PointerCount(),
+				}
+			case schema.Node_Which_interface:
+				set.interfaces[n.Id()] = n
+			}
+		}
+		if err := set.reg.Register(&schemas.Schema{Bytes: data, Nodes: ids}); err != nil {
+			return nil, fmt.Errorf("dynamic: schema %d: %v", i, err)
+		}
+	}
+	return set, nil
+}
+
+// methods returns the server methods of the interfaces in funcs that
+// are in set.  A method that an interface inherits is dispatched to
+// the interface's function unless its superclass has a function too.
+func (set *schemaSet) methods(funcs map[uint64]Func) ([]server.Method, error) {
+	type methodKey struct {
+		interfaceID uint64
+		methodID    uint16
+	}
+	ids := make([]uint64, 0, len(funcs))
+	for id := range funcs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var methods []server.Method
+	added := make(map[methodKey]bool)
+	// Interfaces' own methods come first so that they take precedence
+	// over inherited ones.
+	for _, inherit := range []bool{false, true} {
+		for _, id := range ids {
+			n, ok := set.interfaces[id]
+			if !ok {
+				continue
+			}
+			f := funcs[id]
+			ifaces := []schema.Node{n}
+			if inherit {
+				var err error
+				if ifaces, err = set.superclasses(n); err != nil {
+					return nil, err
+				}
+			}
+			for _, iface := range ifaces {
+				ms, err := set.interfaceMethods(iface, f)
+				if err != nil {
+					return nil, err
+				}
+				for _, m := range ms {
+					k := methodKey{m.InterfaceID, m.MethodID}
+					if !added[k] {
+						added[k] = true
+						methods = append(methods, m)
+					}
+				}
+			}
+		}
+	}
+	return methods, nil
+}
+
+// superclasses returns all of the ancestors of the interface n.
+func (set *schemaSet) superclasses(n schema.Node) ([]schema.Node, error) {
+	var supers []schema.Node
+	seen := map[uint64]bool{n.Id(): true}
+	for queue := []schema.Node{n}; len(queue) > 0; queue = queue[1:] {
+		sc, err := queue[0].Interface().Superclasses()
+		if err != nil {
+			return nil, fmt.Errorf("dynamic: interface @%#x: %v", queue[0].Id(), err)
+		}
+		for i := 0; i < sc.Len(); i++ {
+			id := sc.At(i).Id()
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			super, ok := set.interfaces[id]
+			if !ok {
+				return nil, fmt.Errorf("dynamic: superclass @%#x of @%#x not in schemas", id, n.Id())
+			}
+			supers = append(supers, super)
+			queue = append(queue, super)
+		}
+	}
+	return supers, nil
+}
+
+// interfaceMethods returns the server methods of the interface n that
+// call f.
+func (set *schemaSet) interfaceMethods(n schema.Node, f Func) ([]server.Method, error) {
+	name, err := n.DisplayName()
+	if err != nil {
+		return nil, fmt.Errorf("dynamic: interface @%#x: %v", n.Id(), err)
+	}
+	ms, err := n.Interface().Methods()
+	if err != nil {
+		return nil, fmt.Errorf("dynamic: %s: %v", name, err)
+	}
+	methods := make([]server.Method, ms.Len())
+	for i := range methods {
+		sm := ms.At(i)
+		mname, err := sm.Name()
+		if err != nil {
+			return nil, fmt.Errorf("dynamic: %s method %d: %v", name, i, err)
+		}
+		size, ok := set.sizes[sm.ResultStructType()]
+		if !ok {
+			return nil, fmt.Errorf("dynamic: results of %s.%s not in schemas", name, mname)
+		}
+		m := &Method{
+			Method: capnp.Method{
+				InterfaceID:   n.Id(),
+				MethodID:      uint16(i),
+				InterfaceName: name,
+				MethodName:    mname,
+			},
+			ParamsTypeID:  sm.ParamStructType(),
+			ResultsTypeID: sm.ResultStructType(),
+			ResultsSize:   size,
+			Registry:      set.reg,
+		}
+		methods[i] = server.Method{
+			Method:      m.Method,
+			ResultsSize: size,
+			Impl: func(ctx context.Context, options capnp.CallOptions, params, results capnp.Struct) error {
+				return f(ctx, m, options, params, results)
+			},
+		}
+	}
+	return methods, nil
+}
+
+var errClosed = errors.New("dynamic: server closed")
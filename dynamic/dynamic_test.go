@@ -0,0 +1,98 @@
+package dynamic_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/dynamic"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+// echoFunc implements Echo by returning the method's name followed by
+// the input.
+func echoFunc(ctx context.Context, m *dynamic.Method, options capnp.CallOptions, params, results capnp.Struct) error {
+	in, err := air.Echo_echo_Params{Struct: params}.In()
+	if err != nil {
+		return err
+	}
+	return air.Echo_echo_Results{Struct: results}.SetOut(m.MethodName + " " + in)
+}
+
+func callEcho(c capnp.Client, in string) (string, error) {
+	res, err := air.Echo{Client: c}.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn(in)
+	}).Struct()
+	if err != nil {
+		return "", err
+	}
+	return res.Out()
+}
+
+// shoutSchema returns a CodeGeneratorRequest for a new version of Echo
+// that renames its method to shout.
+func shoutSchema(t *testing.T) []byte {
+	const paramsID, resultsID = 0xe9f6a1dc51b6c2a1, 0xe9f6a1dc51b6c2a2
+	msg, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	req, _ := schema.NewRootCodeGeneratorRequest(seg)
+	nodes, _ := req.NewNodes(3)
+	iface := nodes.At(0)
+	iface.SetId(air.Echo_TypeID)
+	iface.SetDisplayName("v2.capnp:Echo")
+	iface.SetInterface()
+	methods, _ := iface.Interface().NewMethods(1)
+	methods.At(0).SetName("shout")
+	methods.At(0).SetParamStructType(paramsID)
+	methods.At(0).SetResultStructType(resultsID)
+	for i, id := range []uint64{paramsID, resultsID} {
+		n := nodes.At(i + 1)
+		n.SetId(id)
+		n.SetStructNode()
+		n.StructNode().SetPointerCount(1)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestServer(t *testing.T) {
+	s := dynamic.New()
+	defer s.Close()
+	if err := s.Handle(air.Echo_TypeID, echoFunc); err != nil {
+		t.Fatal("Handle:", err)
+	}
+	if _, err := callEcho(s, "hi"); err == nil {
+		t.Error("call before Load succeeded")
+	}
+
+	if err := s.Load(schemas.Find(air.Echo_TypeID)); err != nil {
+		t.Fatal("Load:", err)
+	}
+	if out, err := callEcho(s, "hi"); err != nil || out != "echo hi" {
+		t.Errorf("call = %q, %v; want \"echo hi\", <nil>", out, err)
+	}
+
+	if err := s.Load(shoutSchema(t)); err != nil {
+		t.Fatal("Load new schema:", err)
+	}
+	if out, err := callEcho(s, "hi"); err != nil || out != "shout hi" {
+		t.Errorf("call after reload = %q, %v; want \"shout hi\", <nil>", out, err)
+	}
+	if err := s.Load([]byte("junk")); err == nil {
+		t.Error("Load of bad schema succeeded")
+	}
+	if out, err := callEcho(s, "hi"); err != nil || out != "shout hi" {
+		t.Errorf("call after failed reload = %q, %v; want \"shout hi\", <nil>", out, err)
+	}
+
+	if err := s.Handle(air.Echo_TypeID, nil); err != nil {
+		t.Fatal("Handle(nil):", err)
+	}
+	if _, err := callEcho(s, "hi"); err == nil {
+		t.Error("call after removing function succeeded")
+	}
+}
@@ -0,0 +1,187 @@
+// Package anystruct stores structs of registered types in AnyPointer
+// fields, in the manner of encoding/gob's Register.
+//
+// An AnyPointer field records nothing about the type of the object it
+// points to, so a reader of a heterogeneous payload has to learn the
+// type some other way.  This package stores the struct in a small
+// wrapper struct that also holds its type ID:
+//
+//	struct AnyStruct {
+//	  typeId @0 :UInt64;
+//	  value @1 :AnyPointer;
+//	}
+//
+// Types are registered with the generated wrapper for their structs,
+// and Decode returns a value of the wrapper type:
+//
+//	anystruct.Register(Zdate_TypeID, Zdate{})
+//	...
+//	p, err := anystruct.New(seg, date)
+//	if err != nil {
+//		return err
+//	}
+//	if err := v.SetAnyPointerPtr(p); err != nil {
+//		return err
+//	}
+//	...
+//	x, err := anystruct.Decode(v.AnyPointerPtr())
+//	switch x := x.(type) {
+//	case Zdate:
+//		...
+//	}
+package anystruct // import "zombiezen.com/go/capnproto2/encoding/anystruct"
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// wrapperSize is the size of the struct that holds a type ID and a
+// pointer to the value.
+var wrapperSize = capnp.ObjectSize{DataSize: 8, PointerCount: 1}
+
+// A Registry maps struct type IDs to the Go types that wrap them.  The
+// zero value is an empty registry.  It is safe to use from multiple
+// goroutines.
+type Registry struct {
+	mu     sync.RWMutex
+	byID   map[uint64]reflect.Type
+	byType map[reflect.Type]uint64
+}
+
+// DefaultRegistry is the registry used by the package-level functions.
+var DefaultRegistry = new(Registry)
+
+// Register registers v's type as the wrapper for structs of the type
+// typeID.  v must be a struct, like the ones generated by capnpc-go,
+// that embeds capnp.Struct.  It is an error to register a type ID or a
+// wrapper type twice.
+func (reg *Registry) Register(typeID uint64, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if _, ok := structField(t); !ok {
+		return fmt.Errorf("anystruct: %T does not embed capnp.Struct", v)
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if prev, dup := reg.byID[typeID]; dup {
+		return fmt.Errorf("anystruct: type %#x already registered as %v", typeID, prev)
+	}
+	if prev, dup := reg.byType[t]; dup {
+		return fmt.Errorf("anystruct: %v already registered for type %#x", t, prev)
+	}
+	if reg.byID == nil {
+		reg.byID = make(map[uint64]reflect.Type)
+		reg.byType = make(map[reflect.Type]uint64)
+	}
+	reg.byID[typeID] = t
+	reg.byType[t] = typeID
+	return nil
+}
+
+// New copies v, a value of a registered wrapper type, into seg along
+// with its type ID.  The returned pointer may be stored in an
+// AnyPointer field.
+func (reg *Registry) New(seg *capnp.Segment, v interface{}) (capnp.Ptr, error) {
+	reg.mu.RLock()
+	typeID, ok := reg.byType[reflect.TypeOf(v)]
+	reg.mu.RUnlock()
+	if !ok {
+		return capnp.Ptr{}, fmt.Errorf("anystruct: %T not registered", v)
+	}
+	i, _ := structField(reflect.TypeOf(v))
+	s := reflect.ValueOf(v).Field(i).Interface().(capnp.Struct)
+	w, err := capnp.NewStruct(seg, wrapperSize)
+	if err != nil {
+		return capnp.Ptr{}, err
+	}
+	w.SetUint64(0, typeID)
+	if err := w.SetPtr(0, s.ToPtr()); err != nil {
+		return capnp.Ptr{}, err
+	}
+	return w.ToPtr(), nil
+}
+
+// Decode returns the struct that p, a pointer created by New, refers
+// to, as a value of the wrapper type registered for its type ID.  If p
+// is null, Decode returns nil.
+func (reg *Registry) Decode(p capnp.Ptr) (interface{}, error) {
+	if !p.IsValid() {
+		return nil, nil
+	}
+	typeID, s, err := unwrap(p)
+	if err != nil {
+		return nil, err
+	}
+	reg.mu.RLock()
+	t, ok := reg.byID[typeID]
+	reg.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("anystruct: type %#x not registered", typeID)
+	}
+	i, _ := structField(t)
+	v := reflect.New(t).Elem()
+	v.Field(i).Set(reflect.ValueOf(s))
+	return v.Interface(), nil
+}
+
+// Register registers a wrapper type in the default registry.  Like
+// gob.Register, it is meant to be called during initialization, so it
+// panics if the registration fails.
+func Register(typeID uint64, v interface{}) {
+	if err := DefaultRegistry.Register(typeID, v); err != nil {
+		panic(err)
+	}
+}
+
+// New copies v into seg along with its type ID, using the default
+// registry.
+func New(seg *capnp.Segment, v interface{}) (capnp.Ptr, error) {
+	return DefaultRegistry.New(seg, v)
+}
+
+// Decode returns the struct that p refers to, using the default
+// registry.
+func Decode(p capnp.Ptr) (interface{}, error) {
+	return DefaultRegistry.Decode(p)
+}
+
+// TypeID returns the type ID stored in p, a pointer created by New,
+// without consulting a registry.
+func TypeID(p capnp.Ptr) (uint64, error) {
+	typeID, _, err := unwrap(p)
+	return typeID, err
+}
+
+func unwrap(p capnp.Ptr) (typeID uint64, s capnp.Struct, err error) {
+	w := p.Struct()
+	if !w.IsValid() {
+		return 0, capnp.Struct{}, errors.New("anystruct: pointer is not a struct")
+	}
+	if w.Size().DataSize < wrapperSize.DataSize {
+		return 0, capnp.Struct{}, errors.New("anystruct: struct has no type ID")
+	}
+	vp, err := w.Ptr(0)
+	if err != nil {
+		return 0, capnp.Struct{}, err
+	}
+	return w.Uint64(0), vp.Struct(), nil
+}
+
+// structField returns the index of the embedded capnp.Struct field of
+// the struct type t.
+func structField(t reflect.Type) (int, bool) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return 0, false
+	}
+	st := reflect.TypeOf(capnp.Struct{})
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == st {
+			return i, true
+		}
+	}
+	return 0, false
+}
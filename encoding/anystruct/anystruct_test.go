@@ -0,0 +1,105 @@
+package anystruct_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/anystruct"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/std/capnp/schema"
+)
+
+func TestRoundTrip(t *testing.T) {
+	reg := new(anystruct.Registry)
+	if err := reg.Register(air.Zdate_TypeID, air.Zdate{}); err != nil {
+		t.Fatal("Register(Zdate):", err)
+	}
+	if err := reg.Register(air.PlaneBase_TypeID, air.PlaneBase{}); err != nil {
+		t.Fatal("Register(PlaneBase):", err)
+	}
+
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	v, err := schema.NewRootValue(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Build the date in another message, so New has to copy it.
+	_, dseg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	d, err := air.NewRootZdate(dseg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetYear(2016)
+	p, err := reg.New(seg, d)
+	if err != nil {
+		t.Fatal("New:", err)
+	}
+	if err := v.SetAnyPointerPtr(p); err != nil {
+		t.Fatal(err)
+	}
+
+	ap, err := v.AnyPointerPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := anystruct.TypeID(ap); err != nil || id != air.Zdate_TypeID {
+		t.Errorf("TypeID = %#x, %v; want %#x", id, err, uint64(air.Zdate_TypeID))
+	}
+	x, err := reg.Decode(ap)
+	if err != nil {
+		t.Fatal("Decode:", err)
+	}
+	got, ok := x.(air.Zdate)
+	if !ok {
+		t.Fatalf("Decode = %T; want air.Zdate", x)
+	}
+	if got.Year() != 2016 {
+		t.Errorf("Year() = %d; want 2016", got.Year())
+	}
+	if x, err := reg.Decode(capnp.Ptr{}); x != nil || err != nil {
+		t.Errorf("Decode(null) = %v, %v; want nil, nil", x, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	reg := new(anystruct.Registry)
+	if err := reg.Register(air.Zdate_TypeID, air.Zdate{}); err != nil {
+		t.Fatal("Register:", err)
+	}
+	if err := reg.Register(air.Zdate_TypeID, air.PlaneBase{}); err == nil {
+		t.Error("Register of duplicate type ID succeeded")
+	}
+	if err := reg.Register(air.PlaneBase_TypeID, air.Zdate{}); err == nil {
+		t.Error("Register of duplicate wrapper type succeeded")
+	}
+	if err := reg.Register(air.PlaneBase_TypeID, 42); err == nil {
+		t.Error("Register of non-wrapper type succeeded")
+	}
+
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	b, err := air.NewRootPlaneBase(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.New(seg, b); err == nil {
+		t.Error("New of unregistered type succeeded")
+	}
+	other := new(anystruct.Registry)
+	if err := other.Register(air.PlaneBase_TypeID, air.PlaneBase{}); err != nil {
+		t.Fatal("Register:", err)
+	}
+	p, err := other.New(seg, b)
+	if err != nil {
+		t.Fatal("New:", err)
+	}
+	if _, err := reg.Decode(p); err == nil {
+		t.Error("Decode of unregistered type ID succeeded")
+	}
+	text, err := capnp.NewText(seg, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Decode(text.List.ToPtr()); err == nil {
+		t.Error("Decode of a list succeeded")
+	}
+}
@@ -0,0 +1,734 @@
+// Package arrowcol converts lists of Cap'n Proto structs to and from
+// record batches in the Apache Arrow columnar format, based on a
+// schema.
+//
+// Each scalar, enum, Text, and Data field of the struct becomes a
+// column.  The fields of groups are flattened into columns named by
+// their dotted path, like "grp.first", and a struct or group with a
+// union gets a UInt16 column named "which" (or "grp.which") that holds
+// the union's discriminant.  Union members are nullable: a member's
+// value is null in the rows where the union holds another member.
+// Text and Data columns are nullable too, since their pointers may be
+// null.  Enums are stored as their UInt16 values.  Void, struct, list,
+// interface, and AnyPointer fields have no column.
+//
+// The columns of a Record hold the buffers that the Arrow format
+// specifies for their types, laid out as the format requires, so a
+// Record can be handed to an Arrow implementation without copying.  In
+// the Go implementation, for example, wrap each column's buffers with
+// array.NewData.  This package does not depend on an Arrow
+// implementation itself.
+package arrowcol // import "zombiezen.com/go/capnproto2/encoding/arrowcol"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+// A Type is the Arrow type of a column.
+type Type int
+
+// Column types.
+const (
+	Bool Type = 1 + iota
+	Int8
+	Int16
+	Int32
+	Int64
+	Uint8
+	Uint16
+	Uint32
+	Uint64
+	Float32
+	Float64
+	Utf8
+	Binary
+)
+
+var typeNames = [...]string{
+	Bool:    "bool",
+	Int8:    "int8",
+	Int16:   "int16",
+	Int32:   "int32",
+	Int64:   "int64",
+	Uint8:   "uint8",
+	Uint16:  "uint16",
+	Uint32:  "uint32",
+	Uint64:  "uint64",
+	Float32: "float32",
+	Float64: "float64",
+	Utf8:    "utf8",
+	Binary:  "binary",
+}
+
+// String returns the name that Arrow uses for t.
+func (t Type) String() string {
+	if t <= 0 || int(t) >= len(typeNames) {
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+	return typeNames[t]
+}
+
+// width returns the size in bytes of a value of a fixed-width type, or
+// 0 for Bool, Utf8, and Binary.
+func (t Type) width() int {
+	switch t {
+	case Int8, Uint8:
+		return 1
+	case Int16, Uint16:
+		return 2
+	case Int32, Uint32, Float32:
+		return 4
+	case Int64, Uint64, Float64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// A Field describes a column of a Record.
+type Field struct {
+	Name     string
+	Type     Type
+	Nullable bool
+}
+
+// A Column holds the Arrow buffers of one column of a Record.
+type Column struct {
+	// Validity is the column's validity bitmap: bit i, counting from the
+	// least significant bit of the first byte, is set if row i is not
+	// null.  It is nil if no rows are null.
+	Validity []byte
+
+	// Offsets holds the little-endian int32 offset into Values of each
+	// row's value, followed by the end of the last value.  It is only
+	// used by Utf8 and Binary columns.
+	Offsets []byte
+
+	// Values holds the little-endian values of a fixed-width column, the
+	// bitmap of a Bool column, or the concatenated values of a Utf8 or
+	// Binary column.
+	Values []byte
+
+	// NullCount is the number of null rows.
+	NullCount int
+}
+
+// A Record is a batch of rows stored as columns of equal length.
+type Record struct {
+	Fields  []Field
+	Columns []Column
+	NumRows int
+}
+
+// Index returns the index of the column with the given name, or -1 if
+// the record has no such column.
+func (r *Record) Index(name string) int {
+	for i := range r.Fields {
+		if r.Fields[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsNull reports whether the value in the given row of column col is
+// null.
+func (r *Record) IsNull(col, row int) bool {
+	v := r.Columns[col].Validity
+	return v != nil && v[row/8]&(1<<uint(row%8)) == 0
+}
+
+// Value returns the value in the given row of column col as a bool,
+// int64, uint64, float64, string, or []byte, or nil if it is null.
+// Value is meant for inspecting records; it panics if the column's
+// buffers are too short.
+func (r *Record) Value(col, row int) interface{} {
+	if r.IsNull(col, row) {
+		return nil
+	}
+	c := &r.Columns[col]
+	t := r.Fields[col].Type
+	switch t {
+	case Bool:
+		return c.Values[row/8]&(1<<uint(row%8)) != 0
+	case Utf8:
+		return string(c.bytesAt(row))
+	case Binary:
+		return c.bytesAt(row)
+	}
+	v := c.bitsAt(t, row)
+	switch t {
+	case Int8:
+		return int64(int8(v))
+	case Int16:
+		return int64(int16(v))
+	case Int32:
+		return int64(int32(v))
+	case Int64:
+		return int64(v)
+	case Float32:
+		return float64(math.Float32frombits(uint32(v)))
+	case Float64:
+		return math.Float64frombits(v)
+	default:
+		return v
+	}
+}
+
+// bitsAt returns the value in row of a fixed-width column.
+func (c *Column) bitsAt(t Type, row int) uint64 {
+	w := t.width()
+	b := c.Values[row*w : (row+1)*w]
+	switch w {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	default:
+		return binary.LittleEndian.Uint64(b)
+	}
+}
+
+// bytesAt returns the value in row of a Utf8 or Binary column.
+func (c *Column) bytesAt(row int) []byte {
+	start := binary.LittleEndian.Uint32(c.Offsets[row*4:])
+	end := binary.LittleEndian.Uint32(c.Offsets[(row+1)*4:])
+	return c.Values[start:end:end]
+}
+
+// check returns an error if c's buffers are too short for n rows of
+// type t or if its offsets are out of order.
+func (c *Column) check(t Type, n int) error {
+	if c.Validity != nil && len(c.Validity) < (n+7)/8 {
+		return errShortBuffer
+	}
+	switch t {
+	case Bool:
+		if len(c.Values) < (n+7)/8 {
+			return errShortBuffer
+		}
+	case Utf8, Binary:
+		if len(c.Offsets) < (n+1)*4 {
+			return errShortBuffer
+		}
+		prev := uint32(0)
+		for i := 0; i <= n; i++ {
+			off := binary.LittleEndian.Uint32(c.Offsets[i*4:])
+			if off < prev || int64(off) > int64(len(c.Values)) {
+				return errors.New("arrowcol: column offsets out of range")
+			}
+			prev = off
+		}
+	default:
+		if len(c.Values) < n*t.width() {
+			return errShortBuffer
+		}
+	}
+	return nil
+}
+
+// A Converter converts between struct lists and records.  The zero
+// value uses the default schema registry.  A Converter is not safe to
+// use from multiple goroutines.
+type Converter struct {
+	nodes  nodemap.Map
+	leaves map[uint64][]leaf
+}
+
+// UseRegistry changes the registry that the converter consults for
+// schemas from the default registry.
+func (c *Converter) UseRegistry(reg *schemas.Registry) {
+	c.nodes.UseRegistry(reg)
+	c.leaves = nil
+}
+
+// FromList converts a list of structs of the given type to a record
+// using the default schema registry.
+func FromList(typeID uint64, l capnp.List) (*Record, error) {
+	return new(Converter).FromList(typeID, l)
+}
+
+// ToList converts a record to a list of structs of the given type,
+// allocated in s, using the default schema registry.
+func ToList(s *capnp.Segment, typeID uint64, r *Record) (capnp.List, error) {
+	return new(Converter).ToList(s, typeID, r)
+}
+
+// Fields returns the columns of a record for structs of the given type.
+func (c *Converter) Fields(typeID uint64) ([]Field, error) {
+	leaves, err := c.structLeaves(typeID)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]Field, len(leaves))
+	for i := range leaves {
+		fields[i] = leaves[i].field
+	}
+	return fields, nil
+}
+
+// FromList converts a list of structs of the given type to a record.
+// Default values are applied as the generated accessors would: a field
+// that holds its default value is stored as that value.
+func (c *Converter) FromList(typeID uint64, l capnp.List) (*Record, error) {
+	leaves, err := c.structLeaves(typeID)
+	if err != nil {
+		return nil, err
+	}
+	n := l.Len()
+	builders := make([]builder, len(leaves))
+	for i := range builders {
+		builders[i].init(leaves[i].field.Type, n)
+	}
+	for row := 0; row < n; row++ {
+		s := l.Struct(row)
+		for i := range leaves {
+			if err := leaves[i].read(&builders[i], s); err != nil {
+				return nil, fmt.Errorf("arrowcol: row %d, column %s: %v", row, leaves[i].field.Name, err)
+			}
+		}
+	}
+	r := &Record{
+		Fields:  make([]Field, len(leaves)),
+		Columns: make([]Column, len(leaves)),
+		NumRows: n,
+	}
+	for i := range leaves {
+		r.Fields[i] = leaves[i].field
+		r.Columns[i] = builders[i].finish()
+	}
+	return r, nil
+}
+
+// ToList converts a record to a list of structs of the given type,
+// allocated in s.  Columns are matched to fields by name: columns that
+// do not name a field are ignored, and fields without a column are left
+// at their defaults.  A column whose type differs from the field's, or
+// a non-null value for a union member that the row's discriminant does
+// not select, is an error.
+func (c *Converter) ToList(s *capnp.Segment, typeID uint64, r *Record) (capnp.List, error) {
+	n, err := c.findStruct(typeID)
+	if err != nil {
+		return capnp.List{}, err
+	}
+	leaves, err := c.structLeaves(typeID)
+	if err != nil {
+		return capnp.List{}, err
+	}
+	if len(r.Columns) != len(r.Fields) {
+		return capnp.List{}, errors.New("arrowcol: record has different numbers of fields and columns")
+	}
+	cols := make([]int, len(leaves))
+	for i := range leaves {
+		cols[i] = r.Index(leaves[i].field.Name)
+		if cols[i] < 0 {
+			continue
+		}
+		f := r.Fields[cols[i]]
+		if f.Type != leaves[i].field.Type {
+			return capnp.List{}, fmt.Errorf("arrowcol: column %s is %v, want %v", f.Name, f.Type, leaves[i].field.Type)
+		}
+		if err := r.Columns[cols[i]].check(f.Type, r.NumRows); err != nil {
+			return capnp.List{}, fmt.Errorf("%v (column %s)", err, f.Name)
+		}
+	}
+	sz := capnp.ObjectSize{
+		DataSize:     capnp.Size(n.StructNode().DataWordCount()) * 8,
+		PointerCount: n.StructNode().PointerCount(),
+	}
+	l, err := capnp.NewCompositeList(s, sz, int32(r.NumRows))
+	if err != nil {
+		return capnp.List{}, err
+	}
+	for row := 0; row < r.NumRows; row++ {
+		st := l.Struct(row)
+		for i := range leaves {
+			if cols[i] < 0 || r.IsNull(cols[i], row) {
+				continue
+			}
+			if err := leaves[i].write(st, r, cols[i], row); err != nil {
+				return capnp.List{}, fmt.Errorf("arrowcol: row %d, column %s: %v", row, leaves[i].field.Name, err)
+			}
+		}
+	}
+	return l, nil
+}
+
+func (c *Converter) findStruct(typeID uint64) (schema.Node, error) {
+	n, err := c.nodes.Find(typeID)
+	if err != nil {
+		return schema.Node{}, err
+	}
+	if !n.IsValid() || n.Which() != schema.Node_Which_structNode {
+		return schema.Node{}, fmt.Errorf("arrowcol: cannot find struct type %#x", typeID)
+	}
+	return n, nil
+}
+
+// structLeaves returns the columns of a struct type, computing them
+// the first time the type is seen.
+func (c *Converter) structLeaves(typeID uint64) ([]leaf, error) {
+	if leaves, ok := c.leaves[typeID]; ok {
+		return leaves, nil
+	}
+	leaves, err := c.addStruct(nil, "", typeID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.leaves == nil {
+		c.leaves = make(map[uint64][]leaf)
+	}
+	c.leaves[typeID] = leaves
+	return leaves, nil
+}
+
+// addStruct appends the columns of a struct or group to leaves.  conds
+// are the union members that must be selected for the struct's fields
+// to be present.
+func (c *Converter) addStruct(leaves []leaf, prefix string, typeID uint64, conds []cond) ([]leaf, error) {
+	n, err := c.findStruct(typeID)
+	if err != nil {
+		return nil, err
+	}
+	sn := n.StructNode()
+	if sn.DiscriminantCount() > 0 {
+		leaves = append(leaves, leaf{
+			field:  Field{Name: prefix + "which", Type: Uint16, Nullable: len(conds) > 0},
+			which:  true,
+			offset: sn.DiscriminantOffset(),
+			conds:  conds,
+		})
+	}
+	for _, f := range codeOrderFields(sn) {
+		name, err := f.Name()
+		if err != nil {
+			return nil, err
+		}
+		fconds := conds
+		if dv := f.DiscriminantValue(); dv != schema.Field_noDiscriminant {
+			fconds = append(conds[:len(conds):len(conds)], cond{offset: sn.DiscriminantOffset(), value: dv})
+		}
+		switch f.Which() {
+		case schema.Field_Which_group:
+			leaves, err = c.addStruct(leaves, prefix+name+".", f.Group().TypeId(), fconds)
+			if err != nil {
+				return nil, err
+			}
+		case schema.Field_Which_slot:
+			typ, err := f.Slot().Type()
+			if err != nil {
+				return nil, err
+			}
+			t, ok := columnType(typ.Which())
+			if !ok {
+				continue
+			}
+			def, err := f.Slot().DefaultValue()
+			if err != nil {
+				return nil, err
+			}
+			leaves = append(leaves, leaf{
+				field:  Field{Name: prefix + name, Type: t, Nullable: len(fconds) > 0 || t == Utf8 || t == Binary},
+				offset: f.Slot().Offset(),
+				def:    def,
+				conds:  fconds,
+			})
+		}
+	}
+	return leaves, nil
+}
+
+func codeOrderFields(s schema.Node_structNode) []schema.Field {
+	list, _ := s.Fields()
+	n := list.Len()
+	fields := make([]schema.Field, n)
+	for i := 0; i < n; i++ {
+		f := list.At(i)
+		fields[f.CodeOrder()] = f
+	}
+	return fields
+}
+
+// columnType returns the column type for a field type, or false if the
+// field has no column.
+func columnType(t schema.Type_Which) (Type, bool) {
+	switch t {
+	case schema.Type_Which_bool:
+		return Bool, true
+	case schema.Type_Which_int8:
+		return Int8, true
+	case schema.Type_Which_int16:
+		return Int16, true
+	case schema.Type_Which_int32:
+		return Int32, true
+	case schema.Type_Which_int64:
+		return Int64, true
+	case schema.Type_Which_uint8:
+		return Uint8, true
+	case schema.Type_Which_uint16, schema.Type_Which_enum:
+		return Uint16, true
+	case schema.Type_Which_uint32:
+		return Uint32, true
+	case schema.Type_Which_uint64:
+		return Uint64, true
+	case schema.Type_Which_float32:
+		return Float32, true
+	case schema.Type_Which_float64:
+		return Float64, true
+	case schema.Type_Which_text:
+		return Utf8, true
+	case schema.Type_Which_data:
+		return Binary, true
+	default:
+		return 0, false
+	}
+}
+
+// A leaf is a field that maps to a column.
+type leaf struct {
+	field Field
+
+	// which is true for a union discriminant column.
+	which bool
+
+	// offset is the field's slot offset, in units of its size, or the
+	// discriminant offset in 16-bit units if which is true.
+	offset uint32
+
+	def   schema.Value
+	conds []cond
+}
+
+// A cond is a union member that must be selected for a field to be
+// present.
+type cond struct {
+	offset uint32 // discriminant offset in 16-bit units
+	value  uint16
+}
+
+// present reports whether the unions in s select the leaf's field.
+func (lf *leaf) present(s capnp.Struct) bool {
+	for _, c := range lf.conds {
+		if s.Uint16(capnp.DataOffset(c.offset*2)) != c.value {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultBits returns the default value of a fixed-width field as it
+// is XORed with the stored value.
+func (lf *leaf) defaultBits() uint64 {
+	switch lf.field.Type {
+	case Int8:
+		return uint64(uint8(lf.def.Int8()))
+	case Int16:
+		return uint64(uint16(lf.def.Int16()))
+	case Int32:
+		return uint64(uint32(lf.def.Int32()))
+	case Int64:
+		return uint64(lf.def.Int64())
+	case Uint8:
+		return uint64(lf.def.Uint8())
+	case Uint16:
+		// Enums and UInt16s share a location in the value union.
+		return uint64(lf.def.Uint16())
+	case Uint32:
+		return uint64(lf.def.Uint32())
+	case Uint64:
+		return lf.def.Uint64()
+	case Float32:
+		return uint64(math.Float32bits(lf.def.Float32()))
+	case Float64:
+		return math.Float64bits(lf.def.Float64())
+	default:
+		return 0
+	}
+}
+
+// read appends the leaf's value in s to b.
+func (lf *leaf) read(b *builder, s capnp.Struct) error {
+	if !lf.present(s) {
+		b.appendNull()
+		return nil
+	}
+	if lf.which {
+		b.appendBits(uint64(s.Uint16(capnp.DataOffset(lf.offset * 2))))
+		return nil
+	}
+	switch t := lf.field.Type; t {
+	case Bool:
+		b.appendBool(s.Bit(capnp.BitOffset(lf.offset)) != lf.def.Bool())
+	case Utf8, Binary:
+		p, err := s.Ptr(uint16(lf.offset))
+		if err != nil {
+			return err
+		}
+		var v []byte
+		if p.IsValid() {
+			if t == Utf8 {
+				v = p.TextBytes()
+			} else {
+				v = p.Data()
+			}
+		} else {
+			if t == Utf8 {
+				v, _ = lf.def.TextBytes()
+			} else {
+				v, _ = lf.def.Data()
+			}
+			if len(v) == 0 {
+				b.appendNull()
+				return nil
+			}
+		}
+		b.appendBytes(v)
+	default:
+		w := t.width()
+		off := capnp.DataOffset(lf.offset) * capnp.DataOffset(w)
+		var v uint64
+		switch w {
+		case 1:
+			v = uint64(s.Uint8(off))
+		case 2:
+			v = uint64(s.Uint16(off))
+		case 4:
+			v = uint64(s.Uint32(off))
+		default:
+			v = s.Uint64(off)
+		}
+		b.appendBits(v ^ lf.defaultBits())
+	}
+	return nil
+}
+
+// write sets the leaf's field in s to the value in the given row of
+// column col of r, which must not be null.
+func (lf *leaf) write(s capnp.Struct, r *Record, col, row int) error {
+	if !lf.present(s) {
+		return errors.New("value for union member that is not selected")
+	}
+	c := &r.Columns[col]
+	if lf.which {
+		s.SetUint16(capnp.DataOffset(lf.offset*2), uint16(c.bitsAt(Uint16, row)))
+		return nil
+	}
+	switch t := lf.field.Type; t {
+	case Bool:
+		v := c.Values[row/8]&(1<<uint(row%8)) != 0
+		s.SetBit(capnp.BitOffset(lf.offset), v != lf.def.Bool())
+	case Utf8:
+		return s.SetTextFromBytes(uint16(lf.offset), c.bytesAt(row))
+	case Binary:
+		return s.SetData(uint16(lf.offset), c.bytesAt(row))
+	default:
+		w := t.width()
+		off := capnp.DataOffset(lf.offset) * capnp.DataOffset(w)
+		v := c.bitsAt(t, row) ^ lf.defaultBits()
+		switch w {
+		case 1:
+			s.SetUint8(off, uint8(v))
+		case 2:
+			s.SetUint16(off, uint16(v))
+		case 4:
+			s.SetUint32(off, uint32(v))
+		default:
+			s.SetUint64(off, v)
+		}
+	}
+	return nil
+}
+
+// A builder accumulates the buffers of a column.
+type builder struct {
+	t        Type
+	n        int
+	col      Column
+	validity []byte
+}
+
+func (b *builder) init(t Type, capacity int) {
+	b.t = t
+	b.validity = make([]byte, 0, (capacity+7)/8)
+	switch t {
+	case Bool:
+		b.col.Values = make([]byte, 0, (capacity+7)/8)
+	case Utf8, Binary:
+		b.col.Offsets = make([]byte, 4, (capacity+1)*4)
+	default:
+		b.col.Values = make([]byte, 0, capacity*t.width())
+	}
+}
+
+// next adds a row to the validity bitmap, and to the values bitmap of
+// a Bool column.
+func (b *builder) next(valid bool) {
+	if b.n%8 == 0 {
+		b.validity = append(b.validity, 0)
+		if b.t == Bool {
+			b.col.Values = append(b.col.Values, 0)
+		}
+	}
+	if valid {
+		b.validity[b.n/8] |= 1 << uint(b.n%8)
+	} else {
+		b.col.NullCount++
+	}
+	b.n++
+}
+
+func (b *builder) appendNull() {
+	b.next(false)
+	switch b.t {
+	case Bool:
+	case Utf8, Binary:
+		b.col.Offsets = append(b.col.Offsets, b.col.Offsets[len(b.col.Offsets)-4:]...)
+	default:
+		b.col.Values = append(b.col.Values, make([]byte, b.t.width())...)
+	}
+}
+
+func (b *builder) appendBool(v bool) {
+	b.next(true)
+	if v {
+		i := b.n - 1
+		b.col.Values[i/8] |= 1 << uint(i%8)
+	}
+}
+
+func (b *builder) appendBits(v uint64) {
+	b.next(true)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	b.col.Values = append(b.col.Values, buf[:b.t.width()]...)
+}
+
+func (b *builder) appendBytes(v []byte) {
+	b.next(true)
+	b.col.Values = append(b.col.Values, v...)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(b.col.Values)))
+	b.col.Offsets = append(b.col.Offsets, buf[:]...)
+}
+
+func (b *builder) finish() Column {
+	if b.col.NullCount > 0 {
+		b.col.Validity = b.validity
+	}
+	if b.col.Values == nil {
+		b.col.Values = []byte{}
+	}
+	return b.col
+}
+
+var errShortBuffer = errors.New("arrowcol: column buffer too short")
@@ -0,0 +1,253 @@
+package arrowcol
+
+import (
+	"bytes"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+func TestPlaneBase(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := air.NewPlaneBase_List(seg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := l.At(0)
+	p.SetName("Boeing")
+	p.SetRating(100)
+	p.SetCanFly(true)
+	p.SetCapacity(7)
+	p.SetMaxSpeed(-1.5)
+	l.At(1).SetName("A320")
+	l.At(2).SetRating(-3)
+
+	r, err := FromList(air.PlaneBase_TypeID, l.List)
+	if err != nil {
+		t.Fatal("FromList:", err)
+	}
+	wantFields := []Field{
+		{Name: "name", Type: Utf8, Nullable: true},
+		{Name: "rating", Type: Int64},
+		{Name: "canFly", Type: Bool},
+		{Name: "capacity", Type: Int64},
+		{Name: "maxSpeed", Type: Float64},
+	}
+	if len(r.Fields) != len(wantFields) {
+		t.Fatalf("Fields = %v; want %v", r.Fields, wantFields)
+	}
+	for i := range wantFields {
+		if r.Fields[i] != wantFields[i] {
+			t.Errorf("Fields[%d] = %+v; want %+v", i, r.Fields[i], wantFields[i])
+		}
+	}
+	if r.NumRows != 3 {
+		t.Errorf("NumRows = %d; want 3", r.NumRows)
+	}
+	want := [][]interface{}{
+		{"Boeing", int64(100), true, int64(7), -1.5},
+		{"A320", int64(0), false, int64(0), 0.0},
+		{nil, int64(-3), false, int64(0), 0.0},
+	}
+	checkRows(t, r, want)
+	if n := r.Columns[0].NullCount; n != 1 {
+		t.Errorf("name NullCount = %d; want 1", n)
+	}
+	if r.Columns[1].Validity != nil {
+		t.Error("rating has a validity bitmap; want nil")
+	}
+
+	_, seg2, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	l2, err := ToList(seg2, air.PlaneBase_TypeID, r)
+	if err != nil {
+		t.Fatal("ToList:", err)
+	}
+	pl := air.PlaneBase_List{List: l2}
+	if pl.Len() != 3 {
+		t.Fatalf("ToList len = %d; want 3", pl.Len())
+	}
+	if name, _ := pl.At(0).Name(); name != "Boeing" {
+		t.Errorf("ToList[0].name = %q; want \"Boeing\"", name)
+	}
+	if pl.At(0).MaxSpeed() != -1.5 || !pl.At(0).CanFly() || pl.At(2).Rating() != -3 {
+		t.Errorf("ToList scalars = %v, %v, %v; want -1.5, true, -3", pl.At(0).MaxSpeed(), pl.At(0).CanFly(), pl.At(2).Rating())
+	}
+	if pl.At(2).HasName() {
+		t.Error("ToList[2] has name; want null")
+	}
+}
+
+func TestUnion(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := air.NewZ_List(seg, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.At(0).SetF64(2.5)
+	l.At(1).SetText("hi")
+	l.At(2).SetGrp()
+	l.At(2).Grp().SetFirst(1)
+	l.At(2).Grp().SetSecond(2)
+	l.At(3).SetAirport(air.Airport_lax)
+
+	r, err := FromList(air.Z_TypeID, l.List)
+	if err != nil {
+		t.Fatal("FromList:", err)
+	}
+	cols := map[string]int{}
+	for _, name := range []string{"which", "f64", "text", "grp.first", "grp.second", "airport", "f64vec"} {
+		cols[name] = r.Index(name)
+	}
+	if cols["which"] != 0 {
+		t.Errorf("which column = %d; want 0", cols["which"])
+	}
+	if cols["f64vec"] != -1 {
+		t.Error("list field f64vec has a column")
+	}
+	for name, i := range cols {
+		if i == -1 && name != "f64vec" {
+			t.Fatalf("no column %s", name)
+		}
+	}
+	tests := []struct {
+		row  int
+		name string
+		want interface{}
+	}{
+		{0, "which", uint64(air.Z_Which_f64)},
+		{0, "f64", 2.5},
+		{0, "text", nil},
+		{0, "grp.first", nil},
+		{1, "text", "hi"},
+		{1, "f64", nil},
+		{2, "which", uint64(air.Z_Which_grp)},
+		{2, "grp.first", uint64(1)},
+		{2, "grp.second", uint64(2)},
+		{3, "airport", uint64(air.Airport_lax)},
+		{3, "grp.second", nil},
+	}
+	for _, test := range tests {
+		if v := r.Value(cols[test.name], test.row); !equal(v, test.want) {
+			t.Errorf("row %d %s = %#v; want %#v", test.row, test.name, v, test.want)
+		}
+	}
+	if !r.Fields[cols["f64"]].Nullable {
+		t.Error("union member f64 is not nullable")
+	}
+
+	_, seg2, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	l2, err := ToList(seg2, air.Z_TypeID, r)
+	if err != nil {
+		t.Fatal("ToList:", err)
+	}
+	zl := air.Z_List{List: l2}
+	if z := zl.At(0); z.Which() != air.Z_Which_f64 || z.F64() != 2.5 {
+		t.Errorf("ToList[0] = %v", z)
+	}
+	if text, _ := zl.At(1).Text(); zl.At(1).Which() != air.Z_Which_text || text != "hi" {
+		t.Errorf("ToList[1] = %v", zl.At(1))
+	}
+	if g := zl.At(2).Grp(); zl.At(2).Which() != air.Z_Which_grp || g.First() != 1 || g.Second() != 2 {
+		t.Errorf("ToList[2] = %v", zl.At(2))
+	}
+	if z := zl.At(3); z.Which() != air.Z_Which_airport || z.Airport() != air.Airport_lax {
+		t.Errorf("ToList[3] = %v", z)
+	}
+
+	// A value for a member that the discriminant does not select is an
+	// error.
+	f64 := &r.Columns[cols["f64"]]
+	f64.Validity[0] |= 0x2
+	f64.NullCount--
+	if _, err := ToList(seg2, air.Z_TypeID, r); err == nil {
+		t.Error("ToList with value for inactive union member succeeded")
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := air.NewDefaults_List(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := l.At(1)
+	d.SetText("baz")
+	d.SetData([]byte{})
+	d.SetFloat(1)
+	d.SetInt(0)
+	d.SetUint(7)
+
+	r, err := FromList(air.Defaults_TypeID, l.List)
+	if err != nil {
+		t.Fatal("FromList:", err)
+	}
+	want := [][]interface{}{
+		{"foo", []byte("bar"), float64(float32(3.14)), int64(-123), uint64(42)},
+		{"baz", []byte{}, 1.0, int64(0), uint64(7)},
+	}
+	checkRows(t, r, want)
+
+	_, seg2, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	l2, err := ToList(seg2, air.Defaults_TypeID, r)
+	if err != nil {
+		t.Fatal("ToList:", err)
+	}
+	dl := air.Defaults_List{List: l2}
+	for i := range want {
+		d := dl.At(i)
+		text, _ := d.Text()
+		data, _ := d.Data()
+		got := []interface{}{text, data, float64(d.Float()), int64(d.Int()), uint64(d.Uint())}
+		for j := range got {
+			if !equal(got[j], want[i][j]) {
+				t.Errorf("ToList[%d] field %d = %#v; want %#v", i, j, got[j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestToListTypeMismatch(t *testing.T) {
+	r := &Record{
+		Fields:  []Field{{Name: "rating", Type: Float64}},
+		Columns: []Column{{Values: make([]byte, 8)}},
+		NumRows: 1,
+	}
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	if _, err := ToList(seg, air.PlaneBase_TypeID, r); err == nil {
+		t.Error("ToList with float64 rating column succeeded")
+	}
+	r.Fields[0].Type = Int64
+	r.Columns[0].Values = r.Columns[0].Values[:4]
+	if _, err := ToList(seg, air.PlaneBase_TypeID, r); err == nil {
+		t.Error("ToList with short rating column succeeded")
+	}
+}
+
+func checkRows(t *testing.T, r *Record, want [][]interface{}) {
+	t.Helper()
+	for row := range want {
+		for col := range want[row] {
+			if v := r.Value(col, row); !equal(v, want[row][col]) {
+				t.Errorf("row %d %s = %#v; want %#v", row, r.Fields[col].Name, v, want[row][col])
+			}
+		}
+	}
+}
+
+func equal(a, b interface{}) bool {
+	if ab, ok := a.([]byte); ok {
+		bb, ok := b.([]byte)
+		return ok && bytes.Equal(ab, bb)
+	}
+	return a == b
+}
@@ -0,0 +1,113 @@
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+// Marshal returns the enveloped encoding of msg, whose root struct is
+// of the type with ID typeID.  It is meant for transports that carry
+// one message per payload, like message queues.  Schema hashes are
+// computed with schemas.DefaultRegistry.
+func Marshal(typeID uint64, msg *capnp.Message) ([]byte, error) {
+	return marshal(typeID, msg, false)
+}
+
+// MarshalPacked is like Marshal, but packs the message.  The header is
+// not packed.  The result must be read with UnmarshalPacked.
+func MarshalPacked(typeID uint64, msg *capnp.Message) ([]byte, error) {
+	return marshal(typeID, msg, true)
+}
+
+func marshal(typeID uint64, msg *capnp.Message, packed bool) ([]byte, error) {
+	hash, err := defaultHashes.get(typeID)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: %v", err)
+	}
+	var body []byte
+	if packed {
+		body, err = msg.MarshalPacked()
+	} else {
+		body, err = msg.Marshal()
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, HeaderSize, HeaderSize+len(body))
+	binary.LittleEndian.PutUint64(b[:8], typeID)
+	binary.LittleEndian.PutUint64(b[8:], hash)
+	return append(b, body...), nil
+}
+
+// Unmarshal decodes a payload created by Marshal.  No copying is
+// performed, so the returned message reads directly from data.
+func Unmarshal(data []byte) (Header, *capnp.Message, error) {
+	h, err := parseHeader(data)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	msg, err := capnp.Unmarshal(data[HeaderSize:])
+	if err != nil {
+		return h, nil, err
+	}
+	return h, msg, nil
+}
+
+// UnmarshalPacked decodes a payload created by MarshalPacked.
+func UnmarshalPacked(data []byte) (Header, *capnp.Message, error) {
+	h, err := parseHeader(data)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	msg, err := capnp.UnmarshalPacked(data[HeaderSize:])
+	if err != nil {
+		return h, nil, err
+	}
+	return h, msg, nil
+}
+
+func parseHeader(data []byte) (Header, error) {
+	if len(data) < HeaderSize {
+		return Header{}, errShortPayload
+	}
+	return Header{
+		TypeID:     binary.LittleEndian.Uint64(data[:8]),
+		SchemaHash: binary.LittleEndian.Uint64(data[8:HeaderSize]),
+	}, nil
+}
+
+var errShortPayload = errors.New("envelope: payload shorter than header")
+
+// defaultHashes caches schema hashes from schemas.DefaultRegistry,
+// which is only modified during init.
+var defaultHashes hashCache
+
+type hashCache struct {
+	mu sync.Mutex
+	m  map[uint64]uint64
+}
+
+func (c *hashCache) get(id uint64) (uint64, error) {
+	c.mu.Lock()
+	h, ok := c.m[id]
+	c.mu.Unlock()
+	if ok {
+		return h, nil
+	}
+	h, err := SchemaHash(&schemas.DefaultRegistry, id)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	if c.m == nil {
+		c.m = make(map[uint64]uint64)
+	}
+	c.m[id] = h
+	c.mu.Unlock()
+	return h, nil
+}
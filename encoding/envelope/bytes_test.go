@@ -0,0 +1,65 @@
+package envelope_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/envelope"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+func newZdateMessage(t *testing.T, year int16) *capnp.Message {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := air.NewRootZdate(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetYear(year)
+	return msg
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		marshal   func(uint64, *capnp.Message) ([]byte, error)
+		unmarshal func([]byte) (envelope.Header, *capnp.Message, error)
+	}{
+		{"Marshal", envelope.Marshal, envelope.Unmarshal},
+		{"MarshalPacked", envelope.MarshalPacked, envelope.UnmarshalPacked},
+	}
+	for _, test := range tests {
+		data, err := test.marshal(air.Zdate_TypeID, newZdateMessage(t, 1999))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		h, msg, err := test.unmarshal(data)
+		if err != nil {
+			t.Errorf("%s: unmarshal: %v", test.name, err)
+			continue
+		}
+		if h.TypeID != air.Zdate_TypeID || h.SchemaHash == 0 {
+			t.Errorf("%s: header = %+v; want type %#x and non-zero hash", test.name, h, uint64(air.Zdate_TypeID))
+		}
+		d, err := air.ReadRootZdate(msg)
+		if err != nil {
+			t.Errorf("%s: ReadRootZdate: %v", test.name, err)
+			continue
+		}
+		if d.Year() != 1999 {
+			t.Errorf("%s: Year() = %d; want 1999", test.name, d.Year())
+		}
+	}
+}
+
+func TestUnmarshalShort(t *testing.T) {
+	if _, _, err := envelope.Unmarshal(make([]byte, envelope.HeaderSize-1)); err == nil {
+		t.Error("Unmarshal of short payload succeeded")
+	}
+	if _, _, err := envelope.UnmarshalPacked(nil); err == nil {
+		t.Error("UnmarshalPacked(nil) succeeded")
+	}
+}
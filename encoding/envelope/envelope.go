@@ -0,0 +1,272 @@
+// Package envelope frames Cap'n Proto messages with the type of their
+// root struct, so that streams holding messages of different types can
+// be decoded without knowing the type of each message in advance.
+//
+// Each enveloped message is a 16-byte header followed by the message
+// in the standard (unpacked) stream framing.  The header holds two
+// little-endian 64-bit integers: the type ID of the root struct and a
+// hash of the schema that the writer used for it.  A schema hash of
+// zero means that the writer did not know its schema.
+//
+// A Mux dispatches messages read from a Reader to a handler registered
+// for each type, which makes it easy to consume sockets, event logs, or
+// queues that carry several message types.
+package envelope // import "zombiezen.com/go/capnproto2/encoding/envelope"
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+// HeaderSize is the size in bytes of an envelope header.
+const HeaderSize = 16
+
+// A Header describes the message that follows it.
+type Header struct {
+	// TypeID is the ID of the message's root struct type, as in the
+	// TypeID constants of generated code.
+	TypeID uint64
+
+	// SchemaHash identifies the version of the schema that the writer
+	// used for TypeID.  It is zero if the writer's schema is unknown.
+	SchemaHash uint64
+}
+
+// SchemaHash returns a hash of the schema registered in reg for id.
+// The hash covers the whole schema file that declares id, so it
+// changes whenever anything in that file changes.  If id is not
+// registered, SchemaHash returns zero and no error.
+func SchemaHash(reg *schemas.Registry, id uint64) (uint64, error) {
+	data, err := reg.Find(id)
+	if schemas.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256(data)
+	h := binary.LittleEndian.Uint64(sum[:8])
+	if h == 0 {
+		// Reserve zero for unknown.
+		h = 1
+	}
+	return h, nil
+}
+
+// A Writer writes enveloped messages to a stream.
+type Writer struct {
+	w      io.Writer
+	enc    *capnp.Encoder
+	reg    *schemas.Registry
+	hashes map[uint64]uint64
+	hdr    [HeaderSize]byte
+}
+
+// NewWriter returns a writer that writes to w and computes schema
+// hashes with schemas.DefaultRegistry.
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterRegistry(w, &schemas.DefaultRegistry)
+}
+
+// NewWriterRegistry returns a writer that writes to w and computes
+// schema hashes with reg.
+func NewWriterRegistry(w io.Writer, reg *schemas.Registry) *Writer {
+	return &Writer{
+		w:      w,
+		enc:    capnp.NewEncoder(w),
+		reg:    reg,
+		hashes: make(map[uint64]uint64),
+	}
+}
+
+// Write writes msg, whose root struct is of the type with ID typeID.
+func (w *Writer) Write(typeID uint64, msg *capnp.Message) error {
+	hash, ok := w.hashes[typeID]
+	if !ok {
+		var err error
+		hash, err = SchemaHash(w.reg, typeID)
+		if err != nil {
+			return fmt.Errorf("envelope: %v", err)
+		}
+		w.hashes[typeID] = hash
+	}
+	binary.LittleEndian.PutUint64(w.hdr[:8], typeID)
+	binary.LittleEndian.PutUint64(w.hdr[8:], hash)
+	if _, err := w.w.Write(w.hdr[:]); err != nil {
+		return err
+	}
+	return w.enc.Encode(msg)
+}
+
+// A Reader reads enveloped messages from a stream.
+type Reader struct {
+	r   io.Reader
+	dec *capnp.Decoder
+	hdr [HeaderSize]byte
+}
+
+// NewReader returns a reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r, dec: capnp.NewDecoder(r)}
+}
+
+// Decoder returns the decoder used to read messages, so that its
+// limits can be adjusted.
+func (r *Reader) Decoder() *capnp.Decoder {
+	return r.dec
+}
+
+// Next reads the next message and its header.  At the end of the
+// stream, Next returns io.EOF.  If the stream ends in the middle of a
+// message, Next returns io.ErrUnexpectedEOF.
+func (r *Reader) Next() (Header, *capnp.Message, error) {
+	if _, err := io.ReadFull(r.r, r.hdr[:]); err != nil {
+		return Header{}, nil, err
+	}
+	h := Header{
+		TypeID:     binary.LittleEndian.Uint64(r.hdr[:8]),
+		SchemaHash: binary.LittleEndian.Uint64(r.hdr[8:]),
+	}
+	msg, err := r.dec.Decode()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return h, nil, err
+	}
+	return h, msg, nil
+}
+
+// A Handler processes the root struct of a message.
+type Handler func(h Header, root capnp.Struct) error
+
+// A Mux dispatches messages to a handler for their root type.  The
+// zero value is a mux with no handlers that accepts any schema hash.
+// Handlers may be registered and removed while the mux is dispatching,
+// but its fields must not be changed.
+type Mux struct {
+	mu       sync.RWMutex
+	handlers map[uint64]Handler
+
+	// Fallback handles messages whose type has no handler.  If it is
+	// nil, such messages are rejected with an *UnknownTypeError.
+	Fallback Handler
+
+	// If StrictSchema is true, messages whose schema hash is known and
+	// differs from the hash of the schema in Registry are rejected with
+	// a *SchemaMismatchError.  Cap'n Proto schemas usually evolve in
+	// compatible ways, so this is off by default.
+	StrictSchema bool
+
+	// Registry is used to compute schema hashes for StrictSchema.  If
+	// nil, schemas.DefaultRegistry is used.
+	Registry *schemas.Registry
+
+	// ErrorHandler is called by ReadAll when a message cannot be
+	// dispatched: when its type has no handler, its schema does not
+	// match, its root cannot be read, or its handler returns an error.
+	// If ErrorHandler returns nil, the message is skipped and ReadAll
+	// continues.  Otherwise, ReadAll stops and returns its error.  If
+	// ErrorHandler is nil, ReadAll stops at the first such error.
+	ErrorHandler func(h Header, err error) error
+}
+
+// Handle registers f as the handler for messages whose root struct is
+// of the type with ID typeID, replacing any previous handler.  If f is
+// nil, the handler for typeID is removed.
+func (m *Mux) Handle(typeID uint64, f Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f == nil {
+		delete(m.handlers, typeID)
+		return
+	}
+	if m.handlers == nil {
+		m.handlers = make(map[uint64]Handler)
+	}
+	m.handlers[typeID] = f
+}
+
+// Dispatch calls the handler for h.TypeID with msg's root struct.
+// Dispatch does not call the error handler.
+func (m *Mux) Dispatch(h Header, msg *capnp.Message) error {
+	m.mu.RLock()
+	f := m.handlers[h.TypeID]
+	m.mu.RUnlock()
+	if f == nil {
+		f = m.Fallback
+	}
+	if f == nil {
+		return &UnknownTypeError{TypeID: h.TypeID}
+	}
+	if m.StrictSchema && h.SchemaHash != 0 {
+		reg := m.Registry
+		if reg == nil {
+			reg = &schemas.DefaultRegistry
+		}
+		local, err := SchemaHash(reg, h.TypeID)
+		if err != nil {
+			return fmt.Errorf("envelope: %v", err)
+		}
+		if local != 0 && local != h.SchemaHash {
+			return &SchemaMismatchError{Header: h, Local: local}
+		}
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		return err
+	}
+	return f(h, p.Struct())
+}
+
+// ReadAll dispatches each message read from r until the end of the
+// stream, which is reported as a nil error, or until a message cannot
+// be read or dispatched.  Errors reading the stream are returned
+// directly; errors dispatching a message are passed through the error
+// handler.
+func (m *Mux) ReadAll(r *Reader) error {
+	for {
+		h, msg, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.Dispatch(h, msg); err != nil {
+			if m.ErrorHandler == nil {
+				return err
+			}
+			if err := m.ErrorHandler(h, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// UnknownTypeError is returned by Mux.Dispatch for a message whose type
+// has no handler.
+type UnknownTypeError struct {
+	TypeID uint64
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("envelope: no handler for type @%#x", e.TypeID)
+}
+
+// SchemaMismatchError is returned by Mux.Dispatch in strict mode for a
+// message written with a different schema.
+type SchemaMismatchError struct {
+	Header Header
+	Local  uint64 // hash of the local schema
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return fmt.Sprintf("envelope: type @%#x written with schema %#x, have %#x", e.Header.TypeID, e.Header.SchemaHash, e.Local)
+}
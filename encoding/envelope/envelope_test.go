@@ -0,0 +1,179 @@
+package envelope_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/envelope"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+func writeStream(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := envelope.NewWriter(&buf)
+
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := air.NewRootZdate(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetYear(2017)
+	if err := w.Write(air.Zdate_TypeID, msg); err != nil {
+		t.Fatal("Write Zdate:", err)
+	}
+
+	msg, seg, err = capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := air.NewRootPlaneBase(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.SetName("Spirit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(air.PlaneBase_TypeID, msg); err != nil {
+		t.Fatal("Write PlaneBase:", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMux(t *testing.T) {
+	data := writeStream(t)
+	var got []string
+	var mux envelope.Mux
+	mux.Handle(air.Zdate_TypeID, func(h envelope.Header, root capnp.Struct) error {
+		if y := (air.Zdate{Struct: root}).Year(); y != 2017 {
+			t.Errorf("Zdate.Year() = %d; want 2017", y)
+		}
+		got = append(got, "Zdate")
+		return nil
+	})
+	mux.Handle(air.PlaneBase_TypeID, func(h envelope.Header, root capnp.Struct) error {
+		name, err := (air.PlaneBase{Struct: root}).Name()
+		if err != nil || name != "Spirit" {
+			t.Errorf("PlaneBase.Name() = %q, %v; want \"Spirit\", <nil>", name, err)
+		}
+		got = append(got, "PlaneBase")
+		return nil
+	})
+	if err := mux.ReadAll(envelope.NewReader(bytes.NewReader(data))); err != nil {
+		t.Fatal("ReadAll:", err)
+	}
+	if len(got) != 2 || got[0] != "Zdate" || got[1] != "PlaneBase" {
+		t.Errorf("dispatched %v; want [Zdate PlaneBase]", got)
+	}
+}
+
+func TestReaderHeader(t *testing.T) {
+	data := writeStream(t)
+	r := envelope.NewReader(bytes.NewReader(data))
+	h, _, err := r.Next()
+	if err != nil {
+		t.Fatal("Next:", err)
+	}
+	want, err := envelope.SchemaHash(&schemas.DefaultRegistry, air.Zdate_TypeID)
+	if err != nil {
+		t.Fatal("SchemaHash:", err)
+	}
+	if want == 0 {
+		t.Fatal("SchemaHash of registered type = 0")
+	}
+	if h.TypeID != air.Zdate_TypeID || h.SchemaHash != want {
+		t.Errorf("header = %+v; want {TypeID:%#x SchemaHash:%#x}", h, uint64(air.Zdate_TypeID), want)
+	}
+	if _, _, err := r.Next(); err != nil {
+		t.Fatal("second Next:", err)
+	}
+	if _, _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next at end = %v; want EOF", err)
+	}
+}
+
+func TestReaderTruncated(t *testing.T) {
+	data := writeStream(t)
+	for _, n := range []int{envelope.HeaderSize - 1, envelope.HeaderSize, envelope.HeaderSize + 12} {
+		r := envelope.NewReader(bytes.NewReader(data[:n]))
+		if _, _, err := r.Next(); err != io.ErrUnexpectedEOF {
+			t.Errorf("Next on %d bytes = %v; want ErrUnexpectedEOF", n, err)
+		}
+	}
+}
+
+func TestMuxErrors(t *testing.T) {
+	data := writeStream(t)
+	var mux envelope.Mux
+	err := mux.ReadAll(envelope.NewReader(bytes.NewReader(data)))
+	if e, ok := err.(*envelope.UnknownTypeError); !ok || e.TypeID != air.Zdate_TypeID {
+		t.Errorf("ReadAll with no handlers = %v; want UnknownTypeError for Zdate", err)
+	}
+
+	// Corrupt the first schema hash.
+	bad := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(bad[8:16], 42)
+	mux.Fallback = func(envelope.Header, capnp.Struct) error { return nil }
+	if err := mux.ReadAll(envelope.NewReader(bytes.NewReader(bad))); err != nil {
+		t.Errorf("ReadAll with mismatched schema, not strict = %v; want <nil>", err)
+	}
+	mux.StrictSchema = true
+	err = mux.ReadAll(envelope.NewReader(bytes.NewReader(bad)))
+	if e, ok := err.(*envelope.SchemaMismatchError); !ok || e.Header.SchemaHash != 42 {
+		t.Errorf("ReadAll with mismatched schema, strict = %v; want SchemaMismatchError", err)
+	}
+}
+
+func TestMuxErrorHandler(t *testing.T) {
+	data := writeStream(t)
+	var mux envelope.Mux
+	var years []int16
+	mux.Handle(air.Zdate_TypeID, func(h envelope.Header, root capnp.Struct) error {
+		years = append(years, air.Zdate{Struct: root}.Year())
+		return nil
+	})
+	mux.Handle(air.PlaneBase_TypeID, func(envelope.Header, capnp.Struct) error {
+		return errors.New("bad plane")
+	})
+	err := mux.ReadAll(envelope.NewReader(bytes.NewReader(data)))
+	if err == nil || err.Error() != "bad plane" {
+		t.Errorf("ReadAll = %v; want bad plane", err)
+	}
+	if len(years) != 1 || years[0] != 2017 {
+		t.Errorf("years = %v; want [2017]", years)
+	}
+
+	// Removing a handler makes its type unknown, and the error handler
+	// can skip it.
+	mux.Handle(air.PlaneBase_TypeID, nil)
+	var skipped []uint64
+	mux.ErrorHandler = func(h envelope.Header, err error) error {
+		if _, ok := err.(*envelope.UnknownTypeError); !ok {
+			return err
+		}
+		skipped = append(skipped, h.TypeID)
+		return nil
+	}
+	years = nil
+	if err := mux.ReadAll(envelope.NewReader(bytes.NewReader(data))); err != nil {
+		t.Errorf("ReadAll with error handler = %v; want <nil>", err)
+	}
+	if len(years) != 1 || years[0] != 2017 {
+		t.Errorf("years = %v; want [2017]", years)
+	}
+	if len(skipped) != 1 || skipped[0] != air.PlaneBase_TypeID {
+		t.Errorf("skipped = %#x; want [%#x]", skipped, uint64(air.PlaneBase_TypeID))
+	}
+
+	// Errors reading the stream are not passed to the error handler.
+	if err := mux.ReadAll(envelope.NewReader(bytes.NewReader(data[:len(data)-8]))); err == nil {
+		t.Error("ReadAll on truncated stream = <nil>; want error")
+	}
+}
@@ -0,0 +1,139 @@
+// Package mqcodec adapts the envelope format to the codec interfaces of
+// popular message queue clients, so that Cap'n Proto messages can be
+// published and consumed without framing them by hand.
+//
+// The adapters satisfy the clients' interfaces structurally, so this
+// package does not depend on any client.  For NATS, register a NATS
+// value as an encoder:
+//
+//	nats.RegisterEncoder("capnp", mqcodec.NATS{})
+//	ec, _ := nats.NewEncodedConn(nc, "capnp")
+//	ec.Publish("events", mqcodec.Payload{TypeID: books.Book_TypeID, Message: msg})
+//
+// For Kafka clients that accept a sarama.Encoder, wrap the payload with
+// NewKafkaEncoder.  Clients that use plain byte slices can call
+// Payload.Marshal and Unmarshal directly.
+package mqcodec // import "zombiezen.com/go/capnproto2/encoding/envelope/mqcodec"
+
+import (
+	"fmt"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/envelope"
+)
+
+// A Payload is a message together with the type of its root struct.
+type Payload struct {
+	TypeID  uint64
+	Message *capnp.Message
+
+	// SchemaHash is set by Unmarshal to the hash recorded by the
+	// publisher.  It is ignored when marshaling, since the hash is
+	// always computed from the local schema.
+	SchemaHash uint64
+}
+
+// Marshal returns the enveloped encoding of p, packed if packed is true.
+func (p Payload) Marshal(packed bool) ([]byte, error) {
+	if packed {
+		return envelope.MarshalPacked(p.TypeID, p.Message)
+	}
+	return envelope.Marshal(p.TypeID, p.Message)
+}
+
+// Root returns the root struct of the payload's message.
+func (p Payload) Root() (capnp.Struct, error) {
+	ptr, err := p.Message.RootPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return ptr.Struct(), nil
+}
+
+// Unmarshal decodes a payload created by Payload.Marshal with the same
+// value of packed.  If packed is false, the returned message reads
+// directly from data.
+func Unmarshal(data []byte, packed bool) (Payload, error) {
+	var h envelope.Header
+	var msg *capnp.Message
+	var err error
+	if packed {
+		h, msg, err = envelope.UnmarshalPacked(data)
+	} else {
+		h, msg, err = envelope.Unmarshal(data)
+	}
+	if err != nil {
+		return Payload{}, err
+	}
+	return Payload{TypeID: h.TypeID, Message: msg, SchemaHash: h.SchemaHash}, nil
+}
+
+// NATS is an encoder for NATS encoded connections.  It encodes Payload
+// and *Payload values and decodes into *Payload values.  The subject is
+// not used.
+type NATS struct {
+	// Packed selects the packed encoding.  Publishers and subscribers
+	// must agree on it.
+	Packed bool
+}
+
+// Encode returns the encoding of v, which must be a Payload or
+// *Payload.
+func (n NATS) Encode(subject string, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case Payload:
+		return v.Marshal(n.Packed)
+	case *Payload:
+		return v.Marshal(n.Packed)
+	default:
+		return nil, fmt.Errorf("mqcodec: cannot encode %T; need Payload", v)
+	}
+}
+
+// Decode decodes data into vPtr, which must be a *Payload.  Unless
+// Packed is set, the decoded message reads directly from data.
+func (n NATS) Decode(subject string, data []byte, vPtr interface{}) error {
+	p, ok := vPtr.(*Payload)
+	if !ok {
+		return fmt.Errorf("mqcodec: cannot decode into %T; need *Payload", vPtr)
+	}
+	var err error
+	*p, err = Unmarshal(data, n.Packed)
+	return err
+}
+
+// A KafkaEncoder is a Kafka message key or value that implements
+// sarama.Encoder.  The payload is encoded once, on first use.
+type KafkaEncoder struct {
+	p      Payload
+	packed bool
+
+	data []byte
+	err  error
+	done bool
+}
+
+// NewKafkaEncoder returns an encoder for p, packed if packed is true.
+func NewKafkaEncoder(p Payload, packed bool) *KafkaEncoder {
+	return &KafkaEncoder{p: p, packed: packed}
+}
+
+// Encode returns the enveloped encoding of the payload.
+func (e *KafkaEncoder) Encode() ([]byte, error) {
+	e.encode()
+	return e.data, e.err
+}
+
+// Length returns the size of the encoding in bytes, or zero if the
+// payload could not be encoded.
+func (e *KafkaEncoder) Length() int {
+	e.encode()
+	return len(e.data)
+}
+
+func (e *KafkaEncoder) encode() {
+	if !e.done {
+		e.data, e.err = e.p.Marshal(e.packed)
+		e.done = true
+	}
+}
@@ -0,0 +1,108 @@
+package mqcodec_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/envelope/mqcodec"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+// natsEncoder has the method set of nats.Encoder.
+type natsEncoder interface {
+	Encode(subject string, v interface{}) ([]byte, error)
+	Decode(subject string, data []byte, vPtr interface{}) error
+}
+
+// saramaEncoder has the method set of sarama.Encoder.
+type saramaEncoder interface {
+	Encode() ([]byte, error)
+	Length() int
+}
+
+var (
+	_ natsEncoder   = mqcodec.NATS{}
+	_ saramaEncoder = (*mqcodec.KafkaEncoder)(nil)
+)
+
+func newPayload(t *testing.T) mqcodec.Payload {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := air.NewRootZdate(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetYear(2001)
+	return mqcodec.Payload{TypeID: air.Zdate_TypeID, Message: msg}
+}
+
+func checkPayload(t *testing.T, name string, p mqcodec.Payload) {
+	if p.TypeID != air.Zdate_TypeID {
+		t.Errorf("%s: TypeID = %#x; want %#x", name, p.TypeID, uint64(air.Zdate_TypeID))
+	}
+	root, err := p.Root()
+	if err != nil {
+		t.Errorf("%s: Root: %v", name, err)
+		return
+	}
+	if y := (air.Zdate{Struct: root}).Year(); y != 2001 {
+		t.Errorf("%s: Year() = %d; want 2001", name, y)
+	}
+}
+
+func TestNATS(t *testing.T) {
+	for _, packed := range []bool{false, true} {
+		enc := mqcodec.NATS{Packed: packed}
+		p := newPayload(t)
+		for _, v := range []interface{}{p, &p} {
+			data, err := enc.Encode("subj", v)
+			if err != nil {
+				t.Errorf("packed=%t: Encode(%T): %v", packed, v, err)
+				continue
+			}
+			var got mqcodec.Payload
+			if err := enc.Decode("subj", data, &got); err != nil {
+				t.Errorf("packed=%t: Decode: %v", packed, err)
+				continue
+			}
+			checkPayload(t, "NATS", got)
+		}
+	}
+}
+
+func TestNATSBadValue(t *testing.T) {
+	var enc mqcodec.NATS
+	if _, err := enc.Encode("subj", "hello"); err == nil {
+		t.Error("Encode(string) succeeded")
+	}
+	data, err := enc.Encode("subj", newPayload(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := enc.Decode("subj", data, &s); err == nil {
+		t.Error("Decode into *string succeeded")
+	}
+}
+
+func TestKafkaEncoder(t *testing.T) {
+	for _, packed := range []bool{false, true} {
+		e := mqcodec.NewKafkaEncoder(newPayload(t), packed)
+		data, err := e.Encode()
+		if err != nil {
+			t.Errorf("packed=%t: Encode: %v", packed, err)
+			continue
+		}
+		if e.Length() != len(data) {
+			t.Errorf("packed=%t: Length() = %d; want %d", packed, e.Length(), len(data))
+		}
+		p, err := mqcodec.Unmarshal(data, packed)
+		if err != nil {
+			t.Errorf("packed=%t: Unmarshal: %v", packed, err)
+			continue
+		}
+		checkPayload(t, "Kafka", p)
+	}
+}
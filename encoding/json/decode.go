@@ -0,0 +1,528 @@
+package json
+
+import (
+	"bytes"
+	"encoding/base64"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// parse decodes data into maps, slices, strings, bools, numbers, and
+// nils.
+func parse(data []byte) (interface{}, error) {
+	d := stdjson.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("json: %v", err)
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("json: data after top-level value")
+	}
+	return v, nil
+}
+
+type decoder struct {
+	nodes *nodemap.Map
+}
+
+func (dec *decoder) unmarshalStruct(typeID uint64, v interface{}, s capnp.Struct) error {
+	if text, ok := v.(string); ok {
+		if wk := wellKnownTypes[typeID]; wk != nil {
+			return wk.unmarshal(text, s)
+		}
+	}
+	n, err := findStruct(dec.nodes, typeID)
+	if err != nil {
+		return err
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		dn, _ := n.DisplayName()
+		return fmt.Errorf("json: %s is %s, not an object", dn, describe(v))
+	}
+	fields := make(map[string]schema.Field)
+	for _, f := range codeOrderFields(n.StructNode()) {
+		name, err := f.Name()
+		if err != nil {
+			return err
+		}
+		fields[name] = f
+	}
+	var member string // union member seen
+	for name, fv := range obj {
+		f, ok := fields[name]
+		if !ok {
+			dn, _ := n.DisplayName()
+			return fmt.Errorf("json: %s has no field %q", dn, name)
+		}
+		if dv := f.DiscriminantValue(); dv != schema.Field_noDiscriminant {
+			if member != "" {
+				return fmt.Errorf("json: fields %q and %q are members of the same union", member, name)
+			}
+			member = name
+			s.SetUint16(capnp.DataOffset(n.StructNode().DiscriminantOffset()*2), dv)
+		}
+		switch f.Which() {
+		case schema.Field_Which_slot:
+			if err := dec.unmarshalField(s, f, fv); err != nil {
+				return fmt.Errorf("json: field %s: %v", name, err)
+			}
+		case schema.Field_Which_group:
+			if err := dec.unmarshalStruct(f.Group().TypeId(), fv, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (dec *decoder) unmarshalField(s capnp.Struct, f schema.Field, v interface{}) error {
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return err
+	}
+	dv, err := f.Slot().DefaultValue()
+	if err != nil {
+		return err
+	}
+	off := f.Slot().Offset()
+	switch typ.Which() {
+	case schema.Type_Which_void:
+		if v != nil {
+			return fmt.Errorf("void value is %s, not null", describe(v))
+		}
+	case schema.Type_Which_bool:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("bool value is %s", describe(v))
+		}
+		s.SetBit(capnp.BitOffset(off), b != dv.Bool())
+	case schema.Type_Which_int8:
+		i, err := parseInt(v, 8)
+		if err != nil {
+			return err
+		}
+		s.SetUint8(capnp.DataOffset(off), uint8(i)^uint8(dv.Int8()))
+	case schema.Type_Which_int16:
+		i, err := parseInt(v, 16)
+		if err != nil {
+			return err
+		}
+		s.SetUint16(capnp.DataOffset(off*2), uint16(i)^uint16(dv.Int16()))
+	case schema.Type_Which_int32:
+		i, err := parseInt(v, 32)
+		if err != nil {
+			return err
+		}
+		s.SetUint32(capnp.DataOffset(off*4), uint32(i)^uint32(dv.Int32()))
+	case schema.Type_Which_int64:
+		i, err := parseInt(v, 64)
+		if err != nil {
+			return err
+		}
+		s.SetUint64(capnp.DataOffset(off*8), uint64(i)^uint64(dv.Int64()))
+	case schema.Type_Which_uint8:
+		i, err := parseUint(v, 8)
+		if err != nil {
+			return err
+		}
+		s.SetUint8(capnp.DataOffset(off), uint8(i)^dv.Uint8())
+	case schema.Type_Which_uint16:
+		i, err := parseUint(v, 16)
+		if err != nil {
+			return err
+		}
+		s.SetUint16(capnp.DataOffset(off*2), uint16(i)^dv.Uint16())
+	case schema.Type_Which_uint32:
+		i, err := parseUint(v, 32)
+		if err != nil {
+			return err
+		}
+		s.SetUint32(capnp.DataOffset(off*4), uint32(i)^dv.Uint32())
+	case schema.Type_Which_uint64:
+		i, err := parseUint(v, 64)
+		if err != nil {
+			return err
+		}
+		s.SetUint64(capnp.DataOffset(off*8), i^dv.Uint64())
+	case schema.Type_Which_float32:
+		x, err := parseFloat(v, 32)
+		if err != nil {
+			return err
+		}
+		s.SetUint32(capnp.DataOffset(off*4), math.Float32bits(float32(x))^math.Float32bits(dv.Float32()))
+	case schema.Type_Which_float64:
+		x, err := parseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		s.SetUint64(capnp.DataOffset(off*8), math.Float64bits(x)^math.Float64bits(dv.Float64()))
+	case schema.Type_Which_enum:
+		e, err := dec.parseEnum(typ.Enum().TypeId(), v)
+		if err != nil {
+			return err
+		}
+		s.SetUint16(capnp.DataOffset(off*2), e^dv.Uint16())
+	default:
+		p, err := dec.newPtr(s.Segment(), typ, v)
+		if err != nil {
+			return err
+		}
+		return s.SetPtr(uint16(off), p)
+	}
+	return nil
+}
+
+// newPtr allocates the value of a pointer type in seg.
+func (dec *decoder) newPtr(seg *capnp.Segment, typ schema.Type, v interface{}) (capnp.Ptr, error) {
+	if v == nil {
+		return capnp.Ptr{}, nil
+	}
+	switch typ.Which() {
+	case schema.Type_Which_text:
+		str, ok := v.(string)
+		if !ok {
+			return capnp.Ptr{}, fmt.Errorf("text value is %s", describe(v))
+		}
+		t, err := capnp.NewText(seg, str)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		return t.List.ToPtr(), nil
+	case schema.Type_Which_data:
+		b, err := parseData(v)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		d, err := capnp.NewData(seg, b)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		return d.List.ToPtr(), nil
+	case schema.Type_Which_structType:
+		n, err := findStruct(dec.nodes, typ.StructType().TypeId())
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		s, err := capnp.NewStruct(seg, structSize(n))
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		if err := dec.unmarshalStruct(typ.StructType().TypeId(), v, s); err != nil {
+			return capnp.Ptr{}, err
+		}
+		return s.ToPtr(), nil
+	case schema.Type_Which_list:
+		elem, err := typ.List().ElementType()
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return capnp.Ptr{}, fmt.Errorf("list value is %s, not an array", describe(v))
+		}
+		l, err := dec.newList(seg, elem, arr)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		return l.ToPtr(), nil
+	case schema.Type_Which_interface:
+		return capnp.Ptr{}, fmt.Errorf("interface value is %s, not null", describe(v))
+	case schema.Type_Which_anyPointer:
+		b, err := parseData(v)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		msg, err := capnp.Unmarshal(b)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		// The caller's SetPtr copies the pointer into its message.
+		return msg.RootPtr()
+	default:
+		return capnp.Ptr{}, fmt.Errorf("unknown type %v", typ.Which())
+	}
+}
+
+func (dec *decoder) newList(seg *capnp.Segment, elem schema.Type, arr []interface{}) (capnp.List, error) {
+	n := int32(len(arr))
+	switch elem.Which() {
+	case schema.Type_Which_void:
+		for i := range arr {
+			if arr[i] != nil {
+				return capnp.List{}, fmt.Errorf("void value is %s, not null", describe(arr[i]))
+			}
+		}
+		return capnp.NewVoidList(seg, n).List, nil
+	case schema.Type_Which_bool:
+		l, err := capnp.NewBitList(seg, n)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		for i := range arr {
+			b, ok := arr[i].(bool)
+			if !ok {
+				return capnp.List{}, fmt.Errorf("bool value is %s", describe(arr[i]))
+			}
+			l.Set(i, b)
+		}
+		return l.List, nil
+	case schema.Type_Which_int8, schema.Type_Which_int16, schema.Type_Which_int32, schema.Type_Which_int64:
+		return newIntList(seg, elem.Which(), arr)
+	case schema.Type_Which_uint8, schema.Type_Which_uint16, schema.Type_Which_uint32, schema.Type_Which_uint64:
+		return newUintList(seg, elem.Which(), arr)
+	case schema.Type_Which_float32:
+		l, err := capnp.NewFloat32List(seg, n)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		for i := range arr {
+			x, err := parseFloat(arr[i], 32)
+			if err != nil {
+				return capnp.List{}, err
+			}
+			l.Set(i, float32(x))
+		}
+		return l.List, nil
+	case schema.Type_Which_float64:
+		l, err := capnp.NewFloat64List(seg, n)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		for i := range arr {
+			x, err := parseFloat(arr[i], 64)
+			if err != nil {
+				return capnp.List{}, err
+			}
+			l.Set(i, x)
+		}
+		return l.List, nil
+	case schema.Type_Which_enum:
+		l, err := capnp.NewUInt16List(seg, n)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		for i := range arr {
+			e, err := dec.parseEnum(elem.Enum().TypeId(), arr[i])
+			if err != nil {
+				return capnp.List{}, err
+			}
+			l.Set(i, e)
+		}
+		return l.List, nil
+	case schema.Type_Which_structType:
+		sn, err := findStruct(dec.nodes, elem.StructType().TypeId())
+		if err != nil {
+			return capnp.List{}, err
+		}
+		l, err := capnp.NewCompositeList(seg, structSize(sn), n)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		for i := range arr {
+			if err := dec.unmarshalStruct(elem.StructType().TypeId(), arr[i], l.Struct(i)); err != nil {
+				return capnp.List{}, err
+			}
+		}
+		return l, nil
+	default:
+		l, err := capnp.NewPointerList(seg, n)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		for i := range arr {
+			p, err := dec.newPtr(seg, elem, arr[i])
+			if err != nil {
+				return capnp.List{}, err
+			}
+			if err := l.SetPtr(i, p); err != nil {
+				return capnp.List{}, err
+			}
+		}
+		return l.List, nil
+	}
+}
+
+func newIntList(seg *capnp.Segment, w schema.Type_Which, arr []interface{}) (capnp.List, error) {
+	n := int32(len(arr))
+	var l capnp.List
+	var set func(i int, x int64)
+	var bits int
+	var err error
+	switch w {
+	case schema.Type_Which_int8:
+		var il capnp.Int8List
+		il, err = capnp.NewInt8List(seg, n)
+		l, bits, set = il.List, 8, func(i int, x int64) { il.Set(i, int8(x)) }
+	case schema.Type_Which_int16:
+		var il capnp.Int16List
+		il, err = capnp.NewInt16List(seg, n)
+		l, bits, set = il.List, 16, func(i int, x int64) { il.Set(i, int16(x)) }
+	case schema.Type_Which_int32:
+		var il capnp.Int32List
+		il, err = capnp.NewInt32List(seg, n)
+		l, bits, set = il.List, 32, func(i int, x int64) { il.Set(i, int32(x)) }
+	default:
+		var il capnp.Int64List
+		il, err = capnp.NewInt64List(seg, n)
+		l, bits, set = il.List, 64, func(i int, x int64) { il.Set(i, x) }
+	}
+	if err != nil {
+		return capnp.List{}, err
+	}
+	for i := range arr {
+		x, err := parseInt(arr[i], bits)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		set(i, x)
+	}
+	return l, nil
+}
+
+func newUintList(seg *capnp.Segment, w schema.Type_Which, arr []interface{}) (capnp.List, error) {
+	n := int32(len(arr))
+	var l capnp.List
+	var set func(i int, x uint64)
+	var bits int
+	var err error
+	switch w {
+	case schema.Type_Which_uint8:
+		var ul capnp.UInt8List
+		ul, err = capnp.NewUInt8List(seg, n)
+		l, bits, set = ul.List, 8, func(i int, x uint64) { ul.Set(i, uint8(x)) }
+	case schema.Type_Which_uint16:
+		var ul capnp.UInt16List
+		ul, err = capnp.NewUInt16List(seg, n)
+		l, bits, set = ul.List, 16, func(i int, x uint64) { ul.Set(i, uint16(x)) }
+	case schema.Type_Which_uint32:
+		var ul capnp.UInt32List
+		ul, err = capnp.NewUInt32List(seg, n)
+		l, bits, set = ul.List, 32, func(i int, x uint64) { ul.Set(i, uint32(x)) }
+	default:
+		var ul capnp.UInt64List
+		ul, err = capnp.NewUInt64List(seg, n)
+		l, bits, set = ul.List, 64, func(i int, x uint64) { ul.Set(i, x) }
+	}
+	if err != nil {
+		return capnp.List{}, err
+	}
+	for i := range arr {
+		x, err := parseUint(arr[i], bits)
+		if err != nil {
+			return capnp.List{}, err
+		}
+		set(i, x)
+	}
+	return l, nil
+}
+
+func (dec *decoder) parseEnum(typeID uint64, v interface{}) (uint16, error) {
+	if _, ok := v.(stdjson.Number); ok {
+		x, err := parseUint(v, 16)
+		return uint16(x), err
+	}
+	name, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("enum value is %s", describe(v))
+	}
+	n, err := dec.nodes.Find(typeID)
+	if err != nil {
+		return 0, err
+	}
+	if n.Which() != schema.Node_Which_enum {
+		return 0, fmt.Errorf("type %#x is not an enum", typeID)
+	}
+	enums, err := n.Enum().Enumerants()
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < enums.Len(); i++ {
+		if ename, _ := enums.At(i).Name(); ename == name {
+			return uint16(i), nil
+		}
+	}
+	dn, _ := n.DisplayName()
+	return 0, fmt.Errorf("%s has no enumerant %q", dn, name)
+}
+
+// numberText returns the text of a number, or of a string holding one.
+func numberText(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case stdjson.Number:
+		return string(v), true
+	case string:
+		return v, true
+	default:
+		return "", false
+	}
+}
+
+func parseInt(v interface{}, bits int) (int64, error) {
+	s, ok := numberText(v)
+	if !ok {
+		return 0, fmt.Errorf("integer value is %s", describe(v))
+	}
+	return strconv.ParseInt(s, 10, bits)
+}
+
+func parseUint(v interface{}, bits int) (uint64, error) {
+	s, ok := numberText(v)
+	if !ok {
+		return 0, fmt.Errorf("integer value is %s", describe(v))
+	}
+	return strconv.ParseUint(s, 10, bits)
+}
+
+func parseFloat(v interface{}, bits int) (float64, error) {
+	switch v {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	num, ok := v.(stdjson.Number)
+	if !ok {
+		return 0, fmt.Errorf("float value is %s", describe(v))
+	}
+	return strconv.ParseFloat(string(num), bits)
+}
+
+func parseData(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("data value is %s, not a base64 string", describe(v))
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// describe returns the kind of a parsed JSON value for error messages.
+func describe(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a bool"
+	case stdjson.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,531 @@
+// Package json converts Cap'n Proto structs to and from JSON based on a
+// schema.
+//
+// A struct is encoded as a JSON object with a member for each of its
+// fields in code order.  Only the active member of a union is encoded,
+// and a group is encoded as a nested object.  Pointer fields that are
+// null and have no default value are left out, unless they are the
+// active member of a union.  The remaining field types are encoded as
+// follows:
+//
+//	Void                  null
+//	Bool                  true or false
+//	integers              a number
+//	Float32, Float64      a number, or "NaN", "Infinity", or "-Infinity"
+//	Text                  a string
+//	Data                  a base64 string
+//	enums                 the enumerant's name, or a number if unknown
+//	lists                 an array
+//	UUID (wellknown)      a string, like "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"
+//	Decimal (wellknown)   a string, like "-12.50"
+//	interfaces            null
+//	AnyPointer            a base64 string of a message holding the pointer
+//
+// The UUID and Decimal structs are the ones in std/wellknown.capnp.
+// Decoding accepts the same forms, and also integers given as strings
+// and well-known structs given as objects.
+// Fields that are not present in the JSON are left unset, so they have
+// their default values.  Schema describes the encoding of a struct type
+// as a JSON Schema.
+package json
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+// Marshal returns the JSON encoding of s, which is a struct of the
+// type typeID.
+func Marshal(typeID uint64, s capnp.Struct) ([]byte, error) {
+	return new(Codec).Marshal(typeID, s)
+}
+
+// Unmarshal decodes the JSON encoding of a struct of the type typeID
+// into the root of a new message.
+func Unmarshal(typeID uint64, data []byte) (capnp.Struct, error) {
+	return new(Codec).Unmarshal(typeID, data)
+}
+
+// A Codec converts structs to and from JSON using the schemas in a
+// registry.  The zero value uses the default registry.  A Codec must
+// not be used concurrently.
+type Codec struct {
+	nodes nodemap.Map
+}
+
+// UseRegistry changes the registry that the codec consults for schemas
+// from the default registry.
+func (c *Codec) UseRegistry(reg *schemas.Registry) {
+	c.nodes.UseRegistry(reg)
+}
+
+// Marshal returns the JSON encoding of s, which is a struct of the
+// type typeID.
+func (c *Codec) Marshal(typeID uint64, s capnp.Struct) ([]byte, error) {
+	enc := encoder{nodes: &c.nodes}
+	if err := enc.marshalStruct(typeID, s); err != nil {
+		return nil, err
+	}
+	return enc.buf, nil
+}
+
+// Unmarshal decodes the JSON encoding of a struct of the type typeID
+// into the root of a new message.
+func (c *Codec) Unmarshal(typeID uint64, data []byte) (capnp.Struct, error) {
+	n, err := findStruct(&c.nodes, typeID)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	s, err := capnp.NewRootStruct(seg, structSize(n))
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	if err := c.UnmarshalStruct(typeID, data, s); err != nil {
+		return capnp.Struct{}, err
+	}
+	return s, nil
+}
+
+// UnmarshalStruct decodes the JSON encoding of a struct of the type
+// typeID into s, which must already be allocated.  Fields that are not
+// present in data are left unchanged.
+func (c *Codec) UnmarshalStruct(typeID uint64, data []byte, s capnp.Struct) error {
+	v, err := parse(data)
+	if err != nil {
+		return err
+	}
+	dec := decoder{nodes: &c.nodes}
+	return dec.unmarshalStruct(typeID, v, s)
+}
+
+type encoder struct {
+	nodes *nodemap.Map
+	buf   []byte
+}
+
+func (enc *encoder) marshalStruct(typeID uint64, s capnp.Struct) error {
+	if wk := wellKnownTypes[typeID]; wk != nil {
+		text, err := wk.marshal(s)
+		if err != nil {
+			return err
+		}
+		enc.buf = appendString(enc.buf, text)
+		return nil
+	}
+	n, err := findStruct(enc.nodes, typeID)
+	if err != nil {
+		return err
+	}
+	var discriminant uint16
+	if n.StructNode().DiscriminantCount() > 0 {
+		discriminant = s.Uint16(capnp.DataOffset(n.StructNode().DiscriminantOffset() * 2))
+	}
+	enc.buf = append(enc.buf, '{')
+	first := true
+	for _, f := range codeOrderFields(n.StructNode()) {
+		dv := f.DiscriminantValue()
+		if !(dv == schema.Field_noDiscriminant || dv == discriminant) {
+			continue
+		}
+		if dv == schema.Field_noDiscriminant && f.Which() == schema.Field_Which_slot {
+			if omit, err := omitSlot(s, f); err != nil {
+				return err
+			} else if omit {
+				continue
+			}
+		}
+		if !first {
+			enc.buf = append(enc.buf, ',')
+		}
+		first = false
+		name, err := f.Name()
+		if err != nil {
+			return err
+		}
+		enc.buf = appendString(enc.buf, name)
+		enc.buf = append(enc.buf, ':')
+		switch f.Which() {
+		case schema.Field_Which_slot:
+			if err := enc.marshalField(s, f); err != nil {
+				return fmt.Errorf("json: field %s: %v", name, err)
+			}
+		case schema.Field_Which_group:
+			if err := enc.marshalStruct(f.Group().TypeId(), s); err != nil {
+				return err
+			}
+		}
+	}
+	enc.buf = append(enc.buf, '}')
+	return nil
+}
+
+// omitSlot reports whether a slot field is a null pointer with no
+// default value.
+func omitSlot(s capnp.Struct, f schema.Field) (bool, error) {
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return false, err
+	}
+	if !isPointerType(typ.Which()) {
+		return false, nil
+	}
+	p, err := s.Ptr(uint16(f.Slot().Offset()))
+	if err != nil || p.IsValid() {
+		return false, err
+	}
+	return !f.Slot().HadExplicitDefault(), nil
+}
+
+func (enc *encoder) marshalField(s capnp.Struct, f schema.Field) error {
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return err
+	}
+	dv, err := f.Slot().DefaultValue()
+	if err != nil {
+		return err
+	}
+	if dv.IsValid() && int(typ.Which()) != int(dv.Which()) {
+		return fmt.Errorf("default value is a %v, want %v", dv.Which(), typ.Which())
+	}
+	off := f.Slot().Offset()
+	switch typ.Which() {
+	case schema.Type_Which_void:
+		enc.buf = append(enc.buf, "null"...)
+	case schema.Type_Which_bool:
+		v := s.Bit(capnp.BitOffset(off))
+		enc.buf = strconv.AppendBool(enc.buf, v != dv.Bool())
+	case schema.Type_Which_int8:
+		v := s.Uint8(capnp.DataOffset(off)) ^ uint8(dv.Int8())
+		enc.buf = strconv.AppendInt(enc.buf, int64(int8(v)), 10)
+	case schema.Type_Which_int16:
+		v := s.Uint16(capnp.DataOffset(off*2)) ^ uint16(dv.Int16())
+		enc.buf = strconv.AppendInt(enc.buf, int64(int16(v)), 10)
+	case schema.Type_Which_int32:
+		v := s.Uint32(capnp.DataOffset(off*4)) ^ uint32(dv.Int32())
+		enc.buf = strconv.AppendInt(enc.buf, int64(int32(v)), 10)
+	case schema.Type_Which_int64:
+		v := s.Uint64(capnp.DataOffset(off*8)) ^ uint64(dv.Int64())
+		enc.buf = strconv.AppendInt(enc.buf, int64(v), 10)
+	case schema.Type_Which_uint8:
+		v := s.Uint8(capnp.DataOffset(off)) ^ dv.Uint8()
+		enc.buf = strconv.AppendUint(enc.buf, uint64(v), 10)
+	case schema.Type_Which_uint16:
+		v := s.Uint16(capnp.DataOffset(off*2)) ^ dv.Uint16()
+		enc.buf = strconv.AppendUint(enc.buf, uint64(v), 10)
+	case schema.Type_Which_uint32:
+		v := s.Uint32(capnp.DataOffset(off*4)) ^ dv.Uint32()
+		enc.buf = strconv.AppendUint(enc.buf, uint64(v), 10)
+	case schema.Type_Which_uint64:
+		v := s.Uint64(capnp.DataOffset(off*8)) ^ dv.Uint64()
+		enc.buf = strconv.AppendUint(enc.buf, v, 10)
+	case schema.Type_Which_float32:
+		v := s.Uint32(capnp.DataOffset(off*4)) ^ math.Float32bits(dv.Float32())
+		enc.marshalFloat(float64(math.Float32frombits(v)), 32)
+	case schema.Type_Which_float64:
+		v := s.Uint64(capnp.DataOffset(off*8)) ^ math.Float64bits(dv.Float64())
+		enc.marshalFloat(math.Float64frombits(v), 64)
+	case schema.Type_Which_enum:
+		v := s.Uint16(capnp.DataOffset(off*2)) ^ dv.Uint16()
+		return enc.marshalEnum(typ.Enum().TypeId(), v)
+	case schema.Type_Which_text:
+		p, err := s.Ptr(uint16(off))
+		if err != nil {
+			return err
+		}
+		if !p.IsValid() && dv.IsValid() {
+			b, _ := dv.TextBytes()
+			enc.buf = appendString(enc.buf, string(b))
+			return nil
+		}
+		enc.buf = appendString(enc.buf, string(p.TextBytes()))
+	case schema.Type_Which_data:
+		p, err := s.Ptr(uint16(off))
+		if err != nil {
+			return err
+		}
+		if !p.IsValid() && dv.IsValid() {
+			b, _ := dv.Data()
+			enc.marshalData(b)
+			return nil
+		}
+		enc.marshalData(p.Data())
+	case schema.Type_Which_structType:
+		p, err := s.Ptr(uint16(off))
+		if err != nil {
+			return err
+		}
+		if !p.IsValid() {
+			p, _ = dv.StructValuePtr()
+		}
+		if !p.IsValid() {
+			enc.buf = append(enc.buf, "null"...)
+			return nil
+		}
+		return enc.marshalStruct(typ.StructType().TypeId(), p.Struct())
+	case schema.Type_Which_list:
+		elem, err := typ.List().ElementType()
+		if err != nil {
+			return err
+		}
+		p, err := s.Ptr(uint16(off))
+		if err != nil {
+			return err
+		}
+		if !p.IsValid() {
+			p, _ = dv.ListPtr()
+		}
+		if !p.IsValid() {
+			enc.buf = append(enc.buf, "null"...)
+			return nil
+		}
+		return enc.marshalList(elem, p.List())
+	case schema.Type_Which_interface:
+		enc.buf = append(enc.buf, "null"...)
+	case schema.Type_Which_anyPointer:
+		p, err := s.Ptr(uint16(off))
+		if err != nil {
+			return err
+		}
+		if !p.IsValid() {
+			p, _ = dv.AnyPointerPtr()
+		}
+		return enc.marshalAnyPointer(p)
+	default:
+		return fmt.Errorf("unknown field type %v", typ.Which())
+	}
+	return nil
+}
+
+func (enc *encoder) marshalFloat(f float64, bits int) {
+	switch {
+	case math.IsNaN(f):
+		enc.buf = append(enc.buf, `"NaN"`...)
+	case math.IsInf(f, 1):
+		enc.buf = append(enc.buf, `"Infinity"`...)
+	case math.IsInf(f, -1):
+		enc.buf = append(enc.buf, `"-Infinity"`...)
+	default:
+		enc.buf = strconv.AppendFloat(enc.buf, f, 'g', -1, bits)
+	}
+}
+
+func (enc *encoder) marshalData(b []byte) {
+	enc.buf = append(enc.buf, '"')
+	n := len(enc.buf)
+	enc.buf = append(enc.buf, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+	base64.StdEncoding.Encode(enc.buf[n:], b)
+	enc.buf = append(enc.buf, '"')
+}
+
+func (enc *encoder) marshalAnyPointer(p capnp.Ptr) error {
+	if !p.IsValid() {
+		enc.buf = append(enc.buf, "null"...)
+		return nil
+	}
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return err
+	}
+	if err := msg.SetRootPtr(p); err != nil {
+		return err
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+	enc.marshalData(data)
+	return nil
+}
+
+func (enc *encoder) marshalEnum(typeID uint64, val uint16) error {
+	n, err := enc.nodes.Find(typeID)
+	if err != nil {
+		return err
+	}
+	if n.Which() != schema.Node_Which_enum {
+		return fmt.Errorf("type %#x is not an enum", typeID)
+	}
+	enums, err := n.Enum().Enumerants()
+	if err != nil {
+		return err
+	}
+	if int(val) >= enums.Len() {
+		enc.buf = strconv.AppendUint(enc.buf, uint64(val), 10)
+		return nil
+	}
+	name, err := enums.At(int(val)).Name()
+	if err != nil {
+		return err
+	}
+	enc.buf = appendString(enc.buf, name)
+	return nil
+}
+
+func (enc *encoder) marshalList(elem schema.Type, l capnp.List) error {
+	enc.buf = append(enc.buf, '[')
+	for i := 0; i < l.Len(); i++ {
+		if i > 0 {
+			enc.buf = append(enc.buf, ',')
+		}
+		if err := enc.marshalElem(elem, l, i); err != nil {
+			return err
+		}
+	}
+	enc.buf = append(enc.buf, ']')
+	return nil
+}
+
+func (enc *encoder) marshalElem(elem schema.Type, l capnp.List, i int) error {
+	switch elem.Which() {
+	case schema.Type_Which_void, schema.Type_Which_interface:
+		enc.buf = append(enc.buf, "null"...)
+	case schema.Type_Which_bool:
+		enc.buf = strconv.AppendBool(enc.buf, capnp.BitList{List: l}.At(i))
+	case schema.Type_Which_int8:
+		enc.buf = strconv.AppendInt(enc.buf, int64(capnp.Int8List{List: l}.At(i)), 10)
+	case schema.Type_Which_int16:
+		enc.buf = strconv.AppendInt(enc.buf, int64(capnp.Int16List{List: l}.At(i)), 10)
+	case schema.Type_Which_int32:
+		enc.buf = strconv.AppendInt(enc.buf, int64(capnp.Int32List{List: l}.At(i)), 10)
+	case schema.Type_Which_int64:
+		enc.buf = strconv.AppendInt(enc.buf, capnp.Int64List{List: l}.At(i), 10)
+	case schema.Type_Which_uint8:
+		enc.buf = strconv.AppendUint(enc.buf, uint64(capnp.UInt8List{List: l}.At(i)), 10)
+	case schema.Type_Which_uint16:
+		enc.buf = strconv.AppendUint(enc.buf, uint64(capnp.UInt16List{List: l}.At(i)), 10)
+	case schema.Type_Which_uint32:
+		enc.buf = strconv.AppendUint(enc.buf, uint64(capnp.UInt32List{List: l}.At(i)), 10)
+	case schema.Type_Which_uint64:
+		enc.buf = strconv.AppendUint(enc.buf, capnp.UInt64List{List: l}.At(i), 10)
+	case schema.Type_Which_float32:
+		enc.marshalFloat(float64(capnp.Float32List{List: l}.At(i)), 32)
+	case schema.Type_Which_float64:
+		enc.marshalFloat(capnp.Float64List{List: l}.At(i), 64)
+	case schema.Type_Which_enum:
+		return enc.marshalEnum(elem.Enum().TypeId(), capnp.UInt16List{List: l}.At(i))
+	case schema.Type_Which_text:
+		b, err := capnp.TextList{List: l}.BytesAt(i)
+		if err != nil {
+			return err
+		}
+		enc.buf = appendString(enc.buf, string(b))
+	case schema.Type_Which_data:
+		b, err := capnp.DataList{List: l}.At(i)
+		if err != nil {
+			return err
+		}
+		enc.marshalData(b)
+	case schema.Type_Which_structType:
+		return enc.marshalStruct(elem.StructType().TypeId(), l.Struct(i))
+	case schema.Type_Which_list:
+		ee, err := elem.List().ElementType()
+		if err != nil {
+			return err
+		}
+		p, err := capnp.PointerList{List: l}.PtrAt(i)
+		if err != nil {
+			return err
+		}
+		if !p.IsValid() {
+			enc.buf = append(enc.buf, "null"...)
+			return nil
+		}
+		return enc.marshalList(ee, p.List())
+	case schema.Type_Which_anyPointer:
+		p, err := capnp.PointerList{List: l}.PtrAt(i)
+		if err != nil {
+			return err
+		}
+		return enc.marshalAnyPointer(p)
+	default:
+		return fmt.Errorf("json: unknown list type %v", elem.Which())
+	}
+	return nil
+}
+
+// appendString appends s to b as a JSON string.  Invalid UTF-8 is
+// replaced with U+FFFD.
+func appendString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < 0x20:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "�"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
+
+func findStruct(nodes *nodemap.Map, typeID uint64) (schema.Node, error) {
+	n, err := nodes.Find(typeID)
+	if err != nil {
+		return schema.Node{}, err
+	}
+	if !n.IsValid() || n.Which() != schema.Node_Which_structNode {
+		return schema.Node{}, fmt.Errorf("json: cannot find struct type %#x", typeID)
+	}
+	return n, nil
+}
+
+func structSize(n schema.Node) capnp.ObjectSize {
+	return capnp.ObjectSize{
+		DataSize:     capnp.Size(n.StructNode().DataWordCount()) * 8,
+		PointerCount: n.StructNode().PointerCount(),
+	}
+}
+
+func codeOrderFields(s schema.Node_structNode) []schema.Field {
+	list, _ := s.Fields()
+	n := list.Len()
+	fields := make([]schema.Field, n)
+	for i := 0; i < n; i++ {
+		f := list.At(i)
+		fields[f.CodeOrder()] = f
+	}
+	return fields
+}
+
+func isPointerType(w schema.Type_Which) bool {
+	switch w {
+	case schema.Type_Which_text, schema.Type_Which_data, schema.Type_Which_list,
+		schema.Type_Which_structType, schema.Type_Which_interface, schema.Type_Which_anyPointer:
+		return true
+	}
+	return false
+}
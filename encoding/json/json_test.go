@@ -0,0 +1,260 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/std/capnp/schema"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		make func(air.Z) error
+		want string
+	}{
+		{"void", func(z air.Z) error { z.SetVoid(); return nil }, `{"void":null}`},
+		{"i64", func(z air.Z) error { z.SetI64(-5); return nil }, `{"i64":-5}`},
+		{"u64", func(z air.Z) error { z.SetU64(1 << 63); return nil }, `{"u64":9223372036854775808}`},
+		{"f32", func(z air.Z) error { z.SetF32(1.5); return nil }, `{"f32":1.5}`},
+		{"bool", func(z air.Z) error { z.SetBool(true); return nil }, `{"bool":true}`},
+		{"text", func(z air.Z) error { return z.SetText("a\"b\n\x01") }, `{"text":"a\"b\n\u0001"}`},
+		{"blob", func(z air.Z) error { return z.SetBlob([]byte("hi!")) }, `{"blob":"aGkh"}`},
+		{"nullText", func(z air.Z) error { return z.SetText("") }, `{"text":""}`},
+		{"airport", func(z air.Z) error { z.SetAirport(air.Airport_lax); return nil }, `{"airport":"lax"}`},
+		{"unknownEnum", func(z air.Z) error { z.SetAirport(air.Airport(99)); return nil }, `{"airport":99}`},
+		{"grp", func(z air.Z) error {
+			z.SetGrp()
+			z.Grp().SetFirst(1)
+			z.Grp().SetSecond(2)
+			return nil
+		}, `{"grp":{"first":1,"second":2}}`},
+		{"f64vec", func(z air.Z) error {
+			l, err := z.NewF64vec(2)
+			if err != nil {
+				return err
+			}
+			l.Set(0, 2.5)
+			l.Set(1, -1)
+			return nil
+		}, `{"f64vec":[2.5,-1]}`},
+		{"planebase", func(z air.Z) error {
+			pb, err := z.NewPlanebase()
+			if err != nil {
+				return err
+			}
+			pb.SetName("Boeing")
+			homes, err := pb.NewHomes(2)
+			if err != nil {
+				return err
+			}
+			homes.Set(0, air.Airport_jfk)
+			homes.Set(1, air.Airport_sfo)
+			pb.SetCanFly(true)
+			pb.SetCapacity(100)
+			return nil
+		}, `{"planebase":{"name":"Boeing","homes":["jfk","sfo"],"rating":0,"canFly":true,"capacity":100,"maxSpeed":0}}`},
+		{"nullStruct", func(z air.Z) error {
+			z.SetZdate(air.Zdate{})
+			return nil
+		}, `{"zdate":null}`},
+		{"zvecvec", func(z air.Z) error {
+			l, err := z.NewZvecvec(1)
+			if err != nil {
+				return err
+			}
+			inner, err := air.NewZ_List(z.Segment(), 1)
+			if err != nil {
+				return err
+			}
+			inner.At(0).SetI8(-1)
+			return l.Set(0, inner)
+		}, `{"zvecvec":[[{"i8":-1}]]}`},
+	}
+	for _, test := range tests {
+		_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+		z, err := air.NewRootZ(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := test.make(z); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		got, err := Marshal(air.Z_TypeID, z.Struct)
+		if err != nil {
+			t.Errorf("%s: Marshal: %v", test.name, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: Marshal = %s; want %s", test.name, got, test.want)
+		}
+
+		s, err := Unmarshal(air.Z_TypeID, got)
+		if err != nil {
+			t.Errorf("%s: Unmarshal(%s): %v", test.name, got, err)
+			continue
+		}
+		again, err := Marshal(air.Z_TypeID, s)
+		if err != nil {
+			t.Errorf("%s: Marshal after Unmarshal: %v", test.name, err)
+		} else if !bytes.Equal(again, got) {
+			t.Errorf("%s: Marshal after Unmarshal = %s; want %s", test.name, again, got)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	d, err := air.NewRootDefaults(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Marshal(air.Defaults_TypeID, d.Struct)
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	const want = `{"text":"foo","data":"YmFy","float":3.14,"int":-123,"uint":42}`
+	if string(got) != want {
+		t.Errorf("Marshal = %s; want %s", got, want)
+	}
+
+	s, err := Unmarshal(air.Defaults_TypeID, []byte(`{"int":"7","float":"NaN","text":""}`))
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	d = air.Defaults{Struct: s}
+	if d.Int() != 7 {
+		t.Errorf("int = %d; want 7", d.Int())
+	}
+	if f := d.Float(); f == f {
+		t.Errorf("float = %v; want NaN", f)
+	}
+	if d.Uint() != 42 {
+		t.Errorf("uint = %d; want default 42", d.Uint())
+	}
+	if text, _ := d.Text(); text != "" {
+		t.Errorf("text = %q; want \"\"", text)
+	}
+}
+
+func TestAnyPointer(t *testing.T) {
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	v, err := schema.NewRootValue(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zd, err := air.NewZdate(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zd.SetYear(2016)
+	if err := v.SetAnyPointerPtr(zd.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(schema.Value_TypeID, v.Struct)
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	s, err := Unmarshal(schema.Value_TypeID, data)
+	if err != nil {
+		t.Fatalf("Unmarshal(%s): %v", data, err)
+	}
+	v = schema.Value{Struct: s}
+	if v.Which() != schema.Value_Which_anyPointer {
+		t.Fatalf("which = %v; want anyPointer", v.Which())
+	}
+	p, err := v.AnyPointerPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if year := (air.Zdate{Struct: p.Struct()}).Year(); year != 2016 {
+		t.Errorf("year = %d; want 2016", year)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`{"nope":1}`,
+		`{"i8":300}`,
+		`{"i64":1,"u64":2}`,
+		`{"airport":"ord"}`,
+		`{"blob":"!"}`,
+		`{"echo":"x"}`,
+		`{"void":null} {}`,
+	}
+	for _, data := range tests {
+		if _, err := Unmarshal(air.Z_TypeID, []byte(data)); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded; want error", data)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	got, err := Schema(air.Defaults_TypeID)
+	if err != nil {
+		t.Fatal("Schema:", err)
+	}
+	const want = `{"$schema":"https://json-schema.org/draft/2020-12/schema",` +
+		`"$ref":"#/$defs/aircraft.capnp:Defaults",` +
+		`"$defs":{"aircraft.capnp:Defaults":{"title":"Defaults","type":"object","properties":{` +
+		`"text":{"type":["string","null"],"default":"foo"},` +
+		`"data":{"type":["string","null"],"contentEncoding":"base64"},` +
+		`"float":{"anyOf":[{"type":"number"},{"enum":["NaN","Infinity","-Infinity"]}],"default":3.14},` +
+		`"int":{"type":"integer","minimum":-2147483648,"maximum":2147483647,"default":-123},` +
+		`"uint":{"type":"integer","minimum":0,"maximum":4294967295,"default":42}},` +
+		`"additionalProperties":false}}}`
+	if string(got) != want {
+		t.Errorf("Schema =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchema_Union(t *testing.T) {
+	data, err := Schema(air.Z_TypeID)
+	if err != nil {
+		t.Fatal("Schema:", err)
+	}
+	var doc struct {
+		Ref  string `json:"$ref"`
+		Defs map[string]struct {
+			Properties map[string]map[string]interface{}
+			OneOf      []struct{ Required []string }
+		} `json:"$defs"`
+	}
+	if err := stdjson.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", data, err)
+	}
+	const prefix = "#/$defs/"
+	z, ok := doc.Defs[strings.TrimPrefix(doc.Ref, prefix)]
+	if !ok {
+		t.Fatalf("$ref %q not defined", doc.Ref)
+	}
+	if len(z.OneOf) != len(z.Properties) {
+		t.Errorf("%d union alternatives for %d fields", len(z.OneOf), len(z.Properties))
+	}
+	for _, alt := range z.OneOf {
+		if len(alt.Required) != 1 || z.Properties[alt.Required[0]] == nil {
+			t.Errorf("union alternative requires %q; want one field", alt.Required)
+		}
+	}
+	if typ := fmt.Sprint(z.Properties["i64"]["type"]); typ != "[integer string]" {
+		t.Errorf("i64 type = %s; want [integer string]", typ)
+	}
+	if ref := fmt.Sprint(z.Properties["zvec"]["items"]); ref != "map[anyOf:[map[$ref:"+doc.Ref+"] map[type:null]]]" {
+		t.Errorf("zvec items = %s; want nullable reference to Z", ref)
+	}
+
+	// Every reference is defined.
+	for _, m := range regexp.MustCompile(`"\$ref":"([^"]*)"`).FindAllStringSubmatch(string(data), -1) {
+		if _, ok := doc.Defs[strings.TrimPrefix(m[1], prefix)]; !ok {
+			t.Errorf("$ref %q not defined", m[1])
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect that Schema produces.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns a JSON Schema describing the JSON encoding of structs
+// of the type typeID.  See Codec.Schema.
+func Schema(typeID uint64) ([]byte, error) {
+	return new(Codec).Schema(typeID)
+}
+
+// Schema returns a JSON Schema (draft 2020-12) describing the JSON
+// encoding of structs of the type typeID, so that web frontends can
+// generate forms and validators for them.  Each struct type is defined
+// once under "$defs", named by its display name, and the document
+// refers to the definition of typeID.  Properties are listed in code
+// order.
+//
+// The schema describes what Marshal produces, which Unmarshal also
+// accepts.  Fields are never required, but exactly one member of each
+// union must be present.  Pointers, including list elements, may be
+// null.  Integers are
+// numbers with the type's bounds, except that 64-bit integers may also
+// be strings, since JavaScript numbers cannot represent all of them.
+// Explicit default values of Bool, numeric, enum, and Text fields are
+// given as "default".  The well-known UUID and Decimal structs are
+// described as strings.
+func (c *Codec) Schema(typeID uint64) ([]byte, error) {
+	sg := &schemaGen{
+		nodes: &c.nodes,
+		names: make(map[uint64]string),
+		used:  make(map[string]bool),
+	}
+	ref, err := sg.structRef(typeID)
+	if err != nil {
+		return nil, err
+	}
+	doc := object{
+		{"$schema", jsonSchemaDraft},
+		ref[0],
+		{"$defs", sg.defs},
+	}
+	return doc.MarshalJSON()
+}
+
+type schemaGen struct {
+	nodes *nodemap.Map
+	defs  object            // struct definitions in order of first use
+	names map[uint64]string // struct type ID to definition name
+	used  map[string]bool   // definition names
+}
+
+// structRef returns a reference to the definition of a struct type,
+// adding the definition if necessary.
+func (sg *schemaGen) structRef(typeID uint64) (object, error) {
+	name, ok := sg.names[typeID]
+	if !ok {
+		n, err := findStruct(sg.nodes, typeID)
+		if err != nil {
+			return nil, err
+		}
+		dn, err := n.DisplayName()
+		if err != nil {
+			return nil, err
+		}
+		name = dn
+		if sg.used[name] {
+			name = fmt.Sprintf("%s@%#x", dn, typeID)
+		}
+		sg.names[typeID] = name
+		sg.used[name] = true
+		i := len(sg.defs)
+		sg.defs = append(sg.defs, member{name, nil})
+		var s object
+		if wk := wellKnownTypes[typeID]; wk != nil {
+			s = wk.schema
+		} else if s, err = sg.structSchema(n); err != nil {
+			return nil, err
+		}
+		s = append(object{{"title", dn[n.DisplayNamePrefixLength():]}}, s...)
+		sg.defs[i].val = s
+	}
+	return object{{"$ref", "#/$defs/" + escapePointer(name)}}, nil
+}
+
+// structSchema returns the schema of a struct or group.
+func (sg *schemaGen) structSchema(n schema.Node) (object, error) {
+	var props object
+	var union []interface{}
+	for _, f := range codeOrderFields(n.StructNode()) {
+		name, err := f.Name()
+		if err != nil {
+			return nil, err
+		}
+		var fs object
+		switch f.Which() {
+		case schema.Field_Which_slot:
+			fs, err = sg.fieldSchema(f)
+		case schema.Field_Which_group:
+			var gn schema.Node
+			gn, err = sg.nodes.Find(f.Group().TypeId())
+			if err == nil {
+				fs, err = sg.structSchema(gn)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("json: field %s: %v", name, err)
+		}
+		props = append(props, member{name, fs})
+		if f.DiscriminantValue() != schema.Field_noDiscriminant {
+			union = append(union, object{{"required", []string{name}}})
+		}
+	}
+	s := object{
+		{"type", "object"},
+		{"properties", props},
+		{"additionalProperties", false},
+	}
+	if len(union) > 0 {
+		s = append(s, member{"oneOf", union})
+	}
+	return s, nil
+}
+
+// fieldSchema returns the schema of a slot field.
+func (sg *schemaGen) fieldSchema(f schema.Field) (object, error) {
+	typ, err := f.Slot().Type()
+	if err != nil {
+		return nil, err
+	}
+	s, err := sg.typeSchema(typ)
+	if err != nil {
+		return nil, err
+	}
+	if isPointerType(typ.Which()) && typ.Which() != schema.Type_Which_interface {
+		s = nullable(s)
+	}
+	if f.Slot().HadExplicitDefault() {
+		dv, err := f.Slot().DefaultValue()
+		if err != nil {
+			return nil, err
+		}
+		if def, ok, err := sg.defaultValue(typ, dv); err != nil {
+			return nil, err
+		} else if ok {
+			s = append(s, member{"default", def})
+		}
+	}
+	return s, nil
+}
+
+// typeSchema returns the schema of a value of the type typ.
+func (sg *schemaGen) typeSchema(typ schema.Type) (object, error) {
+	switch typ.Which() {
+	case schema.Type_Which_void, schema.Type_Which_interface:
+		return object{{"type", "null"}}, nil
+	case schema.Type_Which_bool:
+		return object{{"type", "boolean"}}, nil
+	case schema.Type_Which_int8:
+		return intSchema(math.MinInt8, math.MaxInt8), nil
+	case schema.Type_Which_int16:
+		return intSchema(math.MinInt16, math.MaxInt16), nil
+	case schema.Type_Which_int32:
+		return intSchema(math.MinInt32, math.MaxInt32), nil
+	case schema.Type_Which_int64:
+		return object{
+			{"type", []string{"integer", "string"}},
+			{"minimum", int64(math.MinInt64)},
+			{"maximum", int64(math.MaxInt64)},
+			{"pattern", "^-?[0-9]+$"},
+		}, nil
+	case schema.Type_Which_uint8:
+		return intSchema(0, math.MaxUint8), nil
+	case schema.Type_Which_uint16:
+		return intSchema(0, math.MaxUint16), nil
+	case schema.Type_Which_uint32:
+		return intSchema(0, math.MaxUint32), nil
+	case schema.Type_Which_uint64:
+		return object{
+			{"type", []string{"integer", "string"}},
+			{"minimum", 0},
+			{"maximum", uint64(math.MaxUint64)},
+			{"pattern", "^[0-9]+$"},
+		}, nil
+	case schema.Type_Which_float32, schema.Type_Which_float64:
+		return object{{"anyOf", []interface{}{
+			object{{"type", "number"}},
+			object{{"enum", []string{"NaN", "Infinity", "-Infinity"}}},
+		}}}, nil
+	case schema.Type_Which_text:
+		return object{{"type", "string"}}, nil
+	case schema.Type_Which_data:
+		return object{{"type", "string"}, {"contentEncoding", "base64"}}, nil
+	case schema.Type_Which_enum:
+		names, err := sg.enumerants(typ.Enum().TypeId())
+		if err != nil {
+			return nil, err
+		}
+		return object{{"enum", names}}, nil
+	case schema.Type_Which_list:
+		elem, err := typ.List().ElementType()
+		if err != nil {
+			return nil, err
+		}
+		items, err := sg.typeSchema(elem)
+		if err != nil {
+			return nil, err
+		}
+		if isPointerType(elem.Which()) && elem.Which() != schema.Type_Which_interface {
+			items = nullable(items)
+		}
+		return object{{"type", "array"}, {"items", items}}, nil
+	case schema.Type_Which_structType:
+		return sg.structRef(typ.StructType().TypeId())
+	case schema.Type_Which_anyPointer:
+		return object{
+			{"type", "string"},
+			{"contentEncoding", "base64"},
+			{"description", "A Cap'n Proto message holding the pointer."},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %v", typ.Which())
+	}
+}
+
+func (sg *schemaGen) enumerants(typeID uint64) ([]string, error) {
+	n, err := sg.nodes.Find(typeID)
+	if err != nil {
+		return nil, err
+	}
+	if n.Which() != schema.Node_Which_enum {
+		return nil, fmt.Errorf("type %#x is not an enum", typeID)
+	}
+	enums, err := n.Enum().Enumerants()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, enums.Len())
+	for i := range names {
+		if names[i], err = enums.At(i).Name(); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// defaultValue returns the JSON value of a field's default, if it is
+// of a type that has one.
+func (sg *schemaGen) defaultValue(typ schema.Type, dv schema.Value) (v interface{}, ok bool, err error) {
+	switch typ.Which() {
+	case schema.Type_Which_bool:
+		return dv.Bool(), true, nil
+	case schema.Type_Which_int8:
+		return dv.Int8(), true, nil
+	case schema.Type_Which_int16:
+		return dv.Int16(), true, nil
+	case schema.Type_Which_int32:
+		return dv.Int32(), true, nil
+	case schema.Type_Which_int64:
+		return dv.Int64(), true, nil
+	case schema.Type_Which_uint8:
+		return dv.Uint8(), true, nil
+	case schema.Type_Which_uint16:
+		return dv.Uint16(), true, nil
+	case schema.Type_Which_uint32:
+		return dv.Uint32(), true, nil
+	case schema.Type_Which_uint64:
+		return dv.Uint64(), true, nil
+	case schema.Type_Which_float32, schema.Type_Which_float64:
+		var f float64
+		if typ.Which() == schema.Type_Which_float32 {
+			f32 := dv.Float32()
+			f, v = float64(f32), f32
+		} else {
+			f = dv.Float64()
+			v = f
+		}
+		switch {
+		case math.IsNaN(f):
+			return "NaN", true, nil
+		case math.IsInf(f, 1):
+			return "Infinity", true, nil
+		case math.IsInf(f, -1):
+			return "-Infinity", true, nil
+		}
+		return v, true, nil
+	case schema.Type_Which_enum:
+		names, err := sg.enumerants(typ.Enum().TypeId())
+		if err != nil {
+			return nil, false, err
+		}
+		if i := int(dv.Enum()); i < len(names) {
+			return names[i], true, nil
+		}
+		return dv.Enum(), true, nil
+	case schema.Type_Which_text:
+		t, err := dv.Text()
+		return t, err == nil, err
+	default:
+		return nil, false, nil
+	}
+}
+
+func intSchema(min, max int64) object {
+	return object{{"type", "integer"}, {"minimum", min}, {"maximum", max}}
+}
+
+// nullable returns s amended to also accept null.
+func nullable(s object) object {
+	for i, m := range s {
+		if m.key != "type" {
+			continue
+		}
+		t := make(object, len(s))
+		copy(t, s)
+		switch v := m.val.(type) {
+		case string:
+			t[i].val = []string{v, "null"}
+		case []string:
+			t[i].val = append(v[:len(v):len(v)], "null")
+		}
+		return t
+	}
+	return object{{"anyOf", []interface{}{s, object{{"type", "null"}}}}}
+}
+
+// escapePointer escapes s for use as a JSON Pointer reference token.
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// An object is a JSON object whose members are encoded in order.
+type object []member
+
+type member struct {
+	key string
+	val interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for i, m := range o {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendString(b, m.key)
+		b = append(b, ':')
+		v, err := stdjson.Marshal(m.val)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, v...)
+	}
+	return append(b, '}'), nil
+}
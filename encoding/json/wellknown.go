@@ -0,0 +1,41 @@
+package json
+
+import (
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/std/wellknown"
+)
+
+// A wellKnownType is a struct type from std/wellknown.capnp that is
+// encoded as a JSON string rather than an object.
+type wellKnownType struct {
+	marshal   func(s capnp.Struct) (string, error)
+	unmarshal func(text string, s capnp.Struct) error
+	schema    object
+}
+
+var wellKnownTypes = map[uint64]*wellKnownType{
+	wellknown.UUID_TypeID: {
+		marshal: func(s capnp.Struct) (string, error) {
+			return wellknown.UUID{Struct: s}.Text(), nil
+		},
+		unmarshal: func(text string, s capnp.Struct) error {
+			return wellknown.UUID{Struct: s}.SetText(text)
+		},
+		schema: object{
+			{"type", "string"},
+			{"format", "uuid"},
+		},
+	},
+	wellknown.Decimal_TypeID: {
+		marshal: func(s capnp.Struct) (string, error) {
+			return wellknown.Decimal{Struct: s}.Text()
+		},
+		unmarshal: func(text string, s capnp.Struct) error {
+			return wellknown.Decimal{Struct: s}.SetText(text)
+		},
+		schema: object{
+			{"type", "string"},
+			{"pattern", `^[+-]?[0-9]+(\.[0-9]{1,18})?$`},
+		},
+	},
+}
@@ -0,0 +1,80 @@
+package json
+
+import (
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/std/wellknown"
+)
+
+func TestWellKnown(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := wellknown.NewUUIDFrom(seg, [16]byte{0: 0xf8, 15: 0xf6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := wellknown.NewDecimalFrom(seg, -1250, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		typeID uint64
+		s      capnp.Struct
+		want   string
+	}{
+		{wellknown.UUID_TypeID, u.Struct, `"f8000000-0000-0000-0000-0000000000f6"`},
+		{wellknown.Decimal_TypeID, d.Struct, `"-12.50"`},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.typeID, test.s)
+		if err != nil {
+			t.Errorf("Marshal(%#x): %v", test.typeID, err)
+			continue
+		}
+		if string(data) != test.want {
+			t.Errorf("Marshal(%#x) = %s; want %s", test.typeID, data, test.want)
+		}
+		s, err := Unmarshal(test.typeID, data)
+		if err != nil {
+			t.Errorf("Unmarshal(%#x, %s): %v", test.typeID, data, err)
+			continue
+		}
+		if data2, _ := Marshal(test.typeID, s); string(data2) != test.want {
+			t.Errorf("Marshal(Unmarshal(%#x, %s)) = %s", test.typeID, data, data2)
+		}
+	}
+
+	// The object form is accepted too.
+	s, err := Unmarshal(wellknown.Decimal_TypeID, []byte(`{"unscaled":"5","scale":3}`))
+	if err != nil {
+		t.Fatal("Unmarshal decimal object:", err)
+	}
+	if text, _ := (wellknown.Decimal{Struct: s}).Text(); text != "0.005" {
+		t.Errorf("Unmarshal decimal object = %s; want 0.005", text)
+	}
+
+	for _, bad := range []string{`"not-a-uuid"`, `"f8000000-0000-0000-0000-0000000000f"`} {
+		if _, err := Unmarshal(wellknown.UUID_TypeID, []byte(bad)); err == nil {
+			t.Errorf("Unmarshal(UUID, %s) succeeded; want error", bad)
+		}
+	}
+	for _, bad := range []string{`"1e5"`, `"12.5.0"`, `""`} {
+		if _, err := Unmarshal(wellknown.Decimal_TypeID, []byte(bad)); err == nil {
+			t.Errorf("Unmarshal(Decimal, %s) succeeded; want error", bad)
+		}
+	}
+}
+
+func TestSchema_WellKnown(t *testing.T) {
+	data, err := Schema(wellknown.Decimal_TypeID)
+	if err != nil {
+		t.Fatal("Schema:", err)
+	}
+	if !strings.Contains(string(data), `"type":"string","pattern":`) {
+		t.Errorf("Schema(Decimal) = %s; want a string type", data)
+	}
+}
@@ -0,0 +1,115 @@
+// Package sealed encrypts the segments of a Cap'n Proto message with an
+// AEAD cipher.
+//
+// A sealed message is an ordinary message whose segments hold
+// ciphertext instead of objects.  It can be written with capnp.Encoder
+// and read back with capnp.Decoder like any other message: the stream
+// header's segment table is left in the clear, so a reader without the
+// key can still frame, skip, or index sealed records by their length.
+// Only Open can interpret the segments' contents.
+//
+// Each sealed segment is laid out as a random nonce, followed by the
+// AEAD ciphertext, followed by zero padding to a word boundary.  The
+// segment's index and the total number of segments are authenticated
+// as additional data, so segments cannot be reordered, dropped, or
+// moved between messages with different segment counts.
+package sealed // import "zombiezen.com/go/capnproto2/encoding/sealed"
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+const wordSize = 8
+
+// Seal returns a new message whose segments are the segments of msg
+// encrypted with aead.  Nonces are read from random, or from
+// crypto/rand.Reader if random is nil.  msg is not modified.
+func Seal(msg *capnp.Message, aead cipher.AEAD, random io.Reader) (*capnp.Message, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	n := msg.NumSegments()
+	if n == 0 {
+		return nil, errors.New("sealed: empty message")
+	}
+	segs := make([][]byte, n)
+	for i := range segs {
+		seg, err := msg.Segment(capnp.SegmentID(i))
+		if err != nil {
+			return nil, fmt.Errorf("sealed: %v", err)
+		}
+		plain := seg.Data()
+		out := make([]byte, aead.NonceSize(), sealedSize(aead, len(plain)))
+		if _, err := io.ReadFull(random, out); err != nil {
+			return nil, fmt.Errorf("sealed: reading nonce: %v", err)
+		}
+		out = aead.Seal(out, out, plain, additionalData(i, n))
+		segs[i] = out[:cap(out)]
+	}
+	return &capnp.Message{Arena: capnp.MultiSegment(segs)}, nil
+}
+
+// Open returns a new message whose segments are the decrypted segments
+// of msg, which must have been created by Seal with the same key.
+// An error is returned if any segment fails authentication.
+func Open(msg *capnp.Message, aead cipher.AEAD) (*capnp.Message, error) {
+	n := msg.NumSegments()
+	if n == 0 {
+		return nil, errors.New("sealed: empty message")
+	}
+	segs := make([][]byte, n)
+	for i := range segs {
+		seg, err := msg.Segment(capnp.SegmentID(i))
+		if err != nil {
+			return nil, fmt.Errorf("sealed: %v", err)
+		}
+		data := seg.Data()
+		pad := padding(aead)
+		if len(data) < aead.NonceSize()+aead.Overhead()+pad {
+			return nil, fmt.Errorf("sealed: segment %d too short", i)
+		}
+		nonce := data[:aead.NonceSize()]
+		ct := data[aead.NonceSize() : len(data)-pad]
+		plain, err := aead.Open(nil, nonce, ct, additionalData(i, n))
+		if err != nil {
+			return nil, fmt.Errorf("sealed: segment %d: %v", i, err)
+		}
+		if len(plain)%wordSize != 0 {
+			return nil, fmt.Errorf("sealed: segment %d is not word-aligned", i)
+		}
+		segs[i] = plain
+	}
+	if n == 1 {
+		return &capnp.Message{Arena: capnp.SingleSegment(segs[0])}, nil
+	}
+	return &capnp.Message{Arena: capnp.MultiSegment(segs)}, nil
+}
+
+// sealedSize returns the size of a sealed segment holding n bytes of
+// plaintext.
+func sealedSize(aead cipher.AEAD, n int) int {
+	return aead.NonceSize() + n + aead.Overhead() + padding(aead)
+}
+
+// padding returns the number of zero bytes needed after a sealed
+// segment to reach a word boundary.  Segments are always a multiple of
+// the word size, so it only depends on the cipher.
+func padding(aead cipher.AEAD) int {
+	return (wordSize - (aead.NonceSize()+aead.Overhead())%wordSize) % wordSize
+}
+
+// additionalData returns the data authenticated with segment i of a
+// message with n segments.
+func additionalData(i int, n int64) []byte {
+	var ad [12]byte
+	binary.LittleEndian.PutUint32(ad[:4], uint32(i))
+	binary.LittleEndian.PutUint64(ad[4:], uint64(n))
+	return ad[:]
+}
@@ -0,0 +1,118 @@
+package sealed_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/sealed"
+)
+
+func newAEAD(t *testing.T) cipher.AEAD {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{0x42}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+// newMessage returns a message with a root struct that points to a text
+// in another segment.
+func newMessage(t *testing.T) *capnp.Message {
+	msg, seg, err := capnp.NewMessage(capnp.MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := capnp.NewRootStruct(seg, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint64(0, 0xfeedface)
+	if err := root.SetText(0, string(bytes.Repeat([]byte("secret "), 200))); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestRoundTrip(t *testing.T) {
+	aead := newAEAD(t)
+	msg := newMessage(t)
+	if msg.NumSegments() < 2 {
+		t.Fatalf("test message has %d segments; want at least 2", msg.NumSegments())
+	}
+	smsg, err := sealed.Seal(msg, aead, nil)
+	if err != nil {
+		t.Fatal("Seal:", err)
+	}
+	data, err := smsg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Error("sealed message contains plaintext")
+	}
+
+	// The framing is still readable without the key.
+	framed, err := capnp.Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	if framed.NumSegments() != msg.NumSegments() {
+		t.Errorf("sealed message has %d segments; want %d", framed.NumSegments(), msg.NumSegments())
+	}
+
+	out, err := sealed.Open(framed, aead)
+	if err != nil {
+		t.Fatal("Open:", err)
+	}
+	p, err := out.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if v := p.Struct().Uint64(0); v != 0xfeedface {
+		t.Errorf("root.Uint64(0) = %#x; want 0xfeedface", v)
+	}
+	txt, err := p.Struct().Ptr(0)
+	if err != nil {
+		t.Fatal("root.Ptr(0):", err)
+	}
+	if !bytes.HasPrefix(txt.TextBytes(), []byte("secret secret")) {
+		t.Errorf("root.Ptr(0).Text() = %.20q...; want \"secret secret...\"", txt.Text())
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	aead := newAEAD(t)
+	smsg, err := sealed.Seal(newMessage(t), aead, nil)
+	if err != nil {
+		t.Fatal("Seal:", err)
+	}
+	data, err := smsg.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-20] ^= 1
+	framed, err := capnp.Unmarshal(flipped)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	if _, err := sealed.Open(framed, aead); err == nil {
+		t.Error("Open(flipped bit) = <nil>; want error")
+	}
+
+	// Swap the first two segments.
+	seg0, _ := smsg.Segment(0)
+	seg1, _ := smsg.Segment(1)
+	swapped := &capnp.Message{Arena: capnp.MultiSegment([][]byte{seg1.Data(), seg0.Data()})}
+	if _, err := sealed.Open(swapped, aead); err == nil {
+		t.Error("Open(swapped segments) = <nil>; want error")
+	}
+}
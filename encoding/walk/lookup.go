@@ -0,0 +1,102 @@
+package walk
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// Lookup returns the value at path in the struct s of type typeID with
+// a new Walker.  See Walker.Lookup.
+func Lookup(typeID uint64, s capnp.Struct, path string) (Value, error) {
+	return new(Walker).Lookup(typeID, s, path)
+}
+
+// Lookup returns the value at path in the struct s of type typeID.
+// path is a sequence of field names separated by dots, where a field
+// that is a list may be followed by indexes in brackets, like
+// "a.b[2].c".  Groups are selected by name like other fields.
+//
+// Selecting a field of a null struct gives the field's default value,
+// as with generated accessors.  It is an error to select a union member
+// that is not set, to index past the end of a list, or to select a
+// field of a value that isn't a struct.
+func (w *Walker) Lookup(typeID uint64, s capnp.Struct, path string) (Value, error) {
+	val := Value{typ: &Type{Kind: Struct, TypeID: typeID}, ptr: s.ToPtr()}
+	for i := 0; ; i++ {
+		j := i
+		for j < len(path) && path[j] != '.' && path[j] != '[' {
+			j++
+		}
+		var err error
+		if val, err = w.selectField(val, path[i:j]); err != nil {
+			return Value{}, fmt.Errorf("walk: lookup %q: %v", path, err)
+		}
+		i = j
+		for i < len(path) && path[i] == '[' {
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return Value{}, fmt.Errorf("walk: lookup %q: missing ]", path)
+			}
+			n, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || n < 0 {
+				return Value{}, fmt.Errorf("walk: lookup %q: bad index %q", path, path[i+1:i+end])
+			}
+			if val, err = selectElement(val, n); err != nil {
+				return Value{}, fmt.Errorf("walk: lookup %q: %v", path, err)
+			}
+			i += end + 1
+		}
+		if i == len(path) {
+			return val, nil
+		}
+		if path[i] != '.' {
+			return Value{}, fmt.Errorf("walk: lookup %q: unexpected %q after index", path, path[i])
+		}
+	}
+}
+
+func (w *Walker) selectField(val Value, name string) (Value, error) {
+	if name == "" {
+		return Value{}, errors.New("missing field name")
+	}
+	if val.typ.Kind != Struct {
+		return Value{}, fmt.Errorf("can't select field %s of a %v", name, val.typ.Kind)
+	}
+	plan, err := w.plan(val.typ.TypeID)
+	if err != nil {
+		return Value{}, err
+	}
+	var f *Field
+	for _, pf := range plan.fields {
+		if pf.Name == name {
+			f = pf
+			break
+		}
+	}
+	if f == nil {
+		return Value{}, fmt.Errorf("struct %#x has no field %s", val.typ.TypeID, name)
+	}
+	s := val.ptr.Struct()
+	if f.discriminant != schema.Field_noDiscriminant {
+		if disc := s.Uint16(capnp.DataOffset(plan.discOffset * 2)); disc != f.discriminant {
+			return Value{}, fmt.Errorf("union member %s is not set", name)
+		}
+	}
+	return readField(s, f)
+}
+
+func selectElement(val Value, i int) (Value, error) {
+	if val.typ.Kind != List {
+		return Value{}, fmt.Errorf("can't index a %v", val.typ.Kind)
+	}
+	l := val.ptr.List()
+	if i >= l.Len() {
+		return Value{}, fmt.Errorf("index %d out of range for list of length %d", i, l.Len())
+	}
+	return readElement(l, val.typ.Elem, i)
+}
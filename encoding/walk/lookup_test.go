@@ -0,0 +1,90 @@
+package walk_test
+
+import (
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/walk"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+func TestLookup(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := air.NewRootZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvec, err := z.NewZvec(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvec.At(0).SetGrp()
+	zvec.At(0).Grp().SetSecond(7)
+	pb, err := zvec.At(1).NewPlanebase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb.SetName("Spirit")
+	homes, _ := pb.NewHomes(2)
+	homes.Set(1, air.Airport_lax)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"zvec[0].grp.second", "7"},
+		{"zvec[1].planebase.name", `"Spirit"`},
+		{"zvec[1].planebase.homes[1]", "2"},
+		{"zvec[1].planebase.capacity", "0"},
+		{"zvec[1].planebase", "..."},
+	}
+	w := new(walk.Walker)
+	for _, test := range tests {
+		v, err := w.Lookup(air.Z_TypeID, z.Struct, test.path)
+		if err != nil {
+			t.Errorf("Lookup(%q): %v", test.path, err)
+			continue
+		}
+		if got := formatValue(v); got != test.want {
+			t.Errorf("Lookup(%q) = %s; want %s", test.path, got, test.want)
+		}
+	}
+
+	// Fields of a null struct have their defaults.
+	_, seg, _ = capnp.NewMessage(capnp.SingleSegment(nil))
+	nz, err := air.NewRootZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nz.SetPlanebase(air.PlaneBase{})
+	if v, err := walk.Lookup(air.Z_TypeID, nz.Struct, "planebase.canFly"); err != nil || v.Bool() {
+		t.Errorf("Lookup(\"planebase.canFly\") in null struct = %v, %v; want false, <nil>", v.Bool(), err)
+	}
+
+	errTests := []struct {
+		path string
+		err  string
+	}{
+		{"", "missing field name"},
+		{"zvec.", "missing field name"},
+		{"nope", "no field nope"},
+		{"text", "union member text is not set"},
+		{"zvec[2]", "out of range"},
+		{"zvec[-1]", "bad index"},
+		{"zvec[x]", "bad index"},
+		{"zvec[0", "missing ]"},
+		{"zvec[0]x", "unexpected"},
+		{"zvec[0][0]", "can't index a struct"},
+		{"zvec[1].planebase.name.x", "can't select field x of a Text"},
+	}
+	for _, test := range errTests {
+		_, err := w.Lookup(air.Z_TypeID, z.Struct, test.path)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Lookup(%q) error = %v; want %q", test.path, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package walk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+)
+
+// A Projection copies a subset of the fields of a struct type into a
+// new struct, reading only the fields that it copies.  It suits
+// gateways that strip large messages down before passing them on.
+//
+// A Projection doesn't change once it is created, so it is safe to use
+// from multiple goroutines, unlike the Walker that created it.
+type Projection struct {
+	typeID uint64
+	root   *projNode
+}
+
+// projNode is the projection of one struct or group.
+type projNode struct {
+	plan   *structPlan
+	fields []*projField // in code order
+}
+
+type projField struct {
+	f     *Field
+	order int // index in plan.fields
+
+	// whole is true to copy all of the field.  Otherwise, sub is the
+	// projection of the field's struct or group.  Groups are never
+	// whole; a whole group has a sub with all of its fields.
+	whole bool
+	sub   *projNode
+}
+
+// NewProjection returns a projection of the struct type typeID with a
+// new Walker.  See Walker.NewProjection.
+func NewProjection(typeID uint64, paths ...string) (*Projection, error) {
+	return new(Walker).NewProjection(typeID, paths...)
+}
+
+// NewProjection returns a projection of the struct type typeID onto the
+// fields named by paths.  A path is a sequence of field names separated
+// by dots, like "a.b.c", that selects the whole value of the last
+// field.  The fields before the last must be structs or groups; only
+// the selected fields of those are copied.  Paths can't index lists: a
+// list is either copied whole or not at all.
+func (w *Walker) NewProjection(typeID uint64, paths ...string) (*Projection, error) {
+	root, err := w.newProjNode(typeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if strings.IndexByte(path, '[') != -1 {
+			return nil, fmt.Errorf("walk: projection path %q: can't index lists", path)
+		}
+		if err := w.addPath(root, path); err != nil {
+			return nil, fmt.Errorf("walk: projection path %q: %v", path, err)
+		}
+	}
+	return &Projection{typeID: typeID, root: root}, nil
+}
+
+// TypeID returns the ID of the struct type that p projects.
+func (p *Projection) TypeID() uint64 {
+	return p.typeID
+}
+
+// Project allocates a new struct in seg and copies the projected fields
+// of s into it.  Fields that are not projected have their defaults.
+// Union members that are not set in s are not copied.
+func (p *Projection) Project(seg *capnp.Segment, s capnp.Struct) (capnp.Struct, error) {
+	out, err := capnp.NewStruct(seg, p.root.plan.size)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	if err := p.root.copy(out, s); err != nil {
+		return capnp.Struct{}, err
+	}
+	return out, nil
+}
+
+func (w *Walker) newProjNode(typeID uint64) (*projNode, error) {
+	plan, err := w.plan(typeID)
+	if err != nil {
+		return nil, err
+	}
+	return &projNode{plan: plan}, nil
+}
+
+func (w *Walker) addPath(n *projNode, path string) error {
+	for {
+		name, rest := path, ""
+		last := true
+		if i := strings.IndexByte(path, '.'); i != -1 {
+			name, rest, last = path[:i], path[i+1:], false
+		}
+		if name == "" {
+			return errors.New("missing field name")
+		}
+		pf, err := n.field(name)
+		if err != nil {
+			return err
+		}
+		if last {
+			if pf.f.Group {
+				return w.addGroup(pf)
+			}
+			pf.whole, pf.sub = true, nil
+			return nil
+		}
+		if pf.f.Type.Kind != Struct {
+			return fmt.Errorf("can't select field of %s, a %v", name, pf.f.Type.Kind)
+		}
+		if pf.whole {
+			return nil
+		}
+		if pf.sub == nil {
+			if pf.sub, err = w.newProjNode(pf.f.Type.TypeID); err != nil {
+				return err
+			}
+		}
+		n, path = pf.sub, rest
+	}
+}
+
+// addGroup sets pf, a group, to copy all of its fields.
+func (w *Walker) addGroup(pf *projField) error {
+	sub, err := w.newProjNode(pf.f.Type.TypeID)
+	if err != nil {
+		return err
+	}
+	for _, f := range sub.plan.fields {
+		gf, _ := sub.field(f.Name)
+		if f.Group {
+			if err := w.addGroup(gf); err != nil {
+				return err
+			}
+		} else {
+			gf.whole = true
+		}
+	}
+	pf.sub = sub
+	return nil
+}
+
+// field returns the projection of the field named name, adding it if
+// necessary.
+func (n *projNode) field(name string) (*projField, error) {
+	for _, pf := range n.fields {
+		if pf.f.Name == name {
+			return pf, nil
+		}
+	}
+	for i, f := range n.plan.fields {
+		if f.Name != name {
+			continue
+		}
+		j := 0
+		for j < len(n.fields) && n.fields[j].order < i {
+			j++
+		}
+		pf := &projField{f: f, order: i}
+		n.fields = append(n.fields, nil)
+		copy(n.fields[j+1:], n.fields[j:])
+		n.fields[j] = pf
+		return pf, nil
+	}
+	return nil, fmt.Errorf("no field %s", name)
+}
+
+func (n *projNode) copy(dst, src capnp.Struct) error {
+	disc := uint16(schema.Field_noDiscriminant)
+	if n.plan.union {
+		disc = src.Uint16(capnp.DataOffset(n.plan.discOffset * 2))
+	}
+	for _, pf := range n.fields {
+		f := pf.f
+		if f.discriminant != schema.Field_noDiscriminant {
+			if f.discriminant != disc {
+				continue
+			}
+			dst.SetUint16(capnp.DataOffset(n.plan.discOffset*2), disc)
+		}
+		if f.Group {
+			if err := pf.sub.copy(dst, src); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyField(dst, src, f, pf.sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyField copies the stored value of f from src to dst, projecting
+// its struct with sub if sub is not nil.
+func copyField(dst, src capnp.Struct, f *Field, sub *projNode) error {
+	off := f.offset
+	switch f.Type.Kind {
+	case Void:
+	case Bool:
+		dst.SetBit(capnp.BitOffset(off), src.Bit(capnp.BitOffset(off)))
+	case Int8, Uint8:
+		dst.SetUint8(capnp.DataOffset(off), src.Uint8(capnp.DataOffset(off)))
+	case Int16, Uint16, Enum:
+		dst.SetUint16(capnp.DataOffset(off*2), src.Uint16(capnp.DataOffset(off*2)))
+	case Int32, Uint32, Float32:
+		dst.SetUint32(capnp.DataOffset(off*4), src.Uint32(capnp.DataOffset(off*4)))
+	case Int64, Uint64, Float64:
+		dst.SetUint64(capnp.DataOffset(off*8), src.Uint64(capnp.DataOffset(off*8)))
+	default:
+		p, err := src.Ptr(uint16(off))
+		if err != nil {
+			return err
+		}
+		if sub == nil || !p.IsValid() {
+			return dst.SetPtr(uint16(off), p)
+		}
+		ss, err := capnp.NewStruct(dst.Segment(), sub.plan.size)
+		if err != nil {
+			return err
+		}
+		if err := dst.SetPtr(uint16(off), ss.ToPtr()); err != nil {
+			return err
+		}
+		return sub.copy(ss, p.Struct())
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package walk_test
+
+import (
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/walk"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+func TestProjection(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := air.NewRootZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := z.NewPlanebase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb.SetName("Spirit")
+	homes, _ := pb.NewHomes(2)
+	homes.Set(0, air.Airport_jfk)
+	homes.Set(1, air.Airport_lax)
+	pb.SetRating(-5)
+	pb.SetCanFly(true)
+	pb.SetCapacity(350)
+
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{nil, "(void = void)"},
+		{[]string{"planebase"}, "(planebase = " + pb.String() + ")"},
+		{[]string{"planebase.name", "planebase.canFly"}, `(planebase = (name = "Spirit", homes = [], rating = 0, canFly = true, capacity = 0, maxSpeed = 0))`},
+		{[]string{"planebase.homes", "planebase.capacity", "planebase"}, "(planebase = " + pb.String() + ")"},
+		{[]string{"planebase", "planebase.rating"}, "(planebase = " + pb.String() + ")"},
+		{[]string{"zvec", "grp"}, "(void = void)"},
+	}
+	for _, test := range tests {
+		p, err := walk.NewProjection(air.Z_TypeID, test.paths...)
+		if err != nil {
+			t.Errorf("NewProjection(%q): %v", test.paths, err)
+			continue
+		}
+		_, out, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+		ps, err := p.Project(out, z.Struct)
+		if err != nil {
+			t.Errorf("Project(%q): %v", test.paths, err)
+			continue
+		}
+		if got := (air.Z{Struct: ps}).String(); got != test.want {
+			t.Errorf("Project(%q) = %s; want %s", test.paths, got, test.want)
+		}
+	}
+}
+
+func TestProjection_Group(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := air.NewRootZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.SetGrp()
+	z.Grp().SetFirst(1)
+	z.Grp().SetSecond(2)
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"grp"}, "(grp = (first = 1, second = 2))"},
+		{[]string{"grp.second"}, "(grp = (first = 0, second = 2))"},
+		{[]string{"grp.second", "grp"}, "(grp = (first = 1, second = 2))"},
+	}
+	for _, test := range tests {
+		p, err := walk.NewProjection(air.Z_TypeID, test.paths...)
+		if err != nil {
+			t.Errorf("NewProjection(%q): %v", test.paths, err)
+			continue
+		}
+		_, out, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+		ps, err := p.Project(out, z.Struct)
+		if err != nil {
+			t.Errorf("Project(%q): %v", test.paths, err)
+			continue
+		}
+		if got := (air.Z{Struct: ps}).String(); got != test.want {
+			t.Errorf("Project(%q) = %s; want %s", test.paths, got, test.want)
+		}
+	}
+}
+
+func TestNewProjection_Errors(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"", "missing field name"},
+		{"planebase.", "missing field name"},
+		{"nope", "no field nope"},
+		{"zvec[0]", "can't index lists"},
+		{"planebase.name.x", "can't select field of name, a Text"},
+	}
+	for _, test := range tests {
+		_, err := walk.NewProjection(air.Z_TypeID, test.path)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("NewProjection(%q) error = %v; want %q", test.path, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+// Package walk traverses Cap'n Proto messages based on a schema,
+// calling a Visitor for each struct, list, field, and list element
+// instead of building wrapper values.
+//
+// A walk reads the message in place, so it suits filtering or
+// summarizing messages that are too large to comfortably handle with
+// generated types.  The schema is compiled into a plan the first time a
+// Walker sees a type, after which walking allocates nothing on its
+// own; the Visitor decides what, if anything, to copy.  Walking reads
+// every pointer it visits, so a message that is larger than the default
+// traversal limit needs a larger TraverseLimit, or Visitors that skip
+// what they don't need with SkipValue.
+//
+// Lookup reads a single value by its path of field names, like
+// "a.b[2].c", for programs that pick a few values out of messages
+// whose types they only know at run time.  Similarly, a Projection
+// copies chosen fields of a struct into a smaller message.
+package walk // import "zombiezen.com/go/capnproto2/encoding/walk"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+)
+
+// A Kind is the kind of a field or list element.
+type Kind int
+
+// Kinds.
+const (
+	Void Kind = 1 + iota
+	Bool
+	Int8
+	Int16
+	Int32
+	Int64
+	Uint8
+	Uint16
+	Uint32
+	Uint64
+	Float32
+	Float64
+	Text
+	Data
+	List
+	Enum
+	Struct
+	Interface
+	AnyPointer
+)
+
+var kindNames = [...]string{
+	Void:       "Void",
+	Bool:       "Bool",
+	Int8:       "Int8",
+	Int16:      "Int16",
+	Int32:      "Int32",
+	Int64:      "Int64",
+	Uint8:      "UInt8",
+	Uint16:     "UInt16",
+	Uint32:     "UInt32",
+	Uint64:     "UInt64",
+	Float32:    "Float32",
+	Float64:    "Float64",
+	Text:       "Text",
+	Data:       "Data",
+	List:       "List",
+	Enum:       "enum",
+	Struct:     "struct",
+	Interface:  "interface",
+	AnyPointer: "AnyPointer",
+}
+
+// String returns the kind's name in the schema language.
+func (k Kind) String() string {
+	if k <= 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// isPointer reports whether values of kind k are stored in a pointer.
+func (k Kind) isPointer() bool {
+	return k >= Text && k != Enum
+}
+
+// A Type is the type of a field or list element.
+type Type struct {
+	Kind Kind
+
+	// TypeID is the ID of the struct, enum, or interface type, or zero
+	// for other kinds.
+	TypeID uint64
+
+	// Elem is the element type of a list, or nil for other kinds.
+	Elem *Type
+}
+
+// A Field is a field of a struct type.  Only the members of a union
+// that are set are visited.
+type Field struct {
+	Name string
+	Type *Type
+
+	// Group is true if the field is a group.  A group's value is the
+	// struct that contains it, and its Type is a Struct whose TypeID is
+	// the group's.
+	Group bool
+
+	offset       uint32
+	discriminant uint16
+	def          uint64    // XOR mask for scalars
+	defPtr       capnp.Ptr // default for pointers, may be null
+}
+
+// A Value is the value of a field or list element.  Its accessors read
+// the message in place; the bytes and pointers that they return are
+// only valid while the message is.
+type Value struct {
+	typ  *Type
+	bits uint64
+	ptr  capnp.Ptr
+}
+
+// Type returns the value's type.
+func (v Value) Type() *Type {
+	return v.typ
+}
+
+// Bool returns the value of a Bool.
+func (v Value) Bool() bool {
+	return v.bits != 0
+}
+
+// Int returns the value of an Int8, Int16, Int32, or Int64.
+func (v Value) Int() int64 {
+	switch v.typ.Kind {
+	case Int8:
+		return int64(int8(v.bits))
+	case Int16:
+		return int64(int16(v.bits))
+	case Int32:
+		return int64(int32(v.bits))
+	default:
+		return int64(v.bits)
+	}
+}
+
+// Uint returns the value of a UInt8, UInt16, UInt32, UInt64, or enum.
+func (v Value) Uint() uint64 {
+	return v.bits
+}
+
+// Float returns the value of a Float32 or Float64.
+func (v Value) Float() float64 {
+	if v.typ.Kind == Float32 {
+		return float64(math.Float32frombits(uint32(v.bits)))
+	}
+	return math.Float64frombits(v.bits)
+}
+
+// Bytes returns the value of a Text, without its NUL terminator, or of
+// a Data.  The slice refers to the message's memory.
+func (v Value) Bytes() []byte {
+	if v.typ.Kind == Text {
+		return v.ptr.TextBytes()
+	}
+	return v.ptr.Data()
+}
+
+// Struct returns the value of a struct or group.
+func (v Value) Struct() capnp.Struct {
+	return v.ptr.Struct()
+}
+
+// List returns the value of a List.
+func (v Value) List() capnp.List {
+	return v.ptr.List()
+}
+
+// Ptr returns the pointer that a pointer value is stored in, which is
+// how to read an interface or AnyPointer.  It is null for a pointer
+// that is null and has no default, and invalid for other kinds.
+func (v Value) Ptr() capnp.Ptr {
+	return v.ptr
+}
+
+// A Visitor receives the parts of a message as they are walked.  If a
+// method returns an error other than SkipValue, the walk stops and
+// returns it.
+//
+// A struct is visited with StartStruct, then Field for each of its
+// fields in code order, then EndStruct.  A list is visited with
+// StartList, then Element for each element, then EndList.  A field or
+// element that is a struct, group, or list is visited right after its
+// Field or Element call, unless that call returns SkipValue or the
+// pointer is null.
+type Visitor interface {
+	StartStruct(typeID uint64, s capnp.Struct) error
+	Field(f *Field, v Value) error
+	EndStruct(typeID uint64, s capnp.Struct) error
+
+	StartList(t *Type, l capnp.List) error
+	Element(i int, v Value) error
+	EndList(t *Type, l capnp.List) error
+}
+
+// SkipValue is returned by a Visitor to skip the contents of a value:
+// from Field or Element, the struct or list that the value holds; from
+// StartStruct or StartList, the fields or elements.  The matching End
+// method is not called when StartStruct or StartList skips.
+var SkipValue = errors.New("walk: skip value")
+
+// NopVisitor is a Visitor whose methods do nothing.  Embed it in a
+// Visitor to implement only the methods that are needed.
+type NopVisitor struct{}
+
+// StartStruct returns nil.
+func (NopVisitor) StartStruct(typeID uint64, s capnp.Struct) error { return nil }
+
+// Field returns nil.
+func (NopVisitor) Field(f *Field, v Value) error { return nil }
+
+// EndStruct returns nil.
+func (NopVisitor) EndStruct(typeID uint64, s capnp.Struct) error { return nil }
+
+// StartList returns nil.
+func (NopVisitor) StartList(t *Type, l capnp.List) error { return nil }
+
+// Element returns nil.
+func (NopVisitor) Element(i int, v Value) error { return nil }
+
+// EndList returns nil.
+func (NopVisitor) EndList(t *Type, l capnp.List) error { return nil }
+
+// A Walker walks messages.  It caches the plans that it compiles from
+// schemas, so reuse a Walker to walk many messages.  The zero value
+// walks with the default registry.  A Walker is not safe to use from
+// multiple goroutines at once.
+type Walker struct {
+	nodes nodemap.Map
+	plans map[uint64]*structPlan
+}
+
+// UseRegistry changes the registry that the walker consults for
+// schemas from the default registry.
+func (w *Walker) UseRegistry(reg *schemas.Registry) {
+	w.nodes.UseRegistry(reg)
+	w.plans = nil
+}
+
+// Walk walks the struct s of type typeID with a new Walker.
+func Walk(typeID uint64, s capnp.Struct, v Visitor) error {
+	return new(Walker).Walk(typeID, s, v)
+}
+
+// Walk walks the struct s of type typeID.
+func (w *Walker) Walk(typeID uint64, s capnp.Struct, v Visitor) error {
+	return skipOK(w.walkStruct(typeID, s, v))
+}
+
+// WalkList walks the list l of structs of type typeID.
+func (w *Walker) WalkList(typeID uint64, l capnp.List, v Visitor) error {
+	t := &Type{Kind: List, Elem: &Type{Kind: Struct, TypeID: typeID}}
+	return skipOK(w.walkList(t, l, v))
+}
+
+func skipOK(err error) error {
+	if err == SkipValue {
+		return nil
+	}
+	return err
+}
+
+func (w *Walker) walkStruct(typeID uint64, s capnp.Struct, v Visitor) error {
+	plan, err := w.plan(typeID)
+	if err != nil {
+		return err
+	}
+	if err := v.StartStruct(typeID, s); err == SkipValue {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	disc := uint16(schema.Field_noDiscriminant)
+	if plan.union {
+		disc = s.Uint16(capnp.DataOffset(plan.discOffset * 2))
+	}
+	for _, f := range plan.fields {
+		if f.discriminant != schema.Field_noDiscriminant && f.discriminant != disc {
+			continue
+		}
+		val, err := readField(s, f)
+		if err != nil {
+			return err
+		}
+		if err := w.visit(val, v.Field(f, val), v); err != nil {
+			return err
+		}
+	}
+	return v.EndStruct(typeID, s)
+}
+
+// visit walks the contents of val after the Visitor returned err for
+// it.
+func (w *Walker) visit(val Value, err error, v Visitor) error {
+	if err == SkipValue {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch val.typ.Kind {
+	case Struct:
+		if !val.ptr.IsValid() {
+			return nil
+		}
+		return w.walkStruct(val.typ.TypeID, val.ptr.Struct(), v)
+	case List:
+		if !val.ptr.IsValid() {
+			return nil
+		}
+		return w.walkList(val.typ, val.ptr.List(), v)
+	}
+	return nil
+}
+
+func (w *Walker) walkList(t *Type, l capnp.List, v Visitor) error {
+	if err := v.StartList(t, l); err == SkipValue {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := 0; i < l.Len(); i++ {
+		val, err := readElement(l, t.Elem, i)
+		if err != nil {
+			return err
+		}
+		if err := w.visit(val, v.Element(i, val), v); err != nil {
+			return err
+		}
+	}
+	return v.EndList(t, l)
+}
+
+func readElement(l capnp.List, elem *Type, i int) (Value, error) {
+	val := Value{typ: elem}
+	switch elem.Kind {
+	case Void:
+	case Bool:
+		if (capnp.BitList{List: l}).At(i) {
+			val.bits = 1
+		}
+	case Int8, Uint8:
+		val.bits = uint64(capnp.UInt8List{List: l}.At(i))
+	case Int16, Uint16, Enum:
+		val.bits = uint64(capnp.UInt16List{List: l}.At(i))
+	case Int32, Uint32, Float32:
+		val.bits = uint64(capnp.UInt32List{List: l}.At(i))
+	case Int64, Uint64, Float64:
+		val.bits = capnp.UInt64List{List: l}.At(i)
+	case Struct:
+		val.ptr = l.Struct(i).ToPtr()
+	default:
+		p, err := capnp.PointerList{List: l}.PtrAt(i)
+		if err != nil {
+			return Value{}, err
+		}
+		val.ptr = p
+	}
+	return val, nil
+}
+
+func readField(s capnp.Struct, f *Field) (Value, error) {
+	val := Value{typ: f.Type}
+	if f.Group {
+		val.ptr = s.ToPtr()
+		return val, nil
+	}
+	off := f.offset
+	switch f.Type.Kind {
+	case Void:
+	case Bool:
+		if s.Bit(capnp.BitOffset(off)) {
+			val.bits = 1
+		}
+	case Int8, Uint8:
+		val.bits = uint64(s.Uint8(capnp.DataOffset(off)))
+	case Int16, Uint16, Enum:
+		val.bits = uint64(s.Uint16(capnp.DataOffset(off * 2)))
+	case Int32, Uint32, Float32:
+		val.bits = uint64(s.Uint32(capnp.DataOffset(off * 4)))
+	case Int64, Uint64, Float64:
+		val.bits = s.Uint64(capnp.DataOffset(off * 8))
+	default:
+		p, err := s.Ptr(uint16(off))
+		if err != nil {
+			return Value{}, err
+		}
+		if !p.IsValid() {
+			p = f.defPtr
+		}
+		val.ptr = p
+		return val, nil
+	}
+	val.bits ^= f.def
+	return val, nil
+}
+
+// A structPlan is a struct type compiled for walking.
+type structPlan struct {
+	size       capnp.ObjectSize
+	union      bool
+	discOffset uint32
+	fields     []*Field // in code order
+}
+
+func (w *Walker) plan(typeID uint64) (*structPlan, error) {
+	if p := w.plans[typeID]; p != nil {
+		return p, nil
+	}
+	n, err := w.nodes.Find(typeID)
+	if err != nil {
+		return nil, err
+	}
+	if n.Which() != schema.Node_Which_structNode {
+		return nil, fmt.Errorf("walk: type %#x is not a struct", typeID)
+	}
+	sn := n.StructNode()
+	list, err := sn.Fields()
+	if err != nil {
+		return nil, err
+	}
+	p := &structPlan{
+		size: capnp.ObjectSize{
+			DataSize:     capnp.Size(sn.DataWordCount()) * 8,
+			PointerCount: sn.PointerCount(),
+		},
+		union:      sn.DiscriminantCount() > 0,
+		discOffset: sn.DiscriminantOffset(),
+		fields:     make([]*Field, list.Len()),
+	}
+	for i := 0; i < list.Len(); i++ {
+		sf := list.At(i)
+		name, err := sf.Name()
+		if err != nil {
+			return nil, err
+		}
+		f := &Field{Name: name, discriminant: sf.DiscriminantValue()}
+		switch sf.Which() {
+		case schema.Field_Which_group:
+			f.Group = true
+			f.Type = &Type{Kind: Struct, TypeID: sf.Group().TypeId()}
+		case schema.Field_Which_slot:
+			st, err := sf.Slot().Type()
+			if err != nil {
+				return nil, err
+			}
+			if f.Type, err = planType(st); err != nil {
+				return nil, fmt.Errorf("walk: field %s of %#x: %v", name, typeID, err)
+			}
+			f.offset = sf.Slot().Offset()
+			dv, err := sf.Slot().DefaultValue()
+			if err != nil {
+				return nil, err
+			}
+			if err := setDefault(f, dv); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("walk: field %s of %#x has unknown kind", name, typeID)
+		}
+		if int(sf.CodeOrder()) >= len(p.fields) || p.fields[sf.CodeOrder()] != nil {
+			return nil, fmt.Errorf("walk: struct %#x has bad code order", typeID)
+		}
+		p.fields[sf.CodeOrder()] = f
+	}
+	if w.plans == nil {
+		w.plans = make(map[uint64]*structPlan)
+	}
+	w.plans[typeID] = p
+	return p, nil
+}
+
+var scalarKinds = map[schema.Type_Which]Kind{
+	schema.Type_Which_void:    Void,
+	schema.Type_Which_bool:    Bool,
+	schema.Type_Which_int8:    Int8,
+	schema.Type_Which_int16:   Int16,
+	schema.Type_Which_int32:   Int32,
+	schema.Type_Which_int64:   Int64,
+	schema.Type_Which_uint8:   Uint8,
+	schema.Type_Which_uint16:  Uint16,
+	schema.Type_Which_uint32:  Uint32,
+	schema.Type_Which_uint64:  Uint64,
+	schema.Type_Which_float32: Float32,
+	schema.Type_Which_float64: Float64,
+	schema.Type_Which_text:    Text,
+	schema.Type_Which_data:    Data,
+}
+
+func planType(st schema.Type) (*Type, error) {
+	if k, ok := scalarKinds[st.Which()]; ok {
+		return &Type{Kind: k}, nil
+	}
+	switch st.Which() {
+	case schema.Type_Which_list:
+		et, err := st.List().ElementType()
+		if err != nil {
+			return nil, err
+		}
+		elem, err := planType(et)
+		if err != nil {
+			return nil, err
+		}
+		return &Type{Kind: List, Elem: elem}, nil
+	case schema.Type_Which_enum:
+		return &Type{Kind: Enum, TypeID: st.Enum().TypeId()}, nil
+	case schema.Type_Which_structType:
+		return &Type{Kind: Struct, TypeID: st.StructType().TypeId()}, nil
+	case schema.Type_Which_interface:
+		return &Type{Kind: Interface, TypeID: st.Interface().TypeId()}, nil
+	case schema.Type_Which_anyPointer:
+		return &Type{Kind: AnyPointer}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %v", st.Which())
+	}
+}
+
+// setDefault records the default value of f's slot.
+func setDefault(f *Field, dv schema.Value) error {
+	if !dv.IsValid() {
+		return nil
+	}
+	if f.Type.Kind.isPointer() {
+		// Every pointer member of Value's union is its first pointer.
+		p, err := dv.Struct.Ptr(0)
+		if err != nil {
+			return err
+		}
+		f.defPtr = p
+		return nil
+	}
+	switch f.Type.Kind {
+	case Bool:
+		if dv.Bool() {
+			f.def = 1
+		}
+	case Int8:
+		f.def = uint64(uint8(dv.Int8()))
+	case Int16:
+		f.def = uint64(uint16(dv.Int16()))
+	case Int32:
+		f.def = uint64(uint32(dv.Int32()))
+	case Int64:
+		f.def = uint64(dv.Int64())
+	case Uint8:
+		f.def = uint64(dv.Uint8())
+	case Uint16:
+		f.def = uint64(dv.Uint16())
+	case Enum:
+		f.def = uint64(dv.Enum())
+	case Uint32:
+		f.def = uint64(dv.Uint32())
+	case Uint64:
+		f.def = dv.Uint64()
+	case Float32:
+		f.def = uint64(math.Float32bits(dv.Float32()))
+	case Float64:
+		f.def = math.Float64bits(dv.Float64())
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package walk_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/encoding/walk"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+// tracer records a walk as one line per call.
+type tracer struct {
+	lines []string
+	skip  string // name of a field to skip
+}
+
+func (t *tracer) add(format string, args ...interface{}) {
+	t.lines = append(t.lines, fmt.Sprintf(format, args...))
+}
+
+func (t *tracer) StartStruct(typeID uint64, s capnp.Struct) error {
+	t.add("start %#x", typeID)
+	return nil
+}
+
+func (t *tracer) Field(f *walk.Field, v walk.Value) error {
+	t.add("field %s %v = %s", f.Name, f.Type.Kind, formatValue(v))
+	if f.Name == t.skip {
+		return walk.SkipValue
+	}
+	return nil
+}
+
+func (t *tracer) EndStruct(typeID uint64, s capnp.Struct) error {
+	t.add("end %#x", typeID)
+	return nil
+}
+
+func (t *tracer) StartList(typ *walk.Type, l capnp.List) error {
+	t.add("list of %v [%d]", typ.Elem.Kind, l.Len())
+	return nil
+}
+
+func (t *tracer) Element(i int, v walk.Value) error {
+	t.add("elem %d = %s", i, formatValue(v))
+	return nil
+}
+
+func (t *tracer) EndList(typ *walk.Type, l capnp.List) error {
+	t.add("end list")
+	return nil
+}
+
+func formatValue(v walk.Value) string {
+	switch v.Type().Kind {
+	case walk.Void:
+		return "void"
+	case walk.Bool:
+		return fmt.Sprint(v.Bool())
+	case walk.Int8, walk.Int16, walk.Int32, walk.Int64:
+		return fmt.Sprint(v.Int())
+	case walk.Uint8, walk.Uint16, walk.Uint32, walk.Uint64, walk.Enum:
+		return fmt.Sprint(v.Uint())
+	case walk.Float32, walk.Float64:
+		return fmt.Sprint(v.Float())
+	case walk.Text, walk.Data:
+		return fmt.Sprintf("%q", v.Bytes())
+	default:
+		if !v.Ptr().IsValid() {
+			return "null"
+		}
+		return "..."
+	}
+}
+
+func newPlaneBase(t *testing.T) air.PlaneBase {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := air.NewRootPlaneBase(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb.SetName("Spirit")
+	homes, _ := pb.NewHomes(2)
+	homes.Set(0, air.Airport_jfk)
+	homes.Set(1, air.Airport_lax)
+	pb.SetRating(-5)
+	pb.SetCanFly(true)
+	pb.SetCapacity(350)
+	pb.SetMaxSpeed(0.85)
+	return pb
+}
+
+func TestWalk(t *testing.T) {
+	pb := newPlaneBase(t)
+	tr := new(tracer)
+	if err := walk.Walk(air.PlaneBase_TypeID, pb.Struct, tr); err != nil {
+		t.Fatal("Walk:", err)
+	}
+	want := []string{
+		"start 0xd8bccf6e60a73791",
+		`field name Text = "Spirit"`,
+		"field homes List = ...",
+		"list of enum [2]",
+		"elem 0 = 1",
+		"elem 1 = 2",
+		"end list",
+		"field rating Int64 = -5",
+		"field canFly Bool = true",
+		"field capacity Int64 = 350",
+		"field maxSpeed Float64 = 0.85",
+		"end 0xd8bccf6e60a73791",
+	}
+	checkTrace(t, tr.lines, want)
+}
+
+func TestWalk_SkipValue(t *testing.T) {
+	pb := newPlaneBase(t)
+	tr := &tracer{skip: "homes"}
+	if err := walk.Walk(air.PlaneBase_TypeID, pb.Struct, tr); err != nil {
+		t.Fatal("Walk:", err)
+	}
+	for _, line := range tr.lines {
+		if strings.HasPrefix(line, "list") || strings.HasPrefix(line, "elem") {
+			t.Errorf("walked skipped list: %s", line)
+		}
+	}
+}
+
+func TestWalk_UnionAndNested(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := air.NewRootZ(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvec, err := z.NewZvec(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zvec.At(0).SetI8(-3)
+	zvec.At(1).SetText("hi")
+	tr := new(tracer)
+	if err := walk.Walk(air.Z_TypeID, z.Struct, tr); err != nil {
+		t.Fatal("Walk:", err)
+	}
+	want := []string{
+		"start 0xea26e9973bd6a0d9",
+		"field zvec List = ...",
+		"list of struct [2]",
+		"elem 0 = ...",
+		"start 0xea26e9973bd6a0d9",
+		"field i8 Int8 = -3",
+		"end 0xea26e9973bd6a0d9",
+		"elem 1 = ...",
+		"start 0xea26e9973bd6a0d9",
+		`field text Text = "hi"`,
+		"end 0xea26e9973bd6a0d9",
+		"end list",
+		"end 0xea26e9973bd6a0d9",
+	}
+	checkTrace(t, tr.lines, want)
+}
+
+func TestWalk_Defaults(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := air.NewRootDefaults(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := new(tracer)
+	if err := walk.Walk(air.Defaults_TypeID, d.Struct, tr); err != nil {
+		t.Fatal("Walk:", err)
+	}
+	want := []string{
+		"start 0x97e38948c61f878d",
+		`field text Text = "foo"`,
+		`field data Data = "bar"`,
+		"field float Float32 = 3.140000104904175",
+		"field int Int32 = -123",
+		"field uint UInt32 = 42",
+		"end 0x97e38948c61f878d",
+	}
+	checkTrace(t, tr.lines, want)
+}
+
+func TestWalk_Error(t *testing.T) {
+	pb := newPlaneBase(t)
+	errStop := errors.New("stop")
+	v := stopVisitor{err: errStop}
+	if err := walk.Walk(air.PlaneBase_TypeID, pb.Struct, v); err != errStop {
+		t.Errorf("Walk error = %v; want %v", err, errStop)
+	}
+}
+
+type stopVisitor struct {
+	walk.NopVisitor
+	err error
+}
+
+func (sv stopVisitor) Element(i int, v walk.Value) error {
+	return sv.err
+}
+
+func TestWalk_Allocs(t *testing.T) {
+	pb := newPlaneBase(t)
+	w := new(walk.Walker)
+	var v walk.NopVisitor
+	if err := w.Walk(air.PlaneBase_TypeID, pb.Struct, v); err != nil {
+		t.Fatal("Walk:", err)
+	}
+	n := testing.AllocsPerRun(100, func() {
+		w.Walk(air.PlaneBase_TypeID, pb.Struct, v)
+	})
+	if n > 0 {
+		t.Errorf("Walk allocated %v times after the first walk; want 0", n)
+	}
+}
+
+func checkTrace(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("trace:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,237 @@
+package capnp
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// A FlowLimiter bounds the amount of call data that a client has
+// outstanding, that is, sent but not yet answered.  It is used to apply
+// back-pressure to a sender that makes many calls without waiting for
+// each result, like a stream of file chunks.
+type FlowLimiter interface {
+	// StartMessage blocks until a call of the given size in bytes may be
+	// sent, or until ctx is done.  The returned function must be called
+	// once the call's answer arrives, to return its size to the limiter,
+	// and it must not block.
+	StartMessage(ctx context.Context, size uint64) (gotResponse func(), err error)
+}
+
+// NewFixedLimiter returns a FlowLimiter that allows at most size bytes
+// of calls to be outstanding.  A call larger than size is sent once
+// there are no other calls outstanding, so that it does not block
+// forever.
+func NewFixedLimiter(size uint64) FlowLimiter {
+	return &fixedLimiter{limit: size}
+}
+
+type fixedLimiter struct {
+	mu    sync.Mutex
+	limit uint64
+	used  uint64
+	wait  chan struct{} // closed when used decreases; nil if no waiters
+}
+
+func (l *fixedLimiter) StartMessage(ctx context.Context, size uint64) (gotResponse func(), err error) {
+	l.mu.Lock()
+	for l.used > 0 && l.used+size > l.limit {
+		if l.wait == nil {
+			l.wait = make(chan struct{})
+		}
+		wait := l.wait
+		l.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	l.used += size
+	l.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.used -= size
+			if l.wait != nil {
+				close(l.wait)
+				l.wait = nil
+			}
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// A FlowLimitedClient is a client that has the calls made on it held
+// back by its FlowLimiter.  The limiter is applied by the client that
+// sends each call over a network, like one from an rpc.Conn, which
+// waits for the limiter with the size of the encoded call message
+// before sending it (see CallFlow).  A call is outstanding until its
+// answer arrives.  Calls to clients that do not send them over a
+// network are not limited.
+type FlowLimitedClient struct {
+	c Client
+
+	mu        sync.Mutex
+	limiter   FlowLimiter
+	streamErr error // first error from a streaming call
+}
+
+// NewFlowLimitedClient returns a client that makes calls on c that are
+// held back by l.  The returned client takes ownership of c.  A nil l
+// does not limit calls.
+func NewFlowLimitedClient(c Client, l FlowLimiter) *FlowLimitedClient {
+	return &FlowLimitedClient{c: c, limiter: l}
+}
+
+// SetFlowLimiter replaces the client's flow limiter.  Calls that are
+// already outstanding are returned to the limiter they started with.
+func (fc *FlowLimitedClient) SetFlowLimiter(l FlowLimiter) {
+	fc.mu.Lock()
+	fc.limiter = l
+	fc.mu.Unlock()
+}
+
+// FlowLimiter returns the client's flow limiter.
+func (fc *FlowLimitedClient) FlowLimiter() FlowLimiter {
+	fc.mu.Lock()
+	l := fc.limiter
+	fc.mu.Unlock()
+	return l
+}
+
+// Client returns the underlying client.  fc still owns it.
+func (fc *FlowLimitedClient) Client() Client {
+	return fc.c
+}
+
+// Call makes a call on the underlying client with the client's flow
+// limiter attached.
+func (fc *FlowLimitedClient) Call(call *Call) Answer {
+	ans, _ := fc.call(fc.FlowLimiter(), call, false)
+	return ans
+}
+
+// call makes a call on the underlying client with l attached.  If
+// stream is true, the error of the call is recorded for StreamCall.
+// started reports whether the call was sent with l applied.
+func (fc *FlowLimitedClient) call(l FlowLimiter, call *Call, stream bool) (ans Answer, started bool) {
+	if l == nil {
+		return fc.c.Call(call), false
+	}
+	f := &Flow{limiter: l}
+	if stream {
+		f.onDone = fc.setStreamErr
+	}
+	fcall := *call
+	fcall.Options = call.Options.With([]CallOption{SetOptionValue(flowKey{}, f)})
+	ans = fc.c.Call(&fcall)
+	f.mu.Lock()
+	started = f.started
+	if !started {
+		// The caller waits for the answer itself, so an error from a
+		// later Start must not be recorded as well.
+		f.onDone = nil
+	}
+	f.mu.Unlock()
+	return ans, started
+}
+
+func (fc *FlowLimitedClient) setStreamErr(err error) {
+	if err == nil {
+		return
+	}
+	fc.mu.Lock()
+	if fc.streamErr == nil {
+		fc.streamErr = err
+	}
+	fc.mu.Unlock()
+}
+
+// Close releases the underlying client.
+func (fc *FlowLimitedClient) Close() error {
+	return fc.c.Close()
+}
+
+type flowKey struct{}
+
+// A Flow is the flow limiter that a FlowLimitedClient attached to a
+// call.
+type Flow struct {
+	limiter FlowLimiter
+	onDone  func(error)
+
+	mu      sync.Mutex
+	started bool
+}
+
+// CallFlow returns the flow attached to call by a FlowLimitedClient, or
+// nil if there is none.
+func CallFlow(call *Call) *Flow {
+	f, _ := call.Options.Value(flowKey{}).(*Flow)
+	return f
+}
+
+// Start blocks until the flow's limiter allows a call of the given size
+// in bytes to be sent, or until ctx is done.  A client that sends calls
+// over a network calls Start with the size of the encoded call message
+// before sending it, and then calls done with the call's error once its
+// answer arrives.
+func (f *Flow) Start(ctx context.Context, size uint64) (done func(error), err error) {
+	gotResponse, err := f.limiter.StartMessage(ctx, size)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.started = true
+	onDone := f.onDone
+	f.mu.Unlock()
+	return func(err error) {
+		gotResponse()
+		if onDone != nil {
+			onDone(err)
+		}
+	}, nil
+}
+
+// StreamCall makes a call to a method declared with "-> stream" in its
+// schema, which has no results.  Generated client methods for streaming
+// methods call it.
+//
+// If c is a *FlowLimitedClient with a limiter and the call is sent with
+// the limiter applied, StreamCall returns once the limiter allows the
+// call to be sent, without waiting for it to finish, so that the caller
+// can send the next call right away.  Since an error from a call that
+// was not waited for cannot be returned by that call, it is returned by
+// the next StreamCall on the client instead, and by every one after it.
+// Otherwise, StreamCall waits for the call to finish and returns its
+// error.
+func StreamCall(c Client, call *Call) error {
+	fc, ok := c.(*FlowLimitedClient)
+	var l FlowLimiter
+	if ok {
+		l = fc.FlowLimiter()
+	}
+	if l == nil {
+		p := NewPipeline(c.Call(call))
+		_, err := p.Struct()
+		p.Close()
+		return err
+	}
+	fc.mu.Lock()
+	err := fc.streamErr
+	fc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	ans, started := fc.call(l, call, true)
+	if started {
+		return nil
+	}
+	p := NewPipeline(ans)
+	_, err = p.Struct()
+	p.Close()
+	return err
+}
@@ -0,0 +1,140 @@
+package capnp
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestFixedLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewFixedLimiter(10)
+	done1, err := l.StartMessage(ctx, 6)
+	if err != nil {
+		t.Fatal("StartMessage(6):", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	_, err = l.StartMessage(timeout, 6)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("StartMessage(6) over limit = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	started := make(chan func())
+	go func() {
+		done, err := l.StartMessage(ctx, 6)
+		if err != nil {
+			t.Error("StartMessage(6) after response:", err)
+		}
+		started <- done
+	}()
+	select {
+	case <-started:
+		t.Fatal("StartMessage(6) over limit did not block")
+	case <-time.After(10 * time.Millisecond):
+	}
+	done1()
+	done1() // no effect
+	done2 := <-started
+	done2()
+
+	// A message larger than the limit is sent when nothing is outstanding.
+	done3, err := l.StartMessage(ctx, 20)
+	if err != nil {
+		t.Fatal("StartMessage(20):", err)
+	}
+	done3()
+}
+
+func TestStreamCall(t *testing.T) {
+	errFail := errors.New("fail")
+	calls := make(chan func(error), 10)
+	newClient := func(applyFlow bool) Client {
+		// If applyFlow is true, the client applies the call's flow
+		// limiter before sending it, like a client from an rpc.Conn.
+		return funcClient(func(call *Call) Answer {
+			p, err := call.PlaceParams(nil)
+			if err != nil {
+				return ErrorAnswer(err)
+			}
+			var done func(error)
+			if f := CallFlow(call); f != nil && applyFlow {
+				size, _ := p.Segment().Message().TotalSize()
+				done, err = f.Start(call.Ctx, size)
+				if err != nil {
+					return ErrorAnswer(err)
+				}
+			}
+			ans := &chanAnswer{done: make(chan error, 1)}
+			calls <- func(err error) {
+				if done != nil {
+					done(err)
+				}
+				ans.done <- err
+			}
+			return ans
+		})
+	}
+	call := func() *Call {
+		return &Call{
+			Ctx:        context.Background(),
+			ParamsSize: ObjectSize{DataSize: 8},
+			ParamsFunc: func(Struct) error { return nil },
+		}
+	}
+
+	// Without a limiter, StreamCall waits for the call.
+	c := newClient(true)
+	go func() { (<-calls)(errFail) }()
+	if err := StreamCall(c, call()); err != errFail {
+		t.Errorf("StreamCall without limiter = %v; want %v", err, errFail)
+	}
+
+	// A client that does not apply the limiter is waited for too.
+	lc := NewFlowLimitedClient(newClient(false), NewFixedLimiter(1<<20))
+	go func() { (<-calls)(errFail) }()
+	if err := StreamCall(lc, call()); err != errFail {
+		t.Errorf("StreamCall with unapplied limiter = %v; want %v", err, errFail)
+	}
+	if err := lc.streamErr; err != nil {
+		t.Errorf("unapplied limiter recorded stream error %v", err)
+	}
+
+	fc := NewFlowLimitedClient(c, NewFixedLimiter(1<<20))
+	if err := StreamCall(fc, call()); err != nil {
+		t.Fatal("StreamCall:", err)
+	}
+	if err := StreamCall(fc, call()); err != nil {
+		t.Fatal("StreamCall:", err)
+	}
+	(<-calls)(nil)
+	(<-calls)(errFail)
+	if err := StreamCall(fc, call()); err != errFail {
+		t.Errorf("StreamCall after failure = %v; want %v", err, errFail)
+	}
+}
+
+type funcClient func(*Call) Answer
+
+func (f funcClient) Call(call *Call) Answer { return f(call) }
+func (f funcClient) Close() error           { return nil }
+
+// chanAnswer resolves to an empty struct or to the error sent on done.
+type chanAnswer struct {
+	done chan error
+	once sync.Once
+	err  error
+}
+
+func (ans *chanAnswer) Struct() (Struct, error) {
+	ans.once.Do(func() { ans.err = <-ans.done })
+	return Struct{}, ans.err
+}
+
+func (ans *chanAnswer) PipelineCall([]PipelineOp, *Call) Answer {
+	return ErrorAnswer(errors.New("no pipelining"))
+}
+func (ans *chanAnswer) PipelineClose([]PipelineOp) error { return nil }
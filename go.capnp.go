@@ -13,33 +13,43 @@ const Tag = uint64(0xa574b41924caefc7)
 const Notag = uint64(0xc8768679ec52e012)
 const Customtype = uint64(0xfa10659ae02f2093)
 const Name = uint64(0xc2b96012172f8df1)
-const schema_d12a1c51fedd6c88 = "x\xda\x12\x98\xe2\xc0d\xc8z\x9c\x89\x81!P\x81\x95" +
-	"\xed\xff\xf1\xf7\xa7T$\xb7\x94,e\x08\xe4e\xe5\xf8" +
-	"\xdf\x91s\xf7_\xa0\x8c\xd6E\x06\x06FaO\xc6." +
-	"\xe1@Fv\x06\x86`\x1fFfF\x06\xc6\xff\x0f\xb4" +
-	"+\x95\x05\xeaW\xee\x03)eDQj\xcb\xb8J\xd8" +
-	"\x15\xac\xd4\x01\xa2\xf4c\xaf\xbe\xb8P\xc2\xceC\x0c\x17" +
-	"yY\xff\xf1\xa3\xa85d\x9c$l\x09Vk\x02Q" +
-	"\x1b7y~\xe0\xdek]GA\xc6\x9a\xa0(Ue" +
-	"\xec\x12\xd6\x05+\xd5\x80(\x15z\x10\xf4\xa6\xb2\xad\xec" +
-	"\x04\xa6c%\x19g\x09+\x82\x95\xca@\x94nu\xe1" +
-	"Sc\xdcf\xf0\x10\xd3\xb1\xbc\x8c\x8b\x84E\xc1J\x05" +
-	" J'+\xe8?\x98\x95*\xf0\x0b\xa4T\x01E)" +
-	"#\xe3!aN\xb0R\x16\x90R\x9e\xff\xc5%)\xfa" +
-	"\xe9\xf9z\xc9\x8c\x89\x05y\x05V%\x89\xe9\x0c\x0c\x01" +
-	"\x8c\x8c\x8c<\x0cLhR\x05\x89\xc9\xfc\xd9\x89\xe9\xa9" +
-	"\xd8e\xf3\x12s\x19qH\xa5\xe4'\xe323/\xbf" +
-	"\x8491=\x80\x91\x91\x81\x19M&3\xb7\x80=\xbf" +
-	"\xa8\x04]\x1b\x13X2\xb9\xb4\xb8$?\xb7\xa4\xb2 " +
-	"\x15f. \x00\x00\xff\xff\x89\xff\x94\xdf"
+const Presence = uint64(0xf91202a1a2def568)
+const Fixed = uint64(0x8eca5a561b96e1b1)
+const schema_d12a1c51fedd6c88 = "x\xdat\xd01H\x1bQ\x18\x07\xf0\xef\xbb\xe3\x9a\x04" +
+	"\x92&\xcd\x1b\xda\xd0B\x03MKii\xd3@\x96\x06" +
+	"J3\xb4C\xa1C\xce\xa0\x88\xa0x\xdc\x9dg\xd0\xdc" +
+	"\x1d\xc9E\x8c\x8b\x9bH@\x87\x04\x1c\xb2\x88\x01\x07\xc5" +
+	"EP\xc1A\x87\x80(\xd9\xb2\xb8)\xc9.\x88\x83\x83" +
+	"\x0e9\xb9{\x04<\xcf\xcc\xff\xdf\xf7\x7f\x7f^h'" +
+	"\xcd$\xb8\xf7,\x00\x1f\xe3^\x98\xbb\xdd\xb5\xb7#c" +
+	"\xadU\xe0}\\\xd4\\\x9e\xbd\xe8\xf1\xef\xbe\xb4\x01\x90" +
+	"\x94p\x010\xab#\x8b\x80\xe6\xe9u+\x16\xd936" +
+	"\x81\x0fp^\x07\x13\xb0Br\xe8\x01\xc8J\x94v\xbe" +
+	"\x96?\x84\x16\xb7\x8e-\x8a\x0e:\x8c\xdbd\xdc\xa6\xa3" +
+	"\x94\xde\xac\xc4_\x87'\x0f\x9b\xd0\x0ep\xbd\xa0\xc3\xfe" +
+	"\xc3*\xe1m\xfb\x9f\xda\x89\xda:\x7ft^9\xb1j" +
+	"\x93\x0e\xfa\x0b+\xe4\xafM\xd3\x94\x86;CW\xe5\xa5" +
+	"\xb93\xf7\xd8\x04\xd6\xc9O\x9b&)\xdd\xff\xf3\xf2\x13" +
+	"\x1e\xfc\xe8\xba\xc7~\xc4\x06\xf9f\xd3\xcf\x94N\xdf^" +
+	"66\x98\xf0\x9d\xfb\xa7\"X\x05\xcc\xbe\xa1\xac\x16\x8d" +
+	"w\xear\xe8\xdejt2\x1f6\xc9+\xbb\xd1O\xa9" +
+	"\xa2}\x17\x05]\xd5\xe1wj*7/K\xe8\x05\xc6" +
+	",\x1aR\xdc\x0a\xd0JR\x86\xa0\x00d\x10\xd1\xef\x8a" +
+	"tA\x0c\xce\x08\x8a\xfc|\xaa\x0ay\x1c\x10I\x9a8" +
+	"\xa8S\xd5\x0cVP2\x88\xc0>Iry\xdd\xa3\x15" +
+	"\x8c\xfeY\x7f:\xa6\xf4\x82\\\x94U\x11\xe5G'\x8c" +
+	"}\"\x96\x8a\x86\x967\xca\xba\xdc\x7f\xeda\x00\xc0\xd4" +
+	"\xbd\xa1"
 
 func init() {
 	schemas.Register(schema_d12a1c51fedd6c88,
+		0x8eca5a561b96e1b1,
 		0xa574b41924caefc7,
 		0xbea97f1023792be0,
 		0xc2b96012172f8df1,
 		0xc58ad6bd519f935e,
 		0xc8768679ec52e012,
 		0xe130b601260e44b5,
+		0xf91202a1a2def568,
 		0xfa10659ae02f2093)
 }
@@ -0,0 +1,129 @@
+package capnp
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// A Group makes a set of calls concurrently and waits for them to
+// return.  It bounds the number of calls in flight, stops the remaining
+// calls once one fails, and closes the pipeline of every call after its
+// results have been handled, so no answer is left unreleased when a
+// call fails or the group's context is canceled.  A Group must be
+// created with NewGroup.
+//
+// Example:
+//
+//	g := capnp.NewGroup(ctx, 8)
+//	for _, key := range keys {
+//		key := key
+//		g.Go(func(ctx context.Context) *capnp.Pipeline {
+//			return store.Get(ctx, func(p Store_get_Params) error {
+//				return p.SetKey(key)
+//			}).Pipeline
+//		}, func(s capnp.Struct) error {
+//			return record(key, Store_get_Results{Struct: s})
+//		})
+//	}
+//	if err := g.Wait(); err != nil {
+//		return err
+//	}
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{} // nil if there is no limit
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewGroup returns a group whose calls are made with a context derived
+// from ctx.  At most limit calls are in flight at once; if limit < 1,
+// then there is no limit.
+func NewGroup(ctx context.Context, limit int) *Group {
+	g := new(Group)
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	if limit > 0 {
+		g.sem = make(chan struct{}, limit)
+	}
+	return g
+}
+
+// Context returns the context that the group's calls are made with.
+// It is canceled once a call fails or Wait returns.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go makes a call and handles its results once it returns.  call must
+// make the call with ctx and return the root pipeline of its answer,
+// such as the Pipeline field of a generated promise.  Go blocks while
+// the group's limit of calls are in flight, and does not make the call
+// if the group's context is done.
+//
+// Once the call returns successfully, handle is called with its
+// results from another goroutine.  handle may be nil, and it must not
+// use the results after it returns: the group closes the call's
+// pipeline then, releasing the results (see Pipeline.Close).  An error
+// from the call or from handle cancels the group's context and is
+// reported by Wait.
+func (g *Group) Go(call func(ctx context.Context) *Pipeline, handle func(Struct) error) {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			g.fail(g.ctx.Err())
+			return
+		}
+	}
+	if err := g.ctx.Err(); err != nil {
+		if g.sem != nil {
+			<-g.sem
+		}
+		g.fail(err)
+		return
+	}
+	p := call(g.ctx)
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		s, err := p.Struct()
+		if err == nil && handle != nil {
+			err = handle(s)
+		}
+		if cerr := p.Close(); err == nil {
+			err = cerr
+		}
+		if g.sem != nil {
+			<-g.sem
+		}
+		if err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+// fail records err if it is the group's first error and cancels the
+// group's context.
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	if g.err == nil {
+		g.err = err
+	}
+	g.mu.Unlock()
+	g.cancel()
+}
+
+// Wait waits for every call made with Go to return and be handled.  It
+// then cancels the group's context and returns the first error from a
+// call or a handler, or the context's error if it was done before a
+// call could be made.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
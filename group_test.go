@@ -0,0 +1,153 @@
+package capnp
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestGroup(t *testing.T) {
+	gc := newGroupClient(t)
+	g := NewGroup(context.Background(), 2)
+	var mu sync.Mutex
+	var handled []uint64
+	for i := 0; i < 5; i++ {
+		g.Go(gc.call, func(s Struct) error {
+			mu.Lock()
+			handled = append(handled, s.Uint64(0))
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal("Wait:", err)
+	}
+	if len(handled) != 5 {
+		t.Errorf("handled %d results; want 5", len(handled))
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if gc.max > 2 {
+		t.Errorf("%d calls in flight at once; want <= 2", gc.max)
+	}
+	if gc.released != 5 {
+		t.Errorf("released %d results; want 5", gc.released)
+	}
+	if g.Context().Err() == nil {
+		t.Error("group context not canceled after Wait")
+	}
+}
+
+func TestGroup_Failure(t *testing.T) {
+	gc := newGroupClient(t)
+	gc.hang = true
+	g := NewGroup(context.Background(), 0)
+	for i := 0; i < 3; i++ {
+		g.Go(gc.call, nil)
+	}
+	boom := errors.New("boom")
+	g.Go(func(ctx context.Context) *Pipeline {
+		return NewPipeline(ImmediateAnswer(newGroupResult(t, gc, 99)))
+	}, func(Struct) error {
+		return boom
+	})
+	if err := g.Wait(); err != boom {
+		t.Errorf("Wait = %v; want %v", err, boom)
+	}
+	gc.mu.Lock()
+	if gc.released != 1 {
+		t.Errorf("released %d results; want 1", gc.released)
+	}
+	gc.mu.Unlock()
+
+	// Calls are not made once the group has failed.
+	g.Go(func(ctx context.Context) *Pipeline {
+		t.Error("call made after the group failed")
+		return NewPipeline(ErrorAnswer(ctx.Err()))
+	}, nil)
+}
+
+// groupClient makes calls that return results with a releaser, after a
+// short delay or once the call is canceled if hang is set.
+type groupClient struct {
+	t    *testing.T
+	hang bool
+
+	mu       sync.Mutex
+	n        int
+	inFlight int
+	max      int
+	released int
+}
+
+func newGroupClient(t *testing.T) *groupClient {
+	return &groupClient{t: t}
+}
+
+func (gc *groupClient) call(ctx context.Context) *Pipeline {
+	gc.mu.Lock()
+	gc.n++
+	n := gc.n
+	gc.inFlight++
+	if gc.inFlight > gc.max {
+		gc.max = gc.inFlight
+	}
+	gc.mu.Unlock()
+	ans := &groupAnswer{done: make(chan struct{})}
+	go func() {
+		if gc.hang {
+			<-ctx.Done()
+			ans.err = ctx.Err()
+		} else {
+			time.Sleep(time.Millisecond)
+			ans.s = newGroupResult(gc.t, gc, uint64(n))
+		}
+		gc.mu.Lock()
+		gc.inFlight--
+		gc.mu.Unlock()
+		close(ans.done)
+	}()
+	return NewPipeline(ans)
+}
+
+// newGroupResult returns an answer-owned result struct holding n.
+func newGroupResult(t *testing.T, gc *groupClient, n uint64) Struct {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewRootStruct(seg, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetUint64(0, n)
+	msg.AddReleaser(func() {
+		gc.mu.Lock()
+		gc.released++
+		gc.mu.Unlock()
+	})
+	msg.SetAnswerOwned()
+	return s
+}
+
+type groupAnswer struct {
+	done chan struct{}
+	s    Struct
+	err  error
+}
+
+func (ans *groupAnswer) Struct() (Struct, error) {
+	<-ans.done
+	return ans.s, ans.err
+}
+
+func (ans *groupAnswer) PipelineCall(transform []PipelineOp, call *Call) Answer {
+	return ErrorAnswer(ErrUnimplemented)
+}
+
+func (ans *groupAnswer) PipelineClose(transform []PipelineOp) error {
+	return nil
+}
@@ -2250,6 +2250,56 @@ func TestSetNilBlobWithDefault(t *testing.T) {
 	// Specifically not checking for nil.  Anything zero-length is appropriate here.
 }
 
+func TestGeneratedReadAccessors(t *testing.T) {
+	t.Parallel()
+	msg := &capnp.Message{Arena: capnp.SingleSegment([]byte{
+		0, 0, 0, 0, 4, 0, 2, 0, // root PlaneBase pointer
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		2, 0, 0, 0, 9, 0, 0, 0, // name: far pointer to nonexistent segment
+		1, 0, 0, 0, 0x13, 0, 0, 0, // homes: list of 2 enums
+		1, 0, 2, 0, 0, 0, 0, 0,
+	})}
+	pb, err := air.ReadRootPlaneBase(msg)
+	if err != nil {
+		t.Fatal("ReadRootPlaneBase:", err)
+	}
+	homes := pb.ReadHomes()
+	if homes.Len() != 2 || homes.At(0) != air.Airport_jfk || homes.At(1) != air.Airport_lax {
+		got, _ := homes.ToSlice()
+		t.Errorf("pb.ReadHomes() = %v; want [jfk lax]", got)
+	}
+	if err := msg.Err(); err != nil {
+		t.Errorf("after valid read, msg.Err() = %v; want <nil>", err)
+	}
+	if name := pb.ReadName(); name != "" {
+		t.Errorf("pb.ReadName() = %q; want \"\"", name)
+	}
+	if err := msg.Err(); err == nil {
+		t.Error("after invalid read, msg.Err() = <nil>; want error")
+	}
+
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal("NewMessage:", err)
+	}
+	d, err := air.NewRootDefaults(seg)
+	if err != nil {
+		t.Fatal("NewRootDefaults:", err)
+	}
+	if text := d.ReadText(); text != "foo" {
+		t.Errorf("d.ReadText() = %q; want \"foo\"", text)
+	}
+	if data := d.ReadData(); string(data) != "bar" {
+		t.Errorf("d.ReadData() = %q; want \"bar\"", data)
+	}
+	if err := seg.Message().Err(); err != nil {
+		t.Errorf("msg.Err() = %v; want <nil>", err)
+	}
+}
+
 func TestSetEmptyTextWithDefault(t *testing.T) {
 	t.Parallel()
 	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
@@ -2308,6 +2358,37 @@ func TestFuzzedListOutOfBounds(t *testing.T) {
 	}
 }
 
+func TestEnumListSlice(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := air.NewAirport_List(seg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []air.Airport{air.Airport_jfk, air.Airport_lax, air.Airport_dfw}
+	if err := l.SetSlice(want); err != nil {
+		t.Fatal("SetSlice:", err)
+	}
+	if got, err := l.ToSlice(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ToSlice() = %v, %v; want %v, <nil>", got, err, want)
+	}
+	if got, err := (air.Airport_List{}).ToSlice(); got != nil || err != nil {
+		t.Errorf("ToSlice on null list = %v, %v; want [], <nil>", got, err)
+	}
+	if err := l.SetSlice(want[:2]); err == nil {
+		t.Error("SetSlice with short slice succeeded")
+	}
+	if err := l.Validate(); err != nil {
+		t.Error("Validate:", err)
+	}
+	l.Set(1, air.Airport(100))
+	if err := l.Validate(); err == nil {
+		t.Error("Validate with unknown value succeeded")
+	}
+}
+
 func benchmarkGrowth(b *testing.B, newArena func() capnp.Arena) {
 	const (
 		fieldValue = "1234567" // carefully chosen to be word-padded
@@ -134,6 +134,12 @@ func (s Zdata) Data() ([]byte, error) {
 	return []byte(p.Data()), err
 }
 
+// ReadData is like Data, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Zdata) ReadData() []byte {
+	return []byte(s.Struct.ReadData(0))
+}
+
 func (s Zdata) HasData() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -249,6 +255,33 @@ func (l Airport_List) Set(i int, v Airport) {
 	ul.Set(i, uint16(v))
 }
 
+// ToSlice returns a copy of the list's elements.  See capnp.UInt16List.ToSlice.
+func (l Airport_List) ToSlice() ([]Airport, error) {
+	u, err := capnp.UInt16List{List: l.List}.ToSlice()
+	if err != nil || u == nil {
+		return nil, err
+	}
+	s := make([]Airport, len(u))
+	for i := range u {
+		s[i] = Airport(u[i])
+	}
+	return s, nil
+}
+
+// SetSlice sets the list's elements to v, which must be the same length as the list.
+func (l Airport_List) SetSlice(v []Airport) error {
+	u := make([]uint16, len(v))
+	for i := range v {
+		u[i] = uint16(v[i])
+	}
+	return capnp.UInt16List{List: l.List}.SetSlice(u)
+}
+
+// Validate returns an error if any element of the list is not a known Airport value.
+func (l Airport_List) Validate() error {
+	return capnp.UInt16List{List: l.List}.VerifyEnum("Airport", 7)
+}
+
 type PlaneBase struct{ capnp.Struct }
 
 // PlaneBase_TypeID is the unique identifier for the type PlaneBase.
@@ -275,8 +308,13 @@ func (s PlaneBase) String() string {
 }
 
 func (s PlaneBase) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s PlaneBase) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s PlaneBase) HasName() bool {
@@ -285,8 +323,7 @@ func (s PlaneBase) HasName() bool {
 }
 
 func (s PlaneBase) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s PlaneBase) SetName(v string) error {
@@ -298,6 +335,12 @@ func (s PlaneBase) Homes() (Airport_List, error) {
 	return Airport_List{List: p.List()}, err
 }
 
+// ReadHomes is like Homes, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s PlaneBase) ReadHomes() Airport_List {
+	return Airport_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s PlaneBase) HasHomes() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -406,6 +449,12 @@ func (s B737) Base() (PlaneBase, error) {
 	return PlaneBase{Struct: p.Struct()}, err
 }
 
+// ReadBase is like Base, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s B737) ReadBase() PlaneBase {
+	return PlaneBase{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s B737) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -486,6 +535,12 @@ func (s A320) Base() (PlaneBase, error) {
 	return PlaneBase{Struct: p.Struct()}, err
 }
 
+// ReadBase is like Base, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s A320) ReadBase() PlaneBase {
+	return PlaneBase{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s A320) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -566,6 +621,12 @@ func (s F16) Base() (PlaneBase, error) {
 	return PlaneBase{Struct: p.Struct()}, err
 }
 
+// ReadBase is like Base, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s F16) ReadBase() PlaneBase {
+	return PlaneBase{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s F16) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -646,6 +707,12 @@ func (s Regression) Base() (PlaneBase, error) {
 	return PlaneBase{Struct: p.Struct()}, err
 }
 
+// ReadBase is like Base, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Regression) ReadBase() PlaneBase {
+	return PlaneBase{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Regression) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -679,6 +746,12 @@ func (s Regression) Beta() (capnp.Float64List, error) {
 	return capnp.Float64List{List: p.List()}, err
 }
 
+// ReadBeta is like Beta, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Regression) ReadBeta() capnp.Float64List {
+	return capnp.Float64List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Regression) HasBeta() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -704,6 +777,12 @@ func (s Regression) Planes() (Aircraft_List, error) {
 	return Aircraft_List{List: p.List()}, err
 }
 
+// ReadPlanes is like Planes, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Regression) ReadPlanes() Aircraft_List {
+	return Aircraft_List{List: s.Struct.ReadPtr(2).List()}
+}
+
 func (s Regression) HasPlanes() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -835,6 +914,15 @@ func (s Aircraft) B737() (B737, error) {
 	return B737{Struct: p.Struct()}, err
 }
 
+// ReadB737 is like B737, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Aircraft) ReadB737() B737 {
+	if s.Struct.Uint16(0) != 1 {
+		panic("Which() != b737")
+	}
+	return B737{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Aircraft) HasB737() bool {
 	if s.Struct.Uint16(0) != 1 {
 		return false
@@ -868,6 +956,15 @@ func (s Aircraft) A320() (A320, error) {
 	return A320{Struct: p.Struct()}, err
 }
 
+// ReadA320 is like A320, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Aircraft) ReadA320() A320 {
+	if s.Struct.Uint16(0) != 2 {
+		panic("Which() != a320")
+	}
+	return A320{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Aircraft) HasA320() bool {
 	if s.Struct.Uint16(0) != 2 {
 		return false
@@ -901,6 +998,15 @@ func (s Aircraft) F16() (F16, error) {
 	return F16{Struct: p.Struct()}, err
 }
 
+// ReadF16 is like F16, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Aircraft) ReadF16() F16 {
+	if s.Struct.Uint16(0) != 3 {
+		panic("Which() != f16")
+	}
+	return F16{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Aircraft) HasF16() bool {
 	if s.Struct.Uint16(0) != 3 {
 		return false
@@ -1153,6 +1259,15 @@ func (s Z) Zz() (Z, error) {
 	return Z{Struct: p.Struct()}, err
 }
 
+// ReadZz is like Zz, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZz() Z {
+	if s.Struct.Uint16(0) != 1 {
+		panic("Which() != zz")
+	}
+	return Z{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasZz() bool {
 	if s.Struct.Uint16(0) != 1 {
 		return false
@@ -1314,8 +1429,16 @@ func (s Z) Text() (string, error) {
 	if s.Struct.Uint16(0) != 13 {
 		panic("Which() != text")
 	}
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadText is like Text, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadText() string {
+	if s.Struct.Uint16(0) != 13 {
+		panic("Which() != text")
+	}
+	return s.Struct.ReadText(0)
 }
 
 func (s Z) HasText() bool {
@@ -1327,8 +1450,7 @@ func (s Z) HasText() bool {
 }
 
 func (s Z) TextBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Z) SetText(v string) error {
@@ -1344,6 +1466,15 @@ func (s Z) Blob() ([]byte, error) {
 	return []byte(p.Data()), err
 }
 
+// ReadBlob is like Blob, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadBlob() []byte {
+	if s.Struct.Uint16(0) != 14 {
+		panic("Which() != blob")
+	}
+	return []byte(s.Struct.ReadData(0))
+}
+
 func (s Z) HasBlob() bool {
 	if s.Struct.Uint16(0) != 14 {
 		return false
@@ -1365,6 +1496,15 @@ func (s Z) F64vec() (capnp.Float64List, error) {
 	return capnp.Float64List{List: p.List()}, err
 }
 
+// ReadF64vec is like F64vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadF64vec() capnp.Float64List {
+	if s.Struct.Uint16(0) != 15 {
+		panic("Which() != f64vec")
+	}
+	return capnp.Float64List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasF64vec() bool {
 	if s.Struct.Uint16(0) != 15 {
 		return false
@@ -1398,6 +1538,15 @@ func (s Z) F32vec() (capnp.Float32List, error) {
 	return capnp.Float32List{List: p.List()}, err
 }
 
+// ReadF32vec is like F32vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadF32vec() capnp.Float32List {
+	if s.Struct.Uint16(0) != 16 {
+		panic("Which() != f32vec")
+	}
+	return capnp.Float32List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasF32vec() bool {
 	if s.Struct.Uint16(0) != 16 {
 		return false
@@ -1431,6 +1580,15 @@ func (s Z) I64vec() (capnp.Int64List, error) {
 	return capnp.Int64List{List: p.List()}, err
 }
 
+// ReadI64vec is like I64vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadI64vec() capnp.Int64List {
+	if s.Struct.Uint16(0) != 17 {
+		panic("Which() != i64vec")
+	}
+	return capnp.Int64List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasI64vec() bool {
 	if s.Struct.Uint16(0) != 17 {
 		return false
@@ -1464,6 +1622,15 @@ func (s Z) I32vec() (capnp.Int32List, error) {
 	return capnp.Int32List{List: p.List()}, err
 }
 
+// ReadI32vec is like I32vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadI32vec() capnp.Int32List {
+	if s.Struct.Uint16(0) != 18 {
+		panic("Which() != i32vec")
+	}
+	return capnp.Int32List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasI32vec() bool {
 	if s.Struct.Uint16(0) != 18 {
 		return false
@@ -1497,6 +1664,15 @@ func (s Z) I16vec() (capnp.Int16List, error) {
 	return capnp.Int16List{List: p.List()}, err
 }
 
+// ReadI16vec is like I16vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadI16vec() capnp.Int16List {
+	if s.Struct.Uint16(0) != 19 {
+		panic("Which() != i16vec")
+	}
+	return capnp.Int16List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasI16vec() bool {
 	if s.Struct.Uint16(0) != 19 {
 		return false
@@ -1530,6 +1706,15 @@ func (s Z) I8vec() (capnp.Int8List, error) {
 	return capnp.Int8List{List: p.List()}, err
 }
 
+// ReadI8vec is like I8vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadI8vec() capnp.Int8List {
+	if s.Struct.Uint16(0) != 20 {
+		panic("Which() != i8vec")
+	}
+	return capnp.Int8List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasI8vec() bool {
 	if s.Struct.Uint16(0) != 20 {
 		return false
@@ -1563,6 +1748,15 @@ func (s Z) U64vec() (capnp.UInt64List, error) {
 	return capnp.UInt64List{List: p.List()}, err
 }
 
+// ReadU64vec is like U64vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadU64vec() capnp.UInt64List {
+	if s.Struct.Uint16(0) != 21 {
+		panic("Which() != u64vec")
+	}
+	return capnp.UInt64List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasU64vec() bool {
 	if s.Struct.Uint16(0) != 21 {
 		return false
@@ -1596,6 +1790,15 @@ func (s Z) U32vec() (capnp.UInt32List, error) {
 	return capnp.UInt32List{List: p.List()}, err
 }
 
+// ReadU32vec is like U32vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadU32vec() capnp.UInt32List {
+	if s.Struct.Uint16(0) != 22 {
+		panic("Which() != u32vec")
+	}
+	return capnp.UInt32List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasU32vec() bool {
 	if s.Struct.Uint16(0) != 22 {
 		return false
@@ -1629,6 +1832,15 @@ func (s Z) U16vec() (capnp.UInt16List, error) {
 	return capnp.UInt16List{List: p.List()}, err
 }
 
+// ReadU16vec is like U16vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadU16vec() capnp.UInt16List {
+	if s.Struct.Uint16(0) != 23 {
+		panic("Which() != u16vec")
+	}
+	return capnp.UInt16List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasU16vec() bool {
 	if s.Struct.Uint16(0) != 23 {
 		return false
@@ -1662,6 +1874,15 @@ func (s Z) U8vec() (capnp.UInt8List, error) {
 	return capnp.UInt8List{List: p.List()}, err
 }
 
+// ReadU8vec is like U8vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadU8vec() capnp.UInt8List {
+	if s.Struct.Uint16(0) != 24 {
+		panic("Which() != u8vec")
+	}
+	return capnp.UInt8List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasU8vec() bool {
 	if s.Struct.Uint16(0) != 24 {
 		return false
@@ -1695,6 +1916,15 @@ func (s Z) Boolvec() (capnp.BitList, error) {
 	return capnp.BitList{List: p.List()}, err
 }
 
+// ReadBoolvec is like Boolvec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadBoolvec() capnp.BitList {
+	if s.Struct.Uint16(0) != 39 {
+		panic("Which() != boolvec")
+	}
+	return capnp.BitList{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasBoolvec() bool {
 	if s.Struct.Uint16(0) != 39 {
 		return false
@@ -1728,6 +1958,15 @@ func (s Z) Datavec() (capnp.DataList, error) {
 	return capnp.DataList{List: p.List()}, err
 }
 
+// ReadDatavec is like Datavec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadDatavec() capnp.DataList {
+	if s.Struct.Uint16(0) != 40 {
+		panic("Which() != datavec")
+	}
+	return capnp.DataList{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasDatavec() bool {
 	if s.Struct.Uint16(0) != 40 {
 		return false
@@ -1761,6 +2000,15 @@ func (s Z) Textvec() (capnp.TextList, error) {
 	return capnp.TextList{List: p.List()}, err
 }
 
+// ReadTextvec is like Textvec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadTextvec() capnp.TextList {
+	if s.Struct.Uint16(0) != 41 {
+		panic("Which() != textvec")
+	}
+	return capnp.TextList{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasTextvec() bool {
 	if s.Struct.Uint16(0) != 41 {
 		return false
@@ -1794,6 +2042,15 @@ func (s Z) Zvec() (Z_List, error) {
 	return Z_List{List: p.List()}, err
 }
 
+// ReadZvec is like Zvec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZvec() Z_List {
+	if s.Struct.Uint16(0) != 25 {
+		panic("Which() != zvec")
+	}
+	return Z_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasZvec() bool {
 	if s.Struct.Uint16(0) != 25 {
 		return false
@@ -1827,6 +2084,15 @@ func (s Z) Zvecvec() (capnp.PointerList, error) {
 	return capnp.PointerList{List: p.List()}, err
 }
 
+// ReadZvecvec is like Zvecvec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZvecvec() capnp.PointerList {
+	if s.Struct.Uint16(0) != 26 {
+		panic("Which() != zvecvec")
+	}
+	return capnp.PointerList{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasZvecvec() bool {
 	if s.Struct.Uint16(0) != 26 {
 		return false
@@ -1860,6 +2126,15 @@ func (s Z) Zdate() (Zdate, error) {
 	return Zdate{Struct: p.Struct()}, err
 }
 
+// ReadZdate is like Zdate, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZdate() Zdate {
+	if s.Struct.Uint16(0) != 27 {
+		panic("Which() != zdate")
+	}
+	return Zdate{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasZdate() bool {
 	if s.Struct.Uint16(0) != 27 {
 		return false
@@ -1893,6 +2168,15 @@ func (s Z) Zdata() (Zdata, error) {
 	return Zdata{Struct: p.Struct()}, err
 }
 
+// ReadZdata is like Zdata, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZdata() Zdata {
+	if s.Struct.Uint16(0) != 28 {
+		panic("Which() != zdata")
+	}
+	return Zdata{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasZdata() bool {
 	if s.Struct.Uint16(0) != 28 {
 		return false
@@ -1926,6 +2210,15 @@ func (s Z) Aircraftvec() (Aircraft_List, error) {
 	return Aircraft_List{List: p.List()}, err
 }
 
+// ReadAircraftvec is like Aircraftvec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadAircraftvec() Aircraft_List {
+	if s.Struct.Uint16(0) != 29 {
+		panic("Which() != aircraftvec")
+	}
+	return Aircraft_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasAircraftvec() bool {
 	if s.Struct.Uint16(0) != 29 {
 		return false
@@ -1959,6 +2252,15 @@ func (s Z) Aircraft() (Aircraft, error) {
 	return Aircraft{Struct: p.Struct()}, err
 }
 
+// ReadAircraft is like Aircraft, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadAircraft() Aircraft {
+	if s.Struct.Uint16(0) != 30 {
+		panic("Which() != aircraft")
+	}
+	return Aircraft{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasAircraft() bool {
 	if s.Struct.Uint16(0) != 30 {
 		return false
@@ -1992,6 +2294,15 @@ func (s Z) Regression() (Regression, error) {
 	return Regression{Struct: p.Struct()}, err
 }
 
+// ReadRegression is like Regression, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadRegression() Regression {
+	if s.Struct.Uint16(0) != 31 {
+		panic("Which() != regression")
+	}
+	return Regression{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasRegression() bool {
 	if s.Struct.Uint16(0) != 31 {
 		return false
@@ -2025,6 +2336,15 @@ func (s Z) Planebase() (PlaneBase, error) {
 	return PlaneBase{Struct: p.Struct()}, err
 }
 
+// ReadPlanebase is like Planebase, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadPlanebase() PlaneBase {
+	if s.Struct.Uint16(0) != 32 {
+		panic("Which() != planebase")
+	}
+	return PlaneBase{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasPlanebase() bool {
 	if s.Struct.Uint16(0) != 32 {
 		return false
@@ -2070,6 +2390,15 @@ func (s Z) B737() (B737, error) {
 	return B737{Struct: p.Struct()}, err
 }
 
+// ReadB737 is like B737, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadB737() B737 {
+	if s.Struct.Uint16(0) != 34 {
+		panic("Which() != b737")
+	}
+	return B737{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasB737() bool {
 	if s.Struct.Uint16(0) != 34 {
 		return false
@@ -2103,6 +2432,15 @@ func (s Z) A320() (A320, error) {
 	return A320{Struct: p.Struct()}, err
 }
 
+// ReadA320 is like A320, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadA320() A320 {
+	if s.Struct.Uint16(0) != 35 {
+		panic("Which() != a320")
+	}
+	return A320{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasA320() bool {
 	if s.Struct.Uint16(0) != 35 {
 		return false
@@ -2136,6 +2474,15 @@ func (s Z) F16() (F16, error) {
 	return F16{Struct: p.Struct()}, err
 }
 
+// ReadF16 is like F16, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadF16() F16 {
+	if s.Struct.Uint16(0) != 36 {
+		panic("Which() != f16")
+	}
+	return F16{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasF16() bool {
 	if s.Struct.Uint16(0) != 36 {
 		return false
@@ -2169,6 +2516,15 @@ func (s Z) Zdatevec() (Zdate_List, error) {
 	return Zdate_List{List: p.List()}, err
 }
 
+// ReadZdatevec is like Zdatevec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZdatevec() Zdate_List {
+	if s.Struct.Uint16(0) != 37 {
+		panic("Which() != zdatevec")
+	}
+	return Zdate_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasZdatevec() bool {
 	if s.Struct.Uint16(0) != 37 {
 		return false
@@ -2202,6 +2558,15 @@ func (s Z) Zdatavec() (Zdata_List, error) {
 	return Zdata_List{List: p.List()}, err
 }
 
+// ReadZdatavec is like Zdatavec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadZdatavec() Zdata_List {
+	if s.Struct.Uint16(0) != 38 {
+		panic("Which() != zdatavec")
+	}
+	return Zdata_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Z) HasZdatavec() bool {
 	if s.Struct.Uint16(0) != 38 {
 		return false
@@ -2283,6 +2648,15 @@ func (s Z) EchoBases() (EchoBases, error) {
 	return EchoBases{Struct: p.Struct()}, err
 }
 
+// ReadEchoBases is like EchoBases, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Z) ReadEchoBases() EchoBases {
+	if s.Struct.Uint16(0) != 44 {
+		panic("Which() != echoBases")
+	}
+	return EchoBases{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Z) HasEchoBases() bool {
 	if s.Struct.Uint16(0) != 44 {
 		return false
@@ -2422,8 +2796,13 @@ func (s Counter) SetSize(v int64) {
 }
 
 func (s Counter) Words() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadWords is like Words, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Counter) ReadWords() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Counter) HasWords() bool {
@@ -2432,8 +2811,7 @@ func (s Counter) HasWords() bool {
 }
 
 func (s Counter) WordsBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Counter) SetWords(v string) error {
@@ -2445,6 +2823,12 @@ func (s Counter) Wordlist() (capnp.TextList, error) {
 	return capnp.TextList{List: p.List()}, err
 }
 
+// ReadWordlist is like Wordlist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Counter) ReadWordlist() capnp.TextList {
+	return capnp.TextList{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Counter) HasWordlist() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -2470,6 +2854,12 @@ func (s Counter) Bitlist() (capnp.BitList, error) {
 	return capnp.BitList{List: p.List()}, err
 }
 
+// ReadBitlist is like Bitlist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Counter) ReadBitlist() capnp.BitList {
+	return capnp.BitList{List: s.Struct.ReadPtr(2).List()}
+}
+
 func (s Counter) HasBitlist() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -2546,6 +2936,12 @@ func (s Bag) Counter() (Counter, error) {
 	return Counter{Struct: p.Struct()}, err
 }
 
+// ReadCounter is like Counter, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Bag) ReadCounter() Counter {
+	return Counter{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Bag) HasCounter() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -2626,6 +3022,12 @@ func (s Zserver) Waitingjobs() (Zjob_List, error) {
 	return Zjob_List{List: p.List()}, err
 }
 
+// ReadWaitingjobs is like Waitingjobs, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Zserver) ReadWaitingjobs() Zjob_List {
+	return Zjob_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Zserver) HasWaitingjobs() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -2698,8 +3100,13 @@ func (s Zjob) String() string {
 }
 
 func (s Zjob) Cmd() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadCmd is like Cmd, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Zjob) ReadCmd() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Zjob) HasCmd() bool {
@@ -2708,8 +3115,7 @@ func (s Zjob) HasCmd() bool {
 }
 
 func (s Zjob) CmdBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Zjob) SetCmd(v string) error {
@@ -2721,6 +3127,12 @@ func (s Zjob) Args() (capnp.TextList, error) {
 	return capnp.TextList{List: p.List()}, err
 }
 
+// ReadArgs is like Args, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Zjob) ReadArgs() capnp.TextList {
+	return capnp.TextList{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Zjob) HasArgs() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -2974,6 +3386,12 @@ func (s VerOnePtr) Ptr() (VerOneData, error) {
 	return VerOneData{Struct: p.Struct()}, err
 }
 
+// ReadPtr is like Ptr, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerOnePtr) ReadPtr() VerOneData {
+	return VerOneData{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s VerOnePtr) HasPtr() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3054,6 +3472,12 @@ func (s VerTwoPtr) Ptr1() (VerOneData, error) {
 	return VerOneData{Struct: p.Struct()}, err
 }
 
+// ReadPtr1 is like Ptr1, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoPtr) ReadPtr1() VerOneData {
+	return VerOneData{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s VerTwoPtr) HasPtr1() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3079,6 +3503,12 @@ func (s VerTwoPtr) Ptr2() (VerOneData, error) {
 	return VerOneData{Struct: p.Struct()}, err
 }
 
+// ReadPtr2 is like Ptr2, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoPtr) ReadPtr2() VerOneData {
+	return VerOneData{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s VerTwoPtr) HasPtr2() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -3179,6 +3609,12 @@ func (s VerTwoDataTwoPtr) Ptr1() (VerOneData, error) {
 	return VerOneData{Struct: p.Struct()}, err
 }
 
+// ReadPtr1 is like Ptr1, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoDataTwoPtr) ReadPtr1() VerOneData {
+	return VerOneData{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s VerTwoDataTwoPtr) HasPtr1() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3204,6 +3640,12 @@ func (s VerTwoDataTwoPtr) Ptr2() (VerOneData, error) {
 	return VerOneData{Struct: p.Struct()}, err
 }
 
+// ReadPtr2 is like Ptr2, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoDataTwoPtr) ReadPtr2() VerOneData {
+	return VerOneData{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s VerTwoDataTwoPtr) HasPtr2() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -3290,6 +3732,12 @@ func (s HoldsVerEmptyList) Mylist() (VerEmpty_List, error) {
 	return VerEmpty_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerEmptyList) ReadMylist() VerEmpty_List {
+	return VerEmpty_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerEmptyList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3370,6 +3818,12 @@ func (s HoldsVerOneDataList) Mylist() (VerOneData_List, error) {
 	return VerOneData_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerOneDataList) ReadMylist() VerOneData_List {
+	return VerOneData_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerOneDataList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3450,6 +3904,12 @@ func (s HoldsVerTwoDataList) Mylist() (VerTwoData_List, error) {
 	return VerTwoData_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerTwoDataList) ReadMylist() VerTwoData_List {
+	return VerTwoData_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerTwoDataList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3530,6 +3990,12 @@ func (s HoldsVerOnePtrList) Mylist() (VerOnePtr_List, error) {
 	return VerOnePtr_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerOnePtrList) ReadMylist() VerOnePtr_List {
+	return VerOnePtr_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerOnePtrList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3610,6 +4076,12 @@ func (s HoldsVerTwoPtrList) Mylist() (VerTwoPtr_List, error) {
 	return VerTwoPtr_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerTwoPtrList) ReadMylist() VerTwoPtr_List {
+	return VerTwoPtr_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerTwoPtrList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3690,6 +4162,12 @@ func (s HoldsVerTwoTwoList) Mylist() (VerTwoDataTwoPtr_List, error) {
 	return VerTwoDataTwoPtr_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerTwoTwoList) ReadMylist() VerTwoDataTwoPtr_List {
+	return VerTwoDataTwoPtr_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerTwoTwoList) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3770,6 +4248,12 @@ func (s HoldsVerTwoTwoPlus) Mylist() (VerTwoTwoPlus_List, error) {
 	return VerTwoTwoPlus_List{List: p.List()}, err
 }
 
+// ReadMylist is like Mylist, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsVerTwoTwoPlus) ReadMylist() VerTwoTwoPlus_List {
+	return VerTwoTwoPlus_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s HoldsVerTwoTwoPlus) HasMylist() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3866,6 +4350,12 @@ func (s VerTwoTwoPlus) Ptr1() (VerTwoDataTwoPtr, error) {
 	return VerTwoDataTwoPtr{Struct: p.Struct()}, err
 }
 
+// ReadPtr1 is like Ptr1, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoTwoPlus) ReadPtr1() VerTwoDataTwoPtr {
+	return VerTwoDataTwoPtr{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s VerTwoTwoPlus) HasPtr1() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3891,6 +4381,12 @@ func (s VerTwoTwoPlus) Ptr2() (VerTwoDataTwoPtr, error) {
 	return VerTwoDataTwoPtr{Struct: p.Struct()}, err
 }
 
+// ReadPtr2 is like Ptr2, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoTwoPlus) ReadPtr2() VerTwoDataTwoPtr {
+	return VerTwoDataTwoPtr{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s VerTwoTwoPlus) HasPtr2() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -3924,6 +4420,12 @@ func (s VerTwoTwoPlus) Lst3() (capnp.Int64List, error) {
 	return capnp.Int64List{List: p.List()}, err
 }
 
+// ReadLst3 is like Lst3, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s VerTwoTwoPlus) ReadLst3() capnp.Int64List {
+	return capnp.Int64List{List: s.Struct.ReadPtr(2).List()}
+}
+
 func (s VerTwoTwoPlus) HasLst3() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -4004,8 +4506,13 @@ func (s HoldsText) String() string {
 }
 
 func (s HoldsText) Txt() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadTxt is like Txt, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsText) ReadTxt() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s HoldsText) HasTxt() bool {
@@ -4014,8 +4521,7 @@ func (s HoldsText) HasTxt() bool {
 }
 
 func (s HoldsText) TxtBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s HoldsText) SetTxt(v string) error {
@@ -4027,6 +4533,12 @@ func (s HoldsText) Lst() (capnp.TextList, error) {
 	return capnp.TextList{List: p.List()}, err
 }
 
+// ReadLst is like Lst, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsText) ReadLst() capnp.TextList {
+	return capnp.TextList{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s HoldsText) HasLst() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -4052,6 +4564,12 @@ func (s HoldsText) Lstlst() (capnp.PointerList, error) {
 	return capnp.PointerList{List: p.List()}, err
 }
 
+// ReadLstlst is like Lstlst, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s HoldsText) ReadLstlst() capnp.PointerList {
+	return capnp.PointerList{List: s.Struct.ReadPtr(2).List()}
+}
+
 func (s HoldsText) HasLstlst() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -4128,6 +4646,12 @@ func (s WrapEmpty) MightNotBeReallyEmpty() (VerEmpty, error) {
 	return VerEmpty{Struct: p.Struct()}, err
 }
 
+// ReadMightNotBeReallyEmpty is like MightNotBeReallyEmpty, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s WrapEmpty) ReadMightNotBeReallyEmpty() VerEmpty {
+	return VerEmpty{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s WrapEmpty) HasMightNotBeReallyEmpty() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -4208,6 +4732,12 @@ func (s Wrap2x2) MightNotBeReallyEmpty() (VerTwoDataTwoPtr, error) {
 	return VerTwoDataTwoPtr{Struct: p.Struct()}, err
 }
 
+// ReadMightNotBeReallyEmpty is like MightNotBeReallyEmpty, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Wrap2x2) ReadMightNotBeReallyEmpty() VerTwoDataTwoPtr {
+	return VerTwoDataTwoPtr{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Wrap2x2) HasMightNotBeReallyEmpty() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -4288,6 +4818,12 @@ func (s Wrap2x2plus) MightNotBeReallyEmpty() (VerTwoTwoPlus, error) {
 	return VerTwoTwoPlus{Struct: p.Struct()}, err
 }
 
+// ReadMightNotBeReallyEmpty is like MightNotBeReallyEmpty, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Wrap2x2plus) ReadMightNotBeReallyEmpty() VerTwoTwoPlus {
+	return VerTwoTwoPlus{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Wrap2x2plus) HasMightNotBeReallyEmpty() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -4450,6 +4986,12 @@ func (s Nester1Capn) Strs() (capnp.TextList, error) {
 	return capnp.TextList{List: p.List()}, err
 }
 
+// ReadStrs is like Strs, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Nester1Capn) ReadStrs() capnp.TextList {
+	return capnp.TextList{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Nester1Capn) HasStrs() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -4526,6 +5068,12 @@ func (s RWTestCapn) NestMatrix() (capnp.PointerList, error) {
 	return capnp.PointerList{List: p.List()}, err
 }
 
+// ReadNestMatrix is like NestMatrix, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s RWTestCapn) ReadNestMatrix() capnp.PointerList {
+	return capnp.PointerList{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s RWTestCapn) HasNestMatrix() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -4602,6 +5150,12 @@ func (s ListStructCapn) Vec() (Nester1Capn_List, error) {
 	return Nester1Capn_List{List: p.List()}, err
 }
 
+// ReadVec is like Vec, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s ListStructCapn) ReadVec() Nester1Capn_List {
+	return Nester1Capn_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s ListStructCapn) HasVec() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -4678,6 +5232,24 @@ type Echo_Server interface {
 	Echo(Echo_echo) error
 }
 
+// UnimplementedEcho_Server can be embedded in an implementation
+// of Echo_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedEcho_Server struct{}
+
+func (UnimplementedEcho_Server) Echo(Echo_echo) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x8e5322c1e9282534,
+			MethodID:      0,
+			InterfaceName: "aircraft.capnp:Echo",
+			MethodName:    "echo",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func Echo_ServerToClient(s Echo_Server) Echo {
 	c, _ := s.(server.Closer)
 	return Echo{Client: server.New(Echo_Methods(nil, s), c)}
@@ -4713,6 +5285,32 @@ type Echo_echo struct {
 	Results Echo_echo_Results
 }
 
+// TailCall delegates the call to echo on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Echo_echo) TailCall(t Echo, params func(Echo_echo_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x8e5322c1e9282534,
+			MethodID:      0,
+			InterfaceName: "aircraft.capnp:Echo",
+			MethodName:    "echo",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Echo_echo_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type Echo_echo_Params struct{ capnp.Struct }
 
 // Echo_echo_Params_TypeID is the unique identifier for the type Echo_echo_Params.
@@ -4739,8 +5337,13 @@ func (s Echo_echo_Params) String() string {
 }
 
 func (s Echo_echo_Params) In() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadIn is like In, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Echo_echo_Params) ReadIn() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Echo_echo_Params) HasIn() bool {
@@ -4749,8 +5352,7 @@ func (s Echo_echo_Params) HasIn() bool {
 }
 
 func (s Echo_echo_Params) InBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Echo_echo_Params) SetIn(v string) error {
@@ -4811,8 +5413,13 @@ func (s Echo_echo_Results) String() string {
 }
 
 func (s Echo_echo_Results) Out() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadOut is like Out, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Echo_echo_Results) ReadOut() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Echo_echo_Results) HasOut() bool {
@@ -4821,8 +5428,7 @@ func (s Echo_echo_Results) HasOut() bool {
 }
 
 func (s Echo_echo_Results) OutBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Echo_echo_Results) SetOut(v string) error {
@@ -4889,6 +5495,12 @@ func (s Hoth) Base() (EchoBase, error) {
 	return EchoBase{Struct: p.Struct()}, err
 }
 
+// ReadBase is like Base, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Hoth) ReadBase() EchoBase {
+	return EchoBase{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Hoth) HasBase() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -5043,6 +5655,12 @@ func (s EchoBases) Bases() (EchoBase_List, error) {
 	return EchoBase_List{List: p.List()}, err
 }
 
+// ReadBases is like Bases, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s EchoBases) ReadBases() EchoBase_List {
+	return EchoBase_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s EchoBases) HasBases() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -5119,6 +5737,12 @@ func (s StackingRoot) A() (StackingA, error) {
 	return StackingA{Struct: p.Struct()}, err
 }
 
+// ReadA is like A, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s StackingRoot) ReadA() StackingA {
+	return StackingA{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s StackingRoot) HasA() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -5199,7 +5823,7 @@ func (p StackingRoot_Promise) A() StackingA_Promise {
 }
 
 func (p StackingRoot_Promise) AWithDefault() StackingA_Promise {
-	return StackingA_Promise{Pipeline: p.Pipeline.GetPipelineDefault(0, x_832bcc6686a26d56[96:128])}
+	return StackingA_Promise{Pipeline: p.Pipeline.GetPipelineDefault(0, x_832bcc6686a26d56[64:96])}
 }
 
 type StackingA struct{ capnp.Struct }
@@ -5240,6 +5864,12 @@ func (s StackingA) B() (StackingB, error) {
 	return StackingB{Struct: p.Struct()}, err
 }
 
+// ReadB is like B, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s StackingA) ReadB() StackingB {
+	return StackingB{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s StackingA) HasB() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -5379,6 +6009,24 @@ type CallSequence_Server interface {
 	GetNumber(CallSequence_getNumber) error
 }
 
+// UnimplementedCallSequence_Server can be embedded in an implementation
+// of CallSequence_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedCallSequence_Server struct{}
+
+func (UnimplementedCallSequence_Server) GetNumber(CallSequence_getNumber) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xabaedf5f7817c820,
+			MethodID:      0,
+			InterfaceName: "aircraft.capnp:CallSequence",
+			MethodName:    "getNumber",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func CallSequence_ServerToClient(s CallSequence_Server) CallSequence {
 	c, _ := s.(server.Closer)
 	return CallSequence{Client: server.New(CallSequence_Methods(nil, s), c)}
@@ -5414,6 +6062,32 @@ type CallSequence_getNumber struct {
 	Results CallSequence_getNumber_Results
 }
 
+// TailCall delegates the call to getNumber on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c CallSequence_getNumber) TailCall(t CallSequence, params func(CallSequence_getNumber_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xabaedf5f7817c820,
+			MethodID:      0,
+			InterfaceName: "aircraft.capnp:CallSequence",
+			MethodName:    "getNumber",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(CallSequence_getNumber_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type CallSequence_getNumber_Params struct{ capnp.Struct }
 
 // CallSequence_getNumber_Params_TypeID is the unique identifier for the type CallSequence_getNumber_Params.
@@ -5562,6 +6236,12 @@ func (s Defaults) Text() (string, error) {
 	return p.TextDefault("foo"), err
 }
 
+// ReadText is like Text, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Defaults) ReadText() string {
+	return s.Struct.ReadPtr(0).TextDefault("foo")
+}
+
 func (s Defaults) HasText() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -5581,6 +6261,12 @@ func (s Defaults) Data() ([]byte, error) {
 	return []byte(p.DataDefault([]byte{0x62, 0x61, 0x72})), err
 }
 
+// ReadData is like Data, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Defaults) ReadData() []byte {
+	return []byte(s.Struct.ReadPtr(1).DataDefault([]byte{0x62, 0x61, 0x72}))
+}
+
 func (s Defaults) HasData() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -5669,8 +6355,13 @@ func (s BenchmarkA) String() string {
 }
 
 func (s BenchmarkA) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s BenchmarkA) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s BenchmarkA) HasName() bool {
@@ -5679,8 +6370,7 @@ func (s BenchmarkA) HasName() bool {
 }
 
 func (s BenchmarkA) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s BenchmarkA) SetName(v string) error {
@@ -5696,8 +6386,13 @@ func (s BenchmarkA) SetBirthDay(v int64) {
 }
 
 func (s BenchmarkA) Phone() (string, error) {
-	p, err := s.Struct.Ptr(1)
-	return p.Text(), err
+	return s.Struct.Text(1)
+}
+
+// ReadPhone is like Phone, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s BenchmarkA) ReadPhone() string {
+	return s.Struct.ReadText(1)
 }
 
 func (s BenchmarkA) HasPhone() bool {
@@ -5706,8 +6401,7 @@ func (s BenchmarkA) HasPhone() bool {
 }
 
 func (s BenchmarkA) PhoneBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(1)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(1)
 }
 
 func (s BenchmarkA) SetPhone(v string) error {
@@ -5794,6 +6488,12 @@ func (s AllocBenchmark) Fields() (AllocBenchmark_Field_List, error) {
 	return AllocBenchmark_Field_List{List: p.List()}, err
 }
 
+// ReadFields is like Fields, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s AllocBenchmark) ReadFields() AllocBenchmark_Field_List {
+	return AllocBenchmark_Field_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s AllocBenchmark) HasFields() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -5866,8 +6566,13 @@ func (s AllocBenchmark_Field) String() string {
 }
 
 func (s AllocBenchmark_Field) StringValue() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadStringValue is like StringValue, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s AllocBenchmark_Field) ReadStringValue() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s AllocBenchmark_Field) HasStringValue() bool {
@@ -5876,8 +6581,7 @@ func (s AllocBenchmark_Field) HasStringValue() bool {
 }
 
 func (s AllocBenchmark_Field) StringValueBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s AllocBenchmark_Field) SetStringValue(v string) error {
@@ -6269,8 +6973,4 @@ var x_832bcc6686a26d56 = []byte{
 	0, 0, 0, 0, 1, 0, 1, 0,
 	42, 0, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 3, 0, 0, 0,
-	0, 0, 0, 0, 1, 0, 1, 0,
-	42, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0,
 }
@@ -34,8 +34,13 @@ func (s Book) String() string {
 }
 
 func (s Book) Title() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadTitle is like Title, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Book) ReadTitle() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Book) HasTitle() bool {
@@ -44,8 +49,7 @@ func (s Book) HasTitle() bool {
 }
 
 func (s Book) TitleBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Book) SetTitle(v string) error {
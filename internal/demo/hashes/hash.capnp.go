@@ -40,6 +40,24 @@ type HashFactory_Server interface {
 	NewSha1(HashFactory_newSha1) error
 }
 
+// UnimplementedHashFactory_Server can be embedded in an implementation
+// of HashFactory_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedHashFactory_Server struct{}
+
+func (UnimplementedHashFactory_Server) NewSha1(HashFactory_newSha1) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xaead580f97fddabc,
+			MethodID:      0,
+			InterfaceName: "hash.capnp:HashFactory",
+			MethodName:    "newSha1",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func HashFactory_ServerToClient(s HashFactory_Server) HashFactory {
 	c, _ := s.(server.Closer)
 	return HashFactory{Client: server.New(HashFactory_Methods(nil, s), c)}
@@ -75,6 +93,32 @@ type HashFactory_newSha1 struct {
 	Results HashFactory_newSha1_Results
 }
 
+// TailCall delegates the call to newSha1 on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c HashFactory_newSha1) TailCall(t HashFactory, params func(HashFactory_newSha1_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xaead580f97fddabc,
+			MethodID:      0,
+			InterfaceName: "hash.capnp:HashFactory",
+			MethodName:    "newSha1",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(HashFactory_newSha1_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type HashFactory_newSha1_Params struct{ capnp.Struct }
 
 // HashFactory_newSha1_Params_TypeID is the unique identifier for the type HashFactory_newSha1_Params.
@@ -260,6 +304,36 @@ type Hash_Server interface {
 	Sum(Hash_sum) error
 }
 
+// UnimplementedHash_Server can be embedded in an implementation
+// of Hash_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedHash_Server struct{}
+
+func (UnimplementedHash_Server) Write(Hash_write) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xf29f97dd675a9431,
+			MethodID:      0,
+			InterfaceName: "hash.capnp:Hash",
+			MethodName:    "write",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
+func (UnimplementedHash_Server) Sum(Hash_sum) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xf29f97dd675a9431,
+			MethodID:      1,
+			InterfaceName: "hash.capnp:Hash",
+			MethodName:    "sum",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func Hash_ServerToClient(s Hash_Server) Hash {
 	c, _ := s.(server.Closer)
 	return Hash{Client: server.New(Hash_Methods(nil, s), c)}
@@ -309,6 +383,32 @@ type Hash_write struct {
 	Results Hash_write_Results
 }
 
+// TailCall delegates the call to write on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Hash_write) TailCall(t Hash, params func(Hash_write_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf29f97dd675a9431,
+			MethodID:      0,
+			InterfaceName: "hash.capnp:Hash",
+			MethodName:    "write",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Hash_write_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 // Hash_sum holds the arguments for a server call to Hash.sum.
 type Hash_sum struct {
 	Ctx     context.Context
@@ -317,6 +417,32 @@ type Hash_sum struct {
 	Results Hash_sum_Results
 }
 
+// TailCall delegates the call to sum on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Hash_sum) TailCall(t Hash, params func(Hash_sum_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf29f97dd675a9431,
+			MethodID:      1,
+			InterfaceName: "hash.capnp:Hash",
+			MethodName:    "sum",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Hash_sum_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type Hash_write_Params struct{ capnp.Struct }
 
 // Hash_write_Params_TypeID is the unique identifier for the type Hash_write_Params.
@@ -347,6 +473,12 @@ func (s Hash_write_Params) Data() ([]byte, error) {
 	return []byte(p.Data()), err
 }
 
+// ReadData is like Data, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Hash_write_Params) ReadData() []byte {
+	return []byte(s.Struct.ReadData(0))
+}
+
 func (s Hash_write_Params) HasData() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -524,6 +656,12 @@ func (s Hash_sum_Results) Hash() ([]byte, error) {
 	return []byte(p.Data()), err
 }
 
+// ReadHash is like Hash, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Hash_sum_Results) ReadHash() []byte {
+	return []byte(s.Struct.ReadData(0))
+}
+
 func (s Hash_sum_Results) HasHash() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
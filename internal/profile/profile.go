@@ -0,0 +1,32 @@
+// Package profile attributes the work done for Cap'n Proto calls to
+// their methods in CPU profiles.
+package profile // import "zombiezen.com/go/capnproto2/internal/profile"
+
+import (
+	"strconv"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// Profiler label keys.  Work done under Do carries both labels, so a
+// CPU profile can be broken down with, for example:
+//
+//	go tool pprof -tagfocus=capnp.method=getUser profile.out
+const (
+	InterfaceKey = "capnp.interface"
+	MethodKey    = "capnp.method"
+)
+
+// labelValues returns the interface and method label values for m,
+// falling back to the IDs if the names are not known.
+func labelValues(m *capnp.Method) (iface, method string) {
+	iface = m.InterfaceName
+	if iface == "" {
+		iface = "@0x" + strconv.FormatUint(m.InterfaceID, 16)
+	}
+	method = m.MethodName
+	if method == "" {
+		method = "@" + strconv.FormatUint(uint64(m.MethodID), 10)
+	}
+	return iface, method
+}
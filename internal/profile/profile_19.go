@@ -0,0 +1,21 @@
+// +build go1.9
+
+package profile
+
+import (
+	stdcontext "context"
+	"runtime/pprof"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+)
+
+// Do calls f with a context that carries profiler labels for m.  The
+// labels apply to the current goroutine while f runs, and to any
+// goroutines that it starts.
+func Do(ctx context.Context, m *capnp.Method, f func(context.Context)) {
+	iface, method := labelValues(m)
+	pprof.Do(ctx, pprof.Labels(InterfaceKey, iface, MethodKey, method), func(ctx stdcontext.Context) {
+		f(ctx)
+	})
+}
@@ -0,0 +1,13 @@
+// +build !go1.9
+
+package profile
+
+import (
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+)
+
+// Do calls f with ctx.  Profiler labels require Go 1.9.
+func Do(ctx context.Context, m *capnp.Method, f func(context.Context)) {
+	f(ctx)
+}
@@ -0,0 +1,44 @@
+// +build go1.9
+
+package profile
+
+import (
+	"runtime/pprof"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		m             capnp.Method
+		iface, method string
+	}{
+		{
+			m:      capnp.Method{InterfaceID: 0xa7317bd7216570aa, MethodID: 3, InterfaceName: "Echo", MethodName: "echo"},
+			iface:  "Echo",
+			method: "echo",
+		},
+		{
+			m:      capnp.Method{InterfaceID: 0xa7317bd7216570aa, MethodID: 3},
+			iface:  "@0xa7317bd7216570aa",
+			method: "@3",
+		},
+	}
+	for _, test := range tests {
+		called := false
+		Do(context.Background(), &test.m, func(ctx context.Context) {
+			called = true
+			if v, _ := pprof.Label(ctx, InterfaceKey); v != test.iface {
+				t.Errorf("Do(%v): %s = %q; want %q", &test.m, InterfaceKey, v, test.iface)
+			}
+			if v, _ := pprof.Label(ctx, MethodKey); v != test.method {
+				t.Errorf("Do(%v): %s = %q; want %q", &test.m, MethodKey, v, test.method)
+			}
+		})
+		if !called {
+			t.Errorf("Do(%v) did not call f", &test.m)
+		}
+	}
+}
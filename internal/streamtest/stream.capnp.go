@@ -0,0 +1,190 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package streamtest
+
+import (
+	context "golang.org/x/net/context"
+	capnp "zombiezen.com/go/capnproto2"
+	text "zombiezen.com/go/capnproto2/encoding/text"
+	schemas "zombiezen.com/go/capnproto2/schemas"
+	server "zombiezen.com/go/capnproto2/server"
+)
+
+type Writer struct{ Client capnp.Client }
+
+// Writer_TypeID is the unique identifier for the type Writer.
+const Writer_TypeID = 0xd9e2f1a0b3c4d5e6
+
+// Write is a streaming method: see capnp.StreamCall.
+func (c Writer) Write(ctx context.Context, params func(Writer_write_Params) error, opts ...capnp.CallOption) error {
+	if c.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xd9e2f1a0b3c4d5e6,
+			MethodID:      0,
+			InterfaceName: "stream.capnp:Writer",
+			MethodName:    "write",
+		},
+		Options: capnp.NewCallOptions(opts),
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Writer_write_Params{Struct: s}) }
+	}
+	return capnp.StreamCall(c.Client, call)
+}
+
+type Writer_Server interface {
+	Write(Writer_write) error
+}
+
+// UnimplementedWriter_Server can be embedded in an implementation
+// of Writer_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedWriter_Server struct{}
+
+func (UnimplementedWriter_Server) Write(Writer_write) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xd9e2f1a0b3c4d5e6,
+			MethodID:      0,
+			InterfaceName: "stream.capnp:Writer",
+			MethodName:    "write",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
+func Writer_ServerToClient(s Writer_Server) Writer {
+	c, _ := s.(server.Closer)
+	return Writer{Client: server.New(Writer_Methods(nil, s), c)}
+}
+
+func Writer_Methods(methods []server.Method, s Writer_Server) []server.Method {
+	if cap(methods) == 0 {
+		methods = make([]server.Method, 0, 1)
+	}
+
+	methods = append(methods, server.Method{
+		Method: capnp.Method{
+			InterfaceID:   0xd9e2f1a0b3c4d5e6,
+			MethodID:      0,
+			InterfaceName: "stream.capnp:Writer",
+			MethodName:    "write",
+		},
+		Impl: func(c context.Context, opts capnp.CallOptions, p, r capnp.Struct) error {
+			call := Writer_write{c, opts, Writer_write_Params{Struct: p}, r}
+			return s.Write(call)
+		},
+		ResultsSize: capnp.ObjectSize{DataSize: 0, PointerCount: 0},
+	})
+
+	return methods
+}
+
+// Writer_write holds the arguments for a server call to Writer.write.
+type Writer_write struct {
+	Ctx     context.Context
+	Options capnp.CallOptions
+	Params  Writer_write_Params
+	Results capnp.Struct // streaming methods have no results
+}
+
+type Writer_write_Params struct{ capnp.Struct }
+
+// Writer_write_Params_TypeID is the unique identifier for the type Writer_write_Params.
+const Writer_write_Params_TypeID = 0xe1f2a3b4c5d6e7f8
+
+func NewWriter_write_Params(s *capnp.Segment) (Writer_write_Params, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Writer_write_Params{st}, err
+}
+
+func NewRootWriter_write_Params(s *capnp.Segment) (Writer_write_Params, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
+	return Writer_write_Params{st}, err
+}
+
+func ReadRootWriter_write_Params(msg *capnp.Message) (Writer_write_Params, error) {
+	root, err := msg.RootPtr()
+	return Writer_write_Params{root.Struct()}, err
+}
+
+func (s Writer_write_Params) String() string {
+	str, _ := text.Marshal(0xe1f2a3b4c5d6e7f8, s.Struct)
+	return str
+}
+
+func (s Writer_write_Params) Chunk() ([]byte, error) {
+	p, err := s.Struct.Ptr(0)
+	return []byte(p.Data()), err
+}
+
+// ReadChunk is like Chunk, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Writer_write_Params) ReadChunk() []byte {
+	return []byte(s.Struct.ReadData(0))
+}
+
+func (s Writer_write_Params) HasChunk() bool {
+	p, err := s.Struct.Ptr(0)
+	return p.IsValid() || err != nil
+}
+
+func (s Writer_write_Params) SetChunk(v []byte) error {
+	return s.Struct.SetData(0, v)
+}
+
+// Writer_write_Params_List is a list of Writer_write_Params.
+type Writer_write_Params_List struct{ capnp.List }
+
+// NewWriter_write_Params creates a new list of Writer_write_Params.
+func NewWriter_write_Params_List(s *capnp.Segment, sz int32) (Writer_write_Params_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
+	return Writer_write_Params_List{l}, err
+}
+
+func (s Writer_write_Params_List) At(i int) Writer_write_Params {
+	return Writer_write_Params{s.List.Struct(i)}
+}
+
+func (s Writer_write_Params_List) Set(i int, v Writer_write_Params) error {
+	return s.List.SetStruct(i, v.Struct)
+}
+
+func (s Writer_write_Params_List) String() string {
+	str, _ := text.MarshalList(0xe1f2a3b4c5d6e7f8, s.List)
+	return str
+}
+
+// Writer_write_Params_Promise is a wrapper for a Writer_write_Params promised by a client call.
+type Writer_write_Params_Promise struct{ *capnp.Pipeline }
+
+func (p Writer_write_Params_Promise) Struct() (Writer_write_Params, error) {
+	s, err := p.Pipeline.Struct()
+	return Writer_write_Params{s}, err
+}
+
+const schema_c4d1e6a3b2f50987 = "x\xda\x12\xb0q`\x12d\xdd\xce\xc0\x10\xc8\xc1\xca\xf6" +
+	"\xff\xd9\xd5#\x9b\x17||t\x93A\x90\x97\xf9\x7f;" +
+	"\xe7\xd7M\x8b\x9f]<\xc2\xc0\xc0(\xe8\xb8\x08D\xb8" +
+	"30\xfe\xff\xf1\xfc\xda\xd1-\x8b?=d\x10\x14a" +
+	"d``edg`0\xf4\xe4bd`\x14\x0c\xb4" +
+	"g`\xfc_\\R\x94\x9a\x98\xab\x97\xcc\x98X\x90W" +
+	"`\x15^\x94\xc9^\x92Z\x14\xc8\xc2\xcc\xca\xc0\x00\xd7" +
+	"\xca\x98\xb7\xf1@\xb9\xf1\xac\xf8\x99\x82\x82F\x0c\xcc\xf2" +
+	"\xe5E\x99%\xa9p\x8d\xcc0\x8d%\xa9Ez`)" +
+	"\x95\x80\xc4\xa2\xc4\xdcb\x06\x86@\x16f\x16\x06\x06\x16" +
+	"F\x06\x06A^#\x90\x8b\x99\x19\x03E\x98\x18\xe5\x93" +
+	"3J\xf3\xb2\x19y\x19\x98\x18y\x19\x18\x01\x03\x00\x86" +
+	"\x0fB\xba"
+
+func init() {
+	schemas.Register(schema_c4d1e6a3b2f50987,
+		0xd9e2f1a0b3c4d5e6,
+		0xe1f2a3b4c5d6e7f8)
+}
@@ -1,6 +1,7 @@
 package capnp
 
 import (
+	"encoding/binary"
 	"errors"
 	"math"
 	"strconv"
@@ -196,6 +197,31 @@ func (p List) Len() int {
 	return int(p.length)
 }
 
+// RawData returns the list's elements as they are stored in the message,
+// without copying: little-endian values packed end to end, or packed
+// bits for a bit list.  This lets a numeric list be handed to code that
+// works on byte buffers or be written out directly.  Writes to the
+// returned slice modify the message, so it must not be written if the
+// message is frozen.  RawData returns nil for a list of structs or
+// pointers, since its elements are not plain values; use At instead.
+func (p List) RawData() []byte {
+	if p.seg == nil || p.flags&isCompositeList != 0 || p.size.PointerCount > 0 {
+		return nil
+	}
+	var sz Size
+	if p.flags&isBitList != 0 {
+		sz = Size((int64(p.length) + 7) / 8)
+	} else {
+		var ok bool
+		sz, ok = p.size.DataSize.times(p.length)
+		if !ok {
+			return nil
+		}
+	}
+	end := p.off + Address(sz)
+	return p.seg.data[p.off:end:end]
+}
+
 // primitiveElem returns the address of the segment data for a list element.
 // Calling this on a bit list returns an error.
 func (p List) primitiveElem(i int, expectedSize ObjectSize) (Address, error) {
@@ -352,6 +378,18 @@ func (p PointerList) PtrAt(i int) (Ptr, error) {
 	return p.seg.readPtr(addr, p.depthLimit)
 }
 
+// ReadPtrAt returns the i'th pointer in the list.  If the pointer can't
+// be read, ReadPtrAt returns a null pointer and records the error on the
+// message, to be reported later by Message.Err.
+func (p PointerList) ReadPtrAt(i int) Ptr {
+	pp, err := p.PtrAt(i)
+	if err != nil {
+		p.seg.msg.recordErr(err)
+		return Ptr{}
+	}
+	return pp
+}
+
 // Set sets the i'th pointer in the list to v.
 //
 // Deprecated: Use SetPtr.
@@ -368,6 +406,84 @@ func (p PointerList) SetPtr(i int, v Ptr) error {
 	return p.seg.writePtr(addr, v, false)
 }
 
+// NewStruct allocates a struct in p's segment and sets the i'th pointer
+// in the list to it.  It is useful for filling in a List(AnyPointer).
+func (p PointerList) NewStruct(i int, sz ObjectSize) (Struct, error) {
+	addr, err := p.primitiveElem(i, ObjectSize{PointerCount: 1})
+	if err != nil {
+		return Struct{}, err
+	}
+	s, err := NewStruct(p.seg, sz)
+	if err != nil {
+		return Struct{}, err
+	}
+	return s, p.seg.writePtr(addr, s.ToPtr(), false)
+}
+
+// NewPrimitiveList allocates a list of n elements of sz bytes each in
+// p's segment and sets the i'th pointer in the list to it.  sz must be
+// 0, 1, 2, 4, or 8; wrap the result in the matching typed list, like
+// UInt32List{List: l}.  For a list of bits, use NewBitList and SetPtr.
+func (p PointerList) NewPrimitiveList(i int, sz Size, n int32) (List, error) {
+	switch sz {
+	case 0, 1, 2, 4, 8:
+	default:
+		return List{}, errObjectSize
+	}
+	addr, err := p.primitiveElem(i, ObjectSize{PointerCount: 1})
+	if err != nil {
+		return List{}, err
+	}
+	l, err := newPrimitiveList(p.seg, sz, n)
+	if err != nil {
+		return List{}, err
+	}
+	return l, p.seg.writePtr(addr, l.ToPtr(), false)
+}
+
+// NewCompositeList allocates a list of n structs in p's segment and
+// sets the i'th pointer in the list to it.
+func (p PointerList) NewCompositeList(i int, sz ObjectSize, n int32) (List, error) {
+	addr, err := p.primitiveElem(i, ObjectSize{PointerCount: 1})
+	if err != nil {
+		return List{}, err
+	}
+	l, err := NewCompositeList(p.seg, sz, n)
+	if err != nil {
+		return List{}, err
+	}
+	return l, p.seg.writePtr(addr, l.ToPtr(), false)
+}
+
+// NewPointerList allocates a list of n pointers in p's segment and sets
+// the i'th pointer in the list to it.  This builds a List(List(T)) or
+// a List(List(AnyPointer)) one level at a time.
+func (p PointerList) NewPointerList(i int, n int32) (PointerList, error) {
+	addr, err := p.primitiveElem(i, ObjectSize{PointerCount: 1})
+	if err != nil {
+		return PointerList{}, err
+	}
+	l, err := NewPointerList(p.seg, n)
+	if err != nil {
+		return PointerList{}, err
+	}
+	return l, p.seg.writePtr(addr, l.ToPtr(), false)
+}
+
+// NewTextList allocates a list of n text pointers in p's segment and
+// sets the i'th pointer in the list to it.
+func (p PointerList) NewTextList(i int, n int32) (TextList, error) {
+	l, err := p.NewPointerList(i, n)
+	return TextList{l.List}, err
+}
+
+// NewDataList allocates a list of n data pointers in p's segment and
+// sets the i'th pointer in the list to it.
+func (p PointerList) NewDataList(i int, n int32) (DataList, error) {
+	l, err := p.NewPointerList(i, n)
+	return DataList{l.List}, err
+}
+
 // TextList is an array of pointers to strings.
 type TextList struct{ List }
 
@@ -380,7 +496,8 @@ func NewTextList(s *Segment, n int32) (TextList, error) {
 	return TextList{pl.List}, nil
 }
 
-// At returns the i'th string in the list.
+// At returns the i'th string in the list.  If the message's
+// ValidateText field is set, malformed text is reported as a *TextError.
 func (l TextList) At(i int) (string, error) {
 	addr, err := l.primitiveElem(i, ObjectSize{PointerCount: 1})
 	if err != nil {
@@ -390,7 +507,19 @@ func (l TextList) At(i int) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return p.Text(), nil
+	b, err := p.checkedText()
+	return string(b), err
+}
+
+// ReadAt returns the i'th string in the list.  Errors are recorded on
+// the message like PointerList.ReadPtrAt.
+func (l TextList) ReadAt(i int) string {
+	s, err := l.At(i)
+	if err != nil {
+		l.seg.msg.recordErr(err)
+		return ""
+	}
+	return s
 }
 
 // BytesAt returns the i'th element in the list as a byte slice.
@@ -404,7 +533,7 @@ func (l TextList) BytesAt(i int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return p.TextBytes(), nil
+	return p.checkedText()
 }
 
 // Set sets the i'th string in the list to v.
@@ -467,6 +596,17 @@ func (l DataList) At(i int) ([]byte, error) {
 	return p.Data(), nil
 }
 
+// ReadAt returns the i'th data in the list.  Errors are recorded on the
+// message like PointerList.ReadPtrAt.
+func (l DataList) ReadAt(i int) []byte {
+	b, err := l.At(i)
+	if err != nil {
+		l.seg.msg.recordErr(err)
+		return nil
+	}
+	return b
+}
+
 // Set sets the i'th data in the list to v.
 func (l DataList) Set(i int, v []byte) error {
 	addr, err := l.primitiveElem(i, ObjectSize{PointerCount: 1})
@@ -712,6 +852,44 @@ func (l UInt16List) Set(i int, v uint16) {
 	l.seg.writeUint16(addr, v)
 }
 
+// ToSlice returns a copy of the list's elements.  It reads the list in
+// one pass instead of checking each element like At.  It returns an
+// error if the list's elements are too small to hold a uint16, in which
+// case SetSlice would fail too.  ToSlice on a null list returns nil.
+func (l UInt16List) ToSlice() ([]uint16, error) {
+	if l.Len() == 0 {
+		return nil, nil
+	}
+	if _, _, ok := l.column(0, 2); !ok {
+		return nil, errElementSize
+	}
+	return l.Uint16Column(0), nil
+}
+
+// SetSlice sets the list's elements to v, which must be the same length
+// as the list.  It checks the list's element size once up front instead
+// of for each element like Set.
+func (l UInt16List) SetSlice(v []uint16) error {
+	if len(v) != l.Len() {
+		return errSliceLen
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	base, stride, ok := l.column(0, 2)
+	if !ok {
+		return errElementSize
+	}
+	l.seg.checkWritable()
+	data := l.seg.data
+	a := base
+	for _, x := range v {
+		binary.LittleEndian.PutUint16(data[a:], x)
+		a += Address(stride)
+	}
+	return nil
+}
+
 // String returns the list in Cap'n Proto schema format (e.g. "[1, 2, 3]").
 func (l UInt16List) String() string {
 	var buf []byte
@@ -1041,4 +1219,7 @@ const (
 	isBitList
 )
 
-var errBitListStruct = errors.New("capnp: SetStruct called on bit list")
+var (
+	errBitListStruct = errors.New("capnp: SetStruct called on bit list")
+	errSliceLen      = errors.New("capnp: slice length does not match list length")
+)
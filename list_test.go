@@ -2,6 +2,9 @@ package capnp
 
 import (
 	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
 	"testing"
 )
 
@@ -79,6 +82,176 @@ func TestTextListBytesAt(t *testing.T) {
 	}
 }
 
+func TestPointerListNew(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err := NewPointerList(seg, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.SetRootPtr(outer.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	st, err := outer.NewStruct(0, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal("NewStruct:", err)
+	}
+	st.SetUint64(0, 42)
+	l, err := outer.NewPrimitiveList(1, 4, 2)
+	if err != nil {
+		t.Fatal("NewPrimitiveList:", err)
+	}
+	UInt32List{List: l}.Set(1, 7)
+	cl, err := outer.NewCompositeList(2, ObjectSize{DataSize: 8}, 1)
+	if err != nil {
+		t.Fatal("NewCompositeList:", err)
+	}
+	cl.Struct(0).SetUint64(0, 99)
+	inner, err := outer.NewPointerList(3, 1)
+	if err != nil {
+		t.Fatal("NewPointerList:", err)
+	}
+	tl, err := inner.NewTextList(0, 1)
+	if err != nil {
+		t.Fatal("NewTextList:", err)
+	}
+	if err := tl.Set(0, "deep"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outer.NewPrimitiveList(4, 3, 1); err == nil {
+		t.Error("NewPrimitiveList with 3-byte elements succeeded")
+	}
+
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer = PointerList{List: root.List()}
+	if p := outer.ReadPtrAt(0); p.Struct().Uint64(0) != 42 {
+		t.Errorf("outer[0].Uint64(0) = %d; want 42", p.Struct().Uint64(0))
+	}
+	if p := outer.ReadPtrAt(1); (UInt32List{List: p.List()}).At(1) != 7 {
+		t.Errorf("outer[1][1] = %d; want 7", UInt32List{List: p.List()}.At(1))
+	}
+	if p := outer.ReadPtrAt(2); p.List().Struct(0).Uint64(0) != 99 {
+		t.Errorf("outer[2][0].Uint64(0) = %d; want 99", p.List().Struct(0).Uint64(0))
+	}
+	inner = PointerList{List: outer.ReadPtrAt(3).List()}
+	tl = TextList{List: inner.ReadPtrAt(0).List()}
+	if s, err := tl.At(0); err != nil || s != "deep" {
+		t.Errorf("outer[3][0][0] = %q, %v; want \"deep\", <nil>", s, err)
+	}
+	if p := outer.ReadPtrAt(4); p.IsValid() {
+		t.Error("outer[4] is set after failed NewPrimitiveList")
+	}
+}
+
+func TestUInt16ListSlice(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewUInt16List(seg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetSlice([]uint16{1, 2, 3}); err != nil {
+		t.Fatal("SetSlice:", err)
+	}
+	if got, err := l.ToSlice(); err != nil || !reflect.DeepEqual(got, []uint16{1, 2, 3}) {
+		t.Errorf("ToSlice() = %v, %v; want [1 2 3], <nil>", got, err)
+	}
+	if err := l.VerifyEnum("E", 4); err != nil {
+		t.Error("VerifyEnum(4):", err)
+	}
+	if err := l.VerifyEnum("E", 3); err == nil {
+		t.Error("VerifyEnum(3) succeeded")
+	}
+
+	// A list of structs can stand in for a list of uint16s.
+	cl, err := NewCompositeList(seg, ObjectSize{DataSize: 8, PointerCount: 1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ul := UInt16List{List: cl}
+	if err := ul.SetSlice([]uint16{7, 8}); err != nil {
+		t.Fatal("SetSlice on composite list:", err)
+	}
+	if got, err := ul.ToSlice(); err != nil || !reflect.DeepEqual(got, []uint16{7, 8}) || cl.Struct(1).Uint16(0) != 8 {
+		t.Errorf("after SetSlice on composite list, ToSlice() = %v, %v; want [7 8], <nil>", got, err)
+	}
+
+	bl, err := NewUInt8List(seg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (UInt16List{List: bl.List}).SetSlice([]uint16{1}); err == nil {
+		t.Error("SetSlice on byte list succeeded")
+	}
+	if got, err := (UInt16List{List: bl.List}).ToSlice(); err == nil {
+		t.Errorf("ToSlice on byte list = %v, <nil>; want error", got)
+	}
+	if got, err := (UInt16List{}).ToSlice(); got != nil || err != nil {
+		t.Errorf("ToSlice on null list = %v, %v; want [], <nil>", got, err)
+	}
+}
+
+func TestListRawData(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewFloat64List(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Set(0, 1.5)
+	l.Set(1, -2)
+	raw := l.RawData()
+	if len(raw) != 16 || math.Float64frombits(binary.LittleEndian.Uint64(raw[8:])) != -2 {
+		t.Errorf("RawData() = %v; want encoding of [1.5, -2]", raw)
+	}
+	if v := l.View(); v != nil {
+		if len(v) != 2 || v[0] != 1.5 || v[1] != -2 {
+			t.Errorf("View() = %v; want [1.5 -2]", v)
+		}
+		v[0] = 3
+		if l.At(0) != 3 {
+			t.Errorf("after writing to View, At(0) = %v; want 3", l.At(0))
+		}
+	}
+
+	bl, err := NewBitList(seg, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bl.Set(8, true)
+	if raw := bl.RawData(); !bytes.Equal(raw, []byte{0, 1}) {
+		t.Errorf("bit list RawData() = %v; want [0 1]", raw)
+	}
+
+	cl, err := NewCompositeList(seg, ObjectSize{DataSize: 8}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw := cl.RawData(); raw != nil {
+		t.Errorf("composite list RawData() = %v; want nil", raw)
+	}
+	if v := (Float64List{List: cl}).View(); v != nil {
+		t.Errorf("composite list View() = %v; want nil", v)
+	}
+}
+
 func TestListRaw(t *testing.T) {
 	_, seg, err := NewMessage(SingleSegment(nil))
 	if err != nil {
@@ -128,3 +301,70 @@ func TestListRaw(t *testing.T) {
 		}
 	}
 }
+
+func TestListColumns(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewCompositeList(seg, ObjectSize{DataSize: 16, PointerCount: 1}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < l.Len(); i++ {
+		s := l.Struct(i)
+		s.SetUint64(0, uint64(i)<<40|7)
+		s.SetUint32(8, math.Float32bits(float32(i)+0.5))
+		s.SetUint16(12, uint16(i*100))
+		s.SetUint8(14, uint8(i+1))
+	}
+
+	if got, want := l.Uint64Column(0), []uint64{7, 1<<40 | 7, 2<<40 | 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Uint64Column(0) = %v; want %v", got, want)
+	}
+	if got, want := l.Float32Column(8), []float32{0.5, 1.5, 2.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Float32Column(8) = %v; want %v", got, want)
+	}
+	if got, want := l.Uint16Column(12), []uint16{0, 100, 200}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Uint16Column(12) = %v; want %v", got, want)
+	}
+	if got, want := l.AppendUint8Column([]uint8{42}, 14), []uint8{42, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AppendUint8Column([42], 14) = %v; want %v", got, want)
+	}
+	// Past the end of the data section reads as zero, like Struct.Uint64.
+	if got, want := l.Uint64Column(16), []uint64{0, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Uint64Column(16) = %v; want %v", got, want)
+	}
+	if got := (List{}).Float64Column(0); len(got) != 0 {
+		t.Errorf("List{}.Float64Column(0) = %v; want []", got)
+	}
+
+	var seen []uint32
+	l.RangeUint32Column(8, func(i int, v uint32) bool {
+		if i != len(seen) {
+			t.Errorf("RangeUint32Column called with i = %d; want %d", i, len(seen))
+		}
+		seen = append(seen, v)
+		return i < 1
+	})
+	if want := []uint32{math.Float32bits(0.5), math.Float32bits(1.5)}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("RangeUint32Column(8) visited %v; want %v", seen, want)
+	}
+}
+
+func BenchmarkUint64Column(b *testing.B) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	l, err := NewCompositeList(seg, ObjectSize{DataSize: 24, PointerCount: 2}, 4096)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]uint64, 0, l.Len())
+	b.SetBytes(int64(l.Len()) * 8)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = l.AppendUint64Column(buf[:0], 8)
+	}
+}
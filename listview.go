@@ -0,0 +1,121 @@
+// +build go1.17
+// +build 386 amd64 arm64 ppc64le
+
+package capnp
+
+import "unsafe"
+
+// The View methods return a Go slice that shares memory with a list,
+// so that numeric data can be passed to routines that operate on
+// slices without copying each element.  A view is only possible on
+// little-endian architectures, where the message's encoding matches
+// Go's, and when the list's data is aligned for the element type.
+// Otherwise View returns nil and callers must fall back to At or
+// RawData.  Like RawData, writing to a view modifies the message.
+
+// view returns a pointer to the list's data if it can be viewed as a
+// slice of elements of size sz.
+func (p List) view(sz Size) unsafe.Pointer {
+	if p.size != (ObjectSize{DataSize: sz}) || p.flags&(isCompositeList|isBitList) != 0 {
+		return nil
+	}
+	b := p.RawData()
+	if len(b) == 0 {
+		return nil
+	}
+	ptr := unsafe.Pointer(&b[0])
+	if uintptr(ptr)%uintptr(sz) != 0 {
+		return nil
+	}
+	return ptr
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l Int8List) View() []int8 {
+	ptr := l.view(1)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*int8)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l UInt16List) View() []uint16 {
+	ptr := l.view(2)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*uint16)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l Int16List) View() []int16 {
+	ptr := l.view(2)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*int16)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l UInt32List) View() []uint32 {
+	ptr := l.view(4)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*uint32)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l Int32List) View() []int32 {
+	ptr := l.view(4)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*int32)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l UInt64List) View() []uint64 {
+	ptr := l.view(8)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*uint64)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l Int64List) View() []int64 {
+	ptr := l.view(8)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*int64)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l Float32List) View() []float32 {
+	ptr := l.view(4)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*float32)(ptr), l.Len())
+}
+
+// View returns the list's elements as a slice that shares memory with
+// the message, or nil if that is not possible.
+func (l Float64List) View() []float64 {
+	ptr := l.view(8)
+	if ptr == nil {
+		return nil
+	}
+	return unsafe.Slice((*float64)(ptr), l.Len())
+}
@@ -0,0 +1,30 @@
+// +build !go1.17 !386,!amd64,!arm64,!ppc64le
+
+package capnp
+
+// View always returns nil on this platform.  See listview.go.
+func (l Int8List) View() []int8 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l UInt16List) View() []uint16 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l Int16List) View() []int16 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l UInt32List) View() []uint32 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l Int32List) View() []int32 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l UInt64List) View() []uint64 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l Int64List) View() []int64 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l Float32List) View() []float32 { return nil }
+
+// View always returns nil on this platform.  See listview.go.
+func (l Float64List) View() []float64 { return nil }
@@ -0,0 +1,90 @@
+package capnp
+
+import "errors"
+
+// A Mark records how much of a message had been built at some point, so
+// that everything allocated after it can be discarded with Rollback.
+// The zero value is not a valid mark.
+type Mark struct {
+	msg  *Message
+	lens []Size // segment lengths, indexed by segment ID
+	caps int    // length of the capability table
+}
+
+// Mark returns a mark for the message's current size.  It is meant for
+// code that builds part of a message and may fail midway, like a
+// handler filling in results: take a mark first, and on error, roll
+// back to it so the partially built objects don't take up space in the
+// message that is sent.
+func (m *Message) Mark() (Mark, error) {
+	if m.frozen {
+		return Mark{}, errFrozen
+	}
+	n := m.NumSegments()
+	mk := Mark{msg: m, lens: make([]Size, n), caps: len(m.CapTable)}
+	for i := int64(0); i < n; i++ {
+		s, err := m.Segment(SegmentID(i))
+		if err != nil {
+			return Mark{}, err
+		}
+		mk.lens[i] = Size(len(s.data))
+	}
+	return mk, nil
+}
+
+// Rollback discards every object allocated and every capability added
+// to the message since mk was taken.  Segments are truncated to their
+// lengths at the mark, and segments created since are left empty.  The
+// discarded bytes are zeroed, and the clients removed from the
+// capability table are closed.  Marks taken after mk are no longer
+// valid.
+//
+// Rollback does not undo writes to objects allocated before the mark.
+// The caller must clear any pointer that was set since the mark in
+// such an object, since it may now point past the end of a segment.
+func (m *Message) Rollback(mk Mark) error {
+	if mk.msg != m {
+		return errors.New("capnp: rollback to mark from different message")
+	}
+	if m.frozen {
+		return errFrozen
+	}
+	if mk.caps > len(m.CapTable) {
+		return errors.New("capnp: rollback to invalid mark")
+	}
+	m.mu.Lock()
+	for i, n := range mk.lens {
+		if s := m.segment(SegmentID(i)); s == nil || Size(len(s.data)) < n {
+			m.mu.Unlock()
+			return errors.New("capnp: rollback to invalid mark")
+		}
+	}
+	for i := int64(0); i < m.NumSegments(); i++ {
+		s := m.segment(SegmentID(i))
+		if s == nil {
+			continue
+		}
+		var n Size
+		if i < int64(len(mk.lens)) {
+			n = mk.lens[i]
+		}
+		tail := s.data[n:]
+		for j := range tail {
+			tail[j] = 0
+		}
+		s.data = s.data[:n]
+	}
+	caps := m.CapTable[mk.caps:]
+	m.CapTable = m.CapTable[:mk.caps]
+	m.mu.Unlock()
+	var firstErr error
+	for _, c := range caps {
+		if c == nil {
+			continue
+		}
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,100 @@
+package capnp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	msg, seg, err := NewMessage(MultiSegment([][]byte{make([]byte, 0, 64)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint64(0, 42)
+	if err := root.SetText(0, "kept"); err != nil {
+		t.Fatal(err)
+	}
+	kept := mustMarshal(t, msg)
+	mk, err := msg.Mark()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Build enough to spill into a new segment, then fail.
+	cc := new(closeCounter)
+	msg.AddCap(cc)
+	if err := root.SetText(1, string(make([]byte, 100))); err != nil {
+		t.Fatal(err)
+	}
+	if msg.NumSegments() < 2 {
+		t.Fatal("message did not allocate a second segment")
+	}
+	if err := root.SetPtr(1, Ptr{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.Rollback(mk); err != nil {
+		t.Fatal("Rollback:", err)
+	}
+
+	if cc.n != 1 {
+		t.Errorf("rolled back client closed %d times; want 1", cc.n)
+	}
+	if len(msg.CapTable) != 0 {
+		t.Errorf("len(CapTable) = %d; want 0", len(msg.CapTable))
+	}
+	for i := int64(1); i < msg.NumSegments(); i++ {
+		s, err := msg.Segment(SegmentID(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s.Data()) != 0 {
+			t.Errorf("segment %d has %d bytes after rollback; want 0", i, len(s.Data()))
+		}
+	}
+	first, _ := msg.Segment(0)
+	if got := first.Data(); !bytes.Equal(got, kept[8:8+len(got)]) {
+		t.Errorf("first segment = % 02x; want % 02x", got, kept[8:])
+	}
+
+	// The message can still be built on.
+	if err := root.SetText(1, "after"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := root.Text(0); got != "kept" {
+		t.Errorf("root.Text(0) = %q; want \"kept\"", got)
+	}
+	if got, _ := root.Text(1); got != "after" {
+		t.Errorf("root.Text(1) = %q; want \"after\"", got)
+	}
+}
+
+func TestRollbackErrors(t *testing.T) {
+	msg, _, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mk, err := other.Mark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.Rollback(mk); err == nil {
+		t.Error("Rollback to another message's mark succeeded")
+	}
+	if err := msg.Rollback(Mark{}); err == nil {
+		t.Error("Rollback to zero mark succeeded")
+	}
+	if err := msg.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := msg.Mark(); err != errFrozen {
+		t.Errorf("Mark on frozen message: %v; want %v", err, errFrozen)
+	}
+}
@@ -25,7 +25,10 @@ const maxDepth = ^uint(0)
 
 // A Message is a tree of Cap'n Proto objects, split into one or more
 // segments of contiguous memory.  The only required field is Arena.
-// A Message is safe to read from multiple goroutines.
+// A Message is safe to read from multiple goroutines: segments are
+// loaded lazily, but under a lock.  A Message must not be read while
+// another goroutine modifies it (by setting fields or allocating new
+// objects).
 type Message struct {
 	// rlimit must be first so that it is 64-bit aligned.
 	// See sync/atomic docs.
@@ -58,10 +61,57 @@ type Message struct {
 	// If not set, this defaults to 64.
 	DepthLimit uint
 
+	// ValidateText makes the text accessors (Struct.Text,
+	// Struct.TextBytes, TextList.At, and TextList.BytesAt) return a
+	// *TextError for text that is not a NUL-terminated byte list of valid
+	// UTF-8, instead of an empty string.  Services that must reject
+	// malformed strings at the boundary should set it.  Ptr.ValidText
+	// validates a single pointer regardless of this setting.
+	ValidateText bool
+
+	// NoFarPointers makes the message allocate new objects only in its
+	// first segment, growing the segment when it is full instead of
+	// asking the arena for another one.  The message then never needs
+	// far pointers, which some simpler readers do not support.  Objects
+	// already in other segments, like those of a decoded multi-segment
+	// message, are not moved.  Growing a segment copies it, so a message
+	// built this way may allocate more than one built with a
+	// MultiSegment arena.
+	NoFarPointers bool
+
+	// Deterministic makes the message zero objects that are no longer
+	// reachable when a pointer to them is overwritten or cleared, so
+	// that a message's bytes depend only on the sequence of calls that
+	// built it.  Allocation itself is always deterministic and new
+	// objects and padding are always zeroed, but without Deterministic
+	// an overwritten object's old contents stay in the message.  Use it
+	// for messages that are signed or stored by content hash.
+	//
+	// Objects in a deterministic message must not be shared: pointing
+	// two pointers at the same object and then overwriting one of them
+	// zeroes the object that the other still refers to.  For the same
+	// reason, a deterministic message cannot Adopt another message.
+	Deterministic bool
+
 	// mu protects the following fields:
 	mu       sync.Mutex
 	segs     map[SegmentID]*Segment
-	firstSeg Segment // Preallocated first segment. msg is non-nil once initialized.
+	firstSeg Segment       // Preallocated first segment. msg is non-nil once initialized.
+	readErr  error         // first error from an error-free accessor
+	allocs   []AllocRecord // only recorded with the capnpdebug build tag
+
+	// refs is the number of references beyond the first, and releasers
+	// are run when the last reference is released.  See Release.
+	refs      int
+	releasers []func()
+	released  bool
+
+	// answerRef is set by SetAnswerOwned and cleared by ReleaseAnswer.
+	answerRef bool
+
+	// frozen is set by Freeze.  Once it is true, segs and firstSeg are
+	// no longer modified, so they may be read without holding mu.
+	frozen bool
 }
 
 // NewMessage creates a message with a new root and returns the first
@@ -107,6 +157,13 @@ func (m *Message) Reset(arena Arena) {
 	m.CapTable = nil
 	m.segs = nil
 	m.firstSeg = Segment{}
+	m.readErr = nil
+	m.allocs = nil
+	m.refs = 0
+	m.releasers = nil
+	m.released = false
+	m.answerRef = false
+	m.frozen = false
 	m.mu.Unlock()
 	if m.TraverseLimit == 0 {
 		m.ReadLimiter().Reset(defaultTraverseLimit)
@@ -148,6 +205,60 @@ func (m *Message) SetRootPtr(p Ptr) error {
 	return s.root().SetPtr(0, p)
 }
 
+// Adopt appends other's segment to m without copying it and returns
+// other's root pointer as a pointer into m.  Setting a field of m to
+// the returned pointer links to the adopted objects with a far pointer,
+// so a large payload that is built once, like a cached response, can be
+// included in many messages cheaply.
+//
+// m must use a MultiSegment arena and must not have NoFarPointers or
+// Deterministic set.
+// other must have a single segment (see Message.NoFarPointers) and no
+// capabilities.  m and other share the adopted segment's memory: m never
+// allocates in it, but modifying adopted objects through m modifies
+// other, so other should not be changed while m is in use.  Freezing
+// other is a good way to ensure that.
+func (m *Message) Adopt(other *Message) (Ptr, error) {
+	if m.NoFarPointers {
+		return Ptr{}, errors.New("capnp: adopt: message does not allow far pointers")
+	}
+	if m.Deterministic {
+		return Ptr{}, errors.New("capnp: adopt: message is deterministic")
+	}
+	msa, ok := m.Arena.(*multiSegmentArena)
+	if !ok {
+		return Ptr{}, errors.New("capnp: adopt: message does not use a MultiSegment arena")
+	}
+	if other.NumSegments() != 1 {
+		return Ptr{}, errors.New("capnp: adopt: message has more than one segment")
+	}
+	if len(other.CapTable) > 0 {
+		return Ptr{}, errors.New("capnp: adopt: message has capabilities")
+	}
+	root, err := other.RootPtr()
+	if err != nil {
+		return Ptr{}, err
+	}
+	if !root.IsValid() {
+		return Ptr{}, nil
+	}
+	if m.frozen {
+		return Ptr{}, errFrozen
+	}
+	data := root.seg.data
+	m.mu.Lock()
+	id := SegmentID(len(*msa))
+	if isInt32Bit && id > maxInt32 {
+		m.mu.Unlock()
+		return Ptr{}, errSegment32Bit
+	}
+	// Limit the capacity so that m never allocates in the shared memory.
+	*msa = append(*msa, data[:len(data):len(data)])
+	root.seg = m.setSegment(id, (*msa)[id])
+	m.mu.Unlock()
+	return root, nil
+}
+
 // AddCap appends a capability to the message's capability table and
 // returns its ID.
 func (m *Message) AddCap(c Client) CapabilityID {
@@ -156,6 +267,110 @@ func (m *Message) AddCap(c Client) CapabilityID {
 	return n
 }
 
+// ReleaseCaps closes the clients in the message's capability table
+// and removes them from the table, so that capability pointers in the
+// message become null.  Calling ReleaseCaps more than once closes each
+// client only once.  It returns the first error from closing a client.
+//
+// ReleaseCaps may be called from multiple goroutines at once, as when
+// several pipelines share a message, but like any write to CapTable, it
+// must not run concurrently with other uses of the table.
+func (m *Message) ReleaseCaps() error {
+	m.mu.Lock()
+	var caps []Client
+	for i, c := range m.CapTable {
+		if c != nil {
+			caps = append(caps, c)
+			m.CapTable[i] = nil
+		}
+	}
+	m.mu.Unlock()
+	var firstErr error
+	for _, c := range caps {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AddReleaser registers f to be called when the message's last
+// reference is released.  Whatever provides a message's memory, like a
+// transport that decodes into pooled buffers, uses a releaser to get
+// the memory back deterministically instead of waiting for the garbage
+// collector.  Releasers run in the reverse order they were added.
+func (m *Message) AddReleaser(f func()) {
+	m.mu.Lock()
+	m.releasers = append(m.releasers, f)
+	m.mu.Unlock()
+}
+
+// Retain adds a reference to the message, which must be balanced by a
+// call to Release.  A message starts with one reference, held by
+// whoever created or received it.
+func (m *Message) Retain() {
+	m.mu.Lock()
+	if m.released {
+		m.mu.Unlock()
+		panic(errReleased)
+	}
+	m.refs++
+	m.mu.Unlock()
+}
+
+// Release drops a reference to the message.  When the last reference
+// is dropped, Release calls the message's releasers, after which the
+// message and any Struct, List, or Ptr in it must not be used.  A
+// message with no releasers needs no explicit release, but code that
+// passes messages along, like the rpc package, releases every message
+// once it is done with it, so that releasers run.  Release does not
+// close the message's capabilities; see ReleaseCaps.  Releasing a
+// message more times than it was retained panics.
+func (m *Message) Release() {
+	m.mu.Lock()
+	if m.released {
+		m.mu.Unlock()
+		panic(errReleased)
+	}
+	if m.refs > 0 {
+		m.refs--
+		m.mu.Unlock()
+		return
+	}
+	m.released = true
+	rs := m.releasers
+	m.releasers = nil
+	m.mu.Unlock()
+	for i := len(rs) - 1; i >= 0; i-- {
+		rs[i]()
+	}
+}
+
+// SetAnswerOwned records that the message holds the results of a call
+// and that the call's answer owns the message's reference.  Answer
+// implementations that place results in a message of their own, like
+// the server and rpc packages, call SetAnswerOwned before resolving the
+// answer, so that Pipeline.Close releases the results without releasing
+// messages that belong to someone else.
+func (m *Message) SetAnswerOwned() {
+	m.mu.Lock()
+	m.answerRef = true
+	m.mu.Unlock()
+}
+
+// ReleaseAnswer releases the reference held by the answer whose results
+// are in the message, if any (see SetAnswerOwned).  Only the first call
+// releases the message, so every holder of the answer may call it.
+func (m *Message) ReleaseAnswer() {
+	m.mu.Lock()
+	ref := m.answerRef
+	m.answerRef = false
+	m.mu.Unlock()
+	if ref {
+		m.Release()
+	}
+}
+
 // ReadLimiter returns the message's read limiter.  Useful if you want
 // to reset the traversal limit while reading.
 func (m *Message) ReadLimiter() *ReadLimiter {
@@ -169,6 +384,54 @@ func (m *Message) ReadLimiter() *ReadLimiter {
 	return &m.rlimit
 }
 
+// Freeze prevents any further changes to the message.  After Freeze
+// returns, setters that return an error (like Struct.SetPtr or
+// Struct.SetText) report an error, allocating new objects fails, and
+// setters without an error result (like Struct.SetUint32) panic.
+//
+// Freeze loads every segment of the message, so that later reads do
+// not need to take a lock.  This makes a frozen message cheap to share
+// between goroutines and safe to cache.  Freeze must not be called
+// while other goroutines are using the message.  Reset unfreezes the
+// message.
+func (m *Message) Freeze() error {
+	for i := int64(0); i < m.NumSegments(); i++ {
+		if _, err := m.Segment(SegmentID(i)); err != nil {
+			return err
+		}
+	}
+	m.mu.Lock()
+	m.frozen = true
+	m.mu.Unlock()
+	return nil
+}
+
+// Frozen reports whether Freeze has been called on the message.
+func (m *Message) Frozen() bool {
+	return m.frozen
+}
+
+// Err returns the first error encountered by an error-free accessor
+// (like Struct.ReadPtr) while reading the message, or nil if none has
+// occurred.  This allows a series of reads to be checked once at the
+// end instead of after every pointer access.
+func (m *Message) Err() error {
+	m.mu.Lock()
+	err := m.readErr
+	m.mu.Unlock()
+	return err
+}
+
+// recordErr saves err to be returned by Err, unless an earlier error
+// was already recorded.
+func (m *Message) recordErr(err error) {
+	m.mu.Lock()
+	if m.readErr == nil {
+		m.readErr = err
+	}
+	m.mu.Unlock()
+}
+
 func (m *Message) depthLimit() uint {
 	if m.DepthLimit != 0 {
 		return m.DepthLimit
@@ -189,6 +452,10 @@ func (m *Message) Segment(id SegmentID) (*Segment, error) {
 	if int64(id) >= m.Arena.NumSegments() {
 		return nil, errSegmentOutOfBounds
 	}
+	if m.frozen {
+		// All segments were loaded by Freeze.
+		return m.segment(id), nil
+	}
 	m.mu.Lock()
 	if seg := m.segment(id); seg != nil {
 		m.mu.Unlock()
@@ -248,16 +515,31 @@ func (m *Message) setSegment(id SegmentID, data []byte) *Segment {
 // allocSegment creates or resizes an existing segment such that
 // cap(seg.Data) - len(seg.Data) >= sz.
 func (m *Message) allocSegment(sz Size) (*Segment, error) {
+	if m.frozen {
+		return nil, errFrozen
+	}
 	m.mu.Lock()
 	if m.segs == nil && m.firstSeg.msg != nil {
 		m.segs = make(map[SegmentID]*Segment)
 		m.segs[0] = &m.firstSeg
 	}
+	if first := m.segs[0]; m.NoFarPointers && first != nil {
+		err := growSegment(first, sz)
+		m.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return first, nil
+	}
 	id, data, err := m.Arena.Allocate(sz, m.segs)
 	if err != nil {
 		m.mu.Unlock()
 		return nil, err
 	}
+	if m.NoFarPointers && id != 0 {
+		m.mu.Unlock()
+		return nil, errors.New("capnp: arena did not allocate the first segment")
+	}
 	if isInt32Bit && id > maxInt32 {
 		m.mu.Unlock()
 		return nil, errSegment32Bit
@@ -267,16 +549,36 @@ func (m *Message) allocSegment(sz Size) (*Segment, error) {
 	return seg, nil
 }
 
+// growSegment ensures that s has room for sz more bytes by copying its
+// data into a larger buffer.
+func growSegment(s *Segment, sz Size) error {
+	if hasCapacity(s.data, sz) {
+		return nil
+	}
+	inc, err := nextAlloc(int64(cap(s.data)), int64(maxSegmentSize()), sz)
+	if err != nil {
+		return fmt.Errorf("capnp: alloc %d bytes: %v", sz, err)
+	}
+	buf := make([]byte, len(s.data), cap(s.data)+inc)
+	copy(buf, s.data)
+	s.data = buf
+	return nil
+}
+
 // alloc allocates sz zero-filled bytes.  It prefers using s, but may
 // use a different segment in the same message if there's not sufficient
 // capacity.
 func alloc(s *Segment, sz Size) (*Segment, Address, error) {
+	if s.msg.frozen {
+		return nil, 0, errFrozen
+	}
 	sz = sz.padToWord()
 	if sz > maxSize-wordSize {
 		return nil, 0, errOverflow
 	}
 
-	if !hasCapacity(s.data, sz) {
+	newSeg := !hasCapacity(s.data, sz)
+	if newSeg {
 		var err error
 		s, err = s.msg.allocSegment(sz)
 		if err != nil {
@@ -294,10 +596,16 @@ func alloc(s *Segment, sz Size) (*Segment, Address, error) {
 	for i := range space {
 		space[i] = 0
 	}
+	if allocDebug {
+		s.msg.traceAlloc(s, sz, newSeg)
+	}
 	return s, addr, nil
 }
 
-// An Arena loads and allocates segments for a Message.
+// An Arena loads and allocates segments for a Message.  A Message
+// serializes its calls to Data and Allocate, so an Arena does not need
+// its own locking for them, but NumSegments may be called concurrently
+// with Data when a message is read from multiple goroutines.
 type Arena interface {
 	// NumSegments returns the number of segments in the arena.
 	// This must not be larger than 1<<32.
@@ -511,6 +819,10 @@ type Decoder struct {
 	// Maximum number of bytes that can be read per call to Decode.
 	// If not set, a reasonable default is used.
 	MaxMessageSize uint64
+
+	// ValidateText is copied to each decoded message.
+	// See Message.ValidateText.
+	ValidateText bool
 }
 
 // NewDecoder creates a new Cap'n Proto framer that reads from r.
@@ -534,12 +846,12 @@ func (d *Decoder) Decode() (*Message, error) {
 		return nil, err
 	}
 	maxSeg := binary.LittleEndian.Uint32(d.segbuf[:])
-	if maxSeg > maxStreamSegments {
-		return nil, errTooManySegments
+	if maxSeg >= maxStreamSegments {
+		return nil, ErrTooManySegments
 	}
 	hdrSize := streamHeaderSize(maxSeg)
 	if hdrSize > maxSize || hdrSize > (1<<31-1) {
-		return nil, errDecodeLimit
+		return nil, ErrMessageTooLarge
 	}
 	d.hdrbuf = resizeSlice(d.hdrbuf, int(hdrSize))
 	copy(d.hdrbuf, d.segbuf[:])
@@ -557,7 +869,7 @@ func (d *Decoder) Decode() (*Message, error) {
 	// TODO(someday): if total size is greater than can fit in one buffer,
 	// attempt to allocate buffer per segment.
 	if total > maxSize-hdrSize || total > (1<<31-1) {
-		return nil, errDecodeLimit
+		return nil, ErrMessageTooLarge
 	}
 	if !d.reuse {
 		buf := make([]byte, int(total))
@@ -568,7 +880,7 @@ func (d *Decoder) Decode() (*Message, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &Message{Arena: arena}, nil
+		return &Message{Arena: arena, ValidateText: d.ValidateText}, nil
 	}
 	d.buf = resizeSlice(d.buf, int(total))
 	if _, err := io.ReadFull(d.r, d.buf); err != nil {
@@ -586,6 +898,7 @@ func (d *Decoder) Decode() (*Message, error) {
 		}
 	}
 	d.msg.Reset(arena)
+	d.msg.ValidateText = d.ValidateText
 	return &d.msg, nil
 }
 
@@ -751,6 +1064,16 @@ func (m *Message) segmentSizes() ([]Size, error) {
 	return sizes, nil
 }
 
+// TotalSize returns the number of bytes in the message's segments, not
+// counting the framing that Marshal adds.
+func (m *Message) TotalSize() (uint64, error) {
+	sizes, err := m.segmentSizes()
+	if err != nil {
+		return 0, err
+	}
+	return totalSize(sizes), nil
+}
+
 // Marshal concatenates the segments in the message into a single byte
 // slice including framing.
 func (m *Message) Marshal() ([]byte, error) {
@@ -836,7 +1159,9 @@ func parseStreamHeader(data []byte) (h streamHeader, tail []byte, err error) {
 		return streamHeader{}, nil, io.ErrUnexpectedEOF
 	}
 	maxSeg := binary.LittleEndian.Uint32(data)
-	// TODO(light): check int
+	if maxSeg >= maxStreamSegments {
+		return streamHeader{}, nil, ErrTooManySegments
+	}
 	hdrSize := streamHeaderSize(maxSeg)
 	if uint64(len(data)) < hdrSize {
 		return streamHeader{}, nil, io.ErrUnexpectedEOF
@@ -850,11 +1175,11 @@ func (h streamHeader) maxSegment() uint32 {
 
 func (h streamHeader) segmentSize(i uint32) (Size, error) {
 	s := binary.LittleEndian.Uint32(h.b[msgHeaderSize+i*segHeaderSize:])
-	sz, ok := wordSize.times(int32(s))
-	if !ok {
-		return 0, errSegmentTooLarge
+	sz := uint64(s) * uint64(wordSize)
+	if sz > uint64(maxSegmentSize()) {
+		return 0, ErrMessageTooLarge
 	}
-	return sz, nil
+	return Size(sz), nil
 }
 
 func (h streamHeader) totalSize() (uint64, error) {
@@ -908,6 +1233,20 @@ var (
 	errMessageEmpty       = errors.New("capnp: marshalling an empty message")
 	errHasData            = errors.New("capnp: NewMessage called on arena with data")
 	errSegmentTooLarge    = errors.New("capnp: segment too large")
-	errTooManySegments    = errors.New("capnp: too many segments to decode")
-	errDecodeLimit        = errors.New("capnp: message too large")
+	errFrozen             = errors.New("capnp: message is frozen")
+	errReleased           = errors.New("capnp: message already released")
+)
+
+// Errors returned when decoding a message that exceeds a resource
+// limit.  Other decoding errors mean that the message is malformed or
+// truncated.
+var (
+	// ErrTooManySegments is returned for a message with more segments
+	// than a decoder accepts.
+	ErrTooManySegments = errors.New("capnp: too many segments to decode")
+
+	// ErrMessageTooLarge is returned for a message that is larger than
+	// Decoder.MaxMessageSize or that has a segment too large to address
+	// on this platform.
+	ErrMessageTooLarge = errors.New("capnp: message too large")
 )
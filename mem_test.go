@@ -2,9 +2,12 @@ package capnp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -509,6 +512,303 @@ var serializeTests = []serializeTest{
 	},
 }
 
+func TestNoFarPointers(t *testing.T) {
+	build := func(noFar bool) *Message {
+		msg, seg, err := NewMessage(MultiSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg.NoFarPointers = noFar
+		root, err := NewRootStruct(seg, ObjectSize{PointerCount: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := uint16(0); i < 3; i++ {
+			if err := root.SetData(i, make([]byte, 2000)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return msg
+	}
+
+	if n := build(false).NumSegments(); n < 2 {
+		t.Fatalf("without NoFarPointers, NumSegments() = %d; want >= 2 for the test to be meaningful", n)
+	}
+	msg := build(true)
+	if n := msg.NumSegments(); n != 1 {
+		t.Errorf("NumSegments() = %d; want 1", n)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := msg.NumSegments(); n != 1 {
+		t.Errorf("after Unmarshal, NumSegments() = %d; want 1", n)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint16(0); i < 3; i++ {
+		d, err := p.Struct().Ptr(i)
+		if err != nil {
+			t.Fatalf("Ptr(%d): %v", i, err)
+		}
+		if n := len(d.Data()); n != 2000 {
+			t.Errorf("len(Ptr(%d).Data()) = %d; want 2000", i, n)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	secret := bytes.Repeat([]byte{0xa5}, 2000)
+	build := func(deterministic bool) []byte {
+		msg, seg, err := NewMessage(MultiSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg.Deterministic = deterministic
+		root, err := NewRootStruct(seg, ObjectSize{PointerCount: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Large enough to land in other segments behind far pointers.
+		if err := root.SetData(0, secret); err != nil {
+			t.Fatal(err)
+		}
+		child, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		child.SetUint64(0, 0xa5a5a5a5a5a5a5a5)
+		if err := child.SetData(0, secret); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.SetPtr(1, child.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+		l, err := NewCompositeList(seg, ObjectSize{PointerCount: 1}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Struct(1).SetData(0, secret); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.SetPtr(2, l.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+		kept, err := NewText(seg, "kept")
+		if err != nil {
+			t.Fatal(err)
+		}
+		grandchild, err := NewStruct(seg, ObjectSize{PointerCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := grandchild.SetPtr(0, kept.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+		parent, err := NewStruct(seg, ObjectSize{DataSize: 8, PointerCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		parent.SetUint64(0, 0xa5a5a5a5a5a5a5a5)
+		if err := parent.SetPtr(0, grandchild.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.SetPtr(3, parent.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := root.SetData(0, []byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.SetPtr(1, Ptr{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.SetPtr(2, Ptr{}); err != nil {
+			t.Fatal(err)
+		}
+		// Replace a struct with its own descendant.
+		if err := root.SetPtr(3, grandchild.ToPtr()); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := msg.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	if data := build(false); !bytes.Contains(data, secret[:8]) {
+		t.Fatal("without Deterministic, overwritten data is not in message; test is not meaningful")
+	}
+	data := build(true)
+	if bytes.Contains(data, secret[:8]) {
+		t.Error("overwritten data is still in deterministic message")
+	}
+	if !bytes.Equal(data, build(true)) {
+		t.Error("building the same message twice gave different bytes")
+	}
+
+	msg, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := p.Struct()
+	if d, err := root.Ptr(0); err != nil || string(d.Data()) != "new" {
+		t.Errorf("root.Ptr(0) = %q, %v; want \"new\", <nil>", d.Data(), err)
+	}
+	for i := uint16(1); i <= 2; i++ {
+		if p, err := root.Ptr(i); err != nil || p.IsValid() {
+			t.Errorf("root.Ptr(%d) = %v, %v; want null, <nil>", i, p, err)
+		}
+	}
+	gc, err := root.Ptr(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := gc.Struct().Text(0); err != nil || s != "kept" {
+		t.Errorf("root.Ptr(3).Text(0) = %q, %v; want \"kept\", <nil>", s, err)
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	cache, cseg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := NewRootStruct(cseg, ObjectSize{DataSize: 8, PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload.SetUint64(0, 42)
+	if err := payload.SetText(0, "cached"); err != nil {
+		t.Fatal(err)
+	}
+	cacheData := cseg.Data()
+
+	msg, seg, err := NewMessage(MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := msg.Adopt(cache)
+	if err != nil {
+		t.Fatal("Adopt:", err)
+	}
+	if err := root.SetPtr(0, p); err != nil {
+		t.Fatal(err)
+	}
+	if n := msg.NumSegments(); n != 2 {
+		t.Errorf("NumSegments() = %d; want 2", n)
+	}
+	adopted, err := msg.Segment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := adopted.Data(); &d[0] != &cacheData[0] {
+		t.Error("adopted segment was copied")
+	}
+	if !bytes.Equal(cseg.Data(), cacheData) {
+		t.Error("Adopt modified the adopted message")
+	}
+
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := rp.Struct().Ptr(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := pp.Struct().Uint64(0); v != 42 {
+		t.Errorf("payload Uint64(0) = %d; want 42", v)
+	}
+	if s, err := pp.Struct().Text(0); err != nil || s != "cached" {
+		t.Errorf("payload Text(0) = %q, %v; want \"cached\", <nil>", s, err)
+	}
+
+	single, _, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := single.Adopt(cache); err == nil {
+		t.Error("Adopt into SingleSegment arena succeeded")
+	}
+}
+
+func TestMessageRelease(t *testing.T) {
+	msg, _, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []int
+	msg.AddReleaser(func() { order = append(order, 1) })
+	msg.AddReleaser(func() { order = append(order, 2) })
+	msg.Retain()
+	msg.Release()
+	if len(order) != 0 {
+		t.Fatalf("releasers ran with a reference outstanding: %v", order)
+	}
+	msg.Release()
+	if !reflect.DeepEqual(order, []int{2, 1}) {
+		t.Errorf("releasers ran in order %v; want [2 1]", order)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("releasing a released message did not panic")
+		}
+	}()
+	msg.Release()
+}
+
+func TestMessageReleaseCapsConcurrent(t *testing.T) {
+	msg := new(Message)
+	counters := make([]*closeCounter, 8)
+	for i := range counters {
+		counters[i] = new(closeCounter)
+		msg.AddCap(counters[i])
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := msg.ReleaseCaps(); err != nil {
+				t.Error("ReleaseCaps:", err)
+			}
+		}()
+	}
+	wg.Wait()
+	for i, c := range counters {
+		if c.n != 1 {
+			t.Errorf("capability %d closed %d times; want 1", i, c.n)
+		}
+	}
+}
+
 func TestMarshal(t *testing.T) {
 	for i, test := range serializeTests {
 		if test.decodeFails {
@@ -704,6 +1004,62 @@ func TestDecoder_MaxMessageSize(t *testing.T) {
 			t.Errorf("%s test: Decode error: %v", test.name, err)
 		case err == nil && !test.ok:
 			t.Errorf("%s test: Decode success; want error", test.name)
+		case err != nil && err != ErrMessageTooLarge:
+			t.Errorf("%s test: Decode error = %v; want ErrMessageTooLarge", test.name, err)
+		}
+	}
+}
+
+func TestSegmentTableLimits(t *testing.T) {
+	t.Parallel()
+	// header returns a stream header for maxSeg+1 segments that starts
+	// with the given segment sizes in words.
+	header := func(maxSeg uint32, sizes ...uint32) []byte {
+		b := make([]byte, streamHeaderSize(maxSeg))
+		binary.LittleEndian.PutUint32(b, maxSeg)
+		for i, sz := range sizes {
+			binary.LittleEndian.PutUint32(b[msgHeaderSize+i*segHeaderSize:], sz)
+		}
+		return b
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{
+			name: "too many segments",
+			data: header(maxStreamSegments),
+			err:  ErrTooManySegments,
+		},
+		{
+			name: "max segments",
+			data: header(maxStreamSegments - 1),
+			err:  nil,
+		},
+		{
+			name: "segment larger than 31 bits of words",
+			data: header(0, 0xe0000000),
+			err:  ErrMessageTooLarge,
+		},
+		{
+			name: "segment too large to address",
+			data: header(0, 0xffffffff),
+			err:  ErrMessageTooLarge,
+		},
+		{
+			name: "truncated",
+			data: append(header(0, 1), 0, 0, 0, 0),
+			err:  io.ErrUnexpectedEOF,
+		},
+	}
+	for _, test := range tests {
+		if _, err := Unmarshal(test.data); err != test.err {
+			t.Errorf("%s: Unmarshal error = %v; want %v", test.name, err, test.err)
+		}
+		_, err := NewDecoder(bytes.NewReader(test.data)).Decode()
+		if err != test.err {
+			t.Errorf("%s: Decode error = %v; want %v", test.name, err, test.err)
 		}
 	}
 }
@@ -715,6 +1071,209 @@ func TestDecoder_MaxMessageSize(t *testing.T) {
 // this test ensures that the padding is explicitly
 // zeroed. This was not done in previous versions and
 // resulted in the padding being garbage.
+func TestParallelDecoder(t *testing.T) {
+	t.Parallel()
+	const n = 100
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i := 0; i < n; i++ {
+		msg, seg, err := NewMessage(SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := NewRootStruct(seg, ObjectSize{DataSize: 8})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root.SetUint64(0, uint64(i))
+		if err := enc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errOdd := errors.New("odd")
+	pd := NewParallelDecoder(NewDecoder(&buf), 4, func(msg *Message) (interface{}, error) {
+		p, err := msg.RootPtr()
+		if err != nil {
+			return nil, err
+		}
+		v := p.Struct().Uint64(0)
+		if v%2 == 1 {
+			return nil, errOdd
+		}
+		return v * 10, nil
+	})
+	defer pd.Close()
+	for i := 0; i < n; i++ {
+		msg, res, err := pd.Next()
+		if msg == nil {
+			t.Fatalf("Next() #%d: nil message, error = %v", i, err)
+		}
+		if i%2 == 1 {
+			if err != errOdd {
+				t.Errorf("Next() #%d error = %v; want %v", i, err, errOdd)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Next() #%d: %v", i, err)
+			continue
+		}
+		if res != uint64(i*10) {
+			t.Errorf("Next() #%d result = %v; want %d", i, res, i*10)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if msg, _, err := pd.Next(); msg != nil || err != io.EOF {
+			t.Errorf("Next() at end = %v, _, %v; want <nil>, _, EOF", msg, err)
+		}
+	}
+}
+
+func TestParallelDecoder_Close(t *testing.T) {
+	t.Parallel()
+	// An endless stream of empty messages.
+	r := &repeatReader{b: []byte{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}}
+	pd := NewParallelDecoder(NewDecoder(r), 2, nil)
+	if msg, _, err := pd.Next(); msg == nil {
+		t.Fatalf("Next(): %v", err)
+	}
+	pd.Close()
+	if msg, _, err := pd.Next(); msg != nil || err == nil {
+		t.Errorf("Next() after Close = %v, _, %v; want <nil>, _, error", msg, err)
+	}
+}
+
+type repeatReader struct {
+	b []byte
+	i int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	for n := range p {
+		p[n] = r.b[r.i]
+		r.i = (r.i + 1) % len(r.b)
+	}
+	return len(p), nil
+}
+
+func TestConcurrentReads(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(MultiSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 64
+	tl, err := NewTextList(seg, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(0, tl.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := tl.Set(i, fmt.Sprintf("element %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmsg, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rmsg.NumSegments() < 2 {
+		t.Fatalf("message has %d segments; want at least 2", rmsg.NumSegments())
+	}
+
+	// Segments are loaded lazily, so start all the readers on a fresh
+	// message.  Run with -race to catch unsynchronized loads.
+	errs := make(chan error, 8)
+	for g := 0; g < cap(errs); g++ {
+		go func() {
+			p, err := rmsg.RootPtr()
+			if err != nil {
+				errs <- err
+				return
+			}
+			list := TextList{p.Struct().ReadPtr(0).List()}
+			for i := 0; i < n; i++ {
+				if got, want := list.ReadAt(i), fmt.Sprintf("element %d", i); got != want {
+					errs <- fmt.Errorf("list[%d] = %q; want %q", i, got, want)
+					return
+				}
+			}
+			errs <- rmsg.Err()
+		}()
+	}
+	for g := 0; g < cap(errs); g++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	t.Parallel()
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint32(0, 42)
+	if err := root.SetText(0, "before"); err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.Freeze(); err != nil {
+		t.Fatal("Freeze:", err)
+	}
+	if !msg.Frozen() {
+		t.Error("msg.Frozen() = false after Freeze")
+	}
+
+	if err := root.SetText(1, "after"); err != errFrozen {
+		t.Errorf("SetText after Freeze = %v; want %v", err, errFrozen)
+	}
+	if err := root.SetPtr(0, Ptr{}); err != errFrozen {
+		t.Errorf("SetPtr after Freeze = %v; want %v", err, errFrozen)
+	}
+	if _, err := NewStruct(seg, ObjectSize{DataSize: 8}); err != errFrozen {
+		t.Errorf("NewStruct after Freeze = %v; want %v", err, errFrozen)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != errFrozen {
+				t.Errorf("SetUint32 after Freeze panicked with %v; want %v", r, errFrozen)
+			}
+		}()
+		root.SetUint32(0, 7)
+	}()
+
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	if v := p.Struct().Uint32(0); v != 42 {
+		t.Errorf("root.Uint32(0) = %d; want 42", v)
+	}
+	if txt := p.Struct().ReadText(0); txt != "before" {
+		t.Errorf("root.ReadText(0) = %q; want \"before\"", txt)
+	}
+
+	msg.Reset(SingleSegment(nil))
+	if msg.Frozen() {
+		t.Error("msg.Frozen() = true after Reset")
+	}
+}
+
 func TestStreamHeaderPadding(t *testing.T) {
 	msg := &Message{
 		Arena: MultiSegment([][]byte{
@@ -0,0 +1,81 @@
+package ocap
+
+import (
+	"time"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
+)
+
+// An Auditor describes how calls through an audited capability are
+// recorded.
+type Auditor struct {
+	// Sink receives a record of each call once the call's answer is
+	// resolved.  It is called from its own goroutine and may be called
+	// concurrently.  Sink must not be nil.
+	Sink func(rec *AuditRecord)
+
+	// Identify returns the identity of the call's caller, usually from a
+	// value in call.Ctx or call.Options.  It is called from the goroutine
+	// making the call.  If nil, records have an empty Caller.
+	Identify func(call *capnp.Call) string
+
+	// Clock is used to time calls.  If nil, clock.Real is used.
+	Clock clock.Clock
+}
+
+// An AuditRecord describes a call made through an audited capability.
+type AuditRecord struct {
+	Method capnp.Method
+	Caller string
+
+	// Start is when the call was made and Duration is the time from then
+	// until the call's answer was resolved.
+	Start    time.Time
+	Duration time.Duration
+
+	// Err is the error the call failed with or nil if it returned
+	// results.
+	Err error
+}
+
+// Audit returns a capability that forwards calls to c and reports each
+// of them to a's sink.  The returned client takes ownership of c.
+//
+// Audit composes with the other wrappers in this package: auditing an
+// attenuated or quota-limited capability records the calls that were
+// rejected along with those that were delivered.  A capability that is
+// exported over RPC can be audited by passing the audited client to
+// the connection, so that every call from the peer is recorded.
+func Audit(c capnp.Client, a Auditor) capnp.Client {
+	if a.Clock == nil {
+		a.Clock = clock.Real
+	}
+	return &auditedClient{c: c, a: a}
+}
+
+type auditedClient struct {
+	c capnp.Client
+	a Auditor
+}
+
+func (ac *auditedClient) Call(call *capnp.Call) capnp.Answer {
+	rec := &AuditRecord{
+		Method: call.Method,
+		Start:  ac.a.Clock.Now(),
+	}
+	if ac.a.Identify != nil {
+		rec.Caller = ac.a.Identify(call)
+	}
+	ans := ac.c.Call(call)
+	go func() {
+		_, rec.Err = ans.Struct()
+		rec.Duration = ac.a.Clock.Now().Sub(rec.Start)
+		ac.a.Sink(rec)
+	}()
+	return ans
+}
+
+func (ac *auditedClient) Close() error {
+	return ac.c.Close()
+}
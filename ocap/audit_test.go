@@ -0,0 +1,69 @@
+package ocap_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/ocap"
+)
+
+type callerKey struct{}
+
+func TestAudit(t *testing.T) {
+	recs := make(chan *ocap.AuditRecord, 2)
+	closed := false
+	attenuated := ocap.Attenuate(newEchoSeq(&closed), capnp.Method{
+		InterfaceID: air.CallSequence_TypeID,
+		MethodID:    0,
+	})
+	c := ocap.Audit(attenuated, ocap.Auditor{
+		Sink: func(rec *ocap.AuditRecord) {
+			recs <- rec
+		},
+		Identify: func(call *capnp.Call) string {
+			id, _ := call.Options.Value(callerKey{}).(string)
+			return id
+		},
+	})
+
+	_, err := air.CallSequence{Client: c}.GetNumber(context.Background(), nil, capnp.SetOptionValue(callerKey{}, "alice")).Struct()
+	if err != nil {
+		t.Fatal("getNumber:", err)
+	}
+	rec := <-recs
+	if rec.Method.InterfaceID != air.CallSequence_TypeID || rec.Method.MethodID != 0 {
+		t.Errorf("record method = %v; want getNumber", &rec.Method)
+	}
+	if rec.Caller != "alice" {
+		t.Errorf("record caller = %q; want \"alice\"", rec.Caller)
+	}
+	if rec.Err != nil {
+		t.Errorf("record error = %v; want <nil>", rec.Err)
+	}
+	if rec.Start.IsZero() || rec.Duration < 0 {
+		t.Errorf("record start, duration = %v, %v; want call time", rec.Start, rec.Duration)
+	}
+
+	if err := echo(c); err == nil {
+		t.Fatal("echo through attenuated capability succeeded")
+	}
+	rec = <-recs
+	if rec.Method.InterfaceID != air.Echo_TypeID {
+		t.Errorf("record method = %v; want echo", &rec.Method)
+	}
+	if rec.Caller != "" {
+		t.Errorf("record caller = %q; want \"\"", rec.Caller)
+	}
+	if !capnp.IsUnimplemented(rec.Err) {
+		t.Errorf("record error = %v; want unimplemented", rec.Err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !closed {
+		t.Error("underlying client not closed")
+	}
+}
@@ -0,0 +1,139 @@
+// Package ocap provides helpers for object-capability security
+// patterns: sealer/unsealer pairs, which let a capability travel
+// through untrusted hands; attenuation, which hands out a capability
+// that exposes only some of another's methods; quotas, which hand out
+// a capability that expires after limited use; and auditing, which
+// records every call through a capability.
+package ocap // import "zombiezen.com/go/capnproto2/ocap"
+
+import (
+	"errors"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// A brand identifies the boxes made by one Sealer.
+type brand struct {
+	name string
+}
+
+// A Sealer seals capabilities in boxes that only its matching Unsealer
+// can open.  It is safe to use from multiple goroutines.
+type Sealer struct {
+	b *brand
+}
+
+// An Unsealer opens boxes sealed by its matching Sealer.  It is safe to
+// use from multiple goroutines.
+type Unsealer struct {
+	b *brand
+}
+
+// NewSealerPair returns a new sealer and its matching unsealer.  name
+// is used only in error messages.
+func NewSealerPair(name string) (*Sealer, *Unsealer) {
+	b := &brand{name: name}
+	return &Sealer{b}, &Unsealer{b}
+}
+
+// Seal returns a box containing c.  The box is a capability that fails
+// every call, so it can be passed through parties that must not use c.
+// The box takes ownership of c: closing the box closes c.
+func (s *Sealer) Seal(c capnp.Client) capnp.Client {
+	return &box{b: s.b, c: c}
+}
+
+// Unseal returns the capability in c, a box.  It returns ErrNotSealed
+// if c was not made by the unsealer's matching Sealer.  The box retains
+// ownership of the returned client, so the caller must not use it
+// after closing the box.
+//
+// A box that was sent to a peer over an rpc.Conn and then returned by
+// the peer can be unsealed, since the connection hands back the local
+// capability.  A box from any other vat cannot be.
+func (u *Unsealer) Unseal(c capnp.Client) (capnp.Client, error) {
+	bx, ok := unwrap(c).(*box)
+	if !ok || bx.b != u.b {
+		return nil, ErrNotSealed
+	}
+	return bx.c, nil
+}
+
+// unwrap returns the client underneath the wrappers that the rpc
+// package places around local capabilities.
+func unwrap(c capnp.Client) capnp.Client {
+	for {
+		w, ok := c.(interface {
+			Client() capnp.Client
+		})
+		if !ok {
+			return c
+		}
+		next := w.Client()
+		if next == nil || next == c {
+			return c
+		}
+		c = next
+	}
+}
+
+type box struct {
+	b *brand
+	c capnp.Client
+}
+
+func (bx *box) Call(call *capnp.Call) capnp.Answer {
+	return capnp.ErrorAnswer(&capnp.MethodError{Method: &call.Method, Err: errSealedCall})
+}
+
+func (bx *box) Close() error {
+	if bx.c == nil {
+		return nil
+	}
+	return bx.c.Close()
+}
+
+func (bx *box) String() string {
+	return "ocap box sealed by " + bx.b.name
+}
+
+// Attenuate returns a capability that forwards calls to c only for the
+// methods in allow, which are matched by interface and method ID.
+// Calls to other methods fail with capnp.ErrUnimplemented, as if c
+// did not have them.  The returned client takes ownership of c.
+//
+// Only calls made on the returned client are checked.  Capabilities
+// returned by allowed methods are not attenuated.  Use
+// capnp.RestrictClient to report rejected calls as permission errors
+// instead.
+func Attenuate(c capnp.Client, allow ...capnp.Method) capnp.Client {
+	return Filter(c, capnp.NewMethodSet(allow).Has)
+}
+
+// AttenuateInterfaces returns a capability that forwards calls to c
+// only for methods of the interfaces with the given IDs.  It is useful
+// for hiding the methods of a derived interface while exposing those
+// of its superclasses.  See Attenuate.
+func AttenuateInterfaces(c capnp.Client, ids ...uint64) capnp.Client {
+	set := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return Filter(c, func(m *capnp.Method) bool {
+		_, ok := set[m.InterfaceID]
+		return ok
+	})
+}
+
+// Filter returns a capability that forwards a call to c if allow
+// returns true for its method.  allow is called from the goroutine
+// making the call and may be called concurrently.  See Attenuate.
+func Filter(c capnp.Client, allow func(m *capnp.Method) bool) capnp.Client {
+	return capnp.FilterClient(c, allow, capnp.ErrUnimplemented)
+}
+
+// ErrNotSealed is returned by Unsealer.Unseal for a client that is not
+// a box made by the matching Sealer.
+var ErrNotSealed = errors.New("ocap: not sealed by matching sealer")
+
+var errSealedCall = errors.New("ocap: call on sealed box")
@@ -0,0 +1,117 @@
+package ocap_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/ocap"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// echoSeq implements both Echo and CallSequence.
+type echoSeq struct {
+	closed *bool
+}
+
+func (echoSeq) Echo(call air.Echo_echo) error {
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	return call.Results.SetOut(in)
+}
+
+func (echoSeq) GetNumber(call air.CallSequence_getNumber) error {
+	call.Results.SetN(42)
+	return nil
+}
+
+func (s echoSeq) Close() error {
+	if s.closed != nil {
+		*s.closed = true
+	}
+	return nil
+}
+
+func newEchoSeq(closed *bool) capnp.Client {
+	s := echoSeq{closed: closed}
+	methods := air.Echo_Methods(nil, s)
+	methods = air.CallSequence_Methods(methods, s)
+	return server.New(methods, s)
+}
+
+func echo(c capnp.Client) error {
+	_, err := air.Echo{Client: c}.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn("hi")
+	}).Struct()
+	return err
+}
+
+func getNumber(c capnp.Client) error {
+	_, err := air.CallSequence{Client: c}.GetNumber(context.Background(), nil).Struct()
+	return err
+}
+
+func TestSeal(t *testing.T) {
+	closed := false
+	sealer, unsealer := ocap.NewSealerPair("test")
+	box := sealer.Seal(newEchoSeq(&closed))
+	if err := echo(box); err == nil {
+		t.Error("call on box succeeded")
+	}
+
+	if _, err := unsealer.Unseal(newEchoSeq(nil)); err != ocap.ErrNotSealed {
+		t.Errorf("Unseal(unsealed client) error = %v; want ErrNotSealed", err)
+	}
+	_, otherUnsealer := ocap.NewSealerPair("other")
+	if _, err := otherUnsealer.Unseal(box); err != ocap.ErrNotSealed {
+		t.Errorf("Unseal with other unsealer error = %v; want ErrNotSealed", err)
+	}
+	c, err := unsealer.Unseal(box)
+	if err != nil {
+		t.Fatal("Unseal:", err)
+	}
+	if err := echo(c); err != nil {
+		t.Error("call on unsealed client:", err)
+	}
+
+	if err := box.Close(); err != nil {
+		t.Error("box.Close():", err)
+	}
+	if !closed {
+		t.Error("closing box did not close sealed client")
+	}
+}
+
+func TestAttenuate(t *testing.T) {
+	closed := false
+	c := ocap.Attenuate(newEchoSeq(&closed), capnp.Method{
+		InterfaceID: air.CallSequence_TypeID,
+		MethodID:    0,
+	})
+	if err := getNumber(c); err != nil {
+		t.Error("getNumber:", err)
+	}
+	if err := echo(c); !capnp.IsUnimplemented(err) {
+		t.Errorf("echo error = %v; want unimplemented", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !closed {
+		t.Error("closing attenuated client did not close underlying client")
+	}
+}
+
+func TestAttenuateInterfaces(t *testing.T) {
+	c := ocap.AttenuateInterfaces(newEchoSeq(nil), air.Echo_TypeID)
+	defer c.Close()
+	if err := echo(c); err != nil {
+		t.Error("echo:", err)
+	}
+	if err := getNumber(c); !capnp.IsUnimplemented(err) {
+		t.Errorf("getNumber error = %v; want unimplemented", err)
+	}
+}
@@ -0,0 +1,147 @@
+package ocap
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
+)
+
+// A Quota limits the use of a capability.  The zero value of a field
+// means that there is no limit of that kind.
+type Quota struct {
+	// Calls is the maximum number of calls that are delivered.
+	Calls int
+
+	// Bytes is the maximum total size of the calls' parameters.  A call's
+	// size is the size of the message that holds its parameters, so
+	// calls received over RPC are charged for the whole RPC message.
+	Bytes int64
+
+	// Expires is the time after which no calls are delivered.
+	Expires time.Time
+
+	// Clock is used for Expires.  If nil, clock.Real is used.
+	Clock clock.Clock
+}
+
+// Limit returns a capability that forwards calls to c until one of the
+// limits in q is reached.  The capability then breaks: it closes c and
+// fails every subsequent call with ErrQuotaExceeded or ErrExpired.  A
+// call that would exceed a limit is not delivered.  The returned client
+// takes ownership of c.
+//
+// If q has an expiry time, c is closed at that time even if no further
+// calls are made.  Calls being delivered when the capability breaks are
+// allowed to finish being delivered before c is closed.
+func Limit(c capnp.Client, q Quota) capnp.Client {
+	lc := &limitedClient{c: c, q: q}
+	if q.Expires.IsZero() {
+		return lc
+	}
+	clk := q.Clock
+	if clk == nil {
+		clk = clock.Real
+	}
+	d := q.Expires.Sub(clk.Now())
+	if d <= 0 {
+		lc.breakLocked(ErrExpired)
+		return lc
+	}
+	lc.timer = clk.AfterFunc(d, func() {
+		lc.mu.Lock()
+		lc.breakLocked(ErrExpired)
+		lc.mu.Unlock()
+	})
+	return lc
+}
+
+type limitedClient struct {
+	q     Quota
+	timer clock.Timer // nil if the quota has no expiry
+
+	mu       sync.Mutex
+	c        capnp.Client // nil once broken
+	calls    int
+	bytes    int64
+	err      error          // set when broken
+	dispatch sync.WaitGroup // calls being delivered to c
+	closeErr chan error     // receives the result of closing c
+}
+
+func (lc *limitedClient) Call(call *capnp.Call) capnp.Answer {
+	if lc.q.Bytes > 0 && call.ParamsFunc != nil {
+		// Place the parameters now so that they can be measured.
+		var err error
+		call, err = call.Copy(nil)
+		if err != nil {
+			return capnp.ErrorAnswer(err)
+		}
+	}
+	lc.mu.Lock()
+	if lc.err != nil {
+		err := lc.err
+		lc.mu.Unlock()
+		return capnp.ErrorAnswer(&capnp.MethodError{Method: &call.Method, Err: err})
+	}
+	lc.calls++
+	if seg := call.Params.Segment(); lc.q.Bytes > 0 && seg != nil {
+		n, _ := seg.Message().TotalSize()
+		lc.bytes += int64(n)
+	}
+	if lc.q.Calls > 0 && lc.calls > lc.q.Calls || lc.q.Bytes > 0 && lc.bytes > lc.q.Bytes {
+		lc.breakLocked(ErrQuotaExceeded)
+		lc.mu.Unlock()
+		return capnp.ErrorAnswer(&capnp.MethodError{Method: &call.Method, Err: ErrQuotaExceeded})
+	}
+	c := lc.c
+	lc.dispatch.Add(1)
+	lc.mu.Unlock()
+	defer lc.dispatch.Done()
+	return c.Call(call)
+}
+
+// breakLocked fails all subsequent calls with err and closes the
+// underlying client once the calls being delivered to it are
+// acknowledged.  The caller must be holding lc.mu.
+func (lc *limitedClient) breakLocked(err error) {
+	if lc.err != nil {
+		return
+	}
+	lc.err = err
+	c := lc.c
+	lc.c = nil
+	lc.closeErr = make(chan error, 1)
+	go func() {
+		lc.dispatch.Wait()
+		lc.closeErr <- c.Close()
+	}()
+}
+
+// Close breaks the capability if it has not already broken and returns
+// the result of closing the underlying client.
+func (lc *limitedClient) Close() error {
+	if lc.timer != nil {
+		lc.timer.Stop()
+	}
+	lc.mu.Lock()
+	if lc.err == errClosed {
+		lc.mu.Unlock()
+		return errClosed
+	}
+	lc.breakLocked(errClosed)
+	lc.err = errClosed
+	ch := lc.closeErr
+	lc.mu.Unlock()
+	return <-ch
+}
+
+// Errors returned by capabilities from Limit.
+var (
+	ErrQuotaExceeded = errors.New("ocap: quota exceeded")
+	ErrExpired       = errors.New("ocap: capability expired")
+)
+
+var errClosed = errors.New("ocap: capability closed")
@@ -0,0 +1,100 @@
+package ocap_test
+
+import (
+	"testing"
+	"time"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
+	"zombiezen.com/go/capnproto2/ocap"
+)
+
+func TestLimit_Calls(t *testing.T) {
+	closed := false
+	c := ocap.Limit(newEchoSeq(&closed), ocap.Quota{Calls: 2})
+	for i := 0; i < 2; i++ {
+		if err := getNumber(c); err != nil {
+			t.Fatalf("call #%d: %v", i+1, err)
+		}
+	}
+	if err := getNumber(c); !isMethodError(err, ocap.ErrQuotaExceeded) {
+		t.Errorf("call #3 error = %v; want ErrQuotaExceeded", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !closed {
+		t.Error("underlying client not closed")
+	}
+}
+
+func TestLimit_Bytes(t *testing.T) {
+	closed := false
+	c := ocap.Limit(newEchoSeq(&closed), ocap.Quota{Bytes: 100})
+	defer c.Close()
+	// Each echo call's parameters are a few dozen bytes.
+	n := 0
+	var err error
+	for ; n < 10; n++ {
+		if err = echo(c); err != nil {
+			break
+		}
+	}
+	if n == 0 || n == 10 {
+		t.Fatalf("%d calls delivered before error %v; want some but not all", n, err)
+	}
+	if !isMethodError(err, ocap.ErrQuotaExceeded) {
+		t.Errorf("error = %v; want ErrQuotaExceeded", err)
+	}
+}
+
+func TestLimit_Expires(t *testing.T) {
+	start := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	closed := false
+	c := ocap.Limit(newEchoSeq(&closed), ocap.Quota{
+		Expires: start.Add(time.Minute),
+		Clock:   clk,
+	})
+	if err := getNumber(c); err != nil {
+		t.Fatal("call before expiry:", err)
+	}
+	clk.Advance(time.Minute)
+	if err := getNumber(c); !isMethodError(err, ocap.ErrExpired) {
+		t.Errorf("call after expiry error = %v; want ErrExpired", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !closed {
+		t.Error("underlying client not closed")
+	}
+	if n := clk.Pending(); n != 0 {
+		t.Errorf("%d timers pending after Close; want 0", n)
+	}
+}
+
+func TestLimit_AlreadyExpired(t *testing.T) {
+	start := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	closed := false
+	c := ocap.Limit(newEchoSeq(&closed), ocap.Quota{
+		Expires: start,
+		Clock:   clock.NewFake(start),
+	})
+	if err := getNumber(c); !isMethodError(err, ocap.ErrExpired) {
+		t.Errorf("call error = %v; want ErrExpired", err)
+	}
+	c.Close()
+	if !closed {
+		t.Error("underlying client not closed")
+	}
+}
+
+// isMethodError reports whether err is want, possibly wrapped in a
+// *capnp.MethodError.
+func isMethodError(err, want error) bool {
+	if me, ok := err.(*capnp.MethodError); ok {
+		err = me.Err
+	}
+	return err == want
+}
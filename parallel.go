@@ -0,0 +1,142 @@
+package capnp
+
+import (
+	"errors"
+	"sync"
+)
+
+// A ParallelDecoder reads a stream of messages from a Decoder and runs
+// a function over each message on a pool of goroutines.  Messages are
+// still read from the stream one at a time, but the (usually much more
+// expensive) work of interpreting them is spread across workers.
+// Results are returned in the same order as the messages appear in the
+// stream.
+type ParallelDecoder struct {
+	order chan *decodeJob
+	jobs  chan *decodeJob
+	stop  chan struct{}
+	once  sync.Once
+
+	err error // sticky error returned by Next
+}
+
+// A ProcessFunc examines a message decoded by a ParallelDecoder and
+// returns a result for the consumer.  It is called concurrently from
+// multiple goroutines, so it must not modify shared state without
+// synchronization.
+type ProcessFunc func(msg *Message) (interface{}, error)
+
+type decodeJob struct {
+	msg  *Message
+	res  interface{}
+	err  error
+	done chan struct{}
+}
+
+// NewParallelDecoder starts decoding messages from d, calling process
+// on each message from one of n worker goroutines.  If n < 1, then one
+// worker is used.  At most 2n messages are buffered ahead of the
+// consumer.  process may be nil, in which case messages are read ahead
+// without further work.
+//
+// d must not be used by the caller afterwards, and must not have had
+// ReuseBuffer called on it, since messages are held concurrently.
+func NewParallelDecoder(d *Decoder, n int, process ProcessFunc) *ParallelDecoder {
+	if n < 1 {
+		n = 1
+	}
+	pd := &ParallelDecoder{
+		order: make(chan *decodeJob, 2*n),
+		jobs:  make(chan *decodeJob),
+		stop:  make(chan struct{}),
+	}
+	go pd.read(d)
+	for i := 0; i < n; i++ {
+		go pd.work(process)
+	}
+	return pd
+}
+
+// read runs in its own goroutine, feeding messages to the workers.
+func (pd *ParallelDecoder) read(d *Decoder) {
+	defer close(pd.jobs)
+	defer close(pd.order)
+	for {
+		msg, err := d.Decode()
+		if err != nil {
+			j := &decodeJob{err: err, done: make(chan struct{})}
+			close(j.done)
+			select {
+			case pd.order <- j:
+			case <-pd.stop:
+			}
+			return
+		}
+		j := &decodeJob{msg: msg, done: make(chan struct{})}
+		select {
+		case pd.order <- j:
+		case <-pd.stop:
+			return
+		}
+		select {
+		case pd.jobs <- j:
+		case <-pd.stop:
+			j.msg, j.err = nil, errParallelDecoderClosed
+			close(j.done)
+			return
+		}
+	}
+}
+
+// work runs in its own goroutine, processing messages until the reader
+// finishes.
+func (pd *ParallelDecoder) work(process ProcessFunc) {
+	for j := range pd.jobs {
+		if process != nil {
+			j.res, j.err = process(j.msg)
+		}
+		close(j.done)
+	}
+}
+
+// Next returns the next message in the stream along with the result
+// and error of processing it.  An error from process applies only to
+// that message; Next can be called again to continue.  Once the stream
+// returns an error (io.EOF at the end of the stream), Next returns a
+// nil message and that error on every subsequent call.
+func (pd *ParallelDecoder) Next() (*Message, interface{}, error) {
+	if pd.err != nil {
+		return nil, nil, pd.err
+	}
+	select {
+	case <-pd.stop:
+		pd.err = errParallelDecoderClosed
+		return nil, nil, pd.err
+	default:
+	}
+	j, ok := <-pd.order
+	if !ok {
+		pd.err = errParallelDecoderClosed
+		return nil, nil, pd.err
+	}
+	<-j.done
+	if j.msg == nil {
+		pd.err = j.err
+		return nil, nil, pd.err
+	}
+	return j.msg, j.res, j.err
+}
+
+// Close stops reading from the stream.  Messages already handed to a
+// worker are still processed, but their results are discarded, and
+// subsequent calls to Next return an error.  Close does not close the
+// underlying reader, and a read that is in progress will finish before
+// the reading goroutine exits.
+func (pd *ParallelDecoder) Close() error {
+	pd.once.Do(func() {
+		close(pd.stop)
+	})
+	return nil
+}
+
+var errParallelDecoderClosed = errors.New("capnp: parallel decoder closed")
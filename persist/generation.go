@@ -0,0 +1,156 @@
+package persist
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+)
+
+// Generations stamps the SturdyRefs that a server issues with a
+// generation number, so that the server can later invalidate every ref
+// issued before some point, for example after a data migration that
+// the old refs don't survive.  Restoring a ref from an invalidated
+// generation fails with a *StaleRefError.
+//
+// A stamped SturdyRef has the format:
+//
+//	struct GenerationalRef {
+//	  generation @0 :UInt64;
+//	  ref @1 :AnyPointer;
+//	}
+//
+// where ref is the SturdyRef that the wrapped Saver created.
+//
+// It is safe to use a Generations from multiple goroutines.  The
+// generation numbers are not stored anywhere; a server that restarts
+// should save Current and Oldest and pass them to NewGenerations.
+type Generations struct {
+	mu      sync.Mutex
+	current uint64
+	oldest  uint64
+}
+
+// NewGenerations returns a Generations that issues refs in generation
+// current and restores refs from generations oldest through current.
+// It panics if oldest > current.
+func NewGenerations(current, oldest uint64) *Generations {
+	if oldest > current {
+		panic("persist: oldest generation is newer than current generation")
+	}
+	return &Generations{current: current, oldest: oldest}
+}
+
+// Current returns the generation that new refs are issued in.
+func (g *Generations) Current() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.current
+}
+
+// Oldest returns the oldest generation whose refs can be restored.
+func (g *Generations) Oldest() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.oldest
+}
+
+// Advance starts a new generation and returns its number.  Refs from
+// earlier generations can still be restored until they are
+// invalidated.
+func (g *Generations) Advance() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.current++
+	return g.current
+}
+
+// Invalidate makes refs from generations before oldest stale.  If
+// oldest is newer than the current generation, the current generation
+// advances to oldest, so that refs issued afterward can be restored.
+// Generations can't be made valid again, so an oldest before Oldest()
+// has no effect.
+func (g *Generations) Invalidate(oldest uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if oldest <= g.oldest {
+		return
+	}
+	g.oldest = oldest
+	if g.current < oldest {
+		g.current = oldest
+	}
+}
+
+var generationalRefSize = capnp.ObjectSize{DataSize: 8, PointerCount: 1}
+
+// Saver returns a Saver that stamps the refs created by s with the
+// current generation.
+func (g *Generations) Saver(s Saver) Saver {
+	return SaverFunc(func(ctx context.Context, c capnp.Client, seg *capnp.Segment) (capnp.Ptr, error) {
+		ref, err := s.Save(ctx, c, seg)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		st, err := capnp.NewStruct(seg, generationalRefSize)
+		if err != nil {
+			return capnp.Ptr{}, err
+		}
+		st.SetUint64(0, g.Current())
+		if err := st.SetPtr(0, ref); err != nil {
+			return capnp.Ptr{}, err
+		}
+		return st.ToPtr(), nil
+	})
+}
+
+// Restorer returns a Restorer that checks the generation of a ref
+// stamped by Saver and passes the original ref to r.  Refs from an
+// invalidated generation fail with a *StaleRefError without calling r.
+func (g *Generations) Restorer(r Restorer) Restorer {
+	return RestorerFunc(func(ctx context.Context, ref capnp.Ptr) (capnp.Client, error) {
+		st := ref.Struct()
+		if !st.IsValid() {
+			return nil, errNoGeneration
+		}
+		gen := st.Uint64(0)
+		g.mu.Lock()
+		oldest, current := g.oldest, g.current
+		g.mu.Unlock()
+		if gen < oldest || gen > current {
+			return nil, &StaleRefError{Generation: gen, Oldest: oldest, Current: current}
+		}
+		inner, err := st.Ptr(0)
+		if err != nil {
+			return nil, err
+		}
+		return r.Restore(ctx, inner)
+	})
+}
+
+// StaleRefError is returned when restoring a SturdyRef whose generation
+// has been invalidated, or that was issued by a newer generation than
+// the restorer knows of.  The capability must be obtained again from
+// its source rather than restored.
+type StaleRefError struct {
+	Generation uint64 // the ref's generation
+	Oldest     uint64 // the oldest generation that can be restored
+	Current    uint64 // the generation that new refs are issued in
+}
+
+func (e *StaleRefError) Error() string {
+	if e.Generation > e.Current {
+		return fmt.Sprintf("persist: sturdy ref is from generation %d, newer than current generation %d", e.Generation, e.Current)
+	}
+	return fmt.Sprintf("persist: sturdy ref from generation %d is stale (oldest valid generation is %d); obtain the capability again", e.Generation, e.Oldest)
+}
+
+// IsStale reports whether e is a *StaleRefError.
+func IsStale(e error) bool {
+	_, ok := e.(*StaleRefError)
+	return ok
+}
+
+var errNoGeneration = errors.New("persist: sturdy ref has no generation")
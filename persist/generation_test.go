@@ -0,0 +1,91 @@
+package persist_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	. "zombiezen.com/go/capnproto2/persist"
+)
+
+func TestGenerations(t *testing.T) {
+	ctx := context.Background()
+	echo := air.Echo_ServerToClient(echoImpl{"!"})
+	reg := registry{"echo": echo.Client}
+	gens := NewGenerations(1, 1)
+
+	save := func() []byte {
+		_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		base, err := air.NewRootEchoBase(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base.SetEcho(echo)
+		saved, err := Save(ctx, gens.Saver(reg), base.ToPtr())
+		if err != nil {
+			t.Fatal("Save:", err)
+		}
+		data, err := saved.Marshal()
+		if err != nil {
+			t.Fatal("Marshal:", err)
+		}
+		return data
+	}
+	load := func(data []byte) error {
+		msg, err := capnp.Unmarshal(data)
+		if err != nil {
+			t.Fatal("Unmarshal:", err)
+		}
+		_, err = Load(ctx, gens.Restorer(reg), msg)
+		return err
+	}
+
+	gen1 := save()
+	if gen := gens.Advance(); gen != 2 {
+		t.Errorf("Advance() = %d; want 2", gen)
+	}
+	gen2 := save()
+	if err := load(gen1); err != nil {
+		t.Error("Load generation 1 before invalidating:", err)
+	}
+
+	gens.Invalidate(2)
+	err := load(gen1)
+	if !IsStale(err) {
+		t.Fatalf("Load generation 1 after Invalidate(2) = %v; want stale ref error", err)
+	}
+	if e := err.(*StaleRefError); e.Generation != 1 || e.Oldest != 2 {
+		t.Errorf("stale ref error = %+v; want Generation=1, Oldest=2", *e)
+	}
+	if err := load(gen2); err != nil {
+		t.Error("Load generation 2 after Invalidate(2):", err)
+	}
+
+	gens.Invalidate(5)
+	if cur := gens.Current(); cur != 5 {
+		t.Errorf("Current() after Invalidate(5) = %d; want 5", cur)
+	}
+	if err := load(gen2); !IsStale(err) {
+		t.Errorf("Load generation 2 after Invalidate(5) = %v; want stale ref error", err)
+	}
+	if err := load(save()); err != nil {
+		t.Error("Load generation 5:", err)
+	}
+	gens.Invalidate(3)
+	if old := gens.Oldest(); old != 5 {
+		t.Errorf("Oldest() after Invalidate(3) = %d; want 5", old)
+	}
+
+	// Refs from a newer generation, like those issued by a server that
+	// has since been rolled back, are stale too.
+	gens = NewGenerations(6, 6)
+	future := save()
+	gens = NewGenerations(5, 5)
+	if err := load(future); !IsStale(err) {
+		t.Errorf("Load generation 6 in generation 5 = %v; want stale ref error", err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package persist stores Cap'n Proto documents that hold capabilities.
+//
+// A live capability can't be written to disk or sent outside of an RPC
+// connection.  Save replaces each capability in a document with a
+// SturdyRef, a token that the capability's host can later turn back
+// into a live capability, as described in persistent.capnp.  Load
+// reverses the process.  The Saver and Restorer hooks decide what a
+// SturdyRef contains and how it is resolved.
+//
+// A saved message has the format:
+//
+//	struct SavedDocument {
+//	  root @0 :AnyPointer;
+//	  sturdyRefs @1 :List(AnyPointer);
+//	}
+//
+// where sturdyRefs[i] is the SturdyRef for the capability at index i
+// of the capability table that root's interface pointers refer to.  A
+// null SturdyRef stands for a null capability.
+package persist // import "zombiezen.com/go/capnproto2/persist"
+
+import (
+	"errors"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/std/capnp/persistent"
+)
+
+// A Saver creates a SturdyRef for a capability.
+type Saver interface {
+	// Save returns a SturdyRef for c.  The SturdyRef is copied into the
+	// saved message, so it may be in any message, but it must not
+	// contain capabilities.  seg is the saved message's segment, which
+	// Save may allocate the SturdyRef in to avoid the copy.
+	Save(ctx context.Context, c capnp.Client, seg *capnp.Segment) (capnp.Ptr, error)
+}
+
+// A Restorer creates a live capability from a SturdyRef.
+type Restorer interface {
+	// Restore returns a client for the capability that ref refers to.
+	// The caller owns the returned client.
+	Restore(ctx context.Context, ref capnp.Ptr) (capnp.Client, error)
+}
+
+// SaverFunc is a function that implements Saver.
+type SaverFunc func(ctx context.Context, c capnp.Client, seg *capnp.Segment) (capnp.Ptr, error)
+
+// Save calls f.
+func (f SaverFunc) Save(ctx context.Context, c capnp.Client, seg *capnp.Segment) (capnp.Ptr, error) {
+	return f(ctx, c, seg)
+}
+
+// RestorerFunc is a function that implements Restorer.
+type RestorerFunc func(ctx context.Context, ref capnp.Ptr) (capnp.Client, error)
+
+// Restore calls f.
+func (f RestorerFunc) Restore(ctx context.Context, ref capnp.Ptr) (capnp.Client, error) {
+	return f(ctx, ref)
+}
+
+// PersistentSaver is a Saver that asks each capability for its
+// SturdyRef by calling Persistent.save on it.  The capabilities must
+// implement the Persistent interface.
+type PersistentSaver struct {
+	// SealFor is passed as the sealFor parameter of each call.
+	SealFor capnp.Ptr
+}
+
+// Save calls Persistent.save on c.
+func (ps PersistentSaver) Save(ctx context.Context, c capnp.Client, seg *capnp.Segment) (capnp.Ptr, error) {
+	res, err := persistent.Persistent{Client: c}.Save(ctx, func(p persistent.Persistent_SaveParams) error {
+		if !ps.SealFor.IsValid() {
+			return nil
+		}
+		return p.SetSealForPtr(ps.SealFor)
+	}).Struct()
+	if err != nil {
+		return capnp.Ptr{}, err
+	}
+	return res.SturdyRefPtr()
+}
+
+const (
+	rootField       = 0
+	sturdyRefsField = 1
+)
+
+var savedDocumentSize = capnp.ObjectSize{PointerCount: 2}
+
+// Save copies the document rooted at root into a new message, with a
+// SturdyRef from s in place of each of its capabilities.  The new
+// message has an empty capability table, so it can be marshaled and
+// stored.  The document's clients are not closed.
+func Save(ctx context.Context, s Saver, root capnp.Ptr) (*capnp.Message, error) {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := capnp.NewRootStruct(seg, savedDocumentSize)
+	if err != nil {
+		return nil, err
+	}
+	// Copying root into the new message adds its capabilities to the
+	// new message's table.  The clients are still owned by root's
+	// message, so take them out of the table instead of closing them.
+	if err := doc.SetPtr(rootField, root); err != nil {
+		msg.CapTable = nil
+		return nil, err
+	}
+	caps := msg.CapTable
+	msg.CapTable = nil
+	refs, err := capnp.NewPointerList(seg, int32(len(caps)))
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.SetPtr(sturdyRefsField, refs.ToPtr()); err != nil {
+		return nil, err
+	}
+	for i, c := range caps {
+		if c == nil {
+			continue
+		}
+		ref, err := s.Save(ctx, c, seg)
+		if err != nil {
+			return nil, err
+		}
+		if err := refs.SetPtr(i, ref); err != nil {
+			return nil, err
+		}
+		if len(msg.CapTable) > 0 {
+			msg.CapTable = nil
+			return nil, errRefHasCaps
+		}
+	}
+	return msg, nil
+}
+
+// Load restores the capabilities of a message created by Save with r
+// and returns the document's root.  msg's capability table is replaced
+// by the restored clients, which are owned by msg.  If restoring any
+// capability fails, the clients restored so far are closed and msg is
+// left unchanged.
+func Load(ctx context.Context, r Restorer, msg *capnp.Message) (capnp.Ptr, error) {
+	p, err := msg.RootPtr()
+	if err != nil {
+		return capnp.Ptr{}, err
+	}
+	doc := p.Struct()
+	if !doc.IsValid() {
+		return capnp.Ptr{}, errNotSaved
+	}
+	root, err := doc.Ptr(rootField)
+	if err != nil {
+		return capnp.Ptr{}, err
+	}
+	rp, err := doc.Ptr(sturdyRefsField)
+	if err != nil {
+		return capnp.Ptr{}, err
+	}
+	refs := capnp.PointerList{List: rp.List()}
+	clients := make([]capnp.Client, refs.Len())
+	for i := range clients {
+		ref, err := refs.PtrAt(i)
+		if err == nil && ref.IsValid() {
+			clients[i], err = r.Restore(ctx, ref)
+		}
+		if err != nil {
+			for _, c := range clients[:i] {
+				if c != nil {
+					c.Close()
+				}
+			}
+			return capnp.Ptr{}, err
+		}
+	}
+	msg.CapTable = clients
+	return root, nil
+}
+
+var (
+	errNotSaved   = errors.New("persist: message is not a saved document")
+	errRefHasCaps = errors.New("persist: sturdy ref contains a capability")
+)
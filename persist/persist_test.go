@@ -0,0 +1,197 @@
+package persist_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	. "zombiezen.com/go/capnproto2/persist"
+	"zombiezen.com/go/capnproto2/server"
+	"zombiezen.com/go/capnproto2/std/capnp/persistent"
+)
+
+type echoImpl struct{ suffix string }
+
+func (e echoImpl) Echo(call air.Echo_echo) error {
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	return call.Results.SetOut(in + e.suffix)
+}
+
+// registry saves capabilities by name.
+type registry map[string]capnp.Client
+
+func (r registry) Save(ctx context.Context, c capnp.Client, seg *capnp.Segment) (capnp.Ptr, error) {
+	for name, rc := range r {
+		if rc == c {
+			t, err := capnp.NewText(seg, name)
+			return t.ToPtr(), err
+		}
+	}
+	return capnp.Ptr{}, errors.New("unknown capability")
+}
+
+func (r registry) Restore(ctx context.Context, ref capnp.Ptr) (capnp.Client, error) {
+	c := r[ref.Text()]
+	if c == nil {
+		return nil, errors.New("unknown sturdy ref")
+	}
+	return c, nil
+}
+
+func TestSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	echo := air.Echo_ServerToClient(echoImpl{"!"})
+	reg := registry{"echo": echo.Client}
+
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := air.NewRootEchoBase(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := base.SetEcho(echo); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Save(ctx, reg, base.ToPtr())
+	if err != nil {
+		t.Fatal("Save:", err)
+	}
+	if len(saved.CapTable) != 0 {
+		t.Errorf("saved message has %d capabilities; want 0", len(saved.CapTable))
+	}
+	data, err := saved.Marshal()
+	if err != nil {
+		t.Fatal("Marshal:", err)
+	}
+
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		t.Fatal("Unmarshal:", err)
+	}
+	root, err := Load(ctx, reg, msg)
+	if err != nil {
+		t.Fatal("Load:", err)
+	}
+	loaded := air.EchoBase{Struct: root.Struct()}
+	res, err := loaded.Echo().Echo(ctx, func(p air.Echo_echo_Params) error {
+		return p.SetIn("hi")
+	}).Struct()
+	if err != nil {
+		t.Fatal("Echo:", err)
+	}
+	if out, _ := res.Out(); out != "hi!" {
+		t.Errorf("Echo(\"hi\") = %q; want \"hi!\"", out)
+	}
+}
+
+func TestLoad_RestoreError(t *testing.T) {
+	ctx := context.Background()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bases, err := air.NewRootEchoBases(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := bases.NewBases(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := registry{
+		"a": air.Echo_ServerToClient(echoImpl{"a"}).Client,
+		"b": air.Echo_ServerToClient(echoImpl{"b"}).Client,
+	}
+	list.At(0).SetEcho(air.Echo{Client: reg["a"]})
+	list.At(1).SetEcho(air.Echo{Client: reg["b"]})
+	saved, err := Save(ctx, reg, bases.ToPtr())
+	if err != nil {
+		t.Fatal("Save:", err)
+	}
+
+	var closed int
+	restore := RestorerFunc(func(ctx context.Context, ref capnp.Ptr) (capnp.Client, error) {
+		if ref.Text() == "b" {
+			return nil, errors.New("b is gone")
+		}
+		return closeCounter{&closed}, nil
+	})
+	if _, err := Load(ctx, restore, saved); err == nil {
+		t.Fatal("Load succeeded with a failing Restorer")
+	}
+	if closed != 1 {
+		t.Errorf("restored clients closed %d times; want 1", closed)
+	}
+	if len(saved.CapTable) != 0 {
+		t.Errorf("failed Load left %d capabilities in message; want 0", len(saved.CapTable))
+	}
+}
+
+type closeCounter struct{ n *int }
+
+func (cc closeCounter) Call(call *capnp.Call) capnp.Answer {
+	return capnp.ErrorAnswer(capnp.ErrUnimplemented)
+}
+
+func (cc closeCounter) Close() error {
+	*cc.n++
+	return nil
+}
+
+type persistentEcho struct {
+	echoImpl
+	ref string
+}
+
+func (p persistentEcho) Save(call persistent.Persistent_save) error {
+	t, err := capnp.NewText(call.Results.Segment(), p.ref)
+	if err != nil {
+		return err
+	}
+	return call.Results.SetSturdyRefPtr(t.ToPtr())
+}
+
+func TestPersistentSaver(t *testing.T) {
+	ctx := context.Background()
+	impl := persistentEcho{ref: "echo-1"}
+	methods := air.Echo_Methods(nil, impl)
+	methods = persistent.Persistent_Methods(methods, impl)
+	echo := air.Echo{Client: server.New(methods, nil)}
+	defer echo.Client.Close()
+
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := air.NewRootEchoBase(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.SetEcho(echo)
+	saved, err := Save(ctx, PersistentSaver{}, base.ToPtr())
+	if err != nil {
+		t.Fatal("Save:", err)
+	}
+	p, err := saved.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := p.Struct().Ptr(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := capnp.PointerList{List: refs.List()}.PtrAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ref.Text(); got != "echo-1" {
+		t.Errorf("sturdy ref = %q; want \"echo-1\"", got)
+	}
+}
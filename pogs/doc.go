@@ -64,6 +64,12 @@ Note that the unsized int and uint type can't be used: int and float
 types must match in size.  For Data and Text fields using []byte, the
 filled-in byte slice will point to original segment.
 
+The UUID and Decimal structs from std/wellknown.capnp may also be
+mapped to a [16]byte (or any array type of 16 bytes) and a string in
+decimal notation, like "12.50", respectively.  The zero value of these
+Go types is stored as a null pointer, except in lists, where it is
+stored as a zero struct.
+
 Renaming and Omitting Fields
 
 By default, the Go field name is the same as the Cap'n Proto schema
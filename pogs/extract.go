@@ -28,6 +28,9 @@ type extracter struct {
 var clientType = reflect.TypeOf((*capnp.Client)(nil)).Elem()
 
 func (e *extracter) extractStruct(val reflect.Value, typeID uint64, s capnp.Struct) error {
+	if wk := findWellKnown(typeID, val.Type()); wk != nil {
+		return wk.extract(val, s)
+	}
 	if val.Kind() == reflect.Ptr {
 		if val.Type().Elem().Kind() != reflect.Struct {
 			return fmt.Errorf("can't extract struct into %v", val.Type())
@@ -345,7 +348,7 @@ func (e *extracter) extractList(val reflect.Value, typ schema.Type, l capnp.List
 			}
 		}
 	case schema.Type_Which_structType:
-		if val.Type().Elem().Kind() == reflect.Struct {
+		if val.Type().Elem().Kind() != reflect.Ptr {
 			for i := 0; i < n; i++ {
 				err := e.extractStruct(val.Index(i), elem.StructType().TypeId(), l.Struct(i))
 				if err != nil {
@@ -390,7 +393,7 @@ func isTypeMatch(r reflect.Type, s schema.Type) bool {
 	case schema.Type_Which_data:
 		return r.Kind() == reflect.Slice && r.Elem().Kind() == reflect.Uint8
 	case schema.Type_Which_structType:
-		return isStructOrStructPtr(r)
+		return isStructOrStructPtr(r) || findWellKnown(s.StructType().TypeId(), r) != nil
 	case schema.Type_Which_list:
 		e, _ := s.List().ElementType()
 		return r.Kind() == reflect.Slice && isTypeMatch(r.Elem(), e)
@@ -29,6 +29,9 @@ func (ins *inserter) insertStruct(typeID uint64, s capnp.Struct, val reflect.Val
 		// TODO(light): ignore if nil?
 		val = val.Elem()
 	}
+	if wk := findWellKnown(typeID, val.Type()); wk != nil {
+		return wk.insert(s, val)
+	}
 	if val.Kind() != reflect.Struct {
 		return fmt.Errorf("can't insert %v into a struct", val.Kind())
 	}
@@ -189,6 +192,9 @@ func (ins *inserter) insertField(s capnp.Struct, f schema.Field, val reflect.Val
 			sval = val.Elem()
 		}
 		id := typ.StructType().TypeId()
+		if wk := findWellKnown(id, val.Type()); wk != nil && wk.isZero(val) {
+			return s.SetPtr(off, capnp.Ptr{})
+		}
 		sz, err := ins.structSize(id)
 		if err != nil {
 			return err
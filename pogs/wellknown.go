@@ -0,0 +1,82 @@
+package pogs
+
+import (
+	"reflect"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/std/wellknown"
+)
+
+// A wellKnownType converts a struct type from std/wellknown.capnp to
+// and from a Go type that isn't a struct.  The zero Go value is stored
+// as a null pointer.
+type wellKnownType struct {
+	match   func(t reflect.Type) bool
+	isZero  func(val reflect.Value) bool
+	insert  func(s capnp.Struct, val reflect.Value) error
+	extract func(val reflect.Value, s capnp.Struct) error
+}
+
+var wellKnownTypes = map[uint64]*wellKnownType{
+	wellknown.UUID_TypeID: {
+		match: func(t reflect.Type) bool {
+			return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem() == byteType
+		},
+		isZero: func(val reflect.Value) bool {
+			return uuidBytes(val) == [16]byte{}
+		},
+		insert: func(s capnp.Struct, val reflect.Value) error {
+			wellknown.UUID{Struct: s}.SetBytes(uuidBytes(val))
+			return nil
+		},
+		extract: func(val reflect.Value, s capnp.Struct) error {
+			b := wellknown.UUID{Struct: s}.Bytes()
+			reflect.Copy(val, reflect.ValueOf(b))
+			return nil
+		},
+	},
+	wellknown.Decimal_TypeID: {
+		match: func(t reflect.Type) bool {
+			return t.Kind() == reflect.String
+		},
+		isZero: func(val reflect.Value) bool {
+			return val.Len() == 0
+		},
+		insert: func(s capnp.Struct, val reflect.Value) error {
+			if val.Len() == 0 {
+				return nil
+			}
+			return wellknown.Decimal{Struct: s}.SetText(val.String())
+		},
+		extract: func(val reflect.Value, s capnp.Struct) error {
+			if !s.IsValid() {
+				val.SetString("")
+				return nil
+			}
+			text, err := wellknown.Decimal{Struct: s}.Text()
+			if err != nil {
+				return err
+			}
+			val.SetString(text)
+			return nil
+		},
+	},
+}
+
+var byteType = reflect.TypeOf(byte(0))
+
+// findWellKnown returns the conversion between the struct type typeID
+// and t, or nil if t should be treated as a struct.
+func findWellKnown(typeID uint64, t reflect.Type) *wellKnownType {
+	wk := wellKnownTypes[typeID]
+	if wk == nil || !wk.match(t) {
+		return nil
+	}
+	return wk
+}
+
+func uuidBytes(val reflect.Value) [16]byte {
+	var b [16]byte
+	reflect.Copy(reflect.ValueOf(&b).Elem(), val)
+	return b
+}
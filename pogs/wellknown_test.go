@@ -0,0 +1,152 @@
+package pogs
+
+import (
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	"zombiezen.com/go/capnproto2/schemas"
+	"zombiezen.com/go/capnproto2/std/wellknown"
+)
+
+// holderTypeID is the ID of a test struct:
+//
+//	struct Holder {
+//	  id @0 :UUID;
+//	  price @1 :Decimal;
+//	  ids @2 :List(UUID);
+//	  prices @3 :List(Decimal);
+//	}
+const holderTypeID = 0xd3cd4a12a0dba0e1
+
+func init() {
+	msg, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	req, _ := schema.NewRootCodeGeneratorRequest(seg)
+	nodes, _ := req.NewNodes(1)
+	n := nodes.At(0)
+	n.SetId(holderTypeID)
+	n.SetDisplayName("wellknown_test.capnp:Holder")
+	n.SetDisplayNamePrefixLength(uint32(len("wellknown_test.capnp:")))
+	n.SetStructNode()
+	n.StructNode().SetPointerCount(4)
+	fields, _ := n.StructNode().NewFields(4)
+	for i, f := range []struct {
+		name   string
+		typeID uint64
+		list   bool
+	}{
+		{"id", wellknown.UUID_TypeID, false},
+		{"price", wellknown.Decimal_TypeID, false},
+		{"ids", wellknown.UUID_TypeID, true},
+		{"prices", wellknown.Decimal_TypeID, true},
+	} {
+		fi := fields.At(i)
+		fi.SetName(f.name)
+		fi.SetCodeOrder(uint16(i))
+		fi.SetDiscriminantValue(schema.Field_noDiscriminant)
+		fi.SetSlot()
+		fi.Slot().SetOffset(uint32(i))
+		typ, _ := fi.Slot().NewType()
+		dv, _ := fi.Slot().NewDefaultValue()
+		if f.list {
+			typ.SetList()
+			typ, _ = typ.List().NewElementType()
+			dv.SetList(nil)
+		} else {
+			dv.SetStructValue(nil)
+		}
+		typ.SetStructType()
+		typ.StructType().SetTypeId(f.typeID)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		panic(err)
+	}
+	err = schemas.DefaultRegistry.Register(&schemas.Schema{Bytes: data, Nodes: []uint64{holderTypeID}})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ID [16]byte
+
+type Holder struct {
+	ID     ID `capnp:"id"`
+	Price  string
+	IDs    []ID `capnp:"ids"`
+	Prices []string
+}
+
+func TestWellKnown(t *testing.T) {
+	tests := []Holder{
+		{},
+		{
+			ID:     ID{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6},
+			Price:  "-12.50",
+			IDs:    []ID{{15: 1}, {}},
+			Prices: []string{"0.001", "7"},
+		},
+	}
+	for _, h := range tests {
+		_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+		st, err := capnp.NewRootStruct(seg, capnp.ObjectSize{PointerCount: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Insert(holderTypeID, st, &h); err != nil {
+			t.Errorf("Insert(%+v): %v", h, err)
+			continue
+		}
+		if p, _ := st.Ptr(0); h.ID == (ID{}) && p.IsValid() {
+			t.Error("Insert of zero UUID set pointer; want null")
+		}
+		if p, _ := st.Ptr(1); h.Price == "" && p.IsValid() {
+			t.Error("Insert of empty decimal set pointer; want null")
+		}
+		if h.Price != "" {
+			p, _ := st.Ptr(1)
+			if text, err := (wellknown.Decimal{Struct: p.Struct()}).Text(); err != nil || text != h.Price {
+				t.Errorf("Insert(%+v) price = %q, %v; want %q", h, text, err, h.Price)
+			}
+		}
+		if h.ID != (ID{}) {
+			p, _ := st.Ptr(0)
+			if got := (wellknown.UUID{Struct: p.Struct()}).Bytes(); got != h.ID {
+				t.Errorf("Insert(%+v) id = %x; want %x", h, got, h.ID)
+			}
+		}
+
+		var out Holder
+		if err := Extract(&out, holderTypeID, st); err != nil {
+			t.Errorf("Extract(Insert(%+v)): %v", h, err)
+			continue
+		}
+		if diff := pretty.Compare(h, out); diff != "" {
+			t.Errorf("Extract(Insert(%+v)) diff (-want +got):\n%s", h, diff)
+		}
+	}
+}
+
+func TestWellKnown_Errors(t *testing.T) {
+	_, seg, _ := capnp.NewMessage(capnp.SingleSegment(nil))
+	st, err := capnp.NewRootStruct(seg, capnp.ObjectSize{PointerCount: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Insert(holderTypeID, st, &Holder{Price: "1,000"}); err == nil {
+		t.Error("Insert with price 1,000 succeeded; want error")
+	}
+
+	d, err := wellknown.NewDecimalFrom(seg, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetScale(wellknown.MaxDecimalScale + 1)
+	if err := st.SetPtr(1, d.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(new(Holder), holderTypeID, st); err == nil {
+		t.Error("Extract with decimal scale 19 succeeded; want error")
+	}
+}
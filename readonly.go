@@ -0,0 +1,230 @@
+package capnp
+
+import "io"
+
+// A ReadOnlyMessage is a view of a message that only permits reading.
+// Received messages often share their arena with a decoder buffer or
+// another reader, so mutating them corrupts data in ways that are hard
+// to track down.  Reading through ReadOnlyMessage and the other
+// read-only views makes such a mutation a compile-time error.
+//
+// Byte slices returned by the views (like ReadOnlyPtr.Data) point
+// directly into the message and must not be modified.
+type ReadOnlyMessage struct {
+	msg *Message
+}
+
+// ReadOnly returns a read-only view of m.
+func (m *Message) ReadOnly() ReadOnlyMessage {
+	return ReadOnlyMessage{m}
+}
+
+// UnmarshalReadOnly is like Unmarshal, but returns a read-only view of
+// the message.
+func UnmarshalReadOnly(data []byte) (ReadOnlyMessage, error) {
+	msg, err := Unmarshal(data)
+	if err != nil {
+		return ReadOnlyMessage{}, err
+	}
+	return ReadOnlyMessage{msg}, nil
+}
+
+// DecodeReadOnly is like Decode, but returns a read-only view of the
+// message.
+func (d *Decoder) DecodeReadOnly() (ReadOnlyMessage, error) {
+	msg, err := d.Decode()
+	if err != nil {
+		return ReadOnlyMessage{}, err
+	}
+	return ReadOnlyMessage{msg}, nil
+}
+
+// IsValid reports whether the view refers to a message.
+func (m ReadOnlyMessage) IsValid() bool {
+	return m.msg != nil
+}
+
+// RootPtr returns the pointer to the message's root object.
+func (m ReadOnlyMessage) RootPtr() (ReadOnlyPtr, error) {
+	p, err := m.msg.RootPtr()
+	return ReadOnlyPtr{p}, err
+}
+
+// NumSegments returns the number of segments in the message.
+func (m ReadOnlyMessage) NumSegments() int64 {
+	return m.msg.NumSegments()
+}
+
+// Err returns the first error recorded by an error-free accessor.
+// See Message.Err.
+func (m ReadOnlyMessage) Err() error {
+	return m.msg.Err()
+}
+
+// WriteTo writes the message's stream encoding to w.
+func (m ReadOnlyMessage) WriteTo(w io.Writer) (int64, error) {
+	b, err := m.msg.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// ReadOnlyPtr is a read-only view of a Ptr.
+type ReadOnlyPtr struct {
+	p Ptr
+}
+
+// IsValid reports whether the pointer is non-null.
+func (p ReadOnlyPtr) IsValid() bool {
+	return p.p.IsValid()
+}
+
+// Struct converts the pointer to a struct view, returning an invalid
+// view if the pointer is not a struct.
+func (p ReadOnlyPtr) Struct() ReadOnlyStruct {
+	return ReadOnlyStruct{p.p.Struct()}
+}
+
+// List converts the pointer to a list view, returning an invalid view
+// if the pointer is not a list.
+func (p ReadOnlyPtr) List() ReadOnlyList {
+	return ReadOnlyList{p.p.List()}
+}
+
+// Interface converts the pointer to an interface pointer.
+func (p ReadOnlyPtr) Interface() Interface {
+	return p.p.Interface()
+}
+
+// Text returns the pointer as a string.  See Ptr.Text.
+func (p ReadOnlyPtr) Text() string {
+	return p.p.Text()
+}
+
+// TextBytes returns the pointer as a byte slice without the NUL
+// terminator.  See Ptr.TextBytes.
+func (p ReadOnlyPtr) TextBytes() []byte {
+	return p.p.TextBytes()
+}
+
+// Data returns the pointer as a byte slice.  See Ptr.Data.
+func (p ReadOnlyPtr) Data() []byte {
+	return p.p.Data()
+}
+
+// ReadOnlyStruct is a read-only view of a Struct.
+type ReadOnlyStruct struct {
+	s Struct
+}
+
+// IsValid reports whether the struct is valid.
+func (s ReadOnlyStruct) IsValid() bool {
+	return s.s.IsValid()
+}
+
+// Size returns the size of the struct.
+func (s ReadOnlyStruct) Size() ObjectSize {
+	return s.s.Size()
+}
+
+// Ptr returns the i'th pointer in the struct.
+func (s ReadOnlyStruct) Ptr(i uint16) (ReadOnlyPtr, error) {
+	p, err := s.s.Ptr(i)
+	return ReadOnlyPtr{p}, err
+}
+
+// ReadPtr returns the i'th pointer in the struct, recording any error
+// on the message.  See Struct.ReadPtr.
+func (s ReadOnlyStruct) ReadPtr(i uint16) ReadOnlyPtr {
+	return ReadOnlyPtr{s.s.ReadPtr(i)}
+}
+
+// Bit returns the bit that is n bits from the start of the struct.
+func (s ReadOnlyStruct) Bit(n BitOffset) bool {
+	return s.s.Bit(n)
+}
+
+// Uint8 returns an 8-bit integer from the struct's data section.
+func (s ReadOnlyStruct) Uint8(off DataOffset) uint8 {
+	return s.s.Uint8(off)
+}
+
+// Uint16 returns a 16-bit integer from the struct's data section.
+func (s ReadOnlyStruct) Uint16(off DataOffset) uint16 {
+	return s.s.Uint16(off)
+}
+
+// Uint32 returns a 32-bit integer from the struct's data section.
+func (s ReadOnlyStruct) Uint32(off DataOffset) uint32 {
+	return s.s.Uint32(off)
+}
+
+// Uint64 returns a 64-bit integer from the struct's data section.
+func (s ReadOnlyStruct) Uint64(off DataOffset) uint64 {
+	return s.s.Uint64(off)
+}
+
+// TextEquals reports whether the i'th pointer is a text equal to str.
+// See Struct.TextEquals.
+func (s ReadOnlyStruct) TextEquals(i uint16, str string) (bool, error) {
+	return s.s.TextEquals(i, str)
+}
+
+// DataEquals reports whether the i'th pointer is a data equal to b.
+// See Struct.DataEquals.
+func (s ReadOnlyStruct) DataEquals(i uint16, b []byte) (bool, error) {
+	return s.s.DataEquals(i, b)
+}
+
+// ReadOnlyList is a read-only view of a List.
+type ReadOnlyList struct {
+	l List
+}
+
+// IsValid reports whether the list is valid.
+func (l ReadOnlyList) IsValid() bool {
+	return l.l.IsValid()
+}
+
+// Len returns the length of the list.
+func (l ReadOnlyList) Len() int {
+	return l.l.Len()
+}
+
+// Struct returns the i'th element as a struct.
+func (l ReadOnlyList) Struct(i int) ReadOnlyStruct {
+	return ReadOnlyStruct{l.l.Struct(i)}
+}
+
+// PtrAt returns the i'th element of a list of pointers.
+func (l ReadOnlyList) PtrAt(i int) (ReadOnlyPtr, error) {
+	p, err := PointerList{l.l}.PtrAt(i)
+	return ReadOnlyPtr{p}, err
+}
+
+// BitAt returns the i'th element of a list of bits.
+func (l ReadOnlyList) BitAt(i int) bool {
+	return BitList{l.l}.At(i)
+}
+
+// Uint8At returns the i'th element of a list of 8-bit integers.
+func (l ReadOnlyList) Uint8At(i int) uint8 {
+	return UInt8List{l.l}.At(i)
+}
+
+// Uint16At returns the i'th element of a list of 16-bit integers.
+func (l ReadOnlyList) Uint16At(i int) uint16 {
+	return UInt16List{l.l}.At(i)
+}
+
+// Uint32At returns the i'th element of a list of 32-bit integers.
+func (l ReadOnlyList) Uint32At(i int) uint32 {
+	return UInt32List{l.l}.At(i)
+}
+
+// Uint64At returns the i'th element of a list of 64-bit integers.
+func (l ReadOnlyList) Uint64At(i int) uint64 {
+	return UInt64List{l.l}.At(i)
+}
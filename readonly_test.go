@@ -0,0 +1,70 @@
+package capnp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadOnlyMessage(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint32(4, 0xcafe)
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewUInt16List(seg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Set(2, 99)
+	if err := root.SetPtr(1, l.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := NewDecoder(bytes.NewReader(data)).DecodeReadOnly()
+	if err != nil {
+		t.Fatal("DecodeReadOnly:", err)
+	}
+	p, err := ro.RootPtr()
+	if err != nil {
+		t.Fatal("RootPtr:", err)
+	}
+	s := p.Struct()
+	if v := s.Uint32(4); v != 0xcafe {
+		t.Errorf("root.Uint32(4) = %#x; want 0xcafe", v)
+	}
+	if txt := s.ReadPtr(0).Text(); txt != "hello" {
+		t.Errorf("root.ReadPtr(0).Text() = %q; want \"hello\"", txt)
+	}
+	lp, err := s.Ptr(1)
+	if err != nil {
+		t.Fatal("root.Ptr(1):", err)
+	}
+	if n := lp.List().Len(); n != 3 {
+		t.Errorf("root.Ptr(1).List().Len() = %d; want 3", n)
+	}
+	if v := lp.List().Uint16At(2); v != 99 {
+		t.Errorf("root.Ptr(1).List().Uint16At(2) = %d; want 99", v)
+	}
+	if err := ro.Err(); err != nil {
+		t.Errorf("ro.Err() = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := ro.WriteTo(&buf); err != nil {
+		t.Fatal("WriteTo:", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("WriteTo wrote % 02x; want % 02x", buf.Bytes(), data)
+	}
+}
@@ -0,0 +1,119 @@
+package rpc_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestAnomalies(t *testing.T) {
+	tests := []struct {
+		name string
+		// send sends an anomalous message to a connection with a main
+		// interface.
+		send func(t *testing.T, p rpc.Transport) error
+	}{
+		{"duplicate finish", func(t *testing.T, p rpc.Transport) error {
+			_, qid := bootstrapRoundtrip(t, p)
+			for i := 0; i < 2; i++ {
+				err := sendMessage(context.TODO(), p, func(m rpccapnp.Message) error {
+					fin, err := m.NewFinish()
+					if err != nil {
+						return err
+					}
+					fin.SetQuestionId(qid)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"return for unknown question", func(t *testing.T, p rpc.Transport) error {
+			return sendMessage(context.TODO(), p, func(m rpccapnp.Message) error {
+				ret, err := m.NewReturn()
+				if err != nil {
+					return err
+				}
+				ret.SetAnswerId(42)
+				ret.SetCanceled()
+				return nil
+			})
+		}},
+		{"finish for unknown answer", func(t *testing.T, p rpc.Transport) error {
+			return sendMessage(context.TODO(), p, func(m rpccapnp.Message) error {
+				fin, err := m.NewFinish()
+				if err != nil {
+					return err
+				}
+				fin.SetQuestionId(42)
+				return nil
+			})
+		}},
+		{"excessive release", func(t *testing.T, p rpc.Transport) error {
+			id := sendBootstrapAndFinish(t, p)
+			return sendMessage(context.TODO(), p, func(m rpccapnp.Message) error {
+				rel, err := m.NewRelease()
+				if err != nil {
+					return err
+				}
+				rel.SetId(id)
+				rel.SetReferenceCount(2)
+				return nil
+			})
+		}},
+	}
+	for _, test := range tests {
+		for _, strict := range []bool{false, true} {
+			opts := []rpc.ConnOption{rpc.MainInterface(mockClient())}
+			if strict {
+				opts = append(opts, rpc.StrictAnomalies())
+			}
+			conn, p := newUnpairedConn(t, opts...)
+			if err := test.send(t, p); err != nil {
+				t.Errorf("%s (strict=%t): send: %v", test.name, strict, err)
+			} else if !strict {
+				if got := probe(t, p); got != rpccapnp.Message_Which_return {
+					t.Errorf("%s (strict=%t): conn replied to bootstrap with %v; want return", test.name, strict, got)
+				}
+			} else if got := recvWhich(t, p); got != rpccapnp.Message_Which_abort {
+				t.Errorf("%s (strict=%t): conn sent %v; want abort", test.name, strict, got)
+			}
+			p.Close()
+			conn.Close()
+		}
+	}
+}
+
+// probe sends a bootstrap message and returns the type of the next
+// message that the connection sends.
+func probe(t *testing.T, p rpc.Transport) rpccapnp.Message_Which {
+	err := sendMessage(context.TODO(), p, func(m rpccapnp.Message) error {
+		boot, err := m.NewBootstrap()
+		if err != nil {
+			return err
+		}
+		boot.SetQuestionId(77)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("send bootstrap:", err)
+	}
+	return recvWhich(t, p)
+}
+
+// recvWhich returns the type of the next message that the connection
+// sends.
+func recvWhich(t *testing.T, p rpc.Transport) rpccapnp.Message_Which {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("receive:", err)
+	}
+	return m.Which()
+}
@@ -6,13 +6,14 @@ import (
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
 	"zombiezen.com/go/capnproto2/internal/fulfiller"
 	"zombiezen.com/go/capnproto2/internal/queue"
 	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
-// callQueueSize is the maximum number of calls that can be queued per answer or client.
-// TODO(light): make this a ConnOption
+// callQueueSize is the default maximum number of calls that can be
+// queued per answer or client.
 const callQueueSize = 64
 
 // insertAnswer creates a new answer with the given ID, returning nil
@@ -28,7 +29,6 @@ func (c *Conn) insertAnswer(id answerID, cancel context.CancelFunc) *answer {
 		cancel:   cancel,
 		conn:     c,
 		resolved: make(chan struct{}),
-		queue:    make([]pcall, 0, callQueueSize),
 	}
 	c.answers[id] = a
 	return a
@@ -46,15 +46,60 @@ func (c *Conn) popAnswer(id answerID) *answer {
 type answer struct {
 	id         answerID
 	cancel     context.CancelFunc
+	timer      clock.Timer // nil if there is no answer timeout
 	resultCaps []exportID
+	method     *capnp.Method // nil for a bootstrap answer
 	conn       *Conn
 	resolved   chan struct{}
+	yourself   bool // the call was sent with sendResultsTo.yourself
+
+	mu       sync.RWMutex
+	obj      capnp.Ptr
+	err      error
+	done     bool
+	finished bool // the answer has been removed from the table
+	taken    bool // a takeFromOtherQuestion Return owns the results
+	queue    []pcall
+
+	// tailq is the question that the call was forwarded to with
+	// sendResultsTo.yourself, or nil.  Pipelined calls on the answer
+	// are made on tailq, since its results never come back here.
+	tailq *question
+}
 
-	mu    sync.RWMutex
-	obj   capnp.Ptr
-	err   error
-	done  bool
-	queue []pcall
+// maxInlineReply is the size in bytes of the largest results message
+// whose Return is allocated up front.
+const maxInlineReply = 8192
+
+// Sizes in bytes for estimating a Return's size.
+const (
+	returnOverhead    = 16 * 8 // root pointer, Message, Return, Payload, and capability table tag
+	capDescriptorSize = 2 * 8
+)
+
+// replyBuffer returns a buffer for a Return message holding the results
+// obj, big enough that the Return, its Payload, a copy of the results,
+// and the results' capability table fit in the message's first segment
+// without growing it.  A small reply is thus a single allocation and a
+// single write.  The results message was sized from the results size
+// that generated code gives the server, so its size is a good estimate
+// of the copy's.  replyBuffer returns nil for large or multi-segment
+// results, which grow their Return as usual.
+func replyBuffer(obj capnp.Ptr) []byte {
+	seg := obj.Segment()
+	if seg == nil {
+		return make([]byte, 0, returnOverhead)
+	}
+	msg := seg.Message()
+	if msg.NumSegments() != 1 {
+		return nil
+	}
+	size, err := msg.TotalSize()
+	if err != nil || size > maxInlineReply {
+		return nil
+	}
+	ncaps := uint64(len(msg.CapTable))
+	return make([]byte, 0, returnOverhead+size+ncaps*capDescriptorSize)
 }
 
 // fulfill is called to resolve an answer successfully.  It returns an
@@ -66,6 +111,7 @@ func (a *answer) fulfill(obj capnp.Ptr) error {
 		panic("answer.fulfill called more than once")
 	}
 	a.obj, a.done = obj, true
+	a.stopTimer()
 	// TODO(light): populate resultCaps
 
 	var firstErr error
@@ -76,17 +122,54 @@ func (a *answer) fulfill(obj capnp.Ptr) error {
 		}
 		a.queue = nil
 	} else {
-		retmsg := newReturnMessage(nil, a.id)
+		var buf []byte
+		if !a.yourself {
+			buf = replyBuffer(obj)
+		}
+		retmsg := newReturnMessage(buf, a.id)
 		ret, _ := retmsg.Return()
-		payload, _ := ret.NewResults()
-		payload.SetContentPtr(obj)
-		if payloadTab, err := a.conn.makeCapTable(ret.Segment()); err != nil {
-			firstErr = err
-		} else {
-			payload.SetCapTable(payloadTab)
+		if a.yourself {
+			// The results are kept until the caller names this call in
+			// a takeFromOtherQuestion Return.
+			ret.SetResultsSentElsewhere()
 			if err := a.conn.sendMessage(retmsg); err != nil {
 				firstErr = err
 			}
+		} else {
+			payload, _ := ret.NewResults()
+			payload.SetContentPtr(obj)
+			err := a.conn.inspectReturn(a.method, payload)
+			if err == nil {
+				_, err = a.conn.filterCaps(OutgoingResults, a.method, ret.Segment().Message().CapTable)
+			}
+			if err != nil {
+				excmsg := newReturnMessage(nil, a.id)
+				eret, _ := excmsg.Return()
+				setReturnException(eret, err)
+				if err := a.conn.sendMessage(excmsg); err != nil {
+					firstErr = err
+				}
+			} else if payloadTab, err := a.conn.makeCapTable(ret.Segment()); err == ErrExportQuota || isCapTableError(err) {
+				// The peer may not hold the results' capabilities, so it
+				// gets an exception instead.  Local pipelined calls still
+				// see the results.
+				if !a.conn.abortsOn(err) {
+					excmsg := newReturnMessage(nil, a.id)
+					eret, _ := excmsg.Return()
+					setReturnException(eret, err)
+					if err := a.conn.sendMessage(excmsg); err != nil {
+						firstErr = err
+					}
+				}
+			} else if err != nil {
+				firstErr = err
+			} else {
+				payload.SetCapTable(payloadTab)
+				a.conn.recordPayload(OutgoingResults, a.method, payload)
+				if err := a.conn.sendMessage(retmsg); err != nil {
+					firstErr = err
+				}
+			}
 		}
 
 		queues, err := a.emptyQueue(obj)
@@ -100,10 +183,50 @@ func (a *answer) fulfill(obj capnp.Ptr) error {
 		a.conn.workers.Done()
 	}
 	close(a.resolved)
+	release := a.finished && !a.taken
 	a.mu.Unlock()
+	if release {
+		releaseResults(obj)
+	}
 	return firstErr
 }
 
+// takeFrom resolves an answer whose call was forwarded back to the
+// peer as q with sendResultsTo.yourself, after the peer has reported
+// that it kept the results.  The Return tells the peer to use them as
+// the results of this answer, so they never cross the connection.  The
+// caller must be holding onto a.conn.mu.
+func (a *answer) takeFrom(q *question) error {
+	a.mu.Lock()
+	if a.done {
+		panic("answer.takeFrom called on resolved answer")
+	}
+	a.done = true
+	a.stopTimer()
+	m := newReturnMessage(nil, a.id)
+	ret, _ := m.Return()
+	ret.SetTakeFromOtherQuestion(uint32(q.id))
+	err := a.conn.sendMessage(m)
+	close(a.resolved)
+	a.mu.Unlock()
+	return err
+}
+
+// take hands the results of an answer whose call was sent with
+// sendResultsTo.yourself to a question of the peer's that named it in a
+// takeFromOtherQuestion Return.  The question then owns the results.
+// done reports whether the answer has been resolved; if not, the
+// question is resolved by joinTakenAnswer once it is.
+func (a *answer) take() (obj capnp.Ptr, err error, done bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.yourself || a.taken {
+		return capnp.Ptr{}, errBadTakeFrom, true
+	}
+	a.taken = true
+	return a.obj, a.err, a.done
+}
+
 // reject is called to resolve an answer with failure.  It returns an
 // error if its connection is shut down while sending messages.  The
 // caller must be holding onto a.conn.mu.
@@ -116,6 +239,7 @@ func (a *answer) reject(err error) error {
 		panic("answer.reject called more than once")
 	}
 	a.err, a.done = err, true
+	a.stopTimer()
 	m := newReturnMessage(nil, a.id)
 	mret, _ := m.Return()
 	setReturnException(mret, err)
@@ -134,6 +258,38 @@ func (a *answer) reject(err error) error {
 	return firstErr
 }
 
+// finish records that the answer has been removed from the
+// connection's table, by a Finish message or by the connection shutting
+// down.  It returns the results to release with releaseResults once the
+// caller is no longer holding a.conn.mu, or a null pointer if the
+// answer has not been fulfilled, in which case fulfill releases them.
+// Results are not released earlier, since pipelined calls read them
+// until the answer is finished.
+func (a *answer) finish() capnp.Ptr {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.finished = true
+	if !a.done || a.err != nil || a.taken {
+		return capnp.Ptr{}
+	}
+	return a.obj
+}
+
+// releaseResults releases the message holding an answer's results,
+// if the answer owns it.  See capnp.Message.SetAnswerOwned.
+func releaseResults(obj capnp.Ptr) {
+	if seg := obj.Segment(); seg != nil {
+		seg.Message().ReleaseAnswer()
+	}
+}
+
+// stopTimer stops the answer's timeout, if any.
+func (a *answer) stopTimer() {
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+}
+
 // emptyQueue splits the queue by which capability it targets
 // and drops any invalid calls.  Once this function returns, a.queue
 // will be nil.
@@ -170,7 +326,7 @@ func (a *answer) emptyQueue(obj capnp.Ptr) (map[capnp.CapabilityID][]qcall, erro
 // transform and one of pc.a or pc.f to be set.  The caller must be
 // holding onto a.mu.
 func (a *answer) queueCallLocked(call *capnp.Call, pc pcall) error {
-	if len(a.queue) == cap(a.queue) {
+	if len(a.queue) >= a.conn.maxQueued {
 		return errQueueFull
 	}
 	var err error
@@ -186,6 +342,11 @@ func (a *answer) queueCallLocked(call *capnp.Call, pc pcall) error {
 func (a *answer) queueDisembargo(transform []capnp.PipelineOp, id embargoID, target rpccapnp.MessageTarget) (queued bool, err error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	if a.tailq != nil {
+		// Pipelined calls were forwarded to the peer as they arrived,
+		// so they have all been delivered.
+		return false, nil
+	}
 	if !a.done {
 		return false, errDisembargoOngoingAnswer
 	}
@@ -202,7 +363,7 @@ func (a *answer) queueDisembargo(transform []capnp.PipelineOp, id embargoID, tar
 		// No need to embargo, disembargo immediately.
 		return false, nil
 	}
-	if ic := isImport(qc.client); ic == nil || a.conn != ic.conn {
+	if importConn(qc.client) != a.conn {
 		return false, errDisembargoNonImport
 	}
 	qc.mu.Lock()
@@ -220,11 +381,35 @@ func (a *answer) pipelineClient(transform []capnp.PipelineOp) capnp.Client {
 	return &localAnswerClient{a: a, transform: transform}
 }
 
+// tailClient returns the client for transform in the results of the
+// question the answer's call was forwarded to with
+// sendResultsTo.yourself, or nil if it was not forwarded.  The caller
+// must be holding onto a.mu.
+func (a *answer) tailClient(transform []capnp.PipelineOp) capnp.Client {
+	if a.tailq == nil {
+		return nil
+	}
+	p := capnp.NewPipeline(a.tailq)
+	for _, op := range transform {
+		p = p.GetPipelineDefault(op.Field, op.DefaultValue)
+	}
+	return p.Client()
+}
+
 // joinAnswer resolves an RPC answer by waiting on a generic answer.
-// The caller must not be holding onto a.conn.mu.
+// If a has been linked to a tail question other than ca in the
+// meantime, a is resolved through the tail question instead.  The
+// caller must not be holding onto a.conn.mu.
 func joinAnswer(a *answer, ca capnp.Answer) {
 	s, err := ca.Struct()
 	a.conn.mu.Lock()
+	a.mu.RLock()
+	tailq := a.tailq
+	a.mu.RUnlock()
+	if tailq != nil && capnp.Answer(tailq) != ca {
+		a.conn.mu.Unlock()
+		return
+	}
 	if err == nil {
 		a.fulfill(s.ToPtr())
 	} else {
@@ -233,6 +418,30 @@ func joinAnswer(a *answer, ca capnp.Answer) {
 	a.conn.mu.Unlock()
 }
 
+// joinTakenAnswer resolves q, which the peer answered by naming a, with
+// a's results once a is resolved.  The caller must not be holding onto
+// a.conn.mu.
+func joinTakenAnswer(q *question, a *answer) {
+	select {
+	case <-a.resolved:
+	case <-a.conn.bg.Done():
+		return
+	}
+	a.mu.RLock()
+	obj, err := a.obj, a.err
+	a.mu.RUnlock()
+	c := a.conn
+	c.mu.Lock()
+	if c.startWork() != nil {
+		// teardown cancels the question.
+		c.mu.Unlock()
+		return
+	}
+	c.finishTakenQuestion(q, obj, err)
+	c.workers.Done()
+	c.mu.Unlock()
+}
+
 // joinFulfiller resolves a fulfiller by waiting on a generic answer.
 func joinFulfiller(f *fulfiller.Fulfiller, ca capnp.Answer) {
 	s, err := ca.Struct()
@@ -256,7 +465,7 @@ func newQueueClient(c *Conn, client capnp.Client, queue []qcall) *queueClient {
 	qc := &queueClient{
 		client: client,
 		conn:   c,
-		calls:  make(qcallList, callQueueSize),
+		calls:  make(qcallList, c.maxQueued),
 	}
 	qc.q.Init(qc.calls, copy(qc.calls, queue))
 	go qc.flushQueue()
@@ -460,6 +669,10 @@ type localAnswerClient struct {
 
 func (lac *localAnswerClient) Call(call *capnp.Call) capnp.Answer {
 	lac.a.mu.Lock()
+	if client := lac.a.tailClient(lac.transform); client != nil {
+		lac.a.mu.Unlock()
+		return client.Call(call)
+	}
 	if lac.a.done {
 		obj, err := lac.a.obj, lac.a.err
 		lac.a.mu.Unlock()
@@ -480,8 +693,9 @@ func (lac *localAnswerClient) Call(call *capnp.Call) capnp.Answer {
 func (lac *localAnswerClient) Close() error {
 	lac.a.mu.RLock()
 	obj, err, done := lac.a.obj, lac.a.err, lac.a.done
+	tail := lac.a.tailq != nil
 	lac.a.mu.RUnlock()
-	if !done {
+	if !done || tail {
 		return nil
 	}
 	client := clientFromResolution(lac.transform, obj, err)
@@ -490,6 +704,7 @@ func (lac *localAnswerClient) Close() error {
 
 var (
 	errQueueFull       = errors.New("rpc: pipeline queue full")
+	errPipelineTooDeep = errors.New("rpc: pipelined call too deep")
 	errQueueCallCancel = errors.New("rpc: queued call canceled")
 
 	errDisembargoOngoingAnswer = errors.New("rpc: disembargo attempted on in-progress answer")
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestReplyBuffer(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := capnp.NewRootStruct(seg, capnp.ObjectSize{DataSize: 8, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results.SetUint64(0, 42)
+	text, err := capnp.NewText(seg, "Hello, World!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := results.SetPtr(0, text.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	id := seg.Message().AddCap(capnp.ErrorClient(errShutdown))
+	if err := results.SetPtr(1, capnp.NewInterface(seg, id).ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := replyBuffer(results.ToPtr())
+	retmsg := newReturnMessage(buf, 1)
+	ret, _ := retmsg.Return()
+	payload, err := ret.NewResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := payload.SetContentPtr(results.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	ctab, err := payload.NewCapTable(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctab.At(0).SetSenderHosted(0)
+	if data := retmsg.Segment().Data(); cap(data) != cap(buf) {
+		t.Errorf("Return grew from %d to %d bytes; want no growth", cap(buf), cap(data))
+	}
+}
+
+func TestReplyBuffer_Large(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := capnp.NewData(seg, make([]byte, maxInlineReply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf := replyBuffer(data.ToPtr()); buf != nil {
+		t.Errorf("replyBuffer(%d bytes) has capacity %d; want nil", maxInlineReply, cap(buf))
+	}
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc/internal/refcount"
+)
+
+// LazyMainInterface specifies that the connection should call newClient
+// to create the capability for bootstrap messages when the first one is
+// received, instead of requiring it up front like MainInterface.  The
+// connection owns the clients that newClient returns.  By default, the
+// first client is shared by every later bootstrap message until it goes
+// stale; see BootstrapStale.  If newClient returns an error, the
+// bootstrap fails and the next one calls newClient again.
+//
+// Like BootstrapFunc, newClient should not make any RPCs or block.
+func LazyMainInterface(newClient func(context.Context) (capnp.Client, error), opts ...BootstrapOption) ConnOption {
+	return ConnOption{func(c *connParams) {
+		m := &lazyMain{
+			newClient: newClient,
+			stale:     capnp.IsErrorClient,
+		}
+		for _, o := range opts {
+			o.f(m)
+		}
+		c.mainFunc = m.bootstrap
+		c.mainCloser = m
+	}}
+}
+
+// A BootstrapOption controls how LazyMainInterface reuses the clients
+// that its factory creates.
+type BootstrapOption struct {
+	f func(*lazyMain)
+}
+
+// BootstrapPerCall makes every bootstrap message call the factory, so
+// each bootstrap gets a client of its own.
+func BootstrapPerCall() BootstrapOption {
+	return BootstrapOption{func(m *lazyMain) {
+		m.perCall = true
+	}}
+}
+
+// BootstrapStale sets the function that reports whether the shared
+// client can no longer be used, for example because it was revoked.
+// The next bootstrap message closes a stale client and calls the
+// factory for a new one; bootstraps that already received the old
+// client keep it.  By default, a client is stale only if it is an error
+// client (see capnp.IsErrorClient).
+func BootstrapStale(stale func(capnp.Client) bool) BootstrapOption {
+	return BootstrapOption{func(m *lazyMain) {
+		m.stale = stale
+	}}
+}
+
+// lazyMain creates and caches the client for LazyMainInterface.
+// The connection calls bootstrap with its lock held and stops calling
+// it before calling Close, so lazyMain needs no lock of its own.
+type lazyMain struct {
+	newClient func(context.Context) (capnp.Client, error)
+	perCall   bool
+	stale     func(capnp.Client) bool
+
+	main *refcount.Ref // nil until the first bootstrap
+}
+
+func (m *lazyMain) bootstrap(ctx context.Context) (capnp.Client, error) {
+	if m.perCall {
+		return m.newClient(ctx)
+	}
+	if m.main != nil && m.stale(m.main.Client()) {
+		m.main.Close()
+		m.main = nil
+	}
+	if m.main == nil {
+		client, err := m.newClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.main = ownRef(client)
+	}
+	return m.main.Snapshot(), nil
+}
+
+// Close releases the shared client.
+func (m *lazyMain) Close() error {
+	if m.main == nil {
+		return nil
+	}
+	err := m.main.Close()
+	m.main = nil
+	return err
+}
@@ -0,0 +1,88 @@
+package rpc_test
+
+import (
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+// countingFactory is a bootstrap factory that counts how many clients
+// it has made.
+type countingFactory struct {
+	mu    sync.Mutex
+	n     int
+	stale bool
+}
+
+func (f *countingFactory) newClient(ctx context.Context) (capnp.Client, error) {
+	f.mu.Lock()
+	f.n++
+	f.stale = false
+	f.mu.Unlock()
+	return testcapnp.HandleFactory_ServerToClient(new(HandleFactory)).Client, nil
+}
+
+func (f *countingFactory) isStale(capnp.Client) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stale
+}
+
+func (f *countingFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.n
+}
+
+func TestLazyMainInterface(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []rpc.BootstrapOption
+		revoke bool
+		want   [3]int // factory calls after each bootstrap
+	}{
+		{name: "shared", want: [3]int{1, 1, 1}},
+		{name: "per call", opts: []rpc.BootstrapOption{rpc.BootstrapPerCall()}, want: [3]int{1, 2, 3}},
+		{name: "rebuild stale", revoke: true, want: [3]int{1, 2, 3}},
+	}
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		p, q := pipetransport.New()
+		if *logMessages {
+			p = logtransport.New(nil, p)
+		}
+		log := testLogger{t}
+		f := new(countingFactory)
+		opts := append(test.opts, rpc.BootstrapStale(f.isStale))
+		c := rpc.NewConn(p, rpc.ConnLog(log))
+		d := rpc.NewConn(q, rpc.ConnLog(log), rpc.LazyMainInterface(f.newClient, opts...))
+		if n := f.count(); n != 0 {
+			t.Errorf("%s: factory called %d times before bootstrap; want 0", test.name, n)
+		}
+		for i, want := range test.want {
+			client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+			_, err := client.NewHandle(ctx, nil).Struct()
+			if err != nil {
+				t.Errorf("%s: NewHandle #%d: %v", test.name, i+1, err)
+			}
+			if n := f.count(); n != want {
+				t.Errorf("%s: after bootstrap #%d, factory called %d times; want %d", test.name, i+1, n, want)
+			}
+			client.Client.Close()
+			if test.revoke {
+				f.mu.Lock()
+				f.stale = true
+				f.mu.Unlock()
+			}
+		}
+		c.Close()
+		d.Close()
+		cancel()
+	}
+}
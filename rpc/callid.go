@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+)
+
+// QuestionID returns the question ID that the connection assigned to
+// the call that produced ans.  The vat that receives the call sees the
+// same ID through QuestionIDFromContext, so the two sides of a
+// connection can correlate their logs.  ok is false if ans is not an
+// outstanding question on a connection, as is the case for local calls,
+// failed calls, and calls queued on an unresolved promise.
+//
+// Question IDs are scoped to a connection and are reused once a call
+// finishes, so logs should record the ID alongside the connection and
+// the time of the call.
+func QuestionID(ans capnp.Answer) (id uint32, ok bool) {
+	q, ok := ans.(*question)
+	if !ok {
+		return 0, false
+	}
+	return uint32(q.id), true
+}
+
+// QuestionIDFromContext returns the question ID of the incoming call
+// whose context is ctx.  It is the same ID that the caller sees through
+// QuestionID.  ok is false if ctx does not belong to a call received
+// by a Conn.
+func QuestionIDFromContext(ctx context.Context) (id uint32, ok bool) {
+	id, ok = ctx.Value(questionIDKey{}).(uint32)
+	return
+}
+
+// questionIDKey is the context key for the question ID of an incoming
+// call.
+type questionIDKey struct{}
+
+func withQuestionID(ctx context.Context, id answerID) context.Context {
+	return context.WithValue(ctx, questionIDKey{}, uint32(id))
+}
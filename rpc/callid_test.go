@@ -0,0 +1,71 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+func TestQuestionID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	ids := make(chan questionIDResult, 2)
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(questionIDRecorder(ids)).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+
+	for i := 0; i < 2; i++ {
+		ans := client.NewHandle(ctx, nil)
+		if _, err := ans.Struct(); err != nil {
+			t.Fatal("NewHandle:", err)
+		}
+		clientID, ok := rpc.QuestionID(ans.Answer())
+		if !ok {
+			t.Fatal("QuestionID(ans) not ok")
+		}
+		server := <-ids
+		if !server.ok {
+			t.Fatal("QuestionIDFromContext not ok")
+		}
+		if clientID != server.id {
+			t.Errorf("call #%d: QuestionID = %d; server saw %d", i+1, clientID, server.id)
+		}
+		ans.Close()
+	}
+}
+
+func TestQuestionID_Local(t *testing.T) {
+	if _, ok := rpc.QuestionID(capnp.ErrorAnswer(rpc.ErrConnClosed)); ok {
+		t.Error("QuestionID(ErrorAnswer) ok = true; want false")
+	}
+	if _, ok := rpc.QuestionIDFromContext(context.Background()); ok {
+		t.Error("QuestionIDFromContext(Background) ok = true; want false")
+	}
+}
+
+type questionIDResult struct {
+	id uint32
+	ok bool
+}
+
+type questionIDRecorder chan<- questionIDResult
+
+func (r questionIDRecorder) NewHandle(call testcapnp.HandleFactory_newHandle) error {
+	server.Ack(call.Options)
+	id, ok := rpc.QuestionIDFromContext(call.Ctx)
+	r <- questionIDResult{id, ok}
+	return nil
+}
@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"fmt"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// A PayloadKind identifies the payload that a CapFilter is inspecting.
+type PayloadKind int
+
+// Payload kinds.
+const (
+	// OutgoingParams is the params of a call made on the peer.
+	OutgoingParams PayloadKind = iota
+	// OutgoingResults is the results returned to the peer for a call
+	// that it made.
+	OutgoingResults
+	// IncomingParams is the params of a call that the peer made.
+	IncomingParams
+	// IncomingResults is the results that the peer returned for a call
+	// made on it.
+	IncomingResults
+)
+
+// String returns the kind's name.
+func (k PayloadKind) String() string {
+	switch k {
+	case OutgoingParams:
+		return "outgoing params"
+	case OutgoingResults:
+		return "outgoing results"
+	case IncomingParams:
+		return "incoming params"
+	case IncomingResults:
+		return "incoming results"
+	default:
+		return fmt.Sprintf("PayloadKind(%d)", int(k))
+	}
+}
+
+// A CapFilter decides what happens to a capability in a payload that
+// is crossing the connection.  It is called once for each non-null
+// capability in the payload's table.  method is the method of the call
+// that the payload belongs to, or nil for a bootstrap capability; only
+// its InterfaceID and MethodID are set for incoming params.
+//
+// The filter returns the client to send or deliver in place of c: c
+// itself to let it through, nil to strip it, or another client to
+// replace it.  Returning an error denies the whole payload: an outgoing
+// call fails with the error, an incoming call or outgoing results are
+// answered with it as an exception, and incoming results reject the
+// question.
+//
+// For outgoing payloads, the connection never closes c; the client that
+// the filter returns is exported in its place.  For incoming payloads,
+// c was created by the connection, which closes it if the filter
+// returns a different client, and the returned client is owned by the
+// payload's message like c would have been.
+//
+// The filter is called while the connection's lock is held, so it must
+// not block or make calls on the connection.
+type CapFilter func(kind PayloadKind, method *capnp.Method, c capnp.Client) (capnp.Client, error)
+
+// FilterCapabilities specifies a filter that every capability sent or
+// received in a call's params or results passes through.  It is a
+// coarse-grained membrane: enough to strip, replace, or deny the
+// capabilities that flow through particular methods.  By default,
+// capabilities are not filtered.
+func FilterCapabilities(f CapFilter) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.capFilter = f
+	}}
+}
+
+// filterCaps passes the clients in ctab through the connection's
+// filter, replacing them in place.  For incoming payloads, it returns
+// the clients that were replaced, which the caller must close without
+// holding onto c.mu.  If the filter denies the payload, ctab is left
+// unchanged.  The caller holds onto c.mu.
+func (c *Conn) filterCaps(kind PayloadKind, method *capnp.Method, ctab []capnp.Client) (replaced []capnp.Client, err error) {
+	if c.capFilter == nil || len(ctab) == 0 {
+		return nil, nil
+	}
+	out := make([]capnp.Client, len(ctab))
+	for i, client := range ctab {
+		if client == nil {
+			continue
+		}
+		if out[i], err = c.capFilter(kind, method, client); err != nil {
+			// Clients that the filter already returned won't be
+			// delivered, so they are closed for incoming payloads like
+			// the rest of the table.
+			if kind == IncomingParams || kind == IncomingResults {
+				for j, rc := range out[:i] {
+					if rc != nil && rc != ctab[j] {
+						replaced = append(replaced, rc)
+					}
+				}
+			}
+			return replaced, err
+		}
+	}
+	for i, client := range ctab {
+		if out[i] == client {
+			continue
+		}
+		if client != nil && (kind == IncomingParams || kind == IncomingResults) {
+			replaced = append(replaced, client)
+		}
+		ctab[i] = out[i]
+	}
+	return replaced, nil
+}
@@ -0,0 +1,194 @@
+package rpc_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestFilterCapabilities(t *testing.T) {
+	errDenied := errors.New("denied")
+	errReplaced := errors.New("replaced")
+	tests := []struct {
+		name string
+		// filterC and filterD are called for the echo call's
+		// capabilities on the caller's and the echoer's connection.
+		filterC, filterD func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error)
+		// err is a substring of the echo call's error, or empty if the
+		// call succeeds.
+		err string
+		// seq is the error of calling getCallSequence on the echoed
+		// capability: "" if it reaches the CallOrder, "null" if the
+		// capability was stripped.
+		seq string
+	}{
+		{
+			name: "pass",
+		},
+		{
+			name: "strip outgoing params",
+			filterC: func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error) {
+				if kind == rpc.OutgoingParams {
+					return nil, nil
+				}
+				return c, nil
+			},
+			seq: "null",
+		},
+		{
+			name: "replace incoming params",
+			filterD: func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error) {
+				if kind == rpc.IncomingParams {
+					return capnp.ErrorClient(errReplaced), nil
+				}
+				return c, nil
+			},
+			seq: errReplaced.Error(),
+		},
+		{
+			name: "deny incoming params",
+			filterD: func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error) {
+				if kind == rpc.IncomingParams {
+					return nil, errDenied
+				}
+				return c, nil
+			},
+			err: errDenied.Error(),
+		},
+		{
+			name: "deny outgoing results",
+			filterD: func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error) {
+				if kind == rpc.OutgoingResults {
+					return nil, errDenied
+				}
+				return c, nil
+			},
+			err: errDenied.Error(),
+		},
+		{
+			name: "deny incoming results",
+			filterC: func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error) {
+				if kind == rpc.IncomingResults {
+					return nil, errDenied
+				}
+				return c, nil
+			},
+			err: errDenied.Error(),
+		},
+	}
+	for _, test := range tests {
+		func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fc := newRecordingFilter(test.filterC)
+			fd := newRecordingFilter(test.filterD)
+			p, q := pipetransport.New()
+			if *logMessages {
+				p = logtransport.New(nil, p)
+			}
+			log := testLogger{t}
+			c := rpc.NewConn(p, rpc.ConnLog(log), rpc.FilterCapabilities(fc.filter))
+			echoSrv := testcapnp.Echoer_ServerToClient(new(Echoer))
+			d := rpc.NewConn(q, rpc.MainInterface(echoSrv.Client), rpc.ConnLog(log), rpc.FilterCapabilities(fd.filter))
+			defer d.Wait()
+			defer c.Close()
+			echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+			order := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+			ans := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+				return p.SetCap(order)
+			})
+			defer ans.Close()
+			res, err := ans.Struct()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("%s: echo error = %v; want %q", test.name, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("%s: echo: %v", test.name, err)
+				return
+			}
+			_, err = res.Cap().GetCallSequence(ctx, nil).Struct()
+			switch {
+			case test.seq == "" && err != nil:
+				t.Errorf("%s: getCallSequence: %v", test.name, err)
+			case test.seq == "null" && err == nil:
+				t.Errorf("%s: getCallSequence succeeded; want null capability", test.name)
+			case test.seq != "" && test.seq != "null" && (err == nil || !strings.Contains(err.Error(), test.seq)):
+				t.Errorf("%s: getCallSequence error = %v; want %q", test.name, err, test.seq)
+			}
+
+			// Each connection sees the echo's capability on its way in
+			// and out, with the echo method.
+			if got, want := fc.kinds(), []rpc.PayloadKind{rpc.OutgoingParams}; !hasKinds(got, want) {
+				t.Errorf("%s: caller filtered %v; want to include %v", test.name, got, want)
+			}
+			if test.seq != "null" {
+				if got, want := fd.kinds(), []rpc.PayloadKind{rpc.IncomingParams, rpc.OutgoingResults}; !hasKinds(got, want) {
+					t.Errorf("%s: echoer filtered %v; want to include %v", test.name, got, want)
+				}
+			}
+		}()
+	}
+}
+
+// A recordingFilter records the kinds of the payloads that it filters
+// for echo calls.  Bootstrap capabilities are always let through.
+type recordingFilter struct {
+	f func(kind rpc.PayloadKind, c capnp.Client) (capnp.Client, error)
+
+	mu   sync.Mutex
+	seen []rpc.PayloadKind
+}
+
+func newRecordingFilter(f func(rpc.PayloadKind, capnp.Client) (capnp.Client, error)) *recordingFilter {
+	return &recordingFilter{f: f}
+}
+
+func (rf *recordingFilter) filter(kind rpc.PayloadKind, method *capnp.Method, c capnp.Client) (capnp.Client, error) {
+	if method == nil {
+		return c, nil
+	}
+	if method.InterfaceID != testcapnp.Echoer_TypeID {
+		return c, nil
+	}
+	rf.mu.Lock()
+	rf.seen = append(rf.seen, kind)
+	rf.mu.Unlock()
+	if rf.f == nil {
+		return c, nil
+	}
+	return rf.f(kind, c)
+}
+
+func (rf *recordingFilter) kinds() []rpc.PayloadKind {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return append([]rpc.PayloadKind(nil), rf.seen...)
+}
+
+func hasKinds(got, want []rpc.PayloadKind) bool {
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if g == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import "fmt"
+
+// A CapTablePolicy controls what a connection does when it can't
+// describe one of the capabilities in an outgoing message's capability
+// table, for example because a promise's descriptor couldn't be
+// allocated.  Export quota errors are controlled by the ExportQuota
+// instead.
+type CapTablePolicy int
+
+// Capability table policies.
+const (
+	// SendNull logs the failure and sends a null capability in place of
+	// the one that failed.  The rest of the message is sent as usual.
+	SendNull CapTablePolicy = iota
+
+	// FailPayload fails the message instead: an outgoing call returns
+	// a *CapTableError and an incoming call returns an exception to
+	// the peer.
+	FailPayload
+
+	// AbortConn aborts the connection with a *CapTableError.
+	AbortConn
+)
+
+// String returns the policy's name.
+func (p CapTablePolicy) String() string {
+	switch p {
+	case SendNull:
+		return "send null"
+	case FailPayload:
+		return "fail payload"
+	case AbortConn:
+		return "abort connection"
+	default:
+		return fmt.Sprintf("CapTablePolicy(%d)", int(p))
+	}
+}
+
+// OnCapTableError sets the connection's policy for capabilities that
+// it can't send.  The default is SendNull.
+func OnCapTableError(p CapTablePolicy) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.capPolicy = p
+	}}
+}
+
+// A CapTableError is the error for a capability that a connection
+// couldn't send.
+type CapTableError struct {
+	// Index is the capability's index in the message's capability
+	// table.
+	Index int
+	Err   error
+}
+
+func (e *CapTableError) Error() string {
+	return fmt.Sprintf("rpc: capability %d: %v", e.Index, e.Err)
+}
+
+func isCapTableError(err error) bool {
+	_, ok := err.(*CapTableError)
+	return ok
+}
+
+// abortsOn reports whether makeCapTable aborts the connection when it
+// fails with err.
+func (c *Conn) abortsOn(err error) bool {
+	if err == ErrExportQuota {
+		return c.expQuota.Abort
+	}
+	return c.capPolicy == AbortConn
+}
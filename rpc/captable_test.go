@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestMakeCapTablePolicy(t *testing.T) {
+	tests := []struct {
+		policy    CapTablePolicy
+		fail      bool
+		abort     bool
+		nExported int
+	}{
+		{policy: SendNull, nExported: 1},
+		{policy: FailPayload, fail: true},
+		{policy: AbortConn, fail: true, abort: true},
+	}
+	for _, test := range tests {
+		c := NewConn(newNullTransport(), OnCapTableError(test.policy), ConnLog(tlog{t}))
+		ctx, cancel := context.WithCancel(context.Background())
+		promise := c.Bootstrap(ctx)
+
+		// The segment has room for the descriptor list, but not for the
+		// promised answer that describes the bootstrap promise.
+		msg, seg, err := capnp.NewMessage(&fixedArena{buf: make([]byte, 0, 48)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg.AddCap(capnp.ErrorClient(errors.New("local")))
+		msg.AddCap(promise)
+
+		c.mu.Lock()
+		tab, err := c.makeCapTable(seg)
+		nExported := 0
+		for _, e := range c.exports {
+			if e != nil {
+				nExported++
+			}
+		}
+		c.mu.Unlock()
+		if test.fail {
+			ce, ok := err.(*CapTableError)
+			if !ok {
+				t.Errorf("%v: makeCapTable error = %v; want *CapTableError", test.policy, err)
+			} else if ce.Index != 1 {
+				t.Errorf("%v: CapTableError.Index = %d; want 1", test.policy, ce.Index)
+			}
+		} else if err != nil {
+			t.Errorf("%v: makeCapTable: %v", test.policy, err)
+		} else {
+			if w := tab.At(0).Which(); w != rpccapnp.CapDescriptor_Which_senderHosted {
+				t.Errorf("%v: descriptor 0 is %v; want senderHosted", test.policy, w)
+			}
+			if w := tab.At(1).Which(); w != rpccapnp.CapDescriptor_Which_none {
+				t.Errorf("%v: descriptor 1 is %v; want none", test.policy, w)
+			}
+		}
+		if nExported != test.nExported {
+			t.Errorf("%v: %d exports after makeCapTable; want %d", test.policy, nExported, test.nExported)
+		}
+
+		select {
+		case <-c.Done():
+			if !test.abort {
+				t.Errorf("%v: connection aborted", test.policy)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if test.abort {
+				t.Errorf("%v: connection not aborted", test.policy)
+			}
+		}
+		cancel()
+		c.Close()
+	}
+}
+
+// tlog is a Logger that logs to a test.
+type tlog struct {
+	t *testing.T
+}
+
+func (l tlog) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.t.Logf("conn log: "+format, args...)
+}
+
+func (l tlog) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.t.Logf("conn log: "+format, args...)
+}
+
+// nullTransport drops the messages that are sent on it and never
+// receives any.
+type nullTransport struct {
+	closed chan struct{}
+}
+
+func newNullTransport() *nullTransport {
+	return &nullTransport{closed: make(chan struct{})}
+}
+
+func (nt *nullTransport) SendMessage(ctx context.Context, msg rpccapnp.Message) error {
+	return nil
+}
+
+func (nt *nullTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, error) {
+	select {
+	case <-nt.closed:
+		return rpccapnp.Message{}, errors.New("transport closed")
+	case <-ctx.Done():
+		return rpccapnp.Message{}, ctx.Err()
+	}
+}
+
+func (nt *nullTransport) Close() error {
+	close(nt.closed)
+	return nil
+}
+
+// fixedArena is a single-segment arena that can't grow past the
+// capacity of its buffer.
+type fixedArena struct {
+	buf []byte
+}
+
+func (fa *fixedArena) NumSegments() int64 {
+	return 1
+}
+
+func (fa *fixedArena) Data(id capnp.SegmentID) ([]byte, error) {
+	if id != 0 {
+		return nil, errors.New("segment out of bounds")
+	}
+	return fa.buf, nil
+}
+
+func (fa *fixedArena) Allocate(sz capnp.Size, segs map[capnp.SegmentID]*capnp.Segment) (capnp.SegmentID, []byte, error) {
+	data := fa.buf
+	if segs[0] != nil {
+		data = segs[0].Data()
+	}
+	if cap(data)-len(data) < int(sz) {
+		return 0, nil, errors.New("arena full")
+	}
+	return 0, data, nil
+}
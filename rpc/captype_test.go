@@ -0,0 +1,157 @@
+package rpc_test
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/captype"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestCheckCapabilityTypes_Results(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	ft := &finishTransport{Transport: p}
+	log := testLogger{t}
+	c := rpc.NewConn(ft, rpc.ConnLog(log), rpc.CheckCapabilityTypes(new(captype.Checker)))
+	echoSrv := testcapnp.Echoer_ServerToClient(new(Echoer))
+	d := rpc.NewConn(q, rpc.MainInterface(echoSrv.Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	// The echoer returns the capability it was given, which comes back
+	// to c as its own export, so c can check its type.
+	order := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	ans := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(order)
+	})
+	if _, err := ans.Struct(); err != nil {
+		t.Error("echo(CallOrder):", err)
+	}
+	ans.Close()
+
+	adder := testcapnp.Adder_ServerToClient(AdderServer{})
+	ans = echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: adder.Client})
+	})
+	if _, err := ans.Struct(); !captype.IsMismatch(err) {
+		t.Errorf("echo(Adder) error = %v; want mismatch", err)
+	}
+	flushConn(ctx, c)
+
+	// The mismatched capabilities are released by closing them, so the
+	// Finish must not release them again.
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	if ft.finishes == 0 {
+		t.Fatal("no Finish messages sent")
+	}
+	if ft.releases > 0 {
+		t.Errorf("%d Finish messages set releaseResultCaps; want 0", ft.releases)
+	}
+}
+
+func TestCheckCapabilityTypes_Imports(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log), rpc.CheckCapabilityTypes(new(captype.Checker)))
+	srv := new(adderEchoer)
+	echoSrv := testcapnp.Echoer_ServerToClient(srv)
+	d := rpc.NewConn(q, rpc.MainInterface(echoSrv.Client), rpc.ConnLog(log), rpc.CheckCapabilityTypes(new(captype.Checker)))
+	defer d.Wait()
+	defer c.Close()
+	echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	// The results hold an Adder that d exports as a CallOrder.  To c,
+	// it is an import, so c can't check it.
+	ans := echo.Echo(ctx, nil)
+	res, err := ans.Struct()
+	if err != nil {
+		t.Fatal("echo:", err)
+	}
+	adder := res.Cap()
+
+	// Sending the Adder back makes it d's own export, which d checks.
+	ans2 := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(adder)
+	})
+	if _, err := ans2.Struct(); err == nil || !strings.Contains(err.Error(), "captype:") {
+		t.Errorf("echo(Adder) error = %v; want mismatch from server", err)
+	}
+	ans2.Close()
+	ans.Close()
+	if n := atomic.LoadInt32(&srv.calls); n != 1 {
+		t.Errorf("echo delivered %d times; want 1", n)
+	}
+}
+
+// adderEchoer returns an Adder in place of the CallOrder that Echo
+// promises.
+type adderEchoer struct {
+	CallOrder
+	calls int32
+}
+
+func (e *adderEchoer) Echo(call testcapnp.Echoer_echo) error {
+	atomic.AddInt32(&e.calls, 1)
+	adder := testcapnp.Adder_ServerToClient(AdderServer{})
+	return call.Results.SetCap(testcapnp.CallOrder{Client: adder.Client})
+}
+
+// finishTransport counts the Finish messages sent on a Conn for calls
+// to Echoer.  Other calls, like the one flushConn makes, are ignored.
+type finishTransport struct {
+	rpc.Transport
+
+	mu       sync.Mutex
+	echoes   map[uint32]bool // question IDs of echo calls
+	finishes int
+	releases int // Finish messages with releaseResultCaps set
+}
+
+func (ft *finishTransport) SendMessage(ctx context.Context, msg rpccapnp.Message) error {
+	switch msg.Which() {
+	case rpccapnp.Message_Which_call:
+		call, err := msg.Call()
+		if err == nil && call.InterfaceId() == testcapnp.Echoer_TypeID {
+			ft.mu.Lock()
+			if ft.echoes == nil {
+				ft.echoes = make(map[uint32]bool)
+			}
+			ft.echoes[call.QuestionId()] = true
+			ft.mu.Unlock()
+		}
+	case rpccapnp.Message_Which_finish:
+		fin, err := msg.Finish()
+		if err == nil {
+			ft.mu.Lock()
+			if id := fin.QuestionId(); ft.echoes[id] {
+				// Question IDs are reused once finished.
+				delete(ft.echoes, id)
+				ft.finishes++
+				if fin.ReleaseResultCaps() {
+					ft.releases++
+				}
+			}
+			ft.mu.Unlock()
+		}
+	}
+	return ft.Transport.SendMessage(ctx, msg)
+}
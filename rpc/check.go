@@ -0,0 +1,188 @@
+package rpc
+
+import rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+
+// A protocolError reports a received message that violates the RPC
+// protocol.  The connection is aborted when one is found.
+type protocolError struct {
+	which rpccapnp.Message_Which
+	msg   string
+}
+
+func (e protocolError) Error() string {
+	return "rpc: malformed " + e.which.String() + " message: " + e.msg
+}
+
+// checkMessage reports whether m is well-formed enough for
+// handleMessage to process.  It decodes every pointer that a handler
+// reads and checks every union that a handler switches on, so that the
+// handlers can trust the message's structure and report only errors
+// that depend on the connection's state.
+//
+// Unions with a value that is defined by the schema but not supported
+// by Conn, like a third-party capability, are well-formed: the handler
+// answers them with an unimplemented message.  A union with a value
+// that is not in the schema at all is malformed.  Message types that
+// Conn does not handle are not checked.
+func checkMessage(m rpccapnp.Message) error {
+	which := m.Which()
+	fail := func(msg string) error {
+		return protocolError{which, msg}
+	}
+	switch which {
+	case rpccapnp.Message_Which_bootstrap:
+		if _, err := m.Bootstrap(); err != nil {
+			return fail(err.Error())
+		}
+	case rpccapnp.Message_Which_call:
+		mcall, err := m.Call()
+		if err != nil {
+			return fail(err.Error())
+		}
+		if !mcall.IsValid() {
+			return fail("missing call")
+		}
+		if err := checkTarget(mcall.Target()); err != "" {
+			return fail(err)
+		}
+		if mcall.SendResultsTo().Which() > rpccapnp.Call_sendResultsTo_Which_thirdParty {
+			return fail("unknown sendResultsTo")
+		}
+		if !mcall.HasParams() {
+			return fail("missing params")
+		}
+		params, err := mcall.Params()
+		if err != nil {
+			return fail(err.Error())
+		}
+		if err := checkPayload(params); err != "" {
+			return fail("params " + err)
+		}
+	case rpccapnp.Message_Which_return:
+		ret, err := m.Return()
+		if err != nil {
+			return fail(err.Error())
+		}
+		if !ret.IsValid() {
+			return fail("missing return")
+		}
+		switch ret.Which() {
+		case rpccapnp.Return_Which_results:
+			if !ret.HasResults() {
+				return fail("missing results")
+			}
+			results, err := ret.Results()
+			if err != nil {
+				return fail(err.Error())
+			}
+			if err := checkPayload(results); err != "" {
+				return fail("results " + err)
+			}
+		case rpccapnp.Return_Which_exception:
+			if _, err := ret.Exception(); err != nil {
+				return fail(err.Error())
+			}
+		case rpccapnp.Return_Which_canceled,
+			rpccapnp.Return_Which_resultsSentElsewhere,
+			rpccapnp.Return_Which_takeFromOtherQuestion,
+			rpccapnp.Return_Which_acceptFromThirdParty:
+		default:
+			return fail("unknown return type")
+		}
+	case rpccapnp.Message_Which_finish:
+		if _, err := m.Finish(); err != nil {
+			return fail(err.Error())
+		}
+	case rpccapnp.Message_Which_release:
+		if _, err := m.Release(); err != nil {
+			return fail(err.Error())
+		}
+	case rpccapnp.Message_Which_disembargo:
+		d, err := m.Disembargo()
+		if err != nil {
+			return fail(err.Error())
+		}
+		if !d.IsValid() {
+			return fail("missing disembargo")
+		}
+		if err := checkTarget(d.Target()); err != "" {
+			return fail(err)
+		}
+		if d.Context().Which() > rpccapnp.Disembargo_context_Which_provide {
+			return fail("unknown context")
+		}
+	}
+	return nil
+}
+
+// checkTarget returns a description of what is wrong with a message
+// target, or the empty string if it is well-formed.
+func checkTarget(mt rpccapnp.MessageTarget, err error) string {
+	if err != nil {
+		return "target: " + err.Error()
+	}
+	if !mt.IsValid() {
+		return "missing target"
+	}
+	switch mt.Which() {
+	case rpccapnp.MessageTarget_Which_importedCap:
+		return ""
+	case rpccapnp.MessageTarget_Which_promisedAnswer:
+		pa, err := mt.PromisedAnswer()
+		if err != nil {
+			return "target: " + err.Error()
+		}
+		return checkPromisedAnswer(pa)
+	default:
+		return "unknown target type"
+	}
+}
+
+func checkPromisedAnswer(pa rpccapnp.PromisedAnswer) string {
+	if !pa.IsValid() {
+		return "missing promised answer"
+	}
+	ops, err := pa.Transform()
+	if err != nil {
+		return "transform: " + err.Error()
+	}
+	for i := 0; i < ops.Len(); i++ {
+		if ops.At(i).Which() > rpccapnp.PromisedAnswer_Op_Which_getPointerField {
+			return "unknown transform op"
+		}
+	}
+	return ""
+}
+
+// checkPayload returns a description of what is wrong with a payload,
+// or the empty string if it is well-formed.
+func checkPayload(p rpccapnp.Payload) string {
+	if _, err := p.ContentPtr(); err != nil {
+		return "content: " + err.Error()
+	}
+	ctab, err := p.CapTable()
+	if err != nil {
+		return "cap table: " + err.Error()
+	}
+	for i := 0; i < ctab.Len(); i++ {
+		desc := ctab.At(i)
+		switch desc.Which() {
+		case rpccapnp.CapDescriptor_Which_none,
+			rpccapnp.CapDescriptor_Which_senderHosted,
+			rpccapnp.CapDescriptor_Which_senderPromise,
+			rpccapnp.CapDescriptor_Which_receiverHosted,
+			rpccapnp.CapDescriptor_Which_thirdPartyHosted:
+		case rpccapnp.CapDescriptor_Which_receiverAnswer:
+			pa, err := desc.ReceiverAnswer()
+			if err != nil {
+				return "cap table: " + err.Error()
+			}
+			if err := checkPromisedAnswer(pa); err != "" {
+				return "cap table: " + err
+			}
+		default:
+			return "cap table: unknown capability descriptor"
+		}
+	}
+	return ""
+}
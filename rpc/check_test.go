@@ -0,0 +1,222 @@
+package rpc_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// newTestCall builds a well-formed call on import 0 in m.
+func newTestCall(m rpccapnp.Message) (rpccapnp.Call, error) {
+	call, err := m.NewCall()
+	if err != nil {
+		return rpccapnp.Call{}, err
+	}
+	call.SetQuestionId(1)
+	target, err := call.NewTarget()
+	if err != nil {
+		return rpccapnp.Call{}, err
+	}
+	target.SetImportedCap(0)
+	params, err := call.NewParams()
+	if err != nil {
+		return rpccapnp.Call{}, err
+	}
+	content, err := capnp.NewStruct(params.Segment(), capnp.ObjectSize{})
+	if err != nil {
+		return rpccapnp.Call{}, err
+	}
+	return call, params.SetContentPtr(content.ToPtr())
+}
+
+func TestMalformedMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(m rpccapnp.Message) error
+	}{
+		{"call missing", func(m rpccapnp.Message) error {
+			if _, err := m.NewCall(); err != nil {
+				return err
+			}
+			return m.Struct.SetPtr(0, capnp.Ptr{})
+		}},
+		{"call is a list", func(m rpccapnp.Message) error {
+			if _, err := m.NewCall(); err != nil {
+				return err
+			}
+			l, err := capnp.NewText(m.Segment(), "call")
+			if err != nil {
+				return err
+			}
+			return m.Struct.SetPtr(0, l.ToPtr())
+		}},
+		{"call target missing", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			return call.Struct.SetPtr(0, capnp.Ptr{})
+		}},
+		{"call target unknown", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			target, err := call.Target()
+			if err != nil {
+				return err
+			}
+			target.Struct.SetUint16(4, 99)
+			return nil
+		}},
+		{"call transform op unknown", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			target, err := call.Target()
+			if err != nil {
+				return err
+			}
+			pa, err := target.NewPromisedAnswer()
+			if err != nil {
+				return err
+			}
+			ops, err := pa.NewTransform(1)
+			if err != nil {
+				return err
+			}
+			ops.At(0).Struct.SetUint16(0, 99)
+			return nil
+		}},
+		{"call sendResultsTo unknown", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			call.Struct.SetUint16(6, 99)
+			return nil
+		}},
+		{"call params missing", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			return call.Struct.SetPtr(1, capnp.Ptr{})
+		}},
+		{"call cap descriptor unknown", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			params, err := call.Params()
+			if err != nil {
+				return err
+			}
+			ctab, err := params.NewCapTable(1)
+			if err != nil {
+				return err
+			}
+			ctab.At(0).Struct.SetUint16(0, 99)
+			return nil
+		}},
+		{"call receiver answer missing", func(m rpccapnp.Message) error {
+			call, err := newTestCall(m)
+			if err != nil {
+				return err
+			}
+			params, err := call.Params()
+			if err != nil {
+				return err
+			}
+			ctab, err := params.NewCapTable(1)
+			if err != nil {
+				return err
+			}
+			if _, err := ctab.At(0).NewReceiverAnswer(); err != nil {
+				return err
+			}
+			return ctab.At(0).Struct.SetPtr(0, capnp.Ptr{})
+		}},
+		{"return results missing", func(m rpccapnp.Message) error {
+			ret, err := m.NewReturn()
+			if err != nil {
+				return err
+			}
+			if _, err := ret.NewResults(); err != nil {
+				return err
+			}
+			return ret.Struct.SetPtr(0, capnp.Ptr{})
+		}},
+		{"return type unknown", func(m rpccapnp.Message) error {
+			ret, err := m.NewReturn()
+			if err != nil {
+				return err
+			}
+			ret.Struct.SetUint16(6, 99)
+			return nil
+		}},
+		{"disembargo target missing", func(m rpccapnp.Message) error {
+			d, err := m.NewDisembargo()
+			if err != nil {
+				return err
+			}
+			d.Context().SetSenderLoopback(1)
+			return nil
+		}},
+		{"disembargo context unknown", func(m rpccapnp.Message) error {
+			d, err := m.NewDisembargo()
+			if err != nil {
+				return err
+			}
+			target, err := d.NewTarget()
+			if err != nil {
+				return err
+			}
+			target.SetImportedCap(0)
+			d.Struct.SetUint16(4, 99)
+			return nil
+		}},
+	}
+	for _, test := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		conn, p := newUnpairedConn(t)
+		if err := sendMessage(ctx, p, test.build); err != nil {
+			t.Errorf("%s: send: %v", test.name, err)
+		} else if msg, err := p.RecvMessage(ctx); err != nil {
+			t.Errorf("%s: receive: %v", test.name, err)
+		} else if msg.Which() != rpccapnp.Message_Which_abort {
+			t.Errorf("%s: conn replied with %v; want abort", test.name, msg.Which())
+		}
+		conn.Close()
+		p.Close()
+		cancel()
+	}
+}
+
+func TestMalformedMessages_WellFormedCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, p := newUnpairedConn(t)
+	defer conn.Close()
+	defer p.Close()
+	err := sendMessage(ctx, p, func(m rpccapnp.Message) error {
+		_, err := newTestCall(m)
+		return err
+	})
+	if err != nil {
+		t.Fatal("send:", err)
+	}
+	msg, err := p.RecvMessage(ctx)
+	if err != nil {
+		t.Fatal("receive:", err)
+	}
+	// Import 0 doesn't exist, so the call fails, but the connection
+	// stays up.
+	if msg.Which() != rpccapnp.Message_Which_return {
+		t.Errorf("conn replied with %v; want return", msg.Which())
+	}
+}
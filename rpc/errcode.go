@@ -0,0 +1,136 @@
+package rpc
+
+import (
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// ErrorCodeDetail is the ID of the exception detail that carries an
+// error's code.  The detail's data is the code as UTF-8 text.
+const ErrorCodeDetail uint64 = 0xc5e1a7d2f3b89a64
+
+// A CodeError is an error with a stable, machine-readable code, like
+// "quota.exceeded", in addition to its human-readable reason.  When a
+// CodeError is returned to a peer, the code is sent in an exception
+// detail, so that the peer can handle the error or show a localized
+// message without parsing the reason.
+type CodeError struct {
+	Code string
+	Err  error
+}
+
+// WithCode returns an error that has the reason of err and the given
+// code.
+func WithCode(err error, code string) error {
+	return &CodeError{Code: code, Err: err}
+}
+
+// Error returns the error's reason.  The code is not included.
+func (e *CodeError) Error() string {
+	return e.Err.Error()
+}
+
+// ErrorCode returns the code of err, which may be a CodeError from
+// this vat or an Exception from a peer, possibly wrapped in a
+// capnp.MethodError.  ok is false if err has no code.
+func ErrorCode(err error) (code string, ok bool) {
+	for {
+		switch e := err.(type) {
+		case *CodeError:
+			return e.Code, true
+		case *capnp.MethodError:
+			err = e.Err
+		case bootstrapError:
+			err = e.err
+		case Exception:
+			return exceptionCode(e.Exception)
+		case Abort:
+			return exceptionCode(e.Exception)
+		default:
+			return "", false
+		}
+	}
+}
+
+// A Catalog maps error codes to messages, for example translations of
+// errors into a user's language.
+type Catalog map[string]string
+
+// Lookup returns the message for the code of err.  ok is false if err
+// has no code or the code is not in the catalog.
+func (cat Catalog) Lookup(err error) (msg string, ok bool) {
+	code, ok := ErrorCode(err)
+	if !ok {
+		return "", false
+	}
+	msg, ok = cat[code]
+	return msg, ok
+}
+
+// Exceptions with a code are allocated with room for the trace and
+// details fields that later versions of rpc.capnp add to Exception, so
+// that peers that know about details can read the code and others
+// ignore it.  A detail is an (id :UInt64, data :Data) struct.
+const exceptionDetailsPtr = 2
+
+var (
+	exceptionSize = capnp.ObjectSize{DataSize: 8, PointerCount: exceptionDetailsPtr + 1}
+	detailSize    = capnp.ObjectSize{DataSize: 8, PointerCount: 1}
+)
+
+// newException allocates an exception in s that describes err.
+func newException(s *capnp.Segment, err error) (rpccapnp.Exception, error) {
+	code, ok := ErrorCode(err)
+	if !ok {
+		exc, err2 := rpccapnp.NewException(s)
+		if err2 != nil {
+			return rpccapnp.Exception{}, err2
+		}
+		toException(exc, err)
+		return exc, nil
+	}
+	st, err2 := capnp.NewStruct(s, exceptionSize)
+	if err2 != nil {
+		return rpccapnp.Exception{}, err2
+	}
+	exc := rpccapnp.Exception{Struct: st}
+	toException(exc, err)
+	details, err2 := capnp.NewCompositeList(s, detailSize, 1)
+	if err2 != nil {
+		return rpccapnp.Exception{}, err2
+	}
+	d := details.Struct(0)
+	d.SetUint64(0, ErrorCodeDetail)
+	data, err2 := capnp.NewData(s, []byte(code))
+	if err2 != nil {
+		return rpccapnp.Exception{}, err2
+	}
+	if err2 := d.SetPtr(0, data.ToPtr()); err2 != nil {
+		return rpccapnp.Exception{}, err2
+	}
+	if err2 := st.SetPtr(exceptionDetailsPtr, details.ToPtr()); err2 != nil {
+		return rpccapnp.Exception{}, err2
+	}
+	return exc, nil
+}
+
+// exceptionCode returns the code in exc's details.
+func exceptionCode(exc rpccapnp.Exception) (code string, ok bool) {
+	p, err := exc.Ptr(exceptionDetailsPtr)
+	if err != nil {
+		return "", false
+	}
+	details := p.List()
+	for i := 0; i < details.Len(); i++ {
+		d := details.Struct(i)
+		if d.Uint64(0) != ErrorCodeDetail {
+			continue
+		}
+		data, err := d.Ptr(0)
+		if err != nil {
+			return "", false
+		}
+		return string(data.Data()), true
+	}
+	return "", false
+}
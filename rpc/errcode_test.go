@@ -0,0 +1,65 @@
+package rpc_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+type codedAdder struct {
+	err error
+}
+
+func (ca codedAdder) Add(call testcapnp.Adder_add) error {
+	return ca.err
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+		ok   bool
+	}{
+		{err: rpc.WithCode(errors.New("too big"), "adder.overflow"), code: "adder.overflow", ok: true},
+		{err: errors.New("too big")},
+	}
+	catalog := rpc.Catalog{"adder.overflow": "Die Summe ist zu groß."}
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		p, q := pipetransport.New()
+		if *logMessages {
+			p = logtransport.New(nil, p)
+		}
+		log := testLogger{t}
+		c := rpc.NewConn(p, rpc.ConnLog(log))
+		srv := testcapnp.Adder_ServerToClient(codedAdder{test.err})
+		d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(log))
+
+		client := testcapnp.Adder{Client: c.Bootstrap(ctx)}
+		_, err := client.Add(ctx, nil).Struct()
+		if err == nil {
+			t.Errorf("%v: call succeeded", test.err)
+		} else {
+			if !strings.Contains(err.Error(), "too big") {
+				t.Errorf("%v: call error = %v; want reason \"too big\"", test.err, err)
+			}
+			if code, ok := rpc.ErrorCode(err); code != test.code || ok != test.ok {
+				t.Errorf("%v: ErrorCode(%v) = %q, %t; want %q, %t", test.err, err, code, ok, test.code, test.ok)
+			}
+			if msg, ok := catalog.Lookup(err); ok != test.ok {
+				t.Errorf("%v: catalog.Lookup(%v) = %q, %t; want ok = %t", test.err, err, msg, ok, test.ok)
+			}
+		}
+
+		client.Client.Close()
+		c.Close()
+		d.Wait()
+		cancel()
+	}
+}
@@ -3,6 +3,7 @@ package rpc
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
 	"zombiezen.com/go/capnproto2"
 	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
@@ -63,12 +64,28 @@ func toException(exc rpccapnp.Exception, err error) {
 	}
 
 	exc.SetReason(err.Error())
+	if capnp.IsTimeout(err) {
+		exc.SetType(rpccapnp.Exception_Type_overloaded)
+		return
+	}
+	if capnp.IsUnimplemented(err) {
+		exc.SetType(rpccapnp.Exception_Type_unimplemented)
+		return
+	}
 	exc.SetType(rpccapnp.Exception_Type_failed)
 }
 
 // Errors
 var (
 	ErrConnClosed = errors.New("rpc: connection closed")
+
+	// ErrExportQuota is returned when sending a capability would exceed
+	// a connection's ExportQuota.
+	ErrExportQuota = errors.New("rpc: export quota exceeded")
+
+	// ErrNotExported is returned by Conn.ReplaceExport for a capability
+	// that the connection has not exported.
+	ErrNotExported = errors.New("rpc: capability not exported")
 )
 
 // Internal errors
@@ -78,8 +95,24 @@ var (
 	errBadTarget       = errors.New("rpc: target not found")
 	errShutdown        = errors.New("rpc: shutdown")
 	errUnimplemented   = errors.New("rpc: remote used unimplemented protocol feature")
+
+	errReceiverCanceled     = errors.New("receiver reported canceled")
+	errBadTakeFrom          = errors.New("rpc: return takes results from a call that was not sent with sendResultsTo.yourself")
+	errResultsSentElsewhere = errors.New("rpc: results sent elsewhere")
 )
 
+// An idError reports an unknown ID in a received message.  It is
+// formatted only when Error is called, so that returning it does not
+// allocate a string.
+type idError struct {
+	msg string // message, ending with the kind of ID
+	id  uint32
+}
+
+func (e idError) Error() string {
+	return e.msg + " " + strconv.FormatUint(uint64(e.id), 10)
+}
+
 type bootstrapError struct {
 	err error
 }
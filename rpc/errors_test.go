@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestUnknownIDErrorAllocs(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(rpccapnp.CapDescriptor) error
+	}{
+		{"export", func(d rpccapnp.CapDescriptor) error {
+			d.SetReceiverHosted(7)
+			return nil
+		}},
+		{"answer", func(d rpccapnp.CapDescriptor) error {
+			pa, err := d.NewReceiverAnswer()
+			if err != nil {
+				return err
+			}
+			pa.SetQuestionId(7)
+			return nil
+		}},
+	}
+	c := NewConn(newNullTransport(), ConnLog(tlog{t}))
+	defer c.Close()
+	for _, test := range tests {
+		_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := rpccapnp.NewRootPayload(seg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctab, err := payload.NewCapTable(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := test.set(ctab.At(0)); err != nil {
+			t.Fatal(err)
+		}
+
+		// The only allocation is storing the idError in the error
+		// interface: the message is not formatted until it is needed.
+		c.mu.Lock()
+		allocs := testing.AllocsPerRun(100, func() {
+			if err := c.populateMessageCapTable(payload); err == nil {
+				t.Fatalf("%s: populateMessageCapTable succeeded", test.name)
+			}
+		})
+		c.mu.Unlock()
+		if allocs > 1 {
+			t.Errorf("%s: populateMessageCapTable allocated %v times; want at most 1", test.name, allocs)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package rpc_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/streamtest"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+)
+
+func TestStreamFlowLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	w := &streamWriter{writes: make(chan int, 10), release: make(chan error)}
+	d := rpc.NewConn(q, rpc.MainInterface(streamtest.Writer_ServerToClient(w).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+
+	// Each call is a little over 1000 bytes, so two fit in the limit.
+	fc := capnp.NewFlowLimitedClient(c.Bootstrap(ctx), capnp.NewFixedLimiter(2500))
+	defer fc.Close()
+	client := streamtest.Writer{Client: fc}
+	write := func() error {
+		return client.Write(ctx, func(p streamtest.Writer_write_Params) error {
+			return p.SetChunk(make([]byte, 1000))
+		})
+	}
+	for i := 0; i < 2; i++ {
+		if err := write(); err != nil {
+			t.Fatalf("Write #%d: %v", i+1, err)
+		}
+	}
+	third := make(chan error, 1)
+	go func() { third <- write() }()
+	if n := <-w.writes; n != 1000 {
+		t.Errorf("server got %d bytes; want 1000", n)
+	}
+	select {
+	case err := <-third:
+		t.Fatalf("Write #3 returned (%v) before any call returned", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	w.release <- nil
+	select {
+	case err := <-third:
+		if err != nil {
+			t.Fatal("Write #3:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write #3 still blocked after a call returned")
+	}
+
+	// A failed call is reported by a later Write.
+	<-w.writes
+	w.release <- errors.New("disk full")
+	go func() {
+		for {
+			select {
+			case <-w.writes:
+				select {
+				case w.release <- nil:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := write()
+		if err != nil {
+			if !strings.Contains(err.Error(), "disk full") {
+				t.Errorf("Write after failure = %v; want disk full", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Write never reported earlier failure")
+		}
+	}
+}
+
+// streamWriter is a Writer that sends the size of each chunk on writes
+// and then returns the error received on release.
+type streamWriter struct {
+	writes  chan int
+	release chan error
+}
+
+func (w *streamWriter) Write(call streamtest.Writer_write) error {
+	chunk, err := call.Params.Chunk()
+	if err != nil {
+		return err
+	}
+	w.writes <- len(chunk)
+	return <-w.release
+}
@@ -0,0 +1,121 @@
+// +build interop
+// Build tag so that users who run `go get zombiezen.com/go/capnproto2/...` don't install this command.
+// cd rpc/internal/cmd/interop && go build -tags=interop
+
+// interop is the Go side of the RPC interoperability suite.  It either
+// serves the Go implementation of the Interop interface:
+//
+//	interop -listen=:4000
+//
+// or runs the interop checks against the bootstrap interfaces of peers:
+//
+//	interop -connect=cxx:4000,rust:4000
+//
+// and exits with a non-zero status if any check fails.  See
+// rpc/internal/interop/docker for how the suite is run.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/interop"
+)
+
+func main() {
+	listen := flag.String("listen", "", "address to serve the Go implementation on")
+	connect := flag.String("connect", "", "comma-separated addresses of peers to check")
+	wait := flag.Duration("wait", 30*time.Second, "how long to retry connecting to a peer that is starting up")
+	flag.Parse()
+
+	switch {
+	case *listen != "" && *connect == "":
+		if err := serve(*listen); err != nil {
+			fatalf("%v", err)
+		}
+	case *connect != "" && *listen == "":
+		ok := true
+		for _, addr := range strings.Split(*connect, ",") {
+			if !check(addr, *wait) {
+				ok = false
+			}
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	default:
+		fatalf("exactly one of -listen or -connect must be given")
+	}
+}
+
+func serve(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("serving on %v", l.Addr())
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			conn := rpc.NewConn(rpc.StreamTransport(c), rpc.MainInterface(interop.NewServer().Client))
+			if err := conn.Wait(); err != nil {
+				log.Printf("%v: %v", c.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// check runs the interop checks against the peer at addr and reports
+// whether they all passed.
+func check(addr string, wait time.Duration) bool {
+	c, err := dial(addr, wait)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", addr, err)
+		return false
+	}
+	conn := rpc.NewConn(rpc.StreamTransport(c))
+	defer conn.Close()
+	ctx := context.Background()
+	peer := interop.Interop{Client: conn.Bootstrap(ctx)}
+	ok := true
+	for _, chk := range interop.Checks {
+		cctx, cancel := context.WithTimeout(ctx, time.Minute)
+		err := chk.Run(cctx, peer)
+		cancel()
+		if err != nil {
+			fmt.Printf("FAIL %s %s: %v\n", addr, chk.Name, err)
+			ok = false
+			continue
+		}
+		fmt.Printf("ok   %s %s\n", addr, chk.Name)
+	}
+	return ok
+}
+
+// dial connects to addr, retrying until wait has passed so that the
+// suite can start before its peers are listening.
+func dial(addr string, wait time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		c, err := net.Dial("tcp", addr)
+		if err == nil || time.Now().After(deadline) {
+			return c, err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "interop: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,255 @@
+// +build rpcbench
+// Build tag so that users who run `go get zombiezen.com/go/capnproto2/...` don't install this command.
+// cd rpc/internal/cmd/rpcbench && go build -tags=rpcbench
+
+// rpcbench measures the latency of calls over a pair of rpc.Conns.
+//
+// It makes echo calls from a number of goroutines over an in-memory
+// transport, a loopback TCP connection, or both, and reports latency
+// percentiles, throughput, and allocations per call for each payload
+// size:
+//
+//	rpcbench -transport=pipe,tcp -concurrency=1,16 -payload=0,4096 -n=10000
+//
+// Both ends of the connection run in the same process, so allocations
+// are counted for the client and server together.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+)
+
+func main() {
+	transports := flag.String("transport", "pipe,tcp", "comma-separated transports to use: pipe, tcp")
+	concurrency := flag.String("concurrency", "1", "comma-separated numbers of goroutines making calls")
+	payloads := flag.String("payload", "0", "comma-separated payload sizes in bytes")
+	n := flag.Int("n", 10000, "number of calls per run")
+	warmup := flag.Int("warmup", 100, "number of calls before each run that are not measured")
+	hist := flag.Bool("hist", false, "print a latency histogram for each run")
+	flag.Parse()
+
+	concs, err := parseInts(*concurrency)
+	if err != nil {
+		fatalf("-concurrency: %v", err)
+	}
+	sizes, err := parseInts(*payloads)
+	if err != nil {
+		fatalf("-payload: %v", err)
+	}
+	for _, c := range concs {
+		if c == 0 {
+			fatalf("-concurrency: must be positive")
+		}
+	}
+	if *n <= 0 {
+		fatalf("-n: must be positive")
+	}
+	fmt.Printf("%-5s %5s %8s %10s %10s %10s %10s %10s %12s %10s %12s\n",
+		"trans", "conc", "payload", "p50", "p90", "p99", "p99.9", "max", "calls/s", "allocs/op", "bytes/op")
+	for _, tname := range strings.Split(*transports, ",") {
+		for _, conc := range concs {
+			for _, size := range sizes {
+				r := run{
+					transport:   tname,
+					concurrency: conc,
+					payload:     size,
+					n:           *n,
+					warmup:      *warmup,
+				}
+				res, err := r.do()
+				if err != nil {
+					fatalf("%s: %v", r, err)
+				}
+				fmt.Printf("%-5s %5d %8d %10v %10v %10v %10v %10v %12.0f %10.1f %12.0f\n",
+					tname, conc, size,
+					res.lat.percentile(50), res.lat.percentile(90), res.lat.percentile(99), res.lat.percentile(99.9), res.lat.max(),
+					float64(*n)/res.elapsed.Seconds(),
+					float64(res.mallocs)/float64(*n), float64(res.bytes)/float64(*n))
+				if *hist {
+					res.lat.writeHistogram(os.Stdout)
+				}
+			}
+		}
+	}
+}
+
+// A run is a single benchmark configuration.
+type run struct {
+	transport   string
+	concurrency int
+	payload     int
+	n           int
+	warmup      int
+}
+
+func (r run) String() string {
+	return fmt.Sprintf("transport=%s concurrency=%d payload=%d", r.transport, r.concurrency, r.payload)
+}
+
+type result struct {
+	lat     *latencies
+	elapsed time.Duration
+	mallocs uint64
+	bytes   uint64
+}
+
+func (r run) do() (*result, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, cleanup, err := dial(ctx, r.transport)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	echo := air.Echo{Client: client}
+	in := strings.Repeat("x", r.payload)
+	call := func() error {
+		_, err := echo.Echo(ctx, func(p air.Echo_echo_Params) error {
+			return p.SetIn(in)
+		}).Struct()
+		return err
+	}
+
+	for i := 0; i < r.warmup; i++ {
+		if err := call(); err != nil {
+			return nil, err
+		}
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	lat := newLatencies(r.n)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	work := make(chan struct{}, r.n)
+	for i := 0; i < r.n; i++ {
+		work <- struct{}{}
+	}
+	close(work)
+	start := time.Now()
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range work {
+				t := time.Now()
+				err := call()
+				d := time.Since(t)
+				mu.Lock()
+				lat.add(d)
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return &result{
+		lat:     lat,
+		elapsed: elapsed,
+		mallocs: after.Mallocs - before.Mallocs,
+		bytes:   after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// dial starts a server Conn serving an echo capability and returns the
+// bootstrap capability of a client Conn connected to it.  cleanup shuts
+// down both Conns.
+func dial(ctx context.Context, transport string) (client capnp.Client, cleanup func(), err error) {
+	var p, q rpc.Transport
+	var l net.Listener
+	switch transport {
+	case "pipe":
+		p, q = pipetransport.New()
+	case "tcp":
+		l, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer l.Close()
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			c, err := l.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- c
+		}()
+		cc, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return nil, nil, err
+		}
+		sc, ok := <-accepted
+		if !ok {
+			cc.Close()
+			return nil, nil, errors.New("accept failed")
+		}
+		p, q = rpc.StreamTransport(cc), rpc.StreamTransport(sc)
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %q", transport)
+	}
+	srv := air.Echo_ServerToClient(echoServer{})
+	d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(nil))
+	c := rpc.NewConn(p, rpc.ConnLog(nil))
+	cleanup = func() {
+		c.Close()
+		d.Wait()
+	}
+	return c.Bootstrap(ctx), cleanup, nil
+}
+
+type echoServer struct{}
+
+func (echoServer) Echo(call air.Echo_echo) error {
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	return call.Results.SetOut(in)
+}
+
+func parseInts(s string) ([]int, error) {
+	var ns []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%d is negative", n)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "rpcbench: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,92 @@
+// +build rpcbench
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// latencies is a set of call latencies.
+type latencies struct {
+	d      []time.Duration
+	sorted bool
+}
+
+func newLatencies(n int) *latencies {
+	return &latencies{d: make([]time.Duration, 0, n)}
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.d = append(l.d, d)
+	l.sorted = false
+}
+
+func (l *latencies) sort() {
+	if !l.sorted {
+		sort.Slice(l.d, func(i, j int) bool { return l.d[i] < l.d[j] })
+		l.sorted = true
+	}
+}
+
+// percentile returns the smallest latency that is greater than or equal
+// to p percent of the latencies, using the nearest-rank method.
+func (l *latencies) percentile(p float64) time.Duration {
+	if len(l.d) == 0 {
+		return 0
+	}
+	l.sort()
+	rank := int(math.Ceil(p / 100 * float64(len(l.d))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(l.d) {
+		rank = len(l.d)
+	}
+	return l.d[rank-1]
+}
+
+func (l *latencies) max() time.Duration {
+	return l.percentile(100)
+}
+
+// writeHistogram writes a histogram of the latencies with buckets that
+// double in width, starting at one microsecond.
+func (l *latencies) writeHistogram(w io.Writer) {
+	if len(l.d) == 0 {
+		return
+	}
+	l.sort()
+	const barWidth = 50
+	var counts []int
+	var bounds []time.Duration
+	i := 0
+	for bound := time.Microsecond; i < len(l.d); bound *= 2 {
+		n := 0
+		for i < len(l.d) && l.d[i] < bound {
+			n++
+			i++
+		}
+		counts = append(counts, n)
+		bounds = append(bounds, bound)
+	}
+	// Skip empty buckets at the start.
+	first := 0
+	for first < len(counts) && counts[first] == 0 {
+		first++
+	}
+	most := 0
+	for _, n := range counts {
+		if n > most {
+			most = n
+		}
+	}
+	for j := first; j < len(counts); j++ {
+		bar := strings.Repeat("#", (counts[j]*barWidth+most-1)/most)
+		fmt.Fprintf(w, "  < %10v %8d %s\n", bounds[j], counts[j], bar)
+	}
+}
@@ -0,0 +1,154 @@
+// The C++ side of the RPC interoperability suite.
+//
+//   interop server ADDRESS
+//   interop client ADDRESS...
+//
+// The server serves Interop on ADDRESS.  The client runs the checks
+// against each ADDRESS and exits with a non-zero status if any fail.
+// The checks mirror Checks in interop.go.
+
+#include "interop.capnp.h"
+
+#include <capnp/ez-rpc.h>
+#include <kj/async.h>
+#include <kj/debug.h>
+#include <kj/string.h>
+
+#include <cstring>
+#include <iostream>
+
+namespace {
+
+class InteropImpl final: public Interop::Server {
+public:
+  kj::Promise<void> echo(EchoContext context) override {
+    context.getResults().setS(context.getParams().getS());
+    return kj::READY_NOW;
+  }
+
+  kj::Promise<void> newSession(NewSessionContext context) override {
+    context.getResults().setSession(kj::heap<InteropImpl>());
+    return kj::READY_NOW;
+  }
+
+  kj::Promise<void> hang(HangContext context) override {
+    context.allowCancellation();
+    // Destroying the promise, which is what canceling the call does,
+    // runs the deferred count.
+    return kj::Promise<void>(kj::NEVER_DONE).attach(kj::defer([this]() {
+      ++canceledCount;
+    }));
+  }
+
+  kj::Promise<void> canceled(CanceledContext context) override {
+    context.getResults().setN(canceledCount);
+    return kj::READY_NOW;
+  }
+
+  kj::Promise<void> fail(FailContext context) override {
+    return kj::Exception(kj::Exception::Type::FAILED, __FILE__, __LINE__,
+                         kj::str(context.getParams().getReason()));
+  }
+
+private:
+  uint32_t canceledCount = 0;
+};
+
+void echo(kj::WaitScope& waitScope, Interop::Client peer, kj::StringPtr s) {
+  auto req = peer.echoRequest();
+  req.setS(s);
+  auto got = req.send().wait(waitScope).getS();
+  KJ_REQUIRE(got == s, "echo returned wrong string", s, got);
+}
+
+void checkBootstrap(capnp::EzRpcClient&, kj::WaitScope& waitScope, Interop::Client peer) {
+  echo(waitScope, peer, "hello");
+}
+
+void checkPipelining(capnp::EzRpcClient&, kj::WaitScope& waitScope, Interop::Client peer) {
+  auto sess = peer.newSessionRequest().send();
+  // The echo is made on the session before newSession returns, so it
+  // must be pipelined.
+  echo(waitScope, sess.getSession(), "pipelined");
+  sess.wait(waitScope);
+}
+
+void checkCancellation(capnp::EzRpcClient& client, kj::WaitScope& waitScope, Interop::Client peer) {
+  auto sess = peer.newSessionRequest().send().wait(waitScope).getSession();
+  {
+    auto hang = sess.hangRequest().send();
+    // Calls are delivered in order, so the hang has been delivered once
+    // the echo returns.
+    echo(waitScope, sess, "before cancel");
+    // Dropping hang cancels the call.
+  }
+  auto& timer = client.getIoProvider().getTimer();
+  for (int i = 0; i < 500; i++) {
+    auto n = sess.canceledRequest().send().wait(waitScope).getN();
+    if (n == 1) return;
+    KJ_REQUIRE(n == 0, "too many canceled calls", n);
+    timer.afterDelay(10 * kj::MILLISECONDS).wait(waitScope);
+  }
+  KJ_FAIL_REQUIRE("peer did not notice canceled hang");
+}
+
+void checkExceptions(capnp::EzRpcClient&, kj::WaitScope& waitScope, Interop::Client peer) {
+  kj::StringPtr reason = "interop failure";
+  auto req = peer.failRequest();
+  req.setReason(reason);
+  KJ_IF_MAYBE(e, kj::runCatchingExceptions([&]() { req.send().wait(waitScope); })) {
+    KJ_REQUIRE(strstr(e->getDescription().cStr(), reason.cStr()) != nullptr,
+               "exception does not contain reason", e->getDescription());
+  } else {
+    KJ_FAIL_REQUIRE("fail returned without an exception");
+  }
+  // The connection must still work after an exception.
+  echo(waitScope, peer, "after exception");
+}
+
+struct Check {
+  const char* name;
+  void (*run)(capnp::EzRpcClient&, kj::WaitScope&, Interop::Client);
+};
+
+const Check checks[] = {
+  {"bootstrap", checkBootstrap},
+  {"pipelining", checkPipelining},
+  {"cancellation", checkCancellation},
+  {"exceptions", checkExceptions},
+};
+
+bool checkPeer(const char* addr) {
+  capnp::EzRpcClient client(addr);
+  auto& waitScope = client.getWaitScope();
+  auto peer = client.getMain<Interop>();
+  bool ok = true;
+  for (auto& check: checks) {
+    KJ_IF_MAYBE(e, kj::runCatchingExceptions([&]() { check.run(client, waitScope, peer); })) {
+      std::cout << "FAIL " << addr << " " << check.name << ": "
+                << e->getDescription().cStr() << std::endl;
+      ok = false;
+    } else {
+      std::cout << "ok   " << addr << " " << check.name << std::endl;
+    }
+  }
+  return ok;
+}
+
+}  // namespace
+
+int main(int argc, const char* argv[]) {
+  if (argc == 3 && kj::StringPtr(argv[1]) == "server") {
+    capnp::EzRpcServer server(kj::heap<InteropImpl>(), argv[2]);
+    kj::NEVER_DONE.wait(server.getWaitScope());
+  }
+  if (argc >= 3 && kj::StringPtr(argv[1]) == "client") {
+    bool ok = true;
+    for (int i = 2; i < argc; i++) {
+      if (!checkPeer(argv[i])) ok = false;
+    }
+    return ok ? 0 : 1;
+  }
+  std::cerr << "usage: interop server ADDRESS | interop client ADDRESS..." << std::endl;
+  return 2;
+}
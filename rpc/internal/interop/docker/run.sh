@@ -0,0 +1,23 @@
+#!/bin/bash
+# Runs every implementation's interop checks against every
+# implementation, including itself.  Exits non-zero if any check fails.
+
+set -u
+cd "$(dirname "$0")"
+
+compose() {
+  docker-compose -p capnp-interop "$@"
+}
+
+compose build || exit 1
+compose up -d go cxx rust || exit 1
+trap 'compose down' EXIT
+
+peers="go:4000,cxx:4000,rust:4000"
+status=0
+# The Go checker retries until the servers are listening, so it runs
+# first.
+compose run --rm go interop -connect="$peers" || status=1
+compose run --rm cxx interop client ${peers//,/ } || status=1
+compose run --rm rust interop client ${peers//,/ } || status=1
+exit $status
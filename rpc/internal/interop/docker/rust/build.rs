@@ -0,0 +1,9 @@
+fn main() {
+    capnpc::CompilerCommand::new()
+        .src_prefix("schema")
+        .import_path("schema")
+        .file("schema/go.capnp")
+        .file("schema/interop.capnp")
+        .run()
+        .expect("compiling schema");
+}
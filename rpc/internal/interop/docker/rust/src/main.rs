@@ -0,0 +1,255 @@
+//! The Rust side of the RPC interoperability suite.
+//!
+//!     interop server ADDRESS
+//!     interop client ADDRESS...
+//!
+//! The server serves Interop on ADDRESS.  The client runs the checks
+//! against each ADDRESS and exits with a non-zero status if any fail.
+//! The checks mirror Checks in interop.go.
+
+use std::cell::Cell;
+use std::error::Error;
+use std::rc::Rc;
+use std::time::Duration;
+
+use capnp::capability::Promise;
+use capnp_rpc::{pry, rpc_twoparty_capnp, twoparty, RpcSystem};
+use futures::AsyncReadExt;
+use tokio_util::compat::TokioAsyncReadCompatExt;
+
+pub mod go_capnp {
+    include!(concat!(env!("OUT_DIR"), "/go_capnp.rs"));
+}
+
+pub mod interop_capnp {
+    include!(concat!(env!("OUT_DIR"), "/interop_capnp.rs"));
+}
+
+use interop_capnp::interop;
+
+#[derive(Default)]
+struct InteropImpl {
+    canceled: Rc<Cell<u32>>,
+}
+
+/// Counts a canceled hang call when the call's future is dropped.
+struct CancelGuard(Rc<Cell<u32>>);
+
+impl Drop for CancelGuard {
+    fn drop(&mut self) {
+        self.0.set(self.0.get() + 1);
+    }
+}
+
+impl interop::Server for InteropImpl {
+    fn echo(
+        &mut self,
+        params: interop::EchoParams,
+        mut results: interop::EchoResults,
+    ) -> Promise<(), capnp::Error> {
+        let s = pry!(pry!(params.get()).get_s());
+        results.get().set_s(s);
+        Promise::ok(())
+    }
+
+    fn new_session(
+        &mut self,
+        _: interop::NewSessionParams,
+        mut results: interop::NewSessionResults,
+    ) -> Promise<(), capnp::Error> {
+        let session: interop::Client = capnp_rpc::new_client(InteropImpl::default());
+        results.get().set_session(session);
+        Promise::ok(())
+    }
+
+    fn hang(
+        &mut self,
+        _: interop::HangParams,
+        _: interop::HangResults,
+    ) -> Promise<(), capnp::Error> {
+        let guard = CancelGuard(self.canceled.clone());
+        Promise::from_future(async move {
+            let _guard = guard;
+            futures::future::pending::<()>().await;
+            Ok(())
+        })
+    }
+
+    fn canceled(
+        &mut self,
+        _: interop::CanceledParams,
+        mut results: interop::CanceledResults,
+    ) -> Promise<(), capnp::Error> {
+        results.get().set_n(self.canceled.get());
+        Promise::ok(())
+    }
+
+    fn fail(
+        &mut self,
+        params: interop::FailParams,
+        _: interop::FailResults,
+    ) -> Promise<(), capnp::Error> {
+        let reason = pry!(pry!(params.get()).get_reason());
+        Promise::err(capnp::Error::failed(reason.to_string()))
+    }
+}
+
+type CheckResult = Result<(), Box<dyn Error>>;
+
+async fn echo(peer: &interop::Client, s: &str) -> CheckResult {
+    let mut req = peer.echo_request();
+    req.get().set_s(s);
+    let res = req.send().promise.await?;
+    let got = res.get()?.get_s()?;
+    if got != s {
+        return Err(format!("echo({:?}) = {:?}", s, got).into());
+    }
+    Ok(())
+}
+
+async fn check_bootstrap(peer: &interop::Client) -> CheckResult {
+    echo(peer, "hello").await
+}
+
+async fn check_pipelining(peer: &interop::Client) -> CheckResult {
+    let sess = peer.new_session_request().send();
+    // The echo is made on the session before newSession returns, so it
+    // must be pipelined.
+    echo(&sess.pipeline.get_session(), "pipelined").await?;
+    sess.promise.await?;
+    Ok(())
+}
+
+async fn check_cancellation(peer: &interop::Client) -> CheckResult {
+    let res = peer.new_session_request().send().promise.await?;
+    let sess = res.get()?.get_session()?;
+    let hang = sess.hang_request().send().promise;
+    // Calls are delivered in order, so the hang has been delivered once
+    // the echo returns.
+    echo(&sess, "before cancel").await?;
+    // Dropping the promise cancels the call.
+    drop(hang);
+    for _ in 0..500 {
+        let res = sess.canceled_request().send().promise.await?;
+        match res.get()?.get_n() {
+            0 => tokio::time::sleep(Duration::from_millis(10)).await,
+            1 => return Ok(()),
+            n => return Err(format!("canceled = {}; want 1", n).into()),
+        }
+    }
+    Err("peer did not notice canceled hang".into())
+}
+
+async fn check_exceptions(peer: &interop::Client) -> CheckResult {
+    let reason = "interop failure";
+    let mut req = peer.fail_request();
+    req.get().set_reason(reason);
+    match req.send().promise.await {
+        Ok(_) => return Err("fail returned without an exception".into()),
+        Err(e) if !e.description.contains(reason) => {
+            return Err(format!("exception {:?} does not contain reason {:?}", e.description, reason).into());
+        }
+        Err(_) => {}
+    }
+    // The connection must still work after an exception.
+    echo(peer, "after exception").await
+}
+
+async fn check_peer(addr: &str) -> bool {
+    let peer = match connect(addr).await {
+        Ok(peer) => peer,
+        Err(e) => {
+            println!("FAIL {} {}", addr, e);
+            return false;
+        }
+    };
+    let mut ok = true;
+    macro_rules! check {
+        ($name:expr, $f:ident) => {
+            match $f(&peer).await {
+                Ok(()) => println!("ok   {} {}", addr, $name),
+                Err(e) => {
+                    println!("FAIL {} {}: {}", addr, $name, e);
+                    ok = false;
+                }
+            }
+        };
+    }
+    check!("bootstrap", check_bootstrap);
+    check!("pipelining", check_pipelining);
+    check!("cancellation", check_cancellation);
+    check!("exceptions", check_exceptions);
+    ok
+}
+
+async fn connect(addr: &str) -> Result<interop::Client, Box<dyn Error>> {
+    let stream = tokio::net::TcpStream::connect(addr).await?;
+    stream.set_nodelay(true)?;
+    let (reader, writer) = stream.compat().split();
+    let network = twoparty::VatNetwork::new(
+        reader,
+        writer,
+        rpc_twoparty_capnp::Side::Client,
+        Default::default(),
+    );
+    let mut rpc_system = RpcSystem::new(Box::new(network), None);
+    let peer: interop::Client = rpc_system.bootstrap(rpc_twoparty_capnp::Side::Server);
+    tokio::task::spawn_local(rpc_system);
+    Ok(peer)
+}
+
+async fn serve(addr: &str) -> Result<(), Box<dyn Error>> {
+    let listener = tokio::net::TcpListener::bind(addr).await?;
+    let main: interop::Client = capnp_rpc::new_client(InteropImpl::default());
+    loop {
+        let (stream, _) = listener.accept().await?;
+        stream.set_nodelay(true)?;
+        let (reader, writer) = stream.compat().split();
+        let network = twoparty::VatNetwork::new(
+            reader,
+            writer,
+            rpc_twoparty_capnp::Side::Server,
+            Default::default(),
+        );
+        let rpc_system = RpcSystem::new(Box::new(network), Some(main.clone().client));
+        tokio::task::spawn_local(rpc_system);
+    }
+}
+
+fn main() {
+    let args: Vec<String> = std::env::args().collect();
+    let rt = tokio::runtime::Builder::new_current_thread()
+        .enable_all()
+        .build()
+        .expect("creating runtime");
+    let local = tokio::task::LocalSet::new();
+    let code = local.block_on(&rt, async {
+        match args.get(1).map(String::as_str) {
+            Some("server") if args.len() == 3 => match serve(&args[2]).await {
+                Ok(()) => 0,
+                Err(e) => {
+                    eprintln!("interop: {}", e);
+                    1
+                }
+            },
+            Some("client") if args.len() >= 3 => {
+                let mut ok = true;
+                for addr in &args[2..] {
+                    if !check_peer(addr).await {
+                        ok = false;
+                    }
+                }
+                if ok {
+                    0
+                } else {
+                    1
+                }
+            }
+            _ => {
+                eprintln!("usage: interop server ADDRESS | interop client ADDRESS...");
+                2
+            }
+        }
+    });
+    std::process::exit(code);
+}
@@ -0,0 +1,3 @@
+package interop
+
+//go:generate capnp compile -I ../../../std -ogo interop.capnp
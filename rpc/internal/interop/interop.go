@@ -0,0 +1,177 @@
+// Package interop checks that the rpc package interoperates with other
+// Cap'n Proto implementations.
+//
+// Every implementation in the suite serves an Interop capability that
+// behaves like Server as its bootstrap interface, and runs the same
+// checks as Checks against its peers' bootstrap interfaces.  The docker
+// directory builds the reference C++ and Rust implementations next to
+// the Go one and runs each implementation's checks against the others
+// over TCP; see docker/README.md.
+package interop // import "zombiezen.com/go/capnproto2/rpc/internal/interop"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+// Server is the Go implementation of Interop.
+type Server struct {
+	mu       sync.Mutex
+	canceled uint32
+}
+
+// NewServer returns a client for a new Server.
+func NewServer() Interop {
+	return Interop_ServerToClient(new(Server))
+}
+
+// Echo returns its argument.
+func (s *Server) Echo(call Interop_echo) error {
+	str, err := call.Params.S()
+	if err != nil {
+		return err
+	}
+	return call.Results.SetS(str)
+}
+
+// NewSession returns a new Server.
+func (s *Server) NewSession(call Interop_newSession) error {
+	return call.Results.SetSession(NewServer())
+}
+
+// Hang blocks until the call is canceled.
+func (s *Server) Hang(call Interop_hang) error {
+	// Acknowledge the call so that later calls are delivered while
+	// this one hangs.
+	server.Ack(call.Options)
+	<-call.Ctx.Done()
+	s.mu.Lock()
+	s.canceled++
+	s.mu.Unlock()
+	return call.Ctx.Err()
+}
+
+// Canceled returns the number of Hang calls that have been canceled.
+func (s *Server) Canceled(call Interop_canceled) error {
+	s.mu.Lock()
+	n := s.canceled
+	s.mu.Unlock()
+	call.Results.SetN(n)
+	return nil
+}
+
+// Fail returns an error with the given reason.
+func (s *Server) Fail(call Interop_fail) error {
+	reason, err := call.Params.Reason()
+	if err != nil {
+		return err
+	}
+	return errors.New(reason)
+}
+
+// A Check is a named interoperability check.  Run makes calls on a
+// peer's bootstrap capability and returns an error if the peer doesn't
+// behave like Server.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, peer Interop) error
+}
+
+// Checks is the suite that every implementation runs against its peers.
+var Checks = []Check{
+	{"bootstrap", checkBootstrap},
+	{"pipelining", checkPipelining},
+	{"cancellation", checkCancellation},
+	{"exceptions", checkExceptions},
+}
+
+// cancelTimeout is how long checkCancellation waits for the peer to
+// notice a canceled call.
+const cancelTimeout = 5 * time.Second
+
+func checkBootstrap(ctx context.Context, peer Interop) error {
+	return echo(ctx, peer, "hello")
+}
+
+func checkPipelining(ctx context.Context, peer Interop) error {
+	sess := peer.NewSession(ctx, nil)
+	// The echo is made on the session before newSession returns, so it
+	// must be pipelined.
+	if err := echo(ctx, sess.Session(), "pipelined"); err != nil {
+		return err
+	}
+	if _, err := sess.Struct(); err != nil {
+		return fmt.Errorf("newSession: %v", err)
+	}
+	return nil
+}
+
+func checkCancellation(ctx context.Context, peer Interop) error {
+	sess := peer.NewSession(ctx, nil).Session()
+	defer sess.Client.Close()
+	hctx, cancel := context.WithCancel(ctx)
+	hang := sess.Hang(hctx, nil)
+	// Calls are delivered in order, so the hang has been delivered once
+	// the echo returns.
+	if err := echo(ctx, sess, "before cancel"); err != nil {
+		return err
+	}
+	cancel()
+	if _, err := hang.Struct(); err == nil {
+		return errors.New("hang: returned without error after being canceled")
+	}
+	deadline := time.Now().Add(cancelTimeout)
+	for {
+		res, err := sess.Canceled(ctx, nil).Struct()
+		if err != nil {
+			return fmt.Errorf("canceled: %v", err)
+		}
+		if n := res.N(); n == 1 {
+			return nil
+		} else if n > 1 {
+			return fmt.Errorf("canceled = %d; want 1", n)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("peer did not notice canceled hang after %v", cancelTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func checkExceptions(ctx context.Context, peer Interop) error {
+	const reason = "interop failure"
+	_, err := peer.Fail(ctx, func(p Interop_fail_Params) error {
+		return p.SetReason(reason)
+	}).Struct()
+	if err == nil {
+		return errors.New("fail: returned without error")
+	}
+	if !strings.Contains(err.Error(), reason) {
+		return fmt.Errorf("fail: error %q does not contain reason %q", err.Error(), reason)
+	}
+	// The connection must still work after an exception.
+	return echo(ctx, peer, "after exception")
+}
+
+func echo(ctx context.Context, peer Interop, s string) error {
+	res, err := peer.Echo(ctx, func(p Interop_echo_Params) error {
+		return p.SetS(s)
+	}).Struct()
+	if err != nil {
+		return fmt.Errorf("echo(%q): %v", s, err)
+	}
+	got, err := res.S()
+	if err != nil {
+		return fmt.Errorf("echo(%q): %v", s, err)
+	}
+	if got != s {
+		return fmt.Errorf("echo(%q) = %q", s, got)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package interop_test
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/interop"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+)
+
+// TestChecks runs the suite against the Go implementation, so that the
+// checks stay in sync with Server.
+func TestChecks(t *testing.T) {
+	p, q := pipetransport.New()
+	c := rpc.NewConn(p)
+	d := rpc.NewConn(q, rpc.MainInterface(interop.NewServer().Client))
+	defer d.Wait()
+	defer c.Close()
+	runChecks(t, c)
+}
+
+// TestPeers runs the suite against the implementations listening at
+// the comma-separated addresses in $CAPNP_INTEROP_PEERS.  It is skipped
+// if the variable is not set.
+func TestPeers(t *testing.T) {
+	peers := os.Getenv("CAPNP_INTEROP_PEERS")
+	if peers == "" {
+		t.Skip("CAPNP_INTEROP_PEERS not set")
+	}
+	for _, addr := range strings.Split(peers, ",") {
+		nc, err := net.DialTimeout("tcp", addr, 10*time.Second)
+		if err != nil {
+			t.Errorf("%s: %v", addr, err)
+			continue
+		}
+		c := rpc.NewConn(rpc.StreamTransport(nc))
+		t.Logf("peer %s", addr)
+		runChecks(t, c)
+		c.Close()
+	}
+}
+
+func runChecks(t *testing.T, c *rpc.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	peer := interop.Interop{Client: c.Bootstrap(ctx)}
+	for _, chk := range interop.Checks {
+		if err := chk.Run(ctx, peer); err != nil {
+			t.Errorf("%s: %v", chk.Name, err)
+		}
+	}
+}
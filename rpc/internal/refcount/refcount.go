@@ -1,4 +1,16 @@
 // Package refcount implements a reference-counting client.
+//
+// Each Ref owns exactly one reference to the underlying client.  The
+// owner of a Ref must release it with exactly one of:
+//
+//   - Close, which drops the reference.
+//   - Steal, which moves the reference to a new Ref for another owner.
+//
+// Snapshot creates an additional, independently owned reference.  The
+// underlying client is closed once every reference has been dropped.
+// Using Snapshot and Steal instead of taking references from the
+// RefCount directly makes it clear at each hand-off whether the
+// reference count is being shared or transferred.
 package refcount
 
 import (
@@ -18,9 +30,13 @@ type RefCount struct {
 }
 
 // New creates a reference counter and the first client reference.
-func New(c capnp.Client) (rc *RefCount, ref1 capnp.Client) {
+// If c is already a live Ref, then New returns a snapshot of it and c
+// remains owned by the caller.
+func New(c capnp.Client) (rc *RefCount, ref1 *Ref) {
 	if rr, ok := c.(*Ref); ok {
-		return rr.rc, rr.rc.Ref()
+		if ref, err := rr.snapshot(); err == nil {
+			return rr.rc, ref
+		}
 	}
 	rc = &RefCount{Client: c, refs: 1}
 	ref1 = rc.newRef()
@@ -45,11 +61,11 @@ func (rc *RefCount) newRef() *Ref {
 	return r
 }
 
-func (rc *RefCount) call(cl *capnp.Call) capnp.Answer {
+func (rc *RefCount) call(r *Ref, cl *capnp.Call) capnp.Answer {
 	// We lock here so that we can prevent the client from being closed
 	// while we start the call.
 	rc.mu.Lock()
-	if rc.refs <= 0 {
+	if rc.refs <= 0 || r.released {
 		rc.mu.Unlock()
 		return capnp.ErrorAnswer(errClosed)
 	}
@@ -86,13 +102,13 @@ var (
 
 // A Ref is a single reference to a client wrapped by RefCount.
 type Ref struct {
-	rc   *RefCount
-	once sync.Once
+	rc       *RefCount
+	released bool // protected by rc.mu
 }
 
 // Call makes a call on the underlying client.
 func (r *Ref) Call(cl *capnp.Call) capnp.Answer {
-	return r.rc.call(cl)
+	return r.rc.call(r, cl)
 }
 
 // Client returns the underlying client.
@@ -100,17 +116,61 @@ func (r *Ref) Client() capnp.Client {
 	return r.rc.Client
 }
 
+// RefCount returns the reference counter that r belongs to.
+func (r *Ref) RefCount() *RefCount {
+	return r.rc
+}
+
+// Snapshot returns a new reference to the same client.  The caller
+// owns the new reference and must close it independently of r.
+// Snapshot on a closed or stolen Ref returns a client that fails all
+// calls.
+func (r *Ref) Snapshot() capnp.Client {
+	ref, err := r.snapshot()
+	if err != nil {
+		return capnp.ErrorClient(err)
+	}
+	return ref
+}
+
+func (r *Ref) snapshot() (*Ref, error) {
+	r.rc.mu.Lock()
+	if r.released || r.rc.refs <= 0 {
+		r.rc.mu.Unlock()
+		return nil, errClosed
+	}
+	r.rc.refs++
+	r.rc.mu.Unlock()
+	return r.rc.newRef(), nil
+}
+
+// Steal moves r's reference to a new Ref without changing the
+// reference count.  Afterward, r is released: calls on it fail and
+// closing it returns an error without affecting the new reference.
+// Steal on a closed or stolen Ref returns a client that fails all
+// calls.
+func (r *Ref) Steal() capnp.Client {
+	r.rc.mu.Lock()
+	if r.released || r.rc.refs <= 0 {
+		r.rc.mu.Unlock()
+		return capnp.ErrorClient(errClosed)
+	}
+	r.released = true
+	r.rc.mu.Unlock()
+	runtime.SetFinalizer(r, nil)
+	return r.rc.newRef()
+}
+
 // Close decrements the reference count.  Close will be called on
 // finalization (i.e. garbage collection).
 func (r *Ref) Close() error {
-	var err error
-	closed := false
-	r.once.Do(func() {
-		err = r.rc.decref()
-		closed = true
-	})
-	if !closed {
+	r.rc.mu.Lock()
+	if r.released {
+		r.rc.mu.Unlock()
 		return errClosed
 	}
-	return err
+	r.released = true
+	r.rc.mu.Unlock()
+	runtime.SetFinalizer(r, nil)
+	return r.rc.decref()
 }
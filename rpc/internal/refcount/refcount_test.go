@@ -75,6 +75,85 @@ func TestClosingAllRefsCloses(t *testing.T) {
 	}
 }
 
+func TestSnapshotAddsRef(t *testing.T) {
+	c := new(fakeClient)
+
+	_, ref1 := New(c)
+	ref2 := ref1.Snapshot()
+	if err := ref1.Close(); err != nil {
+		t.Errorf("ref1.Close(): %v", err)
+	}
+	if c.closed != 0 {
+		t.Errorf("client Close() called %d times after closing ref1; want 0 times", c.closed)
+	}
+	if err := ref2.Close(); err != nil {
+		t.Errorf("ref2.Close(): %v", err)
+	}
+	if c.closed != 1 {
+		t.Errorf("client Close() called %d times; want 1 time", c.closed)
+	}
+}
+
+func TestStealTransfersRef(t *testing.T) {
+	c := new(fakeClient)
+
+	_, ref1 := New(c)
+	ref2 := ref1.Steal()
+	if err := ref1.Close(); err != errClosed {
+		t.Errorf("ref1.Close() after Steal: %v; want %v", err, errClosed)
+	}
+	if c.closed != 0 {
+		t.Errorf("client Close() called %d times after closing stolen ref; want 0 times", c.closed)
+	}
+	if _, err := ref1.Call(new(capnp.Call)).Struct(); err != errClosed {
+		t.Errorf("ref1.Call() after Steal: %v; want %v", err, errClosed)
+	}
+	if err := ref2.Close(); err != nil {
+		t.Errorf("ref2.Close(): %v", err)
+	}
+	if c.closed != 1 {
+		t.Errorf("client Close() called %d times; want 1 time", c.closed)
+	}
+}
+
+func TestSnapshotAndStealAfterClose(t *testing.T) {
+	c := new(fakeClient)
+
+	rc, ref1 := New(c)
+	ref2 := rc.Ref()
+	ref1.Close()
+	if snap := ref1.Snapshot(); !capnp.IsErrorClient(snap) {
+		t.Errorf("ref1.Snapshot() after Close = %T; want error client", snap)
+	}
+	if stolen := ref1.Steal(); !capnp.IsErrorClient(stolen) {
+		t.Errorf("ref1.Steal() after Close = %T; want error client", stolen)
+	}
+	if err := ref2.Close(); err != nil {
+		t.Errorf("ref2.Close(): %v", err)
+	}
+	if c.closed != 1 {
+		t.Errorf("client Close() called %d times; want 1 time", c.closed)
+	}
+}
+
+func TestNewFromRefSnapshots(t *testing.T) {
+	c := new(fakeClient)
+
+	rc1, ref1 := New(c)
+	rc2, ref2 := New(ref1)
+	if rc1 != rc2 {
+		t.Error("New(ref) created a new RefCount; want existing")
+	}
+	ref1.Close()
+	if c.closed != 0 {
+		t.Errorf("client Close() called %d times; want 0 times", c.closed)
+	}
+	ref2.Close()
+	if c.closed != 1 {
+		t.Errorf("client Close() called %d times; want 1 time", c.closed)
+	}
+}
+
 type fakeClient struct {
 	closed int
 }
@@ -18,6 +18,12 @@ const Handle_TypeID = 0x8161ddf3e74bd0d1
 type Handle_Server interface {
 }
 
+// UnimplementedHandle_Server can be embedded in an implementation
+// of Handle_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedHandle_Server struct{}
+
 func Handle_ServerToClient(s Handle_Server) Handle {
 	c, _ := s.(server.Closer)
 	return Handle{Client: server.New(Handle_Methods(nil, s), c)}
@@ -61,6 +67,24 @@ type HandleFactory_Server interface {
 	NewHandle(HandleFactory_newHandle) error
 }
 
+// UnimplementedHandleFactory_Server can be embedded in an implementation
+// of HandleFactory_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedHandleFactory_Server struct{}
+
+func (UnimplementedHandleFactory_Server) NewHandle(HandleFactory_newHandle) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x8491a7fe75fe0bce,
+			MethodID:      0,
+			InterfaceName: "test.capnp:HandleFactory",
+			MethodName:    "newHandle",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func HandleFactory_ServerToClient(s HandleFactory_Server) HandleFactory {
 	c, _ := s.(server.Closer)
 	return HandleFactory{Client: server.New(HandleFactory_Methods(nil, s), c)}
@@ -96,6 +120,32 @@ type HandleFactory_newHandle struct {
 	Results HandleFactory_newHandle_Results
 }
 
+// TailCall delegates the call to newHandle on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c HandleFactory_newHandle) TailCall(t HandleFactory, params func(HandleFactory_newHandle_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x8491a7fe75fe0bce,
+			MethodID:      0,
+			InterfaceName: "test.capnp:HandleFactory",
+			MethodName:    "newHandle",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(HandleFactory_newHandle_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type HandleFactory_newHandle_Params struct{ capnp.Struct }
 
 // HandleFactory_newHandle_Params_TypeID is the unique identifier for the type HandleFactory_newHandle_Params.
@@ -259,6 +309,24 @@ type Hanger_Server interface {
 	Hang(Hanger_hang) error
 }
 
+// UnimplementedHanger_Server can be embedded in an implementation
+// of Hanger_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedHanger_Server struct{}
+
+func (UnimplementedHanger_Server) Hang(Hanger_hang) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x8ae08044aae8a26e,
+			MethodID:      0,
+			InterfaceName: "test.capnp:Hanger",
+			MethodName:    "hang",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func Hanger_ServerToClient(s Hanger_Server) Hanger {
 	c, _ := s.(server.Closer)
 	return Hanger{Client: server.New(Hanger_Methods(nil, s), c)}
@@ -294,6 +362,32 @@ type Hanger_hang struct {
 	Results Hanger_hang_Results
 }
 
+// TailCall delegates the call to hang on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Hanger_hang) TailCall(t Hanger, params func(Hanger_hang_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x8ae08044aae8a26e,
+			MethodID:      0,
+			InterfaceName: "test.capnp:Hanger",
+			MethodName:    "hang",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Hanger_hang_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type Hanger_hang_Params struct{ capnp.Struct }
 
 // Hanger_hang_Params_TypeID is the unique identifier for the type Hanger_hang_Params.
@@ -434,6 +528,24 @@ type CallOrder_Server interface {
 	GetCallSequence(CallOrder_getCallSequence) error
 }
 
+// UnimplementedCallOrder_Server can be embedded in an implementation
+// of CallOrder_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedCallOrder_Server struct{}
+
+func (UnimplementedCallOrder_Server) GetCallSequence(CallOrder_getCallSequence) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x92c5ca8314cdd2a5,
+			MethodID:      0,
+			InterfaceName: "test.capnp:CallOrder",
+			MethodName:    "getCallSequence",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func CallOrder_ServerToClient(s CallOrder_Server) CallOrder {
 	c, _ := s.(server.Closer)
 	return CallOrder{Client: server.New(CallOrder_Methods(nil, s), c)}
@@ -469,6 +581,32 @@ type CallOrder_getCallSequence struct {
 	Results CallOrder_getCallSequence_Results
 }
 
+// TailCall delegates the call to getCallSequence on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c CallOrder_getCallSequence) TailCall(t CallOrder, params func(CallOrder_getCallSequence_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x92c5ca8314cdd2a5,
+			MethodID:      0,
+			InterfaceName: "test.capnp:CallOrder",
+			MethodName:    "getCallSequence",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 8, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(CallOrder_getCallSequence_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type CallOrder_getCallSequence_Params struct{ capnp.Struct }
 
 // CallOrder_getCallSequence_Params_TypeID is the unique identifier for the type CallOrder_getCallSequence_Params.
@@ -647,6 +785,36 @@ type Echoer_Server interface {
 	GetCallSequence(CallOrder_getCallSequence) error
 }
 
+// UnimplementedEchoer_Server can be embedded in an implementation
+// of Echoer_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedEchoer_Server struct{}
+
+func (UnimplementedEchoer_Server) Echo(Echoer_echo) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x841756c6a41b2a45,
+			MethodID:      0,
+			InterfaceName: "test.capnp:Echoer",
+			MethodName:    "echo",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
+func (UnimplementedEchoer_Server) GetCallSequence(CallOrder_getCallSequence) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x92c5ca8314cdd2a5,
+			MethodID:      0,
+			InterfaceName: "test.capnp:CallOrder",
+			MethodName:    "getCallSequence",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func Echoer_ServerToClient(s Echoer_Server) Echoer {
 	c, _ := s.(server.Closer)
 	return Echoer{Client: server.New(Echoer_Methods(nil, s), c)}
@@ -696,6 +864,32 @@ type Echoer_echo struct {
 	Results Echoer_echo_Results
 }
 
+// TailCall delegates the call to echo on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Echoer_echo) TailCall(t Echoer, params func(Echoer_echo_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x841756c6a41b2a45,
+			MethodID:      0,
+			InterfaceName: "test.capnp:Echoer",
+			MethodName:    "echo",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Echoer_echo_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type Echoer_echo_Params struct{ capnp.Struct }
 
 // Echoer_echo_Params_TypeID is the unique identifier for the type Echoer_echo_Params.
@@ -882,6 +1076,24 @@ type PingPong_Server interface {
 	EchoNum(PingPong_echoNum) error
 }
 
+// UnimplementedPingPong_Server can be embedded in an implementation
+// of PingPong_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedPingPong_Server struct{}
+
+func (UnimplementedPingPong_Server) EchoNum(PingPong_echoNum) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xf004c474c2f8ee7a,
+			MethodID:      0,
+			InterfaceName: "test.capnp:PingPong",
+			MethodName:    "echoNum",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func PingPong_ServerToClient(s PingPong_Server) PingPong {
 	c, _ := s.(server.Closer)
 	return PingPong{Client: server.New(PingPong_Methods(nil, s), c)}
@@ -917,6 +1129,32 @@ type PingPong_echoNum struct {
 	Results PingPong_echoNum_Results
 }
 
+// TailCall delegates the call to echoNum on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c PingPong_echoNum) TailCall(t PingPong, params func(PingPong_echoNum_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xf004c474c2f8ee7a,
+			MethodID:      0,
+			InterfaceName: "test.capnp:PingPong",
+			MethodName:    "echoNum",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 8, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(PingPong_echoNum_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type PingPong_echoNum_Params struct{ capnp.Struct }
 
 // PingPong_echoNum_Params_TypeID is the unique identifier for the type PingPong_echoNum_Params.
@@ -1073,6 +1311,24 @@ type Adder_Server interface {
 	Add(Adder_add) error
 }
 
+// UnimplementedAdder_Server can be embedded in an implementation
+// of Adder_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedAdder_Server struct{}
+
+func (UnimplementedAdder_Server) Add(Adder_add) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x8f9cac550b1bf41f,
+			MethodID:      0,
+			InterfaceName: "test.capnp:Adder",
+			MethodName:    "add",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func Adder_ServerToClient(s Adder_Server) Adder {
 	c, _ := s.(server.Closer)
 	return Adder{Client: server.New(Adder_Methods(nil, s), c)}
@@ -1108,6 +1364,32 @@ type Adder_add struct {
 	Results Adder_add_Results
 }
 
+// TailCall delegates the call to add on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Adder_add) TailCall(t Adder, params func(Adder_add_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x8f9cac550b1bf41f,
+			MethodID:      0,
+			InterfaceName: "test.capnp:Adder",
+			MethodName:    "add",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 8, PointerCount: 0}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Adder_add_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type Adder_add_Params struct{ capnp.Struct }
 
 // Adder_add_Params_TypeID is the unique identifier for the type Adder_add_Params.
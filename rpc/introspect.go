@@ -25,6 +25,18 @@ dig:
 				return curr.Call(cl)
 			}
 			return curr.lockedCall(cl)
+		case *lazyImportClient:
+			if curr.conn != c {
+				// This doesn't use our conn's lock, so it is safe to call.
+				return curr.Call(cl)
+			}
+			client = curr.resolveLocked()
+			if client == nil {
+				return capnp.ErrorAnswer(errImportClosed)
+			}
+		case *capnp.FlowLimitedClient:
+			// Locked calls do not wait on flow limiters.
+			client = curr.Client()
 		case *fulfiller.EmbargoClient:
 			if ans := curr.TryQueue(cl); ans != nil {
 				return ans
@@ -44,7 +56,10 @@ dig:
 			client = curr.client
 		case *localAnswerClient:
 			curr.a.mu.Lock()
-			if curr.a.done {
+			if tail := curr.a.tailClient(curr.transform); tail != nil {
+				curr.a.mu.Unlock()
+				client = tail
+			} else if curr.a.done {
 				obj, err := curr.a.obj, curr.a.err
 				curr.a.mu.Unlock()
 				client = clientFromResolution(curr.transform, obj, err)
@@ -111,17 +126,30 @@ dig:
 }
 
 // descriptorForClient fills desc for client, adding it to the export
-// table if necessary.  The caller must be holding onto c.mu.
+// table if necessary.  Capabilities that were received on c are sent
+// back as receiverHosted or receiverAnswer descriptors, so that
+// proxying them does not grow the export table or lengthen the path a
+// call takes.  The caller must be holding onto c.mu.
 func (c *Conn) descriptorForClient(desc rpccapnp.CapDescriptor, client capnp.Client) error {
 dig:
 	for client := client; ; {
 		switch ct := client.(type) {
 		case *importClient:
-			if ct.conn != c {
+			if ct.conn != c || ct.closed {
+				// A closed import's ID may already have been released.
 				break dig
 			}
 			desc.SetReceiverHosted(uint32(ct.id))
 			return nil
+		case *lazyImportClient:
+			if ct.conn != c || ct.closed {
+				break dig
+			}
+			desc.SetReceiverHosted(uint32(ct.id))
+			return nil
+		case *capnp.FlowLimitedClient:
+			// The limiter only holds back calls made from this vat.
+			client = ct.Client()
 		case *fulfiller.EmbargoClient:
 			client = ct.Client()
 			if client == nil {
@@ -147,8 +175,13 @@ dig:
 			client = ct.client
 		case *localAnswerClient:
 			ct.a.mu.RLock()
+			tail := ct.a.tailClient(ct.transform)
 			obj, err, done := ct.a.obj, ct.a.err, ct.a.done
 			ct.a.mu.RUnlock()
+			if tail != nil {
+				client = tail
+				continue
+			}
 			if !done {
 				break dig
 			}
@@ -174,11 +207,11 @@ dig:
 				ans.mu.RLock()
 				obj, err, state := ans.obj, ans.err, ans.state
 				ans.mu.RUnlock()
-				if state != questionInProgress {
+				if state != questionInProgress && state != questionSentElsewhere {
 					client = clientFromResolution(transform, obj, err)
 					continue
 				}
-				if ans.conn != c {
+				if ans.conn != c || c.findQuestion(ans.id) != ans {
 					break dig
 				}
 				a, err := desc.NewReceiverAnswer()
@@ -199,7 +232,10 @@ dig:
 		}
 	}
 
-	id := c.addExport(client)
+	id, err := c.addExport(client)
+	if err != nil {
+		return err
+	}
 	desc.SetSenderHosted(uint32(id))
 	return nil
 }
@@ -275,13 +311,15 @@ func isSameClient(c, d capnp.Client) bool {
 	return norm(c) == norm(d)
 }
 
-// isImport returns the underlying import if client represents an import
-// or nil otherwise.
-func isImport(client capnp.Client) *importClient {
+// importConn returns the connection that client is imported from if
+// client represents an import or nil otherwise.
+func importConn(client capnp.Client) *Conn {
 	for {
 		switch curr := client.(type) {
 		case *importClient:
-			return curr
+			return curr.conn
+		case *lazyImportClient:
+			return curr.conn
 		case *fulfiller.EmbargoClient:
 			client = curr.Client()
 			if client == nil {
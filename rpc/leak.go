@@ -0,0 +1,81 @@
+package rpc
+
+import "fmt"
+
+// TableSizes is the number of entries in each of a connection's tables.
+type TableSizes struct {
+	Questions int // calls made on the peer that haven't been finished
+	Answers   int // calls from the peer that haven't been finished
+	Imports   int // capabilities received from the peer
+	Exports   int // capabilities sent to the peer
+	Embargoes int // disembargoes waiting for the peer's reply
+}
+
+// IsZero reports whether all of the tables are empty.
+func (ts TableSizes) IsZero() bool {
+	return ts == TableSizes{}
+}
+
+func (ts TableSizes) String() string {
+	return fmt.Sprintf("%d questions, %d answers, %d imports, %d exports, %d embargoes",
+		ts.Questions, ts.Answers, ts.Imports, ts.Exports, ts.Embargoes)
+}
+
+// TableSizes returns the number of entries in the connection's tables.
+// Once every call on the connection has returned and every capability
+// passed over it has been released, all of the tables are empty, so
+// tests can use TableSizes to find bookkeeping leaks.  The tables of a
+// closed connection are empty.
+func (c *Conn) TableSizes() TableSizes {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tableSizes()
+}
+
+// tableSizes counts the entries in the connection's tables.  The caller
+// holds onto c.mu.
+func (c *Conn) tableSizes() TableSizes {
+	ts := TableSizes{
+		Answers: len(c.answers),
+		Imports: len(c.imports),
+	}
+	for _, q := range c.questions {
+		if q != nil {
+			ts.Questions++
+		}
+	}
+	for _, e := range c.exports {
+		if e != nil {
+			ts.Exports++
+		}
+	}
+	for _, e := range c.embargoes {
+		if e != nil {
+			ts.Embargoes++
+		}
+	}
+	return ts
+}
+
+// CheckTablesOnClose makes Close return a *LeakError if any of the
+// connection's tables have entries when it is called.  It is meant for
+// tests that release everything before closing the connection.  Only
+// Close is checked: connections that are aborted or shut down by the
+// peer are not.
+func CheckTablesOnClose() ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.checkTables = true
+	}}
+}
+
+// A LeakError is returned by Close for a connection made with
+// CheckTablesOnClose whose tables were not empty.
+type LeakError struct {
+	// Sizes is the number of entries in the tables when the connection
+	// was closed.
+	Sizes TableSizes
+}
+
+func (e *LeakError) Error() string {
+	return "rpc: connection closed with entries in its tables: " + e.Sizes.String()
+}
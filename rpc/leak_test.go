@@ -0,0 +1,46 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestCheckTablesOnClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	defer c.Close()
+	srv := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	d := rpc.NewConn(q, rpc.MainInterface(srv.Client), rpc.ConnLog(log), rpc.CheckTablesOnClose())
+
+	// Bootstrap and never release, so d keeps the export.
+	client := c.Bootstrap(ctx)
+	if _, err := callseq(ctx, client, 0).Struct(); err != nil {
+		t.Fatal("call:", err)
+	}
+	if ts := d.TableSizes(); ts.Exports != 1 {
+		t.Errorf("d.TableSizes().Exports = %d; want 1", ts.Exports)
+	}
+
+	err := d.Close()
+	le, ok := err.(*rpc.LeakError)
+	if !ok {
+		t.Fatalf("d.Close() = %v; want *rpc.LeakError", err)
+	}
+	if le.Sizes.Exports != 1 {
+		t.Errorf("leaked exports = %d; want 1", le.Sizes.Exports)
+	}
+	if ts := d.TableSizes(); !ts.IsZero() {
+		t.Errorf("d.TableSizes() after Close = %v; want all zero", ts)
+	}
+}
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// Metrics receives measurements of the calls on a connection.
+//
+// Its methods are called while the connection's lock is held, so they
+// must not block or make calls on the connection.
+type Metrics interface {
+	// PayloadSize is called with the size of each call's params and
+	// results as they are sent or received.  size is the number of
+	// bytes in the segments of the message that carries the payload,
+	// which includes the message's small header and capability table.
+	// method is the method of the call; only its InterfaceID and
+	// MethodID are set for incoming params.  Bootstrap results are not
+	// measured.
+	PayloadSize(kind PayloadKind, method *capnp.Method, size uint64)
+}
+
+// ConnMetrics specifies where the connection reports its measurements.
+// By default, nothing is measured.
+func ConnMetrics(m Metrics) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.metrics = m
+	}}
+}
+
+// recordPayload reports the size of payload to the connection's
+// metrics.  The caller holds onto c.mu.
+func (c *Conn) recordPayload(kind PayloadKind, method *capnp.Method, payload rpccapnp.Payload) {
+	if c.metrics == nil || method == nil {
+		return
+	}
+	size, err := payload.Segment().Message().TotalSize()
+	if err != nil {
+		return
+	}
+	c.metrics.PayloadSize(kind, method, size)
+}
+
+// PayloadSizes is a Metrics that keeps a histogram of payload sizes for
+// each kind of payload of each method.  A PayloadSizes can be shared by
+// many connections, and it is safe to use from multiple goroutines.
+// The zero value is an empty set of histograms.
+type PayloadSizes struct {
+	mu    sync.Mutex
+	hists map[payloadKey]*PayloadSizeHistogram
+}
+
+type payloadKey struct {
+	interfaceID uint64
+	methodID    uint16
+	kind        PayloadKind
+}
+
+// A PayloadSizeHistogram counts the payloads of one kind for one
+// method by size.
+type PayloadSizeHistogram struct {
+	// Method is the method whose payloads are counted.  Its names are
+	// set if any of the payloads were for calls that had them.
+	Method capnp.Method
+	Kind   PayloadKind
+
+	Count uint64 // number of payloads
+	Sum   uint64 // total size of payloads in bytes
+
+	// Buckets[i] is the number of payloads of at most 1<<i bytes that
+	// are larger than 1<<(i-1) bytes.  Buckets[0] also counts empty
+	// payloads.  The slice is only as long as the largest payload needs.
+	Buckets []uint64
+}
+
+// PayloadSize adds a payload to the histogram for its kind and method.
+func (ps *PayloadSizes) PayloadSize(kind PayloadKind, method *capnp.Method, size uint64) {
+	k := payloadKey{method.InterfaceID, method.MethodID, kind}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	h := ps.hists[k]
+	if h == nil {
+		if ps.hists == nil {
+			ps.hists = make(map[payloadKey]*PayloadSizeHistogram)
+		}
+		h = &PayloadSizeHistogram{Method: *method, Kind: kind}
+		ps.hists[k] = h
+	}
+	if h.Method.InterfaceName == "" {
+		h.Method.InterfaceName = method.InterfaceName
+	}
+	if h.Method.MethodName == "" {
+		h.Method.MethodName = method.MethodName
+	}
+	h.Count++
+	h.Sum += size
+	i := 0
+	if size > 1 {
+		i = bits.Len64(size - 1)
+	}
+	for len(h.Buckets) <= i {
+		h.Buckets = append(h.Buckets, 0)
+	}
+	h.Buckets[i]++
+}
+
+// Histograms returns a copy of the histograms, sorted by interface ID,
+// method ID, and kind.
+func (ps *PayloadSizes) Histograms() []PayloadSizeHistogram {
+	ps.mu.Lock()
+	hists := make([]PayloadSizeHistogram, 0, len(ps.hists))
+	for _, h := range ps.hists {
+		hc := *h
+		hc.Buckets = append([]uint64(nil), h.Buckets...)
+		hists = append(hists, hc)
+	}
+	ps.mu.Unlock()
+	sort.Slice(hists, func(i, j int) bool {
+		mi, mj := &hists[i].Method, &hists[j].Method
+		if mi.InterfaceID != mj.InterfaceID {
+			return mi.InterfaceID < mj.InterfaceID
+		}
+		if mi.MethodID != mj.MethodID {
+			return mi.MethodID < mj.MethodID
+		}
+		return hists[i].Kind < hists[j].Kind
+	})
+	return hists
+}
+
+// Reset removes all the histograms.
+func (ps *PayloadSizes) Reset() {
+	ps.mu.Lock()
+	ps.hists = nil
+	ps.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+package rpc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestConnMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	sizes := new(rpc.PayloadSizes)
+	c := rpc.NewConn(p, rpc.ConnMetrics(sizes), rpc.ConnLog(log))
+	adder := testcapnp.Adder_ServerToClient(AdderServer{})
+	d := rpc.NewConn(q, rpc.MainInterface(adder.Client), rpc.ConnMetrics(sizes), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Adder{Client: c.Bootstrap(ctx)}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Add(ctx, func(p testcapnp.Adder_add_Params) error {
+			p.SetA(1)
+			p.SetB(2)
+			return nil
+		}).Struct()
+		if err != nil {
+			t.Fatal("add:", err)
+		}
+	}
+
+	hists := sizes.Histograms()
+	kinds := []rpc.PayloadKind{rpc.OutgoingParams, rpc.OutgoingResults, rpc.IncomingParams, rpc.IncomingResults}
+	if len(hists) != len(kinds) {
+		t.Fatalf("got %d histograms; want %d", len(hists), len(kinds))
+	}
+	for i, h := range hists {
+		if h.Kind != kinds[i] {
+			t.Errorf("hists[%d].Kind = %v; want %v", i, h.Kind, kinds[i])
+		}
+		if h.Method.InterfaceID != testcapnp.Adder_TypeID || h.Method.MethodID != 0 {
+			t.Errorf("hists[%d].Method = %v; want add", i, &h.Method)
+		}
+		// Only the caller knows the method's name.
+		want := ""
+		if h.Kind == rpc.OutgoingParams || h.Kind == rpc.IncomingResults {
+			want = "add"
+		}
+		if h.Method.MethodName != want {
+			t.Errorf("hists[%d].Method.MethodName = %q; want %q", i, h.Method.MethodName, want)
+		}
+		if h.Count != 2 || h.Sum == 0 {
+			t.Errorf("hists[%d] count = %d, sum = %d; want 2 payloads of some size", i, h.Count, h.Sum)
+		}
+	}
+}
+
+func TestPayloadSizes(t *testing.T) {
+	var sizes rpc.PayloadSizes
+	m := &capnp.Method{InterfaceID: 0x1234, MethodID: 5}
+	for _, n := range []uint64{0, 1, 2, 3, 8, 9} {
+		sizes.PayloadSize(rpc.OutgoingParams, m, n)
+	}
+	hists := sizes.Histograms()
+	if len(hists) != 1 {
+		t.Fatalf("got %d histograms; want 1", len(hists))
+	}
+	h := hists[0]
+	if h.Count != 6 || h.Sum != 23 {
+		t.Errorf("count = %d, sum = %d; want 6, 23", h.Count, h.Sum)
+	}
+	if want := []uint64{2, 1, 1, 1, 1}; !reflect.DeepEqual(h.Buckets, want) {
+		t.Errorf("buckets = %v; want %v", h.Buckets, want)
+	}
+	sizes.Reset()
+	if hists := sizes.Histograms(); len(hists) != 0 {
+		t.Errorf("after Reset, got %d histograms; want 0", len(hists))
+	}
+}
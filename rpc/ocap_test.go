@@ -0,0 +1,40 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/ocap"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestUnsealReturnedBox(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	echoSrv := testcapnp.Echoer_ServerToClient(new(Echoer))
+	d := rpc.NewConn(q, rpc.MainInterface(echoSrv.Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	sealer, unsealer := ocap.NewSealerPair("test")
+	box := sealer.Seal(testcapnp.CallOrder_ServerToClient(new(CallOrder)).Client)
+	res, err := client.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: box})
+	}).Struct()
+	if err != nil {
+		t.Fatal("Echo:", err)
+	}
+	if _, err := unsealer.Unseal(res.Cap().Client); err != nil {
+		t.Error("Unseal of box returned by peer:", err)
+	}
+}
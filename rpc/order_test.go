@@ -0,0 +1,111 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	"zombiezen.com/go/capnproto2/rpc/ordertest"
+)
+
+// newConnPair connects a vat serving main to a new client vat.  It
+// returns the client vat's connection and a function that closes both
+// connections.
+func newConnPair(t *testing.T, main capnp.Client) (*rpc.Conn, func()) {
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	d := rpc.NewConn(q, rpc.MainInterface(main), rpc.ConnLog(log))
+	return c, func() {
+		c.Close()
+		d.Close()
+	}
+}
+
+// TestPromiseResolvesToOtherConn checks that calls pipelined on a
+// question whose answer is a capability imported over another
+// connection are delivered in order: the calls made before the answer
+// arrives take the long way around, through the answering vat, and the
+// later calls must wait for them.
+func TestPromiseResolvesToOtherConn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	toEcho, closeEcho := newConnPair(t, testcapnp.Echoer_ServerToClient(new(Echoer)).Client)
+	defer closeEcho()
+	toCounter, closeCounter := newConnPair(t, new(ordertest.Counter))
+	defer closeCounter()
+	echo := testcapnp.Echoer{Client: toEcho.Bootstrap(ctx)}
+	counter := toCounter.Bootstrap(ctx)
+
+	ans := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: counter})
+	})
+	pipeline := ans.Cap().Client
+	var calls []capnp.Answer
+	for i := 0; i < 3; i++ {
+		calls = append(calls, ordertest.Call(ctx, pipeline))
+	}
+	if _, err := ans.Struct(); err != nil {
+		t.Fatal("echo:", err)
+	}
+	for i := 0; i < 3; i++ {
+		calls = append(calls, ordertest.Call(ctx, pipeline))
+	}
+	if err := ordertest.Check(calls); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestAnswerResolvesToOtherConn checks that calls queued on an answer
+// are delivered in order when the answer resolves to a capability that
+// the answering vat imported over another connection.
+func TestAnswerResolvesToOtherConn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	toCounter, closeCounter := newConnPair(t, new(ordertest.Counter))
+	defer closeCounter()
+	delay := make(chan struct{})
+	hub := &hubEchoer{cap: toCounter.Bootstrap(ctx), delay: delay}
+	toHub, closeHub := newConnPair(t, testcapnp.Echoer_ServerToClient(hub).Client)
+	defer closeHub()
+	echo := testcapnp.Echoer{Client: toHub.Bootstrap(ctx)}
+
+	// The hub holds the echo until delay is closed, so the first calls
+	// are queued on its answer.
+	ans := echo.Echo(ctx, nil)
+	pipeline := ans.Cap().Client
+	var calls []capnp.Answer
+	for i := 0; i < 3; i++ {
+		calls = append(calls, ordertest.Call(ctx, pipeline))
+	}
+	close(delay)
+	if _, err := ans.Struct(); err != nil {
+		t.Fatal("echo:", err)
+	}
+	for i := 0; i < 3; i++ {
+		calls = append(calls, ordertest.Call(ctx, pipeline))
+	}
+	if err := ordertest.Check(calls); err != nil {
+		t.Error(err)
+	}
+}
+
+// hubEchoer is an Echoer that returns cap from every echo once delay is
+// closed, whatever capability it is sent.
+type hubEchoer struct {
+	CallOrder
+	cap   capnp.Client
+	delay <-chan struct{}
+}
+
+func (h *hubEchoer) Echo(call testcapnp.Echoer_echo) error {
+	<-h.delay
+	return call.Results.SetCap(testcapnp.CallOrder{Client: h.cap})
+}
@@ -0,0 +1,89 @@
+// Package ordertest checks that calls on a capability are delivered in
+// the order that they were made, however many promises and connections
+// they pass through on the way.
+//
+// Serve a Counter, hand it to the code under test, and make calls with
+// Call on the capability that the code hands back, including calls
+// pipelined on a promise for it.  Check then verifies that the Counter
+// received the calls in the order they were made.
+package ordertest // import "zombiezen.com/go/capnproto2/rpc/ordertest"
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+)
+
+// Method is the method that Call calls.  A Counter numbers calls to any
+// method, so Method only needs to get through the code under test.
+var Method = capnp.Method{
+	InterfaceID:   0xe0a9a6d4c1f2b387,
+	MethodID:      0,
+	InterfaceName: "ordertest",
+	MethodName:    "count",
+}
+
+// resultsSize is the size of a call's results: a single word holding
+// the call's number.
+var resultsSize = capnp.ObjectSize{DataSize: 8}
+
+// A Counter is a capability that numbers the calls it receives, in the
+// order it receives them, starting at 0.  Each call returns a struct
+// whose first word is the call's number.  It is safe to use from
+// multiple goroutines.
+type Counter struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+// Call numbers call and returns its number.
+func (c *Counter) Call(call *capnp.Call) capnp.Answer {
+	c.mu.Lock()
+	n := c.n
+	c.n++
+	c.mu.Unlock()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	s, err := capnp.NewRootStruct(seg, resultsSize)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	s.SetUint64(0, n)
+	return capnp.ImmediateAnswer(s)
+}
+
+// Close does nothing.
+func (c *Counter) Close() error {
+	return nil
+}
+
+// Call makes a call on client for a Counter to number.
+func Call(ctx context.Context, client capnp.Client) capnp.Answer {
+	return client.Call(&capnp.Call{
+		Ctx:        ctx,
+		Method:     Method,
+		ParamsSize: capnp.ObjectSize{},
+		ParamsFunc: func(capnp.Struct) error { return nil },
+	})
+}
+
+// Check waits for answers, which are from calls made in order with
+// Call, and returns an error if a call failed or the calls reached the
+// Counter out of order.  The answers must be from the first calls that
+// the Counter received.
+func Check(answers []capnp.Answer) error {
+	for i, a := range answers {
+		s, err := a.Struct()
+		if err != nil {
+			return fmt.Errorf("ordertest: call %d: %v", i, err)
+		}
+		if n := s.Uint64(0); n != uint64(i) {
+			return fmt.Errorf("ordertest: call %d was delivered as call %d", i, n)
+		}
+	}
+	return nil
+}
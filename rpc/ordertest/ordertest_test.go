@@ -0,0 +1,26 @@
+package ordertest_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc/ordertest"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	c := new(ordertest.Counter)
+	answers := []capnp.Answer{
+		ordertest.Call(ctx, c),
+		ordertest.Call(ctx, c),
+		ordertest.Call(ctx, c),
+	}
+	if err := ordertest.Check(answers); err != nil {
+		t.Error("Check:", err)
+	}
+	answers[0], answers[1] = answers[1], answers[0]
+	if err := ordertest.Check(answers); err == nil {
+		t.Error("Check of reordered answers = <nil>; want error")
+	}
+}
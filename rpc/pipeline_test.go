@@ -0,0 +1,78 @@
+package rpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+// newDelayEchoConns returns a client connection to a new server
+// connection that exports a DelayEchoer.
+func newDelayEchoConns(t *testing.T, delay chan struct{}, opts ...rpc.ConnOption) (c, d *rpc.Conn) {
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c = rpc.NewConn(p, rpc.ConnLog(log))
+	echoSrv := testcapnp.Echoer_ServerToClient(&DelayEchoer{delay: delay})
+	opts = append(opts, rpc.MainInterface(echoSrv.Client), rpc.ConnLog(log))
+	d = rpc.NewConn(q, opts...)
+	return c, d
+}
+
+func TestMaxPipelineDepth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delay := make(chan struct{})
+	c, d := newDelayEchoConns(t, delay, rpc.MaxPipelineDepth(1))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	echo := client.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: client.Client})
+	})
+	shallow := callseq(ctx, echo.Cap().Client, 0)
+	deep := callseq(ctx, echo.GetPipeline(0).GetPipeline(0).Client(), 0)
+	close(delay)
+
+	if _, err := shallow.Struct(); err != nil {
+		t.Error("call at depth 1:", err)
+	}
+	if _, err := deep.Struct(); err == nil || !strings.Contains(err.Error(), "too deep") {
+		t.Errorf("call at depth 2: error = %v; want too deep", err)
+	}
+}
+
+func TestMaxQueuedCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delay := make(chan struct{})
+	c, d := newDelayEchoConns(t, delay, rpc.MaxQueuedCalls(1))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	echo := client.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: client.Client})
+	})
+	call0 := callseq(ctx, echo.Cap().Client, 0)
+	call1 := callseq(ctx, echo.Cap().Client, 1)
+	flushConn(ctx, c)
+	close(delay)
+
+	if r, err := call0.Struct(); err != nil {
+		t.Error("call0:", err)
+	} else if r.N() != 0 {
+		t.Errorf("call0 = %d; want 0", r.N())
+	}
+	if _, err := call1.Struct(); err == nil || !strings.Contains(err.Error(), "queue full") {
+		t.Errorf("call1: error = %v; want queue full", err)
+	}
+}
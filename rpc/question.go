@@ -29,6 +29,76 @@ func (c *Conn) newQuestion(ctx context.Context, method *capnp.Method) *question
 	return q
 }
 
+// sendCall adds a question for the Call message msg and sends it.
+// done, if not nil, is called once the question is resolved, or with
+// the error if the message cannot be sent.  The caller must be holding
+// onto c.mu.
+func (c *Conn) sendCall(msg rpccapnp.Message, cl *capnp.Call, done func(error)) (*question, error) {
+	q := c.newQuestion(cl.Ctx, &cl.Method)
+	msgCall, _ := msg.Call()
+	msgCall.SetQuestionId(uint32(q.id))
+	q.yourself = msgCall.SendResultsTo().Which() == rpccapnp.Call_sendResultsTo_Which_yourself
+	var err error
+	select {
+	case c.out <- msg:
+		q.flowDone = done
+		q.start()
+		if q.yourself {
+			c.linkTail(q, cl)
+		}
+		return q, nil
+	case <-cl.Ctx.Done():
+		err = cl.Ctx.Err()
+	case <-c.bg.Done():
+		err = ErrConnClosed
+	}
+	c.popQuestion(q.id)
+	if done != nil {
+		done(err)
+	}
+	return nil, err
+}
+
+// startFlow waits for the flow limiter attached to cl, if any, to allow
+// the Call message msg to be sent, and returns the function to call
+// once its question is resolved.  If cl has no flow limiter, startFlow
+// returns a nil function without waiting.
+//
+// The caller must be holding onto c.mu and have started work.  Both
+// are released while waiting, so the caller must check any state it
+// depends on again once startFlow returns.  If startFlow returns an
+// error, the caller is no longer holding onto either of them.
+func (c *Conn) startFlow(cl *capnp.Call, msg rpccapnp.Message) (done func(error), err error) {
+	f := capnp.CallFlow(cl)
+	if f == nil {
+		return nil, nil
+	}
+	size, err := msg.Segment().Message().TotalSize()
+	if err != nil {
+		c.workers.Done()
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.workers.Done()
+	c.mu.Unlock()
+	done, err = f.Start(cl.Ctx, size)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-c.mu:
+	case <-cl.Ctx.Done():
+		done(cl.Ctx.Err())
+		return nil, cl.Ctx.Err()
+	}
+	if err := c.startWork(); err != nil {
+		c.mu.Unlock()
+		done(err)
+		return nil, err
+	}
+	return done, nil
+}
+
 func (c *Conn) findQuestion(id questionID) *question {
 	if int(id) >= len(c.questions) {
 		return nil
@@ -53,9 +123,12 @@ type question struct {
 	method    *capnp.Method // nil if this is bootstrap
 	paramCaps []exportID
 	resolved  chan struct{}
+	flowDone  func(error) // called once resolved; nil if the call has no flow limiter
+	yourself  bool        // the call was sent with sendResultsTo.yourself
 
 	// Protected by conn.mu
 	derived [][]capnp.PipelineOp
+	tail    *answer // answer that the call was forwarded for, if yourself is set
 
 	// Fields below are protected by mu.
 	mu    sync.RWMutex
@@ -71,6 +144,12 @@ const (
 	questionInProgress questionState = iota
 	questionResolved
 	questionCanceled
+
+	// questionSentElsewhere is the state of a question whose results
+	// the peer kept because the call was sent with
+	// sendResultsTo.yourself.  The question stays in the table, so that
+	// pipelined calls can target it, until its tail answer is finished.
+	questionSentElsewhere
 )
 
 // start signals that the question has been sent.
@@ -117,7 +196,7 @@ func (q *question) fulfill(obj capnp.Ptr) {
 		if !in.IsValid() {
 			continue
 		}
-		if ic := isImport(in.Client()); ic != nil && ic.conn == q.conn {
+		if importConn(in.Client()) == q.conn {
 			// Imported from remote vat.  Don't need to disembargo.
 			continue
 		}
@@ -150,6 +229,9 @@ func (q *question) fulfill(obj capnp.Ptr) {
 	q.obj, q.state = obj, questionResolved
 	close(q.resolved)
 	q.mu.Unlock()
+	if q.flowDone != nil {
+		q.flowDone(nil)
+	}
 }
 
 // reject is called to resolve a question with failure.
@@ -166,6 +248,25 @@ func (q *question) reject(err error) {
 	q.state = questionResolved
 	close(q.resolved)
 	q.mu.Unlock()
+	if q.flowDone != nil {
+		q.flowDone(err)
+	}
+}
+
+// sentElsewhere is called when the peer has kept the question's
+// results.  The caller must be holding onto q.conn.mu.
+func (q *question) sentElsewhere() {
+	q.mu.Lock()
+	if q.state != questionInProgress {
+		panic("question.sentElsewhere called on resolved question")
+	}
+	q.err = errResultsSentElsewhere
+	q.state = questionSentElsewhere
+	close(q.resolved)
+	q.mu.Unlock()
+	if q.flowDone != nil {
+		q.flowDone(nil)
+	}
 }
 
 // cancel is called to resolve a question with cancellation.
@@ -182,6 +283,9 @@ func (q *question) cancel(err error) bool {
 		close(q.resolved)
 	}
 	q.mu.Unlock()
+	if canceled && q.flowDone != nil {
+		q.flowDone(err)
+	}
 	return canceled
 }
 
@@ -231,60 +335,101 @@ func (q *question) PipelineCall(transform []capnp.PipelineOp, ccall *capnp.Call)
 	case <-ccall.Ctx.Done():
 		return capnp.ErrorAnswer(ccall.Ctx.Err())
 	}
-	ans := q.lockedPipelineCall(transform, ccall)
+	if q.conn.findQuestion(q.id) != q {
+		// Question has been finished.  The call is made on its result
+		// without holding the lock, so that the call's flow limiter, if
+		// any, is applied.
+		client := q.resolution(transform)
+		q.conn.workers.Done()
+		q.conn.mu.Unlock()
+		return client.Call(ccall)
+	}
+	msg, err := q.newPipelineCallMessage(transform, ccall)
+	if err != nil {
+		q.conn.workers.Done()
+		q.conn.mu.Unlock()
+		return capnp.ErrorAnswer(err)
+	}
+	done, err := q.conn.startFlow(ccall, msg)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	var ans capnp.Answer
+	if q.conn.findQuestion(q.id) != q {
+		// Finished while waiting for the flow limiter.  The message
+		// targets a question that is gone, so the call is made on the
+		// result instead.
+		if done != nil {
+			done(nil)
+		}
+		ans = q.conn.lockedCall(q.resolution(transform), ccall)
+	} else if pipeq, err := q.conn.sendCall(msg, ccall, done); err != nil {
+		ans = capnp.ErrorAnswer(err)
+	} else {
+		q.addPromise(transform)
+		ans = pipeq
+	}
 	q.conn.workers.Done()
 	q.conn.mu.Unlock()
 	return ans
 }
 
 // lockedPipelineCall is equivalent to PipelineCall but assumes that the
-// caller is already holding onto q.conn.mu.
+// caller is already holding onto q.conn.mu.  It does not wait for the
+// call's flow limiter.
 func (q *question) lockedPipelineCall(transform []capnp.PipelineOp, ccall *capnp.Call) capnp.Answer {
 	if q.conn.findQuestion(q.id) != q {
 		// Question has been finished.  The call should happen as if it is
 		// back in application code.
-		q.mu.RLock()
-		obj, err, state := q.obj, q.err, q.state
-		q.mu.RUnlock()
-		if state == questionInProgress {
-			panic("question popped but not done")
-		}
-		client := clientFromResolution(transform, obj, err)
-		return q.conn.lockedCall(client, ccall)
+		return q.conn.lockedCall(q.resolution(transform), ccall)
 	}
 
-	pipeq := q.conn.newQuestion(ccall.Ctx, &ccall.Method)
+	msg, err := q.newPipelineCallMessage(transform, ccall)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	pipeq, err := q.conn.sendCall(msg, ccall, nil)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	q.addPromise(transform)
+	return pipeq
+}
+
+// resolution returns the client for transform in the result of q,
+// which must have been finished.
+func (q *question) resolution(transform []capnp.PipelineOp) capnp.Client {
+	q.mu.RLock()
+	obj, err, state := q.obj, q.err, q.state
+	q.mu.RUnlock()
+	if state == questionInProgress {
+		panic("question popped but not done")
+	}
+	return clientFromResolution(transform, obj, err)
+}
+
+// newPipelineCallMessage builds a Call message for ccall targeting the
+// answer of q.  Its question ID is set by sendCall.  The caller must be
+// holding onto q.conn.mu.
+func (q *question) newPipelineCallMessage(transform []capnp.PipelineOp, ccall *capnp.Call) (rpccapnp.Message, error) {
 	msg := newMessage(nil)
 	msgCall, _ := msg.NewCall()
-	msgCall.SetQuestionId(uint32(pipeq.id))
 	msgCall.SetInterfaceId(ccall.Method.InterfaceID)
 	msgCall.SetMethodId(ccall.Method.MethodID)
+	if q.conn.sendResultsToYourself(ccall) {
+		msgCall.SendResultsTo().SetYourself()
+	}
 	target, _ := msgCall.NewTarget()
 	a, _ := target.NewPromisedAnswer()
 	a.SetQuestionId(uint32(q.id))
-	err := transformToPromisedAnswer(a.Segment(), a, transform)
-	if err != nil {
-		q.conn.popQuestion(pipeq.id)
-		return capnp.ErrorAnswer(err)
+	if err := transformToPromisedAnswer(a.Segment(), a, transform); err != nil {
+		return rpccapnp.Message{}, err
 	}
 	payload, _ := msgCall.NewParams()
 	if err := q.conn.fillParams(payload, ccall); err != nil {
-		q.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(err)
+		return rpccapnp.Message{}, err
 	}
-
-	select {
-	case q.conn.out <- msg:
-	case <-ccall.Ctx.Done():
-		q.conn.popQuestion(pipeq.id)
-		return capnp.ErrorAnswer(ccall.Ctx.Err())
-	case <-q.conn.bg.Done():
-		q.conn.popQuestion(pipeq.id)
-		return capnp.ErrorAnswer(ErrConnClosed)
-	}
-	q.addPromise(transform)
-	pipeq.start()
-	return pipeq
+	return msg, nil
 }
 
 func (q *question) PipelineClose(transform []capnp.PipelineOp) error {
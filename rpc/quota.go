@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// An ExportQuota limits the capabilities that a vat exports to each of
+// its peers, counted across all of a peer's connections.  Peers are
+// named by an identity string that the application establishes, for
+// example from a TLS client certificate, and passes to LimitExports
+// for each connection.  It is safe to use from multiple goroutines.
+//
+// A capability counts against the quota while it is in a connection's
+// export table: from the first time it is sent to the peer until the
+// peer releases it or the connection closes.  Sending a capability that
+// the peer already holds does not count again.
+type ExportQuota struct {
+	// MaxExports is the maximum number of capabilities exported to a
+	// peer at once.  Zero means no limit.
+	MaxExports int
+
+	// MaxBytes is the maximum total size of the capabilities exported
+	// to a peer at once, as reported by Size.  Zero means no limit.
+	MaxBytes int64
+
+	// Size reports the memory that an exported capability holds.  It is
+	// called with the connection locked, so it must not block or make
+	// calls.  If Size is nil, MaxBytes is not enforced.
+	Size func(capnp.Client) int64
+
+	// Abort controls what happens when a connection would exceed the
+	// quota.  If false, the message that would export the capability
+	// fails instead: an outgoing call returns ErrExportQuota and an
+	// incoming call returns an exception to the peer.  If true, the
+	// connection is aborted.
+	Abort bool
+
+	mu    sync.Mutex
+	peers map[string]*exportUsage
+}
+
+type exportUsage struct {
+	exports int
+	bytes   int64
+}
+
+// LimitExports counts the connection's exports against q under the
+// peer identity peer.  Passing the same quota and identity to several
+// connections shares the limit between them.
+func LimitExports(q *ExportQuota, peer string) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.exportQuota = q
+		c.peer = peer
+	}}
+}
+
+// Usage returns the number and total size of the capabilities exported
+// to peer.
+func (q *ExportQuota) Usage(peer string) (exports int, bytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.peers[peer]
+	if u == nil {
+		return 0, 0
+	}
+	return u.exports, u.bytes
+}
+
+// size returns the size that client counts for.
+func (q *ExportQuota) size(client capnp.Client) int64 {
+	if q.Size == nil {
+		return 0
+	}
+	return q.Size(client)
+}
+
+// acquire counts an export of the given size against peer's quota,
+// returning ErrExportQuota if it would exceed the quota.
+func (q *ExportQuota) acquire(peer string, size int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.peers[peer]
+	if u == nil {
+		u = new(exportUsage)
+	}
+	if q.MaxExports > 0 && u.exports+1 > q.MaxExports {
+		return ErrExportQuota
+	}
+	if q.MaxBytes > 0 && q.Size != nil && u.bytes+size > q.MaxBytes {
+		return ErrExportQuota
+	}
+	if q.peers == nil {
+		q.peers = make(map[string]*exportUsage)
+	}
+	q.peers[peer] = u
+	u.exports++
+	u.bytes += size
+	return nil
+}
+
+// resize changes the size of one of peer's exports from oldSize to
+// newSize, returning ErrExportQuota and leaving the usage unchanged if
+// the new size would exceed the quota.
+func (q *ExportQuota) resize(peer string, oldSize, newSize int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.peers[peer]
+	if u == nil {
+		return nil
+	}
+	if q.MaxBytes > 0 && q.Size != nil && newSize > oldSize && u.bytes-oldSize+newSize > q.MaxBytes {
+		return ErrExportQuota
+	}
+	u.bytes += newSize - oldSize
+	return nil
+}
+
+// release returns an export acquired with acquire.
+func (q *ExportQuota) release(peer string, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.peers[peer]
+	if u == nil {
+		return
+	}
+	u.exports--
+	u.bytes -= size
+	if u.exports <= 0 {
+		delete(q.peers, peer)
+	}
+}
@@ -0,0 +1,103 @@
+package rpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+// newQuotaConns returns a client connection to a new server connection
+// that exports a HandleFactory, charged to peer on quota.
+func newQuotaConns(t *testing.T, hf *HandleFactory, quota *rpc.ExportQuota, peer string) (c, d *rpc.Conn) {
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c = rpc.NewConn(p, rpc.ConnLog(log))
+	main := testcapnp.HandleFactory_ServerToClient(hf).Client
+	d = rpc.NewConn(q, rpc.MainInterface(main), rpc.LimitExports(quota, peer), rpc.ConnLog(log))
+	return c, d
+}
+
+func isExportQuotaErr(err error) bool {
+	return err != nil && strings.Contains(err.Error(), rpc.ErrExportQuota.Error())
+}
+
+func TestExportQuota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	quota := &rpc.ExportQuota{MaxExports: 2}
+	hf := new(HandleFactory)
+	c1, d1 := newQuotaConns(t, hf, quota, "alice")
+	defer d1.Wait()
+	defer c1.Close()
+
+	client := testcapnp.HandleFactory{Client: c1.Bootstrap(ctx)}
+	r, err := client.NewHandle(ctx, nil).Struct()
+	if err != nil {
+		t.Fatal("NewHandle #1:", err)
+	}
+	if n, _ := quota.Usage("alice"); n != 2 {
+		t.Errorf("after NewHandle, usage = %d exports; want 2", n)
+	}
+	if _, err := client.NewHandle(ctx, nil).Struct(); !isExportQuotaErr(err) {
+		t.Errorf("NewHandle over quota: error = %v; want %v", err, rpc.ErrExportQuota)
+	}
+	if n, _ := quota.Usage("alice"); n != 2 {
+		t.Errorf("after rejected NewHandle, usage = %d exports; want 2", n)
+	}
+	if err := r.Handle().Client.Close(); err != nil {
+		t.Error("handle.Client.Close():", err)
+	}
+	flushConn(ctx, c1)
+	if n, _ := quota.Usage("alice"); n != 1 {
+		t.Errorf("after release, usage = %d exports; want 1", n)
+	}
+
+	// Another connection from the same peer shares the quota.
+	c2, d2 := newQuotaConns(t, hf, quota, "alice")
+	client2 := testcapnp.HandleFactory{Client: c2.Bootstrap(ctx)}
+	if _, err := client2.NewHandle(ctx, nil).Struct(); !isExportQuotaErr(err) {
+		t.Errorf("NewHandle on second connection: error = %v; want %v", err, rpc.ErrExportQuota)
+	}
+	// Other peers have quotas of their own.
+	c3, d3 := newQuotaConns(t, hf, quota, "bob")
+	client3 := testcapnp.HandleFactory{Client: c3.Bootstrap(ctx)}
+	if _, err := client3.NewHandle(ctx, nil).Struct(); err != nil {
+		t.Error("NewHandle for another peer:", err)
+	}
+	for _, c := range []*rpc.Conn{c2, d2, c3, d3} {
+		c.Close()
+	}
+	if n, _ := quota.Usage("alice"); n != 1 {
+		t.Errorf("after closing second connection, usage = %d exports; want 1", n)
+	}
+	if n, _ := quota.Usage("bob"); n != 0 {
+		t.Errorf("after closing, usage for bob = %d exports; want 0", n)
+	}
+}
+
+func TestExportQuota_Abort(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	quota := &rpc.ExportQuota{MaxExports: 1, Abort: true}
+	c, d := newQuotaConns(t, new(HandleFactory), quota, "alice")
+	defer c.Close()
+
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+	if _, err := client.NewHandle(ctx, nil).Struct(); err == nil {
+		t.Error("NewHandle over quota succeeded")
+	}
+	// The server aborts, which closes both connections.
+	<-c.Done()
+	<-d.Done()
+	if n, _ := quota.Usage("alice"); n != 0 {
+		t.Errorf("after abort, usage = %d exports; want 0", n)
+	}
+}
@@ -0,0 +1,163 @@
+package rpc_test
+
+import (
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestReexportImport(t *testing.T) {
+	tests := []struct {
+		name   string
+		server testcapnp.Echoer_Server
+	}{
+		{"Echoer", new(Echoer)},
+		{"FlowLimitedEchoer", new(FlowLimitedEchoer)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p, q := pipetransport.New()
+			if *logMessages {
+				p = logtransport.New(nil, p)
+			}
+			log := testLogger{t}
+			c := rpc.NewConn(p, rpc.ConnLog(log))
+			rt := &returnTransport{Transport: q}
+			d := rpc.NewConn(rt, rpc.MainInterface(testcapnp.Echoer_ServerToClient(test.server).Client), rpc.ConnLog(log))
+			defer d.Wait()
+			defer c.Close()
+			echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+			order := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+			res, err := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+				return p.SetCap(order)
+			}).Struct()
+			if err != nil {
+				t.Fatal("echo:", err)
+			}
+			seq, err := res.Cap().GetCallSequence(ctx, nil).Struct()
+			if err != nil {
+				t.Fatal("getCallSequence:", err)
+			}
+			if n := seq.N(); n != 0 {
+				t.Errorf("getCallSequence() = %d; want 0", n)
+			}
+
+			rt.mu.Lock()
+			defer rt.mu.Unlock()
+			want := []rpccapnp.CapDescriptor_Which{rpccapnp.CapDescriptor_Which_senderHosted, rpccapnp.CapDescriptor_Which_receiverHosted}
+			if len(rt.descs) != len(want) {
+				t.Fatalf("returned capabilities = %v; want %v", rt.descs, want)
+			}
+			for i := range want {
+				if rt.descs[i] != want[i] {
+					t.Errorf("returned capabilities = %v; want %v", rt.descs, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestReturnReceiverAnswer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	rt := &returnTransport{Transport: q}
+	d := rpc.NewConn(rt, rpc.MainInterface(testcapnp.Echoer_ServerToClient(new(ForwardEchoer)).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	// d forwards the echo back to the local echoer, which holds it until
+	// delay is closed, and returns a promise for its result.
+	delay := make(chan struct{})
+	local := testcapnp.Echoer_ServerToClient(&DelayEchoer{delay: delay})
+	res, err := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: local.Client})
+	}).Struct()
+	if err != nil {
+		t.Fatal("echo:", err)
+	}
+	call0 := callseq(ctx, res.Cap().Client, 0)
+	close(delay)
+	seq, err := call0.Struct()
+	if err != nil {
+		t.Fatal("getCallSequence:", err)
+	}
+	if n := seq.N(); n != 0 {
+		t.Errorf("getCallSequence() = %d; want 0", n)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	want := []rpccapnp.CapDescriptor_Which{rpccapnp.CapDescriptor_Which_senderHosted, rpccapnp.CapDescriptor_Which_receiverAnswer}
+	if len(rt.descs) != len(want) || rt.descs[1] != want[1] {
+		t.Errorf("returned capabilities = %v; want %v", rt.descs, want)
+	}
+}
+
+// ForwardEchoer is an Echoer that echoes its capability by calling echo
+// on it and returning the promised result.
+type ForwardEchoer struct {
+	CallOrder
+}
+
+func (*ForwardEchoer) Echo(call testcapnp.Echoer_echo) error {
+	cap := call.Params.Cap()
+	ans := testcapnp.Echoer{Client: cap.Client}.Echo(context.Background(), func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(cap)
+	})
+	return call.Results.SetCap(ans.Cap())
+}
+
+// FlowLimitedEchoer is an Echoer that returns its capability wrapped in
+// a flow-limited client.
+type FlowLimitedEchoer struct {
+	CallOrder
+}
+
+func (*FlowLimitedEchoer) Echo(call testcapnp.Echoer_echo) error {
+	fc := capnp.NewFlowLimitedClient(call.Params.Cap().Client, capnp.NewFixedLimiter(1024))
+	call.Results.SetCap(testcapnp.CallOrder{Client: fc})
+	return nil
+}
+
+// returnTransport records the kinds of capability descriptors in the
+// Return messages sent on a Conn.
+type returnTransport struct {
+	rpc.Transport
+
+	mu    sync.Mutex
+	descs []rpccapnp.CapDescriptor_Which
+}
+
+func (rt *returnTransport) SendMessage(ctx context.Context, msg rpccapnp.Message) error {
+	if msg.Which() == rpccapnp.Message_Which_return {
+		ret, _ := msg.Return()
+		if ret.Which() == rpccapnp.Return_Which_results {
+			payload, _ := ret.Results()
+			ctab, _ := payload.CapTable()
+			rt.mu.Lock()
+			for i := 0; i < ctab.Len(); i++ {
+				rt.descs = append(rt.descs, ctab.At(i).Which())
+			}
+			rt.mu.Unlock()
+		}
+	}
+	return rt.Transport.SendMessage(ctx, msg)
+}
@@ -3,6 +3,7 @@ package rpc_test
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
@@ -11,6 +12,7 @@ import (
 	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
 	"zombiezen.com/go/capnproto2/server"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
 func TestRelease(t *testing.T) {
@@ -90,6 +92,190 @@ func TestReleaseAlias(t *testing.T) {
 	}
 }
 
+func TestReleaseAlias_Called(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	hf := singletonHandleFactory()
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(hf).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+	r1, err := client.NewHandle(ctx, nil).Struct()
+	if err != nil {
+		t.Fatal("NewHandle #1:", err)
+	}
+	handle1 := r1.Handle()
+	r2, err := client.NewHandle(ctx, nil).Struct()
+	if err != nil {
+		t.Fatal("NewHandle #2:", err)
+	}
+	handle2 := r2.Handle()
+
+	// Calling handle1 adds it to the import table; handle2 is never used.
+	handle1.Client.Call(&capnp.Call{
+		Ctx:    ctx,
+		Method: capnp.Method{InterfaceID: 0xdeadbeef, MethodID: 42},
+	}).Struct()
+	if err := handle2.Client.Close(); err != nil {
+		t.Error("handle2.Client.Close():", err)
+	}
+	flushConn(ctx, c)
+	if n := hf.numHandles(); n != 1 {
+		t.Errorf("after handle2.Client.Close(), numHandles = %d; want 1", n)
+	}
+	if err := handle1.Client.Close(); err != nil {
+		t.Error("handle1.Client.Close():", err)
+	}
+	flushConn(ctx, c)
+	if n := hf.numHandles(); n != 0 {
+		t.Errorf("after handle1.Close() and handle2.Close(), numHandles = %d; want 0", n)
+	}
+}
+
+func TestReleasePromise(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	hf := new(HandleFactory)
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(hf).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+	promise := client.NewHandle(ctx, nil)
+	if _, err := promise.Struct(); err != nil {
+		t.Fatal("NewHandle:", err)
+	}
+	if n := hf.numHandles(); n != 1 {
+		t.Fatalf("numHandles = %d; want 1", n)
+	}
+
+	if err := promise.Close(); err != nil {
+		t.Error("promise.Close():", err)
+	}
+	flushConn(ctx, c)
+	if n := hf.numHandles(); n != 0 {
+		t.Errorf("after promise.Close(), numHandles = %d; want 0", n)
+	}
+	if err := promise.Close(); err != nil {
+		t.Error("second promise.Close():", err)
+	}
+}
+
+func TestReleaseReceivedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	rt := &releaseTransport{Transport: p}
+	log := testLogger{t}
+	c := rpc.NewConn(rt, rpc.ConnLog(log))
+	hf := new(HandleFactory)
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(hf).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+	promise := client.NewHandle(ctx, nil)
+	if _, err := promise.Struct(); err != nil {
+		t.Fatal("NewHandle:", err)
+	}
+	if err := promise.Close(); err != nil {
+		t.Error("promise.Close():", err)
+	}
+	flushConn(ctx, c)
+	if err := c.Close(); err != nil {
+		t.Error("c.Close():", err)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.received == 0 {
+		t.Fatal("no messages received")
+	}
+	if rt.released != rt.received {
+		t.Errorf("released %d of %d received messages", rt.released, rt.received)
+	}
+}
+
+func TestReleaseServerResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	hf := &releasingHandleFactory{released: make(chan struct{}, 1)}
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(hf).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+	promise := client.NewHandle(ctx, nil)
+	defer promise.Close()
+	if _, err := promise.Struct(); err != nil {
+		t.Fatal("NewHandle:", err)
+	}
+
+	// The Return has been sent, and the Finish follows it, after which
+	// the server's results are released.
+	flushConn(ctx, c)
+	select {
+	case <-hf.released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server results not released after Return and Finish")
+	}
+}
+
+// releasingHandleFactory is a HandleFactory that signals when the
+// message holding its results is released.
+type releasingHandleFactory struct {
+	released chan struct{}
+}
+
+func (hf *releasingHandleFactory) NewHandle(call testcapnp.HandleFactory_newHandle) error {
+	call.Results.Segment().Message().AddReleaser(func() {
+		hf.released <- struct{}{}
+	})
+	return nil
+}
+
+// releaseTransport counts the received messages that the Conn releases.
+type releaseTransport struct {
+	rpc.Transport
+
+	mu       sync.Mutex
+	received int
+	released int
+}
+
+func (rt *releaseTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, error) {
+	msg, err := rt.Transport.RecvMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	rt.mu.Lock()
+	rt.received++
+	rt.mu.Unlock()
+	msg.Segment().Message().AddReleaser(func() {
+		rt.mu.Lock()
+		rt.released++
+		rt.mu.Unlock()
+	})
+	return msg, nil
+}
+
 func flushConn(ctx context.Context, c *rpc.Conn) {
 	// discard result
 	c.Bootstrap(ctx).Call(&capnp.Call{
@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"zombiezen.com/go/capnproto2"
+)
+
+// ReplaceExport atomically replaces the capability behind the export of
+// old with c, keeping its export ID, so that the peer's references to
+// old start calling c without noticing the change.  It suits upgrading
+// a long-lived capability, like the bootstrap interface, without
+// downtime.
+//
+// Calls that the peer makes on the export after ReplaceExport returns
+// go to c.  Calls that were already delivered to old finish on it, and
+// the connection releases its reference to old once they have been
+// delivered.  The connection takes ownership of c.  Only the export
+// changes: if old is the main interface, later bootstrap requests still
+// get old.
+//
+// If the connection has an export quota, c is charged in place of old.
+// If c would exceed the quota, ReplaceExport returns ErrExportQuota.
+// If old is not exported on the connection, ReplaceExport returns
+// ErrNotExported.  In both cases, the export is unchanged and the caller
+// keeps ownership of c.
+func (c *Conn) ReplaceExport(old, client capnp.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.startWork(); err != nil {
+		return err
+	}
+	defer c.workers.Done()
+	for _, e := range c.exports {
+		if e == nil || !isSameClient(e.rc.Client, old) {
+			continue
+		}
+		size := e.size
+		if c.expQuota != nil {
+			size = c.expQuota.size(client)
+			if err := c.expQuota.resize(c.peer, e.size, size); err != nil {
+				return err
+			}
+		}
+		prev := e.client
+		e.client = ownRef(client)
+		e.rc = e.client.RefCount()
+		e.size = size
+		// Closing waits for calls that are being started on old, which
+		// may need the connection's lock.
+		go closeCaps([]capnp.Client{prev})
+		return nil
+	}
+	return ErrNotExported
+}
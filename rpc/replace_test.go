@@ -0,0 +1,139 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/refcount"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestReplaceExport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	blue := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	d := rpc.NewConn(q, rpc.MainInterface(blue.Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := c.Bootstrap(ctx)
+
+	check := func(name string, want uint32) {
+		r, err := callseq(ctx, client, want).Struct()
+		if err != nil {
+			t.Fatalf("%s call: %v", name, err)
+		}
+		if r.N() != want {
+			t.Errorf("%s call = %d; want %d", name, r.N(), want)
+		}
+	}
+	check("blue", 0)
+	green := testcapnp.CallOrder_ServerToClient(&CallOrder{n: 100})
+	if err := d.ReplaceExport(blue.Client, green.Client); err != nil {
+		t.Fatal("ReplaceExport:", err)
+	}
+	check("green", 100)
+	check("green", 101)
+
+	other := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	defer other.Client.Close()
+	if err := d.ReplaceExport(blue.Client, other.Client); err != rpc.ErrNotExported {
+		t.Errorf("ReplaceExport of replaced capability = %v; want %v", err, rpc.ErrNotExported)
+	}
+}
+
+// newReplaceConns returns a client connection to a new server
+// connection whose main interface is blue.
+func newReplaceConns(t *testing.T, blue capnp.Client, opts ...rpc.ConnOption) (c, d *rpc.Conn) {
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c = rpc.NewConn(p, rpc.ConnLog(log))
+	opts = append([]rpc.ConnOption{rpc.MainInterface(blue), rpc.ConnLog(log)}, opts...)
+	d = rpc.NewConn(q, opts...)
+	return c, d
+}
+
+func TestReplaceExport_Ref(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blue := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	c, d := newReplaceConns(t, blue.Client)
+	defer d.Wait()
+	defer c.Close()
+	client := c.Bootstrap(ctx)
+	if _, err := callseq(ctx, client, 0).Struct(); err != nil {
+		t.Fatal("blue call:", err)
+	}
+
+	_, green := refcount.New(testcapnp.CallOrder_ServerToClient(&CallOrder{n: 100}).Client)
+	if err := d.ReplaceExport(blue.Client, green); err != nil {
+		t.Fatal("ReplaceExport:", err)
+	}
+	// The connection took over the caller's reference instead of
+	// taking one of its own.
+	if err := green.Close(); err == nil {
+		t.Error("closing Ref passed to ReplaceExport succeeded; want the connection to own it")
+	}
+	if r, err := callseq(ctx, client, 100).Struct(); err != nil {
+		t.Error("green call:", err)
+	} else if r.N() != 100 {
+		t.Errorf("green call = %d; want 100", r.N())
+	}
+}
+
+func TestReplaceExport_Quota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	size := int64(4)
+	quota := &rpc.ExportQuota{
+		MaxBytes: 10,
+		Size:     func(capnp.Client) int64 { return size },
+	}
+	blue := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	c, d := newReplaceConns(t, blue.Client, rpc.LimitExports(quota, "alice"))
+	defer d.Wait()
+	defer c.Close()
+	client := c.Bootstrap(ctx)
+	if _, err := callseq(ctx, client, 0).Struct(); err != nil {
+		t.Fatal("blue call:", err)
+	}
+	if n, b := quota.Usage("alice"); n != 1 || b != 4 {
+		t.Fatalf("Usage after bootstrap = %d, %d; want 1, 4", n, b)
+	}
+
+	size = 8
+	green := testcapnp.CallOrder_ServerToClient(&CallOrder{n: 100})
+	if err := d.ReplaceExport(blue.Client, green.Client); err != nil {
+		t.Fatal("ReplaceExport:", err)
+	}
+	if n, b := quota.Usage("alice"); n != 1 || b != 8 {
+		t.Errorf("Usage after ReplaceExport = %d, %d; want 1, 8", n, b)
+	}
+
+	size = 20
+	red := testcapnp.CallOrder_ServerToClient(&CallOrder{n: 200})
+	defer red.Client.Close()
+	if err := d.ReplaceExport(green.Client, red.Client); err != rpc.ErrExportQuota {
+		t.Errorf("ReplaceExport over quota = %v; want %v", err, rpc.ErrExportQuota)
+	}
+	if n, b := quota.Usage("alice"); n != 1 || b != 8 {
+		t.Errorf("Usage after failed ReplaceExport = %d, %d; want 1, 8", n, b)
+	}
+	if r, err := callseq(ctx, client, 100).Struct(); err != nil {
+		t.Error("call after failed ReplaceExport:", err)
+	} else if r.N() != 100 {
+		t.Errorf("call after failed ReplaceExport = %d; want 100 from the old export", r.N())
+	}
+}
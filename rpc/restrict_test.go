@@ -0,0 +1,44 @@
+package rpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestRestrictClientRemote(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	echoSrv := testcapnp.Echoer_ServerToClient(new(Echoer))
+	restricted := capnp.RestrictClient(echoSrv.Client, []capnp.Method{
+		{InterfaceID: testcapnp.CallOrder_TypeID, MethodID: 0},
+	})
+	d := rpc.NewConn(q, rpc.MainInterface(restricted), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	if _, err := callseq(ctx, client.Client, 0).Struct(); err != nil {
+		t.Error("getCallSequence:", err)
+	}
+	_, err := client.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: client.Client})
+	}).Struct()
+	if err == nil {
+		t.Error("echo succeeded; want permission denied")
+	} else if !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("echo error = %v; want permission denied", err)
+	}
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"zombiezen.com/go/capnproto2"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// A ReturnHook inspects the results of a call that the peer made right
+// before they are sent in a Return message.  method is the method of
+// the call, or nil for a bootstrap capability.
+//
+// The payload is the one in the outgoing message, which holds a copy of
+// the results, so the hook can change its content to annotate or redact
+// the results without affecting calls pipelined on them locally.
+// Capabilities that the hook adds to the message's table pass through
+// the connection's CapFilter like the rest.  Returning an error sends
+// it to the peer as an exception instead of the results.
+//
+// The hook is called while the connection's lock is held, so it must
+// not block or make calls on the connection.
+type ReturnHook func(method *capnp.Method, payload rpccapnp.Payload) error
+
+// InspectReturns specifies a hook that the results of every call that
+// the peer makes pass through before they are sent.  It suits policies
+// that apply to all of a connection's results, like recording their
+// sizes or removing sensitive fields.  By default, results are sent as
+// they are.
+func InspectReturns(h ReturnHook) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.returnHook = h
+	}}
+}
+
+// inspectReturn passes payload through the connection's return hook.
+// The caller holds onto c.mu.
+func (c *Conn) inspectReturn(method *capnp.Method, payload rpccapnp.Payload) error {
+	if c.returnHook == nil {
+		return nil
+	}
+	return c.returnHook(method, payload)
+}
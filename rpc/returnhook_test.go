@@ -0,0 +1,80 @@
+package rpc_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestInspectReturns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	errTooBig := errors.New("too big")
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	hook := func(m *capnp.Method, payload rpccapnp.Payload) error {
+		mu.Lock()
+		if m == nil {
+			methods = append(methods, "bootstrap")
+		} else {
+			methods = append(methods, m.String())
+		}
+		mu.Unlock()
+		if m == nil {
+			return nil
+		}
+		content, err := payload.ContentPtr()
+		if err != nil {
+			return err
+		}
+		r := testcapnp.Adder_add_Results{Struct: content.Struct()}
+		if r.Result() > 100 {
+			return errTooBig
+		}
+		r.SetResult(r.Result() * 10)
+		return nil
+	}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	adder := testcapnp.Adder_ServerToClient(AdderServer{})
+	d := rpc.NewConn(q, rpc.MainInterface(adder.Client), rpc.InspectReturns(hook), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.Adder{Client: c.Bootstrap(ctx)}
+
+	add := func(a, b int32) (int32, error) {
+		r, err := client.Add(ctx, func(p testcapnp.Adder_add_Params) error {
+			p.SetA(a)
+			p.SetB(b)
+			return nil
+		}).Struct()
+		return r.Result(), err
+	}
+	if n, err := add(2, 3); err != nil || n != 50 {
+		t.Errorf("add(2, 3) = %d, %v; want 50, <nil>", n, err)
+	}
+	if _, err := add(100, 1); err == nil || !strings.Contains(err.Error(), errTooBig.Error()) {
+		t.Errorf("add(100, 1) error = %v; want %v", err, errTooBig)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	add0 := (&capnp.Method{InterfaceID: testcapnp.Adder_TypeID}).String()
+	if len(methods) != 3 || methods[0] != "bootstrap" || methods[1] != add0 || methods[2] != add0 {
+		t.Errorf("hook called for %q; want [bootstrap %s %s]", methods, add0, add0)
+	}
+}
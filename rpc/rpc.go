@@ -2,12 +2,15 @@
 package rpc // import "zombiezen.com/go/capnproto2/rpc"
 
 import (
-	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/captype"
+	"zombiezen.com/go/capnproto2/clock"
+	"zombiezen.com/go/capnproto2/internal/profile"
 	"zombiezen.com/go/capnproto2/rpc/internal/refcount"
 	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
@@ -19,6 +22,21 @@ type Conn struct {
 	log        Logger
 	mainFunc   func(context.Context) (capnp.Client, error)
 	mainCloser io.Closer
+	capCheck   *captype.Checker
+	capFilter  CapFilter
+	returnHook ReturnHook
+	metrics    Metrics
+	clock      clock.Clock
+	ansTimeout time.Duration
+	strict     bool         // abort on anomalies instead of logging them
+	expQuota   *ExportQuota // nil if exports are not limited
+	peer       string       // identity that expQuota is charged to
+	maxDepth   int          // of incoming pipelined calls; zero for no limit
+	maxQueued  int          // pipelined calls queued per answer
+	capPolicy  CapTablePolicy
+	checkTabs  bool
+	hashes     map[uint64]uint64 // interface schema hashes; nil for no handshake
+	onMismatch func(SchemaMismatch)
 	death      chan struct{} // closed after state is connDead
 
 	out chan rpccapnp.Message
@@ -49,7 +67,22 @@ type connParams struct {
 	log            Logger
 	mainFunc       func(context.Context) (capnp.Client, error)
 	mainCloser     io.Closer
+	capCheck       *captype.Checker
+	capFilter      CapFilter
+	returnHook     ReturnHook
+	metrics        Metrics
+	clock          clock.Clock
+	ansTimeout     time.Duration
+	strict         bool
+	exportQuota    *ExportQuota
+	peer           string
 	sendBufferSize int
+	maxDepth       int
+	maxQueued      int
+	capPolicy      CapTablePolicy
+	checkTables    bool
+	hashes         map[uint64]uint64
+	onMismatch     func(SchemaMismatch)
 }
 
 // A ConnOption is an option for opening a connection.
@@ -59,18 +92,44 @@ type ConnOption struct {
 
 // MainInterface specifies that the connection should use client when
 // receiving bootstrap messages.  By default, all bootstrap messages will
-// fail.  The client will be closed when the connection is closed.
+// fail.  NewConn takes ownership of client, and the client will be
+// closed when the connection is closed.  Since the option hands client
+// to the connection it is passed to, it must not be used for more than
+// one connection.
 func MainInterface(client capnp.Client) ConnOption {
-	rc, ref1 := refcount.New(client)
-	ref2 := rc.Ref()
 	return ConnOption{func(c *connParams) {
+		main := ownRef(client)
+		// Each bootstrap gets a reference of its own, so that closing
+		// main when the connection closes releases the caller's.
 		c.mainFunc = func(ctx context.Context) (capnp.Client, error) {
-			return ref1, nil
+			return main.Snapshot(), nil
 		}
-		c.mainCloser = ref2
+		c.mainCloser = main
 	}}
 }
 
+// Share returns a capnp.SharedClient for client, so that references to
+// it can be handed out with capnp.Snapshot and capnp.Steal.  Share takes
+// ownership of client, which is closed once every reference has been
+// closed.  If client is already shared, like a client from a Conn,
+// Share steals the caller's reference.
+func Share(client capnp.Client) capnp.SharedClient {
+	return ownRef(client)
+}
+
+// ownRef returns a reference that owns client.  If client is already a
+// Ref, ownRef takes over the caller's reference: a snapshot would keep
+// the client open after the caller's owner closes it.
+func ownRef(client capnp.Client) *refcount.Ref {
+	if r, ok := client.(*refcount.Ref); ok {
+		if ref, ok := r.Steal().(*refcount.Ref); ok {
+			return ref
+		}
+	}
+	_, ref := refcount.New(client)
+	return ref
+}
+
 // BootstrapFunc specifies the function to call to create a capability
 // for handling bootstrap messages.  This function should not make any
 // RPCs or block.
@@ -89,12 +148,87 @@ func SendBufferSize(numMsgs int) ConnOption {
 	}}
 }
 
+// CheckCapabilityTypes verifies that the capabilities received in the
+// parameters of incoming calls and in the results of outgoing calls
+// implement the interfaces declared by the method's schema, as
+// described in package captype.  A call with a mismatched capability
+// fails with a *captype.MismatchError instead of being delivered.
+// By default, capabilities are not checked.
+//
+// Only capabilities that are hosted in this vat can be checked: the
+// connection's own exports that the peer sends back, and local
+// capabilities.  Capabilities that the peer hosts arrive as imports,
+// whose implementation is on the other side of the connection, so they
+// are passed through unchecked.  In particular, a peer that sends one
+// of its own capabilities with the wrong type is not caught; the
+// mismatch shows up when a call on it fails as unimplemented.
+func CheckCapabilityTypes(chk *captype.Checker) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.capCheck = chk
+	}}
+}
+
+// ConnClock sets the clock that drives the connection's timeouts.
+// Tests can pass a *clock.Fake to trigger timeouts without waiting.
+// By default, the connection uses clock.Real.
+func ConnClock(clk clock.Clock) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.clock = clk
+	}}
+}
+
+// AnswerTimeout limits how long the connection spends answering each
+// incoming call.  Once d has elapsed on the connection's clock, the
+// call's context is canceled.  This stops calls that are still waiting
+// to be delivered, like calls queued behind a busy server, and signals
+// implementations to give up.  By default, there is no timeout.
+func AnswerTimeout(d time.Duration) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.ansTimeout = d
+	}}
+}
+
+// StrictAnomalies makes the connection abort on messages that are
+// well-formed but inconsistent with its state, like a duplicate Finish,
+// a Return for a question it never asked, or a Release of more
+// references than it sent.  Such messages usually come from a buggy
+// peer.  By default, they are logged and ignored.  Malformed messages
+// always abort the connection.
+func StrictAnomalies() ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.strict = true
+	}}
+}
+
+// MaxPipelineDepth limits incoming calls on promised answers to
+// transforms of at most n operations, that is, n pointer fields deep
+// into the answer's results.  Deeper calls fail with an exception,
+// as do capabilities in received messages that name deeper transforms.
+// By default, or if n is zero, depth is not limited.
+func MaxPipelineDepth(n int) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.maxDepth = n
+	}}
+}
+
+// MaxQueuedCalls limits how many calls can be queued on each answer
+// that has not yet returned, whether pipelined by the peer or made
+// locally on the answer's promised capabilities.  Calls beyond the
+// limit fail with an exception.  The default is 64.
+func MaxQueuedCalls(n int) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.maxQueued = n
+	}}
+}
+
 // NewConn creates a new connection that communicates on c.
 // Closing the connection will cause c to be closed.
 func NewConn(t Transport, options ...ConnOption) *Conn {
 	p := &connParams{
 		log:            defaultLogger{},
+		clock:          clock.Real,
 		sendBufferSize: 4,
+		maxQueued:      callQueueSize,
 	}
 	for _, o := range options {
 		o.f(p)
@@ -105,6 +239,21 @@ func NewConn(t Transport, options ...ConnOption) *Conn {
 		out:        make(chan rpccapnp.Message, p.sendBufferSize),
 		mainFunc:   p.mainFunc,
 		mainCloser: p.mainCloser,
+		capCheck:   p.capCheck,
+		capFilter:  p.capFilter,
+		returnHook: p.returnHook,
+		metrics:    p.metrics,
+		clock:      p.clock,
+		ansTimeout: p.ansTimeout,
+		strict:     p.strict,
+		expQuota:   p.exportQuota,
+		peer:       p.peer,
+		maxDepth:   p.maxDepth,
+		maxQueued:  p.maxQueued,
+		capPolicy:  p.capPolicy,
+		checkTabs:  p.checkTables,
+		hashes:     p.hashes,
+		onMismatch: p.onMismatch,
 		log:        p.log,
 		death:      make(chan struct{}),
 		mu:         newChanMutex(),
@@ -194,6 +343,20 @@ func (c *Conn) abort(e error) {
 	c.stateMu.Unlock()
 }
 
+// anomaly handles a message that is inconsistent with the connection's
+// state, as described by err.  It logs err and, if the connection is
+// strict about anomalies, aborts the connection.  It reports whether
+// the message should still be processed.  The caller is holding onto
+// c.mu.
+func (c *Conn) anomaly(err error) bool {
+	c.errorf("%v", err)
+	if !c.strict {
+		return true
+	}
+	c.abort(err)
+	return false
+}
+
 // startWork adds a new worker if c is not dying or dead.
 // Otherwise, it returns the close error.
 // The caller is responsible for calling c.workers.Done().
@@ -215,6 +378,15 @@ func (c *Conn) teardown(abort rpccapnp.Message) {
 	c.workers.Wait()
 
 	c.mu.Lock()
+	if c.checkTabs {
+		if ts := c.tableSizes(); !ts.IsZero() {
+			c.stateMu.Lock()
+			if c.closeErr == ErrConnClosed {
+				c.closeErr = &LeakError{Sizes: ts}
+			}
+			c.stateMu.Unlock()
+		}
+	}
 	for _, q := range c.questions {
 		if q != nil {
 			q.cancel(ErrConnClosed)
@@ -224,13 +396,18 @@ func (c *Conn) teardown(abort rpccapnp.Message) {
 	exps := c.exports
 	c.exports = nil
 	c.embargoes = nil
+	var results []capnp.Ptr
 	for _, a := range c.answers {
 		a.cancel()
+		results = append(results, a.finish())
 	}
 	c.answers = nil
 	c.imports = nil
 	c.mainFunc = nil
 	c.mu.Unlock()
+	for _, r := range results {
+		releaseResults(r)
+	}
 
 	if c.mainCloser != nil {
 		if err := c.mainCloser.Close(); err != nil {
@@ -247,6 +424,9 @@ func (c *Conn) teardown(abort rpccapnp.Message) {
 		if err := e.client.Close(); err != nil {
 			c.errorf("export %v close: %v", id, err)
 		}
+		if c.expQuota != nil {
+			c.expQuota.release(c.peer, e.size)
+		}
 	}
 	exps = nil
 
@@ -296,6 +476,9 @@ func (c *Conn) Bootstrap(ctx context.Context) capnp.Client {
 	select {
 	case c.out <- msg:
 		q.start()
+		if c.hashes != nil {
+			c.startSchemaHandshake(q)
+		}
 		return capnp.NewPipeline(q).Client()
 	case <-ctx.Done():
 		c.popQuestion(q.id)
@@ -308,8 +491,14 @@ func (c *Conn) Bootstrap(ctx context.Context) capnp.Client {
 
 // handleMessage is run from the receive goroutine to process a single
 // message.  m cannot be held onto past the return of handleMessage, and
-// c.mu is not held at the start of handleMessage.
+// c.mu is not held at the start of handleMessage.  A malformed message
+// aborts the connection.
 func (c *Conn) handleMessage(m rpccapnp.Message) {
+	if err := checkMessage(m); err != nil {
+		c.errorf("%v", err)
+		c.abort(err)
+		return
+	}
 	switch m.Which() {
 	case rpccapnp.Message_Which_unimplemented:
 		// no-op for now to avoid feedback loop
@@ -341,8 +530,8 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 		c.mu.Lock()
 		a := c.popAnswer(id)
 		if a == nil {
+			c.anomaly(idError{"rpc: finish called for unknown answer", uint32(id)})
 			c.mu.Unlock()
-			c.errorf("finish called for unknown answer %d", id)
 			return
 		}
 		a.cancel()
@@ -351,7 +540,10 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 				c.releaseExport(id, 1)
 			}
 		}
+		results := a.finish()
+		c.finishTailQuestion(a)
 		c.mu.Unlock()
+		releaseResults(results)
 	case rpccapnp.Message_Which_bootstrap:
 		boot, err := m.Bootstrap()
 		if err != nil {
@@ -386,6 +578,12 @@ func (c *Conn) handleMessage(m rpccapnp.Message) {
 		refs := int(rel.ReferenceCount())
 
 		c.mu.Lock()
+		if e := c.findExport(id); e == nil || refs > e.wireRefs {
+			if !c.anomaly(idError{"rpc: release of more references than were sent for export", uint32(id)}) {
+				c.mu.Unlock()
+				return
+			}
+		}
 		c.releaseExport(id, refs)
 		c.mu.Unlock()
 	case rpccapnp.Message_Which_disembargo:
@@ -419,6 +617,9 @@ func (c *Conn) fillParams(payload rpccapnp.Payload, cl *capnp.Call) error {
 	if err := payload.SetContent(params); err != nil {
 		return err
 	}
+	if _, err := c.filterCaps(OutgoingParams, &cl.Method, payload.Segment().Message().CapTable); err != nil {
+		return err
+	}
 	ctab, err := c.makeCapTable(payload.Segment())
 	if err != nil {
 		return err
@@ -426,6 +627,7 @@ func (c *Conn) fillParams(payload rpccapnp.Payload, cl *capnp.Call) error {
 	if err := payload.SetCapTable(ctab); err != nil {
 		return err
 	}
+	c.recordPayload(OutgoingParams, &cl.Method, payload)
 	return nil
 }
 
@@ -449,15 +651,28 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 		return err
 	}
 	id := questionID(ret.AnswerId())
-	q := c.popQuestion(id)
+	q := c.findQuestion(id)
 	if q == nil {
-		return fmt.Errorf("received return for unknown question id=%d", id)
+		c.anomaly(idError{"rpc: received return for unknown question id", uint32(id)})
+		return nil
 	}
 	if ret.ReleaseParamCaps() {
 		for _, id := range q.paramCaps {
 			c.releaseExport(id, 1)
 		}
 	}
+	switch ret.Which() {
+	case rpccapnp.Return_Which_resultsSentElsewhere:
+		return c.handleResultsSentElsewhere(q)
+	case rpccapnp.Return_Which_takeFromOtherQuestion:
+		return c.handleTakeFromOtherQuestion(q, answerID(ret.TakeFromOtherQuestion()))
+	}
+	c.popQuestion(id)
+	if q.tail != nil {
+		// The peer returned the results instead of keeping them, so
+		// they are returned from here.
+		go joinAnswer(q.tail, q)
+	}
 	q.mu.RLock()
 	qstate := q.state
 	q.mu.RUnlock()
@@ -485,6 +700,40 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 		if err != nil {
 			return err
 		}
+		c.recordPayload(IncomingResults, q.method, results)
+		replaced, err := c.filterCaps(IncomingResults, q.method, results.Segment().Message().CapTable)
+		if len(replaced) > 0 {
+			go closeCaps(replaced)
+		}
+		if err != nil {
+			go closeCaps(results.Segment().Message().CapTable)
+			if q.method != nil {
+				err = &capnp.MethodError{
+					Method: q.method,
+					Err:    err,
+				}
+			} else {
+				err = bootstrapError{err}
+			}
+			q.reject(err)
+			break
+		}
+		if c.capCheck != nil && q.method != nil {
+			// Bootstrap results have no schema type to check against.
+			if err := c.capCheck.Results(q.method, content.Struct()); err != nil {
+				// Closing the capabilities releases them, so the Finish
+				// must not ask the callee to release them again.
+				go closeCaps(results.Segment().Message().CapTable)
+				q.reject(&capnp.MethodError{
+					Method: q.method,
+					Err:    err,
+				})
+				break
+			}
+		}
+		// The question owns the copy of the Return, so closing a
+		// pipeline on it releases the copy.
+		m.Segment().Message().SetAnswerOwned()
 		q.fulfill(content)
 	case rpccapnp.Return_Which_exception:
 		exc, err := ret.Exception()
@@ -505,7 +754,7 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 		err := &questionError{
 			id:     id,
 			method: q.method,
-			err:    fmt.Errorf("receiver reported canceled"),
+			err:    errReceiverCanceled,
 		}
 		c.errorf("%v", err)
 		q.reject(err)
@@ -520,6 +769,125 @@ func (c *Conn) handleReturnMessage(m rpccapnp.Message) error {
 	return nil
 }
 
+// handleResultsSentElsewhere handles a Return reporting that the peer
+// kept the results of a call sent with sendResultsTo.yourself.  The
+// answer that the call was forwarded for tells the peer to take them.
+// The caller is holding onto c.mu.
+func (c *Conn) handleResultsSentElsewhere(q *question) error {
+	q.mu.RLock()
+	qstate := q.state
+	q.mu.RUnlock()
+	if qstate == questionCanceled {
+		// We already sent the finish message.
+		c.popQuestion(q.id)
+		if q.tail != nil {
+			go joinAnswer(q.tail, q)
+		}
+		return nil
+	}
+	if !q.yourself {
+		c.popQuestion(q.id)
+		q.reject(errResultsSentElsewhere)
+		c.sendMessage(newFinishMessage(nil, q.id, true))
+		return errResultsSentElsewhere
+	}
+	if q.tail == nil {
+		// The answer that q was sent for was finished before q could be
+		// linked to it, so nothing will take the results.
+		c.popQuestion(q.id)
+		q.reject(errResultsSentElsewhere)
+		c.sendMessage(newFinishMessage(nil, q.id, true))
+		return nil
+	}
+	q.sentElsewhere()
+	return q.tail.takeFrom(q)
+}
+
+// handleTakeFromOtherQuestion handles a Return that names one of c's
+// answers, whose call the peer sent with sendResultsTo.yourself, as
+// holding the results of q.  The caller is holding onto c.mu.
+func (c *Conn) handleTakeFromOtherQuestion(q *question, id answerID) error {
+	q.mu.RLock()
+	qstate := q.state
+	q.mu.RUnlock()
+	if qstate == questionCanceled {
+		// We already sent the finish message.
+		c.popQuestion(q.id)
+		return nil
+	}
+	a := c.answers[id]
+	if a == nil {
+		c.finishTakenQuestion(q, capnp.Ptr{}, errBadTakeFrom)
+		return errBadTakeFrom
+	}
+	obj, err, done := a.take()
+	if !done {
+		// q stays in the table, so that it can still be pipelined on,
+		// until a is resolved.
+		go joinTakenAnswer(q, a)
+		return nil
+	}
+	c.finishTakenQuestion(q, obj, err)
+	if err == errBadTakeFrom {
+		return err
+	}
+	return nil
+}
+
+// finishTakenQuestion resolves q with results taken from one of c's
+// answers and sends its Finish.  The caller must be holding onto c.mu.
+func (c *Conn) finishTakenQuestion(q *question, obj capnp.Ptr, err error) {
+	if c.popQuestion(q.id) != q {
+		return
+	}
+	q.mu.RLock()
+	qstate := q.state
+	q.mu.RUnlock()
+	if qstate == questionCanceled {
+		// We already sent the finish message.
+		return
+	}
+	if err != nil {
+		q.reject(err)
+	} else {
+		q.fulfill(obj)
+	}
+	// None of the results' capabilities were exported to the peer.
+	c.sendMessage(newFinishMessage(nil, q.id, false))
+}
+
+// finishTailQuestion finishes the question that a's call was forwarded
+// to with sendResultsTo.yourself once the peer has kept its results.  A
+// question that is still in progress is finished by canceling a's
+// context instead.  The caller must be holding onto c.mu.
+func (c *Conn) finishTailQuestion(a *answer) {
+	a.mu.RLock()
+	q := a.tailq
+	a.mu.RUnlock()
+	if q == nil || c.findQuestion(q.id) != q {
+		return
+	}
+	q.mu.RLock()
+	qstate := q.state
+	q.mu.RUnlock()
+	if qstate != questionSentElsewhere {
+		return
+	}
+	c.popQuestion(q.id)
+	c.sendMessage(newFinishMessage(nil, q.id, false))
+}
+
+// closeCaps closes the clients in a capability table that will not be
+// delivered to the application.  It must be called without holding
+// onto c.mu, since closing an import acquires it.
+func closeCaps(ctab []capnp.Client) {
+	for _, client := range ctab {
+		if client != nil {
+			client.Close()
+		}
+	}
+}
+
 func newFinishMessage(buf []byte, questionID questionID, release bool) rpccapnp.Message {
 	m := newMessage(buf)
 	f, _ := m.NewFinish()
@@ -543,8 +911,7 @@ func (c *Conn) populateMessageCapTable(payload rpccapnp.Payload) error {
 			msg.AddCap(nil)
 		case rpccapnp.CapDescriptor_Which_senderHosted:
 			id := importID(desc.SenderHosted())
-			client := c.addImport(id)
-			msg.AddCap(client)
+			msg.AddCap(&lazyImportClient{id: id, conn: c})
 		case rpccapnp.CapDescriptor_Which_senderPromise:
 			// We do the same thing as senderHosted, above. @kentonv suggested this on
 			// issue #2; this let's messages be delivered properly, although it's a bit
@@ -557,15 +924,14 @@ func (c *Conn) populateMessageCapTable(payload rpccapnp.Payload) error {
 			// >   messages sent to it will uselessly round-trip over the network
 			// >   rather than being delivered locally.
 			id := importID(desc.SenderPromise())
-			client := c.addImport(id)
-			msg.AddCap(client)
+			msg.AddCap(&lazyImportClient{id: id, conn: c})
 		case rpccapnp.CapDescriptor_Which_receiverHosted:
 			id := exportID(desc.ReceiverHosted())
 			e := c.findExport(id)
 			if e == nil {
-				return fmt.Errorf("rpc: capability table references unknown export ID %d", id)
+				return idError{"rpc: capability table references unknown export ID", uint32(id)}
 			}
-			msg.AddCap(e.rc.Ref())
+			msg.AddCap(e.client.Snapshot())
 		case rpccapnp.CapDescriptor_Which_receiverAnswer:
 			recvAns, err := desc.ReceiverAnswer()
 			if err != nil {
@@ -574,14 +940,30 @@ func (c *Conn) populateMessageCapTable(payload rpccapnp.Payload) error {
 			id := answerID(recvAns.QuestionId())
 			a := c.answers[id]
 			if a == nil {
-				return fmt.Errorf("rpc: capability table references unknown answer ID %d", id)
+				return idError{"rpc: capability table references unknown answer ID", uint32(id)}
 			}
 			recvTransform, err := recvAns.Transform()
 			if err != nil {
 				return err
 			}
 			transform := promisedAnswerOpsToTransform(recvTransform)
-			msg.AddCap(a.pipelineClient(transform))
+			if c.tooDeep(transform) {
+				msg.AddCap(capnp.ErrorClient(errPipelineTooDeep))
+				continue
+			}
+			a.mu.RLock()
+			tail := a.tailClient(transform)
+			obj, err, done := a.obj, a.err, a.done
+			a.mu.RUnlock()
+			if tail != nil {
+				msg.AddCap(tail)
+			} else if done {
+				// Resolve now: the results may be released once the
+				// peer finishes the question.
+				msg.AddCap(clientFromResolution(transform, obj, err))
+			} else {
+				msg.AddCap(a.pipelineClient(transform))
+			}
 		default:
 			c.errorf("unknown capability type %v", desc.Which())
 			return errUnimplemented
@@ -591,11 +973,15 @@ func (c *Conn) populateMessageCapTable(payload rpccapnp.Payload) error {
 }
 
 // makeCapTable converts the clients in the segment's message into capability descriptors.
+// If a client can't be exported because of the connection's export
+// quota, the exports made for the table are released and the quota
+// error is returned.  If the quota aborts the connection, the
+// connection is aborted as well.
 func (c *Conn) makeCapTable(s *capnp.Segment) (rpccapnp.CapDescriptor_List, error) {
 	msgtab := s.Message().CapTable
 	t, err := rpccapnp.NewCapDescriptor_List(s, int32(len(msgtab)))
 	if err != nil {
-		return rpccapnp.CapDescriptor_List{}, nil
+		return rpccapnp.CapDescriptor_List{}, err
 	}
 	for i, client := range msgtab {
 		desc := t.At(i)
@@ -603,11 +989,42 @@ func (c *Conn) makeCapTable(s *capnp.Segment) (rpccapnp.CapDescriptor_List, erro
 			desc.SetNone()
 			continue
 		}
-		c.descriptorForClient(desc, client)
+		err := c.descriptorForClient(desc, client)
+		if err == nil {
+			continue
+		}
+		if err == ErrExportQuota {
+			c.unexportCapTable(t, i)
+			if c.expQuota.Abort {
+				c.abort(err)
+			}
+			return rpccapnp.CapDescriptor_List{}, err
+		}
+		if c.capPolicy == SendNull {
+			c.errorf("capability %d: %v; sending null", i, err)
+			desc.SetNone()
+			continue
+		}
+		err = &CapTableError{Index: i, Err: err}
+		c.unexportCapTable(t, i)
+		if c.capPolicy == AbortConn {
+			c.abort(err)
+		}
+		return rpccapnp.CapDescriptor_List{}, err
 	}
 	return t, nil
 }
 
+// unexportCapTable releases the exports that were added for the first
+// n descriptors of t.  The caller holds onto c.mu.
+func (c *Conn) unexportCapTable(t rpccapnp.CapDescriptor_List, n int) {
+	for i := 0; i < n; i++ {
+		if d := t.At(i); d.Which() == rpccapnp.CapDescriptor_Which_senderHosted {
+			c.unexport(exportID(d.SenderHosted()))
+		}
+	}
+}
+
 // handleBootstrapMessage handles a received bootstrap message.
 // The caller holds onto c.mu.
 func (c *Conn) handleBootstrapMessage(id answerID) error {
@@ -652,6 +1069,11 @@ func (c *Conn) handleCallMessage(m rpccapnp.Message) error {
 		um := newUnimplementedMessage(nil, m)
 		return c.sendMessage(um)
 	}
+	sendTo := mcall.SendResultsTo().Which()
+	if sendTo != rpccapnp.Call_sendResultsTo_Which_caller && sendTo != rpccapnp.Call_sendResultsTo_Which_yourself {
+		um := newUnimplementedMessage(nil, m)
+		return c.sendMessage(um)
+	}
 	mparams, err := mcall.Params()
 	if err != nil {
 		return err
@@ -671,6 +1093,10 @@ func (c *Conn) handleCallMessage(m rpccapnp.Message) error {
 		c.abort(errQuestionReused)
 		return errQuestionReused
 	}
+	a.yourself = sendTo == rpccapnp.Call_sendResultsTo_Which_yourself
+	if c.ansTimeout > 0 {
+		a.timer = c.clock.AfterFunc(c.ansTimeout, cancel)
+	}
 	meth := capnp.Method{
 		InterfaceID: mcall.InterfaceId(),
 		MethodID:    mcall.MethodId(),
@@ -680,11 +1106,35 @@ func (c *Conn) handleCallMessage(m rpccapnp.Message) error {
 		return err
 	}
 	cl := &capnp.Call{
-		Ctx:    ctx,
+		Ctx:    withQuestionID(ctx, id),
 		Method: meth,
 		Params: paramContent.Struct(),
 	}
-	if err := c.routeCallMessage(a, mt, cl); err != nil {
+	a.method = &meth
+	c.recordPayload(IncomingParams, &meth, mparams)
+	if meth.InterfaceID == schemaHashMethod.InterfaceID && c.hashes != nil {
+		return c.answerSchemaHandshake(a, cl.Params)
+	}
+	replaced, err := c.filterCaps(IncomingParams, &meth, mparams.Segment().Message().CapTable)
+	if len(replaced) > 0 {
+		go closeCaps(replaced)
+	}
+	if err != nil {
+		go closeCaps(mparams.Segment().Message().CapTable)
+		return a.reject(err)
+	}
+	if c.capCheck != nil {
+		if err := c.capCheck.Params(&meth, cl.Params); err != nil {
+			go closeCaps(mparams.Segment().Message().CapTable)
+			return a.reject(err)
+		}
+	}
+	// Goroutines started while routing, like the one that sends the
+	// results, inherit the profiler labels.
+	profile.Do(ctx, &meth, func(context.Context) {
+		err = c.routeCallMessage(a, mt, cl)
+	})
+	if err != nil {
 		return a.reject(err)
 	}
 	return nil
@@ -698,8 +1148,7 @@ func (c *Conn) routeCallMessage(result *answer, mt rpccapnp.MessageTarget, cl *c
 		if e == nil {
 			return errBadTarget
 		}
-		answer := c.lockedCall(e.client, cl)
-		go joinAnswer(result, answer)
+		c.forwardCall(result, e.client, cl)
 	case rpccapnp.MessageTarget_Which_promisedAnswer:
 		mpromise, err := mt.PromisedAnswer()
 		if err != nil {
@@ -719,13 +1168,17 @@ func (c *Conn) routeCallMessage(result *answer, mt rpccapnp.MessageTarget, cl *c
 			return err
 		}
 		transform := promisedAnswerOpsToTransform(mtrans)
+		if c.tooDeep(transform) {
+			return errPipelineTooDeep
+		}
 		pa.mu.Lock()
-		if pa.done {
+		if client := pa.tailClient(transform); client != nil {
+			pa.mu.Unlock()
+			c.forwardCall(result, client, cl)
+		} else if pa.done {
 			obj, err := pa.obj, pa.err
 			pa.mu.Unlock()
-			client := clientFromResolution(transform, obj, err)
-			answer := c.lockedCall(client, cl)
-			go joinAnswer(result, answer)
+			c.forwardCall(result, clientFromResolution(transform, obj, err), cl)
 		} else {
 			err = pa.queueCallLocked(cl, pcall{transform: transform, qcall: qcall{a: result}})
 			pa.mu.Unlock()
@@ -737,6 +1190,136 @@ func (c *Conn) routeCallMessage(result *answer, mt rpccapnp.MessageTarget, cl *c
 	return nil
 }
 
+// tooDeep reports whether transform is deeper than the peer may
+// pipeline.
+func (c *Conn) tooDeep(transform []capnp.PipelineOp) bool {
+	return c.maxDepth > 0 && len(transform) > c.maxDepth
+}
+
+// forwardCall makes the call for result on client.  If the call, or a
+// server's tail call of it, is sent back to the peer, it is sent with
+// sendResultsTo.yourself and result is resolved by telling the peer to
+// take the results, so that they do not make a round trip through this
+// vat.  The caller must be holding onto c.mu.
+func (c *Conn) forwardCall(result *answer, client capnp.Client, cl *capnp.Call) {
+	if result.yourself {
+		// The peer takes its results from result, so they must come
+		// here.
+		go joinAnswer(result, c.lockedCall(client, cl))
+		return
+	}
+	tc := &tailCall{conn: c, result: result}
+	tcl := *cl
+	tcl.Options = cl.Options.With([]capnp.CallOption{capnp.SetOptionValue(tailCallKey{}, tc)})
+	ans := c.lockedCall(client, &tcl)
+	tc.expired = true
+	if q, ok := ans.(*question); ok && q.tail == result {
+		return
+	}
+	go joinAnswer(result, ans)
+}
+
+// tailCallKey is the call option key for a *tailCall.
+type tailCallKey struct{}
+
+// A tailCall is attached to a call that a Conn forwards for its peer.
+// If the call is sent to the same peer while the Conn is routing it,
+// it is sent with sendResultsTo.yourself.  Calls that are queued and
+// sent later are sent normally, since nothing waits to link them,
+// unless they are marked with capnp.TailCallOf.
+type tailCall struct {
+	conn   *Conn
+	result *answer
+
+	// Protected by conn.mu
+	expired bool
+	claimed bool // a Call message with sendResultsTo.yourself was built
+}
+
+// findTailCall returns the tailCall that cl can use to send its results
+// to the peer, or nil.  Following capnp.TailOf finds the tailCall of a
+// call that a server delegated with a tail call.  The caller must be
+// holding onto c.mu.
+func (c *Conn) findTailCall(cl *capnp.Call) *tailCall {
+	if tc, _ := cl.Options.Value(tailCallKey{}).(*tailCall); tc != nil {
+		// tc.expired is protected by tc.conn.mu, so it can only be
+		// read once tc is known to belong to c.  A call forwarded to
+		// a capability imported over another connection has the
+		// tailCall of the connection it arrived on.
+		if tc.conn != c || tc.expired {
+			return nil
+		}
+		return tc
+	}
+	for opts, ok := capnp.TailOf(cl.Options); ok; opts, ok = capnp.TailOf(opts) {
+		if tc, _ := opts.Value(tailCallKey{}).(*tailCall); tc != nil {
+			if tc.conn != c {
+				return nil
+			}
+			return tc
+		}
+	}
+	return nil
+}
+
+// sendResultsToYourself reports whether cl is being forwarded for c's
+// peer and should ask it to keep the results.  If so, the answer that
+// cl is forwarded for is reserved for cl's question, which sendCall
+// links to it.  The caller must be holding onto c.mu.
+func (c *Conn) sendResultsToYourself(cl *capnp.Call) bool {
+	tc := c.findTailCall(cl)
+	if tc == nil || tc.claimed {
+		return false
+	}
+	tc.result.mu.RLock()
+	ok := !tc.result.done && !tc.result.finished
+	tc.result.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	tc.claimed = true
+	return true
+}
+
+// linkTail links q, which was sent for cl with sendResultsTo.yourself,
+// to the answer that cl is forwarded for.  Calls that were pipelined on
+// the answer before it was linked are made on q.  If the answer has
+// been finished in the meantime, q is left without a tail, and its
+// results are released once the peer reports that it kept them.  The
+// caller must be holding onto c.mu.
+func (c *Conn) linkTail(q *question, cl *capnp.Call) {
+	tc, _ := cl.Options.Value(tailCallKey{}).(*tailCall)
+	if tc == nil {
+		tc = c.findTailCall(cl)
+	}
+	if tc == nil {
+		return
+	}
+	a := tc.result
+	a.mu.Lock()
+	if a.done || a.finished || a.tailq != nil {
+		a.mu.Unlock()
+		return
+	}
+	q.tail = a
+	a.tailq = q
+	queue := a.queue
+	a.queue = nil
+	clients := make([]capnp.Client, len(queue))
+	for i := range queue {
+		clients[i] = a.tailClient(queue[i].transform)
+	}
+	a.mu.Unlock()
+	for i, pc := range queue {
+		ans := c.lockedCall(clients[i], pc.call)
+		if pc.a != nil {
+			go joinAnswer(pc.a, ans)
+		} else {
+			go joinFulfiller(pc.f, ans)
+		}
+	}
+}
+
 func (c *Conn) handleDisembargoMessage(msg rpccapnp.Message) error {
 	d, err := msg.Disembargo()
 	if err != nil {
@@ -828,8 +1411,8 @@ func promisedAnswerOpsToTransform(list rpccapnp.PromisedAnswer_Op_List) []capnp.
 
 func newAbortMessage(buf []byte, err error) rpccapnp.Message {
 	n := newMessage(buf)
-	e, _ := n.NewAbort()
-	toException(e, err)
+	e, _ := newException(n.Segment(), err)
+	n.SetAbort(e)
 	return n
 }
 
@@ -842,8 +1425,7 @@ func newReturnMessage(buf []byte, id answerID) rpccapnp.Message {
 }
 
 func setReturnException(ret rpccapnp.Return, err error) rpccapnp.Exception {
-	e, _ := rpccapnp.NewException(ret.Segment())
-	toException(e, err)
+	e, _ := newException(ret.Segment(), err)
 	ret.SetException(e)
 	return e
 }
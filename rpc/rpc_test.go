@@ -5,12 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
 	"zombiezen.com/go/capnproto2/rpc"
 	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
 	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/refcount"
 	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
@@ -335,6 +337,43 @@ func TestMainInterface(t *testing.T) {
 	bootstrapRoundtrip(t, p)
 }
 
+func TestMainInterfaceTakesOwnershipInNewConn(t *testing.T) {
+	closed := make(chan struct{})
+	_, ref := refcount.New(closeNotifier{mockClient(), closed})
+	opt := rpc.MainInterface(ref)
+	call := &capnp.Call{Ctx: context.Background()}
+	if _, err := ref.Call(call).Struct(); err != errMockClient {
+		t.Fatalf("before NewConn, call on client = %v; want %v", err, errMockClient)
+	}
+
+	conn, p := newUnpairedConn(t, opt)
+	if err := ref.Close(); err == nil {
+		t.Error("after NewConn, closing caller's reference succeeded; want error")
+	}
+	select {
+	case <-closed:
+		t.Fatal("main interface closed before connection")
+	default:
+	}
+	p.Close()
+	conn.Close()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("main interface not closed after connection closed")
+	}
+}
+
+type closeNotifier struct {
+	capnp.Client
+	closed chan struct{}
+}
+
+func (cn closeNotifier) Close() error {
+	close(cn.closed)
+	return nil
+}
+
 func bootstrapRoundtrip(t *testing.T, p rpc.Transport) (importID, questionID uint32) {
 	questionID = 54
 	err := sendMessage(context.TODO(), p, func(msg rpccapnp.Message) error {
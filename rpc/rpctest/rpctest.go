@@ -0,0 +1,36 @@
+// Package rpctest provides helpers for testing code that uses rpc
+// connections.
+package rpctest // import "zombiezen.com/go/capnproto2/rpc/rpctest"
+
+import (
+	"testing"
+	"time"
+
+	"zombiezen.com/go/capnproto2/rpc"
+)
+
+// Quiescence is how long VerifyConnClean waits for a connection's
+// tables to empty.
+var Quiescence = time.Second
+
+// VerifyConnClean fails the test if conn has questions, answers,
+// imports, exports, or embargoes in its tables.  Call it once every
+// call on the connection has returned and every capability passed over
+// it has been released.  Since the Finish and Release messages that
+// empty the tables may still be in flight, VerifyConnClean waits up to
+// Quiescence for the tables to empty before failing.
+func VerifyConnClean(t testing.TB, conn *rpc.Conn) {
+	t.Helper()
+	deadline := time.Now().Add(Quiescence)
+	for {
+		ts := conn.TableSizes()
+		if ts.IsZero() {
+			return
+		}
+		if !time.Now().Before(deadline) {
+			t.Errorf("connection not clean: %v", ts)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,52 @@
+package rpctest_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	"zombiezen.com/go/capnproto2/rpc/rpctest"
+	"zombiezen.com/go/capnproto2/server"
+)
+
+type adder struct{}
+
+func (adder) Add(call testcapnp.Adder_add) error {
+	server.Ack(call.Options)
+	call.Results.SetResult(call.Params.A() + call.Params.B())
+	return nil
+}
+
+func TestVerifyConnClean(t *testing.T) {
+	ctx := context.Background()
+	p, q := pipetransport.New()
+	srv := testcapnp.Adder_ServerToClient(adder{})
+	serverConn := rpc.NewConn(p, rpc.MainInterface(srv.Client), rpc.ConnLog(nil))
+	defer serverConn.Close()
+	clientConn := rpc.NewConn(q)
+	defer clientConn.Close()
+
+	a := testcapnp.Adder{Client: clientConn.Bootstrap(ctx)}
+	res, err := a.Add(ctx, func(p testcapnp.Adder_add_Params) error {
+		p.SetA(5)
+		p.SetB(2)
+		return nil
+	}).Struct()
+	if err != nil {
+		t.Fatal("Add:", err)
+	}
+	if res.Result() != 7 {
+		t.Errorf("Add(5, 2) = %d; want 7", res.Result())
+	}
+	if ts := serverConn.TableSizes(); ts.Exports != 1 {
+		t.Errorf("server exports before release = %d; want 1", ts.Exports)
+	}
+	if err := a.Client.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+
+	rpctest.VerifyConnClean(t, clientConn)
+	rpctest.VerifyConnClean(t, serverConn)
+}
@@ -0,0 +1,218 @@
+package rpc
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"sort"
+
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/internal/nodemap"
+	"zombiezen.com/go/capnproto2/internal/schema"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+// A SchemaMismatch is an interface that a connection and its peer
+// hashed differently in the schema hash handshake, which usually means
+// that they were built with different revisions of its schema.
+type SchemaMismatch struct {
+	InterfaceID uint64
+	Local       uint64
+	Remote      uint64
+}
+
+// SchemaHashes makes the connection exchange hashes of interface
+// schemas with its peer, and call onMismatch for each interface that
+// the two hash differently.  hashes maps interface IDs to hashes, like
+// the ones from HashInterface.  Interfaces that only one side hashes
+// are ignored.
+//
+// The connection sends its hashes when it bootstraps, in a call
+// pipelined on the peer's main interface, and answers the hashes that
+// it receives with its own.  A peer that doesn't use SchemaHashes
+// delivers the call to its main interface, which should reject it as
+// unimplemented; the handshake is then skipped.  onMismatch is called
+// from its own goroutine.
+func SchemaHashes(hashes map[uint64]uint64, onMismatch func(SchemaMismatch)) ConnOption {
+	return ConnOption{func(c *connParams) {
+		c.hashes = hashes
+		c.onMismatch = onMismatch
+	}}
+}
+
+// HashInterface returns a hash of the schema of the interface with the
+// given ID, which must be in the default registry.  The hash covers the
+// interface's node and the nodes of its methods' parameter and result
+// structs, so it changes when any of them change.
+func HashInterface(id uint64) (uint64, error) {
+	var nodes nodemap.Map
+	n, err := nodes.Find(id)
+	if err != nil {
+		return 0, err
+	}
+	if n.Which() != schema.Node_Which_interface {
+		return 0, fmt.Errorf("rpc: hash schema @%#x: not an interface", id)
+	}
+	h := fnv.New64a()
+	if err := hashNode(h, n); err != nil {
+		return 0, fmt.Errorf("rpc: hash schema @%#x: %v", id, err)
+	}
+	methods, err := n.Interface().Methods()
+	if err != nil {
+		return 0, fmt.Errorf("rpc: hash schema @%#x: %v", id, err)
+	}
+	for i := 0; i < methods.Len(); i++ {
+		m := methods.At(i)
+		for _, sid := range []uint64{m.ParamStructType(), m.ResultStructType()} {
+			sn, err := nodes.Find(sid)
+			if err != nil {
+				return 0, fmt.Errorf("rpc: hash schema @%#x: %v", id, err)
+			}
+			if err := hashNode(h, sn); err != nil {
+				return 0, fmt.Errorf("rpc: hash schema @%#x: %v", id, err)
+			}
+		}
+	}
+	return h.Sum64(), nil
+}
+
+func hashNode(h hash.Hash, n schema.Node) error {
+	b, err := capnp.Canonicalize(n.Struct)
+	if err != nil {
+		return err
+	}
+	h.Write(b)
+	return nil
+}
+
+// schemaHashMethod is the call that carries the handshake.  Its
+// interface ID doesn't belong to any schema, so peers that don't do the
+// handshake don't mistake it for a call on a real interface.  The
+// parameters and results are a struct with a list of schemaHashSize
+// structs as its only pointer.  Each struct has the interface ID in its
+// first word and the hash in its second.
+var schemaHashMethod = capnp.Method{
+	InterfaceID:   0xe2b8f1c6d9a4735b,
+	MethodID:      0,
+	InterfaceName: "rpc",
+	MethodName:    "schemaHashes",
+}
+
+var (
+	schemaHashesSize = capnp.ObjectSize{PointerCount: 1}
+	schemaHashSize   = capnp.ObjectSize{DataSize: 16}
+)
+
+// startSchemaHandshake sends the connection's hashes in a call
+// pipelined on q, the bootstrap question.  The caller holds onto c.mu.
+func (c *Conn) startSchemaHandshake(q *question) {
+	ans := q.lockedPipelineCall(nil, &capnp.Call{
+		Ctx:        c.bg,
+		Method:     schemaHashMethod,
+		ParamsSize: schemaHashesSize,
+		ParamsFunc: func(s capnp.Struct) error {
+			return writeSchemaHashes(s, c.hashes)
+		},
+	})
+	go func() {
+		res, err := ans.Struct()
+		if isUnimplementedException(err) {
+			// The peer doesn't do the handshake.
+			return
+		}
+		if err != nil {
+			c.infof("schema hash handshake: %v", err)
+			return
+		}
+		mismatches, err := c.schemaMismatches(res)
+		if err != nil {
+			c.infof("schema hash handshake: %v", err)
+			return
+		}
+		c.reportSchemaMismatches(mismatches)
+	}()
+}
+
+// answerSchemaHandshake answers a handshake call from the peer with the
+// connection's hashes.  The caller holds onto c.mu.
+func (c *Conn) answerSchemaHandshake(a *answer, params capnp.Struct) error {
+	mismatches, err := c.schemaMismatches(params)
+	if err != nil {
+		return a.reject(err)
+	}
+	if len(mismatches) > 0 {
+		go c.reportSchemaMismatches(mismatches)
+	}
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return a.reject(err)
+	}
+	res, err := capnp.NewRootStruct(seg, schemaHashesSize)
+	if err != nil {
+		return a.reject(err)
+	}
+	if err := writeSchemaHashes(res, c.hashes); err != nil {
+		return a.reject(err)
+	}
+	return a.fulfill(res.ToPtr())
+}
+
+func writeSchemaHashes(s capnp.Struct, hashes map[uint64]uint64) error {
+	ids := make([]uint64, 0, len(hashes))
+	for id := range hashes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	l, err := capnp.NewCompositeList(s.Segment(), schemaHashSize, int32(len(ids)))
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		e := l.Struct(i)
+		e.SetUint64(0, id)
+		e.SetUint64(8, hashes[id])
+	}
+	return s.SetPtr(0, l.ToPtr())
+}
+
+// schemaMismatches compares the hashes in s, a handshake's parameters
+// or results, with the connection's.
+func (c *Conn) schemaMismatches(s capnp.Struct) ([]SchemaMismatch, error) {
+	p, err := s.Ptr(0)
+	if err != nil {
+		return nil, err
+	}
+	l := p.List()
+	var mismatches []SchemaMismatch
+	for i := 0; i < l.Len(); i++ {
+		e := l.Struct(i)
+		id, remote := e.Uint64(0), e.Uint64(8)
+		if local, ok := c.hashes[id]; ok && local != remote {
+			mismatches = append(mismatches, SchemaMismatch{
+				InterfaceID: id,
+				Local:       local,
+				Remote:      remote,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// isUnimplementedException reports whether err is an unimplemented
+// exception from the peer.
+func isUnimplementedException(err error) bool {
+	if me, ok := err.(*capnp.MethodError); ok {
+		err = me.Err
+	}
+	e, ok := err.(Exception)
+	return ok && e.Type() == rpccapnp.Exception_Type_unimplemented
+}
+
+func (c *Conn) reportSchemaMismatches(mismatches []SchemaMismatch) {
+	if c.onMismatch == nil {
+		return
+	}
+	for _, m := range mismatches {
+		c.onMismatch(m)
+	}
+}
@@ -0,0 +1,112 @@
+package rpc_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestHashInterface(t *testing.T) {
+	h1, err := rpc.HashInterface(testcapnp.Adder_TypeID)
+	if err != nil {
+		t.Fatal("HashInterface(Adder):", err)
+	}
+	h2, err := rpc.HashInterface(testcapnp.HandleFactory_TypeID)
+	if err != nil {
+		t.Fatal("HashInterface(HandleFactory):", err)
+	}
+	if h1 == h2 {
+		t.Errorf("Adder and HandleFactory both hash to %#x", h1)
+	}
+	if h, _ := rpc.HashInterface(testcapnp.Adder_TypeID); h != h1 {
+		t.Errorf("Adder hashed to %#x, then %#x", h1, h)
+	}
+	if _, err := rpc.HashInterface(testcapnp.Adder_add_Params_TypeID); err == nil {
+		t.Error("HashInterface of a struct succeeded")
+	}
+}
+
+func TestSchemaHashes(t *testing.T) {
+	const otherID = 0x9a0c4b2e7d1f3865
+	tests := []struct {
+		name       string
+		peerHashes map[uint64]uint64 // nil for no handshake
+		mismatch   bool
+	}{
+		{name: "match", peerHashes: map[uint64]uint64{testcapnp.Adder_TypeID: 1}},
+		{name: "mismatch", peerHashes: map[uint64]uint64{testcapnp.Adder_TypeID: 2, otherID: 3}, mismatch: true},
+		{name: "no handshake"},
+	}
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		p, q := pipetransport.New()
+		if *logMessages {
+			p = logtransport.New(nil, p)
+		}
+		log := testLogger{t}
+		cMismatch := make(chan rpc.SchemaMismatch, 1)
+		c := rpc.NewConn(p, rpc.ConnLog(log), rpc.SchemaHashes(map[uint64]uint64{testcapnp.Adder_TypeID: 1}, func(m rpc.SchemaMismatch) {
+			cMismatch <- m
+		}))
+		dMismatch := make(chan rpc.SchemaMismatch, 1)
+		dopts := []rpc.ConnOption{rpc.MainInterface(testcapnp.Adder_ServerToClient(AdderServer{}).Client), rpc.ConnLog(log)}
+		if test.peerHashes != nil {
+			dopts = append(dopts, rpc.SchemaHashes(test.peerHashes, func(m rpc.SchemaMismatch) {
+				dMismatch <- m
+			}))
+		}
+		d := rpc.NewConn(q, dopts...)
+
+		adder := testcapnp.Adder{Client: c.Bootstrap(ctx)}
+		res, err := adder.Add(ctx, func(p testcapnp.Adder_add_Params) error {
+			p.SetA(5)
+			p.SetB(2)
+			return nil
+		}).Struct()
+		if err != nil {
+			t.Errorf("%s: Add: %v", test.name, err)
+		} else if res.Result() != 7 {
+			t.Errorf("%s: Add(5, 2) = %d; want 7", test.name, res.Result())
+		}
+
+		if test.mismatch {
+			want := rpc.SchemaMismatch{InterfaceID: testcapnp.Adder_TypeID, Local: 1, Remote: 2}
+			if m := waitMismatch(cMismatch); m == nil || *m != want {
+				t.Errorf("%s: client mismatch = %v; want %v", test.name, m, want)
+			}
+			want.Local, want.Remote = 2, 1
+			if m := waitMismatch(dMismatch); m == nil || *m != want {
+				t.Errorf("%s: server mismatch = %v; want %v", test.name, m, want)
+			}
+		} else {
+			// The peer answered the handshake before the Add call, so
+			// a mismatch would usually have been reported by now.
+			select {
+			case m := <-cMismatch:
+				t.Errorf("%s: client reported mismatch %v", test.name, m)
+			case m := <-dMismatch:
+				t.Errorf("%s: server reported mismatch %v", test.name, m)
+			default:
+			}
+		}
+
+		adder.Client.Close()
+		c.Close()
+		d.Wait()
+		cancel()
+	}
+}
+
+func waitMismatch(ch <-chan rpc.SchemaMismatch) *rpc.SchemaMismatch {
+	select {
+	case m := <-ch:
+		return &m
+	case <-time.After(5 * time.Second):
+		return nil
+	}
+}
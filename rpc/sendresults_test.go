@@ -0,0 +1,327 @@
+package rpc_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestForwardedCallSendsResultsToYourself(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	echoSrv := testcapnp.Echoer_ServerToClient(new(Echoer))
+	conn, p := newUnpairedConn(t, rpc.MainInterface(echoSrv.Client))
+	defer conn.Close()
+	defer p.Close()
+	importID := sendBootstrapAndFinish(t, p)
+
+	// echo a capability hosted here, so that its result points back.
+	const echoQID, seqQID, capID = 1, 2, 5
+	err := sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		call, err := msg.NewCall()
+		if err != nil {
+			return err
+		}
+		call.SetQuestionId(echoQID)
+		call.SetInterfaceId(testcapnp.Echoer_TypeID)
+		call.SetMethodId(0)
+		target, err := call.NewTarget()
+		if err != nil {
+			return err
+		}
+		target.SetImportedCap(importID)
+		payload, err := call.NewParams()
+		if err != nil {
+			return err
+		}
+		content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{PointerCount: 1})
+		if err != nil {
+			return err
+		}
+		if err := content.SetPtr(0, capnp.NewInterface(msg.Segment(), 0).ToPtr()); err != nil {
+			return err
+		}
+		if err := payload.SetContentPtr(content.ToPtr()); err != nil {
+			return err
+		}
+		ctab, err := payload.NewCapTable(1)
+		if err != nil {
+			return err
+		}
+		ctab.At(0).SetSenderHosted(capID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("sending echo call:", err)
+	}
+	if ret := recvReturn(ctx, t, p, echoQID); ret.Which() != rpccapnp.Return_Which_results {
+		t.Fatalf("echo return is %v; want results", ret.Which())
+	}
+
+	// A call pipelined on the echo result is forwarded back here.
+	err = sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		call, err := msg.NewCall()
+		if err != nil {
+			return err
+		}
+		call.SetQuestionId(seqQID)
+		call.SetInterfaceId(testcapnp.CallOrder_TypeID)
+		call.SetMethodId(0)
+		target, err := call.NewTarget()
+		if err != nil {
+			return err
+		}
+		pa, err := target.NewPromisedAnswer()
+		if err != nil {
+			return err
+		}
+		pa.SetQuestionId(echoQID)
+		ops, err := pa.NewTransform(1)
+		if err != nil {
+			return err
+		}
+		ops.At(0).SetGetPointerField(0)
+		payload, err := call.NewParams()
+		if err != nil {
+			return err
+		}
+		content, err := capnp.NewStruct(msg.Segment(), capnp.ObjectSize{DataSize: 8})
+		if err != nil {
+			return err
+		}
+		return payload.SetContentPtr(content.ToPtr())
+	})
+	if err != nil {
+		t.Fatal("sending pipelined call:", err)
+	}
+	msg := recvMessageOf(ctx, t, p, rpccapnp.Message_Which_call)
+	fwd, _ := msg.Call()
+	if w := fwd.SendResultsTo().Which(); w != rpccapnp.Call_sendResultsTo_Which_yourself {
+		t.Errorf("forwarded call sends results to %v; want yourself", w)
+	}
+	if target, _ := fwd.Target(); target.Which() != rpccapnp.MessageTarget_Which_importedCap || target.ImportedCap() != capID {
+		t.Errorf("forwarded call target = %v; want importedCap %d", target, capID)
+	}
+	fwdQID := fwd.QuestionId()
+
+	err = sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		ret, err := msg.NewReturn()
+		if err != nil {
+			return err
+		}
+		ret.SetAnswerId(fwdQID)
+		ret.SetResultsSentElsewhere()
+		return nil
+	})
+	if err != nil {
+		t.Fatal("sending resultsSentElsewhere:", err)
+	}
+	ret := recvReturn(ctx, t, p, seqQID)
+	if ret.Which() != rpccapnp.Return_Which_takeFromOtherQuestion {
+		t.Fatalf("pipelined call return is %v; want takeFromOtherQuestion", ret.Which())
+	}
+	if id := ret.TakeFromOtherQuestion(); id != fwdQID {
+		t.Errorf("takeFromOtherQuestion = %d; want %d", id, fwdQID)
+	}
+
+	// Finishing the pipelined call finishes the forwarded one.
+	err = sendMessage(ctx, p, func(msg rpccapnp.Message) error {
+		fin, err := msg.NewFinish()
+		if err != nil {
+			return err
+		}
+		fin.SetQuestionId(seqQID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("sending finish:", err)
+	}
+	msg = recvMessageOf(ctx, t, p, rpccapnp.Message_Which_finish)
+	if fin, _ := msg.Finish(); fin.QuestionId() != fwdQID {
+		t.Errorf("finish for question %d; want %d", fin.QuestionId(), fwdQID)
+	}
+}
+
+func TestTakeFromOtherQuestion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	ht := &holdTransport{Transport: p, held: make(chan struct{}), release: make(chan struct{})}
+	log := testLogger{t}
+	c := rpc.NewConn(ht, rpc.ConnLog(log))
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.Echoer_ServerToClient(new(Echoer)).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	// Holding back the echo's Return makes the next call a pipelined
+	// call that d has to forward back to c.
+	local := testcapnp.CallOrder_ServerToClient(new(CallOrder))
+	ans := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(local)
+	})
+	<-ht.held
+	seq := callseq(ctx, ans.Cap().Client, 0)
+	close(ht.release)
+	r, err := seq.Struct()
+	if err != nil {
+		t.Fatal("getCallSequence:", err)
+	}
+	if n := r.N(); n != 0 {
+		t.Errorf("getCallSequence() = %d; want 0", n)
+	}
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	if !ht.yourself {
+		t.Error("no call was received with sendResultsTo.yourself")
+	}
+	if !ht.take {
+		t.Error("no return was received with takeFromOtherQuestion")
+	}
+}
+
+func TestServerTailCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	ht := &holdTransport{Transport: p, held: make(chan struct{}), release: make(chan struct{})}
+	log := testLogger{t}
+	c := rpc.NewConn(ht, rpc.ConnLog(log))
+	d := rpc.NewConn(q, rpc.MainInterface(testcapnp.Echoer_ServerToClient(new(TailEchoer)).Client), rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	echo := testcapnp.Echoer{Client: c.Bootstrap(ctx)}
+
+	// d's server delegates the echo to the capability, which is hosted
+	// here, so the results never leave this vat.
+	local := testcapnp.Echoer_ServerToClient(new(Echoer))
+	res, err := echo.Echo(ctx, func(p testcapnp.Echoer_echo_Params) error {
+		return p.SetCap(testcapnp.CallOrder{Client: local.Client})
+	}).Struct()
+	if err != nil {
+		t.Fatal("echo:", err)
+	}
+	seq, err := callseq(ctx, res.Cap().Client, 0).Struct()
+	if err != nil {
+		t.Fatal("getCallSequence:", err)
+	}
+	if n := seq.N(); n != 0 {
+		t.Errorf("getCallSequence() = %d; want 0", n)
+	}
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	if !ht.yourself {
+		t.Error("no call was received with sendResultsTo.yourself")
+	}
+	if !ht.take {
+		t.Error("no return was received with takeFromOtherQuestion")
+	}
+}
+
+// TailEchoer is an Echoer that delegates echo to its capability with a
+// tail call.
+type TailEchoer struct {
+	CallOrder
+}
+
+func (*TailEchoer) Echo(call testcapnp.Echoer_echo) error {
+	return call.TailCall(testcapnp.Echoer{Client: call.Params.Cap().Client}, nil)
+}
+
+// holdTransport holds back the first Return with capabilities that it
+// receives until release is closed, and records whether it received
+// messages that use sendResultsTo.yourself.
+type holdTransport struct {
+	rpc.Transport
+	held    chan struct{}
+	release chan struct{}
+
+	mu       sync.Mutex
+	holding  bool
+	yourself bool
+	take     bool
+}
+
+func (ht *holdTransport) RecvMessage(ctx context.Context) (rpccapnp.Message, error) {
+	msg, err := ht.Transport.RecvMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	ht.mu.Lock()
+	hold := false
+	switch msg.Which() {
+	case rpccapnp.Message_Which_call:
+		call, _ := msg.Call()
+		if call.SendResultsTo().Which() == rpccapnp.Call_sendResultsTo_Which_yourself {
+			ht.yourself = true
+		}
+	case rpccapnp.Message_Which_return:
+		ret, _ := msg.Return()
+		switch ret.Which() {
+		case rpccapnp.Return_Which_results:
+			payload, _ := ret.Results()
+			ctab, _ := payload.CapTable()
+			if ctab.Len() > 0 && ret.AnswerId() != 0 && !ht.holding {
+				ht.holding, hold = true, true
+			}
+		case rpccapnp.Return_Which_takeFromOtherQuestion:
+			ht.take = true
+		}
+	}
+	ht.mu.Unlock()
+	if hold {
+		close(ht.held)
+		select {
+		case <-ht.release:
+		case <-ctx.Done():
+			return rpccapnp.Message{}, ctx.Err()
+		}
+	}
+	return msg, nil
+}
+
+// recvMessageOf receives messages from p until it receives one of the
+// given type.  Release messages for the capability passed to the Conn
+// may arrive at any time, so other messages are skipped.
+func recvMessageOf(ctx context.Context, t *testing.T, p rpc.Transport, which rpccapnp.Message_Which) rpccapnp.Message {
+	t.Helper()
+	for {
+		msg, err := p.RecvMessage(ctx)
+		if err != nil {
+			t.Fatalf("waiting for %v message: %v", which, err)
+		}
+		if msg.Which() == which {
+			return msg
+		}
+	}
+}
+
+func recvReturn(ctx context.Context, t *testing.T, p rpc.Transport, id uint32) rpccapnp.Return {
+	t.Helper()
+	for {
+		msg := recvMessageOf(ctx, t, p, rpccapnp.Message_Which_return)
+		ret, err := msg.Return()
+		if err != nil {
+			t.Fatal("return error:", err)
+		}
+		if ret.AnswerId() == id {
+			return ret
+		}
+	}
+}
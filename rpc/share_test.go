@@ -0,0 +1,67 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+)
+
+func TestShare(t *testing.T) {
+	ctx := context.Background()
+	closed := make(chan struct{})
+	adder := testcapnp.Adder_ServerToClient(AdderServer{})
+	shared := rpc.Share(closeNotifier{adder.Client, closed})
+
+	snap := capnp.Snapshot(shared)
+	stolen := capnp.Steal(shared)
+	if err := shared.Close(); err == nil {
+		t.Error("Close after Steal succeeded; want error")
+	}
+	add := func(c capnp.Client) error {
+		_, err := testcapnp.Adder{Client: c}.Add(ctx, func(p testcapnp.Adder_add_Params) error {
+			p.SetA(1)
+			p.SetB(2)
+			return nil
+		}).Struct()
+		return err
+	}
+	if err := add(snap); err != nil {
+		t.Error("add on snapshot:", err)
+	}
+	if err := add(stolen); err != nil {
+		t.Error("add on stolen reference:", err)
+	}
+
+	if err := stolen.Close(); err != nil {
+		t.Error("stolen.Close():", err)
+	}
+	select {
+	case <-closed:
+		t.Fatal("client closed with a snapshot open")
+	default:
+	}
+	if err := snap.Close(); err != nil {
+		t.Error("snap.Close():", err)
+	}
+	select {
+	case <-closed:
+	default:
+		t.Error("client not closed after all references closed")
+	}
+}
+
+func TestSnapshot_NotShared(t *testing.T) {
+	adder := testcapnp.Adder_ServerToClient(AdderServer{})
+	defer adder.Client.Close()
+	snap := capnp.Snapshot(adder.Client)
+	_, err := snap.Call(&capnp.Call{Ctx: context.Background(), Method: capnp.Method{InterfaceID: testcapnp.Adder_TypeID}}).Struct()
+	if err != capnp.ErrNotShared {
+		t.Errorf("call on Snapshot of unshared client = %v; want %v", err, capnp.ErrNotShared)
+	}
+	if stolen := capnp.Steal(adder.Client); stolen != adder.Client {
+		t.Error("Steal of unshared client returned a different client")
+	}
+}
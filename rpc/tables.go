@@ -5,6 +5,7 @@ import (
 
 	"zombiezen.com/go/capnproto2"
 	"zombiezen.com/go/capnproto2/rpc/internal/refcount"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
 )
 
 // Table IDs
@@ -69,44 +70,69 @@ func (ic *importClient) Call(cl *capnp.Call) capnp.Answer {
 	case <-cl.Ctx.Done():
 		return capnp.ErrorAnswer(cl.Ctx.Err())
 	}
-	ans := ic.lockedCall(cl)
+	msg, err := ic.newCallMessage(cl)
+	if err != nil {
+		ic.conn.workers.Done()
+		ic.conn.mu.Unlock()
+		return capnp.ErrorAnswer(err)
+	}
+	done, err := ic.conn.startFlow(cl, msg)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	var ans capnp.Answer
+	if ic.closed {
+		// Closed while waiting for the flow limiter.
+		if done != nil {
+			done(errImportClosed)
+		}
+		ans = capnp.ErrorAnswer(errImportClosed)
+	} else if q, err := ic.conn.sendCall(msg, cl, done); err != nil {
+		ans = capnp.ErrorAnswer(err)
+	} else {
+		ans = q
+	}
 	ic.conn.workers.Done()
 	ic.conn.mu.Unlock()
 	return ans
 }
 
 // lockedCall is equivalent to Call but assumes that the caller is
-// already holding onto ic.conn.mu.
+// already holding onto ic.conn.mu.  It does not wait for the call's
+// flow limiter.
 func (ic *importClient) lockedCall(cl *capnp.Call) capnp.Answer {
-	if ic.closed {
-		return capnp.ErrorAnswer(errImportClosed)
+	msg, err := ic.newCallMessage(cl)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
 	}
+	q, err := ic.conn.sendCall(msg, cl, nil)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	return q
+}
 
-	q := ic.conn.newQuestion(cl.Ctx, &cl.Method)
+// newCallMessage builds a Call message for cl targeting the import.
+// Its question ID is set by sendCall.  The caller must be holding onto
+// ic.conn.mu.
+func (ic *importClient) newCallMessage(cl *capnp.Call) (rpccapnp.Message, error) {
+	if ic.closed {
+		return rpccapnp.Message{}, errImportClosed
+	}
 	msg := newMessage(nil)
 	msgCall, _ := msg.NewCall()
-	msgCall.SetQuestionId(uint32(q.id))
 	msgCall.SetInterfaceId(cl.Method.InterfaceID)
 	msgCall.SetMethodId(cl.Method.MethodID)
+	if ic.conn.sendResultsToYourself(cl) {
+		msgCall.SendResultsTo().SetYourself()
+	}
 	target, _ := msgCall.NewTarget()
 	target.SetImportedCap(uint32(ic.id))
 	payload, _ := msgCall.NewParams()
 	if err := ic.conn.fillParams(payload, cl); err != nil {
-		ic.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(err)
+		return rpccapnp.Message{}, err
 	}
-
-	select {
-	case ic.conn.out <- msg:
-	case <-cl.Ctx.Done():
-		ic.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(cl.Ctx.Err())
-	case <-ic.conn.bg.Done():
-		ic.conn.popQuestion(q.id)
-		return capnp.ErrorAnswer(ErrConnClosed)
-	}
-	q.start()
-	return q
+	return msg, nil
 }
 
 func (ic *importClient) Close() error {
@@ -145,11 +171,106 @@ func (ic *importClient) Close() error {
 	}
 }
 
+// A lazyImportClient is placed in the capability table of a received
+// message for each import the message references.  It does not add the
+// import to the import table until it is first called, so capabilities
+// that the receiver never uses do not churn the import table.
+type lazyImportClient struct {
+	id   importID
+	conn *Conn
+
+	// The following fields are protected by conn.mu:
+	client capnp.Client // set once the import is added to the table
+	closed bool
+}
+
+// resolveLocked returns the client for the import, adding it to the
+// import table if necessary.  It returns nil if lc has been closed.
+// The caller must be holding onto lc.conn.mu.
+func (lc *lazyImportClient) resolveLocked() capnp.Client {
+	if lc.closed {
+		return nil
+	}
+	if lc.client == nil {
+		lc.client = lc.conn.addImport(lc.id)
+	}
+	return lc.client
+}
+
+func (lc *lazyImportClient) Call(cl *capnp.Call) capnp.Answer {
+	select {
+	case <-lc.conn.mu:
+		if err := lc.conn.startWork(); err != nil {
+			return capnp.ErrorAnswer(err)
+		}
+	case <-cl.Ctx.Done():
+		return capnp.ErrorAnswer(cl.Ctx.Err())
+	}
+	client := lc.resolveLocked()
+	lc.conn.workers.Done()
+	lc.conn.mu.Unlock()
+	if client == nil {
+		return capnp.ErrorAnswer(errImportClosed)
+	}
+	// Call the import without holding the lock, so that the call's flow
+	// limiter, if any, is applied.
+	return client.Call(cl)
+}
+
+// Close releases the message's reference to the import.  If the import
+// was never called, then the reference is handed to the import's table
+// entry if there is one, or released with a Release message otherwise.
+func (lc *lazyImportClient) Close() error {
+	lc.conn.mu.Lock()
+	if err := lc.conn.startWork(); err != nil {
+		lc.conn.mu.Unlock()
+		return err
+	}
+	if lc.closed {
+		lc.conn.workers.Done()
+		lc.conn.mu.Unlock()
+		return errImportClosed
+	}
+	lc.closed = true
+	client := lc.client
+	release := false
+	if client == nil {
+		if ent := lc.conn.imports[lc.id]; ent != nil {
+			ent.refs++
+		} else {
+			release = true
+		}
+	}
+	lc.conn.workers.Done()
+	lc.conn.mu.Unlock()
+
+	if client != nil {
+		return client.Close()
+	}
+	if !release {
+		return nil
+	}
+	msg := newMessage(nil)
+	mr, err := msg.NewRelease()
+	if err != nil {
+		return err
+	}
+	mr.SetId(uint32(lc.id))
+	mr.SetReferenceCount(1)
+	select {
+	case lc.conn.out <- msg:
+		return nil
+	case <-lc.conn.bg.Done():
+		return ErrConnClosed
+	}
+}
+
 type export struct {
 	id       exportID
 	rc       *refcount.RefCount
-	client   capnp.Client
+	client   *refcount.Ref
 	wireRefs int
+	size     int64 // size charged to the connection's export quota
 }
 
 func (c *Conn) findExport(id exportID) *export {
@@ -161,27 +282,37 @@ func (c *Conn) findExport(id exportID) *export {
 
 // addExport ensures that the client is present in the table, returning its ID.
 // If the client is already in the table, the previous ID is returned.
-func (c *Conn) addExport(client capnp.Client) exportID {
+// It returns ErrExportQuota if adding the client would exceed the
+// connection's export quota.
+func (c *Conn) addExport(client capnp.Client) (exportID, error) {
 	for i, e := range c.exports {
 		if e != nil && isSameClient(e.rc.Client, client) {
 			e.wireRefs++
-			return exportID(i)
+			return exportID(i), nil
+		}
+	}
+	var size int64
+	if c.expQuota != nil {
+		size = c.expQuota.size(client)
+		if err := c.expQuota.acquire(c.peer, size); err != nil {
+			return 0, err
 		}
 	}
 	id := exportID(c.exportID.next())
-	rc, client := refcount.New(client)
+	rc, ref := refcount.New(client)
 	export := &export{
 		id:       id,
 		rc:       rc,
-		client:   client,
+		client:   ref,
 		wireRefs: 1,
+		size:     size,
 	}
 	if int(id) == len(c.exports) {
 		c.exports = append(c.exports, export)
 	} else {
 		c.exports[id] = export
 	}
-	return id
+	return id, nil
 }
 
 func (c *Conn) releaseExport(id exportID, refs int) {
@@ -201,6 +332,28 @@ func (c *Conn) releaseExport(id exportID, refs int) {
 	}
 	c.exports[id] = nil
 	c.exportID.remove(uint32(id))
+	if c.expQuota != nil {
+		c.expQuota.release(c.peer, e.size)
+	}
+}
+
+// unexport takes back a reference added by addExport for a message
+// that was not sent.  Unlike releaseExport, it leaves the client open,
+// since the message's capability table still holds it.
+func (c *Conn) unexport(id exportID) {
+	e := c.findExport(id)
+	if e == nil {
+		return
+	}
+	e.wireRefs--
+	if e.wireRefs > 0 {
+		return
+	}
+	c.exports[id] = nil
+	c.exportID.remove(uint32(id))
+	if c.expQuota != nil {
+		c.expQuota.release(c.peer, e.size)
+	}
 }
 
 type embargo <-chan struct{}
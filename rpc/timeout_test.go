@@ -0,0 +1,147 @@
+package rpc_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	"zombiezen.com/go/capnproto2/server"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestAnswerTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	started := make(chan struct{}, 2)
+	d := rpc.NewConn(q,
+		rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(stuckHandleFactory(started)).Client),
+		rpc.ConnLog(log),
+		rpc.ConnClock(clk),
+		rpc.AnswerTimeout(30*time.Second))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+
+	// The first call blocks the server, so the second waits in the
+	// server's queue.
+	ans1 := client.NewHandle(ctx, nil)
+	defer ans1.Close()
+	<-started
+	ans2 := client.NewHandle(ctx, nil)
+	defer ans2.Close()
+	waitPending(t, clk, 2)
+
+	clk.Advance(29 * time.Second)
+	select {
+	case <-started:
+		t.Fatal("second call delivered while first call is running")
+	default:
+	}
+	clk.Advance(time.Second)
+	if _, err := ans1.Struct(); err == nil {
+		t.Error("running call succeeded after timeout")
+	}
+	if _, err := ans2.Struct(); err == nil {
+		t.Error("queued call succeeded after timeout")
+	}
+}
+
+func TestAnswerTimeout_StopsOnReturn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	d := rpc.NewConn(q,
+		rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(new(HandleFactory)).Client),
+		rpc.ConnLog(log),
+		rpc.ConnClock(clk),
+		rpc.AnswerTimeout(30*time.Second))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+
+	ans := client.NewHandle(ctx, nil)
+	defer ans.Close()
+	if _, err := ans.Struct(); err != nil {
+		t.Fatal("NewHandle:", err)
+	}
+	if n := clk.Pending(); n != 0 {
+		t.Errorf("after return, %d timers pending; want 0", n)
+	}
+}
+
+func TestMethodTimeoutRemote(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	clk := clock.NewFake(time.Unix(0, 0))
+	started := make(chan struct{}, 1)
+	methods := testcapnp.HandleFactory_Methods(nil, stuckHandleFactory(started))
+	methods[0].Timeout = 30 * time.Second
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	d := rpc.NewConn(q,
+		rpc.MainInterface(server.New(methods, nil, server.Clock(clk))),
+		rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+
+	ans := client.NewHandle(ctx, nil)
+	defer ans.Close()
+	<-started
+	waitPending(t, clk, 1)
+	clk.Advance(30 * time.Second)
+	_, err := ans.Struct()
+	if me, ok := err.(*capnp.MethodError); ok {
+		err = me.Err
+	}
+	e, ok := err.(rpc.Exception)
+	if !ok {
+		t.Fatalf("NewHandle error = %v; want rpc.Exception", err)
+	}
+	if e.Type() != rpccapnp.Exception_Type_overloaded {
+		t.Errorf("exception type = %v; want %v", e.Type(), rpccapnp.Exception_Type_overloaded)
+	}
+}
+
+// waitPending waits for the connection to schedule n answer timeouts.
+func waitPending(t *testing.T, clk *clock.Fake, n int) {
+	for i := 0; clk.Pending() < n; i++ {
+		if i >= 1000 {
+			t.Fatalf("timed out waiting for %d pending timers (have %d)", n, clk.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// stuckHandleFactory is a HandleFactory whose calls block until their
+// context is canceled.  It sends on the channel as each call starts.
+type stuckHandleFactory chan<- struct{}
+
+func (hf stuckHandleFactory) NewHandle(call testcapnp.HandleFactory_newHandle) error {
+	hf <- struct{}{}
+	<-call.Ctx.Done()
+	return call.Ctx.Err()
+}
@@ -18,7 +18,10 @@ type Transport interface {
 
 	// RecvMessage waits to receive a message and returns it.
 	// Implementations may re-use buffers between calls, so the message is
-	// only valid until the next call to RecvMessage.
+	// only valid until the next call to RecvMessage.  The Conn releases
+	// each received message's capnp.Message once it is done with it, so
+	// an implementation that decodes into pooled buffers can add a
+	// releaser to return the buffer (see capnp.Message.AddReleaser).
 	RecvMessage(ctx context.Context) (rpccapnp.Message, error)
 
 	// Close releases any resources associated with the transport.
@@ -129,7 +132,9 @@ func (c *Conn) dispatchRecv() {
 	for {
 		msg, err := c.transport.RecvMessage(c.bg)
 		if err == nil {
+			// handleMessage copies anything it keeps.
 			c.handleMessage(msg)
+			msg.Segment().Message().Release()
 		} else if isTemporaryError(err) {
 			c.errorf("read temporary error: %v", err)
 		} else {
@@ -0,0 +1,45 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/rpc"
+	"zombiezen.com/go/capnproto2/rpc/internal/logtransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/pipetransport"
+	"zombiezen.com/go/capnproto2/rpc/internal/testcapnp"
+	rpccapnp "zombiezen.com/go/capnproto2/std/capnp/rpc"
+)
+
+func TestUnimplementedMethod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, q := pipetransport.New()
+	if *logMessages {
+		p = logtransport.New(nil, p)
+	}
+	log := testLogger{t}
+	c := rpc.NewConn(p, rpc.ConnLog(log))
+	impl := struct {
+		testcapnp.UnimplementedHandleFactory_Server
+	}{}
+	d := rpc.NewConn(q,
+		rpc.MainInterface(testcapnp.HandleFactory_ServerToClient(impl).Client),
+		rpc.ConnLog(log))
+	defer d.Wait()
+	defer c.Close()
+	client := testcapnp.HandleFactory{Client: c.Bootstrap(ctx)}
+
+	_, err := client.NewHandle(ctx, nil).Struct()
+	if me, ok := err.(*capnp.MethodError); ok {
+		err = me.Err
+	}
+	e, ok := err.(rpc.Exception)
+	if !ok {
+		t.Fatalf("NewHandle error = %v; want rpc.Exception", err)
+	}
+	if e.Type() != rpccapnp.Exception_Type_unimplemented {
+		t.Errorf("exception type = %v; want %v", e.Type(), rpccapnp.Exception_Type_unimplemented)
+	}
+}
@@ -42,11 +42,19 @@ type Registry struct {
 }
 
 // Register indexes a schema in the registry.  It is an error to
-// register schemas with overlapping IDs.
+// register schemas with overlapping IDs, except that registering a
+// schema whose IDs are all already registered is a no-op.  This happens
+// when a program links two packages generated from the same schema
+// file, for instance one by the upstream capnpc-go and one by this
+// module's, while it migrates from one to the other.  The first
+// registration is kept.
 func (reg *Registry) Register(s *Schema) error {
 	if len(s.String) > 0 && len(s.Bytes) > 0 {
 		return errors.New("schemas: schema should have only one of string or bytes")
 	}
+	if reg.hasAll(s.Nodes) {
+		return nil
+	}
 	r := &record{
 		s:          s.String,
 		data:       s.Bytes,
@@ -64,6 +72,19 @@ func (reg *Registry) Register(s *Schema) error {
 	return nil
 }
 
+// hasAll reports whether every one of ids is registered.
+func (reg *Registry) hasAll(ids []uint64) bool {
+	if len(ids) == 0 {
+		return false
+	}
+	for _, id := range ids {
+		if _, ok := reg.m[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // Find returns the CodeGeneratorRequest message for the given ID,
 // suitable for capnp.Unmarshal.  If the ID is not found, Find returns
 // an error that can be identified with IsNotFound.  The returned byte
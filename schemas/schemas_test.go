@@ -51,3 +51,22 @@ func TestNotFound(t *testing.T) {
 		t.Errorf("new(schemas.Registry).Find(0) = %v; want not found error", err)
 	}
 }
+
+func TestRegisterDuplicate(t *testing.T) {
+	reg := new(schemas.Registry)
+	first := &schemas.Schema{Bytes: []byte("first"), Nodes: []uint64{1, 2}}
+	if err := reg.Register(first); err != nil {
+		t.Fatal("Register:", err)
+	}
+	again := &schemas.Schema{Bytes: []byte("again"), Nodes: []uint64{2, 1}}
+	if err := reg.Register(again); err != nil {
+		t.Error("Register of same IDs:", err)
+	}
+	if b, err := reg.Find(1); err != nil || string(b) != "first" {
+		t.Errorf("Find(1) = %q, %v; want first registration", b, err)
+	}
+	overlap := &schemas.Schema{Bytes: []byte("overlap"), Nodes: []uint64{2, 3}}
+	if err := reg.Register(overlap); err == nil {
+		t.Error("Register of overlapping IDs succeeded")
+	}
+}
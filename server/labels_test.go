@@ -0,0 +1,36 @@
+// +build go1.9
+
+package server_test
+
+import (
+	"runtime/pprof"
+	"testing"
+
+	"golang.org/x/net/context"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+)
+
+func TestProfilerLabels(t *testing.T) {
+	labels := make(chan [2]string, 1)
+	echo := air.Echo_ServerToClient(labelEcho(labels))
+	defer echo.Client.Close()
+
+	if _, err := echo.Echo(context.Background(), nil).Struct(); err != nil {
+		t.Fatal("Echo:", err)
+	}
+	want := [2]string{"aircraft.capnp:Echo", "echo"}
+	if got := <-labels; got != want {
+		t.Errorf("labels = %q; want %q", got, want)
+	}
+}
+
+// labelEcho is an Echo server that sends the profiler labels of each
+// call on the channel.
+type labelEcho chan<- [2]string
+
+func (e labelEcho) Echo(call air.Echo_echo) error {
+	iface, _ := pprof.Label(call.Ctx, "capnp.interface")
+	method, _ := pprof.Label(call.Ctx, "capnp.method")
+	e <- [2]string{iface, method}
+	return nil
+}
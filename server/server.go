@@ -6,10 +6,13 @@ import (
 	"errors"
 	"sort"
 	"sync"
+	"time"
 
 	"golang.org/x/net/context"
 	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
 	"zombiezen.com/go/capnproto2/internal/fulfiller"
+	"zombiezen.com/go/capnproto2/internal/profile"
 )
 
 // A Method describes a single method on a server object.
@@ -17,6 +20,15 @@ type Method struct {
 	capnp.Method
 	Impl        Func
 	ResultsSize capnp.ObjectSize
+
+	// Timeout limits how long the method may run, from the time a call
+	// is made until its implementation function returns, instead of the
+	// server's CallTimeout.  Once Timeout has elapsed on the server's
+	// clock, the call fails with capnp.ErrTimeout without waiting for
+	// the implementation function, and the call's context is canceled.
+	// The server moves on to the next call, so a hung dependency can't
+	// hold up the server.  Zero means the server's CallTimeout applies.
+	Timeout time.Duration
 }
 
 // A Func is a function that implements a single method.
@@ -34,6 +46,35 @@ type server struct {
 	queue   chan *call
 	stop    chan struct{}
 	done    chan struct{}
+
+	clock   clock.Clock
+	timeout time.Duration
+}
+
+// An Option is an option for creating a server.
+type Option struct {
+	f func(*server)
+}
+
+// Clock sets the clock that drives the server's timeouts.  Tests can
+// pass a *clock.Fake to trigger timeouts without waiting.  By default,
+// the server uses clock.Real.
+func Clock(clk clock.Clock) Option {
+	return Option{func(s *server) {
+		s.clock = clk
+	}}
+}
+
+// CallTimeout limits how long the server spends on each call, from
+// the time it is made until its implementation function returns.
+// Once d has elapsed on the server's clock, the call's context is
+// canceled.  This stops calls that are still waiting in the server's
+// queue and signals implementations to give up.  By default, there is
+// no timeout.
+func CallTimeout(d time.Duration) Option {
+	return Option{func(s *server) {
+		s.timeout = d
+	}}
 }
 
 // New returns a client that makes calls to a set of methods.
@@ -41,13 +82,22 @@ type server struct {
 // guarantees message delivery order by blocking each call on the
 // return or acknowledgment of the previous call.  See the Ack function
 // for more details.
-func New(methods []Method, closer Closer) capnp.Client {
+//
+// Implementation functions run with the profiler labels
+// "capnp.interface" and "capnp.method" set to the names of the method's
+// interface and the method, so CPU profiles attribute their work to the
+// method.
+func New(methods []Method, closer Closer, options ...Option) capnp.Client {
 	s := &server{
 		methods: make(sortedMethods, len(methods)),
 		closer:  closer,
 		queue:   make(chan *call),
 		stop:    make(chan struct{}),
 		done:    make(chan struct{}),
+		clock:   clock.Real,
+	}
+	for _, o := range options {
+		o.f(s)
 	}
 	copy(s.methods, methods)
 	sort.Sort(s.methods)
@@ -63,7 +113,8 @@ func (s *server) dispatch() {
 		case cl := <-s.queue:
 			err := s.startCall(cl)
 			if err != nil {
-				cl.ans.Reject(err)
+				cl.reject(err)
+				cl.stopTimer()
 			}
 		case <-s.stop:
 			return
@@ -82,15 +133,35 @@ func (s *server) startCall(cl *call) error {
 		return err
 	}
 	acksig := newAckSignal()
-	opts := cl.Options.With([]capnp.CallOption{capnp.SetOptionValue(ackSignalKey, acksig)})
-	go func() {
-		err := cl.method.Impl(cl.Ctx, opts, cl.Params, results)
-		if err == nil {
-			cl.ans.Fulfill(results)
+	tail := new(tailSlot)
+	opts := cl.Options.With([]capnp.CallOption{
+		capnp.SetOptionValue(ackSignalKey, acksig),
+		capnp.SetOptionValue(tailSlotKey, tail),
+	})
+	go profile.Do(cl.Ctx, &cl.method.Method, func(ctx context.Context) {
+		err := cl.method.Impl(ctx, opts, cl.Params, results)
+		if ans := tail.answer(); ans != nil && err == nil {
+			// The results of the tail call are the results.
+			out.Message().Release()
+			r, err := ans.Struct()
+			if err == nil {
+				cl.fulfill(r)
+			} else {
+				cl.reject(err)
+			}
+		} else if err == nil {
+			out.Message().SetAnswerOwned()
+			if !cl.fulfill(results) {
+				// The call timed out, so nothing can reach the results.
+				out.Message().ReleaseAnswer()
+			}
 		} else {
-			cl.ans.Reject(err)
+			// Nothing can reach the results, so release them now.
+			out.Message().Release()
+			cl.reject(err)
 		}
-	}()
+		cl.stopTimer()
+	})
 	select {
 	case <-acksig.c:
 	case <-cl.ans.Done():
@@ -116,13 +187,22 @@ func (s *server) Call(cl *capnp.Call) capnp.Answer {
 		return capnp.ErrorAnswer(err)
 	}
 	scall := newCall(cl, sm)
+	if sm.Timeout > 0 {
+		scall.startTimer(s.clock, sm.Timeout, true)
+	} else if s.timeout > 0 {
+		scall.startTimer(s.clock, s.timeout, false)
+	}
 	select {
 	case s.queue <- scall:
 		return &scall.ans
 	case <-s.stop:
+		scall.stopTimer()
 		return capnp.ErrorAnswer(errClosed)
-	case <-cl.Ctx.Done():
-		return capnp.ErrorAnswer(cl.Ctx.Err())
+	case <-scall.Ctx.Done():
+		// If the method timed out, the answer already has its error.
+		scall.reject(scall.Ctx.Err())
+		scall.stopTimer()
+		return &scall.ans
 	}
 }
 
@@ -135,6 +215,18 @@ func (s *server) Close() error {
 	return s.closer.Close()
 }
 
+// Implements reports whether c is a client returned by New that has an
+// implementation for the method m.  If c was not returned by New, then
+// known is false: the methods of c can't be determined without making
+// a call.
+func Implements(c capnp.Client, m *capnp.Method) (implemented, known bool) {
+	s, ok := c.(*server)
+	if !ok {
+		return false, false
+	}
+	return s.methods.find(m) != nil, true
+}
+
 // Ack acknowledges delivery of a server call, allowing other methods
 // to be called on the server.  It is intended to be used inside the
 // implementation of a server function.  Calling Ack on options that
@@ -159,16 +251,142 @@ func Ack(opts capnp.CallOptions) {
 	}
 }
 
+// TailCall delegates a server call to a call on another capability:
+// the answer of call becomes the answer of the server call, instead of
+// the results that the implementation function fills in.  It is
+// intended to be used inside the implementation of a server function,
+// which should return TailCall's error without setting any results.
+// opts are the options that the implementation function was given.
+//
+// Example:
+//
+//	func (p *proxy) MyMethod(call schema.MyServer_myMethod) error {
+//		return server.TailCall(call.Options, p.backend.Client, &capnp.Call{
+//			Ctx:    call.Ctx,
+//			Method: capnp.Method{InterfaceID: schema.MyServer_TypeID, MethodID: 0},
+//			Params: call.Params.Struct,
+//		})
+//	}
+//
+// TailCall acknowledges the server call (see Ack) and does not wait for
+// call to return.  If the server call was delivered by an RPC
+// connection and client is a capability from the same connection, then
+// the results can be sent directly to the original caller.  Generated
+// code has a TailCall method on each server call type that delegates
+// to the same method on another client.
+func TailCall(opts capnp.CallOptions, client capnp.Client, call *capnp.Call) error {
+	tail, _ := opts.Value(tailSlotKey).(*tailSlot)
+	if tail == nil {
+		return errNotServerCall
+	}
+	if tail.answer() != nil {
+		return errDoubleTailCall
+	}
+	c := *call
+	c.Options = call.Options.With([]capnp.CallOption{capnp.TailCallOf(opts)})
+	if err := tail.set(client.Call(&c)); err != nil {
+		return err
+	}
+	Ack(opts)
+	return nil
+}
+
+// A tailSlot holds the answer of a server call's tail call.
+type tailSlot struct {
+	mu  sync.Mutex
+	ans capnp.Answer
+}
+
+func (t *tailSlot) set(ans capnp.Answer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ans != nil {
+		return errDoubleTailCall
+	}
+	t.ans = ans
+	return nil
+}
+
+func (t *tailSlot) answer() capnp.Answer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ans
+}
+
 type call struct {
 	*capnp.Call
 	ans    fulfiller.Fulfiller
 	method *Method
+
+	timer  clock.Timer // nil if there is no call timeout
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	resolved bool // whether ans has been fulfilled or rejected
 }
 
 func newCall(cl *capnp.Call, sm *Method) *call {
 	return &call{Call: cl, method: sm}
 }
 
+// startTimer replaces the call's context with one that is canceled
+// once d has elapsed on clk.  If reject is true, the call's answer is
+// also rejected with capnp.ErrTimeout at that time.
+func (cl *call) startTimer(clk clock.Clock, d time.Duration, reject bool) {
+	c := *cl.Call
+	c.Ctx, cl.cancel = context.WithCancel(c.Ctx)
+	cl.Call = &c
+	if !reject {
+		cl.timer = clk.AfterFunc(d, cl.cancel)
+		return
+	}
+	cl.timer = clk.AfterFunc(d, func() {
+		// Reject before canceling, so that the implementation function
+		// returning the context's error doesn't win.
+		cl.reject(&capnp.MethodError{Method: &cl.Method, Err: capnp.ErrTimeout})
+		cl.cancel()
+	})
+}
+
+// stopTimer stops the call's timeout, if any, once the call is done.
+func (cl *call) stopTimer() {
+	if cl.timer == nil {
+		return
+	}
+	cl.timer.Stop()
+	cl.cancel()
+}
+
+// fulfill resolves the call's answer with s, unless the answer has
+// already been resolved.  It reports whether it resolved the answer.
+func (cl *call) fulfill(s capnp.Struct) bool {
+	if !cl.resolve() {
+		return false
+	}
+	cl.ans.Fulfill(s)
+	return true
+}
+
+// reject rejects the call's answer with err, unless the answer has
+// already been resolved.
+func (cl *call) reject(err error) {
+	if cl.resolve() {
+		cl.ans.Reject(err)
+	}
+}
+
+// resolve marks the call's answer as resolved, reporting whether the
+// caller is the first to do so.
+func (cl *call) resolve() bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.resolved {
+		return false
+	}
+	cl.resolved = true
+	return true
+}
+
 type sortedMethods []Method
 
 // find returns the method with the given ID or nil.
@@ -226,6 +444,11 @@ type callOptionKey int
 // Predefined call options
 const (
 	ackSignalKey callOptionKey = iota + 1
+	tailSlotKey
 )
 
-var errClosed = errors.New("capnp: server closed")
+var (
+	errClosed         = errors.New("capnp: server closed")
+	errNotServerCall  = errors.New("capnp: tail call outside of a server method")
+	errDoubleTailCall = errors.New("capnp: server call already has a tail call")
+)
@@ -3,8 +3,11 @@ package server_test
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/clock"
 	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
 	. "zombiezen.com/go/capnproto2/server"
 )
@@ -43,6 +46,59 @@ func TestServerCall(t *testing.T) {
 	}
 }
 
+func TestServerUnimplemented(t *testing.T) {
+	echo := air.Echo_ServerToClient(struct{ air.UnimplementedEcho_Server }{})
+	defer echo.Client.Close()
+
+	_, err := echo.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn("foo")
+	}).Struct()
+	if !capnp.IsUnimplemented(err) {
+		t.Errorf("echo.Echo() error = %v; want unimplemented", err)
+	}
+}
+
+// tailEcho is an Echo server that delegates to next, appending "!" to
+// the input.
+type tailEcho struct {
+	next air.Echo
+}
+
+func (e tailEcho) Echo(call air.Echo_echo) error {
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	return call.TailCall(e.next, func(p air.Echo_echo_Params) error {
+		return p.SetIn(in + "!")
+	})
+}
+
+func TestTailCall(t *testing.T) {
+	echo := air.Echo_ServerToClient(tailEcho{air.Echo_ServerToClient(echoImpl{})})
+	defer echo.Client.Close()
+
+	result, err := echo.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn("foo")
+	}).Struct()
+	if err != nil {
+		t.Fatal("echo.Echo() error:", err)
+	}
+	if out, err := result.Out(); err != nil {
+		t.Errorf("echo.Echo() error: %v", err)
+	} else if out != "foo!foo!" {
+		t.Errorf("echo.Echo() = %q; want %q", out, "foo!foo!")
+	}
+}
+
+func TestTailCall_OutsideServer(t *testing.T) {
+	echo := air.Echo_ServerToClient(echoImpl{})
+	defer echo.Client.Close()
+	if err := (air.Echo_echo{Ctx: context.Background()}).TailCall(echo, nil); err == nil {
+		t.Error("TailCall outside of a server method succeeded; want error")
+	}
+}
+
 type callSeq uint32
 
 func (seq *callSeq) GetNumber(call air.CallSequence_getNumber) error {
@@ -108,3 +164,119 @@ func testCallOrder(t *testing.T, seq air.CallSequence) {
 	check(call3, 3)
 	check(call4, 4)
 }
+
+func TestCallTimeout(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	started := make(chan struct{}, 2)
+	echo := air.Echo{Client: New(air.Echo_Methods(nil, stuckEcho(started)), nil, Clock(clk), CallTimeout(30*time.Second))}
+	defer echo.Client.Close()
+	ctx := context.Background()
+
+	// The first call blocks the server, so the second waits to be
+	// queued.
+	ans1 := echo.Echo(ctx, nil)
+	<-started
+	done2 := make(chan error, 1)
+	go func() {
+		_, err := echo.Echo(ctx, nil).Struct()
+		done2 <- err
+	}()
+	waitPending(t, clk, 2)
+
+	clk.Advance(29 * time.Second)
+	select {
+	case <-started:
+		t.Fatal("second call delivered while first call is running")
+	default:
+	}
+	clk.Advance(time.Second)
+	if _, err := ans1.Struct(); err != context.Canceled {
+		t.Errorf("running call error = %v; want %v", err, context.Canceled)
+	}
+	if err := <-done2; err != context.Canceled {
+		t.Errorf("queued call error = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestCallTimeout_StopsOnReturn(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	echo := air.Echo{Client: New(air.Echo_Methods(nil, echoImpl{}), nil, Clock(clk), CallTimeout(30*time.Second))}
+	defer echo.Client.Close()
+
+	if _, err := echo.Echo(context.Background(), nil).Struct(); err != nil {
+		t.Fatal("Echo:", err)
+	}
+	for i := 0; clk.Pending() > 0; i++ {
+		if i >= 1000 {
+			t.Fatalf("%d timers pending after call returned; want 0", clk.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMethodTimeout(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	defer close(release)
+	methods := air.Echo_Methods(nil, hungEcho{started, release})
+	methods[0].Timeout = 30 * time.Second
+	echo := air.Echo{Client: New(methods, nil, Clock(clk), CallTimeout(time.Hour))}
+	defer echo.Client.Close()
+	ctx := context.Background()
+
+	ans1 := echo.Echo(ctx, nil)
+	<-started
+	waitPending(t, clk, 1)
+	clk.Advance(30 * time.Second)
+	if _, err := ans1.Struct(); !capnp.IsTimeout(err) {
+		t.Errorf("hung call error = %v; want %v", err, capnp.ErrTimeout)
+	}
+
+	// The server does not wait for the hung implementation function.
+	ans2 := echo.Echo(ctx, nil)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second call not delivered after first call timed out")
+	}
+	waitPending(t, clk, 1)
+	clk.Advance(30 * time.Second)
+	if _, err := ans2.Struct(); !capnp.IsTimeout(err) {
+		t.Errorf("second call error = %v; want %v", err, capnp.ErrTimeout)
+	}
+}
+
+// waitPending waits until clk has n timers scheduled.
+func waitPending(t *testing.T, clk *clock.Fake, n int) {
+	t.Helper()
+	for i := 0; clk.Pending() < n; i++ {
+		if i >= 1000 {
+			t.Fatalf("timed out waiting for %d pending timers (have %d)", n, clk.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// stuckEcho is an Echo server whose calls block until their context is
+// canceled.  It sends on the channel as each call starts.
+type stuckEcho chan<- struct{}
+
+func (e stuckEcho) Echo(call air.Echo_echo) error {
+	e <- struct{}{}
+	<-call.Ctx.Done()
+	return call.Ctx.Err()
+}
+
+// hungEcho is an Echo server whose calls ignore their context and block
+// until release is closed.
+type hungEcho struct {
+	started chan<- struct{}
+	release <-chan struct{}
+}
+
+func (e hungEcho) Echo(call air.Echo_echo) error {
+	e.started <- struct{}{}
+	<-e.release
+	return nil
+}
@@ -104,8 +104,16 @@ func (s JsonValue) String_() (string, error) {
 	if s.Struct.Uint16(0) != 3 {
 		panic("Which() != string_")
 	}
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadString_ is like String_, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue) ReadString_() string {
+	if s.Struct.Uint16(0) != 3 {
+		panic("Which() != string_")
+	}
+	return s.Struct.ReadText(0)
 }
 
 func (s JsonValue) HasString_() bool {
@@ -117,8 +125,7 @@ func (s JsonValue) HasString_() bool {
 }
 
 func (s JsonValue) String_Bytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s JsonValue) SetString_(v string) error {
@@ -134,6 +141,15 @@ func (s JsonValue) Array() (JsonValue_List, error) {
 	return JsonValue_List{List: p.List()}, err
 }
 
+// ReadArray is like Array, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue) ReadArray() JsonValue_List {
+	if s.Struct.Uint16(0) != 4 {
+		panic("Which() != array")
+	}
+	return JsonValue_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s JsonValue) HasArray() bool {
 	if s.Struct.Uint16(0) != 4 {
 		return false
@@ -167,6 +183,15 @@ func (s JsonValue) Object() (JsonValue_Field_List, error) {
 	return JsonValue_Field_List{List: p.List()}, err
 }
 
+// ReadObject is like Object, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue) ReadObject() JsonValue_Field_List {
+	if s.Struct.Uint16(0) != 5 {
+		panic("Which() != object")
+	}
+	return JsonValue_Field_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s JsonValue) HasObject() bool {
 	if s.Struct.Uint16(0) != 5 {
 		return false
@@ -200,6 +225,15 @@ func (s JsonValue) Call() (JsonValue_Call, error) {
 	return JsonValue_Call{Struct: p.Struct()}, err
 }
 
+// ReadCall is like Call, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue) ReadCall() JsonValue_Call {
+	if s.Struct.Uint16(0) != 6 {
+		panic("Which() != call")
+	}
+	return JsonValue_Call{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s JsonValue) HasCall() bool {
 	if s.Struct.Uint16(0) != 6 {
 		return false
@@ -281,8 +315,13 @@ func (s JsonValue_Field) String() string {
 }
 
 func (s JsonValue_Field) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue_Field) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s JsonValue_Field) HasName() bool {
@@ -291,8 +330,7 @@ func (s JsonValue_Field) HasName() bool {
 }
 
 func (s JsonValue_Field) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s JsonValue_Field) SetName(v string) error {
@@ -304,6 +342,12 @@ func (s JsonValue_Field) Value() (JsonValue, error) {
 	return JsonValue{Struct: p.Struct()}, err
 }
 
+// ReadValue is like Value, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue_Field) ReadValue() JsonValue {
+	return JsonValue{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s JsonValue_Field) HasValue() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -382,8 +426,13 @@ func (s JsonValue_Call) String() string {
 }
 
 func (s JsonValue_Call) Function() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadFunction is like Function, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue_Call) ReadFunction() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s JsonValue_Call) HasFunction() bool {
@@ -392,8 +441,7 @@ func (s JsonValue_Call) HasFunction() bool {
 }
 
 func (s JsonValue_Call) FunctionBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s JsonValue_Call) SetFunction(v string) error {
@@ -405,6 +453,12 @@ func (s JsonValue_Call) Params() (JsonValue_List, error) {
 	return JsonValue_List{List: p.List()}, err
 }
 
+// ReadParams is like Params, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s JsonValue_Call) ReadParams() JsonValue_List {
+	return JsonValue_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s JsonValue_Call) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
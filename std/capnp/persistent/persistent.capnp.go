@@ -42,6 +42,24 @@ type Persistent_Server interface {
 	Save(Persistent_save) error
 }
 
+// UnimplementedPersistent_Server can be embedded in an implementation
+// of Persistent_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedPersistent_Server struct{}
+
+func (UnimplementedPersistent_Server) Save(Persistent_save) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0xc8cb212fcd9f5691,
+			MethodID:      0,
+			InterfaceName: "persistent.capnp:Persistent",
+			MethodName:    "save",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func Persistent_ServerToClient(s Persistent_Server) Persistent {
 	c, _ := s.(server.Closer)
 	return Persistent{Client: server.New(Persistent_Methods(nil, s), c)}
@@ -77,6 +95,32 @@ type Persistent_save struct {
 	Results Persistent_SaveResults
 }
 
+// TailCall delegates the call to save on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c Persistent_save) TailCall(t Persistent, params func(Persistent_SaveParams) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0xc8cb212fcd9f5691,
+			MethodID:      0,
+			InterfaceName: "persistent.capnp:Persistent",
+			MethodName:    "save",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(Persistent_SaveParams{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type Persistent_SaveParams struct{ capnp.Struct }
 
 // Persistent_SaveParams_TypeID is the unique identifier for the type Persistent_SaveParams.
@@ -289,6 +333,36 @@ type RealmGateway_Server interface {
 	Export(RealmGateway_export) error
 }
 
+// UnimplementedRealmGateway_Server can be embedded in an implementation
+// of RealmGateway_Server to supply the methods it doesn't define.  They
+// fail with capnp.ErrUnimplemented, so the server keeps compiling as
+// methods are added to the interface.
+type UnimplementedRealmGateway_Server struct{}
+
+func (UnimplementedRealmGateway_Server) Import(RealmGateway_import) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x84ff286cd00a3ed4,
+			MethodID:      0,
+			InterfaceName: "persistent.capnp:RealmGateway",
+			MethodName:    "import",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
+func (UnimplementedRealmGateway_Server) Export(RealmGateway_export) error {
+	return &capnp.MethodError{
+		Method: &capnp.Method{
+			InterfaceID:   0x84ff286cd00a3ed4,
+			MethodID:      1,
+			InterfaceName: "persistent.capnp:RealmGateway",
+			MethodName:    "export",
+		},
+		Err: capnp.ErrUnimplemented,
+	}
+}
+
 func RealmGateway_ServerToClient(s RealmGateway_Server) RealmGateway {
 	c, _ := s.(server.Closer)
 	return RealmGateway{Client: server.New(RealmGateway_Methods(nil, s), c)}
@@ -338,6 +412,32 @@ type RealmGateway_import struct {
 	Results Persistent_SaveResults
 }
 
+// TailCall delegates the call to import on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c RealmGateway_import) TailCall(t RealmGateway, params func(RealmGateway_import_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x84ff286cd00a3ed4,
+			MethodID:      0,
+			InterfaceName: "persistent.capnp:RealmGateway",
+			MethodName:    "import",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 2}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(RealmGateway_import_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 // RealmGateway_export holds the arguments for a server call to RealmGateway.export.
 type RealmGateway_export struct {
 	Ctx     context.Context
@@ -346,6 +446,32 @@ type RealmGateway_export struct {
 	Results Persistent_SaveResults
 }
 
+// TailCall delegates the call to export on t: the results of t's call
+// become the results of this call.  If params is nil, the call's own
+// parameters are passed on.  The server method should return TailCall's
+// error without setting any results.  See server.TailCall.
+func (c RealmGateway_export) TailCall(t RealmGateway, params func(RealmGateway_export_Params) error) error {
+	if t.Client == nil {
+		return capnp.ErrNullClient
+	}
+	call := &capnp.Call{
+		Ctx: c.Ctx,
+		Method: capnp.Method{
+			InterfaceID:   0x84ff286cd00a3ed4,
+			MethodID:      1,
+			InterfaceName: "persistent.capnp:RealmGateway",
+			MethodName:    "export",
+		},
+	}
+	if params != nil {
+		call.ParamsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 2}
+		call.ParamsFunc = func(s capnp.Struct) error { return params(RealmGateway_export_Params{Struct: s}) }
+	} else {
+		call.Params = c.Params.Struct
+	}
+	return server.TailCall(c.Options, t.Client, call)
+}
+
 type RealmGateway_import_Params struct{ capnp.Struct }
 
 // RealmGateway_import_Params_TypeID is the unique identifier for the type RealmGateway_import_Params.
@@ -395,6 +521,12 @@ func (s RealmGateway_import_Params) Params() (Persistent_SaveParams, error) {
 	return Persistent_SaveParams{Struct: p.Struct()}, err
 }
 
+// ReadParams is like Params, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s RealmGateway_import_Params) ReadParams() Persistent_SaveParams {
+	return Persistent_SaveParams{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s RealmGateway_import_Params) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -502,6 +634,12 @@ func (s RealmGateway_export_Params) Params() (Persistent_SaveParams, error) {
 	return Persistent_SaveParams{Struct: p.Struct()}, err
 }
 
+// ReadParams is like Params, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s RealmGateway_export_Params) ReadParams() Persistent_SaveParams {
+	return Persistent_SaveParams{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s RealmGateway_export_Params) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -99,6 +99,15 @@ func (s Message) Unimplemented() (Message, error) {
 	return Message{Struct: p.Struct()}, err
 }
 
+// ReadUnimplemented is like Unimplemented, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadUnimplemented() Message {
+	if s.Struct.Uint16(0) != 0 {
+		panic("Which() != unimplemented")
+	}
+	return Message{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasUnimplemented() bool {
 	if s.Struct.Uint16(0) != 0 {
 		return false
@@ -132,6 +141,15 @@ func (s Message) Abort() (Exception, error) {
 	return Exception{Struct: p.Struct()}, err
 }
 
+// ReadAbort is like Abort, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadAbort() Exception {
+	if s.Struct.Uint16(0) != 1 {
+		panic("Which() != abort")
+	}
+	return Exception{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasAbort() bool {
 	if s.Struct.Uint16(0) != 1 {
 		return false
@@ -165,6 +183,15 @@ func (s Message) Bootstrap() (Bootstrap, error) {
 	return Bootstrap{Struct: p.Struct()}, err
 }
 
+// ReadBootstrap is like Bootstrap, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadBootstrap() Bootstrap {
+	if s.Struct.Uint16(0) != 8 {
+		panic("Which() != bootstrap")
+	}
+	return Bootstrap{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasBootstrap() bool {
 	if s.Struct.Uint16(0) != 8 {
 		return false
@@ -198,6 +225,15 @@ func (s Message) Call() (Call, error) {
 	return Call{Struct: p.Struct()}, err
 }
 
+// ReadCall is like Call, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadCall() Call {
+	if s.Struct.Uint16(0) != 2 {
+		panic("Which() != call")
+	}
+	return Call{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasCall() bool {
 	if s.Struct.Uint16(0) != 2 {
 		return false
@@ -231,6 +267,15 @@ func (s Message) Return() (Return, error) {
 	return Return{Struct: p.Struct()}, err
 }
 
+// ReadReturn is like Return, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadReturn() Return {
+	if s.Struct.Uint16(0) != 3 {
+		panic("Which() != return")
+	}
+	return Return{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasReturn() bool {
 	if s.Struct.Uint16(0) != 3 {
 		return false
@@ -264,6 +309,15 @@ func (s Message) Finish() (Finish, error) {
 	return Finish{Struct: p.Struct()}, err
 }
 
+// ReadFinish is like Finish, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadFinish() Finish {
+	if s.Struct.Uint16(0) != 4 {
+		panic("Which() != finish")
+	}
+	return Finish{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasFinish() bool {
 	if s.Struct.Uint16(0) != 4 {
 		return false
@@ -297,6 +351,15 @@ func (s Message) Resolve() (Resolve, error) {
 	return Resolve{Struct: p.Struct()}, err
 }
 
+// ReadResolve is like Resolve, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadResolve() Resolve {
+	if s.Struct.Uint16(0) != 5 {
+		panic("Which() != resolve")
+	}
+	return Resolve{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasResolve() bool {
 	if s.Struct.Uint16(0) != 5 {
 		return false
@@ -330,6 +393,15 @@ func (s Message) Release() (Release, error) {
 	return Release{Struct: p.Struct()}, err
 }
 
+// ReadRelease is like Release, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadRelease() Release {
+	if s.Struct.Uint16(0) != 6 {
+		panic("Which() != release")
+	}
+	return Release{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasRelease() bool {
 	if s.Struct.Uint16(0) != 6 {
 		return false
@@ -363,6 +435,15 @@ func (s Message) Disembargo() (Disembargo, error) {
 	return Disembargo{Struct: p.Struct()}, err
 }
 
+// ReadDisembargo is like Disembargo, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadDisembargo() Disembargo {
+	if s.Struct.Uint16(0) != 13 {
+		panic("Which() != disembargo")
+	}
+	return Disembargo{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasDisembargo() bool {
 	if s.Struct.Uint16(0) != 13 {
 		return false
@@ -454,6 +535,15 @@ func (s Message) Provide() (Provide, error) {
 	return Provide{Struct: p.Struct()}, err
 }
 
+// ReadProvide is like Provide, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadProvide() Provide {
+	if s.Struct.Uint16(0) != 10 {
+		panic("Which() != provide")
+	}
+	return Provide{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasProvide() bool {
 	if s.Struct.Uint16(0) != 10 {
 		return false
@@ -487,6 +577,15 @@ func (s Message) Accept() (Accept, error) {
 	return Accept{Struct: p.Struct()}, err
 }
 
+// ReadAccept is like Accept, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadAccept() Accept {
+	if s.Struct.Uint16(0) != 11 {
+		panic("Which() != accept")
+	}
+	return Accept{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasAccept() bool {
 	if s.Struct.Uint16(0) != 11 {
 		return false
@@ -520,6 +619,15 @@ func (s Message) Join() (Join, error) {
 	return Join{Struct: p.Struct()}, err
 }
 
+// ReadJoin is like Join, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Message) ReadJoin() Join {
+	if s.Struct.Uint16(0) != 12 {
+		panic("Which() != join")
+	}
+	return Join{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Message) HasJoin() bool {
 	if s.Struct.Uint16(0) != 12 {
 		return false
@@ -771,6 +879,12 @@ func (s Call) Target() (MessageTarget, error) {
 	return MessageTarget{Struct: p.Struct()}, err
 }
 
+// ReadTarget is like Target, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Call) ReadTarget() MessageTarget {
+	return MessageTarget{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Call) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -820,6 +934,12 @@ func (s Call) Params() (Payload, error) {
 	return Payload{Struct: p.Struct()}, err
 }
 
+// ReadParams is like Params, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Call) ReadParams() Payload {
+	return Payload{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s Call) HasParams() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -1016,6 +1136,15 @@ func (s Return) Results() (Payload, error) {
 	return Payload{Struct: p.Struct()}, err
 }
 
+// ReadResults is like Results, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Return) ReadResults() Payload {
+	if s.Struct.Uint16(6) != 0 {
+		panic("Which() != results")
+	}
+	return Payload{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Return) HasResults() bool {
 	if s.Struct.Uint16(6) != 0 {
 		return false
@@ -1049,6 +1178,15 @@ func (s Return) Exception() (Exception, error) {
 	return Exception{Struct: p.Struct()}, err
 }
 
+// ReadException is like Exception, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Return) ReadException() Exception {
+	if s.Struct.Uint16(6) != 1 {
+		panic("Which() != exception")
+	}
+	return Exception{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Return) HasException() bool {
 	if s.Struct.Uint16(6) != 1 {
 		return false
@@ -1292,6 +1430,15 @@ func (s Resolve) Cap() (CapDescriptor, error) {
 	return CapDescriptor{Struct: p.Struct()}, err
 }
 
+// ReadCap is like Cap, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Resolve) ReadCap() CapDescriptor {
+	if s.Struct.Uint16(4) != 0 {
+		panic("Which() != cap")
+	}
+	return CapDescriptor{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Resolve) HasCap() bool {
 	if s.Struct.Uint16(4) != 0 {
 		return false
@@ -1325,6 +1472,15 @@ func (s Resolve) Exception() (Exception, error) {
 	return Exception{Struct: p.Struct()}, err
 }
 
+// ReadException is like Exception, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Resolve) ReadException() Exception {
+	if s.Struct.Uint16(4) != 1 {
+		panic("Which() != exception")
+	}
+	return Exception{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Resolve) HasException() bool {
 	if s.Struct.Uint16(4) != 1 {
 		return false
@@ -1506,6 +1662,12 @@ func (s Disembargo) Target() (MessageTarget, error) {
 	return MessageTarget{Struct: p.Struct()}, err
 }
 
+// ReadTarget is like Target, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Disembargo) ReadTarget() MessageTarget {
+	return MessageTarget{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Disembargo) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1652,6 +1814,12 @@ func (s Provide) Target() (MessageTarget, error) {
 	return MessageTarget{Struct: p.Struct()}, err
 }
 
+// ReadTarget is like Target, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Provide) ReadTarget() MessageTarget {
+	return MessageTarget{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Provide) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1857,6 +2025,12 @@ func (s Join) Target() (MessageTarget, error) {
 	return MessageTarget{Struct: p.Struct()}, err
 }
 
+// ReadTarget is like Target, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Join) ReadTarget() MessageTarget {
+	return MessageTarget{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Join) HasTarget() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1998,6 +2172,15 @@ func (s MessageTarget) PromisedAnswer() (PromisedAnswer, error) {
 	return PromisedAnswer{Struct: p.Struct()}, err
 }
 
+// ReadPromisedAnswer is like PromisedAnswer, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s MessageTarget) ReadPromisedAnswer() PromisedAnswer {
+	if s.Struct.Uint16(4) != 1 {
+		panic("Which() != promisedAnswer")
+	}
+	return PromisedAnswer{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s MessageTarget) HasPromisedAnswer() bool {
 	if s.Struct.Uint16(4) != 1 {
 		return false
@@ -2104,6 +2287,12 @@ func (s Payload) CapTable() (CapDescriptor_List, error) {
 	return CapDescriptor_List{List: p.List()}, err
 }
 
+// ReadCapTable is like CapTable, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Payload) ReadCapTable() CapDescriptor_List {
+	return CapDescriptor_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Payload) HasCapTable() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -2261,6 +2450,15 @@ func (s CapDescriptor) ReceiverAnswer() (PromisedAnswer, error) {
 	return PromisedAnswer{Struct: p.Struct()}, err
 }
 
+// ReadReceiverAnswer is like ReceiverAnswer, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CapDescriptor) ReadReceiverAnswer() PromisedAnswer {
+	if s.Struct.Uint16(0) != 4 {
+		panic("Which() != receiverAnswer")
+	}
+	return PromisedAnswer{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s CapDescriptor) HasReceiverAnswer() bool {
 	if s.Struct.Uint16(0) != 4 {
 		return false
@@ -2294,6 +2492,15 @@ func (s CapDescriptor) ThirdPartyHosted() (ThirdPartyCapDescriptor, error) {
 	return ThirdPartyCapDescriptor{Struct: p.Struct()}, err
 }
 
+// ReadThirdPartyHosted is like ThirdPartyHosted, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CapDescriptor) ReadThirdPartyHosted() ThirdPartyCapDescriptor {
+	if s.Struct.Uint16(0) != 5 {
+		panic("Which() != thirdPartyHosted")
+	}
+	return ThirdPartyCapDescriptor{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s CapDescriptor) HasThirdPartyHosted() bool {
 	if s.Struct.Uint16(0) != 5 {
 		return false
@@ -2391,6 +2598,12 @@ func (s PromisedAnswer) Transform() (PromisedAnswer_Op_List, error) {
 	return PromisedAnswer_Op_List{List: p.List()}, err
 }
 
+// ReadTransform is like Transform, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s PromisedAnswer) ReadTransform() PromisedAnswer_Op_List {
+	return PromisedAnswer_Op_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s PromisedAnswer) HasTransform() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -2644,8 +2857,13 @@ func (s Exception) String() string {
 }
 
 func (s Exception) Reason() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadReason is like Reason, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Exception) ReadReason() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Exception) HasReason() bool {
@@ -2654,8 +2872,7 @@ func (s Exception) HasReason() bool {
 }
 
 func (s Exception) ReasonBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Exception) SetReason(v string) error {
@@ -2777,6 +2994,33 @@ func (l Exception_Type_List) Set(i int, v Exception_Type) {
 	ul.Set(i, uint16(v))
 }
 
+// ToSlice returns a copy of the list's elements.  See capnp.UInt16List.ToSlice.
+func (l Exception_Type_List) ToSlice() ([]Exception_Type, error) {
+	u, err := capnp.UInt16List{List: l.List}.ToSlice()
+	if err != nil || u == nil {
+		return nil, err
+	}
+	s := make([]Exception_Type, len(u))
+	for i := range u {
+		s[i] = Exception_Type(u[i])
+	}
+	return s, nil
+}
+
+// SetSlice sets the list's elements to v, which must be the same length as the list.
+func (l Exception_Type_List) SetSlice(v []Exception_Type) error {
+	u := make([]uint16, len(v))
+	for i := range v {
+		u[i] = uint16(v[i])
+	}
+	return capnp.UInt16List{List: l.List}.SetSlice(u)
+}
+
+// Validate returns an error if any element of the list is not a known Exception_Type value.
+func (l Exception_Type_List) Validate() error {
+	return capnp.UInt16List{List: l.List}.VerifyEnum("Exception_Type", 4)
+}
+
 const schema_b312981b2552a250 = "x\xda\x9cX\x7f\x8c\x15\xd5\x15>\xe7\xde\xb7\xef-\xec" +
 	"\x8f\xf7f\xefC\x0b\x95@mM\x0a)D\xacm\xed" +
 	"\xb6\xe6!\xec\x12\xd6,a\xef\xbe\xa5*5ig\xdf" +
@@ -63,6 +63,33 @@ func (l Side_List) Set(i int, v Side) {
 	ul.Set(i, uint16(v))
 }
 
+// ToSlice returns a copy of the list's elements.  See capnp.UInt16List.ToSlice.
+func (l Side_List) ToSlice() ([]Side, error) {
+	u, err := capnp.UInt16List{List: l.List}.ToSlice()
+	if err != nil || u == nil {
+		return nil, err
+	}
+	s := make([]Side, len(u))
+	for i := range u {
+		s[i] = Side(u[i])
+	}
+	return s, nil
+}
+
+// SetSlice sets the list's elements to v, which must be the same length as the list.
+func (l Side_List) SetSlice(v []Side) error {
+	u := make([]uint16, len(v))
+	for i := range v {
+		u[i] = uint16(v[i])
+	}
+	return capnp.UInt16List{List: l.List}.SetSlice(u)
+}
+
+// Validate returns an error if any element of the list is not a known Side value.
+func (l Side_List) Validate() error {
+	return capnp.UInt16List{List: l.List}.VerifyEnum("Side", 2)
+}
+
 type VatId struct{ capnp.Struct }
 
 // VatId_TypeID is the unique identifier for the type VatId.
@@ -87,8 +87,13 @@ func (s Node) SetId(v uint64) {
 }
 
 func (s Node) DisplayName() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadDisplayName is like DisplayName, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node) ReadDisplayName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Node) HasDisplayName() bool {
@@ -97,8 +102,7 @@ func (s Node) HasDisplayName() bool {
 }
 
 func (s Node) DisplayNameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Node) SetDisplayName(v string) error {
@@ -126,6 +130,12 @@ func (s Node) Parameters() (Node_Parameter_List, error) {
 	return Node_Parameter_List{List: p.List()}, err
 }
 
+// ReadParameters is like Parameters, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node) ReadParameters() Node_Parameter_List {
+	return Node_Parameter_List{List: s.Struct.ReadPtr(5).List()}
+}
+
 func (s Node) HasParameters() bool {
 	p, err := s.Struct.Ptr(5)
 	return p.IsValid() || err != nil
@@ -159,6 +169,12 @@ func (s Node) NestedNodes() (Node_NestedNode_List, error) {
 	return Node_NestedNode_List{List: p.List()}, err
 }
 
+// ReadNestedNodes is like NestedNodes, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node) ReadNestedNodes() Node_NestedNode_List {
+	return Node_NestedNode_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Node) HasNestedNodes() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -184,6 +200,12 @@ func (s Node) Annotations() (Annotation_List, error) {
 	return Annotation_List{List: p.List()}, err
 }
 
+// ReadAnnotations is like Annotations, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node) ReadAnnotations() Annotation_List {
+	return Annotation_List{List: s.Struct.ReadPtr(2).List()}
+}
+
 func (s Node) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -268,6 +290,12 @@ func (s Node_structNode) Fields() (Field_List, error) {
 	return Field_List{List: p.List()}, err
 }
 
+// ReadFields is like Fields, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_structNode) ReadFields() Field_List {
+	return Field_List{List: s.Struct.ReadPtr(3).List()}
+}
+
 func (s Node_structNode) HasFields() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -299,6 +327,12 @@ func (s Node_enum) Enumerants() (Enumerant_List, error) {
 	return Enumerant_List{List: p.List()}, err
 }
 
+// ReadEnumerants is like Enumerants, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_enum) ReadEnumerants() Enumerant_List {
+	return Enumerant_List{List: s.Struct.ReadPtr(3).List()}
+}
+
 func (s Node_enum) HasEnumerants() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -330,6 +364,12 @@ func (s Node_interface) Methods() (Method_List, error) {
 	return Method_List{List: p.List()}, err
 }
 
+// ReadMethods is like Methods, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_interface) ReadMethods() Method_List {
+	return Method_List{List: s.Struct.ReadPtr(3).List()}
+}
+
 func (s Node_interface) HasMethods() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -355,6 +395,12 @@ func (s Node_interface) Superclasses() (Superclass_List, error) {
 	return Superclass_List{List: p.List()}, err
 }
 
+// ReadSuperclasses is like Superclasses, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_interface) ReadSuperclasses() Superclass_List {
+	return Superclass_List{List: s.Struct.ReadPtr(4).List()}
+}
+
 func (s Node_interface) HasSuperclasses() bool {
 	p, err := s.Struct.Ptr(4)
 	return p.IsValid() || err != nil
@@ -386,6 +432,12 @@ func (s Node_const) Type() (Type, error) {
 	return Type{Struct: p.Struct()}, err
 }
 
+// ReadType is like Type, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_const) ReadType() Type {
+	return Type{Struct: s.Struct.ReadPtr(3).Struct()}
+}
+
 func (s Node_const) HasType() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -411,6 +463,12 @@ func (s Node_const) Value() (Value, error) {
 	return Value{Struct: p.Struct()}, err
 }
 
+// ReadValue is like Value, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_const) ReadValue() Value {
+	return Value{Struct: s.Struct.ReadPtr(4).Struct()}
+}
+
 func (s Node_const) HasValue() bool {
 	p, err := s.Struct.Ptr(4)
 	return p.IsValid() || err != nil
@@ -442,6 +500,12 @@ func (s Node_annotation) Type() (Type, error) {
 	return Type{Struct: p.Struct()}, err
 }
 
+// ReadType is like Type, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_annotation) ReadType() Type {
+	return Type{Struct: s.Struct.ReadPtr(3).Struct()}
+}
+
 func (s Node_annotation) HasType() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -672,8 +736,13 @@ func (s Node_Parameter) String() string {
 }
 
 func (s Node_Parameter) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_Parameter) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Node_Parameter) HasName() bool {
@@ -682,8 +751,7 @@ func (s Node_Parameter) HasName() bool {
 }
 
 func (s Node_Parameter) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Node_Parameter) SetName(v string) error {
@@ -742,8 +810,13 @@ func (s Node_NestedNode) String() string {
 }
 
 func (s Node_NestedNode) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Node_NestedNode) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Node_NestedNode) HasName() bool {
@@ -752,8 +825,7 @@ func (s Node_NestedNode) HasName() bool {
 }
 
 func (s Node_NestedNode) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Node_NestedNode) SetName(v string) error {
@@ -865,8 +937,13 @@ func (s Field) Which() Field_Which {
 	return Field_Which(s.Struct.Uint16(8))
 }
 func (s Field) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Field) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Field) HasName() bool {
@@ -875,8 +952,7 @@ func (s Field) HasName() bool {
 }
 
 func (s Field) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Field) SetName(v string) error {
@@ -896,6 +972,12 @@ func (s Field) Annotations() (Annotation_List, error) {
 	return Annotation_List{List: p.List()}, err
 }
 
+// ReadAnnotations is like Annotations, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Field) ReadAnnotations() Annotation_List {
+	return Annotation_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Field) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -943,6 +1025,12 @@ func (s Field_slot) Type() (Type, error) {
 	return Type{Struct: p.Struct()}, err
 }
 
+// ReadType is like Type, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Field_slot) ReadType() Type {
+	return Type{Struct: s.Struct.ReadPtr(2).Struct()}
+}
+
 func (s Field_slot) HasType() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -968,6 +1056,12 @@ func (s Field_slot) DefaultValue() (Value, error) {
 	return Value{Struct: p.Struct()}, err
 }
 
+// ReadDefaultValue is like DefaultValue, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Field_slot) ReadDefaultValue() Value {
+	return Value{Struct: s.Struct.ReadPtr(3).Struct()}
+}
+
 func (s Field_slot) HasDefaultValue() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -1122,8 +1216,13 @@ func (s Enumerant) String() string {
 }
 
 func (s Enumerant) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Enumerant) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Enumerant) HasName() bool {
@@ -1132,8 +1231,7 @@ func (s Enumerant) HasName() bool {
 }
 
 func (s Enumerant) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Enumerant) SetName(v string) error {
@@ -1153,6 +1251,12 @@ func (s Enumerant) Annotations() (Annotation_List, error) {
 	return Annotation_List{List: p.List()}, err
 }
 
+// ReadAnnotations is like Annotations, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Enumerant) ReadAnnotations() Annotation_List {
+	return Annotation_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Enumerant) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -1237,6 +1341,12 @@ func (s Superclass) Brand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadBrand is like Brand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Superclass) ReadBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Superclass) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1313,8 +1423,13 @@ func (s Method) String() string {
 }
 
 func (s Method) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Method) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s Method) HasName() bool {
@@ -1323,8 +1438,7 @@ func (s Method) HasName() bool {
 }
 
 func (s Method) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Method) SetName(v string) error {
@@ -1344,6 +1458,12 @@ func (s Method) ImplicitParameters() (Node_Parameter_List, error) {
 	return Node_Parameter_List{List: p.List()}, err
 }
 
+// ReadImplicitParameters is like ImplicitParameters, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Method) ReadImplicitParameters() Node_Parameter_List {
+	return Node_Parameter_List{List: s.Struct.ReadPtr(4).List()}
+}
+
 func (s Method) HasImplicitParameters() bool {
 	p, err := s.Struct.Ptr(4)
 	return p.IsValid() || err != nil
@@ -1377,6 +1497,12 @@ func (s Method) ParamBrand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadParamBrand is like ParamBrand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Method) ReadParamBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(2).Struct()}
+}
+
 func (s Method) HasParamBrand() bool {
 	p, err := s.Struct.Ptr(2)
 	return p.IsValid() || err != nil
@@ -1410,6 +1536,12 @@ func (s Method) ResultBrand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadResultBrand is like ResultBrand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Method) ReadResultBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(3).Struct()}
+}
+
 func (s Method) HasResultBrand() bool {
 	p, err := s.Struct.Ptr(3)
 	return p.IsValid() || err != nil
@@ -1435,6 +1567,12 @@ func (s Method) Annotations() (Annotation_List, error) {
 	return Annotation_List{List: p.List()}, err
 }
 
+// ReadAnnotations is like Annotations, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Method) ReadAnnotations() Annotation_List {
+	return Annotation_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s Method) HasAnnotations() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -1722,6 +1860,12 @@ func (s Type_list) ElementType() (Type, error) {
 	return Type{Struct: p.Struct()}, err
 }
 
+// ReadElementType is like ElementType, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Type_list) ReadElementType() Type {
+	return Type{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Type_list) HasElementType() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1761,6 +1905,12 @@ func (s Type_enum) Brand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadBrand is like Brand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Type_enum) ReadBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Type_enum) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1800,6 +1950,12 @@ func (s Type_structType) Brand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadBrand is like Brand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Type_structType) ReadBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Type_structType) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -1839,6 +1995,12 @@ func (s Type_interface) Brand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadBrand is like Brand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Type_interface) ReadBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Type_interface) HasBrand() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -2095,6 +2257,12 @@ func (s Brand) Scopes() (Brand_Scope_List, error) {
 	return Brand_Scope_List{List: p.List()}, err
 }
 
+// ReadScopes is like Scopes, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Brand) ReadScopes() Brand_Scope_List {
+	return Brand_Scope_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Brand) HasScopes() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -2203,6 +2371,15 @@ func (s Brand_Scope) Bind() (Brand_Binding_List, error) {
 	return Brand_Binding_List{List: p.List()}, err
 }
 
+// ReadBind is like Bind, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Brand_Scope) ReadBind() Brand_Binding_List {
+	if s.Struct.Uint16(8) != 0 {
+		panic("Which() != bind")
+	}
+	return Brand_Binding_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s Brand_Scope) HasBind() bool {
 	if s.Struct.Uint16(8) != 0 {
 		return false
@@ -2318,6 +2495,15 @@ func (s Brand_Binding) Type() (Type, error) {
 	return Type{Struct: p.Struct()}, err
 }
 
+// ReadType is like Type, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Brand_Binding) ReadType() Type {
+	if s.Struct.Uint16(0) != 1 {
+		panic("Which() != type")
+	}
+	return Type{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Brand_Binding) HasType() bool {
 	if s.Struct.Uint16(0) != 1 {
 		return false
@@ -2611,8 +2797,16 @@ func (s Value) Text() (string, error) {
 	if s.Struct.Uint16(0) != 12 {
 		panic("Which() != text")
 	}
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadText is like Text, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Value) ReadText() string {
+	if s.Struct.Uint16(0) != 12 {
+		panic("Which() != text")
+	}
+	return s.Struct.ReadText(0)
 }
 
 func (s Value) HasText() bool {
@@ -2624,8 +2818,7 @@ func (s Value) HasText() bool {
 }
 
 func (s Value) TextBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s Value) SetText(v string) error {
@@ -2641,6 +2834,15 @@ func (s Value) Data() ([]byte, error) {
 	return []byte(p.Data()), err
 }
 
+// ReadData is like Data, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Value) ReadData() []byte {
+	if s.Struct.Uint16(0) != 13 {
+		panic("Which() != data")
+	}
+	return []byte(s.Struct.ReadData(0))
+}
+
 func (s Value) HasData() bool {
 	if s.Struct.Uint16(0) != 13 {
 		return false
@@ -2834,6 +3036,12 @@ func (s Annotation) Brand() (Brand, error) {
 	return Brand{Struct: p.Struct()}, err
 }
 
+// ReadBrand is like Brand, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Annotation) ReadBrand() Brand {
+	return Brand{Struct: s.Struct.ReadPtr(1).Struct()}
+}
+
 func (s Annotation) HasBrand() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -2859,6 +3067,12 @@ func (s Annotation) Value() (Value, error) {
 	return Value{Struct: p.Struct()}, err
 }
 
+// ReadValue is like Value, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s Annotation) ReadValue() Value {
+	return Value{Struct: s.Struct.ReadPtr(0).Struct()}
+}
+
 func (s Annotation) HasValue() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -2998,6 +3212,33 @@ func (l ElementSize_List) Set(i int, v ElementSize) {
 	ul.Set(i, uint16(v))
 }
 
+// ToSlice returns a copy of the list's elements.  See capnp.UInt16List.ToSlice.
+func (l ElementSize_List) ToSlice() ([]ElementSize, error) {
+	u, err := capnp.UInt16List{List: l.List}.ToSlice()
+	if err != nil || u == nil {
+		return nil, err
+	}
+	s := make([]ElementSize, len(u))
+	for i := range u {
+		s[i] = ElementSize(u[i])
+	}
+	return s, nil
+}
+
+// SetSlice sets the list's elements to v, which must be the same length as the list.
+func (l ElementSize_List) SetSlice(v []ElementSize) error {
+	u := make([]uint16, len(v))
+	for i := range v {
+		u[i] = uint16(v[i])
+	}
+	return capnp.UInt16List{List: l.List}.SetSlice(u)
+}
+
+// Validate returns an error if any element of the list is not a known ElementSize value.
+func (l ElementSize_List) Validate() error {
+	return capnp.UInt16List{List: l.List}.VerifyEnum("ElementSize", 8)
+}
+
 type CodeGeneratorRequest struct{ capnp.Struct }
 
 // CodeGeneratorRequest_TypeID is the unique identifier for the type CodeGeneratorRequest.
@@ -3028,6 +3269,12 @@ func (s CodeGeneratorRequest) Nodes() (Node_List, error) {
 	return Node_List{List: p.List()}, err
 }
 
+// ReadNodes is like Nodes, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CodeGeneratorRequest) ReadNodes() Node_List {
+	return Node_List{List: s.Struct.ReadPtr(0).List()}
+}
+
 func (s CodeGeneratorRequest) HasNodes() bool {
 	p, err := s.Struct.Ptr(0)
 	return p.IsValid() || err != nil
@@ -3053,6 +3300,12 @@ func (s CodeGeneratorRequest) RequestedFiles() (CodeGeneratorRequest_RequestedFi
 	return CodeGeneratorRequest_RequestedFile_List{List: p.List()}, err
 }
 
+// ReadRequestedFiles is like RequestedFiles, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CodeGeneratorRequest) ReadRequestedFiles() CodeGeneratorRequest_RequestedFile_List {
+	return CodeGeneratorRequest_RequestedFile_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s CodeGeneratorRequest) HasRequestedFiles() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -3137,8 +3390,13 @@ func (s CodeGeneratorRequest_RequestedFile) SetId(v uint64) {
 }
 
 func (s CodeGeneratorRequest_RequestedFile) Filename() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadFilename is like Filename, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CodeGeneratorRequest_RequestedFile) ReadFilename() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s CodeGeneratorRequest_RequestedFile) HasFilename() bool {
@@ -3147,8 +3405,7 @@ func (s CodeGeneratorRequest_RequestedFile) HasFilename() bool {
 }
 
 func (s CodeGeneratorRequest_RequestedFile) FilenameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s CodeGeneratorRequest_RequestedFile) SetFilename(v string) error {
@@ -3160,6 +3417,12 @@ func (s CodeGeneratorRequest_RequestedFile) Imports() (CodeGeneratorRequest_Requ
 	return CodeGeneratorRequest_RequestedFile_Import_List{List: p.List()}, err
 }
 
+// ReadImports is like Imports, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CodeGeneratorRequest_RequestedFile) ReadImports() CodeGeneratorRequest_RequestedFile_Import_List {
+	return CodeGeneratorRequest_RequestedFile_Import_List{List: s.Struct.ReadPtr(1).List()}
+}
+
 func (s CodeGeneratorRequest_RequestedFile) HasImports() bool {
 	p, err := s.Struct.Ptr(1)
 	return p.IsValid() || err != nil
@@ -3244,8 +3507,13 @@ func (s CodeGeneratorRequest_RequestedFile_Import) SetId(v uint64) {
 }
 
 func (s CodeGeneratorRequest_RequestedFile_Import) Name() (string, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.Text(), err
+	return s.Struct.Text(0)
+}
+
+// ReadName is like Name, but it records an error
+// on the message, to be reported by Message.Err, instead of returning it.
+func (s CodeGeneratorRequest_RequestedFile_Import) ReadName() string {
+	return s.Struct.ReadText(0)
 }
 
 func (s CodeGeneratorRequest_RequestedFile_Import) HasName() bool {
@@ -3254,8 +3522,7 @@ func (s CodeGeneratorRequest_RequestedFile_Import) HasName() bool {
 }
 
 func (s CodeGeneratorRequest_RequestedFile_Import) NameBytes() ([]byte, error) {
-	p, err := s.Struct.Ptr(0)
-	return p.TextBytes(), err
+	return s.Struct.TextBytes(0)
 }
 
 func (s CodeGeneratorRequest_RequestedFile_Import) SetName(v string) error {
@@ -0,0 +1,138 @@
+package wellknown
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// MaxDecimalScale is the largest scale that a Decimal can have: the
+// number of decimal digits that always fit in an Int64.
+const MaxDecimalScale = 18
+
+// Validate returns an error if d's scale is greater than
+// MaxDecimalScale.
+func (d Decimal) Validate() error {
+	if d.Scale() > MaxDecimalScale {
+		return errDecimalScale
+	}
+	return nil
+}
+
+// Text returns d in decimal notation, like "-12.50", with exactly
+// d.Scale() digits after the decimal point.
+func (d Decimal) Text() (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+	return FormatDecimal(d.Unscaled(), d.Scale()), nil
+}
+
+// SetText sets d to the number in s, which is in decimal notation.
+// The scale is the number of digits after the decimal point in s, so
+// trailing zeros are kept.  d is not changed if s is not valid.
+func (d Decimal) SetText(s string) error {
+	unscaled, scale, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	d.SetUnscaled(unscaled)
+	d.SetScale(scale)
+	return nil
+}
+
+// NewDecimalFrom allocates a Decimal that holds unscaled * 10^-scale in
+// s.  It returns an error if scale is greater than MaxDecimalScale.
+func NewDecimalFrom(s *capnp.Segment, unscaled int64, scale uint8) (Decimal, error) {
+	if scale > MaxDecimalScale {
+		return Decimal{}, errDecimalScale
+	}
+	d, err := NewDecimal(s)
+	if err != nil {
+		return Decimal{}, err
+	}
+	d.SetUnscaled(unscaled)
+	d.SetScale(scale)
+	return d, nil
+}
+
+// FormatDecimal returns unscaled * 10^-scale in decimal notation, with
+// exactly scale digits after the decimal point.
+func FormatDecimal(unscaled int64, scale uint8) string {
+	var buf []byte
+	mag := uint64(unscaled)
+	if unscaled < 0 {
+		buf = append(buf, '-')
+		mag = -mag
+	}
+	digits := strconv.FormatUint(mag, 10)
+	if scale == 0 {
+		return string(append(buf, digits...))
+	}
+	if n := int(scale) + 1 - len(digits); n > 0 {
+		for i := 0; i < n; i++ {
+			buf = append(buf, '0')
+		}
+	}
+	buf = append(buf, digits...)
+	point := len(buf) - int(scale)
+	buf = append(buf, 0)
+	copy(buf[point+1:], buf[point:])
+	buf[point] = '.'
+	return string(buf)
+}
+
+// ParseDecimal parses a number in decimal notation: an optional sign,
+// then digits with an optional decimal point.  Exponents are not
+// allowed.  The scale is the number of digits after the decimal point.
+func ParseDecimal(s string) (unscaled int64, scale uint8, err error) {
+	i := 0
+	neg := false
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		neg = s[i] == '-'
+		i++
+	}
+	var mag uint64
+	ndigits, point := 0, -1
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '.' && point == -1 {
+			point = ndigits
+			continue
+		}
+		if c < '0' || c > '9' {
+			return 0, 0, fmt.Errorf("wellknown: parse decimal %q: invalid character %q", s, c)
+		}
+		if mag > (math.MaxUint64-9)/10 {
+			return 0, 0, fmt.Errorf("wellknown: parse decimal %q: out of range", s)
+		}
+		mag = mag*10 + uint64(c-'0')
+		ndigits++
+	}
+	if ndigits == 0 {
+		return 0, 0, fmt.Errorf("wellknown: parse decimal %q: no digits", s)
+	}
+	if point == 0 || point == ndigits {
+		return 0, 0, fmt.Errorf("wellknown: parse decimal %q: need digits on both sides of the decimal point", s)
+	}
+	if point > 0 {
+		if ndigits-point > MaxDecimalScale {
+			return 0, 0, errDecimalScale
+		}
+		scale = uint8(ndigits - point)
+	}
+	switch {
+	case !neg && mag > math.MaxInt64, neg && mag > 1<<63:
+		return 0, 0, fmt.Errorf("wellknown: parse decimal %q: out of range", s)
+	case neg:
+		unscaled = int64(-mag)
+	default:
+		unscaled = int64(mag)
+	}
+	return unscaled, scale, nil
+}
+
+var errDecimalScale = errors.New("wellknown: decimal has more than 18 digits after the decimal point")
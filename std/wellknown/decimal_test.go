@@ -0,0 +1,88 @@
+package wellknown
+
+import (
+	"math"
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s        string
+		unscaled int64
+		scale    uint8
+		text     string
+	}{
+		{"0", 0, 0, "0"},
+		{"-0", 0, 0, "0"},
+		{"+42", 42, 0, "42"},
+		{"12.50", 1250, 2, "12.50"},
+		{"-0.05", -5, 2, "-0.05"},
+		{"0.000000000000000001", 1, 18, "0.000000000000000001"},
+		{"9223372036854775807", math.MaxInt64, 0, "9223372036854775807"},
+		{"-9.223372036854775808", math.MinInt64, 18, "-9.223372036854775808"},
+	}
+	for _, test := range tests {
+		unscaled, scale, err := ParseDecimal(test.s)
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): %v", test.s, err)
+			continue
+		}
+		if unscaled != test.unscaled || scale != test.scale {
+			t.Errorf("ParseDecimal(%q) = %d, %d; want %d, %d", test.s, unscaled, scale, test.unscaled, test.scale)
+		}
+		if text := FormatDecimal(unscaled, scale); text != test.text {
+			t.Errorf("FormatDecimal(%d, %d) = %q; want %q", unscaled, scale, text, test.text)
+		}
+	}
+
+	bad := []string{
+		"",
+		"-",
+		".5",
+		"5.",
+		"1.2.3",
+		"1e3",
+		" 1",
+		"0.0000000000000000001",
+		"9223372036854775808",
+		"-9223372036854775809",
+		"99999999999999999999999",
+	}
+	for _, s := range bad {
+		if unscaled, scale, err := ParseDecimal(s); err == nil {
+			t.Errorf("ParseDecimal(%q) = %d, %d; want error", s, unscaled, scale)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecimalFrom(seg, 1, MaxDecimalScale+1); err == nil {
+		t.Error("NewDecimalFrom with scale 19 succeeded; want error")
+	}
+	d, err := NewDecimalFrom(seg, -1999, 3)
+	if err != nil {
+		t.Fatal("NewDecimalFrom:", err)
+	}
+	if text, err := d.Text(); err != nil || text != "-1.999" {
+		t.Errorf("Text() = %q, %v; want \"-1.999\", <nil>", text, err)
+	}
+	if err := d.SetText("bogus"); err == nil {
+		t.Error("SetText(\"bogus\") succeeded; want error")
+	}
+	if d.Unscaled() != -1999 || d.Scale() != 3 {
+		t.Errorf("after failed SetText, d = %d, %d; want unchanged", d.Unscaled(), d.Scale())
+	}
+	d.SetScale(MaxDecimalScale + 1)
+	if err := d.Validate(); err == nil {
+		t.Error("Validate() with scale 19 = <nil>; want error")
+	}
+	if _, err := d.Text(); err == nil {
+		t.Error("Text() with scale 19 succeeded; want error")
+	}
+}
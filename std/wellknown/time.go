@@ -0,0 +1,51 @@
+// Package wellknown contains the types in std/wellknown.capnp, with
+// conversions to and from Go types.
+package wellknown // import "zombiezen.com/go/capnproto2/std/wellknown"
+
+import (
+	"time"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// Time returns the point in time that ts holds, in UTC.  A zero
+// Timestamp is the Unix epoch, not the zero time.Time.
+func (ts Timestamp) Time() time.Time {
+	return time.Unix(ts.Seconds(), int64(ts.Nanos())).UTC()
+}
+
+// SetTime sets ts to t.  The time zone of t is not stored.
+func (ts Timestamp) SetTime(t time.Time) {
+	ts.SetSeconds(t.Unix())
+	ts.SetNanos(uint32(t.Nanosecond()))
+}
+
+// NewTimestampFrom allocates a Timestamp that holds t in s.
+func NewTimestampFrom(s *capnp.Segment, t time.Time) (Timestamp, error) {
+	ts, err := NewTimestamp(s)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	ts.SetTime(t)
+	return ts, nil
+}
+
+// Duration returns the span of time that d holds.
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d.Nanoseconds())
+}
+
+// SetDuration sets d to v.
+func (d Duration) SetDuration(v time.Duration) {
+	d.SetNanoseconds(int64(v))
+}
+
+// NewDurationFrom allocates a Duration that holds v in s.
+func NewDurationFrom(s *capnp.Segment, v time.Duration) (Duration, error) {
+	d, err := NewDuration(s)
+	if err != nil {
+		return Duration{}, err
+	}
+	d.SetDuration(v)
+	return d, nil
+}
@@ -0,0 +1,49 @@
+package wellknown
+
+import (
+	"testing"
+	"time"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestTimestamp(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []time.Time{
+		time.Unix(0, 0),
+		time.Date(2017, time.March, 4, 5, 6, 7, 8, time.UTC),
+		time.Date(1969, time.December, 31, 23, 59, 59, 999999999, time.UTC),
+		time.Date(2020, time.June, 1, 12, 0, 0, 0, time.FixedZone("X", -7*60*60)),
+	}
+	for _, want := range tests {
+		ts, err := NewTimestampFrom(seg, want)
+		if err != nil {
+			t.Fatal("NewTimestampFrom:", err)
+		}
+		if got := ts.Time(); !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("NewTimestampFrom(%v).Time() = %v; want %v in UTC", want, got, want)
+		}
+		if n := ts.Nanos(); n >= 1e9 {
+			t.Errorf("NewTimestampFrom(%v).Nanos() = %d; want < 1e9", want, n)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []time.Duration{0, time.Nanosecond, -90 * time.Minute, 1<<63 - 1} {
+		d, err := NewDurationFrom(seg, want)
+		if err != nil {
+			t.Fatal("NewDurationFrom:", err)
+		}
+		if got := d.Duration(); got != want {
+			t.Errorf("NewDurationFrom(%v).Duration() = %v", want, got)
+		}
+	}
+}
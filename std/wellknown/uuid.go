@@ -0,0 +1,102 @@
+package wellknown
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// Bytes returns the 16 bytes of u.
+func (u UUID) Bytes() [16]byte {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.High())
+	binary.BigEndian.PutUint64(b[8:], u.Low())
+	return b
+}
+
+// SetBytes sets u to b.
+func (u UUID) SetBytes(b [16]byte) {
+	u.SetHigh(binary.BigEndian.Uint64(b[:8]))
+	u.SetLow(binary.BigEndian.Uint64(b[8:]))
+}
+
+// Text returns u in the RFC 4122 string form, like
+// "f81d4fae-7dec-11d0-a765-00a0c91e6bf6".
+func (u UUID) Text() string {
+	return FormatUUID(u.Bytes())
+}
+
+// SetText sets u to the UUID in s, which must be in the RFC 4122
+// string form.  u is not changed if s is not a valid UUID.
+func (u UUID) SetText(s string) error {
+	b, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+	u.SetBytes(b)
+	return nil
+}
+
+// NewUUIDFrom allocates a UUID that holds b in s.
+func NewUUIDFrom(s *capnp.Segment, b [16]byte) (UUID, error) {
+	u, err := NewUUID(s)
+	if err != nil {
+		return UUID{}, err
+	}
+	u.SetBytes(b)
+	return u, nil
+}
+
+// FormatUUID returns b in the RFC 4122 string form, in lowercase.
+func FormatUUID(b [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+// ParseUUID parses a UUID in the RFC 4122 string form.  Hex digits may
+// be in either case.
+func ParseUUID(s string) ([16]byte, error) {
+	var b [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return b, errBadUUID
+	}
+	for j, i := range uuidByteOffsets {
+		hi, ok1 := fromHex(s[i])
+		lo, ok2 := fromHex(s[i+1])
+		if !ok1 || !ok2 {
+			return [16]byte{}, errBadUUID
+		}
+		b[j] = hi<<4 | lo
+	}
+	return b, nil
+}
+
+// uuidByteOffsets is the offset in the string form of each byte of a
+// UUID.
+var uuidByteOffsets = [16]int{0, 2, 4, 6, 9, 11, 14, 16, 19, 21, 24, 26, 28, 30, 32, 34}
+
+func fromHex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
+
+var errBadUUID = errors.New("wellknown: UUID must have the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
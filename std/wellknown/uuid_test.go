@@ -0,0 +1,59 @@
+package wellknown
+
+import (
+	"testing"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+func TestUUID(t *testing.T) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [16]byte{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6}
+	u, err := NewUUIDFrom(seg, want)
+	if err != nil {
+		t.Fatal("NewUUIDFrom:", err)
+	}
+	if got := u.Bytes(); got != want {
+		t.Errorf("Bytes() = %x; want %x", got, want)
+	}
+	if u.High() != 0xf81d4fae7dec11d0 || u.Low() != 0xa76500a0c91e6bf6 {
+		t.Errorf("High(), Low() = %#x, %#x; want big-endian halves", u.High(), u.Low())
+	}
+	if got, want := u.Text(), "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"; got != want {
+		t.Errorf("Text() = %q; want %q", got, want)
+	}
+	if err := u.SetText("00000000-0000-0000-0000-00000000000A"); err != nil {
+		t.Error("SetText:", err)
+	} else if u.High() != 0 || u.Low() != 10 {
+		t.Errorf("after SetText, High(), Low() = %#x, %#x; want 0, 0xa", u.High(), u.Low())
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	bad := []string{
+		"",
+		"f81d4fae7dec11d0a76500a0c91e6bf6",
+		"f81d4fae-7dec-11d0-a765-00a0c91e6bf",
+		"f81d4fae-7dec-11d0-a765-00a0c91e6bf60",
+		"f81d4fae-7dec-11d0-a765_00a0c91e6bf6",
+		"f81d4fae-7dec-11d0-a765-00a0c91e6bfg",
+		"-81d4fae-7dec-11d0-a765-00a0c91e6bf6",
+		"{81d4fae-7dec-11d0-a765-00a0c91e6bf}",
+	}
+	for _, s := range bad {
+		if b, err := ParseUUID(s); err == nil {
+			t.Errorf("ParseUUID(%q) = %x; want error", s, b)
+		}
+	}
+	const s = "F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6"
+	b, err := ParseUUID(s)
+	if err != nil {
+		t.Fatalf("ParseUUID(%q): %v", s, err)
+	}
+	if got, want := FormatUUID(b), "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"; got != want {
+		t.Errorf("FormatUUID(ParseUUID(%q)) = %q; want %q", s, got, want)
+	}
+}
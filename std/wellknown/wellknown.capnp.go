@@ -0,0 +1,303 @@
+// Code generated by capnpc-go. DO NOT EDIT.
+
+package wellknown
+
+import (
+	capnp "zombiezen.com/go/capnproto2"
+	text "zombiezen.com/go/capnproto2/encoding/text"
+	schemas "zombiezen.com/go/capnproto2/schemas"
+)
+
+type Timestamp struct{ capnp.Struct }
+
+// Timestamp_TypeID is the unique identifier for the type Timestamp.
+const Timestamp_TypeID = 0xa92a0e17fbe5cadd
+
+func NewTimestamp(s *capnp.Segment) (Timestamp, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0})
+	return Timestamp{st}, err
+}
+
+func NewRootTimestamp(s *capnp.Segment) (Timestamp, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0})
+	return Timestamp{st}, err
+}
+
+func ReadRootTimestamp(msg *capnp.Message) (Timestamp, error) {
+	root, err := msg.RootPtr()
+	return Timestamp{root.Struct()}, err
+}
+
+func (s Timestamp) String() string {
+	str, _ := text.Marshal(0xa92a0e17fbe5cadd, s.Struct)
+	return str
+}
+
+func (s Timestamp) Seconds() int64 {
+	return int64(s.Struct.Uint64(0))
+}
+
+func (s Timestamp) SetSeconds(v int64) {
+	s.Struct.SetUint64(0, uint64(v))
+}
+
+func (s Timestamp) Nanos() uint32 {
+	return s.Struct.Uint32(8)
+}
+
+func (s Timestamp) SetNanos(v uint32) {
+	s.Struct.SetUint32(8, v)
+}
+
+// Timestamp_List is a list of Timestamp.
+type Timestamp_List struct{ capnp.List }
+
+// NewTimestamp creates a new list of Timestamp.
+func NewTimestamp_List(s *capnp.Segment, sz int32) (Timestamp_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0}, sz)
+	return Timestamp_List{l}, err
+}
+
+func (s Timestamp_List) At(i int) Timestamp { return Timestamp{s.List.Struct(i)} }
+
+func (s Timestamp_List) Set(i int, v Timestamp) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s Timestamp_List) String() string {
+	str, _ := text.MarshalList(0xa92a0e17fbe5cadd, s.List)
+	return str
+}
+
+// Timestamp_Promise is a wrapper for a Timestamp promised by a client call.
+type Timestamp_Promise struct{ *capnp.Pipeline }
+
+func (p Timestamp_Promise) Struct() (Timestamp, error) {
+	s, err := p.Pipeline.Struct()
+	return Timestamp{s}, err
+}
+
+type Duration struct{ capnp.Struct }
+
+// Duration_TypeID is the unique identifier for the type Duration.
+const Duration_TypeID = 0xd90938768dfb38c4
+
+func NewDuration(s *capnp.Segment) (Duration, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 0})
+	return Duration{st}, err
+}
+
+func NewRootDuration(s *capnp.Segment) (Duration, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 0})
+	return Duration{st}, err
+}
+
+func ReadRootDuration(msg *capnp.Message) (Duration, error) {
+	root, err := msg.RootPtr()
+	return Duration{root.Struct()}, err
+}
+
+func (s Duration) String() string {
+	str, _ := text.Marshal(0xd90938768dfb38c4, s.Struct)
+	return str
+}
+
+func (s Duration) Nanoseconds() int64 {
+	return int64(s.Struct.Uint64(0))
+}
+
+func (s Duration) SetNanoseconds(v int64) {
+	s.Struct.SetUint64(0, uint64(v))
+}
+
+// Duration_List is a list of Duration.
+type Duration_List struct{ capnp.List }
+
+// NewDuration creates a new list of Duration.
+func NewDuration_List(s *capnp.Segment, sz int32) (Duration_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 0}, sz)
+	return Duration_List{l}, err
+}
+
+func (s Duration_List) At(i int) Duration { return Duration{s.List.Struct(i)} }
+
+func (s Duration_List) Set(i int, v Duration) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s Duration_List) String() string {
+	str, _ := text.MarshalList(0xd90938768dfb38c4, s.List)
+	return str
+}
+
+// Duration_Promise is a wrapper for a Duration promised by a client call.
+type Duration_Promise struct{ *capnp.Pipeline }
+
+func (p Duration_Promise) Struct() (Duration, error) {
+	s, err := p.Pipeline.Struct()
+	return Duration{s}, err
+}
+
+type UUID struct{ capnp.Struct }
+
+// UUID_TypeID is the unique identifier for the type UUID.
+const UUID_TypeID = 0xba7e1900fea96dad
+
+func NewUUID(s *capnp.Segment) (UUID, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0})
+	return UUID{st}, err
+}
+
+func NewRootUUID(s *capnp.Segment) (UUID, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0})
+	return UUID{st}, err
+}
+
+func ReadRootUUID(msg *capnp.Message) (UUID, error) {
+	root, err := msg.RootPtr()
+	return UUID{root.Struct()}, err
+}
+
+func (s UUID) String() string {
+	str, _ := text.Marshal(0xba7e1900fea96dad, s.Struct)
+	return str
+}
+
+func (s UUID) High() uint64 {
+	return s.Struct.Uint64(0)
+}
+
+func (s UUID) SetHigh(v uint64) {
+	s.Struct.SetUint64(0, v)
+}
+
+func (s UUID) Low() uint64 {
+	return s.Struct.Uint64(8)
+}
+
+func (s UUID) SetLow(v uint64) {
+	s.Struct.SetUint64(8, v)
+}
+
+// UUID_List is a list of UUID.
+type UUID_List struct{ capnp.List }
+
+// NewUUID creates a new list of UUID.
+func NewUUID_List(s *capnp.Segment, sz int32) (UUID_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0}, sz)
+	return UUID_List{l}, err
+}
+
+func (s UUID_List) At(i int) UUID { return UUID{s.List.Struct(i)} }
+
+func (s UUID_List) Set(i int, v UUID) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s UUID_List) String() string {
+	str, _ := text.MarshalList(0xba7e1900fea96dad, s.List)
+	return str
+}
+
+// UUID_Promise is a wrapper for a UUID promised by a client call.
+type UUID_Promise struct{ *capnp.Pipeline }
+
+func (p UUID_Promise) Struct() (UUID, error) {
+	s, err := p.Pipeline.Struct()
+	return UUID{s}, err
+}
+
+type Decimal struct{ capnp.Struct }
+
+// Decimal_TypeID is the unique identifier for the type Decimal.
+const Decimal_TypeID = 0xfa7f2299899a67f1
+
+func NewDecimal(s *capnp.Segment) (Decimal, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0})
+	return Decimal{st}, err
+}
+
+func NewRootDecimal(s *capnp.Segment) (Decimal, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0})
+	return Decimal{st}, err
+}
+
+func ReadRootDecimal(msg *capnp.Message) (Decimal, error) {
+	root, err := msg.RootPtr()
+	return Decimal{root.Struct()}, err
+}
+
+func (s Decimal) String() string {
+	str, _ := text.Marshal(0xfa7f2299899a67f1, s.Struct)
+	return str
+}
+
+func (s Decimal) Unscaled() int64 {
+	return int64(s.Struct.Uint64(0))
+}
+
+func (s Decimal) SetUnscaled(v int64) {
+	s.Struct.SetUint64(0, uint64(v))
+}
+
+func (s Decimal) Scale() uint8 {
+	return s.Struct.Uint8(8)
+}
+
+func (s Decimal) SetScale(v uint8) {
+	s.Struct.SetUint8(8, v)
+}
+
+// Decimal_List is a list of Decimal.
+type Decimal_List struct{ capnp.List }
+
+// NewDecimal creates a new list of Decimal.
+func NewDecimal_List(s *capnp.Segment, sz int32) (Decimal_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 0}, sz)
+	return Decimal_List{l}, err
+}
+
+func (s Decimal_List) At(i int) Decimal { return Decimal{s.List.Struct(i)} }
+
+func (s Decimal_List) Set(i int, v Decimal) error { return s.List.SetStruct(i, v.Struct) }
+
+func (s Decimal_List) String() string {
+	str, _ := text.MarshalList(0xfa7f2299899a67f1, s.List)
+	return str
+}
+
+// Decimal_Promise is a wrapper for a Decimal promised by a client call.
+type Decimal_Promise struct{ *capnp.Pipeline }
+
+func (p Decimal_Promise) Struct() (Decimal, error) {
+	s, err := p.Pipeline.Struct()
+	return Decimal{s}, err
+}
+
+const schema_fc58552e8c670230 = "x\xdal\x901h\xd4P\x18\xc7\xbf\xffK\xae\xdfu" +
+	"\xa8\xcd#\x8a\x08\x82\xd8\xc9\x16-\xbd\xd2\xc2!\x8aA" +
+	"\xba\xe8t\x0f<p\x8diH\x83\xc9\xcbaZ3i" +
+	"\xe7\x82\x0e\x8e\xe7\xeep\x82\xa3\x8b \x88\xb8x\xa3\x9b" +
+	"\x83\xa3\xb3\xb8zb\xe4\xc5x\xde\x99\x83\xfc\x86\xfc\xf9" +
+	"\xf2\xfd\xbe\x7f\x9cw\x9e\xe8\xb4\"\x10)\xa7\xb5T~" +
+	"\x19\x7f\x9d\x9c=\xb51\"\xe5@\x94[\"z\xb2\xd9" +
+	"\xbf\xfb\x93l&\x92\xc3O\xf2\x05WOA\xe4^\x04" +
+	"\x7f{\x95\x8e~\x9d{\xfc\xa69\xeb\xae\xe0\xa5{\x06" +
+	"\\c\xe6\x9f\x81\xcb\x0f\xdd\xc9\xd3\x87\xdd\xe5\xcff;" +
+	"\xfe\xfb\xe2\x11\xc6\xee\x09\xb8\xe6\x06\x91\xfb\x11\\~\x8f" +
+	"\x9e\x9f\x0c\xd7\x8e\x7f,\xb8\xc7}\x8d\xf7\xee[p\x8d" +
+	"q\\\x17\\\x16a\x92\xdc\xd7Y!\xf4f\xe0\x0f\xf4" +
+	"\xe0\xea\x9d8\x0d\xf3C?\xc5\xa0\x07\xf4 T\xdb\xb2" +
+	"\x89l\x10\xc9\xf5\x9br\x9d\xd5%\x0bjG@B\x9c" +
+	"\x86I;\xdb\xb2\xc3j\xcb\x82\xba&p\x9c\x87A\xa6" +
+	"\xf7\xf3\x1e\x04Zd\xc0\x05\xed\xeb\xac\x0a\xdad\x80\x87" +
+	"\xa9\x15\x7f\xad\xab\xfd\xfe\xad\xbd\xa6pcN\x88Z\xb8" +
+	"6#\\=\x88\xa3\x03\xb3|\x99\x0c\xe0$+f^" +
+	"=4\x1b\xee\x1d=\xf0\x0f\xe3L\x13\xd5B{*\\" +
+	"\xb9'%+\xc7\x82:/PV\x87\x87AF<\xdf" +
+	"h\xe1\xd20\x88S?\xa1f\x87\xdb\xf2\x0a\xab\xcb\x16" +
+	"T\xd7th\xff\xe9\xb0\xbb-wY\xedXP\x9e@" +
+	"y\xa4\xf3\xc0O\xc2}2'\xfd\xfbqUh\x82%" +
+	"2\xc0\xc3\xef\x01\x00G\xf6\x8a\xe9"
+
+func init() {
+	schemas.Register(schema_fc58552e8c670230,
+		0xa92a0e17fbe5cadd,
+		0xba7e1900fea96dad,
+		0xd90938768dfb38c4,
+		0xfa7f2299899a67f1)
+}
@@ -1,5 +1,7 @@
 package capnp
 
+import "bytes"
+
 // Struct is a pointer to a struct.
 type Struct struct {
 	seg        *Segment
@@ -130,6 +132,125 @@ func (p Struct) Ptr(i uint16) (Ptr, error) {
 	return p.seg.readPtr(p.pointerAddress(i), p.depthLimit)
 }
 
+// ReadPtr returns the i'th pointer in the struct.  Unlike Ptr, it does
+// not return an error: if the pointer can't be read, ReadPtr returns a
+// null pointer and records the error on the message, to be reported
+// later by Message.Err.
+func (p Struct) ReadPtr(i uint16) Ptr {
+	pp, err := p.Ptr(i)
+	if err != nil {
+		p.seg.msg.recordErr(err)
+		return Ptr{}
+	}
+	return pp
+}
+
+// ReadText returns the i'th pointer in the struct as a string.  Errors
+// are recorded on the message like ReadPtr.
+func (p Struct) ReadText(i uint16) string {
+	s, err := p.Text(i)
+	if err != nil {
+		p.seg.msg.recordErr(err)
+		return ""
+	}
+	return s
+}
+
+// ReadData returns the i'th pointer in the struct as a byte slice.
+// Errors are recorded on the message like ReadPtr.
+func (p Struct) ReadData(i uint16) []byte {
+	return p.ReadPtr(i).Data()
+}
+
+// FixedData copies the i'th pointer in the struct, which must be null
+// or a data of exactly len(dst) bytes, into dst.  If the pointer is
+// null, dst is zeroed.  Generated code uses FixedData for Data fields
+// annotated with $Go.fixed.
+func (p Struct) FixedData(i uint16, dst []byte) error {
+	pp, err := p.Ptr(i)
+	if err != nil {
+		return err
+	}
+	return fixedData(pp, dst)
+}
+
+// ReadFixedData is like FixedData, but it records an error on the
+// message like ReadPtr.  On error, dst is zeroed.
+func (p Struct) ReadFixedData(i uint16, dst []byte) {
+	if err := fixedData(p.ReadPtr(i), dst); err != nil {
+		p.seg.msg.recordErr(err)
+	}
+}
+
+func fixedData(p Ptr, dst []byte) error {
+	err := verifyFixedData(p, len(dst))
+	if err != nil || !p.IsValid() {
+		for i := range dst {
+			dst[i] = 0
+		}
+		return err
+	}
+	copy(dst, p.Data())
+	return nil
+}
+
+// Text returns the i'th pointer in the struct as a string, or the empty
+// string if the pointer is null or not a text.  It is equivalent to
+// calling Text on the result of Ptr, but is faster for the common case
+// of a text in the same segment as the struct.  If the message's
+// ValidateText field is set, malformed text is reported as a *TextError.
+func (p Struct) Text(i uint16) (string, error) {
+	b, err := p.textBytes(i)
+	return string(b), err
+}
+
+// TextBytes returns the i'th pointer in the struct as a byte slice, or
+// nil if the pointer is null or not a text.  The slice points directly
+// into the segment.  See Struct.Text.
+func (p Struct) TextBytes(i uint16) ([]byte, error) {
+	return p.textBytes(i)
+}
+
+func (p Struct) textBytes(i uint16) ([]byte, error) {
+	if p.seg == nil || i >= p.size.PointerCount {
+		return nil, nil
+	}
+	paddr := p.pointerAddress(i)
+	val := p.seg.readRawPointer(paddr)
+	if val == 0 {
+		return nil, nil
+	}
+	if val.pointerType() != listPointer || val.listType() != byte1List || p.depthLimit == 0 {
+		// Far pointers, non-text pointers, and errors take the slow path.
+		pp, err := p.Ptr(i)
+		if err != nil {
+			return nil, err
+		}
+		return pp.checkedText()
+	}
+	base, ok := paddr.addSize(wordSize)
+	if !ok {
+		return nil, errOverflow
+	}
+	addr, ok := val.offset().resolve(base)
+	n := Size(val.numListElements())
+	if !ok || !p.seg.regionInBounds(addr, n) {
+		return nil, errPointerAddress
+	}
+	if !p.seg.msg.ReadLimiter().canRead(n) {
+		return nil, errReadLimit
+	}
+	b := p.seg.data[addr : addr+Address(n)]
+	if p.seg.msg.ValidateText {
+		return validateText(b)
+	}
+	if n == 0 || b[n-1] != 0 {
+		// Text must be null-terminated.
+		return nil, nil
+	}
+	return b[: n-1 : n], nil
+}
+
 // SetPointer sets the i'th pointer in the struct to src.
 //
 // Deprecated: Use SetPtr.
@@ -186,6 +307,32 @@ func (p Struct) SetData(i uint16, v []byte) error {
 	return p.SetPtr(i, d.List.ToPtr())
 }
 
+// TextEquals reports whether the i'th pointer is a text equal to s.
+// A null pointer is equal to the empty string.  Unlike calling Text on
+// the pointer, TextEquals does not copy the text out of the segment.
+func (p Struct) TextEquals(i uint16, s string) (bool, error) {
+	pp, err := p.Ptr(i)
+	if err != nil {
+		return false, err
+	}
+	b, ok := pp.text()
+	if !ok {
+		return s == "", nil
+	}
+	return string(b) == s, nil
+}
+
+// DataEquals reports whether the i'th pointer is a data equal to b.
+// A null pointer is equal to an empty or nil slice.  The comparison is
+// done directly against the segment's bytes.
+func (p Struct) DataEquals(i uint16, b []byte) (bool, error) {
+	pp, err := p.Ptr(i)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(pp.Data(), b), nil
+}
+
 func (p Struct) pointerAddress(i uint16) Address {
 	// Struct already had bounds check
 	ptrStart, _ := p.off.addSize(p.size.DataSize)
@@ -301,6 +448,30 @@ func (p Struct) SetUint64(off DataOffset, v uint64) {
 	p.seg.writeUint64(addr, v)
 }
 
+// Present reports whether bit n is set in the 64-bit presence bitmap
+// that is off bytes from the start of the struct.  Bits beyond the end
+// of the struct's data section (as written by an older schema) read
+// as unset.  Generated code uses Present for fields tracked by a
+// $Go.presence annotation.
+func (p Struct) Present(off DataOffset, n uint) bool {
+	return n < 64 && p.Uint64(off)&(1<<n) != 0
+}
+
+// SetPresent sets bit n in the 64-bit presence bitmap that is off
+// bytes from the start of the struct to v.
+func (p Struct) SetPresent(off DataOffset, n uint, v bool) {
+	if n >= 64 {
+		panic(errOutOfBounds)
+	}
+	bits := p.Uint64(off)
+	if v {
+		bits |= 1 << n
+	} else {
+		bits &^= 1 << n
+	}
+	p.SetUint64(off, bits)
+}
+
 // structFlags is a bitmask of flags for a pointer.
 type structFlags uint8
 
@@ -314,6 +485,9 @@ func copyStruct(dst, src Struct) error {
 	if dst.seg == nil {
 		return nil
 	}
+	if dst.seg.msg.frozen {
+		return errFrozen
+	}
 
 	// Q: how does version handling happen here, when the
 	//    destination toData[] slice can be bigger or smaller
@@ -0,0 +1,174 @@
+// Package switchboard provides a capability that routes calls to one
+// of several backing capabilities.
+//
+// A Switchboard is useful for exporting a single capability whose
+// implementation depends on the caller, like per-tenant routing, or
+// for replacing an implementation without handing out a new capability
+// to every client.  Backends can be added, swapped, and removed while
+// calls are being made.
+package switchboard // import "zombiezen.com/go/capnproto2/switchboard"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// A Selector chooses the backend for a call by returning its key.
+// A Selector is called from the goroutine making the call and may be
+// called concurrently.  It must not block: it usually inspects only
+// call.Method and call.Options.
+type Selector func(call *capnp.Call) (key string, err error)
+
+// A Switchboard is a capnp.Client that routes each call to the backend
+// chosen by its selector.  It is safe to use from multiple goroutines.
+//
+// Calls routed to the same backend are delivered in the order they are
+// made.  No order is guaranteed between calls delivered to different
+// backends, including an old and a new backend across a Swap.
+type Switchboard struct {
+	sel Selector
+
+	mu       sync.RWMutex
+	backends map[string]*backend
+	closed   bool
+}
+
+type backend struct {
+	c        capnp.Client
+	dispatch sync.WaitGroup // calls being delivered to c
+}
+
+// New returns a switchboard with no backends that routes calls using
+// sel.
+func New(sel Selector) *Switchboard {
+	return &Switchboard{
+		sel:      sel,
+		backends: make(map[string]*backend),
+	}
+}
+
+// Swap sets the backend for key to c and returns the previous backend,
+// or nil if there was none.  If c is nil, the backend is removed.  The
+// switchboard takes ownership of c, and the caller takes ownership of
+// the returned client.  If the switchboard is closed, Swap closes c and
+// returns nil.
+//
+// Swap waits for calls that are being delivered to the previous backend
+// to be acknowledged, so the returned client can be closed immediately.
+// A backend's method must therefore not call Swap for its own key
+// before acknowledging delivery with server.Ack.
+func (sb *Switchboard) Swap(key string, c capnp.Client) capnp.Client {
+	sb.mu.Lock()
+	if sb.closed {
+		sb.mu.Unlock()
+		if c != nil {
+			c.Close()
+		}
+		return nil
+	}
+	old := sb.backends[key]
+	if c == nil {
+		delete(sb.backends, key)
+	} else {
+		sb.backends[key] = &backend{c: c}
+	}
+	sb.mu.Unlock()
+	if old == nil {
+		return nil
+	}
+	old.dispatch.Wait()
+	return old.c
+}
+
+// Set sets the backend for key to c, closing the previous backend.
+func (sb *Switchboard) Set(key string, c capnp.Client) error {
+	if old := sb.Swap(key, c); old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Remove removes and closes the backend for key.
+func (sb *Switchboard) Remove(key string) error {
+	return sb.Set(key, nil)
+}
+
+// Keys returns the keys of the switchboard's backends in no particular
+// order.
+func (sb *Switchboard) Keys() []string {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+	keys := make([]string, 0, len(sb.backends))
+	for k := range sb.backends {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Call routes call to the backend chosen by the selector.  If the
+// selector returns an error or there is no backend for its key, then
+// the call fails without being delivered anywhere.
+func (sb *Switchboard) Call(call *capnp.Call) capnp.Answer {
+	key, err := sb.sel(call)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	sb.mu.RLock()
+	if sb.closed {
+		sb.mu.RUnlock()
+		return capnp.ErrorAnswer(errClosed)
+	}
+	b := sb.backends[key]
+	if b == nil {
+		sb.mu.RUnlock()
+		return capnp.ErrorAnswer(&NoBackendError{Key: key})
+	}
+	b.dispatch.Add(1)
+	sb.mu.RUnlock()
+	defer b.dispatch.Done()
+	return b.c.Call(call)
+}
+
+// Close closes all of the switchboard's backends.  Subsequent calls
+// fail.
+func (sb *Switchboard) Close() error {
+	sb.mu.Lock()
+	if sb.closed {
+		sb.mu.Unlock()
+		return errClosed
+	}
+	sb.closed = true
+	backends := sb.backends
+	sb.backends = nil
+	sb.mu.Unlock()
+
+	var firstErr error
+	for _, b := range backends {
+		b.dispatch.Wait()
+		if err := b.c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NoBackendError is the error returned from a call whose selector
+// chose a key that has no backend.
+type NoBackendError struct {
+	Key string
+}
+
+func (e *NoBackendError) Error() string {
+	return fmt.Sprintf("switchboard: no backend for %q", e.Key)
+}
+
+// IsNoBackend reports whether e is a *NoBackendError.
+func IsNoBackend(e error) bool {
+	_, ok := e.(*NoBackendError)
+	return ok
+}
+
+var errClosed = errors.New("switchboard: closed")
@@ -0,0 +1,141 @@
+package switchboard_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/switchboard"
+)
+
+type tenantKey struct{}
+
+func selectTenant(call *capnp.Call) (string, error) {
+	tenant, ok := call.Options.Value(tenantKey{}).(string)
+	if !ok {
+		return "", errors.New("no tenant")
+	}
+	return tenant, nil
+}
+
+// constSeq is a CallSequence that always returns the same number.
+type constSeq struct {
+	n      uint32
+	closed *bool
+}
+
+func (seq constSeq) GetNumber(call air.CallSequence_getNumber) error {
+	call.Results.SetN(seq.n)
+	return nil
+}
+
+func (seq constSeq) Close() error {
+	if seq.closed != nil {
+		*seq.closed = true
+	}
+	return nil
+}
+
+func getNumber(seq air.CallSequence, tenant string) (uint32, error) {
+	var opts []capnp.CallOption
+	if tenant != "" {
+		opts = append(opts, capnp.SetOptionValue(tenantKey{}, tenant))
+	}
+	res, err := seq.GetNumber(context.Background(), nil, opts...).Struct()
+	if err != nil {
+		return 0, err
+	}
+	return res.N(), nil
+}
+
+func TestSwitchboard(t *testing.T) {
+	sb := switchboard.New(selectTenant)
+	defer sb.Close()
+	sb.Set("a", air.CallSequence_ServerToClient(constSeq{n: 1}).Client)
+	sb.Set("b", air.CallSequence_ServerToClient(constSeq{n: 2}).Client)
+	seq := air.CallSequence{Client: sb}
+
+	for _, test := range []struct {
+		tenant string
+		want   uint32
+	}{{"a", 1}, {"b", 2}, {"a", 1}} {
+		if n, err := getNumber(seq, test.tenant); err != nil {
+			t.Errorf("getNumber(%q): %v", test.tenant, err)
+		} else if n != test.want {
+			t.Errorf("getNumber(%q) = %d; want %d", test.tenant, n, test.want)
+		}
+	}
+	if _, err := getNumber(seq, "c"); !switchboard.IsNoBackend(err) {
+		t.Errorf("getNumber(\"c\") error = %v; want no backend", err)
+	}
+	if _, err := getNumber(seq, ""); err == nil {
+		t.Error("getNumber without tenant succeeded; want selector error")
+	}
+
+	keys := sb.Keys()
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Keys() = %q; want [a b]", keys)
+	}
+}
+
+func TestSwitchboard_Swap(t *testing.T) {
+	sb := switchboard.New(selectTenant)
+	defer sb.Close()
+	seq := air.CallSequence{Client: sb}
+
+	var closed1, closed2 bool
+	if old := sb.Swap("a", air.CallSequence_ServerToClient(constSeq{n: 1, closed: &closed1}).Client); old != nil {
+		t.Errorf("first Swap returned %v; want nil", old)
+	}
+	if n, err := getNumber(seq, "a"); err != nil || n != 1 {
+		t.Errorf("getNumber(\"a\") = %d, %v; want 1, <nil>", n, err)
+	}
+	old := sb.Swap("a", air.CallSequence_ServerToClient(constSeq{n: 2, closed: &closed2}).Client)
+	if old == nil {
+		t.Fatal("second Swap returned nil")
+	}
+	if closed1 {
+		t.Error("Swap closed the previous backend")
+	}
+	if n, err := getNumber(seq, "a"); err != nil || n != 2 {
+		t.Errorf("after Swap, getNumber(\"a\") = %d, %v; want 2, <nil>", n, err)
+	}
+	if err := old.Close(); err != nil {
+		t.Error("old.Close():", err)
+	}
+	if !closed1 {
+		t.Error("closing the swapped-out backend did not close its server")
+	}
+
+	if err := sb.Remove("a"); err != nil {
+		t.Error("Remove(\"a\"):", err)
+	}
+	if !closed2 {
+		t.Error("Remove did not close the backend")
+	}
+	if _, err := getNumber(seq, "a"); !switchboard.IsNoBackend(err) {
+		t.Errorf("after Remove, getNumber(\"a\") error = %v; want no backend", err)
+	}
+}
+
+func TestSwitchboard_Close(t *testing.T) {
+	var closed bool
+	sb := switchboard.New(selectTenant)
+	sb.Set("a", air.CallSequence_ServerToClient(constSeq{closed: &closed}).Client)
+	if err := sb.Close(); err != nil {
+		t.Error("Close():", err)
+	}
+	if !closed {
+		t.Error("Close did not close backends")
+	}
+	if _, err := getNumber(air.CallSequence{Client: sb}, "a"); err == nil {
+		t.Error("call after Close succeeded")
+	}
+	if err := sb.Close(); err == nil {
+		t.Error("second Close returned nil; want error")
+	}
+}
@@ -0,0 +1,98 @@
+package capnp
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// TextError is returned by text accessors for malformed text when text
+// validation is enabled.  See Message.ValidateText.
+type TextError struct {
+	// Kind is the way in which the text is malformed.
+	Kind TextErrorKind
+
+	// Offset is the byte offset of the first invalid UTF-8 sequence when
+	// Kind is TextInvalidUTF8.
+	Offset int
+}
+
+// TextErrorKind is the kind of a TextError.
+type TextErrorKind int
+
+// Kinds of TextError.
+const (
+	// TextNotBytes means that the pointer is not a list of bytes.
+	TextNotBytes TextErrorKind = 1 + iota
+
+	// TextUnterminated means that the byte list does not end in a NUL.
+	TextUnterminated
+
+	// TextInvalidUTF8 means that the text is not valid UTF-8.
+	TextInvalidUTF8
+)
+
+func (e *TextError) Error() string {
+	switch e.Kind {
+	case TextNotBytes:
+		return "capnp: text is not a byte list"
+	case TextUnterminated:
+		return "capnp: text is not NUL-terminated"
+	case TextInvalidUTF8:
+		return "capnp: text is not valid UTF-8 at byte " + strconv.Itoa(e.Offset)
+	default:
+		return "capnp: malformed text"
+	}
+}
+
+// ValidText converts p into Text like Ptr.Text, but returns a *TextError
+// if p is not a NUL-terminated byte list of valid UTF-8.  A null pointer
+// is the empty string.  ValidText validates regardless of the message's
+// ValidateText setting.
+func (p Ptr) ValidText() (string, error) {
+	b, err := p.validText()
+	return string(b), err
+}
+
+func (p Ptr) validText() ([]byte, error) {
+	if !p.IsValid() {
+		return nil, nil
+	}
+	if !isOneByteList(p) {
+		return nil, &TextError{Kind: TextNotBytes}
+	}
+	l := p.List()
+	return validateText(l.seg.slice(l.off, Size(l.length)))
+}
+
+// checkedText returns p's text, validating it if the message asks for it.
+func (p Ptr) checkedText() ([]byte, error) {
+	if p.seg != nil && p.seg.msg != nil && p.seg.msg.ValidateText {
+		return p.validText()
+	}
+	return p.TextBytes(), nil
+}
+
+// validateText checks b, the bytes of a text including its NUL
+// terminator, and returns the text without the terminator.
+func validateText(b []byte) ([]byte, error) {
+	n := len(b)
+	if n == 0 || b[n-1] != 0 {
+		return nil, &TextError{Kind: TextUnterminated}
+	}
+	b = b[: n-1 : n]
+	if !utf8.Valid(b) {
+		return nil, &TextError{Kind: TextInvalidUTF8, Offset: invalidUTF8Offset(b)}
+	}
+	return b, nil
+}
+
+func invalidUTF8Offset(b []byte) int {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return len(b)
+}
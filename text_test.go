@@ -0,0 +1,159 @@
+package capnp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValidateText(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{PointerCount: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "héllo"); err != nil {
+		t.Fatal(err)
+	}
+	bad, err := NewData(seg, []byte("ab\xffc\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(1, bad.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	unterm, err := NewData(seg, []byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(2, unterm.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := seg.msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		i      uint16
+		text   string
+		raw    string // result without validation
+		kind   TextErrorKind
+		offset int
+	}{
+		{i: 0, text: "héllo", raw: "héllo"},
+		{i: 1, raw: "ab\xffc", kind: TextInvalidUTF8, offset: 2},
+		{i: 2, kind: TextUnterminated},
+		{i: 3},
+	}
+
+	msg, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if s, err := p.Struct().Text(test.i); err != nil {
+			t.Errorf("without validation, Text(%d) error: %v", test.i, err)
+		} else if s != test.raw {
+			t.Errorf("without validation, Text(%d) = %q; want %q", test.i, s, test.raw)
+		}
+	}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.ValidateText = true
+	msg, err = dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		s, err := p.Struct().Text(test.i)
+		if test.kind == 0 {
+			if err != nil || s != test.text {
+				t.Errorf("Text(%d) = %q, %v; want %q, <nil>", test.i, s, err, test.text)
+			}
+			continue
+		}
+		te, ok := err.(*TextError)
+		if !ok {
+			t.Errorf("Text(%d) error = %v; want *TextError", test.i, err)
+			continue
+		}
+		if te.Kind != test.kind || te.Offset != test.offset {
+			t.Errorf("Text(%d) error = %+v; want Kind=%d Offset=%d", test.i, te, test.kind, test.offset)
+		}
+	}
+	if s := p.Struct().ReadText(1); s != "" {
+		t.Errorf("ReadText(1) = %q; want \"\"", s)
+	}
+	if _, ok := msg.Err().(*TextError); !ok {
+		t.Errorf("Err() = %v; want *TextError", msg.Err())
+	}
+}
+
+func TestValidateText_List(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl, err := NewTextList(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl.Set(0, "ok")
+	bad, err := NewData(seg, []byte("\xc0\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (PointerList{List: tl.List}).SetPtr(1, bad.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := tl.At(1); err != nil || s != "\xc0" {
+		t.Errorf("without validation, At(1) = %q, %v; want \"\\xc0\", <nil>", s, err)
+	}
+	msg.ValidateText = true
+	if s, err := tl.At(0); err != nil || s != "ok" {
+		t.Errorf("At(0) = %q, %v; want \"ok\", <nil>", s, err)
+	}
+	if _, err := tl.At(1); err == nil {
+		t.Error("At(1) with invalid UTF-8 succeeded")
+	}
+	if _, err := tl.BytesAt(1); err == nil {
+		t.Error("BytesAt(1) with invalid UTF-8 succeeded")
+	}
+}
+
+func TestPtrValidText(t *testing.T) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt, err := NewText(seg, "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := txt.ToPtr().ValidText(); err != nil || s != "hi" {
+		t.Errorf("ValidText() = %q, %v; want \"hi\", <nil>", s, err)
+	}
+	if s, err := (Ptr{}).ValidText(); err != nil || s != "" {
+		t.Errorf("null ValidText() = %q, %v; want \"\", <nil>", s, err)
+	}
+	st, err := NewStruct(seg, ObjectSize{DataSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.ToPtr().ValidText()
+	if te, ok := err.(*TextError); !ok || te.Kind != TextNotBytes {
+		t.Errorf("struct ValidText() error = %v; want TextNotBytes", err)
+	}
+}
@@ -0,0 +1,107 @@
+package capnp
+
+import "errors"
+
+// A struct written by a newer version of a schema may be larger than
+// the local schema's struct: new fields are appended to the end of the
+// data and pointer sections.  The functions in this file help a proxy
+// pass such structs along without dropping the fields that it doesn't
+// know about.  Copying a struct with SetPtr or Message.SetRootPtr
+// already preserves them; building a new struct with the local
+// schema's size and copying its fields one at a time, or setting an
+// element of a struct list that was allocated with the local size, does
+// not.
+
+// CopyStruct allocates a copy of src, preferring placement in s.  The
+// copy is at least as large as sz, which is usually the local schema's
+// size for the struct, and otherwise as large as src, so it holds every
+// field of src, including the ones that the local schema doesn't know
+// about.  The copy's known fields can then be changed as usual through
+// a generated wrapper.  If src is null, CopyStruct returns a new zeroed
+// struct of size sz.
+func CopyStruct(s *Segment, src Struct, sz ObjectSize) (Struct, error) {
+	if src.size.DataSize > sz.DataSize {
+		sz.DataSize = src.size.DataSize
+	}
+	if src.size.PointerCount > sz.PointerCount {
+		sz.PointerCount = src.size.PointerCount
+	}
+	dst, err := NewStruct(s, sz)
+	if err != nil {
+		return Struct{}, err
+	}
+	if !src.IsValid() {
+		return dst, nil
+	}
+	if err := copyStruct(dst, src); err != nil {
+		return Struct{}, err
+	}
+	return dst, nil
+}
+
+// HasUnknownFields reports whether s holds data or pointers beyond
+// known, the local schema's size for the struct.  Zeroed data and null
+// pointers past known are not counted, since they are indistinguishable
+// from fields left at their defaults.
+func HasUnknownFields(s Struct, known ObjectSize) bool {
+	if s.seg == nil {
+		return false
+	}
+	if s.size.DataSize > known.DataSize {
+		start, _ := s.off.addSize(known.DataSize)
+		for _, b := range s.seg.slice(start, s.size.DataSize-known.DataSize) {
+			if b != 0 {
+				return true
+			}
+		}
+	}
+	for i := known.PointerCount; i < s.size.PointerCount; i++ {
+		if s.seg.readRawPointer(s.pointerAddress(i)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyUnknownFields copies the fields of src that lie beyond known, the
+// local schema's size for the struct, into dst.  It is meant for
+// structs that are rebuilt from their known fields, for example with
+// the pogs package: the rebuilt struct, allocated with CopyStruct or
+// otherwise large enough, gets back the fields that were lost.  dst's
+// known fields are left as is.  CopyUnknownFields returns an error if
+// dst is too small to hold src's unknown fields.
+func CopyUnknownFields(dst, src Struct, known ObjectSize) error {
+	if !src.IsValid() || !HasUnknownFields(src, known) {
+		return nil
+	}
+	if dst.seg == nil {
+		return errUnknownFieldsSize
+	}
+	if dst.seg.msg.frozen {
+		return errFrozen
+	}
+	if src.size.DataSize > known.DataSize {
+		if dst.size.DataSize < src.size.DataSize {
+			return errUnknownFieldsSize
+		}
+		sz := src.size.DataSize - known.DataSize
+		srcStart, _ := src.off.addSize(known.DataSize)
+		dstStart, _ := dst.off.addSize(known.DataSize)
+		copy(dst.seg.slice(dstStart, sz), src.seg.slice(srcStart, sz))
+	}
+	if src.size.PointerCount > dst.size.PointerCount {
+		return errUnknownFieldsSize
+	}
+	for i := known.PointerCount; i < src.size.PointerCount; i++ {
+		p, err := src.Ptr(i)
+		if err != nil {
+			return err
+		}
+		if err := dst.SetPtr(i, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errUnknownFieldsSize = errors.New("capnp: struct too small for unknown fields")
@@ -0,0 +1,114 @@
+package capnp
+
+import "testing"
+
+// newVersionedStruct returns a struct written by a newer schema than
+// known: it has a field past known in both sections.
+func newVersionedStruct(t *testing.T) (s Struct, known ObjectSize) {
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewRootStruct(seg, ObjectSize{DataSize: 16, PointerCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetUint64(0, 1)
+	s.SetUint64(8, 42)
+	if err := s.SetText(0, "old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetText(1, "new"); err != nil {
+		t.Fatal(err)
+	}
+	return s, ObjectSize{DataSize: 8, PointerCount: 1}
+}
+
+func checkUnknownFields(t *testing.T, name string, s Struct) {
+	t.Helper()
+	if got := s.Uint64(8); got != 42 {
+		t.Errorf("%s: unknown data = %d; want 42", name, got)
+	}
+	if got, err := s.Text(1); err != nil || got != "new" {
+		t.Errorf("%s: unknown pointer = %q, %v; want \"new\"", name, got, err)
+	}
+}
+
+func TestHasUnknownFields(t *testing.T) {
+	s, known := newVersionedStruct(t)
+	if !HasUnknownFields(s, known) {
+		t.Error("HasUnknownFields = false; want true")
+	}
+	if HasUnknownFields(s, s.Size()) {
+		t.Error("HasUnknownFields(s, s.Size()) = true; want false")
+	}
+	s.SetUint64(8, 0)
+	s.SetPtr(1, Ptr{})
+	if HasUnknownFields(s, known) {
+		t.Error("HasUnknownFields with zeroed extra fields = true; want false")
+	}
+	if HasUnknownFields(Struct{}, known) {
+		t.Error("HasUnknownFields(Struct{}) = true; want false")
+	}
+}
+
+func TestCopyStruct(t *testing.T) {
+	src, known := newVersionedStruct(t)
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := CopyStruct(seg, src, known)
+	if err != nil {
+		t.Fatal("CopyStruct:", err)
+	}
+	if dst.Segment() != seg {
+		t.Error("CopyStruct did not allocate in seg")
+	}
+	if dst.Uint64(0) != 1 {
+		t.Errorf("known data = %d; want 1", dst.Uint64(0))
+	}
+	checkUnknownFields(t, "CopyStruct", dst)
+
+	// A newer local schema gets room for its own fields.
+	big := ObjectSize{DataSize: 24, PointerCount: 3}
+	dst, err = CopyStruct(seg, src, big)
+	if err != nil {
+		t.Fatal("CopyStruct with larger size:", err)
+	}
+	if dst.Size() != big {
+		t.Errorf("size = %v; want %v", dst.Size(), big)
+	}
+	checkUnknownFields(t, "CopyStruct with larger size", dst)
+}
+
+func TestCopyUnknownFields(t *testing.T) {
+	src, known := newVersionedStruct(t)
+	_, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := NewStruct(seg, src.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.SetUint64(0, 7)
+	if err := CopyUnknownFields(dst, src, known); err != nil {
+		t.Fatal("CopyUnknownFields:", err)
+	}
+	if dst.Uint64(0) != 7 {
+		t.Errorf("known data = %d; want unchanged 7", dst.Uint64(0))
+	}
+	if p, _ := dst.Ptr(0); p.IsValid() {
+		t.Error("known pointer set; want unchanged null")
+	}
+	checkUnknownFields(t, "CopyUnknownFields", dst)
+
+	small, err := NewStruct(seg, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyUnknownFields(small, src, known); err == nil {
+		t.Error("CopyUnknownFields into small struct succeeded")
+	}
+}
@@ -0,0 +1,246 @@
+// Package vat structures a program as a set of in-process vats that
+// exchange capabilities, without serializing calls between them.
+//
+// A Vat is a membrane around the capabilities of one module.  Every
+// capability that crosses the membrane, whether handed out with Export,
+// taken in with Import, or carried in the parameters or results of a
+// call across it, is wrapped so that it can be revoked along with the
+// vat.  A capability that crosses back to its own side is unwrapped,
+// so the module sees its own objects again.  Calls across the membrane
+// are delivered directly to the other side's client: parameters and
+// results are only copied when they hold capabilities, and never
+// encoded.
+//
+// Attenuation composes with a vat: export a capability from the ocap
+// package (such as one made with ocap.Attenuate) to hand out only some
+// of an object's methods.  Only the network edges of the program need
+// an rpc.Conn.
+package vat // import "zombiezen.com/go/capnproto2/vat"
+
+import (
+	"errors"
+	"sync"
+
+	"zombiezen.com/go/capnproto2"
+)
+
+// A Vat is a membrane around a group of capabilities in the current
+// process.  It is safe to use from multiple goroutines.
+type Vat struct {
+	name string
+
+	mu      sync.Mutex
+	revoked bool
+	owned   map[*client]struct{} // clients passed to Export or Import
+}
+
+// New returns a new vat.  name is used only for debugging.
+func New(name string) *Vat {
+	return &Vat{name: name, owned: make(map[*client]struct{})}
+}
+
+// String returns the vat's name.
+func (v *Vat) String() string {
+	return v.name
+}
+
+// Export returns a capability for code outside the vat that forwards
+// calls to c, an object inside the vat.  The returned client takes
+// ownership of c.
+func (v *Vat) Export(c capnp.Client) capnp.Client {
+	return v.own(c, true)
+}
+
+// Import returns a capability for code inside the vat that forwards
+// calls to c, an object outside the vat.  The returned client takes
+// ownership of c.
+func (v *Vat) Import(c capnp.Client) capnp.Client {
+	return v.own(c, false)
+}
+
+func (v *Vat) own(c capnp.Client, inward bool) capnp.Client {
+	cl := &client{v: v, c: c, inward: inward, owned: true}
+	v.mu.Lock()
+	if v.revoked {
+		v.mu.Unlock()
+		c.Close()
+		return cl
+	}
+	v.owned[cl] = struct{}{}
+	v.mu.Unlock()
+	return cl
+}
+
+// Revoke cuts the vat off from the rest of the process.  Subsequent
+// calls on every capability that crossed the membrane, in either
+// direction, fail with ErrRevoked, and the clients passed to Export and
+// Import are closed.  Calls that were already delivered are allowed to
+// return, but the capabilities in their results are revoked as well.
+// Revoke returns the first error from closing a client.
+func (v *Vat) Revoke() error {
+	v.mu.Lock()
+	if v.revoked {
+		v.mu.Unlock()
+		return nil
+	}
+	v.revoked = true
+	owned := v.owned
+	v.owned = nil
+	v.mu.Unlock()
+	var firstErr error
+	for cl := range owned {
+		if err := cl.c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Revoked reports whether Revoke has been called.
+func (v *Vat) Revoked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.revoked
+}
+
+// wrap returns the capability that stands for c on the other side of
+// the membrane.  inward reports whether c is an object inside the vat.
+// The result does not own c.
+func (v *Vat) wrap(c capnp.Client, inward bool) capnp.Client {
+	if c == nil {
+		return nil
+	}
+	if cl, ok := c.(*client); ok && cl.v == v && cl.inward != inward {
+		// c is crossing back to the side that its object is on.
+		return borrowed{cl.c}
+	}
+	return &client{v: v, c: c, inward: inward}
+}
+
+// cross returns the struct s as seen from the other side of the
+// membrane.  inward reports whether s is crossing into the vat.  s is
+// returned as is if its message holds no capabilities.
+func (v *Vat) cross(s capnp.Struct, inward bool) (capnp.Struct, error) {
+	if !s.IsValid() || len(s.Segment().Message().CapTable) == 0 {
+		return s, nil
+	}
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	if err := msg.SetRootPtr(s.ToPtr()); err != nil {
+		return capnp.Struct{}, err
+	}
+	for i, c := range msg.CapTable {
+		// The capabilities in s point to the side that s comes from.
+		msg.CapTable[i] = v.wrap(c, !inward)
+	}
+	p, err := msg.RootPtr()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	return p.Struct(), nil
+}
+
+// call makes call across the membrane with f.  inward reports whether
+// the call is going into the vat.
+func (v *Vat) call(call *capnp.Call, inward bool, f func(*capnp.Call) capnp.Answer) capnp.Answer {
+	if v.Revoked() {
+		return capnp.ErrorAnswer(&capnp.MethodError{Method: &call.Method, Err: ErrRevoked})
+	}
+	call, err := call.Copy(nil)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	params, err := v.cross(call.Params, inward)
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	vcall := *call
+	vcall.Params = params
+	return &answer{v: v, ans: f(&vcall), inward: inward}
+}
+
+// A client is a capability that crosses a vat's membrane.
+type client struct {
+	v      *Vat
+	c      capnp.Client
+	inward bool // whether c is an object inside the vat
+	owned  bool // whether the client was passed to Export or Import
+}
+
+func (cl *client) Call(call *capnp.Call) capnp.Answer {
+	return cl.v.call(call, cl.inward, cl.c.Call)
+}
+
+// Close closes the underlying client if it was passed to Export or
+// Import and has not been revoked.
+func (cl *client) Close() error {
+	if !cl.owned {
+		return nil
+	}
+	cl.v.mu.Lock()
+	_, ok := cl.v.owned[cl]
+	delete(cl.v.owned, cl)
+	cl.v.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return cl.c.Close()
+}
+
+// An answer is the answer to a call across a vat's membrane.
+type answer struct {
+	v      *Vat
+	ans    capnp.Answer
+	inward bool // whether the call went into the vat
+
+	once sync.Once
+	s    capnp.Struct
+	err  error
+}
+
+func (a *answer) Struct() (capnp.Struct, error) {
+	a.once.Do(func() {
+		s, err := a.ans.Struct()
+		if err != nil {
+			a.err = err
+			return
+		}
+		a.s, a.err = a.v.cross(s, !a.inward)
+	})
+	return a.s, a.err
+}
+
+func (a *answer) PipelineCall(transform []capnp.PipelineOp, call *capnp.Call) capnp.Answer {
+	return a.v.call(call, a.inward, func(call *capnp.Call) capnp.Answer {
+		return a.ans.PipelineCall(transform, call)
+	})
+}
+
+func (a *answer) PipelineClose(transform []capnp.PipelineOp) error {
+	return a.ans.PipelineClose(transform)
+}
+
+// A borrowed client is a capability that has crossed back to its own
+// side of a membrane in a message.  The message does not own it.
+type borrowed struct {
+	c capnp.Client
+}
+
+func (b borrowed) Call(call *capnp.Call) capnp.Answer {
+	return b.c.Call(call)
+}
+
+func (b borrowed) Close() error {
+	return nil
+}
+
+// Client returns the underlying client.
+func (b borrowed) Client() capnp.Client {
+	return b.c
+}
+
+// ErrRevoked is the error returned by calls across the membrane of a
+// revoked vat.
+var ErrRevoked = errors.New("vat: capability revoked")
@@ -0,0 +1,178 @@
+package vat_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"zombiezen.com/go/capnproto2"
+	air "zombiezen.com/go/capnproto2/internal/aircraftlib"
+	"zombiezen.com/go/capnproto2/vat"
+)
+
+type echoServer struct {
+	closed bool
+}
+
+func (*echoServer) Echo(call air.Echo_echo) error {
+	in, err := call.Params.In()
+	if err != nil {
+		return err
+	}
+	return call.Results.SetOut(in)
+}
+
+func (e *echoServer) Close() error {
+	e.closed = true
+	return nil
+}
+
+func echo(c capnp.Client) error {
+	_, err := air.Echo{Client: c}.Echo(context.Background(), func(p air.Echo_echo_Params) error {
+		return p.SetIn("hi")
+	}).Struct()
+	return err
+}
+
+// Methods of holder's made-up interface.
+var (
+	getMethod  = capnp.Method{InterfaceID: 0xe1b5b3d4c2a7f001, MethodID: 0}
+	keepMethod = capnp.Method{InterfaceID: 0xe1b5b3d4c2a7f001, MethodID: 1}
+)
+
+// holder returns its Echo capability from get and keeps the capability
+// passed to keep.
+type holder struct {
+	echo   capnp.Client
+	kept   []capnp.Client
+	closed bool
+}
+
+func (h *holder) Call(call *capnp.Call) capnp.Answer {
+	msg, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	s, err := capnp.NewRootStruct(seg, capnp.ObjectSize{PointerCount: 1})
+	if err != nil {
+		return capnp.ErrorAnswer(err)
+	}
+	switch call.Method.MethodID {
+	case getMethod.MethodID:
+		if err := s.SetPtr(0, capnp.NewInterface(seg, msg.AddCap(h.echo)).ToPtr()); err != nil {
+			return capnp.ErrorAnswer(err)
+		}
+	case keepMethod.MethodID:
+		p, err := call.Params.Ptr(0)
+		if err != nil {
+			return capnp.ErrorAnswer(err)
+		}
+		h.kept = append(h.kept, p.Interface().Client())
+	}
+	return capnp.ImmediateAnswer(s)
+}
+
+func (h *holder) Close() error {
+	h.closed = true
+	return nil
+}
+
+func get(c capnp.Client) (capnp.Client, error) {
+	res, err := c.Call(&capnp.Call{Ctx: context.Background(), Method: getMethod}).Struct()
+	if err != nil {
+		return nil, err
+	}
+	p, err := res.Ptr(0)
+	if err != nil {
+		return nil, err
+	}
+	return p.Interface().Client(), nil
+}
+
+func keep(c, kept capnp.Client) error {
+	_, err := c.Call(&capnp.Call{
+		Ctx:        context.Background(),
+		Method:     keepMethod,
+		ParamsSize: capnp.ObjectSize{PointerCount: 1},
+		ParamsFunc: func(s capnp.Struct) error {
+			id := s.Segment().Message().AddCap(kept)
+			return s.SetPtr(0, capnp.NewInterface(s.Segment(), id).ToPtr())
+		},
+	}).Struct()
+	return err
+}
+
+func isRevoked(err error) bool {
+	me, ok := err.(*capnp.MethodError)
+	return ok && me.Err == vat.ErrRevoked
+}
+
+func TestVat(t *testing.T) {
+	v := vat.New("test")
+	inside := new(echoServer)
+	h := &holder{echo: air.Echo_ServerToClient(inside).Client}
+	hc := v.Export(h)
+
+	e, err := get(hc)
+	if err != nil {
+		t.Fatal("get:", err)
+	}
+	if err := echo(e); err != nil {
+		t.Error("echo on exported result:", err)
+	}
+	outside := new(echoServer)
+	if err := keep(hc, air.Echo_ServerToClient(outside).Client); err != nil {
+		t.Fatal("keep(outside):", err)
+	}
+	if err := keep(hc, e); err != nil {
+		t.Fatal("keep(inside):", err)
+	}
+	for i, c := range h.kept {
+		if err := echo(c); err != nil {
+			t.Errorf("echo on kept[%d]: %v", i, err)
+		}
+	}
+
+	if err := v.Revoke(); err != nil {
+		t.Error("Revoke:", err)
+	}
+	if !h.closed {
+		t.Error("Revoke did not close the exported client")
+	}
+	if _, err := get(hc); !isRevoked(err) {
+		t.Errorf("get after Revoke: error = %v; want %v", err, vat.ErrRevoked)
+	}
+	if err := echo(e); !isRevoked(err) {
+		t.Errorf("echo on exported result after Revoke: error = %v; want %v", err, vat.ErrRevoked)
+	}
+	if err := echo(h.kept[0]); !isRevoked(err) {
+		t.Errorf("echo on imported parameter after Revoke: error = %v; want %v", err, vat.ErrRevoked)
+	}
+	// The vat's own capability came back unwrapped.
+	if err := echo(h.kept[1]); err != nil {
+		t.Error("echo on returned capability after Revoke:", err)
+	}
+	if err := hc.Close(); err != nil {
+		t.Error("Close after Revoke:", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	v := vat.New("test")
+	outside := new(echoServer)
+	c := v.Import(air.Echo_ServerToClient(outside).Client)
+	if err := echo(c); err != nil {
+		t.Error("echo:", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	if !outside.closed {
+		t.Error("Close did not close the imported client")
+	}
+	if err := v.Revoke(); err != nil {
+		t.Error("Revoke:", err)
+	}
+	if err := echo(v.Export(air.Echo_ServerToClient(new(echoServer)).Client)); !isRevoked(err) {
+		t.Errorf("echo on client exported after Revoke: error = %v; want %v", err, vat.ErrRevoked)
+	}
+}
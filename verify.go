@@ -0,0 +1,355 @@
+package capnp
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Verify checks that msg's root is a struct and then calls f with it.
+// f is usually a verifier generated by capnpc-go with the -verify flag,
+// which checks that every pointer the schema describes is in bounds and
+// of the expected kind, that text is NUL-terminated, and that enums and
+// union tags are in range.
+//
+// Verification reads the whole message, so it counts against the
+// message's read limit like any other read.  If f succeeds, Verify
+// restores the read limit to its value before the call, so that reading
+// a verified message costs no more than reading an unverified one.
+// After a message is verified, the generated accessors will not return
+// errors for it, and the non-failing Read accessors like
+// Struct.ReadText are safe to use.
+//
+// Pointers that the schema does not describe, like those added by a
+// newer version of it, are not checked, since the generated accessors
+// never read them.
+func Verify(msg *Message, f func(Struct) error) error {
+	limit := atomic.LoadUint64(&msg.ReadLimiter().limit)
+	p, err := msg.RootPtr()
+	if err != nil {
+		return err
+	}
+	if p.flags.ptrType() != structPtrType {
+		return errVerifyStruct
+	}
+	if err := f(p.Struct()); err != nil {
+		return err
+	}
+	msg.ReadLimiter().Reset(limit)
+	return nil
+}
+
+// VerifyPtr checks that p and every object reachable from it are in
+// bounds.  It does not know the schema of the objects, so it is used
+// for AnyPointer fields and other untyped data.
+func VerifyPtr(p Ptr) error {
+	switch p.flags.ptrType() {
+	case structPtrType:
+		return verifyStructPtrs(p.Struct())
+	case listPtrType:
+		return verifyListPtrs(p.List())
+	}
+	return nil
+}
+
+func verifyStructPtrs(s Struct) error {
+	for i := uint16(0); i < s.size.PointerCount; i++ {
+		p, err := s.Ptr(i)
+		if err != nil {
+			return err
+		}
+		if err := VerifyPtr(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyListPtrs(l List) error {
+	if l.flags&isBitList != 0 || l.size.PointerCount == 0 {
+		return nil
+	}
+	if l.depthLimit == 0 {
+		return errDepthLimit
+	}
+	for i := 0; i < l.Len(); i++ {
+		if err := verifyStructPtrs(l.Struct(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyEnum returns an error if v is not less than n, the number of
+// values of the enum or union named name.
+func VerifyEnum(name string, v, n uint16) error {
+	if v >= n {
+		return fmt.Errorf("capnp: %s value %d out of range", name, v)
+	}
+	return nil
+}
+
+// VerifyText checks that the i'th pointer in s is null or text.
+func VerifyText(s Struct, i uint16) error {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return err
+	}
+	return verifyText(p)
+}
+
+func verifyText(p Ptr) error {
+	if !p.IsValid() {
+		return nil
+	}
+	if _, ok := p.text(); !ok {
+		return errVerifyText
+	}
+	return nil
+}
+
+// VerifyData checks that the i'th pointer in s is null or data.
+func VerifyData(s Struct, i uint16) error {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return err
+	}
+	return verifyData(p)
+}
+
+func verifyData(p Ptr) error {
+	if p.IsValid() && !isOneByteList(p) {
+		return errVerifyData
+	}
+	return nil
+}
+
+// VerifyFixedData checks that the i'th pointer in s is null or a data
+// of exactly n bytes.
+func VerifyFixedData(s Struct, i uint16, n int) error {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return err
+	}
+	return verifyFixedData(p, n)
+}
+
+func verifyFixedData(p Ptr, n int) error {
+	if err := verifyData(p); err != nil || !p.IsValid() {
+		return err
+	}
+	if m := p.List().Len(); m != n {
+		return &FixedDataError{Len: m, Want: n}
+	}
+	return nil
+}
+
+// VerifyStruct checks that the i'th pointer in s is null or a struct.
+// If it is a struct, VerifyStruct calls f with it, or checks the
+// struct's pointers with VerifyPtr if f is nil.
+func VerifyStruct(s Struct, i uint16, f func(Struct) error) error {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return err
+	}
+	return verifyStruct(p, f)
+}
+
+func verifyStruct(p Ptr, f func(Struct) error) error {
+	if !p.IsValid() {
+		return nil
+	}
+	if p.flags.ptrType() != structPtrType {
+		return errVerifyStruct
+	}
+	if f == nil {
+		return verifyStructPtrs(p.Struct())
+	}
+	return f(p.Struct())
+}
+
+// VerifyInterface checks that the i'th pointer in s is null or an
+// interface.
+func VerifyInterface(s Struct, i uint16) error {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return err
+	}
+	if p.IsValid() && p.flags.ptrType() != interfacePtrType {
+		return errVerifyInterface
+	}
+	return nil
+}
+
+// VerifyAnyPointer checks the i'th pointer in s with VerifyPtr.
+func VerifyAnyPointer(s Struct, i uint16) error {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return err
+	}
+	return VerifyPtr(p)
+}
+
+// verifyList returns the list in s's i'th pointer, or an error if it
+// is not null or a list.
+func verifyList(s Struct, i uint16) (List, error) {
+	p, err := s.Ptr(i)
+	if err != nil {
+		return List{}, err
+	}
+	if p.IsValid() && p.flags.ptrType() != listPtrType {
+		return List{}, errVerifyList
+	}
+	return p.List(), nil
+}
+
+// VerifyList checks that the i'th pointer in s is null or a list whose
+// elements can be read as elements of size sz, and that any pointers in
+// its elements are in bounds.  sz is the element size of a primitive or
+// pointer list; a zero sz accepts any list except a bit list.
+func VerifyList(s Struct, i uint16, sz ObjectSize) error {
+	l, err := verifyList(s, i)
+	if err != nil {
+		return err
+	}
+	if err := verifyElementSize(l, sz); err != nil {
+		return err
+	}
+	return verifyListPtrs(l)
+}
+
+func verifyElementSize(l List, sz ObjectSize) error {
+	if !l.IsValid() {
+		return nil
+	}
+	if l.flags&isBitList != 0 {
+		return errElementSize
+	}
+	if l.flags&isCompositeList != 0 {
+		if l.size.DataSize < sz.DataSize || l.size.PointerCount < sz.PointerCount {
+			return errElementSize
+		}
+		return nil
+	}
+	if sz != (ObjectSize{}) && l.size != sz {
+		return errElementSize
+	}
+	return nil
+}
+
+// VerifyBitList checks that the i'th pointer in s is null or a list of
+// bits.
+func VerifyBitList(s Struct, i uint16) error {
+	l, err := verifyList(s, i)
+	if err != nil {
+		return err
+	}
+	if l.IsValid() && l.flags&isBitList == 0 {
+		return errElementSize
+	}
+	return nil
+}
+
+// VerifyEnumList checks that the i'th pointer in s is null or a list of
+// values of the enum named name, which has n values.
+func VerifyEnumList(s Struct, i uint16, name string, n uint16) error {
+	l, err := verifyList(s, i)
+	if err != nil {
+		return err
+	}
+	if err := verifyElementSize(l, ObjectSize{DataSize: 2}); err != nil {
+		return err
+	}
+	return UInt16List{List: l}.VerifyEnum(name, n)
+}
+
+// VerifyEnum returns an error if any element of l is not less than n,
+// the number of values of the enum named name.  Generated enum lists
+// call it from their Validate method.
+func (l UInt16List) VerifyEnum(name string, n uint16) error {
+	var err error
+	l.RangeUint16Column(0, func(_ int, v uint16) bool {
+		err = VerifyEnum(name, v, n)
+		return err == nil
+	})
+	return err
+}
+
+// VerifyStructList checks that the i'th pointer in s is null or a list
+// of structs, and calls f with each element.  If f is nil, the
+// elements' pointers are checked with VerifyPtr.
+func VerifyStructList(s Struct, i uint16, f func(Struct) error) error {
+	l, err := verifyList(s, i)
+	if err != nil {
+		return err
+	}
+	if err := verifyElementSize(l, ObjectSize{}); err != nil {
+		return err
+	}
+	if f == nil {
+		return verifyListPtrs(l)
+	}
+	if l.Len() > 0 && l.depthLimit == 0 {
+		return errDepthLimit
+	}
+	for j := 0; j < l.Len(); j++ {
+		if err := f(l.Struct(j)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyTextList checks that the i'th pointer in s is null or a list
+// of text.
+func VerifyTextList(s Struct, i uint16) error {
+	return verifyPointerList(s, i, verifyText)
+}
+
+// VerifyDataList checks that the i'th pointer in s is null or a list
+// of data.
+func VerifyDataList(s Struct, i uint16) error {
+	return verifyPointerList(s, i, verifyData)
+}
+
+func verifyPointerList(s Struct, i uint16, f func(Ptr) error) error {
+	l, err := verifyList(s, i)
+	if err != nil {
+		return err
+	}
+	if err := verifyElementSize(l, ObjectSize{PointerCount: 1}); err != nil {
+		return err
+	}
+	if l.Len() > 0 && l.depthLimit == 0 {
+		return errDepthLimit
+	}
+	for j := 0; j < l.Len(); j++ {
+		p, err := l.Struct(j).Ptr(0)
+		if err != nil {
+			return err
+		}
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	errVerifyStruct    = errors.New("capnp: pointer is not a struct")
+	errVerifyList      = errors.New("capnp: pointer is not a list")
+	errVerifyInterface = errors.New("capnp: pointer is not an interface")
+	errVerifyText      = errors.New("capnp: text is not a NUL-terminated byte list")
+	errVerifyData      = errors.New("capnp: data is not a byte list")
+)
+
+// A FixedDataError is returned for a fixed-size Data field (see
+// $Go.fixed) that holds the wrong number of bytes.
+type FixedDataError struct {
+	Len  int // number of bytes in the data
+	Want int // number of bytes that the field holds
+}
+
+func (e *FixedDataError) Error() string {
+	return fmt.Sprintf("capnp: data is %d bytes, want %d", e.Len, e.Want)
+}
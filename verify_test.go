@@ -0,0 +1,191 @@
+package capnp
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewRootStruct(seg, ObjectSize{DataSize: 8, PointerCount: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetText(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetData(1, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	child, err := NewStruct(seg, ObjectSize{PointerCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.SetText(0, "child"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.SetPtr(2, child.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	tl, err := NewTextList(seg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl.Set(0, "a")
+	tl.Set(1, "b")
+	if err := root.SetPtr(3, tl.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	root.SetUint16(0, 2)
+
+	check := func(s Struct) error {
+		if err := VerifyEnum("E", s.Uint16(0), 3); err != nil {
+			return err
+		}
+		if err := VerifyText(s, 0); err != nil {
+			return err
+		}
+		if err := VerifyData(s, 1); err != nil {
+			return err
+		}
+		if err := VerifyStruct(s, 2, func(s Struct) error { return VerifyText(s, 0) }); err != nil {
+			return err
+		}
+		return VerifyTextList(s, 3)
+	}
+	msg.ReadLimiter().Reset(1 << 20)
+	if err := Verify(msg, check); err != nil {
+		t.Fatal("Verify:", err)
+	}
+	if limit := atomic.LoadUint64(&msg.ReadLimiter().limit); limit != 1<<20 {
+		t.Errorf("read limit after Verify = %d; want %d", limit, 1<<20)
+	}
+
+	root.SetUint16(0, 3)
+	if err := Verify(msg, check); err == nil {
+		t.Error("Verify with enum out of range succeeded")
+	}
+	root.SetUint16(0, 0)
+
+	// Clobber the text's NUL terminator.
+	p, _ := root.Ptr(0)
+	b := p.Data()
+	b[len(b)-1] = 'x'
+	if err := Verify(msg, check); err == nil {
+		t.Error("Verify with unterminated text succeeded")
+	}
+	b[len(b)-1] = 0
+
+	if err := VerifyStruct(root, 0, nil); err == nil {
+		t.Error("VerifyStruct of text succeeded")
+	}
+	if err := VerifyList(root, 1, ObjectSize{DataSize: 8}); err == nil {
+		t.Error("VerifyList of data as 64-bit list succeeded")
+	}
+	if err := VerifyList(root, 3, ObjectSize{PointerCount: 1}); err != nil {
+		t.Error("VerifyList of text list as pointer list:", err)
+	}
+	if err := VerifyBitList(root, 3); err == nil {
+		t.Error("VerifyBitList of text list succeeded")
+	}
+	if err := VerifyInterface(root, 2); err == nil {
+		t.Error("VerifyInterface of struct succeeded")
+	}
+}
+
+func TestVerify_NotStruct(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewText(seg, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.SetRootPtr(l.ToPtr()); err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	err = Verify(msg, func(Struct) error {
+		called = true
+		return nil
+	})
+	if err == nil {
+		t.Error("Verify of list root succeeded")
+	}
+	if called {
+		t.Error("Verify called f for list root")
+	}
+}
+
+func TestVerifyPtr(t *testing.T) {
+	// A struct with one pointer that points past the end of the segment.
+	msg := &Message{Arena: SingleSegment(make([]byte, 16))}
+	seg, err := msg.Segment(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Root: struct at word 1 with 0 data words and 1 pointer.
+	seg.writeRawPointer(0, rawStructPointer(0, ObjectSize{PointerCount: 1}))
+	// Pointer in root: struct far out of bounds.
+	seg.writeRawPointer(8, rawStructPointer(100, ObjectSize{DataSize: 8}))
+	p, err := msg.RootPtr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPtr(p); err == nil {
+		t.Error("VerifyPtr with out of bounds pointer succeeded")
+	}
+	if err := VerifyAnyPointer(p.Struct(), 0); err == nil {
+		t.Error("VerifyAnyPointer with out of bounds pointer succeeded")
+	}
+}
+
+func TestFixedData(t *testing.T) {
+	msg, seg, err := NewMessage(SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewRootStruct(seg, ObjectSize{PointerCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [4]byte{1, 2, 3, 4}
+	if err := s.SetData(0, want[:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetData(1, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [4]byte
+	if err := s.FixedData(0, got[:]); err != nil || got != want {
+		t.Errorf("FixedData(0) = %v, %v; want %v, <nil>", got, err, want)
+	}
+	got = [4]byte{9, 9, 9, 9}
+	if err := s.FixedData(2, got[:]); err != nil || got != [4]byte{} {
+		t.Errorf("FixedData(2) of null = %v, %v; want zeroes, <nil>", got, err)
+	}
+	err = s.FixedData(1, got[:])
+	if e, ok := err.(*FixedDataError); !ok || e.Len != 3 || e.Want != 4 {
+		t.Errorf("FixedData(1) of 3 bytes error = %v; want FixedDataError{3, 4}", err)
+	}
+	if err := VerifyFixedData(s, 0, 4); err != nil {
+		t.Errorf("VerifyFixedData(0) = %v", err)
+	}
+	if err := VerifyFixedData(s, 1, 4); err == nil {
+		t.Error("VerifyFixedData(1) of 3 bytes = <nil>; want error")
+	}
+
+	got = [4]byte{9, 9, 9, 9}
+	s.ReadFixedData(1, got[:])
+	if got != [4]byte{} {
+		t.Errorf("ReadFixedData(1) = %v; want zeroes", got)
+	}
+	if _, ok := msg.Err().(*FixedDataError); !ok {
+		t.Errorf("msg.Err() = %v; want FixedDataError", msg.Err())
+	}
+}
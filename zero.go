@@ -0,0 +1,86 @@
+package capnp
+
+// zeroOrphan zeroes the objects that the pointer at paddr refers to
+// before the pointer is set to keep, if the message is deterministic.
+func (s *Segment) zeroOrphan(paddr Address, keep Ptr) {
+	if s.msg.Deterministic {
+		s.zeroPtr(paddr, keep, maxDepth)
+	}
+}
+
+// zeroPtr zeroes the object that the pointer at paddr refers to, every
+// object reachable from it, and any far pointer landing pads on the
+// way, so that no trace of them is left in the message once the
+// pointer is overwritten.  The object keep, which the pointer is about
+// to be set to, and its children are left alone: they may already be
+// part of the old tree.  Malformed pointers are skipped, since there is
+// nothing sensible to zero.
+func (s *Segment) zeroPtr(paddr Address, keep Ptr, depth uint) {
+	if depth == 0 {
+		return
+	}
+	val := s.readRawPointer(paddr)
+	if val == 0 {
+		return
+	}
+	dst, base, resolved, err := s.resolveFarPointer(paddr)
+	if err != nil {
+		return
+	}
+	switch resolved.pointerType() {
+	case structPointer:
+		st, err := dst.readStructPtr(base, resolved)
+		if err == nil && !(keep.seg == st.seg && keep.off == st.off && keep.flags.ptrType() == structPtrType) {
+			zeroStruct(st, keep, depth)
+		}
+	case listPointer:
+		l, err := dst.readListPtr(base, resolved)
+		if err == nil && !(keep.seg == l.seg && keep.off == l.off && keep.flags.ptrType() == listPtrType) {
+			zeroList(l, keep, depth)
+		}
+	}
+
+	// Zero the landing pad last, since resolving the pointer reads it.
+	switch val.pointerType() {
+	case farPointer:
+		if pad, err := s.lookupSegment(val.farSegment()); err == nil {
+			pad.writeRawPointer(val.farAddress(), 0)
+		}
+	case doubleFarPointer:
+		if pad, err := s.lookupSegment(val.farSegment()); err == nil {
+			pad.writeRawPointer(val.farAddress(), 0)
+			pad.writeRawPointer(val.farAddress()+Address(wordSize), 0)
+		}
+	}
+}
+
+func zeroStruct(st Struct, keep Ptr, depth uint) {
+	for i := uint16(0); i < st.size.PointerCount; i++ {
+		st.seg.zeroPtr(st.pointerAddress(i), keep, depth-1)
+	}
+	zeroBytes(st.seg, st.off, st.size.totalSize())
+}
+
+func zeroList(l List, keep Ptr, depth uint) {
+	if l.flags&isBitList == 0 && l.size.PointerCount > 0 {
+		for i := 0; i < l.Len(); i++ {
+			el := l.Struct(i)
+			for j := uint16(0); j < el.size.PointerCount; j++ {
+				l.seg.zeroPtr(el.pointerAddress(j), keep, depth-1)
+			}
+		}
+	}
+	off := l.off
+	if l.flags&isCompositeList != 0 {
+		off -= Address(wordSize)
+	}
+	zeroBytes(l.seg, off, l.allocSize())
+}
+
+func zeroBytes(s *Segment, off Address, sz Size) {
+	s.checkWritable()
+	b := s.slice(off, sz)
+	for i := range b {
+		b[i] = 0
+	}
+}